@@ -0,0 +1,85 @@
+// Command rdbconvert converts a snapshot between Mnemo's format and the
+// RDB format of Redis, to move a dataset from one to the other:
+//
+//	rdbconvert -to mnemo dump.rdb mnemo.rdb
+//	rdbconvert -to redis -redis-version 9 mnemo.rdb dump.rdb
+//
+// The input may be in either format. Keys that have already expired are
+// left out.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rdbconvert:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("rdbconvert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "", "format to write: mnemo or redis")
+	redisVersion := flags.Int("redis-version", rdb.RedisVersion, "RDB version to write with -to redis: 9 for Redis 5 to 6.2, 10 for 7.0, 11 for 7.2 and later")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rdbconvert -to mnemo|redis [-redis-version n] input output")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("want an input and an output file")
+	}
+	input, output := flags.Arg(0), flags.Arg(1)
+
+	var save func(*keyspace.Snapshot) error
+	switch *to {
+	case "mnemo":
+		save = func(snapshot *keyspace.Snapshot) error {
+			return rdb.Save(output, snapshot, nil)
+		}
+	case "redis":
+		if *redisVersion < rdb.RedisMinVersion || *redisVersion > rdb.RedisVersion {
+			return fmt.Errorf("-redis-version must be from %d to %d", rdb.RedisMinVersion, rdb.RedisVersion)
+		}
+		save = func(snapshot *keyspace.Snapshot) error {
+			return rdb.SaveRedis(output, snapshot, *redisVersion, nil)
+		}
+	default:
+		return fmt.Errorf("-to must be mnemo or redis, not %q", *to)
+	}
+
+	// Load takes a missing file for an empty one, which is not what
+	// anyone converting it means
+	if _, err := os.Stat(input); err != nil {
+		return err
+	}
+	store := keyspace.New()
+	loaded, err := rdb.Load(input, store)
+	if err != nil {
+		return err
+	}
+	snapshot := store.Snapshot()
+	defer snapshot.Release()
+	if err := save(snapshot); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "converted %d keys from %s to %s\n", loaded, input, output)
+	return nil
+}
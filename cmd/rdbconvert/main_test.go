@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+)
+
+const redisDump = "../../internal/core/rdb/testdata/redis-7.2.rdb"
+
+func load(t *testing.T, path string) *keyspace.Keyspace {
+	t.Helper()
+	store := keyspace.New()
+	_, err := rdb.Load(path, store)
+	require.NoError(t, err)
+	return store
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	mnemo := filepath.Join(dir, "mnemo.rdb")
+	redis := filepath.Join(dir, "redis.rdb")
+
+	var stdout bytes.Buffer
+	require.NoError(t, run([]string{"-to", "mnemo", redisDump, mnemo}, &stdout, &stdout))
+	assert.Equal(t, "converted 7 keys from "+redisDump+" to "+mnemo+"\n", stdout.String())
+	require.NoError(t, run([]string{"-to", "redis", "-redis-version", "9", mnemo, redis}, &stdout, &stdout))
+
+	original, converted := load(t, redisDump), load(t, redis)
+	assert.Equal(t, original.Len(), converted.Len())
+	for _, key := range []string{"compressed", "ttl"} {
+		assert.Equal(t, original.LookupRead(key).Str, converted.LookupRead(key).Str)
+	}
+	assert.Equal(t, original.LookupRead("hash").Hash, converted.LookupRead("hash").Hash)
+	assert.Equal(t, original.LookupRead("list").List.Range(0, -1), converted.LookupRead("list").List.Range(0, -1))
+}
+
+func TestConvertErrors(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.rdb")
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no files", []string{"-to", "redis"}, "want an input and an output file"},
+		{"unknown format", []string{"-to", "json", redisDump, output}, `-to must be mnemo or redis, not "json"`},
+		{"missing format", []string{redisDump, output}, `-to must be mnemo or redis, not ""`},
+		{"old version", []string{"-to", "redis", "-redis-version", "8", redisDump, output}, "-redis-version must be from 9 to 11"},
+		{"missing input", []string{"-to", "redis", filepath.Join(dir, "missing.rdb"), output}, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			assert.ErrorContains(t, run(tt.args, &stderr, &stderr), tt.expected)
+			assert.NoFileExists(t, output)
+		})
+	}
+}
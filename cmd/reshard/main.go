@@ -0,0 +1,118 @@
+// Command reshard moves hash slots from one node of a cluster to another
+// while the cluster serves traffic, to rebalance it:
+//
+//	reshard -from 127.0.0.1:7000 -to 127.0.0.1:7001 -slots 0-99,200
+//	reshard -from 127.0.0.1:7000 -to 127.0.0.1:7001 -count 1000
+//
+// -count moves that many of the source's slots, lowest first. A move that
+// stops part way, for whatever reason, is finished by running the same
+// command again with -replace.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/cluster/reshard"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reshard:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("reshard", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "address of the node to move slots from")
+	to := flags.String("to", "", "address of the node to move slots to")
+	slotList := flags.String("slots", "", "slots to move, as comma-separated slots and first-last ranges")
+	count := flags.Int("count", 0, "number of the source's slots to move, lowest first")
+	batch := flags.Int("batch", 100, "keys to move in each MIGRATE")
+	timeout := flags.Duration("timeout", time.Minute, "how long a MIGRATE may wait for the target")
+	replace := flags.Bool("replace", false, "overwrite keys the target already has")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: reshard -from addr -to addr (-slots list | -count n) [options]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || (*slotList == "") == (*count == 0) || flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("want -from, -to and one of -slots and -count")
+	}
+	var slots []int
+	if *slotList != "" {
+		var err error
+		if slots, err = parseSlots(*slotList); err != nil {
+			return err
+		}
+	}
+
+	source, err := parser.Dial(*from, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := parser.Dial(*to, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	if *count > 0 {
+		owned, err := reshard.OwnedSlots(source)
+		if err != nil {
+			return err
+		}
+		if *count > len(owned) {
+			return fmt.Errorf("%s serves only %d slots", *from, len(owned))
+		}
+		slots = owned[:*count]
+	}
+
+	moved := 0
+	err = reshard.Move(source, target, slots, reshard.Options{
+		Batch:   *batch,
+		Timeout: *timeout,
+		Replace: *replace,
+		Moved: func(slot, keys int) {
+			moved++
+			fmt.Fprintf(stdout, "moved slot %d with %d keys\n", slot, keys)
+		},
+	})
+	fmt.Fprintf(stdout, "moved %d of %d slots from %s to %s\n", moved, len(slots), *from, *to)
+	return err
+}
+
+// parseSlots parses a comma-separated list of slots and ranges.
+func parseSlots(list string) ([]int, error) {
+	var slots []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		first, last, err := reshard.ParseRange(part)
+		if err != nil {
+			return nil, err
+		}
+		for slot := first; slot <= last; slot++ {
+			if seen[slot] {
+				return nil, fmt.Errorf("slot %d is given more than once", slot)
+			}
+			seen[slot] = true
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
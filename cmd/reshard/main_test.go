@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSlots(t *testing.T) {
+	slots, err := parseSlots("0-2,10,5-5")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 10, 5}, slots)
+
+	for _, list := range []string{"", "x", "3-1", "16384", "-1", "0-2,2"} {
+		_, err := parseSlots(list)
+		assert.Error(t, err, list)
+	}
+}
+
+func TestReshardErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no nodes", []string{"-slots", "1"}, "want -from, -to and one of -slots and -count"},
+		{"no slots", []string{"-from", "a:1", "-to", "b:2"}, "want -from, -to and one of -slots and -count"},
+		{"slots and count", []string{"-from", "a:1", "-to", "b:2", "-slots", "1", "-count", "1"}, "want -from, -to and one of -slots and -count"},
+		{"bad slots", []string{"-from", "a:1", "-to", "b:2", "-slots", "1-x"}, `invalid slot range "1-x"`},
+		{"unreachable", []string{"-from", "127.0.0.1:1", "-to", "127.0.0.1:2", "-slots", "1"}, "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			assert.ErrorContains(t, run(tt.args, &stderr, &stderr), tt.expected)
+		})
+	}
+}
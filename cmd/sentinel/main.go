@@ -0,0 +1,109 @@
+// Command sentinel watches a master and its replicas and, should the
+// master fail, promotes the replica with the most of its history and
+// points the others at it. Run three or more, each told of the master:
+//
+//	sentinel -port 26379 -monitor "mymaster 127.0.0.1 6379 2" -down-after 5s
+//
+// Clients find the current master with SENTINEL get-master-addr-by-name.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/suryansh0301/Mnemo/internal/core/sentinel"
+)
+
+func main() {
+	slog.SetLogLoggerLevel(slog.LevelDebug)
+	port, cfg, err := parseConfig(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sentinel:", err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sentinel:", err)
+		os.Exit(1)
+	}
+	s, err := sentinel.New(cfg, listener)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sentinel:", err)
+		os.Exit(1)
+	}
+	slog.Info("sentinel started", "port", port, "id", s.ID())
+	go func() {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
+		s.Close()
+	}()
+	s.Run()
+}
+
+// monitors collects the -monitor flags.
+type monitors []sentinel.MasterConfig
+
+func (m *monitors) String() string {
+	return ""
+}
+
+// Set parses "name host port quorum", as Redis's sentinel monitor takes.
+func (m *monitors) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return errors.New(`want "name host port quorum"`)
+	}
+	quorum, err := strconv.Atoi(fields[3])
+	if err != nil || quorum <= 0 {
+		return fmt.Errorf("invalid quorum %q", fields[3])
+	}
+	if port, err := strconv.Atoi(fields[2]); err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port %q", fields[2])
+	}
+	*m = append(*m, sentinel.MasterConfig{Name: fields[0], Addr: net.JoinHostPort(fields[1], fields[2]), Quorum: quorum})
+	return nil
+}
+
+func parseConfig(args []string, stderr io.Writer) (int, sentinel.Config, error) {
+	flags := flag.NewFlagSet("sentinel", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	port := flags.Int("port", 26379, "port to listen on")
+	var masters monitors
+	flags.Var(&masters, "monitor", `master to watch, as "name host port quorum"; may be repeated`)
+	downAfter := flags.Duration("down-after", sentinel.DefaultDownAfter, "how long a server may go without answering before it is considered down")
+	failoverTimeout := flags.Duration("failover-timeout", sentinel.DefaultFailoverTimeout, "how long each step of a failover may take")
+	pingPeriod := flags.Duration("ping-period", sentinel.DefaultPingPeriod, "how often every server is pinged")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, `usage: sentinel -monitor "name host port quorum" [options]`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 0, sentinel.Config{}, err
+	}
+	if len(masters) == 0 || flags.NArg() != 0 {
+		flags.Usage()
+		return 0, sentinel.Config{}, errors.New("want at least one -monitor")
+	}
+	if *downAfter <= 0 || *failoverTimeout <= 0 || *pingPeriod <= 0 {
+		return 0, sentinel.Config{}, errors.New("-down-after, -failover-timeout and -ping-period must be positive")
+	}
+	for i := range masters {
+		masters[i].DownAfter = *downAfter
+		masters[i].FailoverTimeout = *failoverTimeout
+	}
+	return *port, sentinel.Config{Masters: masters, PingPeriod: min(*pingPeriod, *downAfter)}, nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/core/sentinel"
+)
+
+func TestParseConfig(t *testing.T) {
+	var stderr bytes.Buffer
+	port, cfg, err := parseConfig([]string{"-port", "26380", "-monitor", "a 10.0.0.1 6379 2", "-monitor", "b 10.0.0.2 6380 1",
+		"-down-after", "5s", "-ping-period", "10s"}, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 26380, port)
+	assert.Equal(t, sentinel.Config{
+		Masters: []sentinel.MasterConfig{
+			{Name: "a", Addr: "10.0.0.1:6379", Quorum: 2, DownAfter: 5 * time.Second, FailoverTimeout: sentinel.DefaultFailoverTimeout},
+			{Name: "b", Addr: "10.0.0.2:6380", Quorum: 1, DownAfter: 5 * time.Second, FailoverTimeout: sentinel.DefaultFailoverTimeout},
+		},
+		// pinging no less often than a server may go silent for
+		PingPeriod: 5 * time.Second,
+	}, cfg)
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no master", nil, "want at least one -monitor"},
+		{"extra arguments", []string{"-monitor", "m 127.0.0.1 6379 2", "x"}, "want at least one -monitor"},
+		{"short monitor", []string{"-monitor", "m 127.0.0.1 6379"}, `want "name host port quorum"`},
+		{"bad quorum", []string{"-monitor", "m 127.0.0.1 6379 0"}, `invalid quorum "0"`},
+		{"bad port", []string{"-monitor", "m 127.0.0.1 x 2"}, `invalid port "x"`},
+		{"bad duration", []string{"-monitor", "m 127.0.0.1 6379 2", "-down-after", "0s"}, "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			_, _, err := parseConfig(tt.args, &stderr)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+// freePort returns a port nothing is listening on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// startServer runs the server binary on port with any extra arguments,
+// returning once it answers.
+func startServer(t *testing.T, binary string, port int, args ...string) (*exec.Cmd, *parser.Client) {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command(binary, append([]string{"-port", strconv.Itoa(port), "-dir", dir, "-save", ""}, args...)...)
+	cmd.Stderr = os.Stderr
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := parser.Dial(addr, time.Second)
+		if err == nil {
+			conn.Timeout = 5 * time.Second
+			t.Cleanup(func() { conn.Close() })
+			return cmd, conn
+		}
+		require.True(t, time.Now().Before(deadline), "the server on port %d never started", port)
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func do(t *testing.T, conn *parser.Client, args ...string) common.RespValue {
+	t.Helper()
+	reply, err := conn.Do(args...)
+	require.NoError(t, err)
+	return reply
+}
+
+// eventually polls check until it holds.
+func eventually(t *testing.T, timeout time.Duration, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !check() {
+		require.True(t, time.Now().Before(deadline), "timed out waiting until %s", what)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestFailover runs a master and two replicas as processes, watched by
+// three sentinels, and kills the master.
+func TestFailover(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs the server")
+	}
+	binary := filepath.Join(t.TempDir(), "mnemo")
+	build := exec.Command("go", "build", "-o", binary, "../server")
+	build.Stderr = os.Stderr
+	require.NoError(t, build.Run())
+
+	masterPort := freePort(t)
+	masterProcess, master := startServer(t, binary, masterPort)
+	replicaOf := "127.0.0.1 " + strconv.Itoa(masterPort)
+	replicaPorts := []int{freePort(t), freePort(t)}
+	replicas := make(map[string]*parser.Client)
+	for _, port := range replicaPorts {
+		_, conn := startServer(t, binary, port, "-replicaof", replicaOf)
+		replicas[strconv.Itoa(port)] = conn
+	}
+
+	assert.Equal(t, "OK", do(t, master, "SET", "k", "v").Str)
+	for _, conn := range replicas {
+		eventually(t, 10*time.Second, "the replicas catch up", func() bool {
+			return do(t, conn, "GET", "k").Str == "v"
+		})
+	}
+
+	var sentinels []*parser.Client
+	for range 3 {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		s, err := sentinel.New(sentinel.Config{
+			Masters: []sentinel.MasterConfig{{
+				Name:            "mymaster",
+				Addr:            net.JoinHostPort("127.0.0.1", strconv.Itoa(masterPort)),
+				Quorum:          2,
+				DownAfter:       time.Second,
+				FailoverTimeout: 5 * time.Second,
+			}},
+			PingPeriod: 100 * time.Millisecond,
+		}, listener)
+		require.NoError(t, err)
+		go s.Run()
+		t.Cleanup(s.Close)
+		conn, err := parser.Dial(listener.Addr().String(), 5*time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		sentinels = append(sentinels, conn)
+	}
+	for _, conn := range sentinels {
+		eventually(t, 10*time.Second, "the sentinels find each other and the replicas", func() bool {
+			return len(do(t, conn, "SENTINEL", "SENTINELS", "mymaster").Array) == 2 &&
+				len(do(t, conn, "SENTINEL", "REPLICAS", "mymaster").Array) == 2
+		})
+	}
+	assert.Equal(t, "OK 3 usable Sentinels. Quorum and failover authorization can be reached",
+		do(t, sentinels[0], "SENTINEL", "CKQUORUM", "mymaster").Str)
+
+	require.NoError(t, masterProcess.Process.Kill())
+	masterProcess.Wait()
+
+	var promoted string
+	for _, conn := range sentinels {
+		eventually(t, 20*time.Second, "every sentinel reports a new master", func() bool {
+			reply := do(t, conn, "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster")
+			if len(reply.Array) != 2 || reply.Array[1].Str == strconv.Itoa(masterPort) {
+				return false
+			}
+			if promoted == "" {
+				promoted = reply.Array[1].Str
+			}
+			assert.Equal(t, promoted, reply.Array[1].Str, "the sentinels agree")
+			return true
+		})
+	}
+	require.Contains(t, replicas, promoted)
+
+	newMaster := replicas[promoted]
+	assert.Equal(t, "master", do(t, newMaster, "ROLE").Array[0].Str)
+	assert.Equal(t, "v", do(t, newMaster, "GET", "k").Str)
+	assert.Equal(t, "OK", do(t, newMaster, "SET", "after", "failover").Str)
+	for port, conn := range replicas {
+		if port == promoted {
+			continue
+		}
+		eventually(t, 10*time.Second, "the other replica follows the new master", func() bool {
+			role := do(t, conn, "ROLE")
+			return len(role.Array) == 5 && strconv.FormatInt(role.Array[2].Int, 10) == promoted &&
+				do(t, conn, "GET", "after").Str == "failover"
+		})
+	}
+}
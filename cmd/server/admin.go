@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/metrics"
+)
+
+// trafficMetrics are the metrics the connections record. Each server has
+// its own, on the registry its admin port serves.
+type trafficMetrics struct {
+	inputBytes     *metrics.Counter
+	outputBytes    *metrics.Counter
+	protocolErrors *metrics.Counter
+}
+
+func newTrafficMetrics(r *metrics.Registry) *trafficMetrics {
+	return &trafficMetrics{
+		inputBytes:     r.Counter("mnemo_net_input_bytes_total", "Bytes read from clients."),
+		outputBytes:    r.Counter("mnemo_net_output_bytes_total", "Bytes written to clients."),
+		protocolErrors: r.Counter("mnemo_protocol_errors_total", "Requests that could not be parsed, each of which closes its connection."),
+	}
+}
+
+// adminHandler serves the metrics in r on /metrics, /healthz, which
+// answers as long as the server is up, and /readyz, which only answers 200
+// once ready is set.
+func adminHandler(r *metrics.Registry, ready *atomic.Bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", r)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "loading", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok\n")
+	})
+	return mux
+}
+
+// serveAdmin listens on port and serves handler there in the background.
+func serveAdmin(port int, handler http.Handler) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			slog.Error("the admin server stopped", "error", err)
+		}
+	}()
+	slog.Debug("Serving metrics", "port", port)
+	return nil
+}
@@ -8,7 +8,6 @@ import (
 	"log/slog"
 	"net"
 	"os"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -18,22 +17,21 @@ import (
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
-const (
-	ReadTimeout  = 30 * time.Second
-	WriteTimeout = 30 * time.Second
-)
-
 type client struct {
-	reader         *bufio.Reader
-	writer         *bufio.Writer
-	responseChan   chan common.RespValue
-	pendingRequest sync.WaitGroup
-	parserBuffer   []byte
-	readBuffer     []byte
-	conn           net.Conn
+	reader       *bufio.Reader
+	writer       *bufio.Writer
+	responseChan chan common.RespValue
+	parserBuffer []byte
+	readBuffer   []byte
+	conn         net.Conn
+	session      *datastore.Session
+	traffic      *trafficMetrics
+	// writerDone is closed once the writer has sent everything the
+	// executor queued for this connection
+	writerDone chan struct{}
 }
 
-func newClient(connection net.Conn) *client {
+func newClient(connection net.Conn, traffic *trafficMetrics) *client {
 	reader := bufio.NewReader(connection)
 	writer := bufio.NewWriter(connection)
 
@@ -44,29 +42,66 @@ func newClient(connection net.Conn) *client {
 		parserBuffer: make([]byte, 0, 4096),
 		readBuffer:   make([]byte, 4096),
 		conn:         connection,
+		session: &datastore.Session{
+			Kill:      func() { connection.Close() },
+			CloseRead: func() { closeRead(connection) },
+			Addr:      connection.RemoteAddr().String(),
+			LocalAddr: connection.LocalAddr().String(),
+		},
+		traffic:    traffic,
+		writerDone: make(chan struct{}),
 	}
 }
 
 func (c *client) handleConnection(exec *datastore.Executor, totalClients *atomic.Int64) {
-	_, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 
 	defer c.conn.Close()
 	defer func() {
-		c.drainRequests()
-		close(c.responseChan)
+		// the executor closes responseChan once every reply to our requests
+		// has been queued, so waiting for the writer flushes them all
+		submitted := exec.Submit(datastore.Value{
+			ResponseChan: c.responseChan,
+			Session:      c.session,
+			Disconnect:   true,
+		})
+		if submitted {
+			<-c.writerDone
+		}
 		totalClients.Add(-1)
 	}()
 	defer cancel()
 
-	go c.handleWrites(cancel)
-	c.handleReads(exec)
+	go c.handleWrites(exec, cancel)
+	if !exec.Submit(datastore.Value{ResponseChan: c.responseChan, Session: c.session, Connect: true}) {
+		return
+	}
+	c.handleReads(ctx, exec)
 }
 
-func (c *client) handleWrites(cancel context.CancelFunc) {
-	for resp := range c.responseChan {
-		byteResp := parser.Encoder(resp)
-		c.handleWrite(cancel, byteResp)
+// closeRead stops reading from connection, leaving it open for the replies
+// still to be written, or closes it if it is not TCP.
+func closeRead(connection net.Conn) {
+	if tcp, ok := connection.(*net.TCPConn); ok {
+		tcp.CloseRead()
+		return
+	}
+	connection.Close()
+}
 
+func (c *client) handleWrites(exec *datastore.Executor, cancel context.CancelFunc) {
+	defer close(c.writerDone)
+	for {
+		select {
+		case resp, ok := <-c.responseChan:
+			if !ok {
+				return
+			}
+			byteResp := parser.Encoder(resp)
+			c.handleWrite(cancel, byteResp)
+		case <-exec.Done():
+			return
+		}
 	}
 }
 
@@ -74,7 +109,6 @@ func (c *client) handleWrite(cancel context.CancelFunc, byteResp []byte) {
 	var err error
 
 	defer func() {
-		c.decreasePendingRequest()
 		if err != nil {
 			slog.Info("encountered error while writing", "error", err.Error())
 			c.setReadDeadline(-1)
@@ -82,25 +116,31 @@ func (c *client) handleWrite(cancel context.CancelFunc, byteResp []byte) {
 		}
 	}()
 
-	_, err = c.writer.Write(byteResp)
+	n, err := c.writer.Write(byteResp)
+	c.traffic.outputBytes.Add(uint64(n))
 	if err != nil {
 		return
 	}
 
 	if len(c.responseChan) == 0 {
-		c.setWriteDeadline(WriteTimeout)
+		c.setWriteDeadline(time.Duration(limits.writeTimeout.Load()))
 		err = c.writer.Flush()
 		if err != nil {
 			return
 		}
 	}
+	c.session.SetOutputBuffer(c.writer.Buffered())
 
 }
 
-func (c *client) handleReads(exec *datastore.Executor) {
+func (c *client) handleReads(ctx context.Context, exec *datastore.Executor) {
 	for {
-		c.conn.SetReadDeadline(time.Now().Add(ReadTimeout))
+		c.setReadDeadline(time.Duration(limits.idleTimeout.Load()))
 		n, err := c.read()
+		if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() == nil && c.session.Waiting() {
+			// not idle: blocked on a command or listening for messages
+			continue
+		}
 		if err != nil {
 			if err != io.EOF && !errors.Is(err, os.ErrDeadlineExceeded) {
 				c.handleError()
@@ -114,6 +154,7 @@ func (c *client) handleReads(exec *datastore.Executor) {
 			response := parser.Parse(c.parserBuffer)
 			if response.Error() != nil {
 				// we receive an error response
+				c.traffic.protocolErrors.Inc()
 				c.handleError()
 				return
 			}
@@ -131,17 +172,24 @@ func (c *client) handleReads(exec *datastore.Executor) {
 
 			value, err := parser.Decoder(response)
 			if err != nil {
+				c.traffic.protocolErrors.Inc()
 				c.handleError()
 				return
 			}
 
-			c.increasePendingRequest()
-			exec.ExecutorChan <- datastore.Value{
+			submitted := exec.Submit(datastore.Value{
 				Command:      value,
 				ResponseChan: c.responseChan,
+				Session:      c.session,
+			})
+			if !submitted {
+				return
+			}
+			if enums.StringToCommandName(value.Name) == enums.QuitCommandName {
+				return
 			}
-
 		}
+		c.session.SetQueryBuffer(len(c.parserBuffer), cap(c.parserBuffer)-len(c.parserBuffer))
 	}
 }
 
@@ -150,6 +198,7 @@ func (c *client) read() (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	c.traffic.inputBytes.Add(uint64(n))
 	return n, nil
 }
 
@@ -162,28 +211,25 @@ func (c *client) handleError() {
 		Type: enums.ErrorRespType,
 		Str:  "ERR Protocol error",
 	}
-	c.increasePendingRequest()
 	c.responseChan <- responseErr
 }
 
-func (c *client) increasePendingRequest() {
-	c.pendingRequest.Add(1)
-}
-
-func (c *client) decreasePendingRequest() {
-	c.pendingRequest.Done()
-}
-
-func (c *client) drainRequests() {
-	c.pendingRequest.Wait()
-}
-
+// setReadDeadline sets the read deadline duration from now; a duration of
+// 0 clears it.
 func (c *client) setReadDeadline(duration time.Duration) error {
+	if duration == 0 {
+		return c.conn.SetReadDeadline(time.Time{})
+	}
 	err := c.conn.SetReadDeadline(time.Now().Add(duration))
 	return err
 }
 
+// setWriteDeadline sets the write deadline duration from now; a duration
+// of 0 clears it.
 func (c *client) setWriteDeadline(duration time.Duration) error {
+	if duration == 0 {
+		return c.conn.SetWriteDeadline(time.Time{})
+	}
 	err := c.conn.SetWriteDeadline(time.Now().Add(duration))
 	return err
 }
@@ -2,15 +2,28 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"strconv"
+	"strings"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/core/cluster/reshard"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore"
+	"github.com/suryansh0301/Mnemo/internal/core/metrics"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
 // ── Server Setup ──────────────────────────────────────────────────
@@ -22,9 +35,41 @@ func startTestServer(t *testing.T) string {
 	if err != nil {
 		t.Fatalf("error in listening to the port: %s", err)
 	}
+	serveTestClients(t, listener, startExecutor(), newTrafficMetrics(metrics.NewRegistry()))
+	return listener.Addr().String()
+}
 
-	exec := startExecutor()
-	var totalClients atomic.Int64
+// startClusterTestServer starts a server in cluster mode, with its bus on
+// a port of its own and its node config in a temporary directory.
+func startClusterTestServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error in listening to the port: %s", err)
+	}
+	bus, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error in listening to the bus port: %s", err)
+	}
+	exec := datastore.NewExecutor()
+	err = exec.EnableCluster(cluster.Config{
+		File:        filepath.Join(t.TempDir(), "nodes.conf"),
+		Port:        listener.Addr().(*net.TCPAddr).Port,
+		NodeTimeout: time.Second,
+	}, bus)
+	if err != nil {
+		t.Fatalf("error in enabling cluster mode: %s", err)
+	}
+	runExecutor(exec)
+	serveTestClients(t, listener, exec, newTrafficMetrics(metrics.NewRegistry()))
+	return listener.Addr().String()
+}
+
+// serveTestClients accepts clients on listener for exec until the test
+// ends, recording their traffic in traffic.
+func serveTestClients(t *testing.T, listener net.Listener, exec *datastore.Executor, traffic *trafficMetrics) {
+	connections := exec.Connections()
 
 	go func() {
 		for {
@@ -32,17 +77,19 @@ func startTestServer(t *testing.T) string {
 			if err != nil {
 				return
 			}
+			connections.Received.Add(1)
 
 			accepted := false
 		inner:
 			for {
-				current := totalClients.Load()
-				if current >= MaxClients {
+				current := connections.Connected.Load()
+				if current >= limits.maxClients.Load() {
+					connections.Rejected.Add(1)
 					conn.Write([]byte("-ERR max number of clients reached\r\n"))
 					conn.Close()
 					break inner
 				}
-				if totalClients.CompareAndSwap(current, current+1) {
+				if connections.Connected.CompareAndSwap(current, current+1) {
 					accepted = true
 					break
 				}
@@ -52,17 +99,15 @@ func startTestServer(t *testing.T) string {
 				continue
 			}
 
-			client := newClient(conn)
-			go client.handleConnection(exec, &totalClients)
+			client := newClient(conn, traffic)
+			go client.handleConnection(exec, &connections.Connected)
 		}
 	}()
 
 	t.Cleanup(func() {
 		listener.Close()
-		close(exec.ExecutorChan)
+		exec.Stop()
 	})
-
-	return listener.Addr().String()
 }
 
 func dial(t *testing.T, addr string) net.Conn {
@@ -264,3 +309,525 @@ func TestIntegrationGracefulDisconnect(t *testing.T) {
 	resp := send(t, conn, "*1\r\n$4\r\nPING\r\n")
 	assert.Equal(t, "+PONG\r\n", resp)
 }
+
+func TestIntegrationInfo(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+	assert.Equal(t, "+PONG\r\n", send(t, conn, "*1\r\n$4\r\nPING\r\n"))
+
+	client, err := parser.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+	reply, err := client.Do("INFO", "clients", "stats")
+	if err != nil {
+		t.Fatalf("INFO failed: %v", err)
+	}
+	assert.Contains(t, reply.Str, "# Clients\r\nconnected_clients:2\r\n")
+	assert.Contains(t, reply.Str, "total_connections_received:2\r\n")
+	// as in Redis, the INFO is counted once it has run
+	assert.Contains(t, reply.Str, "total_commands_processed:1\r\n")
+}
+
+func TestIntegrationExpiry(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+
+	resp := send(t, conn, "*5\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n$2\r\nPX\r\n$2\r\n50\r\n")
+	assert.Equal(t, "+OK\r\n", resp)
+
+	resp = send(t, conn, "*2\r\n$3\r\nTTL\r\n$3\r\nfoo\r\n")
+	assert.Equal(t, ":0\r\n", resp)
+
+	time.Sleep(60 * time.Millisecond)
+
+	resp = send(t, conn, "*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n")
+	assert.Equal(t, "$-1\r\n", resp)
+}
+
+func TestIntegrationList(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+
+	resp := send(t, conn, "*4\r\n$5\r\nRPUSH\r\n$4\r\njobs\r\n$1\r\na\r\n$1\r\nb\r\n")
+	assert.Equal(t, ":2\r\n", resp)
+
+	resp = send(t, conn, "*4\r\n$6\r\nLRANGE\r\n$4\r\njobs\r\n$1\r\n0\r\n$2\r\n-1\r\n")
+	assert.Equal(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", resp)
+
+	resp = send(t, conn, "*2\r\n$3\r\nGET\r\n$4\r\njobs\r\n")
+	assert.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", resp)
+}
+
+func TestIntegrationBlockingPop(t *testing.T) {
+	addr := startTestServer(t)
+	worker := dial(t, addr)
+	defer worker.Close()
+	producer := dial(t, addr)
+	defer producer.Close()
+
+	// the worker parks, and its pipelined PING waits behind the BLPOP
+	_, err := worker.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n0\r\n*1\r\n$4\r\nPING\r\n"))
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	resp := send(t, producer, "*3\r\n$5\r\nRPUSH\r\n$4\r\njobs\r\n$3\r\njob\r\n")
+	assert.Equal(t, ":1\r\n", resp)
+
+	expected := "*2\r\n$4\r\njobs\r\n$3\r\njob\r\n+PONG\r\n"
+	assert.Equal(t, expected, readUntil(t, worker, expected))
+}
+
+func TestIntegrationBlockingPopTimeout(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+
+	start := time.Now()
+	resp := send(t, conn, "*3\r\n$8\r\nBZPOPMIN\r\n$5\r\nqueue\r\n$3\r\n0.2\r\n")
+	assert.Equal(t, "*-1\r\n", resp)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestIntegrationBlockingPopDisconnect(t *testing.T) {
+	addr := startTestServer(t)
+	worker := dial(t, addr)
+	producer := dial(t, addr)
+	defer producer.Close()
+
+	_, err := worker.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$4\r\njobs\r\n$1\r\n0\r\n"))
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	worker.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// the closed worker must not swallow the job
+	send(t, producer, "*3\r\n$5\r\nRPUSH\r\n$4\r\njobs\r\n$3\r\njob\r\n")
+	resp := send(t, producer, "*2\r\n$4\r\nLLEN\r\n$4\r\njobs\r\n")
+	assert.Equal(t, ":1\r\n", resp)
+}
+
+func TestIntegrationPubSub(t *testing.T) {
+	addr := startTestServer(t)
+	subscriber := dial(t, addr)
+	defer subscriber.Close()
+	publisher := dial(t, addr)
+	defer publisher.Close()
+
+	resp := send(t, subscriber, "*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n")
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", resp)
+
+	resp = send(t, publisher, "*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+	assert.Equal(t, ":1\r\n", resp)
+
+	expected := "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n"
+	assert.Equal(t, expected, readUntil(t, subscriber, expected))
+
+	resp = send(t, subscriber, "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")
+	assert.Equal(t, "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n", resp)
+}
+
+func TestIntegrationQuit(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+
+	resp := send(t, conn, "*1\r\n$4\r\nQUIT\r\n")
+	assert.Equal(t, "+OK\r\n", resp)
+
+	// the server closes the connection after replying
+	_, err := conn.Read(make([]byte, 16))
+	assert.Error(t, err)
+}
+
+func TestIntegrationTransaction(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dial(t, addr)
+	defer conn.Close()
+	other := dial(t, addr)
+	defer other.Close()
+
+	pipeline := "*1\r\n$5\r\nMULTI\r\n*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n*1\r\n$4\r\nEXEC\r\n"
+	_, err := conn.Write([]byte(pipeline))
+	assert.NoError(t, err)
+	expected := "+OK\r\n+QUEUED\r\n+QUEUED\r\n*2\r\n:1\r\n:2\r\n"
+	assert.Equal(t, expected, readUntil(t, conn, expected))
+
+	// a watched key changed by another client makes EXEC fail
+	assert.Equal(t, "+OK\r\n", send(t, conn, "*2\r\n$5\r\nWATCH\r\n$1\r\nn\r\n"))
+	assert.Equal(t, ":3\r\n", send(t, other, "*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n"))
+	assert.Equal(t, "+OK\r\n", send(t, conn, "*1\r\n$5\r\nMULTI\r\n"))
+	assert.Equal(t, "+QUEUED\r\n", send(t, conn, "*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n"))
+	assert.Equal(t, "*-1\r\n", send(t, conn, "*1\r\n$4\r\nEXEC\r\n"))
+}
+
+// command encodes a command as a client sends it.
+func command(args ...string) string {
+	encoded := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		encoded += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return encoded
+}
+
+func TestIntegrationReplication(t *testing.T) {
+	masterAddr, replicaAddr := startTestServer(t), startTestServer(t)
+	master := dial(t, masterAddr)
+	defer master.Close()
+	replica := dial(t, replicaAddr)
+	defer replica.Close()
+
+	assert.Equal(t, "+OK\r\n", send(t, master, command("SET", "before", "1")))
+	host, port, _ := net.SplitHostPort(masterAddr)
+	assert.Equal(t, "+OK\r\n", send(t, replica, command("REPLICAOF", host, port)))
+	assert.Equal(t, "+OK\r\n", send(t, master, command("SET", "after", "2")))
+
+	// the replica has the snapshot and then the stream once it has
+	// caught up
+	deadline := time.Now().Add(5 * time.Second)
+	for send(t, replica, command("GET", "after")) != "$1\r\n2\r\n" {
+		if time.Now().After(deadline) {
+			t.Fatal("the replica never caught up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "$1\r\n1\r\n", send(t, replica, command("GET", "before")))
+	assert.Equal(t, "-READONLY You can't write against a read only replica.\r\n", send(t, replica, command("SET", "k", "v")))
+	assert.Contains(t, send(t, replica, command("ROLE")), "$9\r\nconnected\r\n")
+
+	// WAIT asks the replica to acknowledge instead of waiting for its
+	// next periodic acknowledgement
+	assert.Equal(t, "+OK\r\n", send(t, master, command("SET", "acked", "3")))
+	start := time.Now()
+	assert.Equal(t, ":1\r\n", send(t, master, command("WAIT", "1", "5000")))
+	assert.Less(t, time.Since(start), time.Second)
+	// a replica without an append-only file never counts for WAITAOF
+	assert.Equal(t, "*2\r\n:0\r\n:0\r\n", send(t, master, command("WAITAOF", "0", "1", "50")))
+
+	assert.Equal(t, "+OK\r\n", send(t, replica, command("REPLICAOF", "NO", "ONE")))
+	assert.Equal(t, "+OK\r\n", send(t, replica, command("SET", "k", "v")))
+}
+
+func TestIntegrationCluster(t *testing.T) {
+	addrs := []string{startClusterTestServer(t), startClusterTestServer(t), startClusterTestServer(t)}
+	conns := make([]net.Conn, len(addrs))
+	for i, addr := range addrs {
+		conns[i] = dial(t, addr)
+		defer conns[i].Close()
+		start, end := i*cluster.SlotCount/3, (i+1)*cluster.SlotCount/3-1
+		assert.Equal(t, "+OK\r\n", send(t, conns[i], command("CLUSTER", "ADDSLOTSRANGE", strconv.Itoa(start), strconv.Itoa(end))))
+	}
+	assert.Equal(t, "-CLUSTERDOWN The cluster is down\r\n", send(t, conns[0], command("GET", "foo")))
+
+	formCluster(t, addrs, conns)
+
+	// foo hashes to slot 12182, which the third node serves
+	assert.Equal(t, ":12182\r\n", send(t, conns[0], command("CLUSTER", "KEYSLOT", "foo")))
+	assert.Equal(t, "-MOVED 12182 "+addrs[2]+"\r\n", send(t, conns[0], command("SET", "foo", "1")))
+	assert.Equal(t, "+OK\r\n", send(t, conns[2], command("SET", "foo", "1")))
+	assert.Equal(t, "-CROSSSLOT Keys in request don't hash to the same slot\r\n", send(t, conns[2], command("SINTER", "foo", "bar")))
+	assert.Equal(t, ":2\r\n", send(t, conns[2], command("SADD", "{foo}a", "x", "y")))
+	assert.Equal(t, ":2\r\n", send(t, conns[2], command("SUNIONSTORE", "{foo}b", "{foo}a")))
+	assert.Equal(t, ":3\r\n", send(t, conns[2], command("CLUSTER", "COUNTKEYSINSLOT", "12182")))
+}
+
+// formCluster has the first node meet the others, who then meet each
+// other through its gossip, and waits for every node to see the cluster
+// up.
+func formCluster(t *testing.T, addrs []string, conns []net.Conn) {
+	t.Helper()
+	for _, addr := range addrs[1:] {
+		host, port, _ := net.SplitHostPort(addr)
+		assert.Equal(t, "+OK\r\n", send(t, conns[0], command("CLUSTER", "MEET", host, port, busPort(t, addr))))
+	}
+	known := "cluster_known_nodes:" + strconv.Itoa(len(addrs))
+	deadline := time.Now().Add(10 * time.Second)
+	for _, conn := range conns {
+		for {
+			info := send(t, conn, command("CLUSTER", "INFO"))
+			if strings.Contains(info, "cluster_state:ok") && strings.Contains(info, known) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("the cluster never formed: %s", info)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// TestIntegrationReshard moves slots between two nodes while clients keep
+// incrementing counters in them, and checks that every key ends up on the
+// new owner exactly once with every increment in it.
+func TestIntegrationReshard(t *testing.T) {
+	addrs := []string{startClusterTestServer(t), startClusterTestServer(t)}
+	conns := make([]net.Conn, len(addrs))
+	for i, addr := range addrs {
+		conns[i] = dial(t, addr)
+		defer conns[i].Close()
+		start, end := i*cluster.SlotCount/2, (i+1)*cluster.SlotCount/2-1
+		assert.Equal(t, "+OK\r\n", send(t, conns[i], command("CLUSTER", "ADDSLOTSRANGE", strconv.Itoa(start), strconv.Itoa(end))))
+		// distinct epochs up front, so no collision is resolved mid-test
+		assert.Equal(t, "+OK\r\n", send(t, conns[i], command("CLUSTER", "SET-CONFIG-EPOCH", strconv.Itoa(i+1))))
+	}
+	formCluster(t, addrs, conns)
+
+	// a few hash tags the first node serves, each a slot of keys
+	var tags []string
+	var slots []int
+	for i := 0; len(tags) < 4; i++ {
+		tag := "{user" + strconv.Itoa(i) + "}"
+		if slot := cluster.KeySlot(tag); slot < cluster.SlotCount/2 {
+			tags = append(tags, tag)
+			slots = append(slots, slot)
+		}
+	}
+	const keysPerTag = 25
+
+	// each writer increments the keys of one tag, in rounds, following
+	// redirects as a cluster client does, until told to stop
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	counts := make([]map[string]int, len(tags))
+	started := make(chan struct{}, len(tags))
+	for i, tag := range tags {
+		counts[i] = make(map[string]int)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := newClusterClient(addrs[0])
+			defer client.close()
+			for round := 0; round == 0 || !stop.Load(); round++ {
+				for k := range keysPerTag {
+					key := tag + ":" + strconv.Itoa(k)
+					reply, err := client.do("INCR", key)
+					if err != nil {
+						t.Errorf("INCR %s: %s", key, err)
+						return
+					}
+					counts[i][key]++
+					if reply.Int != int64(counts[i][key]) {
+						t.Errorf("INCR %s gave %d, want %d", key, reply.Int, counts[i][key])
+						return
+					}
+				}
+				if round == 0 {
+					started <- struct{}{}
+				}
+			}
+		}()
+	}
+	for range tags {
+		<-started
+	}
+
+	source, err := parser.Dial(addrs[0], time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer source.Close()
+	target, err := parser.Dial(addrs[1], time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer target.Close()
+	// small batches, so the writers run between them
+	if err := reshard.Move(source, target, slots, reshard.Options{Batch: 3}); err != nil {
+		t.Fatalf("reshard failed: %v", err)
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	for i, slot := range slots {
+		id := strconv.Itoa(slot)
+		assert.Equal(t, ":0\r\n", send(t, conns[0], command("CLUSTER", "COUNTKEYSINSLOT", id)))
+		assert.Equal(t, ":"+strconv.Itoa(keysPerTag)+"\r\n", send(t, conns[1], command("CLUSTER", "COUNTKEYSINSLOT", id)))
+		assert.Equal(t, "-MOVED "+id+" "+addrs[1]+"\r\n", send(t, conns[0], command("GET", tags[i]+":0")))
+		for key, count := range counts[i] {
+			value := strconv.Itoa(count)
+			assert.Equal(t, "$"+strconv.Itoa(len(value))+"\r\n"+value+"\r\n", send(t, conns[1], command("GET", key)), key)
+		}
+	}
+}
+
+// clusterClient sends commands to whichever node serves their key,
+// following -MOVED and -ASK.
+type clusterClient struct {
+	addr    string
+	clients map[string]*parser.Client
+}
+
+func newClusterClient(addr string) *clusterClient {
+	return &clusterClient{addr: addr, clients: make(map[string]*parser.Client)}
+}
+
+func (c *clusterClient) node(addr string) (*parser.Client, error) {
+	if client := c.clients[addr]; client != nil {
+		return client, nil
+	}
+	client, err := parser.Dial(addr, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[addr] = client
+	return client, nil
+}
+
+func (c *clusterClient) do(args ...string) (common.RespValue, error) {
+	addr, asking := c.addr, false
+	for range 10 {
+		client, err := c.node(addr)
+		if err != nil {
+			return common.RespValue{}, err
+		}
+		if asking {
+			if _, err := client.Do("ASKING"); err != nil {
+				return common.RespValue{}, err
+			}
+		}
+		reply, err := client.Do(args...)
+		if err != nil || reply.Type != enums.ErrorRespType {
+			return reply, err
+		}
+		fields := strings.Fields(reply.Str)
+		switch {
+		case fields[0] == "MOVED" && len(fields) == 3:
+			// the slot's owner changed: every later command goes there
+			c.addr, addr, asking = fields[2], fields[2], false
+		case fields[0] == "ASK" && len(fields) == 3:
+			addr, asking = fields[2], true
+		default:
+			return reply, fmt.Errorf("%s", reply.Str)
+		}
+	}
+	return common.RespValue{}, fmt.Errorf("too many redirects for %v", args)
+}
+
+func (c *clusterClient) close() {
+	for _, client := range c.clients {
+		client.Close()
+	}
+}
+
+// busPort returns the bus port of the node serving clients on addr, as
+// CLUSTER NODES on that node tells it.
+func busPort(t *testing.T, addr string) string {
+	t.Helper()
+	conn := dial(t, addr)
+	defer conn.Close()
+	nodes := send(t, conn, command("CLUSTER", "NODES"))
+	_, bus, found := strings.Cut(nodes, "@")
+	if !found {
+		t.Fatalf("no bus port in %q", nodes)
+	}
+	bus, _, _ = strings.Cut(bus, " ")
+	return bus
+}
+
+func TestIntegrationAdmin(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error in listening to the port: %s", err)
+	}
+	registry := metrics.NewRegistry()
+	var ready atomic.Bool
+	admin := httptest.NewServer(adminHandler(registry, &ready))
+	defer admin.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(admin.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status, "still loading")
+
+	exec := datastore.NewExecutor()
+	exec.SetMetrics(registry)
+	runExecutor(exec)
+	serveTestClients(t, listener, exec, newTrafficMetrics(registry))
+	ready.Store(true)
+	status, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, status)
+
+	requests := []string{"*1\r\n$4\r\nPING\r\n", "*1\r\n$4\r\nNOPE\r\n", "*1\r\n$x\r\n"}
+	replies := []string{"+PONG\r\n", "-ERR unknown command 'NOPE'\r\n", "-ERR Protocol error\r\n"}
+	conn := dial(t, listener.Addr().String())
+	defer conn.Close()
+	assert.Equal(t, replies[0], send(t, conn, requests[0]))
+	assert.Equal(t, replies[1], send(t, conn, requests[1]))
+	bad := dial(t, listener.Addr().String())
+	defer bad.Close()
+	assert.Equal(t, replies[2], send(t, bad, requests[2]))
+
+	status, body := get("/metrics")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "mnemo_commands_total{command=\"ping\",result=\"ok\"} 1\n")
+	assert.Contains(t, body, "mnemo_commands_total{command=\"unknown\",result=\"error\"} 1\n")
+	assert.Contains(t, body, "mnemo_command_duration_seconds_count{command=\"ping\"} 1\n")
+	assert.Contains(t, body, "mnemo_executor_queue_capacity 1024\n")
+	assert.Contains(t, body, fmt.Sprintf("mnemo_net_input_bytes_total %d\n", len(strings.Join(requests, ""))))
+	assert.Contains(t, body, fmt.Sprintf("mnemo_net_output_bytes_total %d\n", len(strings.Join(replies, ""))))
+	assert.Contains(t, body, "mnemo_protocol_errors_total 1\n")
+	assert.Regexp(t, `(?m)^mnemo_connected_clients [12]$`, body)
+}
+
+func TestIntegrationMonitor(t *testing.T) {
+	addr := startTestServer(t)
+	monitor := dial(t, addr)
+	defer monitor.Close()
+	assert.Equal(t, "+OK\r\n", send(t, monitor, command("MONITOR")))
+
+	conn := dial(t, addr)
+	defer conn.Close()
+	assert.Equal(t, "+OK\r\n", send(t, conn, command("SET", "greeting", "hello world")))
+	line := readUntil(t, monitor, "\r\n")
+	assert.Regexp(t, `^\+\d+\.\d{6} \[0 `+conn.LocalAddr().String()+`\] "SET" "greeting" "hello world"\r\n$`, line)
+}
+
+func TestIntegrationClient(t *testing.T) {
+	addr := startTestServer(t)
+	idle := dial(t, addr)
+	defer idle.Close()
+	admin, err := parser.Dial(addr, time.Second)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	reply, err := admin.Do("CLIENT", "SETNAME", "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", reply.Str)
+	// the idle connection is listed though it has sent nothing
+	var lines []string
+	require.Eventually(t, func() bool {
+		reply, err := admin.Do("CLIENT", "LIST")
+		require.NoError(t, err)
+		lines = strings.Split(strings.TrimSuffix(reply.Str, "\n"), "\n")
+		return len(lines) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, strings.Join(lines, "\n"), "addr="+idle.LocalAddr().String()+" laddr="+addr+" name= ")
+	assert.Contains(t, strings.Join(lines, "\n"), "addr="+admin.LocalAddr().String()+" laddr="+addr+" name=admin ")
+
+	reply, err = admin.Do("CLIENT", "KILL", idle.LocalAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "OK", reply.Str)
+	_, err = idle.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "a killed client is disconnected")
+
+	// a client killing itself gets the reply before it is disconnected
+	reply, err = admin.Do("CLIENT", "KILL", "SKIPME", "no")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), reply.Int)
+	_, err = admin.Receive()
+	assert.Error(t, err)
+}
@@ -1,44 +1,148 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/suryansh0301/Mnemo/internal/core/aof"
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/core/config"
 	"github.com/suryansh0301/Mnemo/internal/core/datastore"
+	"github.com/suryansh0301/Mnemo/internal/core/metrics"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
 )
 
-const (
-	MaxClients = 100000
-)
+// clientLimits are the limits on clients that CONFIG SET can change while
+// connections are using them.
+type clientLimits struct {
+	maxClients atomic.Int64
+	// idleTimeout and writeTimeout are durations, 0 meaning no limit
+	idleTimeout  atomic.Int64
+	writeTimeout atomic.Int64
+}
+
+var limits = newClientLimits(config.New())
+
+func newClientLimits(cfg *config.Config) *clientLimits {
+	l := &clientLimits{}
+	l.load(cfg)
+	return l
+}
+
+func (l *clientLimits) load(cfg *config.Config) {
+	l.maxClients.Store(cfg.Int("maxclients"))
+	l.idleTimeout.Store(int64(time.Duration(cfg.Int("timeout")) * time.Second))
+	l.writeTimeout.Store(int64(time.Duration(cfg.Int("write-timeout")) * time.Second))
+}
+
+// logLevels maps loglevel's values, Redis's, onto slog's levels.
+var logLevels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"verbose": slog.LevelDebug + 2,
+	"notice":  slog.LevelInfo,
+	"warning": slog.LevelWarn,
+	"nothing": slog.LevelError + 4,
+}
 
 func main() {
-	listener, err := net.Listen("tcp", ":6379")
+	cfg, err := loadConfig(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	slog.SetLogLoggerLevel(logLevels[cfg.String("loglevel")])
+	limits.load(cfg)
+
+	// the admin port is up while the data loads, so that /readyz can say
+	// it is not done. The executor registers its metrics on the same
+	// registry once it is set up.
+	registry := metrics.NewRegistry()
+	traffic := newTrafficMetrics(registry)
+	var ready atomic.Bool
+	adminPort := int(cfg.Int("admin-port"))
+	if adminPort != 0 {
+		if err := serveAdmin(adminPort, adminHandler(registry, &ready)); err != nil {
+			slog.Error("could not listen on the admin port", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	exec := datastore.NewExecutorWithQueue(int(cfg.Int("executor-queue-size")))
+	// as in Redis, the append-only file is the more complete record, so
+	// the snapshot is only loaded when it is off
+	if cfg.Bool("appendonly") {
+		if err := loadAppendOnly(exec, cfg); err != nil {
+			slog.Error("could not load the append-only file", "error", err)
+			os.Exit(1)
+		}
+	} else if err := loadSnapshot(exec, cfg); err != nil {
+		slog.Error("could not load the snapshot", "error", err)
+		os.Exit(1)
+	}
+	// the rules were checked when the config was loaded
+	rules, _ := rdb.ParseSaveRules(cfg.String("save"))
+	exec.SetRDB(filepath.Join(cfg.String("dir"), cfg.String("dbfilename")), rules)
+	port := int(cfg.Int("port"))
+	exec.SetReplication(port, int(cfg.Int("repl-backlog-size")), cfg.Bool("replica-read-only"))
+	if replicaOf := strings.Fields(cfg.String("replicaof")); len(replicaOf) == 2 {
+		exec.ReplicaOf(net.JoinHostPort(replicaOf[0], replicaOf[1]))
+	}
+	if cfg.Bool("cluster-enabled") {
+		if err := enableCluster(exec, cfg); err != nil {
+			slog.Error("could not start cluster mode", "error", err)
+			os.Exit(1)
+		}
+	}
+	exec.SetConfig(cfg)
+	onConfigChange(cfg)
+	if adminPort != 0 {
+		exec.SetMetrics(registry)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		panic(err)
 	}
-	slog.SetLogLoggerLevel(-4)
-	slog.Debug("Listening on port 6379")
+	slog.Debug("Listening", "port", port, "config", cfg.Path())
 
-	exec := startExecutor()
-	var totalClients atomic.Int64
+	executorDone := runExecutor(exec)
+	go shutdownOnSignal(exec, executorDone)
+	ready.Store(true)
+	connections := exec.Connections()
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
 			panic(err)
 		}
+		connections.Received.Add(1)
 
 		accepted := false
 	inner:
 		for {
-			current := totalClients.Load()
-			if current >= MaxClients {
+			current := connections.Connected.Load()
+			if current >= limits.maxClients.Load() {
+				connections.Rejected.Add(1)
 				conn.Write([]byte("-ERR max number of clients reached\r\n"))
 				conn.Close()
 				break inner
 			}
-			if totalClients.CompareAndSwap(current, current+1) {
+			if connections.Connected.CompareAndSwap(current, current+1) {
 				accepted = true
 				break
 			}
@@ -48,25 +152,159 @@ func main() {
 			continue
 		}
 
-		client := newClient(conn)
-		go client.handleConnection(exec, &totalClients)
+		client := newClient(conn, traffic)
+		go client.handleConnection(exec, &connections.Connected)
+	}
+}
+
+// loadConfig builds the configuration from the defaults, then the config
+// file, if the first argument names one as it does for redis-server, and
+// then the flags that follow.
+func loadConfig(args []string, stderr io.Writer) (*config.Config, error) {
+	cfg := config.New()
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if err := cfg.Load(args[0]); err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	cfg.RegisterFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: server [config-file] [options]")
+		flags.PrintDefaults()
+	}
+	if err := cfg.ParseFlags(flags, args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected argument %q; the config file must come first", flags.Arg(0))
 	}
+	return cfg, nil
+}
+
+// onConfigChange registers the hooks for the parameters CONFIG SET can
+// change that belong to the server rather than the executor.
+func onConfigChange(cfg *config.Config) {
+	reload := func(string) error {
+		limits.load(cfg)
+		return nil
+	}
+	cfg.OnChange("maxclients", reload)
+	cfg.OnChange("timeout", reload)
+	cfg.OnChange("write-timeout", reload)
+	cfg.OnChange("loglevel", func(value string) error {
+		slog.SetLogLoggerLevel(logLevels[value])
+		return nil
+	})
+}
+
+// loadAppendOnly replays the append-only file into exec and then has exec
+// append to it.
+func loadAppendOnly(exec *datastore.Executor, cfg *config.Config) error {
+	policy, err := aof.ParseFsyncPolicy(cfg.String("appendfsync"))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	appendDir := filepath.Join(cfg.String("dir"), cfg.String("appenddirname"))
+	applied, err := aof.Load(appendDir, cfg.String("appendfilename"), cfg.Bool("aof-load-truncated"), exec.Execute)
+	if err != nil {
+		return err
+	}
+	slog.Info("loaded the append-only file", "commands", applied, "duration", time.Since(start))
+
+	file, err := aof.Open(appendDir, cfg.String("appendfilename"), policy)
+	if err != nil {
+		return err
+	}
+	file.SetAutoRewrite(int(cfg.Int("auto-aof-rewrite-percentage")), cfg.Int("auto-aof-rewrite-min-size"))
+	exec.SetAppendOnly(file)
+	return nil
+}
+
+// loadSnapshot loads the snapshot, if there is one, into exec.
+func loadSnapshot(exec *datastore.Executor, cfg *config.Config) error {
+	start := time.Now()
+	loaded, err := exec.LoadRDB(filepath.Join(cfg.String("dir"), cfg.String("dbfilename")))
+	if err != nil {
+		return err
+	}
+	slog.Info("loaded the snapshot", "keys", loaded, "duration", time.Since(start))
+	return nil
+}
+
+// enableCluster makes exec a cluster node, serving the bus on its port.
+func enableCluster(exec *datastore.Executor, cfg *config.Config) error {
+	if cfg.String("replicaof") != "" {
+		return fmt.Errorf("replicaof is not allowed in cluster mode")
+	}
+	port := int(cfg.Int("port"))
+	busPort := int(cfg.Int("cluster-port"))
+	if busPort == 0 {
+		busPort = port + cluster.BusPortOffset
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", busPort))
+	if err != nil {
+		return err
+	}
+	return exec.EnableCluster(cluster.Config{
+		File:        filepath.Join(cfg.String("dir"), cfg.String("cluster-config-file")),
+		Port:        port,
+		NodeTimeout: time.Duration(cfg.Int("cluster-node-timeout")) * time.Millisecond,
+	}, listener)
+}
+
+// shutdownOnSignal stops the executor on SIGINT or SIGTERM and exits once
+// it has saved a final snapshot, if there are save rules, and flushed the
+// append-only file.
+func shutdownOnSignal(exec *datastore.Executor, executorDone <-chan struct{}) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	slog.Info("shutting down")
+	exec.Stop()
+	<-executorDone
+	os.Exit(0)
 }
 
 func startExecutor() *datastore.Executor {
 	exec := datastore.NewExecutor()
+	runExecutor(exec)
+	return exec
+}
 
+// runExecutor starts the executor goroutine. The returned channel is closed
+// once it has exited.
+func runExecutor(exec *datastore.Executor) <-chan struct{} {
+	exited := make(chan struct{})
 	go func() {
-		for value := range exec.ExecutorChan {
-			response := exec.Execute(value.Command)
+		defer close(exited)
+		defer func() {
+			if err := exec.Close(); err != nil {
+				slog.Error("failed to close the append-only file", "error", err)
+			}
+		}()
+		cron := time.NewTicker(time.Second / datastore.CronHz)
+		defer cron.Stop()
 
+		for {
 			select {
-			case value.ResponseChan <- response:
-			default:
-				slog.Debug("response channel full, dropping response")
+			case value, ok := <-exec.ExecutorChan:
+				if !ok {
+					return
+				}
+				exec.Handle(value)
+			case <-exec.Done():
+				return
+			case <-cron.C:
+				// housekeeping shares the executor goroutine so the
+				// datastore never needs a lock
+				exec.Cron()
 			}
 		}
 	}()
 
-	return exec
+	return exited
 }
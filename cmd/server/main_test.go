@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mnemo.conf")
+	require.NoError(t, os.WriteFile(path, []byte("port 7000\nmaxclients 10\nsave 900 1\n"), 0o644))
+
+	var stderr bytes.Buffer
+	cfg, err := loadConfig([]string{path, "-maxclients", "20", "-appendonly", "yes", "-loglevel", "notice"}, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7000), cfg.Int("port"))
+	assert.Equal(t, int64(20), cfg.Int("maxclients"), "flags override the file")
+	assert.True(t, cfg.Bool("appendonly"))
+	assert.Equal(t, "notice", cfg.String("loglevel"))
+	assert.Equal(t, "900 1", cfg.String("save"))
+	assert.Equal(t, path, cfg.Path())
+
+	cfg, err = loadConfig(nil, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6379), cfg.Int("port"))
+	assert.Equal(t, "", cfg.Path())
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"missing file", []string{filepath.Join(t.TempDir(), "missing.conf")}, "no such file or directory"},
+		{"file after flags", []string{"-port", "7000", "mnemo.conf"}, `unexpected argument "mnemo.conf"; the config file must come first`},
+		{"invalid flag", []string{"-timeout", "-1"}, "argument must be between 0 and 2147483647 inclusive"},
+		{"unknown flag", []string{"-nope", "1"}, "flag provided but not defined: -nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			_, err := loadConfig(tt.args, &stderr)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+
+	var stderr bytes.Buffer
+	_, err := loadConfig([]string{"-h"}, &stderr)
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.Contains(t, stderr.String(), "usage: server [config-file] [options]")
+	assert.Contains(t, stderr.String(), "(CONFIG SET changes it at runtime)")
+}
@@ -0,0 +1,372 @@
+// Package aof implements append-only file persistence: every write the
+// executor makes is logged as the RESP command that made it, and replaying
+// the log at startup rebuilds the keyspace. A rewrite compacts the log by
+// replacing it with the commands that recreate the dataset as it is.
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+)
+
+// FsyncPolicy says when the log is forced to disk, trading durability for
+// throughput exactly as Redis's appendfsync does.
+type FsyncPolicy int
+
+const (
+	// FsyncAlways syncs before any reply to a write is sent, so an
+	// acknowledged write is never lost.
+	FsyncAlways FsyncPolicy = iota
+	// FsyncEverySec syncs once a second in the background, losing at most
+	// about a second of writes on a crash.
+	FsyncEverySec
+	// FsyncNo leaves syncing to the operating system.
+	FsyncNo
+)
+
+// ParseFsyncPolicy parses an appendfsync value.
+func ParseFsyncPolicy(value string) (FsyncPolicy, error) {
+	switch value {
+	case "always":
+		return FsyncAlways, nil
+	case "everysec":
+		return FsyncEverySec, nil
+	case "no":
+		return FsyncNo, nil
+	}
+	return 0, fmt.Errorf("aof: invalid appendfsync %q, must be always, everysec or no", value)
+}
+
+func (p FsyncPolicy) String() string {
+	switch p {
+	case FsyncAlways:
+		return "always"
+	case FsyncEverySec:
+		return "everysec"
+	default:
+		return "no"
+	}
+}
+
+// AOF is an open append-only log, kept as a directory of files tied
+// together by a manifest; see manifest. Writes are appended to the last
+// incr file. Its methods must be called from a single goroutine, the
+// executor's; with FsyncEverySec a background goroutine syncs the file
+// once a second.
+type AOF struct {
+	dir      string
+	name     string
+	policy   FsyncPolicy
+	manifest *manifest
+	// file is the incr file being appended to. A rewrite swaps it while
+	// the syncing goroutine may be using it.
+	file atomic.Pointer[logFile]
+	// replaced holds the files swapped out whose sync may not have
+	// finished yet, oldest first
+	replaced []*logFile
+	// buf holds commands appended since the last Flush
+	buf []byte
+	// offset counts the bytes written to the files since Open
+	offset int64
+	// currentSize is the size of the files in the manifest, and baseSize
+	// what it was after the last rewrite, or at Open
+	currentSize int64
+	baseSize    int64
+	// autoRewritePercentage and autoRewriteMinSize are the growth that
+	// makes a rewrite due; see RewriteDue
+	autoRewritePercentage int
+	autoRewriteMinSize    int64
+	rewrite               *rewrite
+	// retryRewriteAfter holds off automatic rewrites after one fails
+	retryRewriteAfter time.Time
+	stop              chan struct{}
+	// stopSyncing stops the goroutine syncing the file once a second,
+	// when the policy is FsyncEverySec
+	stopSyncing chan struct{}
+	wg          sync.WaitGroup
+}
+
+// logFile is an incr file being appended to. end is the log offset its
+// writes have reached and synced the one its last fsync covered, so the
+// goroutines that sync it can tell how much of the log is on disk.
+type logFile struct {
+	*os.File
+	end    atomic.Int64
+	synced atomic.Int64
+}
+
+func (f *logFile) sync() error {
+	end := f.end.Load()
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	f.synced.Store(end)
+	return nil
+}
+
+// Open opens the log for name in dir for appending, creating the
+// directory, manifest and an incr file as needed.
+func Open(dir, name string, policy FsyncPolicy) (*AOF, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("aof: %w", err)
+	}
+	m, err := readManifest(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = &manifest{}
+	}
+	a := &AOF{
+		dir:      dir,
+		name:     name,
+		policy:   policy,
+		manifest: m,
+		stop:     make(chan struct{}),
+	}
+	if len(m.incrs) == 0 {
+		err = a.switchIncr()
+	} else {
+		err = a.openIncr(m.incrs[len(m.incrs)-1])
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.deleteHistory()
+	if a.currentSize, err = a.filesSize(); err != nil {
+		a.file.Load().Close()
+		return nil, err
+	}
+	a.baseSize = a.currentSize
+
+	if policy == FsyncEverySec {
+		a.startSyncing()
+	}
+	return a, nil
+}
+
+// Policy returns the log's fsync policy.
+func (a *AOF) Policy() FsyncPolicy {
+	return a.policy
+}
+
+// SetPolicy changes the log's fsync policy, as CONFIG SET appendfsync
+// does.
+func (a *AOF) SetPolicy(policy FsyncPolicy) {
+	if policy == a.policy {
+		return
+	}
+	if a.policy == FsyncEverySec {
+		close(a.stopSyncing)
+	}
+	a.policy = policy
+	if policy == FsyncEverySec {
+		a.startSyncing()
+	}
+}
+
+// Append logs commands. With FsyncAlways they are written and synced
+// before Append returns; otherwise they are buffered until the next Flush.
+func (a *AOF) Append(cmds ...commands.Command) error {
+	for _, command := range cmds {
+		a.buf = append(a.buf, parser.EncodeCommand(command)...)
+	}
+	if a.policy == FsyncAlways {
+		return a.Flush()
+	}
+	return nil
+}
+
+// Offset returns how many bytes have been written to the log since it
+// was opened. Commands appended but not flushed yet are not counted.
+func (a *AOF) Offset() int64 {
+	return a.offset
+}
+
+// Sizes returns the size of the files in the manifest, and what it was
+// after the last rewrite, or at Open.
+func (a *AOF) Sizes() (current, base int64) {
+	return a.currentSize, a.baseSize
+}
+
+// SyncedOffset returns how much of what Offset counts is known to be on
+// disk.
+func (a *AOF) SyncedOffset() int64 {
+	for len(a.replaced) > 0 {
+		f := a.replaced[0]
+		if synced := f.synced.Load(); synced < f.end.Load() {
+			return synced
+		}
+		a.replaced = a.replaced[1:]
+	}
+	return a.file.Load().synced.Load()
+}
+
+// Flush writes buffered commands to the file, syncing it if the policy is
+// FsyncAlways. On error the commands stay buffered and the next Flush
+// tries again.
+func (a *AOF) Flush() error {
+	if len(a.buf) == 0 {
+		return nil
+	}
+	file := a.file.Load()
+	n, err := file.Write(a.buf)
+	a.currentSize += int64(n)
+	a.offset += int64(n)
+	file.end.Store(a.offset)
+	if err != nil {
+		a.buf = append(a.buf[:0], a.buf[n:]...)
+		return fmt.Errorf("aof: write: %w", err)
+	}
+	a.buf = a.buf[:0]
+	if a.policy == FsyncAlways {
+		if err := file.sync(); err != nil {
+			return fmt.Errorf("aof: fsync: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close abandons any rewrite in progress, then flushes and syncs the file,
+// whatever the policy, and closes it.
+func (a *AOF) Close() error {
+	if rw := a.rewrite; rw != nil {
+		close(rw.cancel)
+		<-rw.done
+		a.discardRewrite(rw)
+	}
+	close(a.stop)
+	a.wg.Wait()
+	file := a.file.Load()
+	err := a.Flush()
+	if syncErr := file.sync(); err == nil && syncErr != nil {
+		err = fmt.Errorf("aof: fsync: %w", syncErr)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("aof: %w", closeErr)
+	}
+	return err
+}
+
+// switchIncr starts a new incr file and makes it the one appended to. The
+// file is created before the manifest lists it, so a crash never leaves
+// the manifest naming a file that does not exist.
+func (a *AOF) switchIncr() error {
+	next := a.manifest.clone()
+	entry := next.addIncr(a.name)
+	path := filepath.Join(a.dir, entry.name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	if err := writeManifest(a.dir, a.name, next); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	a.manifest = next
+	a.setFile(file)
+	return nil
+}
+
+func (a *AOF) openIncr(entry manifestEntry) error {
+	file, err := os.OpenFile(filepath.Join(a.dir, entry.name), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	a.setFile(file)
+	return nil
+}
+
+// setFile makes file the one appended to. The previous file is synced
+// and closed in the background, so the executor never waits on a sync.
+func (a *AOF) setFile(file *os.File) {
+	next := &logFile{File: file}
+	next.end.Store(a.offset)
+	next.synced.Store(a.offset)
+	previous := a.file.Swap(next)
+	if previous == nil {
+		return
+	}
+	a.replaced = append(a.replaced, previous)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		previous.sync()
+		previous.Close()
+	}()
+}
+
+// deleteHistory drops the files a rewrite replaced from the manifest and
+// removes them in the background, since unlinking a big file can be slow.
+// Failing to is logged rather than returned: history files are never
+// replayed, so at worst they are left behind to be retried at next Open.
+func (a *AOF) deleteHistory() {
+	if len(a.manifest.history) == 0 {
+		return
+	}
+	next := a.manifest.clone()
+	history := next.history
+	next.history = nil
+	if err := writeManifest(a.dir, a.name, next); err != nil {
+		slog.Warn("could not remove replaced append-only files from the manifest", "error", err)
+		return
+	}
+	a.manifest = next
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		for _, entry := range history {
+			if err := os.Remove(filepath.Join(a.dir, entry.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("could not delete a replaced append-only file", "file", entry.name, "error", err)
+			}
+		}
+	}()
+}
+
+// filesSize returns the total size of the files in the manifest.
+func (a *AOF) filesSize() (int64, error) {
+	var size int64
+	for _, entry := range a.manifest.files() {
+		info, err := os.Stat(filepath.Join(a.dir, entry.name))
+		if err != nil {
+			return 0, fmt.Errorf("aof: %w", err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// startSyncing starts the goroutine that syncs the file once a second.
+func (a *AOF) startSyncing() {
+	a.stopSyncing = make(chan struct{})
+	a.wg.Add(1)
+	go a.syncEverySecond(a.stopSyncing)
+}
+
+// syncEverySecond runs fsync off the executor goroutine, since it can take
+// far longer than any command.
+func (a *AOF) syncEverySecond(stop <-chan struct{}) {
+	defer a.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.file.Load().sync()
+		case <-stop:
+			return
+		case <-a.stop:
+			return
+		}
+	}
+}
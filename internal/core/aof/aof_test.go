@@ -0,0 +1,229 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+func command(name string, args ...string) commands.Command {
+	return commands.Command{Name: name, Args: args}
+}
+
+const testName = "appendonly.aof"
+
+// loadAll loads the log in dir and returns the commands it replayed.
+func loadAll(t *testing.T, dir string, truncate bool) ([]commands.Command, error) {
+	t.Helper()
+	var replayed []commands.Command
+	_, err := Load(dir, testName, truncate, func(command commands.Command) common.RespValue {
+		replayed = append(replayed, command)
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "OK"}
+	})
+	return replayed, err
+}
+
+// writeLog writes a log to dir made of files with the given contents: a
+// base file, if base is not empty, and then the incr files.
+func writeLog(t *testing.T, dir, base string, incrs ...string) {
+	t.Helper()
+	m := &manifest{}
+	if base != "" {
+		m.baseSeq = 1
+		m.base = &manifestEntry{name: baseName(testName, 1), seq: 1, typ: baseFile}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, m.base.name), []byte(base), 0o644))
+	}
+	for _, contents := range incrs {
+		entry := m.addIncr(testName)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, entry.name), []byte(contents), 0o644))
+	}
+	require.NoError(t, writeManifest(dir, testName, m))
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	for _, policy := range []FsyncPolicy{FsyncAlways, FsyncEverySec, FsyncNo} {
+		parsed, err := ParseFsyncPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+	_, err := ParseFsyncPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestAppendAndLoad(t *testing.T) {
+	for _, policy := range []FsyncPolicy{FsyncAlways, FsyncEverySec, FsyncNo} {
+		t.Run(policy.String(), func(t *testing.T) {
+			dir := t.TempDir()
+			file, err := Open(dir, testName, policy)
+			require.NoError(t, err)
+
+			require.NoError(t, file.Append(command("SET", "k", "v")))
+			require.NoError(t, file.Append(command("MULTI"), command("INCR", "n"), command("EXEC")))
+			require.NoError(t, file.Flush())
+			require.NoError(t, file.Append(command("DEL", "k")))
+			require.NoError(t, file.Close())
+
+			replayed, err := loadAll(t, dir, false)
+			assert.NoError(t, err)
+			assert.Equal(t, []commands.Command{
+				command("SET", "k", "v"),
+				command("INCR", "n"),
+				command("DEL", "k"),
+			}, replayed)
+
+			// reopening appends to the same incr file
+			file, err = Open(dir, testName, policy)
+			require.NoError(t, err)
+			require.NoError(t, file.Append(command("DEL", "n")))
+			require.NoError(t, file.Close())
+			replayed, err = loadAll(t, dir, false)
+			assert.NoError(t, err)
+			assert.Len(t, replayed, 4)
+		})
+	}
+}
+
+func TestAlwaysWritesBeforeAppendReturns(t *testing.T) {
+	dir := t.TempDir()
+	file, err := Open(dir, testName, FsyncAlways)
+	require.NoError(t, err)
+	defer file.Close()
+
+	require.NoError(t, file.Append(command("SET", "k", "v")))
+	data, err := os.ReadFile(filepath.Join(dir, incrName(testName, 1)))
+	require.NoError(t, err)
+	assert.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n", string(data))
+}
+
+func TestSyncedOffset(t *testing.T) {
+	dir := t.TempDir()
+	always, err := Open(dir, testName, FsyncAlways)
+	require.NoError(t, err)
+	defer always.Close()
+	require.NoError(t, always.Append(command("SET", "k", "v")))
+	assert.Equal(t, int64(27), always.Offset())
+	assert.Equal(t, int64(27), always.SyncedOffset())
+
+	file, err := Open(t.TempDir(), testName, FsyncNo)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, file.Append(command("SET", "k", "v")))
+	assert.Zero(t, file.Offset(), "nothing is written before Flush")
+	require.NoError(t, file.Flush())
+	assert.Equal(t, int64(27), file.Offset())
+	assert.Zero(t, file.SyncedOffset())
+
+	// starting a new incr file syncs the one it replaces
+	require.NoError(t, file.switchIncr())
+	require.Eventually(t, func() bool {
+		return file.SyncedOffset() == 27
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, file.Append(command("DEL", "k")))
+	require.NoError(t, file.Flush())
+	assert.Equal(t, int64(27+20), file.Offset())
+	assert.Equal(t, int64(27), file.SyncedOffset())
+}
+
+func TestSetPolicy(t *testing.T) {
+	file, err := Open(t.TempDir(), testName, FsyncNo)
+	require.NoError(t, err)
+	defer file.Close()
+
+	file.SetPolicy(FsyncAlways)
+	assert.Equal(t, FsyncAlways, file.Policy())
+	require.NoError(t, file.Append(command("SET", "k", "v")))
+	assert.Equal(t, int64(27), file.SyncedOffset())
+
+	// the syncing goroutine starts and stops with everysec
+	file.SetPolicy(FsyncEverySec)
+	require.NoError(t, file.Append(command("DEL", "k")))
+	require.NoError(t, file.Flush())
+	require.Eventually(t, func() bool {
+		return file.SyncedOffset() == 27+20
+	}, 5*time.Second, 10*time.Millisecond)
+	file.SetPolicy(FsyncNo)
+	file.SetPolicy(FsyncEverySec)
+}
+
+func TestLoadMissingDirectory(t *testing.T) {
+	replayed, err := loadAll(t, filepath.Join(t.TempDir(), "missing"), false)
+	assert.NoError(t, err)
+	assert.Empty(t, replayed)
+}
+
+func TestLoadMissingListedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "", "")
+	require.NoError(t, os.Remove(filepath.Join(dir, incrName(testName, 1))))
+
+	_, err := loadAll(t, dir, true)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLoadTruncated(t *testing.T) {
+	const whole = "*2\r\n$4\r\nINCR\r\n$1\r\na\r\n"
+	tests := []struct {
+		name     string
+		tail     string
+		replayed []commands.Command
+	}{
+		{"partial command", "*2\r\n$4\r\nIN", []commands.Command{command("INCR", "a")}},
+		{"partial header", "*2\r", []commands.Command{command("INCR", "a")}},
+		{"transaction without exec", "*1\r\n$5\r\nMULTI\r\n" + whole, []commands.Command{command("INCR", "a")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeLog(t, dir, "", whole+tt.tail)
+			path := filepath.Join(dir, incrName(testName, 1))
+
+			_, err := loadAll(t, dir, false)
+			assert.ErrorIs(t, err, ErrTruncated)
+			data, _ := os.ReadFile(path)
+			assert.Equal(t, whole+tt.tail, string(data), "the file is left alone")
+
+			replayed, err := loadAll(t, dir, true)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.replayed, replayed)
+			data, _ = os.ReadFile(path)
+			assert.Equal(t, whole, string(data))
+		})
+	}
+}
+
+func TestLoadTruncatedOnlyInLastFile(t *testing.T) {
+	const whole = "*2\r\n$4\r\nINCR\r\n$1\r\na\r\n"
+	dir := t.TempDir()
+	writeLog(t, dir, whole+"*2\r\n$4", whole)
+
+	// a truncated command anywhere but at the very end is corruption
+	_, err := loadAll(t, dir, true)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func TestLoadBadFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "", "*1\r\n$4\r\nPING\r\ngarbage\r\n")
+
+	_, err := loadAll(t, dir, true)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTruncated)
+}
+
+func TestLoadStopsOnFailedCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "", "*1\r\n$4\r\nNOPE\r\n")
+
+	_, err := Load(dir, testName, true, func(command commands.Command) common.RespValue {
+		return common.RespValue{Type: enums.ErrorRespType, Str: "ERR unknown command 'NOPE'"}
+	})
+	assert.ErrorContains(t, err, "unknown command")
+}
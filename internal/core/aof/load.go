@@ -0,0 +1,149 @@
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// ErrTruncated is returned by Load when a file ends partway through a
+// command and truncating it was not allowed.
+var ErrTruncated = errors.New("aof: file ends with a truncated command")
+
+// Load replays the log for name in dir, the base file and then each incr
+// file listed in its manifest, passing each logged command to apply. A
+// directory without a manifest is an empty log, unless a single-file log
+// from before manifests, at path name next to dir, is found; that is moved
+// into dir as the base file, as Redis 7 upgrades an old appendonly.aof.
+// Load returns the number of commands applied.
+//
+// A crash can leave the last command of the last incr file half written.
+// If truncate is set, Load cuts the file back to the end of the last whole
+// command, and transaction, and carries on, as Redis does with
+// aof-load-truncated; otherwise it returns ErrTruncated and leaves the
+// file alone. Anything else wrong with the files is an error.
+func Load(dir, name string, truncate bool, apply func(commands.Command) common.RespValue) (int, error) {
+	if err := upgradeLegacy(dir, name); err != nil {
+		return 0, err
+	}
+	m, err := readManifest(dir, name)
+	if err != nil || m == nil {
+		return 0, err
+	}
+	files := m.files()
+	applied := 0
+	for i, entry := range files {
+		n, err := loadFile(filepath.Join(dir, entry.name), truncate && i == len(files)-1, apply)
+		applied += n
+		if err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// upgradeLegacy moves a single-file log at name, next to dir, into dir as
+// the base file of a new manifest, unless dir already has a manifest.
+func upgradeLegacy(dir, name string) error {
+	legacy := filepath.Join(filepath.Dir(dir), name)
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, manifestName(name))); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	if err := os.Rename(legacy, filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	slog.Info("moved the append-only file into its directory", "from", legacy, "dir", dir)
+	m := &manifest{base: &manifestEntry{name: name, seq: 1, typ: baseFile}, baseSeq: 1}
+	return writeManifest(dir, name, m)
+}
+
+// loadFile replays the file at path. The commands of a MULTI/EXEC block
+// are applied only once its EXEC has been read, so a transaction is
+// replayed whole or not at all. A truncated tail is cut off if truncate is
+// set and is ErrTruncated otherwise.
+func loadFile(path string, truncate bool, apply func(commands.Command) common.RespValue) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("aof: %w", err)
+	}
+
+	var (
+		offset  int
+		applied int
+		// validEnd is where the last whole command, or transaction, ends
+		validEnd int
+		inMulti  bool
+		queued   []commands.Command
+	)
+	for offset < len(data) {
+		parsed := parser.Parse(data[offset:])
+		if parsed.Error() != nil {
+			return applied, fmt.Errorf("aof: %s: bad file format at offset %d: %w", filepath.Base(path), offset, parsed.Error())
+		}
+		if parsed.BytesConsumed() == 0 {
+			break
+		}
+		command, err := parser.Decoder(parsed)
+		if err != nil {
+			return applied, fmt.Errorf("aof: %s: bad file format at offset %d: %w", filepath.Base(path), offset, err)
+		}
+		offset += parsed.BytesConsumed()
+
+		switch name := enums.StringToCommandName(command.Name); {
+		case name == enums.MultiCommandName && !inMulti:
+			inMulti = true
+			continue
+		case name == enums.ExecCommandName && inMulti:
+			for _, queuedCommand := range queued {
+				if err := replay(queuedCommand, apply); err != nil {
+					return applied, err
+				}
+				applied++
+			}
+			inMulti, queued = false, nil
+		case inMulti:
+			queued = append(queued, command)
+			continue
+		default:
+			if err := replay(command, apply); err != nil {
+				return applied, err
+			}
+			applied++
+		}
+		validEnd = offset
+	}
+
+	if validEnd == len(data) {
+		return applied, nil
+	}
+	if !truncate {
+		return applied, fmt.Errorf("%w: %s at offset %d", ErrTruncated, filepath.Base(path), validEnd)
+	}
+	slog.Warn("truncating the append-only file after an incomplete command",
+		"path", path, "offset", validEnd, "discarded", len(data)-validEnd)
+	if err := os.Truncate(path, int64(validEnd)); err != nil {
+		return applied, fmt.Errorf("aof: %w", err)
+	}
+	return applied, nil
+}
+
+func replay(command commands.Command, apply func(commands.Command) common.RespValue) error {
+	reply := apply(command)
+	if reply.Type == enums.ErrorRespType {
+		return fmt.Errorf("aof: replaying %s: %s", command.Name, reply.Str)
+	}
+	return nil
+}
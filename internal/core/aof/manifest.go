@@ -0,0 +1,231 @@
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fileType is the role of a file listed in the manifest.
+type fileType byte
+
+const (
+	// baseFile holds the dataset as it was when the last rewrite started.
+	baseFile fileType = 'b'
+	// incrFile holds the writes made after its base, in order.
+	incrFile fileType = 'i'
+	// historyFile was replaced by a rewrite and is waiting to be deleted.
+	historyFile fileType = 'h'
+)
+
+// manifestEntry is one line of the manifest.
+type manifestEntry struct {
+	name string
+	seq  int64
+	typ  fileType
+}
+
+// manifest lists the files that make up the log, like the manifest of a
+// Redis 7 multi-part AOF: one base file, then the incr files replayed on
+// top of it in order, the last being the one appended to. A rewrite
+// produces a new base and leaves the files it replaced as history until
+// they are deleted.
+type manifest struct {
+	base    *manifestEntry
+	incrs   []manifestEntry
+	history []manifestEntry
+	// baseSeq and incrSeq are the highest sequence numbers used so far
+	baseSeq int64
+	incrSeq int64
+}
+
+func manifestName(name string) string {
+	return name + ".manifest"
+}
+
+func baseName(name string, seq int64) string {
+	return fmt.Sprintf("%s.%d.base.aof", name, seq)
+}
+
+func incrName(name string, seq int64) string {
+	return fmt.Sprintf("%s.%d.incr.aof", name, seq)
+}
+
+// tempName is the name a file is written under before being renamed into
+// place, so a crash never leaves a half-written file under a real name.
+func tempName(name string) string {
+	return "temp-" + name
+}
+
+func (m *manifest) clone() *manifest {
+	c := *m
+	if m.base != nil {
+		base := *m.base
+		c.base = &base
+	}
+	c.incrs = slices.Clone(m.incrs)
+	c.history = slices.Clone(m.history)
+	return &c
+}
+
+// addIncr adds a new, empty incr file to the end of the manifest and
+// returns it.
+func (m *manifest) addIncr(name string) manifestEntry {
+	m.incrSeq++
+	entry := manifestEntry{name: incrName(name, m.incrSeq), seq: m.incrSeq, typ: incrFile}
+	m.incrs = append(m.incrs, entry)
+	return entry
+}
+
+// files returns the base, if any, and the incr files, in replay order.
+func (m *manifest) files() []manifestEntry {
+	var files []manifestEntry
+	if m.base != nil {
+		files = append(files, *m.base)
+	}
+	return append(files, m.incrs...)
+}
+
+func (m *manifest) encode() []byte {
+	var buf bytes.Buffer
+	for _, entry := range append(m.files(), m.history...) {
+		fmt.Fprintf(&buf, "file %s seq %d type %c\n", entry.name, entry.seq, entry.typ)
+	}
+	return buf.Bytes()
+}
+
+// readManifest reads the manifest for name in dir, returning nil if there
+// is none.
+func readManifest(dir, name string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("aof: %w", err)
+	}
+	return parseManifest(data)
+}
+
+func parseManifest(data []byte) (*manifest, error) {
+	m := &manifest{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entry, err := parseManifestLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("aof: invalid manifest line %d: %w", line, err)
+		}
+		switch entry.typ {
+		case baseFile:
+			if m.base != nil {
+				return nil, fmt.Errorf("aof: invalid manifest line %d: more than one base file", line)
+			}
+			m.base = &entry
+			m.baseSeq = max(m.baseSeq, entry.seq)
+		case incrFile:
+			if len(m.incrs) > 0 && entry.seq <= m.incrs[len(m.incrs)-1].seq {
+				return nil, fmt.Errorf("aof: invalid manifest line %d: incr files out of order", line)
+			}
+			m.incrs = append(m.incrs, entry)
+			m.incrSeq = max(m.incrSeq, entry.seq)
+		case historyFile:
+			m.history = append(m.history, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("aof: %w", err)
+	}
+	return m, nil
+}
+
+// parseManifestLine parses a line of space separated key value pairs, of
+// which file, seq and type are required and anything else is ignored.
+func parseManifestLine(line string) (manifestEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields)%2 != 0 {
+		return manifestEntry{}, errors.New("odd number of fields")
+	}
+	var entry manifestEntry
+	var hasSeq bool
+	for i := 0; i < len(fields); i += 2 {
+		key, value := fields[i], fields[i+1]
+		switch key {
+		case "file":
+			if strings.ContainsAny(value, `/\`) {
+				return manifestEntry{}, fmt.Errorf("file name %q is not in the directory", value)
+			}
+			entry.name = value
+		case "seq":
+			seq, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || seq < 1 {
+				return manifestEntry{}, fmt.Errorf("invalid seq %q", value)
+			}
+			entry.seq, hasSeq = seq, true
+		case "type":
+			if len(value) != 1 || !strings.Contains("bih", value) {
+				return manifestEntry{}, fmt.Errorf("invalid type %q", value)
+			}
+			entry.typ = fileType(value[0])
+		}
+	}
+	if entry.name == "" || !hasSeq || entry.typ == 0 {
+		return manifestEntry{}, errors.New("missing file, seq or type")
+	}
+	return entry, nil
+}
+
+// writeManifest replaces the manifest for name in dir. It writes a
+// temporary file and renames it over the old one, so a crash leaves
+// either the old manifest or the new one.
+func writeManifest(dir, name string, m *manifest) error {
+	path := filepath.Join(dir, manifestName(name))
+	temp := filepath.Join(dir, tempName(manifestName(name)))
+	if err := writeFileSync(temp, m.encode()); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	return syncDir(dir)
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	return nil
+}
+
+// syncDir makes renames and new files in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("aof: fsync %s: %w", dir, err)
+	}
+	return nil
+}
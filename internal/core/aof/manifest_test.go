@@ -0,0 +1,90 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *manifest
+		err      string
+	}{
+		{
+			name: "base, incrs and history",
+			data: "# comment\n" +
+				"file a.2.base.aof seq 2 type b\n" +
+				"file a.3.incr.aof seq 3 type i\n" +
+				"\n" +
+				"file a.4.incr.aof type i seq 4 startoffset 0\n" +
+				"file a.1.base.aof seq 1 type h\n",
+			expected: &manifest{
+				base: &manifestEntry{name: "a.2.base.aof", seq: 2, typ: baseFile},
+				incrs: []manifestEntry{
+					{name: "a.3.incr.aof", seq: 3, typ: incrFile},
+					{name: "a.4.incr.aof", seq: 4, typ: incrFile},
+				},
+				history: []manifestEntry{{name: "a.1.base.aof", seq: 1, typ: historyFile}},
+				baseSeq: 2,
+				incrSeq: 4,
+			},
+		},
+		{name: "empty", data: "", expected: &manifest{}},
+		{name: "two bases", data: "file a seq 1 type b\nfile b seq 2 type b\n", err: "more than one base"},
+		{name: "incrs out of order", data: "file a seq 2 type i\nfile b seq 1 type i\n", err: "out of order"},
+		{name: "missing type", data: "file a seq 1\n", err: "missing"},
+		{name: "odd fields", data: "file a seq\n", err: "odd number"},
+		{name: "bad seq", data: "file a seq x type i\n", err: "invalid seq"},
+		{name: "bad type", data: "file a seq 1 type x\n", err: "invalid type"},
+		{name: "path", data: "file ../a seq 1 type i\n", err: "not in the directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseManifest([]byte(tt.data))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+
+			// encoding and parsing again gives the same manifest
+			again, err := parseManifest(m.encode())
+			require.NoError(t, err)
+			assert.Equal(t, m, again)
+		})
+	}
+}
+
+func TestUpgradeLegacyFile(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "appendonlydir")
+	legacy := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, testName), []byte(legacy), 0o644))
+
+	replayed, err := loadAll(t, dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []commands.Command{command("SET", "k", "v")}, replayed)
+	assert.NoFileExists(t, filepath.Join(root, testName))
+
+	// the old file is now the base, and writes go to an incr file after it
+	file, err := Open(dir, testName, FsyncAlways)
+	require.NoError(t, err)
+	require.NoError(t, file.Append(command("DEL", "k")))
+	require.NoError(t, file.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName(testName)))
+	require.NoError(t, err)
+	assert.Equal(t, "file appendonly.aof seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\n", string(data))
+	replayed, err = loadAll(t, dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []commands.Command{command("SET", "k", "v"), command("DEL", "k")}, replayed)
+}
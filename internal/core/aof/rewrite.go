@@ -0,0 +1,220 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+)
+
+// ErrRewriteInProgress is returned by StartRewrite while a rewrite is
+// already running.
+var ErrRewriteInProgress = errors.New("aof: a rewrite is already in progress")
+
+var errRewriteCanceled = errors.New("aof: rewrite canceled")
+
+// rewriteRetryDelay is how long automatic rewrites are held off after one
+// fails, so a full disk does not start a rewrite every cron tick.
+const rewriteRetryDelay = time.Minute
+
+// rewrite is a rewrite in progress. Its base file is written by a
+// goroutine of its own, which sends the outcome on done.
+type rewrite struct {
+	snapshot *keyspace.Snapshot
+	// incrSeq is the incr file started with the rewrite; it and any later
+	// incr files hold the writes the new base does not
+	incrSeq int64
+	temp    string
+	done    chan error
+	cancel  chan struct{}
+}
+
+// StartRewrite begins compacting the log into a new base file holding
+// snapshot, which must have been taken just before, with nothing written
+// since. Writes from now on go to a new incr file, so the new base plus
+// that file are the whole dataset; the old files stay in the manifest, and
+// are replayed on restart, until the rewrite completes. The base file is
+// written in the background; CheckRewrite completes the rewrite.
+//
+// StartRewrite takes ownership of snapshot and releases it when the
+// rewrite is over, or at once if it cannot start.
+func (a *AOF) StartRewrite(snapshot *keyspace.Snapshot) error {
+	if a.rewrite != nil {
+		snapshot.Release()
+		return ErrRewriteInProgress
+	}
+	if err := a.Flush(); err != nil {
+		snapshot.Release()
+		return err
+	}
+	if err := a.switchIncr(); err != nil {
+		snapshot.Release()
+		return err
+	}
+	rw := &rewrite{
+		snapshot: snapshot,
+		incrSeq:  a.manifest.incrSeq,
+		temp:     filepath.Join(a.dir, tempName(baseName(a.name, a.manifest.baseSeq+1))),
+		done:     make(chan error, 1),
+		cancel:   make(chan struct{}),
+	}
+	a.rewrite = rw
+	go func() {
+		rw.done <- writeBase(rw.temp, snapshot, rw.cancel)
+	}()
+	return nil
+}
+
+// RewriteInProgress reports whether a rewrite has started and not yet
+// been completed by CheckRewrite.
+func (a *AOF) RewriteInProgress() bool {
+	return a.rewrite != nil
+}
+
+// CheckRewrite completes the rewrite in progress if its base file has been
+// written: the base is renamed into place and the manifest switched over
+// to it, and the files it replaces are deleted. It must be called
+// regularly while a rewrite is in progress, and reports whether one
+// finished, and if so how.
+func (a *AOF) CheckRewrite() (bool, error) {
+	rw := a.rewrite
+	if rw == nil {
+		return false, nil
+	}
+	var err error
+	select {
+	case err = <-rw.done:
+	default:
+		return false, nil
+	}
+	if err == nil {
+		err = a.installBase(rw)
+	}
+	a.discardRewrite(rw)
+	if err != nil {
+		a.retryRewriteAfter = time.Now().Add(rewriteRetryDelay)
+	}
+	return true, err
+}
+
+// installBase puts the base file written by rw into the manifest, with
+// the incr files started since; everything else becomes history.
+func (a *AOF) installBase(rw *rewrite) error {
+	next := a.manifest.clone()
+	next.baseSeq++
+	base := manifestEntry{name: baseName(a.name, next.baseSeq), seq: next.baseSeq, typ: baseFile}
+	path := filepath.Join(a.dir, base.name)
+	if err := os.Rename(rw.temp, path); err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+
+	if next.base != nil {
+		next.history = append(next.history, manifestEntry{name: next.base.name, seq: next.base.seq, typ: historyFile})
+	}
+	var incrs []manifestEntry
+	for _, entry := range next.incrs {
+		if entry.seq >= rw.incrSeq {
+			incrs = append(incrs, entry)
+			continue
+		}
+		next.history = append(next.history, manifestEntry{name: entry.name, seq: entry.seq, typ: historyFile})
+	}
+	next.base, next.incrs = &base, incrs
+	if err := writeManifest(a.dir, a.name, next); err != nil {
+		os.Remove(path)
+		return err
+	}
+	a.manifest = next
+
+	if size, err := a.filesSize(); err == nil {
+		a.currentSize = size + int64(len(a.buf))
+	}
+	a.baseSize = a.currentSize
+	a.deleteHistory()
+	return nil
+}
+
+// discardRewrite ends rw, removing its temporary file if it is still
+// there.
+func (a *AOF) discardRewrite(rw *rewrite) {
+	if err := os.Remove(rw.temp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("could not delete a temporary append-only file", "file", rw.temp, "error", err)
+	}
+	rw.snapshot.Release()
+	a.rewrite = nil
+}
+
+// SetAutoRewrite sets how much the log must grow for a rewrite to be due:
+// by percentage percent of its size after the last rewrite, and to at
+// least minSize bytes, as auto-aof-rewrite-percentage and
+// auto-aof-rewrite-min-size do in Redis. A percentage of 0 turns automatic
+// rewrites off.
+func (a *AOF) SetAutoRewrite(percentage int, minSize int64) {
+	a.autoRewritePercentage = percentage
+	a.autoRewriteMinSize = minSize
+}
+
+// RewriteDue reports whether the log has grown enough since the last
+// rewrite, or since Open, for an automatic rewrite to start.
+func (a *AOF) RewriteDue() bool {
+	if a.rewrite != nil || a.autoRewritePercentage <= 0 || a.currentSize < a.autoRewriteMinSize {
+		return false
+	}
+	if time.Now().Before(a.retryRewriteAfter) {
+		return false
+	}
+	base := max(a.baseSize, 1)
+	growth := a.currentSize*100/base - 100
+	return growth >= int64(a.autoRewritePercentage)
+}
+
+// writeBase writes the commands that recreate snapshot to a new file at
+// path and syncs it.
+func writeBase(path string, snapshot *keyspace.Snapshot, cancel <-chan struct{}) error {
+	select {
+	case <-snapshot.Ready():
+	case <-cancel:
+		return errRewriteCanceled
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("aof: %w", err)
+	}
+	w := bufio.NewWriterSize(file, 64*1024)
+	err = func() error {
+		for key, object := range snapshot.All() {
+			select {
+			case <-cancel:
+				return errRewriteCanceled
+			default:
+			}
+			expireAt := int64(-1)
+			if when, exists := snapshot.ExpireAt(key); exists {
+				expireAt = when
+			}
+			for command := range commands.Rewrite(key, object, expireAt) {
+				if _, err := w.Write(parser.EncodeCommand(command)); err != nil {
+					return fmt.Errorf("aof: write: %w", err)
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("aof: write: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("aof: fsync: %w", err)
+		}
+		return nil
+	}()
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("aof: %w", closeErr)
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// run executes command against store and logs it to file, as the executor
+// does.
+func run(t *testing.T, file *AOF, store *keyspace.Keyspace, name string, args ...string) {
+	t.Helper()
+	cmd := command(name, args...)
+	reply := commands.CommandHandler(cmd.Name)(cmd, store)
+	require.NotEqual(t, enums.ErrorRespType, reply.Type, reply.Str)
+	require.NoError(t, file.Append(cmd))
+}
+
+// replayed loads the log in dir into a new keyspace.
+func replayed(t *testing.T, dir string) *keyspace.Keyspace {
+	t.Helper()
+	store := keyspace.New()
+	_, err := Load(dir, testName, false, func(cmd commands.Command) common.RespValue {
+		return commands.CommandHandler(cmd.Name)(cmd, store)
+	})
+	require.NoError(t, err)
+	return store
+}
+
+// finishRewrite waits for the rewrite in progress to complete.
+func finishRewrite(t *testing.T, file *AOF) error {
+	t.Helper()
+	var err error
+	require.Eventually(t, func() bool {
+		var finished bool
+		finished, err = file.CheckRewrite()
+		return finished
+	}, 5*time.Second, time.Millisecond)
+	return err
+}
+
+func manifestOf(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, manifestName(testName)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRewrite(t *testing.T) {
+	dir := t.TempDir()
+	file, err := Open(dir, testName, FsyncNo)
+	require.NoError(t, err)
+	defer file.Close()
+	store := keyspace.New()
+
+	for range 100 {
+		run(t, file, store, "INCR", "counter")
+	}
+	run(t, file, store, "RPUSH", "list", "a", "b")
+	run(t, file, store, "SET", "ttl", "v", "PXAT", "99999999999999")
+	run(t, file, store, "SET", "gone", "v")
+	run(t, file, store, "DEL", "gone")
+
+	require.NoError(t, file.StartRewrite(store.Snapshot()))
+	assert.True(t, file.RewriteInProgress())
+	assert.ErrorIs(t, file.StartRewrite(store.Snapshot()), ErrRewriteInProgress)
+
+	// writes made while the base is written go to the new incr file, and
+	// are in the log whether or not the rewrite has completed
+	run(t, file, store, "INCR", "counter")
+	run(t, file, store, "RPUSH", "list", "c")
+	require.NoError(t, file.Flush())
+	assert.Equal(t, "file appendonly.aof.1.incr.aof seq 1 type i\nfile appendonly.aof.2.incr.aof seq 2 type i\n", manifestOf(t, dir))
+	assert.Equal(t, "101", replayed(t, dir).LookupRead("counter").Str)
+
+	require.NoError(t, finishRewrite(t, file))
+	assert.False(t, file.RewriteInProgress())
+	assert.Equal(t, "file appendonly.aof.1.base.aof seq 1 type b\nfile appendonly.aof.2.incr.aof seq 2 type i\n", manifestOf(t, dir))
+
+	run(t, file, store, "INCR", "counter")
+	require.NoError(t, file.Flush())
+	restored := replayed(t, dir)
+	assert.Equal(t, "102", restored.LookupRead("counter").Str)
+	assert.Equal(t, []string{"a", "b", "c"}, restored.LookupRead("list").List.Range(0, 2))
+	assert.False(t, restored.Exists("gone"))
+	when, _ := restored.ExpireAt("ttl")
+	assert.Equal(t, int64(99999999999999), when)
+
+	// the base holds one command per key instead of a hundred INCRs, and
+	// the replaced incr file is deleted
+	var base []commands.Command
+	_, err = loadFile(filepath.Join(dir, baseName(testName, 1)), false, func(cmd commands.Command) common.RespValue {
+		base = append(base, cmd)
+		return common.RespValue{}
+	})
+	require.NoError(t, err)
+	assert.Len(t, base, 4)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, incrName(testName, 1)))
+		return os.IsNotExist(err)
+	}, 5*time.Second, time.Millisecond)
+
+	// a second rewrite replaces the base as well
+	require.NoError(t, file.StartRewrite(store.Snapshot()))
+	require.NoError(t, finishRewrite(t, file))
+	assert.Equal(t, "file appendonly.aof.2.base.aof seq 2 type b\nfile appendonly.aof.3.incr.aof seq 3 type i\n", manifestOf(t, dir))
+	assert.Equal(t, "102", replayed(t, dir).LookupRead("counter").Str)
+}
+
+func TestRewriteAbandonedOnClose(t *testing.T) {
+	dir := t.TempDir()
+	file, err := Open(dir, testName, FsyncNo)
+	require.NoError(t, err)
+	store := keyspace.New()
+	run(t, file, store, "SET", "k", "v")
+
+	require.NoError(t, file.StartRewrite(store.Snapshot()))
+	run(t, file, store, "SET", "k", "w")
+	require.NoError(t, file.Close())
+
+	// the log is intact without the base, and no temporary file is left
+	assert.Equal(t, "w", replayed(t, dir).LookupRead("k").Str)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), "temp-")
+	}
+}
+
+func TestRewriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	file, err := Open(dir, testName, FsyncNo)
+	require.NoError(t, err)
+	defer file.Close()
+	file.SetAutoRewrite(100, 0)
+	store := keyspace.New()
+	run(t, file, store, "SET", "k", "v")
+
+	// a directory in the way of the base file makes the rename fail
+	require.NoError(t, os.Mkdir(filepath.Join(dir, baseName(testName, 1)), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, baseName(testName, 1), "f"), nil, 0o644))
+	require.NoError(t, file.StartRewrite(store.Snapshot()))
+	assert.Error(t, finishRewrite(t, file))
+
+	// the log still has everything, and automatic rewrites back off
+	assert.Equal(t, "v", replayed(t, dir).LookupRead("k").Str)
+	file.currentSize, file.baseSize = 1000, 1
+	assert.False(t, file.RewriteDue())
+}
+
+func TestRewriteDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		percentage int
+		minSize    int64
+		baseSize   int64
+		size       int64
+		due        bool
+	}{
+		{"doubled", 100, 100, 1000, 2000, true},
+		{"not grown enough", 100, 100, 1000, 1999, false},
+		{"below minimum size", 100, 5000, 1000, 2000, false},
+		{"disabled", 0, 0, 1000, 10000, false},
+		{"empty base", 100, 100, 0, 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &AOF{baseSize: tt.baseSize, currentSize: tt.size}
+			file.SetAutoRewrite(tt.percentage, tt.minSize)
+			assert.Equal(t, tt.due, file.RewriteDue())
+		})
+	}
+}
+
+func TestOpenDeletesHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "", "")
+	old := filepath.Join(dir, "old.aof")
+	require.NoError(t, os.WriteFile(old, nil, 0o644))
+	m, err := readManifest(dir, testName)
+	require.NoError(t, err)
+	m.history = append(m.history, manifestEntry{name: "old.aof", seq: 1, typ: historyFile})
+	require.NoError(t, writeManifest(dir, testName, m))
+
+	file, err := Open(dir, testName, FsyncNo)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	assert.NoFileExists(t, old)
+	assert.Equal(t, "file appendonly.aof.1.incr.aof seq 1 type i\n", manifestOf(t, dir))
+}
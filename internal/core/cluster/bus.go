@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// linkQueueLen is how many packets may wait to be written to a link.
+	// A link that falls further behind is closed and opened again, as
+	// Redis does with links over their send buffer limit.
+	linkQueueLen = 1024
+	// linkWriteTimeout bounds each write, so a peer that stops reading
+	// cannot hold a link open forever.
+	linkWriteTimeout = 10 * time.Second
+)
+
+// link is a bus connection to another node. Its goroutines read and write
+// the connection, handing each packet read to the executor goroutine;
+// everything else about it belongs to the executor goroutine.
+type link struct {
+	// node is the node the link was opened to ping, or nil for a link
+	// another node opened to this one.
+	node      *Node
+	connected bool
+	created   time.Time
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLink(node *Node) *link {
+	return &link{
+		node:    node,
+		created: time.Now(),
+		send:    make(chan []byte, linkQueueLen),
+		done:    make(chan struct{}),
+	}
+}
+
+// close stops the link's goroutines, which close the connection.
+func (l *link) close() {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+}
+
+// queue hands packet to the link's writer without blocking, closing the
+// link instead if it has fallen too far behind.
+func (l *link) queue(packet []byte) {
+	select {
+	case l.send <- packet:
+	default:
+		l.close()
+	}
+}
+
+// serve writes the link's packets to conn and reads the other node's
+// until either fails or the link is closed.
+func (l *link) serve(c *Cluster, conn net.Conn) {
+	go func() {
+		defer conn.Close()
+		for {
+			select {
+			case packet := <-l.send:
+				conn.SetWriteDeadline(time.Now().Add(linkWriteTimeout))
+				if _, err := conn.Write(packet); err != nil {
+					l.close()
+					return
+				}
+			case <-l.done:
+				return
+			}
+		}
+	}()
+	localIP := hostOf(conn.LocalAddr())
+	remoteIP := hostOf(conn.RemoteAddr())
+	reader := bufio.NewReader(conn)
+	for {
+		m, err := readMessage(reader)
+		if err != nil {
+			l.close()
+			c.submit(func() { c.freeLink(l) })
+			return
+		}
+		if !c.submit(func() { c.receive(l, m, localIP, remoteIP) }) {
+			l.close()
+			return
+		}
+	}
+}
+
+func hostOf(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	host, _, _ := net.SplitHostPort(addr.String())
+	return host
+}
+
+// accept serves the links other nodes open to this one, until the
+// listener is closed.
+func (c *Cluster) accept() {
+	for {
+		conn, err := c.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("cluster bus accept failed", "error", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		l := newLink(nil)
+		l.connected = true
+		if !c.submit(func() { c.inbound[l] = struct{}{} }) {
+			conn.Close()
+			return
+		}
+		go l.serve(c, conn)
+	}
+}
+
+// connect opens a link to node. Its first packet is queued at once, so
+// the node counts as pinged from now on even if it cannot be reached.
+func (c *Cluster) connect(node *Node) {
+	l := newLink(node)
+	node.link = l
+	c.ping(node)
+	addr := node.busAddr()
+	timeout := c.cfg.NodeTimeout
+	go func() {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			l.close()
+			c.submit(func() { c.freeLink(l) })
+			return
+		}
+		if !c.submit(func() { l.connected = true }) {
+			conn.Close()
+			return
+		}
+		l.serve(c, conn)
+	}()
+}
+
+// freeLink forgets a link that has failed or is no longer wanted. A node
+// left without one is connected to again by the cron.
+func (c *Cluster) freeLink(l *link) {
+	l.close()
+	l.connected = false
+	if l.node != nil && l.node.link == l {
+		l.node.link = nil
+	}
+	delete(c.inbound, l)
+}
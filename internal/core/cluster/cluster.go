@@ -0,0 +1,797 @@
+package cluster
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultNodeTimeout is how long a node may go without answering a
+	// ping before it is suspected of having failed.
+	DefaultNodeTimeout = 15 * time.Second
+	// BusPortOffset is added to a node's client port to get its bus port
+	// when none is configured.
+	BusPortOffset = 10000
+	// forgetTime is how long a node removed with CLUSTER FORGET is kept
+	// from being added back by gossip, giving the FORGET time to reach
+	// every node.
+	forgetTime = time.Minute
+	// failReportValidityMult times the node timeout is how long a failure
+	// report counts towards marking a node as failed.
+	failReportValidityMult = 2
+	// failUndoTimeMult times the node timeout is how long a failed master
+	// that still serves slots stays failed after it answers again, unless
+	// something else takes its slots.
+	failUndoTimeMult = 2
+	// rejoinDelay is how long a node restarted into a cluster waits
+	// before serving, giving the others time to tell it what changed
+	// while it was away.
+	rejoinDelay = 2 * time.Second
+)
+
+// Config is how a node takes part in the cluster.
+type Config struct {
+	// File is where the node keeps its view of the cluster across
+	// restarts, Redis's nodes.conf. If empty nothing is kept.
+	File string
+	// Port is the port clients reach the node on.
+	Port int
+	// NodeTimeout is how long a node may go without answering before it
+	// is suspected of having failed; DefaultNodeTimeout if zero.
+	NodeTimeout time.Duration
+}
+
+// Cluster is this node's view of the cluster: the nodes it knows, the
+// slot each serves and which of them are reachable. Every method must be
+// called from the executor goroutine.
+type Cluster struct {
+	cfg      Config
+	listener net.Listener
+	// countKeys returns how many of this node's keys hash to a slot.
+	countKeys func(slot int) int
+	// submit runs a function on the executor goroutine, reporting false
+	// if the executor has stopped.
+	submit func(func()) bool
+
+	myself *Node
+	nodes  map[string]*Node
+	slots  [SlotCount]*Node
+	// migrating and importing hold the slots being moved away from this
+	// node, and to it, with the node on the other end.
+	migrating     map[int]*Node
+	importing     map[int]*Node
+	currentEpoch  uint64
+	lastVoteEpoch uint64
+	stateOK       bool
+	// rejoinUntil is when a node restarted into a cluster may start
+	// serving; see rejoinDelay.
+	rejoinUntil time.Time
+	// size is the number of masters serving at least one slot.
+	size int
+	// forgotten holds nodes removed with CLUSTER FORGET, and until when
+	// gossip about them is ignored.
+	forgotten map[string]time.Time
+	inbound   map[*link]struct{}
+	sent      [msgTypes]int64
+	received  [msgTypes]int64
+	cronLoops int
+
+	// what the current call has left to do before returning; see flush
+	saveConfig  bool
+	updateState bool
+	broadcast   bool
+	// slotLostHook is called with each slot that still held keys when
+	// another node took it over; the caller deletes the keys.
+	slotLostHook func(slot int)
+}
+
+// Open joins the cluster as it was when this node last saved its view,
+// or as a new node on its own, and serves the bus on listener. submit
+// must run its function on the executor goroutine, and countKeys return
+// how many keys hash to a slot.
+func Open(cfg Config, listener net.Listener, countKeys func(slot int) int, submit func(func()) bool) (*Cluster, error) {
+	if cfg.NodeTimeout <= 0 {
+		cfg.NodeTimeout = DefaultNodeTimeout
+	}
+	c := &Cluster{
+		cfg:       cfg,
+		listener:  listener,
+		countKeys: countKeys,
+		submit:    submit,
+		nodes:     make(map[string]*Node),
+		migrating: make(map[int]*Node),
+		importing: make(map[int]*Node),
+		forgotten: make(map[string]time.Time),
+		inbound:   make(map[*link]struct{}),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	if c.myself == nil {
+		c.myself = newNode("", flagMyself|flagMaster)
+		c.nodes[c.myself.ID] = c.myself
+		slog.Info("no cluster configuration found, starting as a new node", "id", c.myself.ID)
+	} else if len(c.nodes) > 1 {
+		c.rejoinUntil = time.Now().Add(rejoinDelay)
+	}
+	c.myself.Port = cfg.Port
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		c.myself.BusPort = addr.Port
+	}
+	if err := c.save(); err != nil {
+		return nil, fmt.Errorf("cluster: saving %s: %w", cfg.File, err)
+	}
+	c.refreshState()
+	go c.accept()
+	return c, nil
+}
+
+// Close stops the bus.
+func (c *Cluster) Close() {
+	c.listener.Close()
+	for _, node := range c.nodes {
+		if node.link != nil {
+			c.freeLink(node.link)
+		}
+	}
+	for l := range c.inbound {
+		c.freeLink(l)
+	}
+}
+
+// SetSlotLostHook registers a function called with every slot that held
+// keys of this node's when another node took it over.
+func (c *Cluster) SetSlotLostHook(hook func(slot int)) {
+	c.slotLostHook = hook
+}
+
+// Myself returns this node.
+func (c *Cluster) Myself() *Node {
+	return c.myself
+}
+
+// SlotOwner returns the node serving slot, or nil if none does.
+func (c *Cluster) SlotOwner(slot int) *Node {
+	return c.slots[slot]
+}
+
+// Migrating returns the node slot is being moved to, if it is.
+func (c *Cluster) Migrating(slot int) *Node {
+	return c.migrating[slot]
+}
+
+// Importing returns the node slot is being moved from, if it is.
+func (c *Cluster) Importing(slot int) *Node {
+	return c.importing[slot]
+}
+
+// StateOK reports whether the cluster serves queries: every slot is
+// served by a node not agreed to be down, and a majority of the masters
+// are reachable.
+func (c *Cluster) StateOK() bool {
+	return c.stateOK
+}
+
+// Nodes returns the known nodes, leaving out those still in handshake,
+// ordered by ID.
+func (c *Cluster) Nodes() []*Node {
+	var nodes []*Node
+	for _, node := range c.nodes {
+		if !node.is(flagHandshake) {
+			nodes = append(nodes, node)
+		}
+	}
+	slices.SortFunc(nodes, func(a, b *Node) int { return strings.Compare(a.ID, b.ID) })
+	return nodes
+}
+
+// Node returns the node with id, or nil.
+func (c *Cluster) Node(id string) *Node {
+	return c.nodes[id]
+}
+
+// SlotRanges returns every served slot, as ranges of consecutive slots
+// with the same owner, in order.
+func (c *Cluster) SlotRanges() []OwnedRange {
+	var ranges []OwnedRange
+	for slot, owner := range c.slots {
+		if owner == nil {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].Node == owner && ranges[n-1].End == slot-1 {
+			ranges[n-1].End = slot
+			continue
+		}
+		ranges = append(ranges, OwnedRange{SlotRange{slot, slot}, owner})
+	}
+	return ranges
+}
+
+// OwnedRange is a range of slots served by Node.
+type OwnedRange struct {
+	SlotRange
+	Node *Node
+}
+
+// assignSlot makes node, which may be nil, the owner of slot.
+func (c *Cluster) assignSlot(slot int, node *Node) {
+	if old := c.slots[slot]; old != nil {
+		old.delSlot(slot)
+	}
+	c.slots[slot] = node
+	if node != nil {
+		node.addSlot(slot)
+	}
+}
+
+// flush does what the current call has left for the end: saving the
+// config, recomputing the state, and telling every node about this one
+// when its slots or epoch changed.
+func (c *Cluster) flush() {
+	if c.updateState {
+		c.updateState = false
+		c.refreshState()
+	}
+	if c.saveConfig {
+		c.saveConfig = false
+		if err := c.save(); err != nil {
+			slog.Error("failed to save the cluster configuration", "error", err)
+		}
+	}
+	if c.broadcast {
+		c.broadcast = false
+		c.broadcastMessage(c.newMessage(msgPong))
+	}
+}
+
+func (c *Cluster) refreshState() {
+	ok := true
+	for _, owner := range c.slots {
+		if owner == nil || owner.is(flagFail) {
+			ok = false
+			break
+		}
+	}
+	size, reachable := 0, 0
+	for _, node := range c.nodes {
+		if node.is(flagMaster) && node.numSlots > 0 {
+			size++
+			if !node.is(flagPFail | flagFail) {
+				reachable++
+			}
+		}
+	}
+	c.size = size
+	if reachable < size/2+1 || time.Now().Before(c.rejoinUntil) {
+		ok = false
+	}
+	if ok != c.stateOK {
+		c.stateOK = ok
+		state := "fail"
+		if ok {
+			state = "ok"
+		}
+		slog.Info("cluster state changed", "state", state)
+	}
+}
+
+// receive acts on a packet read from l. localIP and remoteIP are the
+// addresses of the two ends of the link.
+func (c *Cluster) receive(l *link, m *message, localIP, remoteIP string) {
+	defer c.flush()
+	if _, live := c.inbound[l]; !live && (l.node == nil || l.node.link != l) {
+		// a link freed while its packets were on their way
+		return
+	}
+	c.received[m.typ]++
+	if m.sender == c.myself.ID {
+		return
+	}
+	sender := c.nodes[m.sender]
+	if sender != nil && sender.is(flagHandshake) {
+		sender = nil
+	}
+
+	ip := m.ip
+	if ip == "" {
+		ip = remoteIP
+	}
+	if m.typ == msgPing || m.typ == msgMeet {
+		if c.myself.IP == "" || m.typ == msgMeet && c.myself.IP != localIP {
+			c.myself.IP = localIP
+			c.saveConfig = true
+		}
+		if sender == nil && m.typ == msgMeet {
+			sender = newNode(m.sender, flagMaster)
+			sender.IP, sender.Port, sender.BusPort = ip, m.port, m.busPort
+			c.nodes[sender.ID] = sender
+			c.saveConfig = true
+			slog.Info("met a new cluster node", "id", sender.ID, "addr", sender.Addr())
+		}
+		c.send(l, c.newMessage(msgPong))
+	}
+
+	if l.node != nil && m.typ != msgFail {
+		if l.node.is(flagHandshake) {
+			if sender == nil {
+				c.renameNode(l.node, m.sender)
+				sender = l.node
+			} else {
+				// known under its ID already; the handshake was redundant
+				c.deleteNode(l.node)
+				return
+			}
+		} else if l.node.ID != m.sender {
+			// something else now listens at the node's address
+			c.freeLink(l)
+			return
+		}
+	}
+
+	if m.typ == msgPong && l.node != nil {
+		l.node.pongReceived = time.Now()
+		l.node.pingSent = time.Time{}
+		c.clearFailure(l.node)
+	}
+
+	if sender == nil {
+		return
+	}
+	if m.currentEpoch > c.currentEpoch {
+		c.currentEpoch = m.currentEpoch
+		c.saveConfig = true
+	}
+	if m.configEpoch > sender.ConfigEpoch {
+		sender.ConfigEpoch = m.configEpoch
+		c.saveConfig = true
+	}
+	if m.typ == msgFail {
+		c.receiveFail(sender, m.failing)
+		return
+	}
+	if sender.IP != ip || sender.Port != m.port || sender.BusPort != m.busPort {
+		sender.IP, sender.Port, sender.BusPort = ip, m.port, m.busPort
+		sender.flags &^= flagNoAddr
+		if sender.link != nil {
+			c.freeLink(sender.link)
+		}
+		c.saveConfig = true
+	}
+	if m.flags&flagMaster != 0 {
+		c.claimSlots(sender, &m.slots)
+		if c.myself.is(flagMaster) && m.configEpoch == c.myself.ConfigEpoch && m.configEpoch == sender.ConfigEpoch {
+			c.resolveEpochCollision(sender)
+		}
+	}
+	for _, g := range m.gossip {
+		c.receiveGossip(sender, g)
+	}
+}
+
+// claimSlots gives sender the slots it claims whose owners, as far as
+// this node knows, have smaller config epochs, or that have none.
+func (c *Cluster) claimSlots(sender *Node, claimed *slotBitmap) {
+	for slot := range SlotCount {
+		if !claimed.has(slot) {
+			continue
+		}
+		owner := c.slots[slot]
+		if owner == sender || c.importing[slot] != nil {
+			continue
+		}
+		if owner != nil && owner.ConfigEpoch >= sender.ConfigEpoch {
+			continue
+		}
+		if owner == c.myself {
+			slog.Info("lost a slot to another node", "slot", slot, "node", sender.ID)
+			delete(c.migrating, slot)
+			if c.countKeys(slot) > 0 && c.slotLostHook != nil {
+				c.slotLostHook(slot)
+			}
+		}
+		c.assignSlot(slot, sender)
+		c.saveConfig = true
+		c.updateState = true
+	}
+}
+
+// resolveEpochCollision gives this node a new config epoch when it shares
+// one with sender and has the smaller ID, so that no two masters' claims
+// ever tie.
+func (c *Cluster) resolveEpochCollision(sender *Node) {
+	if c.myself.ID >= sender.ID {
+		return
+	}
+	c.currentEpoch++
+	c.myself.ConfigEpoch = c.currentEpoch
+	c.saveConfig = true
+	slog.Info("config epoch collision resolved", "epoch", c.myself.ConfigEpoch)
+}
+
+func (c *Cluster) receiveGossip(sender *Node, g gossip) {
+	if g.id == c.myself.ID {
+		return
+	}
+	node := c.nodes[g.id]
+	if node != nil {
+		if node.is(flagHandshake) {
+			return
+		}
+		if sender.is(flagMaster) {
+			if g.flags&(flagPFail|flagFail) != 0 {
+				node.failReports[sender] = time.Now()
+				c.markFailingIfNeeded(node)
+			} else {
+				delete(node.failReports, sender)
+			}
+		}
+		if node.is(flagNoAddr) && g.flags&flagNoAddr == 0 && g.ip != "" {
+			node.IP, node.Port, node.BusPort = g.ip, g.port, g.busPort
+			node.flags &^= flagNoAddr
+		}
+		return
+	}
+	if _, forgotten := c.forgotten[g.id]; forgotten || g.flags&(flagNoAddr|flagHandshake) != 0 || g.ip == "" {
+		return
+	}
+	c.startHandshake(g.ip, g.port, g.busPort, false)
+}
+
+func (c *Cluster) receiveFail(sender *Node, id string) {
+	node := c.nodes[id]
+	if node == nil || node == c.myself || node.is(flagFail) {
+		return
+	}
+	slog.Info("cluster node marked as failing", "id", node.ID, "reported by", sender.ID)
+	node.flags = node.flags&^flagPFail | flagFail
+	node.failTime = time.Now()
+	c.saveConfig = true
+	c.updateState = true
+}
+
+// markFailingIfNeeded marks node as failed once this node and enough
+// other masters to make a majority have stopped hearing from it, and
+// tells every node.
+func (c *Cluster) markFailingIfNeeded(node *Node) {
+	if !node.is(flagPFail) || node.is(flagFail) {
+		return
+	}
+	reports := c.countFailureReports(node)
+	if c.myself.is(flagMaster) {
+		reports++
+	}
+	if reports < c.size/2+1 {
+		return
+	}
+	slog.Info("cluster node marked as failing", "id", node.ID, "reports", reports)
+	node.flags = node.flags&^flagPFail | flagFail
+	node.failTime = time.Now()
+	m := c.newMessage(msgFail)
+	m.failing = node.ID
+	c.broadcastMessage(m)
+	c.saveConfig = true
+	c.updateState = true
+}
+
+// countFailureReports drops the node's stale failure reports and counts
+// the rest.
+func (c *Cluster) countFailureReports(node *Node) int {
+	validity := c.cfg.NodeTimeout * failReportValidityMult
+	for reporter, when := range node.failReports {
+		if time.Since(when) > validity {
+			delete(node.failReports, reporter)
+		}
+	}
+	return len(node.failReports)
+}
+
+// clearFailure clears what this node suspects of node now that it has
+// answered, and lifts a failure the node no longer needs, as a master
+// without slots, or one whose failure nobody acted on for a while.
+func (c *Cluster) clearFailure(node *Node) {
+	if node.is(flagPFail) {
+		node.flags &^= flagPFail
+		c.updateState = true
+	}
+	if node.is(flagFail) && (node.numSlots == 0 || time.Since(node.failTime) > c.cfg.NodeTimeout*failUndoTimeMult) {
+		slog.Info("cluster node is reachable again", "id", node.ID)
+		node.flags &^= flagFail
+		c.saveConfig = true
+		c.updateState = true
+	}
+}
+
+// startHandshake adds a node known only by its address, which it takes
+// the ID of when it answers. meet makes the node add this one even if it
+// does not know it.
+func (c *Cluster) startHandshake(ip string, port, busPort int, meet bool) {
+	for _, node := range c.nodes {
+		if node.is(flagHandshake) && node.IP == ip && node.Port == port && node.BusPort == busPort {
+			return
+		}
+	}
+	flags := flagHandshake | flagMaster
+	if meet {
+		flags |= flagMeet
+	}
+	node := newNode("", flags)
+	node.IP, node.Port, node.BusPort = ip, port, busPort
+	c.nodes[node.ID] = node
+	c.connect(node)
+}
+
+func (c *Cluster) renameNode(node *Node, id string) {
+	delete(c.nodes, node.ID)
+	node.ID = id
+	node.flags &^= flagHandshake
+	c.nodes[id] = node
+	c.saveConfig = true
+	slog.Info("handshake with cluster node completed", "id", id, "addr", node.Addr())
+}
+
+// deleteNode forgets node, along with everything it served and reported.
+func (c *Cluster) deleteNode(node *Node) {
+	for slot, owner := range c.slots {
+		if owner == node {
+			c.assignSlot(slot, nil)
+		}
+	}
+	for slot, other := range c.migrating {
+		if other == node {
+			delete(c.migrating, slot)
+		}
+	}
+	for slot, other := range c.importing {
+		if other == node {
+			delete(c.importing, slot)
+		}
+	}
+	for _, other := range c.nodes {
+		delete(other.failReports, node)
+	}
+	if node.link != nil {
+		c.freeLink(node.link)
+	}
+	delete(c.nodes, node.ID)
+	c.saveConfig = true
+	c.updateState = true
+}
+
+// newMessage returns a packet of typ describing this node, with gossip
+// about a few random nodes and every node suspected of failing.
+func (c *Cluster) newMessage(typ msgType) *message {
+	m := &message{
+		typ:          typ,
+		sender:       c.myself.ID,
+		currentEpoch: c.currentEpoch,
+		configEpoch:  c.myself.ConfigEpoch,
+		flags:        c.myself.flags &^ flagMyself,
+		slots:        c.myself.slots,
+		ip:           c.myself.IP,
+		port:         c.myself.Port,
+		busPort:      c.myself.BusPort,
+		stateOK:      c.stateOK,
+	}
+	if typ == msgFail {
+		return m
+	}
+	var candidates, failing []*Node
+	for _, node := range c.nodes {
+		if node == c.myself || node.is(flagHandshake|flagNoAddr) {
+			continue
+		}
+		if node.is(flagPFail) {
+			failing = append(failing, node)
+		} else {
+			candidates = append(candidates, node)
+		}
+	}
+	wanted := min(max(3, len(c.nodes)/10), len(candidates))
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	for _, node := range append(candidates[:wanted], failing...) {
+		m.gossip = append(m.gossip, gossip{
+			id:      node.ID,
+			ip:      node.IP,
+			port:    node.Port,
+			busPort: node.BusPort,
+			flags:   node.flags,
+		})
+	}
+	return m
+}
+
+func (c *Cluster) send(l *link, m *message) {
+	c.sent[m.typ]++
+	l.queue(m.encode())
+}
+
+func (c *Cluster) broadcastMessage(m *message) {
+	packet := m.encode()
+	for _, node := range c.nodes {
+		if node.link == nil || node.is(flagHandshake) || node == c.myself {
+			continue
+		}
+		c.sent[m.typ]++
+		node.link.queue(packet)
+	}
+}
+
+// ping sends node a PING, or a MEET if it was met with CLUSTER MEET.
+func (c *Cluster) ping(node *Node) {
+	typ := msgPing
+	if node.is(flagMeet) {
+		typ = msgMeet
+		node.flags &^= flagMeet
+	}
+	if node.pingSent.IsZero() {
+		node.pingSent = time.Now()
+	}
+	c.send(node.link, c.newMessage(typ))
+}
+
+// Cron runs the bus's periodic work: connecting to nodes, pinging them
+// and suspecting those that stop answering. It must be called ten times
+// a second, as the executor's cron is.
+func (c *Cluster) Cron() {
+	defer c.flush()
+	c.cronLoops++
+	now := time.Now()
+	timeout := c.cfg.NodeTimeout
+	handshakeTimeout := max(timeout, time.Second)
+
+	for id, until := range c.forgotten {
+		if now.After(until) {
+			delete(c.forgotten, id)
+		}
+	}
+	nodes := make([]*Node, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		if node == c.myself {
+			continue
+		}
+		if node.is(flagHandshake) && now.Sub(node.created) > handshakeTimeout {
+			c.deleteNode(node)
+			continue
+		}
+		if node.link == nil && !node.is(flagNoAddr) {
+			c.connect(node)
+		}
+		nodes = append(nodes, node)
+	}
+
+	// once a second ping the node that has gone longest without a pong
+	// out of a few random ones
+	if c.cronLoops%10 == 0 && len(nodes) > 0 {
+		var oldest *Node
+		for range 5 {
+			node := nodes[rand.IntN(len(nodes))]
+			if node.link == nil || !node.pingSent.IsZero() || node.is(flagHandshake) {
+				continue
+			}
+			if oldest == nil || node.pongReceived.Before(oldest.pongReceived) {
+				oldest = node
+			}
+		}
+		if oldest != nil {
+			c.ping(oldest)
+		}
+	}
+
+	for _, node := range nodes {
+		if node.is(flagHandshake) {
+			continue
+		}
+		l := node.link
+		// a link that gets no answer for half the timeout may be stuck
+		// rather than the node down, so try a fresh one
+		if l != nil && l.connected && !node.pingSent.IsZero() &&
+			now.Sub(node.pingSent) > timeout/2 && now.Sub(l.created) > timeout/2 {
+			c.freeLink(l)
+			continue
+		}
+		if l != nil && node.pingSent.IsZero() && now.Sub(node.pongReceived) > timeout/2 {
+			c.ping(node)
+			continue
+		}
+		if !node.pingSent.IsZero() && now.Sub(node.pingSent) > timeout && !node.is(flagPFail|flagFail) {
+			slog.Info("cluster node is not answering", "id", node.ID)
+			node.flags |= flagPFail
+			c.updateState = true
+		}
+	}
+	if !c.stateOK {
+		c.updateState = true
+	}
+}
+
+// Info returns the text of CLUSTER INFO.
+func (c *Cluster) Info() string {
+	assigned, pfail, fail := 0, 0, 0
+	for _, owner := range c.slots {
+		if owner == nil {
+			continue
+		}
+		assigned++
+		switch {
+		case owner.is(flagFail):
+			fail++
+		case owner.is(flagPFail):
+			pfail++
+		}
+	}
+	state := "fail"
+	if c.stateOK {
+		state = "ok"
+	}
+	var b strings.Builder
+	line := func(name string, value any) {
+		fmt.Fprintf(&b, "%s:%v\r\n", name, value)
+	}
+	line("cluster_state", state)
+	line("cluster_slots_assigned", assigned)
+	line("cluster_slots_ok", assigned-pfail-fail)
+	line("cluster_slots_pfail", pfail)
+	line("cluster_slots_fail", fail)
+	line("cluster_known_nodes", len(c.nodes))
+	line("cluster_size", c.size)
+	line("cluster_current_epoch", c.currentEpoch)
+	line("cluster_my_epoch", c.myself.ConfigEpoch)
+	var sent, received int64
+	for typ := range msgTypes {
+		sent += c.sent[typ]
+		received += c.received[typ]
+	}
+	for typ := range msgTypes {
+		if c.sent[typ] > 0 {
+			line("cluster_stats_messages_"+typ.String()+"_sent", c.sent[typ])
+		}
+	}
+	line("cluster_stats_messages_sent", sent)
+	for typ := range msgTypes {
+		if c.received[typ] > 0 {
+			line("cluster_stats_messages_"+typ.String()+"_received", c.received[typ])
+		}
+	}
+	line("cluster_stats_messages_received", received)
+	return b.String()
+}
+
+// NodesText returns the text of CLUSTER NODES.
+func (c *Cluster) NodesText() string {
+	return c.nodesText(false)
+}
+
+// nodesText lists every node, one line each; the config file leaves out
+// nodes still in handshake, which may never answer.
+func (c *Cluster) nodesText(saving bool) string {
+	nodes := make([]*Node, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		if !saving || !node.is(flagHandshake) {
+			nodes = append(nodes, node)
+		}
+	}
+	slices.SortFunc(nodes, func(a, b *Node) int { return strings.Compare(a.ID, b.ID) })
+	var b strings.Builder
+	for _, node := range nodes {
+		b.WriteString(node.line(c))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func sortedSlots(slots map[int]*Node) []int {
+	sorted := make([]int, 0, len(slots))
+	for slot := range slots {
+		sorted = append(sorted, slot)
+	}
+	slices.SortFunc(sorted, cmp.Compare)
+	return sorted
+}
@@ -0,0 +1,201 @@
+package cluster
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNode is a Cluster whose executor goroutine is the test's: submitted
+// functions wait in tasks until pump runs them.
+type testNode struct {
+	*Cluster
+	tasks chan func()
+}
+
+var nextTestPort = 7000
+
+func openTestNode(t *testing.T, file string, timeout time.Duration) *testNode {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	n := &testNode{tasks: make(chan func(), 4096)}
+	done := make(chan struct{})
+	c, err := Open(Config{File: file, Port: nextTestPort, NodeTimeout: timeout}, listener,
+		func(int) int { return 0 },
+		func(fn func()) bool {
+			select {
+			case n.tasks <- fn:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	require.NoError(t, err)
+	nextTestPort++
+	n.Cluster = c
+	t.Cleanup(func() {
+		close(done)
+		c.Close()
+	})
+	return n
+}
+
+// pump runs the nodes' submitted functions, and their crons ten times a
+// second, until cond holds.
+func pump(t *testing.T, cond func() bool, nodes ...*testNode) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	nextCron := time.Now()
+	for !cond() {
+		require.True(t, time.Now().Before(deadline), "condition not met in time")
+		ran := false
+		for _, n := range nodes {
+			select {
+			case fn := <-n.tasks:
+				fn()
+				ran = true
+			default:
+			}
+		}
+		if time.Now().After(nextCron) {
+			for _, n := range nodes {
+				n.Cron()
+			}
+			nextCron = time.Now().Add(100 * time.Millisecond)
+		} else if !ran {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func (n *testNode) meet(t *testing.T, other *testNode) {
+	t.Helper()
+	me := other.Myself()
+	require.NoError(t, n.Meet("127.0.0.1", me.Port, me.BusPort))
+}
+
+// threeNodeCluster returns three nodes that know each other, the first
+// having met the other two, each serving a third of the slots.
+func threeNodeCluster(t *testing.T, timeout time.Duration) []*testNode {
+	t.Helper()
+	nodes := []*testNode{openTestNode(t, "", timeout), openTestNode(t, "", timeout), openTestNode(t, "", timeout)}
+	for i, n := range nodes {
+		var slots []int
+		for slot := i * SlotCount / 3; slot < (i+1)*SlotCount/3; slot++ {
+			slots = append(slots, slot)
+		}
+		require.NoError(t, n.AddSlots(slots))
+		require.NoError(t, n.SetConfigEpoch(uint64(i+1)))
+	}
+	nodes[0].meet(t, nodes[1])
+	nodes[0].meet(t, nodes[2])
+	pump(t, func() bool {
+		for _, n := range nodes {
+			if !n.StateOK() || len(n.Nodes()) != 3 {
+				return false
+			}
+		}
+		return true
+	}, nodes...)
+	return nodes
+}
+
+func TestClusterFormsThroughGossip(t *testing.T) {
+	nodes := threeNodeCluster(t, time.Second)
+	// the second and third nodes only met through the first one's gossip
+	second, third := nodes[1], nodes[2]
+	require.NotNil(t, second.Node(third.Myself().ID))
+	assert.Equal(t, third.Myself().ID, second.SlotOwner(SlotCount-1).ID)
+	assert.Equal(t, 3, second.size)
+	assert.Contains(t, second.Info(), "cluster_state:ok\r\n")
+	assert.Contains(t, second.Info(), "cluster_known_nodes:3\r\n")
+	assert.Len(t, strings.Split(strings.TrimSpace(second.NodesText()), "\n"), 3)
+}
+
+func TestClusterPropagatesSlotMoves(t *testing.T) {
+	nodes := threeNodeCluster(t, time.Second)
+	first, second := nodes[0], nodes[1]
+	require.NoError(t, second.SetSlot(0, "importing", first.Myself().ID))
+	require.NoError(t, first.SetSlot(0, "migrating", second.Myself().ID))
+	require.NoError(t, second.SetSlot(0, "node", second.Myself().ID))
+	assert.Nil(t, second.Importing(0))
+	require.NoError(t, first.SetSlot(0, "node", second.Myself().ID))
+	assert.Nil(t, first.Migrating(0))
+
+	// the importing node's bumped epoch wins the slot everywhere
+	pump(t, func() bool {
+		for _, n := range nodes {
+			if n.SlotOwner(0).ID != second.Myself().ID {
+				return false
+			}
+		}
+		return true
+	}, nodes...)
+	assert.Greater(t, second.Myself().ConfigEpoch, uint64(3))
+}
+
+func TestClusterAgreesANodeHasFailed(t *testing.T) {
+	nodes := threeNodeCluster(t, 300*time.Millisecond)
+	down := nodes[2]
+	downID := down.Myself().ID
+	down.Close()
+	live := nodes[:2]
+
+	// each live node suspects it, and together they are a majority
+	pump(t, func() bool {
+		for _, n := range live {
+			if !n.Node(downID).Failed() {
+				return false
+			}
+		}
+		return true
+	}, live...)
+	for _, n := range live {
+		assert.False(t, n.StateOK(), "the failed node's slots are not served")
+		assert.Contains(t, n.Info(), "cluster_slots_fail:5462\r\n")
+	}
+}
+
+func TestClusterCommandErrors(t *testing.T) {
+	n := openTestNode(t, "", 0)
+	id := n.Myself().ID
+	other := strings.Repeat("f", idLength)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"bad address", n.Meet("nowhere", 7000, 0), "ERR Invalid node address specified: nowhere:7000"},
+		{"slot twice", n.AddSlots([]int{1, 1}), "ERR Slot 1 specified multiple times"},
+		{"unassigned", n.DelSlots([]int{1}), "ERR Slot 1 is already unassigned"},
+		{"not the owner", n.SetSlot(1, "migrating", id), "ERR I'm not the owner of hash slot 1"},
+		{"unknown source", n.SetSlot(1, "importing", other), "ERR I don't know about node " + other},
+		{"importing from myself", n.SetSlot(1, "importing", id), "ERR Source node is myself"},
+		{"unknown node", n.SetSlot(1, "node", other), "ERR Unknown node " + other},
+		{"bad action", n.SetSlot(1, "elsewhere", id), "ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP"},
+		{"forget myself", n.Forget(id), "ERR I tried hard but I can't forget myself..."},
+		{"forget unknown", n.Forget(other), "ERR Unknown node " + other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.err, tt.want)
+		})
+	}
+
+	require.NoError(t, n.AddSlots([]int{1}))
+	assert.EqualError(t, n.AddSlots([]int{1}), "ERR Slot 1 is already busy")
+	assert.EqualError(t, n.SetSlot(1, "importing", id), "ERR I'm already the owner of hash slot 1")
+	assert.EqualError(t, n.SetSlot(1, "migrating", id), "ERR Target node is myself")
+	require.NoError(t, n.SetConfigEpoch(4))
+	assert.EqualError(t, n.SetConfigEpoch(5), "ERR Node config epoch is already non-zero")
+	bumped, epoch := n.BumpEpoch()
+	assert.False(t, bumped)
+	assert.Equal(t, uint64(4), epoch)
+}
@@ -0,0 +1,234 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// The operations behind CLUSTER's subcommands. Their errors are the
+// replies Redis gives, ready to send to the client.
+
+// Meet starts a handshake with the node at ip, which is added once it
+// answers. A busPort of zero means the usual port+BusPortOffset.
+func (c *Cluster) Meet(ip string, port, busPort int) error {
+	defer c.flush()
+	if busPort == 0 {
+		busPort = port + BusPortOffset
+	}
+	if net.ParseIP(ip) == nil || port <= 0 || port > 65535 || busPort <= 0 || busPort > 65535 {
+		return fmt.Errorf("ERR Invalid node address specified: %s:%d", ip, port)
+	}
+	c.startHandshake(ip, port, busPort, true)
+	return nil
+}
+
+// AddSlots makes this node serve slots, which must all be unassigned.
+func (c *Cluster) AddSlots(slots []int) error {
+	defer c.flush()
+	if err := checkSlots(slots); err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if c.slots[slot] != nil {
+			return fmt.Errorf("ERR Slot %d is already busy", slot)
+		}
+	}
+	for _, slot := range slots {
+		delete(c.importing, slot)
+		c.assignSlot(slot, c.myself)
+	}
+	c.slotsChanged()
+	return nil
+}
+
+// DelSlots unassigns slots, which must all be assigned. Other nodes keep
+// their view until some node claims the slots.
+func (c *Cluster) DelSlots(slots []int) error {
+	defer c.flush()
+	if err := checkSlots(slots); err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if c.slots[slot] == nil {
+			return fmt.Errorf("ERR Slot %d is already unassigned", slot)
+		}
+	}
+	for _, slot := range slots {
+		c.assignSlot(slot, nil)
+		delete(c.migrating, slot)
+	}
+	c.slotsChanged()
+	return nil
+}
+
+func checkSlots(slots []int) error {
+	seen := make(map[int]bool, len(slots))
+	for _, slot := range slots {
+		if seen[slot] {
+			return fmt.Errorf("ERR Slot %d specified multiple times", slot)
+		}
+		seen[slot] = true
+	}
+	return nil
+}
+
+// FlushSlots unassigns every slot this node serves.
+func (c *Cluster) FlushSlots() {
+	defer c.flush()
+	for slot, owner := range c.slots {
+		if owner == c.myself {
+			c.assignSlot(slot, nil)
+		}
+	}
+	clear(c.migrating)
+	c.slotsChanged()
+}
+
+func (c *Cluster) slotsChanged() {
+	c.saveConfig = true
+	c.updateState = true
+	c.broadcast = true
+}
+
+// SetSlot moves slot between nodes, in the steps Redis uses: IMPORTING
+// on the node receiving it, MIGRATING on the node giving it up, NODE on
+// both once its keys have moved, and STABLE to abandon a move. id is
+// ignored for STABLE.
+func (c *Cluster) SetSlot(slot int, action, id string) error {
+	defer c.flush()
+	action = strings.ToLower(action)
+	if action == "stable" {
+		delete(c.migrating, slot)
+		delete(c.importing, slot)
+		c.saveConfig = true
+		return nil
+	}
+	node := c.nodes[id]
+	switch action {
+	case "migrating":
+		if c.slots[slot] != c.myself {
+			return fmt.Errorf("ERR I'm not the owner of hash slot %d", slot)
+		}
+		if node == nil {
+			return fmt.Errorf("ERR I don't know about node %s", id)
+		}
+		if node == c.myself {
+			return errors.New("ERR Target node is myself")
+		}
+		c.migrating[slot] = node
+	case "importing":
+		if c.slots[slot] == c.myself {
+			return fmt.Errorf("ERR I'm already the owner of hash slot %d", slot)
+		}
+		if node == nil {
+			return fmt.Errorf("ERR I don't know about node %s", id)
+		}
+		if node == c.myself {
+			return errors.New("ERR Source node is myself")
+		}
+		c.importing[slot] = node
+	case "node":
+		if node == nil {
+			return fmt.Errorf("ERR Unknown node %s", id)
+		}
+		keys := c.countKeys(slot)
+		if c.slots[slot] == c.myself && node != c.myself && keys > 0 {
+			return fmt.Errorf("ERR Can't assign hashslot %d to a different node while I still hold keys for this hash slot.", slot)
+		}
+		if keys == 0 {
+			delete(c.migrating, slot)
+		}
+		if node == c.myself && c.importing[slot] != nil {
+			// the move ends here, so this node's claim must beat the old
+			// owner's without waiting for a vote
+			c.bumpEpoch()
+			delete(c.importing, slot)
+		}
+		c.assignSlot(slot, node)
+		c.slotsChanged()
+	default:
+		return errors.New("ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")
+	}
+	c.saveConfig = true
+	return nil
+}
+
+// Forget removes the node with id and ignores gossip about it for a
+// minute, long enough to forget it on every node.
+func (c *Cluster) Forget(id string) error {
+	defer c.flush()
+	node := c.nodes[id]
+	if node == nil {
+		return fmt.Errorf("ERR Unknown node %s", id)
+	}
+	if node == c.myself {
+		return errors.New("ERR I tried hard but I can't forget myself...")
+	}
+	c.forgotten[id] = time.Now().Add(forgetTime)
+	c.deleteNode(node)
+	return nil
+}
+
+// SetConfigEpoch gives a new node its first config epoch, which lets a
+// cluster be set up with distinct epochs without waiting for collisions
+// to be resolved.
+func (c *Cluster) SetConfigEpoch(epoch uint64) error {
+	defer c.flush()
+	if len(c.nodes) > 1 {
+		return errors.New("ERR The user can assign a config epoch only when the node does not know any other node.")
+	}
+	if c.myself.ConfigEpoch != 0 {
+		return errors.New("ERR Node config epoch is already non-zero")
+	}
+	c.myself.ConfigEpoch = epoch
+	c.currentEpoch = max(c.currentEpoch, epoch)
+	c.saveConfig = true
+	return nil
+}
+
+// BumpEpoch gives this node a config epoch greater than every other
+// node's, unless it already has one, and reports whether it did, with
+// the epoch.
+func (c *Cluster) BumpEpoch() (bool, uint64) {
+	defer c.flush()
+	bumped := c.bumpEpoch()
+	if bumped {
+		c.broadcast = true
+	}
+	return bumped, c.myself.ConfigEpoch
+}
+
+func (c *Cluster) bumpEpoch() bool {
+	highest := c.currentEpoch
+	for _, node := range c.nodes {
+		highest = max(highest, node.ConfigEpoch)
+	}
+	if c.myself.ConfigEpoch != 0 && c.myself.ConfigEpoch == highest {
+		return false
+	}
+	c.currentEpoch++
+	c.myself.ConfigEpoch = c.currentEpoch
+	c.saveConfig = true
+	return true
+}
+
+// SaveConfig writes the node config file now.
+func (c *Cluster) SaveConfig() error {
+	if err := c.save(); err != nil {
+		return fmt.Errorf("ERR error saving the cluster node config: %s", err)
+	}
+	return nil
+}
+
+// CountFailureReports returns how many masters currently report the node
+// with id as failing.
+func (c *Cluster) CountFailureReports(id string) (int, error) {
+	node := c.nodes[id]
+	if node == nil {
+		return 0, fmt.Errorf("ERR Unknown node %s", id)
+	}
+	return c.countFailureReports(node), nil
+}
@@ -0,0 +1,183 @@
+package cluster
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The node config file is Redis's nodes.conf: the CLUSTER NODES line of
+// every node, then a line of variables. It is rewritten in full each time
+// the view of the cluster changes in a way that matters across a restart,
+// such as who serves which slots.
+
+// save writes the node config file, if there is one.
+func (c *Cluster) save() error {
+	if c.cfg.File == "" {
+		return nil
+	}
+	content := c.nodesText(true) + fmt.Sprintf("vars currentEpoch %d lastVoteEpoch %d\n", c.currentEpoch, c.lastVoteEpoch)
+	dir := filepath.Dir(c.cfg.File)
+	temp := filepath.Join(dir, "temp-"+filepath.Base(c.cfg.File))
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, c.cfg.File)
+	}
+	if err != nil {
+		os.Remove(temp)
+	}
+	return err
+}
+
+// load reads the node config file, if there is one, leaving c.myself nil
+// when there is not.
+func (c *Cluster) load() error {
+	if c.cfg.File == "" {
+		return nil
+	}
+	file, err := os.Open(c.cfg.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	type pendingSlot struct {
+		slot      int
+		node      string
+		importing bool
+	}
+	var pending []pendingSlot
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		bad := func(reason string) error {
+			return fmt.Errorf("cluster: %s line %d: %s", c.cfg.File, lineNumber, reason)
+		}
+		if fields[0] == "vars" {
+			for i := 1; i+1 < len(fields); i += 2 {
+				value, err := strconv.ParseUint(fields[i+1], 10, 64)
+				if err != nil {
+					return bad("invalid " + fields[i])
+				}
+				switch fields[i] {
+				case "currentEpoch":
+					c.currentEpoch = value
+				case "lastVoteEpoch":
+					c.lastVoteEpoch = value
+				}
+			}
+			continue
+		}
+		if len(fields) < 8 {
+			return bad("too few fields")
+		}
+		if !validID(fields[0]) {
+			return bad("invalid node ID")
+		}
+		flags, err := parseFlags(fields[2])
+		if err != nil {
+			return bad(err.Error())
+		}
+		node := c.nodes[fields[0]]
+		if node == nil {
+			node = newNode(fields[0], 0)
+			c.nodes[node.ID] = node
+		}
+		// pfail is this node's own suspicion, which a restart forgets
+		node.flags = flags &^ flagPFail
+		if node.is(flagMyself) {
+			c.myself = node
+		}
+		if node.IP, node.Port, node.BusPort, err = parseNodeAddr(fields[1]); err != nil {
+			return bad(err.Error())
+		}
+		if node.ConfigEpoch, err = strconv.ParseUint(fields[6], 10, 64); err != nil {
+			return bad("invalid config epoch")
+		}
+		for _, field := range fields[8:] {
+			if strings.HasPrefix(field, "[") {
+				slotText, target, importing := strings.Cut(strings.Trim(field, "[]"), "-<-")
+				if !importing {
+					slotText, target, _ = strings.Cut(strings.Trim(field, "[]"), "->-")
+				}
+				slot, err := strconv.Atoi(slotText)
+				if err != nil || slot < 0 || slot >= SlotCount {
+					return bad("invalid slot " + field)
+				}
+				pending = append(pending, pendingSlot{slot, target, importing})
+				continue
+			}
+			startText, endText, isRange := strings.Cut(field, "-")
+			if !isRange {
+				endText = startText
+			}
+			start, err1 := strconv.Atoi(startText)
+			end, err2 := strconv.Atoi(endText)
+			if err1 != nil || err2 != nil || start < 0 || start > end || end >= SlotCount {
+				return bad("invalid slot range " + field)
+			}
+			for slot := start; slot <= end; slot++ {
+				c.assignSlot(slot, node)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if c.myself == nil && len(c.nodes) > 0 {
+		return fmt.Errorf("cluster: %s has no node flagged myself", c.cfg.File)
+	}
+	for _, p := range pending {
+		node := c.nodes[p.node]
+		if node == nil {
+			return fmt.Errorf("cluster: %s: slot %d refers to unknown node %s", c.cfg.File, p.slot, p.node)
+		}
+		if p.importing {
+			c.importing[p.slot] = node
+		} else {
+			c.migrating[p.slot] = node
+		}
+	}
+	return nil
+}
+
+// parseNodeAddr parses ip:port@busport, ignoring a trailing ,hostname.
+func parseNodeAddr(addr string) (ip string, port, busPort int, err error) {
+	addr, _, _ = strings.Cut(addr, ",")
+	addr, bus, _ := strings.Cut(addr, "@")
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if port, err = strconv.Atoi(portText); err != nil {
+		return "", 0, 0, fmt.Errorf("invalid port %q", portText)
+	}
+	if bus != "" {
+		if busPort, err = strconv.Atoi(bus); err != nil {
+			return "", 0, 0, fmt.Errorf("invalid bus port %q", bus)
+		}
+	}
+	return host, port, busPort, nil
+}
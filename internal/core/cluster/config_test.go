@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	me, other := strings.Repeat("a", idLength), strings.Repeat("b", idLength)
+	file := filepath.Join(t.TempDir(), "nodes.conf")
+	require.NoError(t, os.WriteFile(file, []byte(
+		me+" 127.0.0.1:7000@17000 myself,master - 0 0 1 connected 0-100 200 [5->-"+other+"] [300-<-"+other+"]\n"+
+			other+" 127.0.0.1:7001@17001 master,fail? - 0 0 2 disconnected 101-199 300\n"+
+			"vars currentEpoch 5 lastVoteEpoch 3\n"), 0o644))
+
+	c := openTestNode(t, file, 0).Cluster
+	assert.Equal(t, me, c.Myself().ID)
+	assert.Equal(t, []SlotRange{{0, 100}, {200, 200}}, c.Myself().Slots())
+	assert.Equal(t, other, c.SlotOwner(150).ID)
+	assert.Equal(t, other, c.Migrating(5).ID)
+	assert.Equal(t, other, c.Importing(300).ID)
+	assert.Equal(t, uint64(5), c.currentEpoch)
+	assert.Equal(t, uint64(3), c.lastVoteEpoch)
+	assert.False(t, c.Node(other).is(flagPFail), "a suspicion does not survive a restart")
+	// a node that knew others waits before serving
+	assert.False(t, c.StateOK())
+
+	// reopened, the node is the same but for the ports it listens on
+	saved, err := os.ReadFile(file)
+	require.NoError(t, err)
+	again := openTestNode(t, file, 0).Cluster
+	assert.Equal(t, withoutMyAddr(string(saved)), withoutMyAddr(again.nodesText(true)+"vars currentEpoch 5 lastVoteEpoch 3\n"))
+}
+
+func withoutMyAddr(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 2 && strings.Contains(fields[2], "myself") {
+			fields[1] = "-"
+			lines[i] = strings.Join(fields, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestConfigLoadRejectsMalformed(t *testing.T) {
+	me, other := strings.Repeat("a", idLength), strings.Repeat("b", idLength)
+	line := func(id, flags, slots string) string {
+		return id + " 127.0.0.1:7000@17000 " + flags + " - 0 0 1 connected " + slots + "\n"
+	}
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"too few fields", me + " 127.0.0.1:7000@17000 myself\n", "too few fields"},
+		{"bad id", line("xyz", "myself,master", ""), "invalid node ID"},
+		{"bad flag", line(me, "myself,boss", ""), "unknown node flag"},
+		{"bad address", line(me, "myself,master", "")[:idLength+1] + "nowhere myself - 0 0 1 connected\n", "line 1"},
+		{"bad range", line(me, "myself,master", "10-5"), "invalid slot range"},
+		{"slot out of range", line(me, "myself,master", "16384"), "invalid slot range"},
+		{"bad migrating slot", line(me, "myself,master", "[x->-"+other+"]"), "invalid slot"},
+		{"unknown migration target", line(me, "myself,master", "[5->-"+other+"]"), "unknown node"},
+		{"no myself", line(other, "master", ""), "no node flagged myself"},
+		{"bad vars", line(me, "myself,master", "") + "vars currentEpoch x\n", "invalid currentEpoch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "nodes.conf")
+			require.NoError(t, os.WriteFile(file, []byte(tt.data), 0o644))
+			c := &Cluster{
+				cfg:       Config{File: file},
+				nodes:     make(map[string]*Node),
+				migrating: make(map[int]*Node),
+				importing: make(map[int]*Node),
+			}
+			assert.ErrorContains(t, c.load(), tt.err)
+		})
+	}
+}
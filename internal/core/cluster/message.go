@@ -0,0 +1,201 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// msgType is the kind of a bus packet.
+type msgType uint16
+
+const (
+	msgPing msgType = iota
+	msgPong
+	// msgMeet is a ping that makes the receiver add the sender to its
+	// nodes, which it would otherwise ignore.
+	msgMeet
+	// msgFail tells every node that a node has been agreed to be down.
+	msgFail
+	msgTypes
+)
+
+var msgTypeNames = [msgTypes]string{"ping", "pong", "meet", "fail"}
+
+func (t msgType) String() string {
+	if t < msgTypes {
+		return msgTypeNames[t]
+	}
+	return fmt.Sprintf("type%d", uint16(t))
+}
+
+const (
+	// msgMagic starts every packet, so a client talking RESP to the bus
+	// port, or garbage, is told apart from a node.
+	msgMagic   = "MNcb"
+	msgVersion = 1
+	// maxMsgLen bounds the packets accepted, well above what the biggest
+	// cluster's gossip needs.
+	maxMsgLen = 1 << 20
+)
+
+// message is a bus packet. Every packet describes its sender, so each one
+// a node receives refreshes what it knows about the sender's slots and
+// epochs; pings and pongs also gossip about a few other nodes.
+type message struct {
+	typ          msgType
+	sender       string
+	currentEpoch uint64
+	configEpoch  uint64
+	flags        nodeFlags
+	slots        slotBitmap
+	// ip is the sender's address as it knows it, which may be empty; the
+	// receiver falls back on the connection's address.
+	ip      string
+	port    int
+	busPort int
+	stateOK bool
+	gossip  []gossip
+	// failing is the node a FAIL packet is about.
+	failing string
+}
+
+// gossip is what a packet's sender knows about another node.
+type gossip struct {
+	id      string
+	ip      string
+	port    int
+	busPort int
+	flags   nodeFlags
+}
+
+// encode returns the packet as it is written to a link: the magic, the
+// packet's length, then its fields in order.
+func (m *message) encode() []byte {
+	b := make([]byte, 0, 2200+len(m.gossip)*64)
+	b = append(b, msgMagic...)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = binary.BigEndian.AppendUint16(b, msgVersion)
+	b = binary.BigEndian.AppendUint16(b, uint16(m.typ))
+	b = appendID(b, m.sender)
+	b = binary.BigEndian.AppendUint64(b, m.currentEpoch)
+	b = binary.BigEndian.AppendUint64(b, m.configEpoch)
+	b = binary.BigEndian.AppendUint16(b, uint16(m.flags))
+	b = binary.BigEndian.AppendUint16(b, uint16(m.port))
+	b = binary.BigEndian.AppendUint16(b, uint16(m.busPort))
+	if m.stateOK {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = appendString(b, m.ip)
+	b = append(b, m.slots[:]...)
+	if m.typ == msgFail {
+		b = appendID(b, m.failing)
+	} else {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(m.gossip)))
+		for _, g := range m.gossip {
+			b = appendID(b, g.id)
+			b = binary.BigEndian.AppendUint16(b, uint16(g.flags))
+			b = binary.BigEndian.AppendUint16(b, uint16(g.port))
+			b = binary.BigEndian.AppendUint16(b, uint16(g.busPort))
+			b = appendString(b, g.ip)
+		}
+	}
+	binary.BigEndian.PutUint32(b[len(msgMagic):], uint32(len(b)))
+	return b
+}
+
+func appendID(b []byte, id string) []byte {
+	var field [idLength]byte
+	copy(field[:], id)
+	return append(b, field[:]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	s = s[:min(len(s), 255)]
+	b = append(b, byte(len(s)))
+	return append(b, s...)
+}
+
+var errBadMessage = errors.New("cluster: malformed bus packet")
+
+// readMessage reads one packet from r.
+func readMessage(r io.Reader) (*message, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != msgMagic {
+		return nil, errBadMessage
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < uint32(len(header)) || length > maxMsgLen {
+		return nil, errBadMessage
+	}
+	body := make([]byte, length-uint32(len(header)))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return decodeMessage(body)
+}
+
+// decoder reads a packet's fields, remembering the first overrun so that
+// the caller checks once at the end.
+type decoder struct {
+	b   []byte
+	bad bool
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.bad || len(d.b) < n {
+		d.bad = true
+		return make([]byte, n)
+	}
+	field := d.b[:n]
+	d.b = d.b[n:]
+	return field
+}
+
+func (d *decoder) uint16() uint16 { return binary.BigEndian.Uint16(d.take(2)) }
+func (d *decoder) uint64() uint64 { return binary.BigEndian.Uint64(d.take(8)) }
+func (d *decoder) id() string     { return string(d.take(idLength)) }
+func (d *decoder) string() string { return string(d.take(int(d.take(1)[0]))) }
+
+func decodeMessage(body []byte) (*message, error) {
+	d := &decoder{b: body}
+	if d.uint16() != msgVersion {
+		return nil, errBadMessage
+	}
+	m := &message{
+		typ:          msgType(d.uint16()),
+		sender:       d.id(),
+		currentEpoch: d.uint64(),
+		configEpoch:  d.uint64(),
+		flags:        nodeFlags(d.uint16()),
+		port:         int(d.uint16()),
+		busPort:      int(d.uint16()),
+		stateOK:      d.take(1)[0] == 1,
+		ip:           d.string(),
+	}
+	copy(m.slots[:], d.take(len(m.slots)))
+	if m.typ == msgFail {
+		m.failing = d.id()
+	} else {
+		count := int(d.uint16())
+		for i := 0; i < count && !d.bad; i++ {
+			m.gossip = append(m.gossip, gossip{
+				id:      d.id(),
+				flags:   nodeFlags(d.uint16()),
+				port:    int(d.uint16()),
+				busPort: int(d.uint16()),
+				ip:      d.string(),
+			})
+		}
+	}
+	if d.bad || m.typ >= msgTypes {
+		return nil, errBadMessage
+	}
+	return m, nil
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	sender, other := randomID(), randomID()
+	ping := &message{
+		typ:          msgPing,
+		sender:       sender,
+		currentEpoch: 7,
+		configEpoch:  3,
+		flags:        flagMaster,
+		ip:           "127.0.0.1",
+		port:         7000,
+		busPort:      17000,
+		stateOK:      true,
+		gossip: []gossip{
+			{id: other, ip: "10.0.0.2", port: 7001, busPort: 17001, flags: flagMaster | flagPFail},
+		},
+	}
+	ping.slots.set(0)
+	ping.slots.set(SlotCount - 1)
+	fail := &message{typ: msgFail, sender: sender, failing: other}
+
+	for _, m := range []*message{ping, fail} {
+		t.Run(m.typ.String(), func(t *testing.T) {
+			got, err := readMessage(bytes.NewReader(m.encode()))
+			require.NoError(t, err)
+			assert.Equal(t, m, got)
+		})
+	}
+}
+
+func TestReadMessageRejectsMalformed(t *testing.T) {
+	packet := (&message{typ: msgPong, sender: randomID()}).encode()
+	badType := bytes.Clone(packet)
+	badType[11] = byte(msgTypes)
+
+	tests := []struct {
+		name   string
+		packet []byte
+	}{
+		{"bad magic", append([]byte("*1\r\n"), packet[4:]...)},
+		{"bad version", append(bytes.Clone(packet[:8]), append([]byte{0, 9}, packet[10:]...)...)},
+		{"unknown type", badType},
+		{"truncated body", append([]byte(msgMagic), 0, 0, 0, 12, 0, 1, 0, 0)},
+		{"too long", append([]byte(msgMagic), 0xff, 0xff, 0xff, 0xff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMessage(bytes.NewReader(tt.packet))
+			assert.ErrorIs(t, err, errBadMessage)
+		})
+	}
+}
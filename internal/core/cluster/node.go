@@ -0,0 +1,207 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idLength is the length of a node ID: 40 hex characters, as in Redis.
+const idLength = 40
+
+// nodeFlags are what a node is, and what this node believes about it.
+type nodeFlags uint16
+
+const (
+	flagMyself nodeFlags = 1 << iota
+	flagMaster
+	flagReplica
+	// flagPFail is set when this node has not heard from the node within
+	// the node timeout, and flagFail once a majority of masters agree.
+	flagPFail
+	flagFail
+	// flagHandshake marks a node met only by address, whose ID is a
+	// placeholder until it answers.
+	flagHandshake
+	flagNoAddr
+	// flagMeet makes the next packet to the node a MEET, which makes the
+	// node add this one even though it does not know it.
+	flagMeet
+)
+
+var flagNames = []struct {
+	flag nodeFlags
+	name string
+}{
+	{flagMyself, "myself"},
+	{flagMaster, "master"},
+	{flagReplica, "slave"},
+	{flagPFail, "fail?"},
+	{flagFail, "fail"},
+	{flagHandshake, "handshake"},
+	{flagNoAddr, "noaddr"},
+}
+
+func (f nodeFlags) String() string {
+	var names []string
+	for _, n := range flagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "noflags"
+	}
+	return strings.Join(names, ",")
+}
+
+func parseFlags(s string) (nodeFlags, error) {
+	var flags nodeFlags
+	if s == "noflags" {
+		return 0, nil
+	}
+next:
+	for _, name := range strings.Split(s, ",") {
+		for _, n := range flagNames {
+			if n.name == name {
+				flags |= n.flag
+				continue next
+			}
+		}
+		if name != "nofailover" {
+			return 0, fmt.Errorf("unknown node flag %q", name)
+		}
+	}
+	return flags, nil
+}
+
+// Node is a member of the cluster as this node sees it.
+type Node struct {
+	ID      string
+	IP      string
+	Port    int
+	BusPort int
+	flags   nodeFlags
+	// ConfigEpoch versions the node's claim on its slots: where two
+	// masters claim a slot, the greater epoch wins.
+	ConfigEpoch uint64
+	slots       slotBitmap
+	numSlots    int
+	created     time.Time
+	// pingSent is when the ping still unanswered was sent, or zero, and
+	// pongReceived when the node last answered one.
+	pingSent     time.Time
+	pongReceived time.Time
+	failTime     time.Time
+	// failReports records when each master last reported the node as
+	// failing.
+	failReports map[*Node]time.Time
+	// link is this node's connection to the node, used to ping it. The
+	// node's pings to this one arrive on a link of their own.
+	link *link
+}
+
+func newNode(id string, flags nodeFlags) *Node {
+	if id == "" {
+		id = randomID()
+	}
+	return &Node{
+		ID:          id,
+		flags:       flags,
+		created:     time.Now(),
+		failReports: make(map[*Node]time.Time),
+	}
+}
+
+func randomID() string {
+	id := make([]byte, idLength/2)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func validID(id string) bool {
+	if len(id) != idLength {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Addr returns the node's client address, ip:port.
+func (n *Node) Addr() string {
+	return n.IP + ":" + strconv.Itoa(n.Port)
+}
+
+func (n *Node) busAddr() string {
+	return n.IP + ":" + strconv.Itoa(n.BusPort)
+}
+
+// Failed reports whether the cluster agrees the node is down.
+func (n *Node) Failed() bool {
+	return n.flags&flagFail != 0
+}
+
+// Slots returns the slots the node serves, as ranges.
+func (n *Node) Slots() []SlotRange {
+	return n.slots.ranges()
+}
+
+// NumSlots returns how many slots the node serves.
+func (n *Node) NumSlots() int {
+	return n.numSlots
+}
+
+func (n *Node) is(flag nodeFlags) bool {
+	return n.flags&flag != 0
+}
+
+func (n *Node) addSlot(slot int) {
+	if !n.slots.has(slot) {
+		n.slots.set(slot)
+		n.numSlots++
+	}
+}
+
+func (n *Node) delSlot(slot int) {
+	if n.slots.has(slot) {
+		n.slots.clear(slot)
+		n.numSlots--
+	}
+}
+
+// line is the node's line in CLUSTER NODES and nodes.conf.
+func (n *Node) line(c *Cluster) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s:%d@%d %s - ", n.ID, n.IP, n.Port, n.BusPort, n.flags)
+	pingSent, pong := int64(0), int64(0)
+	if !n.pingSent.IsZero() {
+		pingSent = n.pingSent.UnixMilli()
+	}
+	if !n.pongReceived.IsZero() {
+		pong = n.pongReceived.UnixMilli()
+	}
+	state := "disconnected"
+	if n.is(flagMyself) || n.link != nil && n.link.connected {
+		state = "connected"
+	}
+	fmt.Fprintf(&b, "%d %d %d %s", pingSent, pong, n.ConfigEpoch, state)
+	for _, r := range n.slots.ranges() {
+		if r.Start == r.End {
+			fmt.Fprintf(&b, " %d", r.Start)
+		} else {
+			fmt.Fprintf(&b, " %d-%d", r.Start, r.End)
+		}
+	}
+	if n.is(flagMyself) {
+		for _, slot := range sortedSlots(c.migrating) {
+			fmt.Fprintf(&b, " [%d->-%s]", slot, c.migrating[slot].ID)
+		}
+		for _, slot := range sortedSlots(c.importing) {
+			fmt.Fprintf(&b, " [%d-<-%s]", slot, c.importing[slot].ID)
+		}
+	}
+	return b.String()
+}
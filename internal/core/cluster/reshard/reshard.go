@@ -0,0 +1,167 @@
+// Package reshard moves hash slots between the nodes of a running
+// cluster, the way redis-cli --cluster reshard does, so that a cluster can
+// be rebalanced while clients keep using it.
+//
+// Each slot is moved in the steps CLUSTER SETSLOT describes: the target
+// is told it is IMPORTING the slot and the source that it is MIGRATING it,
+// the keys go over with MIGRATE a batch at a time, and then both nodes are
+// told the target owns it. While that happens the source answers for the
+// keys it still has and sends clients to the target, with -ASK, for the
+// ones it no longer has, so every key is served by exactly one node at
+// any time.
+package reshard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// call is Do with an error reply turned into an error.
+func call(c *parser.Client, args ...string) (common.RespValue, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return reply, fmt.Errorf("%s: %w", c.Addr, err)
+	}
+	if reply.Type == enums.ErrorRespType {
+		return reply, fmt.Errorf("%s: %s: %s", c.Addr, strings.Join(args[:min(len(args), 3)], " "), reply.Str)
+	}
+	return reply, nil
+}
+
+// Options says how to move slots.
+type Options struct {
+	// Batch is how many keys each MIGRATE moves
+	Batch int
+	// Timeout is how long the source waits for the target in a MIGRATE
+	Timeout time.Duration
+	// Replace overwrites keys the target already has, which a move
+	// interrupted part way through and then run again may find
+	Replace bool
+	// Moved is called after each slot has moved, if set
+	Moved func(slot, keys int)
+}
+
+// Move moves slots from source to target, one slot at a time. A slot
+// whose move fails is left MIGRATING on the source and IMPORTING on the
+// target, which still serves every key; running Move again finishes it.
+func Move(source, target *parser.Client, slots []int, opts Options) error {
+	if opts.Batch <= 0 {
+		opts.Batch = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	sourceID, err := call(source, "CLUSTER", "MYID")
+	if err != nil {
+		return err
+	}
+	targetID, err := call(target, "CLUSTER", "MYID")
+	if err != nil {
+		return err
+	}
+	host, port, err := net.SplitHostPort(target.Addr)
+	if err != nil {
+		return err
+	}
+	timeout := strconv.FormatInt(opts.Timeout.Milliseconds(), 10)
+
+	for _, slot := range slots {
+		id := strconv.Itoa(slot)
+		// the target first, so that the -ASK the source starts giving
+		// once it is migrating always finds the target ready
+		if _, err := call(target, "CLUSTER", "SETSLOT", id, "IMPORTING", sourceID.Str); err != nil {
+			return err
+		}
+		if _, err := call(source, "CLUSTER", "SETSLOT", id, "MIGRATING", targetID.Str); err != nil {
+			return err
+		}
+		moved := 0
+		for {
+			keys, err := call(source, "CLUSTER", "GETKEYSINSLOT", id, strconv.Itoa(opts.Batch))
+			if err != nil {
+				return err
+			}
+			if len(keys.Array) == 0 {
+				break
+			}
+			args := []string{"MIGRATE", host, port, "", "0", timeout}
+			if opts.Replace {
+				args = append(args, "REPLACE")
+			}
+			args = append(args, "KEYS")
+			for _, key := range keys.Array {
+				args = append(args, key.Str)
+			}
+			if _, err := call(source, args...); err != nil {
+				return fmt.Errorf("slot %d: %w", slot, err)
+			}
+			moved += len(keys.Array)
+		}
+		// the target first again: once it owns the slot it stops sending
+		// clients back, and the source can then hand the slot over
+		if _, err := call(target, "CLUSTER", "SETSLOT", id, "NODE", targetID.Str); err != nil {
+			return err
+		}
+		if _, err := call(source, "CLUSTER", "SETSLOT", id, "NODE", targetID.Str); err != nil {
+			return err
+		}
+		if opts.Moved != nil {
+			opts.Moved(slot, moved)
+		}
+	}
+	return nil
+}
+
+// OwnedSlots returns the slots the node c is connected to serves, lowest
+// first, as its CLUSTER NODES reports them.
+func OwnedSlots(c *parser.Client) ([]int, error) {
+	nodes, err := call(c, "CLUSTER", "NODES")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(nodes.Str, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || !strings.Contains(fields[2], "myself") {
+			continue
+		}
+		var slots []int
+		for _, field := range fields[8:] {
+			if strings.HasPrefix(field, "[") {
+				// a slot being moved, which is listed among the ranges too
+				continue
+			}
+			first, last, err := ParseRange(field)
+			if err != nil {
+				return nil, err
+			}
+			for slot := first; slot <= last; slot++ {
+				slots = append(slots, slot)
+			}
+		}
+		return slots, nil
+	}
+	return nil, errors.New(c.Addr + ": CLUSTER NODES has no line for the node itself")
+}
+
+// ParseRange parses a slot or a first-last range of slots.
+func ParseRange(text string) (int, int, error) {
+	firstText, lastText, isRange := strings.Cut(text, "-")
+	first, err1 := strconv.Atoi(firstText)
+	last, err2 := first, error(nil)
+	if isRange {
+		last, err2 = strconv.Atoi(lastText)
+	}
+	if err1 != nil || err2 != nil || first < 0 || first > last || last >= cluster.SlotCount {
+		return 0, 0, fmt.Errorf("invalid slot range %q", text)
+	}
+	return first, last, nil
+}
@@ -0,0 +1,94 @@
+// Package cluster shards the keyspace across nodes as Redis Cluster does.
+// Keys hash to one of SlotCount slots, each slot is served by one node,
+// and the nodes tell each other which slots they serve, and which of
+// them have stopped answering, over a gossip bus of their own.
+//
+// A Cluster is this node's view of the rest. Like the keyspace it is
+// owned by the executor goroutine: the bus's goroutines only read and
+// write packets, and hand what they read to the executor to act on.
+package cluster
+
+import (
+	"strings"
+)
+
+// SlotCount is the number of hash slots the keyspace is split into.
+const SlotCount = 16384
+
+// KeySlot returns the slot key hashes to: the CRC16 of the key modulo
+// SlotCount. If the key holds a hash tag, a non-empty part between the
+// first { and the } after it, only the tag is hashed, so keys sharing a
+// tag share a slot and can be used together.
+func KeySlot(key string) int {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			key = key[open+1 : open+1+end]
+		}
+	}
+	return int(crc16(key) & (SlotCount - 1))
+}
+
+var crc16Table = func() (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// crc16 is CRC-16/XMODEM, the variant Redis Cluster hashes keys with.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+	return crc
+}
+
+// slotBitmap records a set of slots, one bit each, the form slots are
+// sent over the bus in.
+type slotBitmap [SlotCount / 8]byte
+
+func (b *slotBitmap) has(slot int) bool {
+	return b[slot/8]&(1<<(slot%8)) != 0
+}
+
+func (b *slotBitmap) set(slot int) {
+	b[slot/8] |= 1 << (slot % 8)
+}
+
+func (b *slotBitmap) clear(slot int) {
+	b[slot/8] &^= 1 << (slot % 8)
+}
+
+// SlotRange is the slots from Start to End, both included.
+type SlotRange struct {
+	Start int
+	End   int
+}
+
+// ranges returns the slots set in b as ranges, in order.
+func (b *slotBitmap) ranges() []SlotRange {
+	var ranges []SlotRange
+	start := -1
+	for slot := 0; slot <= SlotCount; slot++ {
+		if slot < SlotCount && b.has(slot) {
+			if start < 0 {
+				start = slot
+			}
+			continue
+		}
+		if start >= 0 {
+			ranges = append(ranges, SlotRange{start, slot - 1})
+			start = -1
+		}
+	}
+	return ranges
+}
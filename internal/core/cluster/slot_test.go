@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected int
+	}{
+		{"plain key", "foo", 12182},
+		{"empty key", "", 0},
+		{"hash tag", "{user1000}.following", 3443},
+		{"same tag, same slot", "{user1000}.followers", 3443},
+		{"empty tag hashes the whole key", "foo{}{bar}", int(crc16("foo{}{bar}") & (SlotCount - 1))},
+		{"tag may hold an opening brace", "foo{{bar}}zap", KeySlot("{bar")},
+		{"only the first tag counts", "foo{bar}{zap}", KeySlot("bar")},
+		{"unclosed tag hashes the whole key", "foo{bar", int(crc16("foo{bar") & (SlotCount - 1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, KeySlot(tt.key))
+		})
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// the check value of CRC-16/XMODEM
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+}
+
+func TestSlotBitmapRanges(t *testing.T) {
+	var b slotBitmap
+	assert.Empty(t, b.ranges())
+	for _, slot := range []int{0, 1, 2, 100, SlotCount - 2, SlotCount - 1} {
+		b.set(slot)
+	}
+	b.clear(1)
+	assert.True(t, b.has(2))
+	assert.False(t, b.has(1))
+	assert.Equal(t, []SlotRange{{0, 0}, {2, 2}, {100, 100}, {SlotCount - 2, SlotCount - 1}}, b.ranges())
+}
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// Block tells the executor that a blocking command found nothing to serve
+// and the client should wait until one of Keys holds a value of Type.
+type Block struct {
+	Keys []string
+	Type keyspace.ObjectType
+	// Timeout is how long to wait, with zero meaning forever.
+	Timeout time.Duration
+}
+
+// BlockingHandler tries to serve a blocking command. When it cannot, it
+// returns a non-nil Block together with the reply to send if the wait
+// times out. The executor runs it again each time one of the keys becomes
+// ready.
+type BlockingHandler func(Command, *keyspace.Keyspace) (common.RespValue, *Block)
+
+var blockingHandlers = map[enums.CommandName]BlockingHandler{
+	enums.BLPopCommandName:    blockingBLPop,
+	enums.BRPopCommandName:    blockingBRPop,
+	enums.BLMoveCommandName:   blockingBLMove,
+	enums.BZPopMinCommandName: blockingBZPopMin,
+	enums.BZPopMaxCommandName: blockingBZPopMax,
+}
+
+// BlockingCommandHandler returns the BlockingHandler for commandName, or
+// nil if the command never blocks.
+func BlockingCommandHandler(commandName string) BlockingHandler {
+	return blockingHandlers[enums.StringToCommandName(commandName)]
+}
+
+// HandlerBLPop implements BLPOP key [key ...] timeout when it is not
+// allowed to block: with every key empty it replies as if the timeout had
+// already passed.
+func HandlerBLPop(command Command, store *keyspace.Keyspace) common.RespValue {
+	return withoutBlocking(blockingBLPop(command, store))
+}
+
+func HandlerBRPop(command Command, store *keyspace.Keyspace) common.RespValue {
+	return withoutBlocking(blockingBRPop(command, store))
+}
+
+func HandlerBLMove(command Command, store *keyspace.Keyspace) common.RespValue {
+	return withoutBlocking(blockingBLMove(command, store))
+}
+
+func HandlerBZPopMin(command Command, store *keyspace.Keyspace) common.RespValue {
+	return withoutBlocking(blockingBZPopMin(command, store))
+}
+
+func HandlerBZPopMax(command Command, store *keyspace.Keyspace) common.RespValue {
+	return withoutBlocking(blockingBZPopMax(command, store))
+}
+
+func withoutBlocking(reply common.RespValue, _ *Block) common.RespValue {
+	return reply
+}
+
+func blockingBLPop(command Command, store *keyspace.Keyspace) (common.RespValue, *Block) {
+	return blockingPopGeneric(command, store, true)
+}
+
+func blockingBRPop(command Command, store *keyspace.Keyspace) (common.RespValue, *Block) {
+	return blockingPopGeneric(command, store, false)
+}
+
+// blockingPopGeneric pops from the first non-empty list among the keys and
+// replies with the key and the element.
+func blockingPopGeneric(command Command, store *keyspace.Keyspace, front bool) (common.RespValue, *Block) {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command), nil
+	}
+	timeout, errResp := parseBlockTimeout(command.Args[len(command.Args)-1])
+	if errResp != nil {
+		return *errResp, nil
+	}
+
+	keys := command.Args[:len(command.Args)-1]
+	for _, key := range keys {
+		list, ok := writeList(store, key)
+		if !ok {
+			return errorReply(wrongTypeError), nil
+		}
+		if list == nil {
+			continue
+		}
+
+		var value string
+		if front {
+			value = list.PopFront()
+		} else {
+			value = list.PopBack()
+		}
+		store.SignalModified(key)
+		deleteIfEmptyList(store, key, list)
+		return arrayReply([]string{key, value}), nil
+	}
+
+	return nullArrayReply(), &Block{
+		Keys:    keys,
+		Type:    keyspace.ListType,
+		Timeout: timeout,
+	}
+}
+
+// blockingBLMove implements BLMOVE source destination LEFT|RIGHT
+// LEFT|RIGHT timeout, waiting on source only.
+func blockingBLMove(command Command, store *keyspace.Keyspace) (common.RespValue, *Block) {
+	if len(command.Args) != 5 {
+		return wrongArityReply(command), nil
+	}
+	timeout, errResp := parseBlockTimeout(command.Args[4])
+	if errResp != nil {
+		return *errResp, nil
+	}
+
+	reply := HandlerLMove(Command{Name: command.Name, Args: command.Args[:4]}, store)
+	if !reply.IsNull {
+		return reply, nil
+	}
+	return reply, &Block{
+		Keys:    command.Args[:1],
+		Type:    keyspace.ListType,
+		Timeout: timeout,
+	}
+}
+
+func blockingBZPopMin(command Command, store *keyspace.Keyspace) (common.RespValue, *Block) {
+	return blockingZPopGeneric(command, store, false)
+}
+
+func blockingBZPopMax(command Command, store *keyspace.Keyspace) (common.RespValue, *Block) {
+	return blockingZPopGeneric(command, store, true)
+}
+
+// blockingZPopGeneric pops one member from the first non-empty sorted set
+// among the keys and replies with the key, the member and its score.
+func blockingZPopGeneric(command Command, store *keyspace.Keyspace, highest bool) (common.RespValue, *Block) {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command), nil
+	}
+	timeout, errResp := parseBlockTimeout(command.Args[len(command.Args)-1])
+	if errResp != nil {
+		return *errResp, nil
+	}
+
+	keys := command.Args[:len(command.Args)-1]
+	for _, key := range keys {
+		zset, ok := writeZSet(store, key)
+		if !ok {
+			return errorReply(wrongTypeError), nil
+		}
+		if zset == nil {
+			continue
+		}
+
+		var popped []keyspace.ZMember
+		if highest {
+			popped = zset.PopMax(1)
+		} else {
+			popped = zset.PopMin(1)
+		}
+		store.SignalModified(key)
+		deleteIfEmptyZSet(store, key, zset)
+		return arrayReply([]string{key, popped[0].Member, formatScore(popped[0].Score)}), nil
+	}
+
+	return nullArrayReply(), &Block{
+		Keys:    keys,
+		Type:    keyspace.ZSetType,
+		Timeout: timeout,
+	}
+}
+
+// parseBlockTimeout parses a timeout in seconds, which may have a
+// fractional part. Zero means wait forever.
+func parseBlockTimeout(value string) (time.Duration, *common.RespValue) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		resp := errorReply("ERR timeout is not a float or out of range")
+		return 0, &resp
+	}
+	if seconds < 0 {
+		resp := errorReply("ERR timeout is negative")
+		return 0, &resp
+	}
+	if seconds > float64(math.MaxInt64/int64(time.Second)) {
+		resp := errorReply("ERR timeout is out of range")
+		return 0, &resp
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+func TestBlockingPopServesImmediately(t *testing.T) {
+	store := makeListStore("b", "x", "y")
+
+	reply, block := blockingBLPop(Command{Name: "BLPOP", Args: []string{"a", "b", "0"}}, store)
+	assert.Nil(t, block)
+	assert.Equal(t, arrayReply([]string{"b", "x"}), reply)
+
+	reply, block = blockingBRPop(Command{Name: "BRPOP", Args: []string{"b", "0"}}, store)
+	assert.Nil(t, block)
+	assert.Equal(t, arrayReply([]string{"b", "y"}), reply)
+	assert.False(t, store.Exists("b"))
+}
+
+func TestBlockingPopReturnsBlock(t *testing.T) {
+	store := makeStore()
+
+	reply, block := blockingBLPop(Command{Name: "BLPOP", Args: []string{"a", "b", "2.5"}}, store)
+	assert.Equal(t, nullArrayReply(), reply)
+	assert.Equal(t, &Block{Keys: []string{"a", "b"}, Type: keyspace.ListType, Timeout: 2500 * time.Millisecond}, block)
+
+	reply, block = blockingBLMove(Command{Name: "BLMOVE", Args: []string{"src", "dst", "LEFT", "LEFT", "0"}}, store)
+	assert.Equal(t, nullBulkReply(), reply)
+	assert.Equal(t, &Block{Keys: []string{"src"}, Type: keyspace.ListType}, block)
+
+	_, block = blockingBZPopMin(Command{Name: "BZPOPMIN", Args: []string{"z", "1"}}, store)
+	assert.Equal(t, keyspace.ZSetType, block.Type)
+
+	// called as a plain handler, for example inside MULTI, it never waits
+	assert.Equal(t, nullArrayReply(), HandlerBLPop(Command{Name: "BLPOP", Args: []string{"a", "0"}}, store))
+}
+
+func TestBlockingZPop(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b")
+
+	assert.Equal(t, arrayReply([]string{"z", "a", "1"}), HandlerBZPopMin(Command{Name: "BZPOPMIN", Args: []string{"missing", "z", "0"}}, store))
+	assert.Equal(t, arrayReply([]string{"z", "b", "2"}), HandlerBZPopMax(Command{Name: "BZPOPMAX", Args: []string{"z", "0"}}, store))
+	assert.False(t, store.Exists("z"))
+}
+
+func TestBlockingErrors(t *testing.T) {
+	store := makeStore()
+	store.Set("str", "value")
+
+	tests := []struct {
+		name     string
+		handler  BlockingHandler
+		args     []string
+		expected string
+	}{
+		{name: "BLPOP", handler: blockingBLPop, args: []string{"q", "abc"}, expected: "ERR timeout is not a float or out of range"},
+		{name: "BLPOP", handler: blockingBLPop, args: []string{"q", "-1"}, expected: "ERR timeout is negative"},
+		{name: "BRPOP", handler: blockingBRPop, args: []string{"q", "inf"}, expected: "ERR timeout is not a float or out of range"},
+		{name: "BLPOP", handler: blockingBLPop, args: []string{"q"}, expected: "ERR wrong number of arguments for 'BLPOP' command"},
+		{name: "BLPOP", handler: blockingBLPop, args: []string{"str", "0"}, expected: wrongTypeError},
+		{name: "BLMOVE", handler: blockingBLMove, args: []string{"str", "dst", "LEFT", "LEFT", "0"}, expected: wrongTypeError},
+		{name: "BLMOVE", handler: blockingBLMove, args: []string{"q", "dst", "UP", "LEFT", "0"}, expected: syntaxError},
+		{name: "BZPOPMIN", handler: blockingBZPopMin, args: []string{"str", "0"}, expected: wrongTypeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			reply, block := tt.handler(Command{Name: tt.name, Args: tt.args}, store)
+			assert.Nil(t, block)
+			assert.Equal(t, errorReply(tt.expected), reply)
+		})
+	}
+}
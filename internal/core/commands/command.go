@@ -3,8 +3,10 @@ package commands
 import (
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
@@ -13,19 +15,96 @@ type Command struct {
 	Args []string
 }
 
-var commandsHandler map[enums.CommandName]func(Command, map[string]string) common.RespValue
+var commandsHandler map[enums.CommandName]func(Command, *keyspace.Keyspace) common.RespValue
 
 func init() {
-	commandsHandler = make(map[enums.CommandName]func(Command, map[string]string) common.RespValue)
+	commandsHandler = make(map[enums.CommandName]func(Command, *keyspace.Keyspace) common.RespValue)
 	commandsHandler[enums.PingCommandName] = HandlerPing
 	commandsHandler[enums.EchoCommandName] = HandlerEcho
 	commandsHandler[enums.SetCommandName] = HandlerSet
 	commandsHandler[enums.GetCommandName] = HandlerGet
 	commandsHandler[enums.IncrCommandName] = HandlerIncr
 	commandsHandler[enums.DeleteCommandName] = HandlerDel
+	commandsHandler[enums.ExpireCommandName] = HandlerExpire
+	commandsHandler[enums.PExpireCommandName] = HandlerPExpire
+	commandsHandler[enums.ExpireAtCommandName] = HandlerExpireAt
+	commandsHandler[enums.PExpireAtCommandName] = HandlerPExpireAt
+	commandsHandler[enums.TTLCommandName] = HandlerTTL
+	commandsHandler[enums.PTTLCommandName] = HandlerPTTL
+	commandsHandler[enums.PersistCommandName] = HandlerPersist
+	commandsHandler[enums.ExpireTimeCommandName] = HandlerExpireTime
+	commandsHandler[enums.PExpireTimeCommandName] = HandlerPExpireTime
+	commandsHandler[enums.LPushCommandName] = HandlerLPush
+	commandsHandler[enums.RPushCommandName] = HandlerRPush
+	commandsHandler[enums.LPopCommandName] = HandlerLPop
+	commandsHandler[enums.RPopCommandName] = HandlerRPop
+	commandsHandler[enums.LRangeCommandName] = HandlerLRange
+	commandsHandler[enums.LLenCommandName] = HandlerLLen
+	commandsHandler[enums.LIndexCommandName] = HandlerLIndex
+	commandsHandler[enums.LSetCommandName] = HandlerLSet
+	commandsHandler[enums.LRemCommandName] = HandlerLRem
+	commandsHandler[enums.LTrimCommandName] = HandlerLTrim
+	commandsHandler[enums.LInsertCommandName] = HandlerLInsert
+	commandsHandler[enums.LMoveCommandName] = HandlerLMove
+	commandsHandler[enums.HSetCommandName] = HandlerHSet
+	commandsHandler[enums.HSetNXCommandName] = HandlerHSetNX
+	commandsHandler[enums.HGetCommandName] = HandlerHGet
+	commandsHandler[enums.HMGetCommandName] = HandlerHMGet
+	commandsHandler[enums.HDelCommandName] = HandlerHDel
+	commandsHandler[enums.HExistsCommandName] = HandlerHExists
+	commandsHandler[enums.HLenCommandName] = HandlerHLen
+	commandsHandler[enums.HKeysCommandName] = HandlerHKeys
+	commandsHandler[enums.HValsCommandName] = HandlerHVals
+	commandsHandler[enums.HGetAllCommandName] = HandlerHGetAll
+	commandsHandler[enums.HIncrByCommandName] = HandlerHIncrBy
+	commandsHandler[enums.HIncrByFloatCommandName] = HandlerHIncrByFloat
+	commandsHandler[enums.HStrLenCommandName] = HandlerHStrLen
+	commandsHandler[enums.HRandFieldCommandName] = HandlerHRandField
+	commandsHandler[enums.HScanCommandName] = HandlerHScan
+	commandsHandler[enums.SAddCommandName] = HandlerSAdd
+	commandsHandler[enums.SRemCommandName] = HandlerSRem
+	commandsHandler[enums.SCardCommandName] = HandlerSCard
+	commandsHandler[enums.SIsMemberCommandName] = HandlerSIsMember
+	commandsHandler[enums.SMIsMemberCommandName] = HandlerSMIsMember
+	commandsHandler[enums.SMembersCommandName] = HandlerSMembers
+	commandsHandler[enums.SPopCommandName] = HandlerSPop
+	commandsHandler[enums.SRandMemberCommandName] = HandlerSRandMember
+	commandsHandler[enums.SMoveCommandName] = HandlerSMove
+	commandsHandler[enums.SInterCommandName] = HandlerSInter
+	commandsHandler[enums.SUnionCommandName] = HandlerSUnion
+	commandsHandler[enums.SDiffCommandName] = HandlerSDiff
+	commandsHandler[enums.SInterStoreCommandName] = HandlerSInterStore
+	commandsHandler[enums.SUnionStoreCommandName] = HandlerSUnionStore
+	commandsHandler[enums.SDiffStoreCommandName] = HandlerSDiffStore
+	commandsHandler[enums.SInterCardCommandName] = HandlerSInterCard
+	commandsHandler[enums.ZAddCommandName] = HandlerZAdd
+	commandsHandler[enums.ZIncrByCommandName] = HandlerZIncrBy
+	commandsHandler[enums.ZRemCommandName] = HandlerZRem
+	commandsHandler[enums.ZScoreCommandName] = HandlerZScore
+	commandsHandler[enums.ZMScoreCommandName] = HandlerZMScore
+	commandsHandler[enums.ZCardCommandName] = HandlerZCard
+	commandsHandler[enums.ZRankCommandName] = HandlerZRank
+	commandsHandler[enums.ZRevRankCommandName] = HandlerZRevRank
+	commandsHandler[enums.ZRangeCommandName] = HandlerZRange
+	commandsHandler[enums.ZRangeStoreCommandName] = HandlerZRangeStore
+	commandsHandler[enums.ZCountCommandName] = HandlerZCount
+	commandsHandler[enums.ZLexCountCommandName] = HandlerZLexCount
+	commandsHandler[enums.ZPopMinCommandName] = HandlerZPopMin
+	commandsHandler[enums.ZPopMaxCommandName] = HandlerZPopMax
+	commandsHandler[enums.ZRemRangeByRankCommandName] = HandlerZRemRangeByRank
+	commandsHandler[enums.ZRemRangeByScoreCommandName] = HandlerZRemRangeByScore
+	commandsHandler[enums.ZRemRangeByLexCommandName] = HandlerZRemRangeByLex
+	commandsHandler[enums.ZUnionStoreCommandName] = HandlerZUnionStore
+	commandsHandler[enums.ZInterStoreCommandName] = HandlerZInterStore
+	commandsHandler[enums.BLPopCommandName] = HandlerBLPop
+	commandsHandler[enums.BRPopCommandName] = HandlerBRPop
+	commandsHandler[enums.BLMoveCommandName] = HandlerBLMove
+	commandsHandler[enums.BZPopMinCommandName] = HandlerBZPopMin
+	commandsHandler[enums.BZPopMaxCommandName] = HandlerBZPopMax
+	commandsHandler[enums.QuitCommandName] = HandlerQuit
 }
 
-func CommandHandler(commandName string) func(Command, map[string]string) common.RespValue {
+func CommandHandler(commandName string) func(Command, *keyspace.Keyspace) common.RespValue {
 	handler, exists := commandsHandler[enums.StringToCommandName(commandName)]
 	if !exists {
 		return nil
@@ -33,7 +112,7 @@ func CommandHandler(commandName string) func(Command, map[string]string) common.
 	return handler
 }
 
-func HandlerPing(command Command, _ map[string]string) common.RespValue {
+func HandlerPing(command Command, _ *keyspace.Keyspace) common.RespValue {
 	if len(command.Args) != 0 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
@@ -46,7 +125,7 @@ func HandlerPing(command Command, _ map[string]string) common.RespValue {
 	}
 }
 
-func HandlerEcho(command Command, _ map[string]string) common.RespValue {
+func HandlerEcho(command Command, _ *keyspace.Keyspace) common.RespValue {
 	if len(command.Args) != 1 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
@@ -61,50 +140,115 @@ func HandlerEcho(command Command, _ map[string]string) common.RespValue {
 
 }
 
-func HandlerSet(command Command, store map[string]string) common.RespValue {
-	if len(command.Args) != 2 {
+// HandlerQuit acknowledges QUIT. The connection closes itself once the
+// reply has been written.
+func HandlerQuit(_ Command, _ *keyspace.Keyspace) common.RespValue {
+	return okReply()
+}
+
+// HandlerSet implements SET key value [EX seconds | PX milliseconds |
+// EXAT unix-seconds | PXAT unix-milliseconds | KEEPTTL].
+func HandlerSet(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
 			Str:  common.WrongNumberOfArgumentsError(command.Name),
 		}
 	}
-	store[command.Args[0]] = command.Args[1]
-	return common.RespValue{
-		Type: enums.SimpleStringRespType,
-		Str:  "OK",
+
+	var (
+		expireArg string
+		unit      int64
+		base      int64
+		hasExpire bool
+		keepTTL   bool
+	)
+
+	for i := 2; i < len(command.Args); i++ {
+		switch option := strings.ToUpper(command.Args[i]); option {
+		case "KEEPTTL":
+			if hasExpire || keepTTL {
+				return errorReply(syntaxError)
+			}
+			keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if hasExpire || keepTTL || i+1 >= len(command.Args) {
+				return errorReply(syntaxError)
+			}
+			hasExpire = true
+			i++
+			expireArg = command.Args[i]
+			unit, base = expireUnitAndBase(option, store.Now())
+		default:
+			return errorReply(syntaxError)
+		}
 	}
+
+	key, value := command.Args[0], command.Args[1]
+
+	if hasExpire {
+		when, err := strconv.ParseInt(expireArg, 10, 64)
+		if err != nil {
+			return errorReply(notIntegerError)
+		}
+		expireAt, ok := absoluteExpireTime(when, unit, base)
+		if when <= 0 || !ok {
+			return errorReply(invalidExpireMessage(command))
+		}
+		store.Set(key, value)
+		if expireAt <= store.Now() {
+			store.Delete(key)
+		} else {
+			store.SetExpireAt(key, expireAt)
+		}
+		return okReply()
+	}
+
+	if keepTTL {
+		store.SetKeepTTL(key, value)
+	} else {
+		store.Set(key, value)
+	}
+	return okReply()
 }
 
-func HandlerGet(command Command, store map[string]string) common.RespValue {
+func HandlerGet(command Command, store *keyspace.Keyspace) common.RespValue {
 	if len(command.Args) != 1 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
 			Str:  common.WrongNumberOfArgumentsError(command.Name),
 		}
 	}
-	value, exists := store[command.Args[0]]
-	if !exists {
+	object := store.LookupRead(command.Args[0])
+	if object == nil {
 		return common.RespValue{
 			Type:   enums.BulkStringRespType,
 			IsNull: true,
 		}
 	}
+	if object.Type != keyspace.StringType {
+		return errorReply(wrongTypeError)
+	}
 	return common.RespValue{
 		Type: enums.BulkStringRespType,
-		Str:  value,
+		Str:  object.Str,
 	}
 }
 
-func HandlerIncr(command Command, store map[string]string) common.RespValue {
+func HandlerIncr(command Command, store *keyspace.Keyspace) common.RespValue {
 	if len(command.Args) != 1 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
 			Str:  common.WrongNumberOfArgumentsError(command.Name),
 		}
 	}
-	value, exists := store[command.Args[0]]
-	if !exists {
-		value = "0"
+	value := "0"
+	object := store.LookupWrite(command.Args[0])
+	if object != nil {
+		if object.Type != keyspace.StringType {
+			return errorReply(wrongTypeError)
+		}
+		value = object.Str
 	}
 
 	integer, err := strconv.Atoi(value)
@@ -123,28 +267,32 @@ func HandlerIncr(command Command, store map[string]string) common.RespValue {
 	}
 
 	integer = integer + 1
-	store[command.Args[0]] = strconv.Itoa(integer)
+	if object != nil {
+		// updating in place keeps the key's TTL, as Redis does
+		object.Str = strconv.Itoa(integer)
+		store.SignalModified(command.Args[0])
+	} else {
+		store.Set(command.Args[0], strconv.Itoa(integer))
+	}
 	return common.RespValue{
 		Type: enums.IntRespType,
 		Int:  int64(integer),
 	}
 }
 
-func HandlerDel(command Command, store map[string]string) common.RespValue {
+func HandlerDel(command Command, store *keyspace.Keyspace) common.RespValue {
 	if len(command.Args) != 1 {
 		return common.RespValue{
 			Type: enums.ErrorRespType,
 			Str:  common.WrongNumberOfArgumentsError(command.Name),
 		}
 	}
-	_, exists := store[command.Args[0]]
-	if !exists {
+	if !store.Delete(command.Args[0]) {
 		return common.RespValue{
 			Type: enums.IntRespType,
 			Int:  0,
 		}
 	}
-	delete(store, command.Args[0])
 	return common.RespValue{
 		Type: enums.IntRespType,
 		Int:  1,
@@ -5,14 +5,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
 // Helpers
-func makeStore(pairs ...string) map[string]string {
-	store := make(map[string]string)
+func makeStore(pairs ...string) *keyspace.Keyspace {
+	store := keyspace.New()
 	for i := 0; i+1 < len(pairs); i += 2 {
-		store[pairs[i]] = pairs[i+1]
+		store.Set(pairs[i], pairs[i+1])
 	}
 	return store
 }
@@ -94,25 +95,33 @@ func TestSet(t *testing.T) {
 		name        string
 		args        []string
 		expectError bool
+		errorMsg    string
 	}{
 		{
 			name: "valid",
 			args: []string{"foo", "bar"},
 		},
+		{
+			name: "with expiry",
+			args: []string{"foo", "bar", "EX", "10"},
+		},
 		{
 			name:        "no args",
 			args:        []string{},
 			expectError: true,
+			errorMsg:    common.WrongNumberOfArgumentsError("SET"),
 		},
 		{
 			name:        "only key",
 			args:        []string{"foo"},
 			expectError: true,
+			errorMsg:    common.WrongNumberOfArgumentsError("SET"),
 		},
 		{
 			name:        "too many args",
 			args:        []string{"foo", "bar", "baz"},
 			expectError: true,
+			errorMsg:    "ERR syntax error",
 		},
 	}
 
@@ -123,11 +132,11 @@ func TestSet(t *testing.T) {
 			resp := HandlerSet(cmd, store)
 			if tt.expectError {
 				assert.Equal(t, enums.ErrorRespType, resp.Type)
-				assert.Equal(t, common.WrongNumberOfArgumentsError("SET"), resp.Str)
+				assert.Equal(t, tt.errorMsg, resp.Str)
 			} else {
 				assert.Equal(t, enums.SimpleStringRespType, resp.Type)
 				assert.Equal(t, "OK", resp.Str)
-				assert.Equal(t, tt.args[1], store[tt.args[0]])
+				assert.Equal(t, tt.args[1], store.LookupRead(tt.args[0]).Str)
 			}
 		})
 	}
@@ -136,7 +145,7 @@ func TestSet(t *testing.T) {
 func TestGet(t *testing.T) {
 	tests := []struct {
 		name        string
-		store       map[string]string
+		store       *keyspace.Keyspace
 		args        []string
 		expectNull  bool
 		expectError bool
@@ -188,7 +197,7 @@ func TestGet(t *testing.T) {
 func TestIncr(t *testing.T) {
 	tests := []struct {
 		name        string
-		store       map[string]string
+		store       *keyspace.Keyspace
 		args        []string
 		expectError bool
 		errorMsg    string
@@ -254,7 +263,7 @@ func TestIncr(t *testing.T) {
 func TestDel(t *testing.T) {
 	tests := []struct {
 		name        string
-		store       map[string]string
+		store       *keyspace.Keyspace
 		args        []string
 		expectError bool
 		expected    int64
@@ -301,7 +310,7 @@ func TestDel(t *testing.T) {
 				assert.Equal(t, enums.IntRespType, resp.Type)
 				assert.Equal(t, tt.expected, resp.Int)
 				if tt.expected == 1 {
-					if _, exists := tt.store[tt.args[0]]; exists {
+					if tt.store.Exists(tt.args[0]) {
 						t.Fatal("key should have been deleted from store")
 					}
 				}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+const (
+	secondsUnit      int64 = 1000
+	millisecondsUnit int64 = 1
+)
+
+// HandlerExpire implements EXPIRE key seconds [NX | XX | GT | LT].
+func HandlerExpire(command Command, store *keyspace.Keyspace) common.RespValue {
+	return expireGeneric(command, store, store.Now(), secondsUnit)
+}
+
+// HandlerPExpire implements PEXPIRE key milliseconds [NX | XX | GT | LT].
+func HandlerPExpire(command Command, store *keyspace.Keyspace) common.RespValue {
+	return expireGeneric(command, store, store.Now(), millisecondsUnit)
+}
+
+// HandlerExpireAt implements EXPIREAT key unix-seconds [NX | XX | GT | LT].
+func HandlerExpireAt(command Command, store *keyspace.Keyspace) common.RespValue {
+	return expireGeneric(command, store, 0, secondsUnit)
+}
+
+// HandlerPExpireAt implements PEXPIREAT key unix-milliseconds [NX | XX | GT | LT].
+func HandlerPExpireAt(command Command, store *keyspace.Keyspace) common.RespValue {
+	return expireGeneric(command, store, 0, millisecondsUnit)
+}
+
+func HandlerTTL(command Command, store *keyspace.Keyspace) common.RespValue {
+	return ttlGeneric(command, store, secondsUnit, false)
+}
+
+func HandlerPTTL(command Command, store *keyspace.Keyspace) common.RespValue {
+	return ttlGeneric(command, store, millisecondsUnit, false)
+}
+
+func HandlerExpireTime(command Command, store *keyspace.Keyspace) common.RespValue {
+	return ttlGeneric(command, store, secondsUnit, true)
+}
+
+func HandlerPExpireTime(command Command, store *keyspace.Keyspace) common.RespValue {
+	return ttlGeneric(command, store, millisecondsUnit, true)
+}
+
+func HandlerPersist(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+	if store.Persist(command.Args[0]) {
+		return integerReply(1)
+	}
+	return integerReply(0)
+}
+
+// expireGeneric sets a TTL of args[1] units relative to base. A time that
+// is already in the past deletes the key, matching Redis.
+func expireGeneric(command Command, store *keyspace.Keyspace, base, unit int64) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	when, err := strconv.ParseInt(command.Args[1], 10, 64)
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+
+	var nx, xx, gt, lt bool
+	for _, arg := range command.Args[2:] {
+		switch strings.ToUpper(arg) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		default:
+			return errorReply(fmt.Sprintf("ERR Unsupported option %s", arg))
+		}
+	}
+	if nx && (xx || gt || lt) {
+		return errorReply("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if gt && lt {
+		return errorReply("ERR GT and LT options at the same time are not compatible")
+	}
+
+	expireAt, ok := absoluteExpireTime(when, unit, base)
+	if !ok {
+		return errorReply(invalidExpireMessage(command))
+	}
+
+	key := command.Args[0]
+	if !store.Exists(key) {
+		return integerReply(0)
+	}
+
+	// A key without a TTL is treated as having an infinite one, so GT can
+	// never succeed against it and LT always can.
+	current, hasTTL := store.ExpireAt(key)
+	switch {
+	case nx && hasTTL,
+		xx && !hasTTL,
+		gt && (!hasTTL || expireAt <= current),
+		lt && hasTTL && expireAt >= current:
+		return integerReply(0)
+	}
+
+	if expireAt <= store.Now() {
+		store.Delete(key)
+		return integerReply(1)
+	}
+	store.SetExpireAt(key, expireAt)
+	return integerReply(1)
+}
+
+// ttlGeneric reports the remaining TTL of a key, or its absolute expiry
+// time when absolute is set. Missing keys yield -2 and keys without a TTL
+// yield -1.
+func ttlGeneric(command Command, store *keyspace.Keyspace, unit int64, absolute bool) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+
+	key := command.Args[0]
+	if !store.Exists(key) {
+		return integerReply(-2)
+	}
+	expireAt, hasTTL := store.ExpireAt(key)
+	if !hasTTL {
+		return integerReply(-1)
+	}
+
+	ttl := expireAt
+	if !absolute {
+		ttl = max(expireAt-store.Now(), 0)
+	}
+	// like Redis, round a TTL to the nearest second, but truncate a time
+	switch {
+	case unit != secondsUnit:
+	case absolute:
+		ttl /= 1000
+	default:
+		ttl = (ttl + 500) / 1000
+	}
+	return integerReply(ttl)
+}
+
+// expireUnitAndBase maps a SET expiry option to its unit and the base time
+// the value is relative to.
+func expireUnitAndBase(option string, now int64) (int64, int64) {
+	switch option {
+	case "EX":
+		return secondsUnit, now
+	case "PX":
+		return millisecondsUnit, now
+	case "EXAT":
+		return secondsUnit, 0
+	default:
+		return millisecondsUnit, 0
+	}
+}
+
+// absoluteExpireTime converts when units past base into unix milliseconds,
+// reporting false if the result does not fit in an int64.
+func absoluteExpireTime(when, unit, base int64) (int64, bool) {
+	if when > math.MaxInt64/unit || when < math.MinInt64/unit {
+		return 0, false
+	}
+	when *= unit
+	if (when > 0 && base > math.MaxInt64-when) || (when < 0 && base < math.MinInt64-when) {
+		return 0, false
+	}
+	return base + when, true
+}
+
+func invalidExpireMessage(command Command) string {
+	return fmt.Sprintf(invalidExpireError, strings.ToLower(command.Name))
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// makeClockedStore returns a store whose clock is driven by *now.
+func makeClockedStore(now *int64, pairs ...string) *keyspace.Keyspace {
+	store := makeStore(pairs...)
+	store.SetClock(func() int64 { return *now })
+	return store
+}
+
+func TestSetExpiryOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		errorMsg  string
+		expectTTL int64
+	}{
+		{name: "EX", args: []string{"k", "v", "EX", "10"}, expectTTL: 10_000},
+		{name: "PX", args: []string{"k", "v", "px", "1500"}, expectTTL: 1_500},
+		{name: "EXAT", args: []string{"k", "v", "EXAT", "1010"}, expectTTL: 10_000},
+		{name: "PXAT", args: []string{"k", "v", "PXAT", "1000250"}, expectTTL: 250},
+		{name: "no expiry", args: []string{"k", "v"}, expectTTL: -1},
+		{name: "zero EX", args: []string{"k", "v", "EX", "0"}, errorMsg: "ERR invalid expire time in 'set' command"},
+		{name: "negative PX", args: []string{"k", "v", "PX", "-5"}, errorMsg: "ERR invalid expire time in 'set' command"},
+		{name: "overflowing EX", args: []string{"k", "v", "EX", "9223372036854775807"}, errorMsg: "ERR invalid expire time in 'set' command"},
+		{name: "non integer", args: []string{"k", "v", "EX", "ten"}, errorMsg: "ERR value is not an integer or out of range"},
+		{name: "missing value", args: []string{"k", "v", "EX"}, errorMsg: "ERR syntax error"},
+		{name: "EX and PX", args: []string{"k", "v", "EX", "1", "PX", "1"}, errorMsg: "ERR syntax error"},
+		{name: "EX and KEEPTTL", args: []string{"k", "v", "EX", "1", "KEEPTTL"}, errorMsg: "ERR syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := int64(1_000_000)
+			store := makeClockedStore(&now)
+			resp := HandlerSet(Command{Name: "SET", Args: tt.args}, store)
+			if tt.errorMsg != "" {
+				assert.Equal(t, enums.ErrorRespType, resp.Type)
+				assert.Equal(t, tt.errorMsg, resp.Str)
+				return
+			}
+			assert.Equal(t, "OK", resp.Str)
+			ttl := HandlerPTTL(Command{Name: "PTTL", Args: []string{"k"}}, store)
+			assert.Equal(t, tt.expectTTL, ttl.Int)
+		})
+	}
+}
+
+func TestSetOverwriteAndKeepTTL(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now)
+
+	HandlerSet(Command{Name: "SET", Args: []string{"k", "v", "EX", "10"}}, store)
+	HandlerSet(Command{Name: "SET", Args: []string{"k", "v2", "KEEPTTL"}}, store)
+	assert.Equal(t, int64(10), HandlerTTL(Command{Name: "TTL", Args: []string{"k"}}, store).Int)
+
+	HandlerSet(Command{Name: "SET", Args: []string{"k", "v3"}}, store)
+	assert.Equal(t, int64(-1), HandlerTTL(Command{Name: "TTL", Args: []string{"k"}}, store).Int)
+}
+
+func TestSetExpiryInThePastDeletesKey(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "k", "old")
+
+	resp := HandlerSet(Command{Name: "SET", Args: []string{"k", "v", "PXAT", "10"}}, store)
+	assert.Equal(t, "OK", resp.Str)
+	assert.False(t, store.Exists("k"))
+}
+
+func TestExpireFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		args     []string
+		expected int64
+		errorMsg string
+	}{
+		{name: "missing key", args: []string{"missing", "10"}, expected: 0},
+		{name: "set ttl", args: []string{"k", "10"}, expected: 1},
+		{name: "NX without ttl", args: []string{"k", "10", "NX"}, expected: 1},
+		{name: "NX with ttl", existing: "20", args: []string{"k", "10", "NX"}, expected: 0},
+		{name: "XX without ttl", args: []string{"k", "10", "XX"}, expected: 0},
+		{name: "XX with ttl", existing: "20", args: []string{"k", "10", "xx"}, expected: 1},
+		{name: "GT without ttl", args: []string{"k", "10", "GT"}, expected: 0},
+		{name: "GT smaller", existing: "20", args: []string{"k", "10", "GT"}, expected: 0},
+		{name: "GT larger", existing: "20", args: []string{"k", "30", "GT"}, expected: 1},
+		{name: "LT without ttl", args: []string{"k", "10", "LT"}, expected: 1},
+		{name: "LT larger", existing: "20", args: []string{"k", "30", "LT"}, expected: 0},
+		{name: "LT smaller", existing: "20", args: []string{"k", "10", "LT"}, expected: 1},
+		{name: "NX and XX", args: []string{"k", "10", "NX", "XX"}, errorMsg: "ERR NX and XX, GT or LT options at the same time are not compatible"},
+		{name: "GT and LT", args: []string{"k", "10", "GT", "LT"}, errorMsg: "ERR GT and LT options at the same time are not compatible"},
+		{name: "unknown option", args: []string{"k", "10", "YY"}, errorMsg: "ERR Unsupported option YY"},
+		{name: "non integer", args: []string{"k", "soon"}, errorMsg: "ERR value is not an integer or out of range"},
+		{name: "overflow", args: []string{"k", "9223372036854775807"}, errorMsg: "ERR invalid expire time in 'expire' command"},
+		{name: "no args", args: []string{"k"}, errorMsg: "ERR wrong number of arguments for 'EXPIRE' command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := int64(1_000_000)
+			store := makeClockedStore(&now, "k", "v")
+			if tt.existing != "" {
+				HandlerExpire(Command{Name: "EXPIRE", Args: []string{"k", tt.existing}}, store)
+			}
+			resp := HandlerExpire(Command{Name: "EXPIRE", Args: tt.args}, store)
+			if tt.errorMsg != "" {
+				assert.Equal(t, enums.ErrorRespType, resp.Type)
+				assert.Equal(t, tt.errorMsg, resp.Str)
+				return
+			}
+			assert.Equal(t, enums.IntRespType, resp.Type)
+			assert.Equal(t, tt.expected, resp.Int)
+		})
+	}
+}
+
+func TestExpireVariants(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "a", "1", "b", "1", "c", "1", "d", "1", "e", "1")
+
+	HandlerExpire(Command{Name: "EXPIRE", Args: []string{"a", "10"}}, store)
+	HandlerPExpire(Command{Name: "PEXPIRE", Args: []string{"b", "1500"}}, store)
+	HandlerExpireAt(Command{Name: "EXPIREAT", Args: []string{"c", "2000"}}, store)
+	HandlerPExpireAt(Command{Name: "PEXPIREAT", Args: []string{"d", "1000999"}}, store)
+
+	pexpiretime := func(key string) int64 {
+		return HandlerPExpireTime(Command{Name: "PEXPIRETIME", Args: []string{key}}, store).Int
+	}
+	assert.Equal(t, int64(1_010_000), pexpiretime("a"))
+	assert.Equal(t, int64(1_001_500), pexpiretime("b"))
+	assert.Equal(t, int64(2_000_000), pexpiretime("c"))
+	assert.Equal(t, int64(1_000_999), pexpiretime("d"))
+	assert.Equal(t, int64(2_000), HandlerExpireTime(Command{Name: "EXPIRETIME", Args: []string{"c"}}, store).Int)
+
+	HandlerPExpireAt(Command{Name: "PEXPIREAT", Args: []string{"e", "9999999999600"}}, store)
+	assert.Equal(t, int64(9_999_999_999), HandlerExpireTime(Command{Name: "EXPIRETIME", Args: []string{"e"}}, store).Int,
+		"an absolute time is truncated, not rounded")
+}
+
+func TestExpireInThePastDeletesKey(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "k", "v")
+
+	resp := HandlerExpire(Command{Name: "EXPIRE", Args: []string{"k", "-1"}}, store)
+	assert.Equal(t, int64(1), resp.Int)
+	assert.False(t, store.Exists("k"))
+}
+
+func TestTTL(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "plain", "v", "volatile", "v")
+	HandlerPExpire(Command{Name: "PEXPIRE", Args: []string{"volatile", "2600"}}, store)
+
+	ttl := func(key string) int64 {
+		return HandlerTTL(Command{Name: "TTL", Args: []string{key}}, store).Int
+	}
+	pttl := func(key string) int64 {
+		return HandlerPTTL(Command{Name: "PTTL", Args: []string{key}}, store).Int
+	}
+
+	assert.Equal(t, int64(-2), ttl("missing"))
+	assert.Equal(t, int64(-1), ttl("plain"))
+	assert.Equal(t, int64(3), ttl("volatile"))
+	assert.Equal(t, int64(2600), pttl("volatile"))
+	assert.Equal(t, int64(-2), HandlerExpireTime(Command{Name: "EXPIRETIME", Args: []string{"missing"}}, store).Int)
+	assert.Equal(t, int64(-1), HandlerExpireTime(Command{Name: "EXPIRETIME", Args: []string{"plain"}}, store).Int)
+
+	now += 2600
+	assert.Equal(t, int64(0), pttl("volatile"))
+
+	now++
+	assert.Equal(t, int64(-2), pttl("volatile"))
+}
+
+func TestPersist(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "plain", "v", "volatile", "v")
+	HandlerExpire(Command{Name: "EXPIRE", Args: []string{"volatile", "10"}}, store)
+
+	persist := func(key string) int64 {
+		return HandlerPersist(Command{Name: "PERSIST", Args: []string{key}}, store).Int
+	}
+	assert.Equal(t, int64(0), persist("missing"))
+	assert.Equal(t, int64(0), persist("plain"))
+	assert.Equal(t, int64(1), persist("volatile"))
+	assert.Equal(t, int64(-1), HandlerTTL(Command{Name: "TTL", Args: []string{"volatile"}}, store).Int)
+}
+
+func TestLazyExpiry(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "k", "5")
+	HandlerPExpire(Command{Name: "PEXPIRE", Args: []string{"k", "100"}}, store)
+
+	now += 101
+	assert.True(t, HandlerGet(Command{Name: "GET", Args: []string{"k"}}, store).IsNull)
+	assert.Equal(t, int64(0), HandlerDel(Command{Name: "DEL", Args: []string{"k"}}, store).Int)
+	assert.Equal(t, int64(1), HandlerIncr(Command{Name: "INCR", Args: []string{"k"}}, store).Int)
+	assert.Equal(t, int64(-1), HandlerTTL(Command{Name: "TTL", Args: []string{"k"}}, store).Int)
+}
+
+func TestIncrKeepsTTL(t *testing.T) {
+	now := int64(1_000_000)
+	store := makeClockedStore(&now, "counter", "1")
+	HandlerExpire(Command{Name: "EXPIRE", Args: []string{"counter", "10"}}, store)
+
+	HandlerIncr(Command{Name: "INCR", Args: []string{"counter"}}, store)
+	assert.Equal(t, int64(10), HandlerTTL(Command{Name: "TTL", Args: []string{"counter"}}, store).Int)
+}
@@ -0,0 +1,473 @@
+package commands
+
+import (
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// HandlerHSet implements HSET key field value [field value ...] and returns
+// the number of fields that were added rather than updated.
+func HandlerHSet(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 3 || len(command.Args)%2 != 1 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := createHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var added int64
+	for i := 1; i < len(command.Args); i += 2 {
+		if _, exists := hash[command.Args[i]]; !exists {
+			added++
+		}
+		hash[command.Args[i]] = command.Args[i+1]
+	}
+	store.SignalModified(command.Args[0])
+	return integerReply(added)
+}
+
+// HandlerHSetNX implements HSETNX key field value.
+func HandlerHSetNX(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := createHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if _, exists := hash[command.Args[1]]; exists {
+		return integerReply(0)
+	}
+	hash[command.Args[1]] = command.Args[2]
+	store.SignalModified(command.Args[0])
+	return integerReply(1)
+}
+
+func HandlerHGet(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	value, exists := hash[command.Args[1]]
+	if !exists {
+		return nullBulkReply()
+	}
+	return bulkReply(value)
+}
+
+// HandlerHMGet implements HMGET key field [field ...], replying with a null
+// for each field that does not exist.
+func HandlerHMGet(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	elements := make([]*common.RespValue, 0, len(command.Args)-1)
+	for _, field := range command.Args[1:] {
+		value, exists := hash[field]
+		elements = append(elements, nullableBulk(value, exists))
+	}
+	return common.RespValue{
+		Type:  enums.ArrayRespType,
+		Array: elements,
+	}
+}
+
+// HandlerHDel implements HDEL key field [field ...].
+func HandlerHDel(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	key := command.Args[0]
+	hash, ok := writeHash(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var removed int64
+	for _, field := range command.Args[1:] {
+		if _, exists := hash[field]; exists {
+			delete(hash, field)
+			removed++
+		}
+	}
+	if removed > 0 {
+		store.SignalModified(key)
+	}
+	if hash != nil && len(hash) == 0 {
+		store.Delete(key)
+	}
+	return integerReply(removed)
+}
+
+func HandlerHExists(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if _, exists := hash[command.Args[1]]; exists {
+		return integerReply(1)
+	}
+	return integerReply(0)
+}
+
+func HandlerHLen(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	return integerReply(int64(len(hash)))
+}
+
+func HandlerHKeys(command Command, store *keyspace.Keyspace) common.RespValue {
+	return hashGetAllGeneric(command, store, true, false)
+}
+
+func HandlerHVals(command Command, store *keyspace.Keyspace) common.RespValue {
+	return hashGetAllGeneric(command, store, false, true)
+}
+
+// HandlerHGetAll replies with a flat array of alternating fields and values.
+func HandlerHGetAll(command Command, store *keyspace.Keyspace) common.RespValue {
+	return hashGetAllGeneric(command, store, true, true)
+}
+
+// HandlerHIncrBy implements HINCRBY key field increment. A missing field
+// counts as 0.
+func HandlerHIncrBy(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	increment, err := strconv.ParseInt(command.Args[2], 10, 64)
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+
+	hash, ok := createHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var current int64
+	if value, exists := hash[command.Args[1]]; exists {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errorReply("ERR hash value is not an integer")
+		}
+	}
+	if (increment > 0 && current > math.MaxInt64-increment) ||
+		(increment < 0 && current < math.MinInt64-increment) {
+		return errorReply("ERR increment or decrement would overflow")
+	}
+
+	current += increment
+	hash[command.Args[1]] = strconv.FormatInt(current, 10)
+	store.SignalModified(command.Args[0])
+	return integerReply(current)
+}
+
+// HandlerHIncrByFloat implements HINCRBYFLOAT key field increment and
+// replies with the new value as a bulk string.
+func HandlerHIncrByFloat(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	increment, ok := parseFloat(command.Args[2])
+	if !ok {
+		return errorReply("ERR value is not a valid float")
+	}
+
+	hash, ok := createHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var current float64
+	if value, exists := hash[command.Args[1]]; exists {
+		current, ok = parseFloat(value)
+		if !ok {
+			return errorReply("ERR hash value is not a float")
+		}
+	}
+
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return errorReply("ERR increment would produce NaN or Infinity")
+	}
+
+	formatted := strconv.FormatFloat(current, 'f', -1, 64)
+	hash[command.Args[1]] = formatted
+	store.SignalModified(command.Args[0])
+	return bulkReply(formatted)
+}
+
+func HandlerHStrLen(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	return integerReply(int64(len(hash[command.Args[1]])))
+}
+
+// HandlerHRandField implements HRANDFIELD key [count [WITHVALUES]]. A
+// positive count returns distinct fields; a negative one may repeat them.
+func HandlerHRandField(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 1 || len(command.Args) > 3 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	if len(command.Args) == 1 {
+		if len(hash) == 0 {
+			return nullBulkReply()
+		}
+		fields := keyspace.RandomSample(maps.Keys(hash), 1)
+		return bulkReply(fields[0])
+	}
+
+	count, err := strconv.ParseInt(command.Args[1], 10, 64)
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+	withValues := false
+	if len(command.Args) == 3 {
+		if !strings.EqualFold(command.Args[2], "WITHVALUES") {
+			return errorReply(syntaxError)
+		}
+		withValues = true
+	}
+	if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+		return errorReply("ERR value is out of range")
+	}
+
+	var fields []string
+	switch {
+	case len(hash) == 0 || count == 0:
+		fields = []string{}
+	case count < 0:
+		fields = keyspace.RandomSampleWithRepeats(maps.Keys(hash), int(-count))
+	default:
+		fields = keyspace.RandomSample(maps.Keys(hash), int(min(count, int64(len(hash)))))
+	}
+
+	if !withValues {
+		return arrayReply(fields)
+	}
+	pairs := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		pairs = append(pairs, field, hash[field])
+	}
+	return arrayReply(pairs)
+}
+
+// HandlerHScan implements HSCAN key cursor [MATCH pattern] [COUNT count]
+// [NOVALUES].
+func HandlerHScan(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	options, errReply := parseScanOptions(command.Args[1:], true)
+	if errReply != nil {
+		return *errReply
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	next, fields := keyspace.Scan(maps.Keys(hash), options.cursor, options.count)
+	page := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		if !options.matches(field) {
+			continue
+		}
+		page = append(page, field)
+		if !options.noValues {
+			page = append(page, hash[field])
+		}
+	}
+	return scanReply(next, page)
+}
+
+func hashGetAllGeneric(command Command, store *keyspace.Keyspace, fields, values bool) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+
+	hash, ok := readHash(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	result := make([]string, 0, len(hash)*2)
+	for field, value := range hash {
+		if fields {
+			result = append(result, field)
+		}
+		if values {
+			result = append(result, value)
+		}
+	}
+	return arrayReply(result)
+}
+
+// readHash returns the hash stored at key, or nil if the key is missing.
+// The boolean is false when the key holds another type.
+func readHash(store *keyspace.Keyspace, key string) (map[string]string, bool) {
+	return hashFromObject(store.LookupRead(key))
+}
+
+// writeHash is readHash for callers that modify the hash.
+func writeHash(store *keyspace.Keyspace, key string) (map[string]string, bool) {
+	return hashFromObject(store.LookupWrite(key))
+}
+
+// createHash is writeHash for callers that add fields, creating an empty
+// hash at key if there is none.
+func createHash(store *keyspace.Keyspace, key string) (map[string]string, bool) {
+	hash, ok := writeHash(store, key)
+	if !ok || hash != nil {
+		return hash, ok
+	}
+	object := keyspace.NewHashObject()
+	store.SetObject(key, object)
+	return object.Hash, true
+}
+
+func hashFromObject(object *keyspace.Object) (map[string]string, bool) {
+	if object == nil {
+		return nil, true
+	}
+	if object.Type != keyspace.HashType {
+		return nil, false
+	}
+	return object.Hash, true
+}
+
+// parseFloat parses a float argument the way Redis does, rejecting NaN.
+func parseFloat(value string) (float64, bool) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) {
+		return 0, false
+	}
+	return parsed, true
+}
+
+// nullableBulk returns a bulk string array element, or a null one when
+// exists is false.
+func nullableBulk(value string, exists bool) *common.RespValue {
+	if !exists {
+		return &common.RespValue{
+			Type:   enums.BulkStringRespType,
+			IsNull: true,
+		}
+	}
+	return &common.RespValue{
+		Type: enums.BulkStringRespType,
+		Str:  value,
+	}
+}
+
+// scanOptions holds the arguments shared by the SCAN family.
+type scanOptions struct {
+	cursor   uint64
+	pattern  string
+	count    int
+	noValues bool
+}
+
+func (o scanOptions) matches(element string) bool {
+	return o.pattern == "" || common.GlobMatch(o.pattern, element)
+}
+
+// parseScanOptions parses cursor [MATCH pattern] [COUNT count], plus
+// NOVALUES when allowNoValues is set.
+func parseScanOptions(args []string, allowNoValues bool) (scanOptions, *common.RespValue) {
+	options := scanOptions{count: 10}
+
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		reply := errorReply("ERR invalid cursor")
+		return options, &reply
+	}
+	options.cursor = cursor
+
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "MATCH" && i+1 < len(args):
+			i++
+			options.pattern = args[i]
+			if options.pattern == "*" {
+				options.pattern = ""
+			}
+		case option == "COUNT" && i+1 < len(args):
+			i++
+			count, err := strconv.Atoi(args[i])
+			if err != nil {
+				reply := errorReply(notIntegerError)
+				return options, &reply
+			}
+			if count < 1 {
+				reply := errorReply(syntaxError)
+				return options, &reply
+			}
+			options.count = count
+		case option == "NOVALUES" && allowNoValues:
+			options.noValues = true
+		default:
+			reply := errorReply(syntaxError)
+			return options, &reply
+		}
+	}
+	return options, nil
+}
+
+// scanReply builds the two-element reply shared by the SCAN family: the
+// next cursor as a bulk string, then the page of elements.
+func scanReply(cursor uint64, page []string) common.RespValue {
+	elements := arrayReply(page)
+	return common.RespValue{
+		Type: enums.ArrayRespType,
+		Array: []*common.RespValue{
+			{Type: enums.BulkStringRespType, Str: strconv.FormatUint(cursor, 10)},
+			&elements,
+		},
+	}
+}
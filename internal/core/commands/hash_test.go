@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// makeHashStore returns a store holding key as a hash of field/value pairs.
+func makeHashStore(key string, pairs ...string) *keyspace.Keyspace {
+	store := makeStore()
+	if len(pairs) > 0 {
+		HandlerHSet(Command{Name: "HSET", Args: append([]string{key}, pairs...)}, store)
+	}
+	return store
+}
+
+func sorted(values []string) []string {
+	sort.Strings(values)
+	return values
+}
+
+func TestHSet(t *testing.T) {
+	store := makeStore()
+
+	resp := HandlerHSet(Command{Name: "HSET", Args: []string{"h", "a", "1", "b", "2"}}, store)
+	assert.Equal(t, int64(2), resp.Int)
+
+	resp = HandlerHSet(Command{Name: "HSET", Args: []string{"h", "a", "10", "c", "3"}}, store)
+	assert.Equal(t, int64(1), resp.Int)
+	assert.Equal(t, bulkReply("10"), HandlerHGet(Command{Name: "HGET", Args: []string{"h", "a"}}, store))
+
+	resp = HandlerHSet(Command{Name: "HSET", Args: []string{"h", "a"}}, store)
+	assert.Equal(t, common.WrongNumberOfArgumentsError("HSET"), resp.Str)
+	resp = HandlerHSet(Command{Name: "HSET", Args: []string{"h", "a", "1", "b"}}, store)
+	assert.Equal(t, common.WrongNumberOfArgumentsError("HSET"), resp.Str)
+}
+
+func TestHSetNX(t *testing.T) {
+	store := makeHashStore("h", "a", "1")
+
+	assert.Equal(t, int64(0), HandlerHSetNX(Command{Name: "HSETNX", Args: []string{"h", "a", "2"}}, store).Int)
+	assert.Equal(t, int64(1), HandlerHSetNX(Command{Name: "HSETNX", Args: []string{"h", "b", "2"}}, store).Int)
+	assert.Equal(t, bulkReply("1"), HandlerHGet(Command{Name: "HGET", Args: []string{"h", "a"}}, store))
+}
+
+func TestHGetAndHMGet(t *testing.T) {
+	store := makeHashStore("h", "a", "1", "b", "2")
+
+	assert.Equal(t, nullBulkReply(), HandlerHGet(Command{Name: "HGET", Args: []string{"h", "zz"}}, store))
+	assert.Equal(t, nullBulkReply(), HandlerHGet(Command{Name: "HGET", Args: []string{"missing", "a"}}, store))
+
+	resp := HandlerHMGet(Command{Name: "HMGET", Args: []string{"h", "a", "zz", "b"}}, store)
+	assert.Equal(t, enums.ArrayRespType, resp.Type)
+	assert.Len(t, resp.Array, 3)
+	assert.Equal(t, "1", resp.Array[0].Str)
+	assert.True(t, resp.Array[1].IsNull)
+	assert.Equal(t, "2", resp.Array[2].Str)
+
+	resp = HandlerHMGet(Command{Name: "HMGET", Args: []string{"missing", "a"}}, store)
+	assert.Len(t, resp.Array, 1)
+	assert.True(t, resp.Array[0].IsNull)
+}
+
+func TestHDel(t *testing.T) {
+	store := makeHashStore("h", "a", "1", "b", "2")
+
+	assert.Equal(t, int64(1), HandlerHDel(Command{Name: "HDEL", Args: []string{"h", "a", "zz"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerHDel(Command{Name: "HDEL", Args: []string{"missing", "a"}}, store).Int)
+	assert.Equal(t, int64(1), HandlerHDel(Command{Name: "HDEL", Args: []string{"h", "b"}}, store).Int)
+	assert.False(t, store.Exists("h"))
+}
+
+func TestHExistsLenStrLen(t *testing.T) {
+	store := makeHashStore("h", "a", "hello", "b", "")
+
+	assert.Equal(t, int64(1), HandlerHExists(Command{Name: "HEXISTS", Args: []string{"h", "a"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerHExists(Command{Name: "HEXISTS", Args: []string{"h", "zz"}}, store).Int)
+	assert.Equal(t, int64(2), HandlerHLen(Command{Name: "HLEN", Args: []string{"h"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerHLen(Command{Name: "HLEN", Args: []string{"missing"}}, store).Int)
+	assert.Equal(t, int64(5), HandlerHStrLen(Command{Name: "HSTRLEN", Args: []string{"h", "a"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerHStrLen(Command{Name: "HSTRLEN", Args: []string{"h", "zz"}}, store).Int)
+}
+
+func TestHKeysValsGetAll(t *testing.T) {
+	store := makeHashStore("h", "a", "1", "b", "2")
+
+	keys := bulkStrings(HandlerHKeys(Command{Name: "HKEYS", Args: []string{"h"}}, store))
+	assert.Equal(t, []string{"a", "b"}, sorted(keys))
+
+	vals := bulkStrings(HandlerHVals(Command{Name: "HVALS", Args: []string{"h"}}, store))
+	assert.Equal(t, []string{"1", "2"}, sorted(vals))
+
+	all := bulkStrings(HandlerHGetAll(Command{Name: "HGETALL", Args: []string{"h"}}, store))
+	pairs := map[string]string{}
+	for i := 0; i+1 < len(all); i += 2 {
+		pairs[all[i]] = all[i+1]
+	}
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, pairs)
+
+	empty := HandlerHGetAll(Command{Name: "HGETALL", Args: []string{"missing"}}, store)
+	assert.Equal(t, enums.ArrayRespType, empty.Type)
+	assert.Empty(t, empty.Array)
+}
+
+func TestHIncrBy(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		increment string
+		expected  common.RespValue
+	}{
+		{name: "missing field", field: "new", increment: "5", expected: integerReply(5)},
+		{name: "existing field", field: "n", increment: "-3", expected: integerReply(7)},
+		{name: "not an integer", field: "text", increment: "1", expected: errorReply("ERR hash value is not an integer")},
+		{name: "bad increment", field: "n", increment: "x", expected: errorReply(notIntegerError)},
+		{name: "overflow", field: "big", increment: "1", expected: errorReply("ERR increment or decrement would overflow")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeHashStore("h", "n", "10", "text", "abc", "big", "9223372036854775807")
+			resp := HandlerHIncrBy(Command{Name: "HINCRBY", Args: []string{"h", tt.field, tt.increment}}, store)
+			assert.Equal(t, tt.expected, resp)
+		})
+	}
+}
+
+func TestHIncrByFloat(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		increment string
+		expected  common.RespValue
+	}{
+		{name: "missing field", field: "new", increment: "1.5", expected: bulkReply("1.5")},
+		{name: "existing field", field: "f", increment: "0.25", expected: bulkReply("10.75")},
+		{name: "integer result", field: "f", increment: "0.5", expected: bulkReply("11")},
+		{name: "exponent", field: "f", increment: "5.0e3", expected: bulkReply("5010.5")},
+		{name: "not a float", field: "text", increment: "1", expected: errorReply("ERR hash value is not a float")},
+		{name: "bad increment", field: "f", increment: "abc", expected: errorReply("ERR value is not a valid float")},
+		{name: "infinity", field: "f", increment: "inf", expected: errorReply("ERR increment would produce NaN or Infinity")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeHashStore("h", "f", "10.5", "text", "abc")
+			resp := HandlerHIncrByFloat(Command{Name: "HINCRBYFLOAT", Args: []string{"h", tt.field, tt.increment}}, store)
+			assert.Equal(t, tt.expected, resp)
+		})
+	}
+}
+
+func TestHRandField(t *testing.T) {
+	store := makeHashStore("h", "a", "1", "b", "2", "c", "3")
+	hrandfield := func(args ...string) common.RespValue {
+		return HandlerHRandField(Command{Name: "HRANDFIELD", Args: args}, store)
+	}
+
+	single := hrandfield("h")
+	assert.Contains(t, []string{"a", "b", "c"}, single.Str)
+	assert.Equal(t, nullBulkReply(), hrandfield("missing"))
+
+	distinct := bulkStrings(hrandfield("h", "2"))
+	assert.Len(t, distinct, 2)
+	assert.NotEqual(t, distinct[0], distinct[1])
+
+	assert.Equal(t, []string{"a", "b", "c"}, sorted(bulkStrings(hrandfield("h", "10"))))
+	assert.Len(t, bulkStrings(hrandfield("h", "-10")), 10)
+	assert.Empty(t, hrandfield("h", "0").Array)
+	assert.Empty(t, hrandfield("missing", "3").Array)
+
+	withValues := bulkStrings(hrandfield("h", "-4", "WITHVALUES"))
+	assert.Len(t, withValues, 8)
+	for i := 0; i < len(withValues); i += 2 {
+		expected := map[string]string{"a": "1", "b": "2", "c": "3"}[withValues[i]]
+		assert.Equal(t, expected, withValues[i+1])
+	}
+
+	assert.Equal(t, errorReply(syntaxError), hrandfield("h", "1", "WITHSCORES"))
+	assert.Equal(t, errorReply(notIntegerError), hrandfield("h", "x"))
+}
+
+func TestHScan(t *testing.T) {
+	store := makeStore()
+	expected := map[string]string{}
+	for _, field := range []string{"user:1", "user:2", "user:3", "admin:1", "admin:2"} {
+		HandlerHSet(Command{Name: "HSET", Args: []string{"h", field, "v-" + field}}, store)
+		expected[field] = "v-" + field
+	}
+
+	scanAll := func(extra ...string) map[string]string {
+		found := map[string]string{}
+		cursor := "0"
+		for {
+			resp := HandlerHScan(Command{Name: "HSCAN", Args: append([]string{"h", cursor}, extra...)}, store)
+			assert.Len(t, resp.Array, 2)
+			page := bulkStrings(*resp.Array[1])
+			for i := 0; i+1 < len(page); i += 2 {
+				found[page[i]] = page[i+1]
+			}
+			cursor = resp.Array[0].Str
+			if cursor == "0" {
+				return found
+			}
+		}
+	}
+
+	assert.Equal(t, expected, scanAll("COUNT", "2"))
+	assert.Equal(t, map[string]string{"admin:1": "v-admin:1", "admin:2": "v-admin:2"}, scanAll("MATCH", "admin:*", "COUNT", "1"))
+
+	resp := HandlerHScan(Command{Name: "HSCAN", Args: []string{"h", "0", "COUNT", "100", "NOVALUES"}}, store)
+	assert.Equal(t, "0", resp.Array[0].Str)
+	assert.Equal(t, []string{"admin:1", "admin:2", "user:1", "user:2", "user:3"}, sorted(bulkStrings(*resp.Array[1])))
+
+	resp = HandlerHScan(Command{Name: "HSCAN", Args: []string{"missing", "0"}}, store)
+	assert.Equal(t, "0", resp.Array[0].Str)
+	assert.Empty(t, resp.Array[1].Array)
+
+	assert.Equal(t, errorReply("ERR invalid cursor"), HandlerHScan(Command{Name: "HSCAN", Args: []string{"h", "-1"}}, store))
+	assert.Equal(t, errorReply(syntaxError), HandlerHScan(Command{Name: "HSCAN", Args: []string{"h", "0", "COUNT", "0"}}, store))
+	assert.Equal(t, errorReply(syntaxError), HandlerHScan(Command{Name: "HSCAN", Args: []string{"h", "0", "LIMIT", "3"}}, store))
+}
+
+func TestHashWrongType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(Command, *keyspace.Keyspace) common.RespValue
+		args    []string
+	}{
+		{name: "HSET", handler: HandlerHSet, args: []string{"str", "f", "v"}},
+		{name: "HSETNX", handler: HandlerHSetNX, args: []string{"str", "f", "v"}},
+		{name: "HGET", handler: HandlerHGet, args: []string{"str", "f"}},
+		{name: "HMGET", handler: HandlerHMGet, args: []string{"str", "f"}},
+		{name: "HDEL", handler: HandlerHDel, args: []string{"str", "f"}},
+		{name: "HEXISTS", handler: HandlerHExists, args: []string{"str", "f"}},
+		{name: "HLEN", handler: HandlerHLen, args: []string{"str"}},
+		{name: "HKEYS", handler: HandlerHKeys, args: []string{"str"}},
+		{name: "HVALS", handler: HandlerHVals, args: []string{"str"}},
+		{name: "HGETALL", handler: HandlerHGetAll, args: []string{"str"}},
+		{name: "HINCRBY", handler: HandlerHIncrBy, args: []string{"str", "f", "1"}},
+		{name: "HINCRBYFLOAT", handler: HandlerHIncrByFloat, args: []string{"str", "f", "1"}},
+		{name: "HSTRLEN", handler: HandlerHStrLen, args: []string{"str", "f"}},
+		{name: "HRANDFIELD", handler: HandlerHRandField, args: []string{"str"}},
+		{name: "HSCAN", handler: HandlerHScan, args: []string{"str", "0"}},
+		{name: "GET", handler: HandlerGet, args: []string{"hash"}},
+		{name: "LPUSH", handler: HandlerLPush, args: []string{"hash", "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeHashStore("hash", "f", "v")
+			store.Set("str", "value")
+			resp := tt.handler(Command{Name: tt.name, Args: tt.args}, store)
+			assert.Equal(t, errorReply(wrongTypeError), resp)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// keySpec says where a command's keys are among its arguments, counting
+// from the first argument after the name: every step-th argument from
+// first to last, where a negative last counts from the end. numKeys
+// marks commands that give the number of keys at first, just before the
+// keys themselves; dest is a key before that count, as in ZUNIONSTORE.
+type keySpec struct {
+	first, last, step int
+	numKeys           bool
+	dest              bool
+}
+
+var (
+	singleKey = keySpec{0, 0, 1, false, false}
+	twoKeys   = keySpec{0, 1, 1, false, false}
+	allKeys   = keySpec{0, -1, 1, false, false}
+	// blocking pops end with a timeout
+	keysBeforeTimeout = keySpec{0, -2, 1, false, false}
+)
+
+// commandKeys lists where every command that takes keys finds them.
+// Commands missing from it take none.
+var commandKeys = map[enums.CommandName]keySpec{
+	enums.SetCommandName:     singleKey,
+	enums.GetCommandName:     singleKey,
+	enums.IncrCommandName:    singleKey,
+	enums.DeleteCommandName:  singleKey,
+	enums.PersistCommandName: singleKey,
+
+	enums.ExpireCommandName:      singleKey,
+	enums.PExpireCommandName:     singleKey,
+	enums.ExpireAtCommandName:    singleKey,
+	enums.PExpireAtCommandName:   singleKey,
+	enums.TTLCommandName:         singleKey,
+	enums.PTTLCommandName:        singleKey,
+	enums.ExpireTimeCommandName:  singleKey,
+	enums.PExpireTimeCommandName: singleKey,
+
+	enums.LPushCommandName:   singleKey,
+	enums.RPushCommandName:   singleKey,
+	enums.LPopCommandName:    singleKey,
+	enums.RPopCommandName:    singleKey,
+	enums.LRangeCommandName:  singleKey,
+	enums.LLenCommandName:    singleKey,
+	enums.LIndexCommandName:  singleKey,
+	enums.LSetCommandName:    singleKey,
+	enums.LRemCommandName:    singleKey,
+	enums.LTrimCommandName:   singleKey,
+	enums.LInsertCommandName: singleKey,
+	enums.LMoveCommandName:   twoKeys,
+
+	enums.HSetCommandName:         singleKey,
+	enums.HSetNXCommandName:       singleKey,
+	enums.HGetCommandName:         singleKey,
+	enums.HMGetCommandName:        singleKey,
+	enums.HDelCommandName:         singleKey,
+	enums.HExistsCommandName:      singleKey,
+	enums.HLenCommandName:         singleKey,
+	enums.HKeysCommandName:        singleKey,
+	enums.HValsCommandName:        singleKey,
+	enums.HGetAllCommandName:      singleKey,
+	enums.HIncrByCommandName:      singleKey,
+	enums.HIncrByFloatCommandName: singleKey,
+	enums.HStrLenCommandName:      singleKey,
+	enums.HRandFieldCommandName:   singleKey,
+	enums.HScanCommandName:        singleKey,
+
+	enums.SAddCommandName:        singleKey,
+	enums.SRemCommandName:        singleKey,
+	enums.SCardCommandName:       singleKey,
+	enums.SIsMemberCommandName:   singleKey,
+	enums.SMIsMemberCommandName:  singleKey,
+	enums.SMembersCommandName:    singleKey,
+	enums.SPopCommandName:        singleKey,
+	enums.SRandMemberCommandName: singleKey,
+	enums.SMoveCommandName:       twoKeys,
+	enums.SInterCommandName:      allKeys,
+	enums.SUnionCommandName:      allKeys,
+	enums.SDiffCommandName:       allKeys,
+	enums.SInterStoreCommandName: allKeys,
+	enums.SUnionStoreCommandName: allKeys,
+	enums.SDiffStoreCommandName:  allKeys,
+	enums.SInterCardCommandName:  {0, 0, 1, true, false},
+
+	enums.ZAddCommandName:             singleKey,
+	enums.ZIncrByCommandName:          singleKey,
+	enums.ZRemCommandName:             singleKey,
+	enums.ZScoreCommandName:           singleKey,
+	enums.ZMScoreCommandName:          singleKey,
+	enums.ZCardCommandName:            singleKey,
+	enums.ZRankCommandName:            singleKey,
+	enums.ZRevRankCommandName:         singleKey,
+	enums.ZRangeCommandName:           singleKey,
+	enums.ZRangeStoreCommandName:      twoKeys,
+	enums.ZCountCommandName:           singleKey,
+	enums.ZLexCountCommandName:        singleKey,
+	enums.ZPopMinCommandName:          singleKey,
+	enums.ZPopMaxCommandName:          singleKey,
+	enums.ZRemRangeByRankCommandName:  singleKey,
+	enums.ZRemRangeByScoreCommandName: singleKey,
+	enums.ZRemRangeByLexCommandName:   singleKey,
+	enums.ZUnionStoreCommandName:      {1, 0, 1, true, true},
+	enums.ZInterStoreCommandName:      {1, 0, 1, true, true},
+
+	enums.BLPopCommandName:    keysBeforeTimeout,
+	enums.BRPopCommandName:    keysBeforeTimeout,
+	enums.BLMoveCommandName:   twoKeys,
+	enums.BZPopMinCommandName: keysBeforeTimeout,
+	enums.BZPopMaxCommandName: keysBeforeTimeout,
+
+	enums.WatchCommandName: allKeys,
+
+	enums.DumpCommandName:          singleKey,
+	enums.RestoreCommandName:       singleKey,
+	enums.RestoreAskingCommandName: singleKey,
+}
+
+// Keys returns the keys command names, in the order given, which cluster
+// mode needs to find the slot the command runs against. MIGRATE's keys
+// are found by MigrateKeys, as they follow its options. A command with
+// the wrong number of arguments, or a bad key count, names none; it fails
+// before touching any key.
+func Keys(command Command) []string {
+	name := enums.StringToCommandName(command.Name)
+	spec, exists := commandKeys[name]
+	if name == enums.MigrateCommandName && CheckArity(command) {
+		return MigrateKeys(command.Args)
+	}
+	if !exists || !CheckArity(command) {
+		return nil
+	}
+	args := command.Args
+	var keys []string
+	if spec.dest {
+		keys = append(keys, args[0])
+	}
+	first, last := spec.first, spec.last
+	if spec.numKeys {
+		count, err := strconv.Atoi(args[first])
+		if err != nil || count <= 0 || count > len(args)-first-1 {
+			return keys
+		}
+		first, last = first+1, first+count
+	} else if last < 0 {
+		last += len(args)
+	}
+	for i := first; i <= last && i < len(args); i += spec.step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// MigrateKeys returns the keys MIGRATE's arguments name: the key after
+// the port or, if that is empty, those after the KEYS option.
+func MigrateKeys(args []string) []string {
+	if args[2] != "" {
+		return args[2:3]
+	}
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			i++
+		case "AUTH2":
+			i += 2
+		case "KEYS":
+			return args[i+1:]
+		}
+	}
+	return nil
+}
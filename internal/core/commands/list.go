@@ -0,0 +1,404 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+func HandlerLPush(command Command, store *keyspace.Keyspace) common.RespValue {
+	return pushGeneric(command, store, true)
+}
+
+func HandlerRPush(command Command, store *keyspace.Keyspace) common.RespValue {
+	return pushGeneric(command, store, false)
+}
+
+// HandlerLPop implements LPOP key [count].
+func HandlerLPop(command Command, store *keyspace.Keyspace) common.RespValue {
+	return popGeneric(command, store, true)
+}
+
+// HandlerRPop implements RPOP key [count].
+func HandlerRPop(command Command, store *keyspace.Keyspace) common.RespValue {
+	return popGeneric(command, store, false)
+}
+
+// HandlerLRange implements LRANGE key start stop.
+func HandlerLRange(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	start, err1 := strconv.Atoi(command.Args[1])
+	stop, err2 := strconv.Atoi(command.Args[2])
+	if err1 != nil || err2 != nil {
+		return errorReply(notIntegerError)
+	}
+
+	list, ok := readList(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return arrayReply(nil)
+	}
+
+	start, stop = normalizeRange(start, stop, list.Len())
+	return arrayReply(list.Range(start, stop))
+}
+
+func HandlerLLen(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+	list, ok := readList(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return integerReply(0)
+	}
+	return integerReply(int64(list.Len()))
+}
+
+// HandlerLIndex implements LINDEX key index. Negative indexes count back
+// from the tail.
+func HandlerLIndex(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+	index, err := strconv.Atoi(command.Args[1])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+
+	list, ok := readList(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return nullBulkReply()
+	}
+
+	if index < 0 {
+		index += list.Len()
+	}
+	if index < 0 || index >= list.Len() {
+		return nullBulkReply()
+	}
+	return bulkReply(list.Index(index))
+}
+
+// HandlerLSet implements LSET key index element.
+func HandlerLSet(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	index, err := strconv.Atoi(command.Args[1])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+
+	list, ok := writeList(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return errorReply(noSuchKeyError)
+	}
+
+	if index < 0 {
+		index += list.Len()
+	}
+	if index < 0 || index >= list.Len() {
+		return errorReply("ERR index out of range")
+	}
+	list.Set(index, command.Args[2])
+	store.SignalModified(command.Args[0])
+	return okReply()
+}
+
+// HandlerLRem implements LREM key count element.
+func HandlerLRem(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	count, err := strconv.Atoi(command.Args[1])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+
+	key := command.Args[0]
+	list, ok := writeList(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return integerReply(0)
+	}
+
+	removed := list.RemoveValue(command.Args[2], count)
+	if removed > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptyList(store, key, list)
+	return integerReply(int64(removed))
+}
+
+// HandlerLTrim implements LTRIM key start stop.
+func HandlerLTrim(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	start, err1 := strconv.Atoi(command.Args[1])
+	stop, err2 := strconv.Atoi(command.Args[2])
+	if err1 != nil || err2 != nil {
+		return errorReply(notIntegerError)
+	}
+
+	key := command.Args[0]
+	list, ok := writeList(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return okReply()
+	}
+
+	length := list.Len()
+	start, stop = normalizeRange(start, stop, length)
+	list.Trim(start, stop)
+	if list.Len() != length {
+		store.SignalModified(key)
+	}
+	deleteIfEmptyList(store, key, list)
+	return okReply()
+}
+
+// HandlerLInsert implements LINSERT key BEFORE|AFTER pivot element. It
+// returns the new length, -1 when the pivot is missing and 0 when the key
+// is.
+func HandlerLInsert(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 4 {
+		return wrongArityReply(command)
+	}
+
+	var after bool
+	switch strings.ToUpper(command.Args[1]) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return errorReply(syntaxError)
+	}
+
+	list, ok := writeList(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		return integerReply(0)
+	}
+
+	index := list.IndexOf(command.Args[2])
+	if index == -1 {
+		return integerReply(-1)
+	}
+	if after {
+		index++
+	}
+	list.Insert(index, command.Args[3])
+	store.SignalModified(command.Args[0])
+	return integerReply(int64(list.Len()))
+}
+
+// HandlerLMove implements LMOVE source destination LEFT|RIGHT LEFT|RIGHT.
+// Source and destination may be the same key, which rotates the list.
+func HandlerLMove(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 4 {
+		return wrongArityReply(command)
+	}
+	fromLeft, ok1 := parseListEnd(command.Args[2])
+	toLeft, ok2 := parseListEnd(command.Args[3])
+	if !ok1 || !ok2 {
+		return errorReply(syntaxError)
+	}
+
+	source, destination := command.Args[0], command.Args[1]
+	sourceList, ok := writeList(store, source)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if sourceList == nil {
+		return nullBulkReply()
+	}
+
+	// check the destination before popping so a type error leaves the
+	// source untouched
+	destinationList, ok := writeList(store, destination)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var value string
+	if fromLeft {
+		value = sourceList.PopFront()
+	} else {
+		value = sourceList.PopBack()
+	}
+
+	if destinationList == nil {
+		object := keyspace.NewListObject()
+		store.SetObject(destination, object)
+		destinationList = object.List
+	}
+	if toLeft {
+		destinationList.PushFront(value)
+	} else {
+		destinationList.PushBack(value)
+	}
+
+	store.SignalModified(source)
+	store.SignalModified(destination)
+	deleteIfEmptyList(store, source, sourceList)
+	return bulkReply(value)
+}
+
+func pushGeneric(command Command, store *keyspace.Keyspace, front bool) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	key := command.Args[0]
+	list, ok := writeList(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		object := keyspace.NewListObject()
+		store.SetObject(key, object)
+		list = object.List
+	}
+
+	for _, value := range command.Args[1:] {
+		if front {
+			list.PushFront(value)
+		} else {
+			list.PushBack(value)
+		}
+	}
+	store.SignalModified(key)
+	return integerReply(int64(list.Len()))
+}
+
+// popGeneric pops a single element as a bulk string, or up to count
+// elements as an array when a count is given.
+func popGeneric(command Command, store *keyspace.Keyspace, front bool) common.RespValue {
+	if len(command.Args) < 1 || len(command.Args) > 2 {
+		return wrongArityReply(command)
+	}
+
+	hasCount := len(command.Args) == 2
+	count := 1
+	if hasCount {
+		parsed, err := strconv.Atoi(command.Args[1])
+		if err != nil || parsed < 0 {
+			return errorReply(mustBePositiveError)
+		}
+		count = parsed
+	}
+
+	key := command.Args[0]
+	list, ok := writeList(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if list == nil {
+		if hasCount {
+			return nullArrayReply()
+		}
+		return nullBulkReply()
+	}
+
+	values := make([]string, 0, min(count, list.Len()))
+	for len(values) < count && list.Len() > 0 {
+		if front {
+			values = append(values, list.PopFront())
+		} else {
+			values = append(values, list.PopBack())
+		}
+	}
+	if len(values) > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptyList(store, key, list)
+
+	if !hasCount {
+		return bulkReply(values[0])
+	}
+	return arrayReply(values)
+}
+
+// readList returns the list stored at key, or nil if the key is missing.
+// The boolean is false when the key holds another type.
+func readList(store *keyspace.Keyspace, key string) (*keyspace.List, bool) {
+	return listFromObject(store.LookupRead(key))
+}
+
+// writeList is readList for callers that modify the list.
+func writeList(store *keyspace.Keyspace, key string) (*keyspace.List, bool) {
+	return listFromObject(store.LookupWrite(key))
+}
+
+func listFromObject(object *keyspace.Object) (*keyspace.List, bool) {
+	if object == nil {
+		return nil, true
+	}
+	if object.Type != keyspace.ListType {
+		return nil, false
+	}
+	return object.List, true
+}
+
+// deleteIfEmptyList removes key once its list has no elements left; Redis
+// never keeps empty aggregate values around.
+func deleteIfEmptyList(store *keyspace.Keyspace, key string, list *keyspace.List) {
+	if list.Len() == 0 {
+		store.Delete(key)
+	}
+}
+
+func parseListEnd(where string) (left bool, ok bool) {
+	switch strings.ToUpper(where) {
+	case "LEFT":
+		return true, true
+	case "RIGHT":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// normalizeRange converts a Redis start/stop pair, where negative values
+// count back from the end, into in-bounds positions. An empty range comes
+// back with start > stop.
+func normalizeRange(start, stop, length int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return 0, -1
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	return start, stop
+}
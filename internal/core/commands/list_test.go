@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// makeListStore returns a store holding key as a list of values.
+func makeListStore(key string, values ...string) *keyspace.Keyspace {
+	store := makeStore()
+	if len(values) > 0 {
+		HandlerRPush(Command{Name: "RPUSH", Args: append([]string{key}, values...)}, store)
+	}
+	return store
+}
+
+func lrange(store *keyspace.Keyspace, key string) []string {
+	resp := HandlerLRange(Command{Name: "LRANGE", Args: []string{key, "0", "-1"}}, store)
+	return bulkStrings(resp)
+}
+
+func bulkStrings(resp common.RespValue) []string {
+	values := make([]string, 0, len(resp.Array))
+	for _, element := range resp.Array {
+		values = append(values, element.Str)
+	}
+	return values
+}
+
+func TestPush(t *testing.T) {
+	store := makeStore()
+
+	resp := HandlerRPush(Command{Name: "RPUSH", Args: []string{"list", "b", "c"}}, store)
+	assert.Equal(t, int64(2), resp.Int)
+
+	resp = HandlerLPush(Command{Name: "LPUSH", Args: []string{"list", "a", "z"}}, store)
+	assert.Equal(t, int64(4), resp.Int)
+	assert.Equal(t, []string{"z", "a", "b", "c"}, lrange(store, "list"))
+
+	resp = HandlerLPush(Command{Name: "LPUSH", Args: []string{"list"}}, store)
+	assert.Equal(t, common.WrongNumberOfArgumentsError("LPUSH"), resp.Str)
+}
+
+func TestPop(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  func(Command, *keyspace.Keyspace) common.RespValue
+		args     []string
+		expected common.RespValue
+		left     []string
+	}{
+		{
+			name:     "lpop single",
+			handler:  HandlerLPop,
+			args:     []string{"list"},
+			expected: bulkReply("a"),
+			left:     []string{"b", "c"},
+		},
+		{
+			name:     "rpop single",
+			handler:  HandlerRPop,
+			args:     []string{"list"},
+			expected: bulkReply("c"),
+			left:     []string{"a", "b"},
+		},
+		{
+			name:     "lpop count",
+			handler:  HandlerLPop,
+			args:     []string{"list", "2"},
+			expected: arrayReply([]string{"a", "b"}),
+			left:     []string{"c"},
+		},
+		{
+			name:     "rpop count larger than list",
+			handler:  HandlerRPop,
+			args:     []string{"list", "10"},
+			expected: arrayReply([]string{"c", "b", "a"}),
+			left:     []string{},
+		},
+		{
+			name:     "zero count",
+			handler:  HandlerLPop,
+			args:     []string{"list", "0"},
+			expected: arrayReply([]string{}),
+			left:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "missing key",
+			handler:  HandlerLPop,
+			args:     []string{"missing"},
+			expected: nullBulkReply(),
+			left:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "missing key with count",
+			handler:  HandlerRPop,
+			args:     []string{"missing", "2"},
+			expected: nullArrayReply(),
+			left:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "negative count",
+			handler:  HandlerLPop,
+			args:     []string{"list", "-1"},
+			expected: errorReply("ERR value is out of range, must be positive"),
+			left:     []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeListStore("list", "a", "b", "c")
+			resp := tt.handler(Command{Name: "POP", Args: tt.args}, store)
+			assert.Equal(t, tt.expected, resp)
+			assert.Equal(t, tt.left, lrange(store, "list"))
+		})
+	}
+}
+
+func TestPopLastElementDeletesKey(t *testing.T) {
+	store := makeListStore("list", "a")
+	HandlerLPop(Command{Name: "LPOP", Args: []string{"list"}}, store)
+	assert.False(t, store.Exists("list"))
+}
+
+func TestLRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    string
+		stop     string
+		expected []string
+	}{
+		{name: "all", start: "0", stop: "-1", expected: []string{"a", "b", "c", "d"}},
+		{name: "middle", start: "1", stop: "2", expected: []string{"b", "c"}},
+		{name: "negative", start: "-3", stop: "-2", expected: []string{"b", "c"}},
+		{name: "stop past end", start: "2", stop: "100", expected: []string{"c", "d"}},
+		{name: "start before head", start: "-100", stop: "0", expected: []string{"a"}},
+		{name: "start past end", start: "5", stop: "10", expected: []string{}},
+		{name: "start after stop", start: "3", stop: "1", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeListStore("list", "a", "b", "c", "d")
+			resp := HandlerLRange(Command{Name: "LRANGE", Args: []string{"list", tt.start, tt.stop}}, store)
+			assert.Equal(t, enums.ArrayRespType, resp.Type)
+			assert.Equal(t, tt.expected, bulkStrings(resp))
+		})
+	}
+}
+
+func TestLLenAndLIndex(t *testing.T) {
+	store := makeListStore("list", "a", "b", "c")
+
+	assert.Equal(t, int64(3), HandlerLLen(Command{Name: "LLEN", Args: []string{"list"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerLLen(Command{Name: "LLEN", Args: []string{"missing"}}, store).Int)
+
+	lindex := func(index string) common.RespValue {
+		return HandlerLIndex(Command{Name: "LINDEX", Args: []string{"list", index}}, store)
+	}
+	assert.Equal(t, bulkReply("a"), lindex("0"))
+	assert.Equal(t, bulkReply("c"), lindex("-1"))
+	assert.Equal(t, nullBulkReply(), lindex("3"))
+	assert.Equal(t, nullBulkReply(), lindex("-4"))
+	assert.Equal(t, errorReply(notIntegerError), lindex("x"))
+}
+
+func TestLSet(t *testing.T) {
+	store := makeListStore("list", "a", "b", "c")
+
+	lset := func(key, index, value string) common.RespValue {
+		return HandlerLSet(Command{Name: "LSET", Args: []string{key, index, value}}, store)
+	}
+	assert.Equal(t, okReply(), lset("list", "1", "B"))
+	assert.Equal(t, okReply(), lset("list", "-1", "C"))
+	assert.Equal(t, errorReply("ERR index out of range"), lset("list", "3", "x"))
+	assert.Equal(t, errorReply("ERR no such key"), lset("missing", "0", "x"))
+	assert.Equal(t, []string{"a", "B", "C"}, lrange(store, "list"))
+}
+
+func TestLRem(t *testing.T) {
+	store := makeListStore("list", "x", "a", "x", "b", "x")
+
+	resp := HandlerLRem(Command{Name: "LREM", Args: []string{"list", "-2", "x"}}, store)
+	assert.Equal(t, int64(2), resp.Int)
+	assert.Equal(t, []string{"x", "a", "b"}, lrange(store, "list"))
+
+	HandlerLRem(Command{Name: "LREM", Args: []string{"list", "0", "x"}}, store)
+	HandlerLRem(Command{Name: "LREM", Args: []string{"list", "0", "a"}}, store)
+	HandlerLRem(Command{Name: "LREM", Args: []string{"list", "0", "b"}}, store)
+	assert.False(t, store.Exists("list"))
+}
+
+func TestLTrim(t *testing.T) {
+	store := makeListStore("list", "a", "b", "c", "d", "e")
+
+	assert.Equal(t, okReply(), HandlerLTrim(Command{Name: "LTRIM", Args: []string{"list", "1", "-2"}}, store))
+	assert.Equal(t, []string{"b", "c", "d"}, lrange(store, "list"))
+
+	assert.Equal(t, okReply(), HandlerLTrim(Command{Name: "LTRIM", Args: []string{"list", "5", "10"}}, store))
+	assert.False(t, store.Exists("list"))
+}
+
+func TestLInsert(t *testing.T) {
+	store := makeListStore("list", "a", "c")
+
+	linsert := func(args ...string) common.RespValue {
+		return HandlerLInsert(Command{Name: "LINSERT", Args: args}, store)
+	}
+	assert.Equal(t, int64(3), linsert("list", "BEFORE", "c", "b").Int)
+	assert.Equal(t, int64(4), linsert("list", "after", "c", "d").Int)
+	assert.Equal(t, int64(-1), linsert("list", "BEFORE", "zz", "x").Int)
+	assert.Equal(t, int64(0), linsert("missing", "BEFORE", "a", "x").Int)
+	assert.Equal(t, errorReply(syntaxError), linsert("list", "AROUND", "a", "x"))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, lrange(store, "list"))
+}
+
+func TestLMove(t *testing.T) {
+	store := makeListStore("src", "a", "b", "c")
+
+	lmove := func(args ...string) common.RespValue {
+		return HandlerLMove(Command{Name: "LMOVE", Args: args}, store)
+	}
+	assert.Equal(t, bulkReply("c"), lmove("src", "dst", "RIGHT", "LEFT"))
+	assert.Equal(t, bulkReply("a"), lmove("src", "dst", "LEFT", "RIGHT"))
+	assert.Equal(t, []string{"b"}, lrange(store, "src"))
+	assert.Equal(t, []string{"c", "a"}, lrange(store, "dst"))
+
+	// rotating a list onto itself
+	assert.Equal(t, bulkReply("c"), lmove("dst", "dst", "LEFT", "RIGHT"))
+	assert.Equal(t, []string{"a", "c"}, lrange(store, "dst"))
+
+	assert.Equal(t, bulkReply("b"), lmove("src", "dst", "LEFT", "LEFT"))
+	assert.False(t, store.Exists("src"))
+	assert.Equal(t, nullBulkReply(), lmove("src", "dst", "LEFT", "LEFT"))
+	assert.Equal(t, errorReply(syntaxError), lmove("dst", "src", "UP", "LEFT"))
+}
+
+func TestListWrongType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(Command, *keyspace.Keyspace) common.RespValue
+		args    []string
+	}{
+		{name: "LPUSH", handler: HandlerLPush, args: []string{"str", "a"}},
+		{name: "RPUSH", handler: HandlerRPush, args: []string{"str", "a"}},
+		{name: "LPOP", handler: HandlerLPop, args: []string{"str"}},
+		{name: "RPOP", handler: HandlerRPop, args: []string{"str"}},
+		{name: "LRANGE", handler: HandlerLRange, args: []string{"str", "0", "-1"}},
+		{name: "LLEN", handler: HandlerLLen, args: []string{"str"}},
+		{name: "LINDEX", handler: HandlerLIndex, args: []string{"str", "0"}},
+		{name: "LSET", handler: HandlerLSet, args: []string{"str", "0", "a"}},
+		{name: "LREM", handler: HandlerLRem, args: []string{"str", "0", "a"}},
+		{name: "LTRIM", handler: HandlerLTrim, args: []string{"str", "0", "1"}},
+		{name: "LINSERT", handler: HandlerLInsert, args: []string{"str", "BEFORE", "a", "b"}},
+		{name: "LMOVE source", handler: HandlerLMove, args: []string{"str", "list", "LEFT", "LEFT"}},
+		{name: "LMOVE destination", handler: HandlerLMove, args: []string{"list", "str", "LEFT", "LEFT"}},
+		{name: "GET", handler: HandlerGet, args: []string{"list"}},
+		{name: "INCR", handler: HandlerIncr, args: []string{"list"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeListStore("list", "x")
+			store.Set("str", "value")
+			resp := tt.handler(Command{Name: tt.name, Args: tt.args}, store)
+			assert.Equal(t, errorReply(wrongTypeError), resp)
+			assert.Equal(t, []string{"x"}, lrange(store, "list"))
+		})
+	}
+}
+
+func TestSetOverwritesList(t *testing.T) {
+	store := makeListStore("key", "a")
+	HandlerSet(Command{Name: "SET", Args: []string{"key", "value"}}, store)
+	assert.Equal(t, bulkReply("value"), HandlerGet(Command{Name: "GET", Args: []string{"key"}}, store))
+
+	assert.Equal(t, int64(1), HandlerDel(Command{Name: "DEL", Args: []string{"key"}}, store).Int)
+}
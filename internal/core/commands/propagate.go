@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// Propagate returns the commands to log for command, which has just run
+// against store, modified it and returned reply. Replaying them must make
+// the same change whenever they run, so, as in Redis, commands whose
+// effect depends on the clock or on chance are rewritten: relative
+// expiries become PEXPIREAT, SPOP becomes SREM of what it popped, and
+// blocking pops become the plain pop they ended up doing. A RESTORE is
+// pinned to the absolute expiry it resolved to.
+func Propagate(command Command, reply common.RespValue, store *keyspace.Keyspace) []Command {
+	switch enums.StringToCommandName(command.Name) {
+	case enums.SetCommandName:
+		return propagateSet(command, store)
+	case enums.ExpireCommandName, enums.PExpireCommandName, enums.ExpireAtCommandName, enums.PExpireAtCommandName:
+		return []Command{absoluteExpire(command.Args[0], store)}
+	case enums.SPopCommandName:
+		return propagateSPop(command, reply)
+	case enums.BLPopCommandName:
+		return []Command{{Name: "LPOP", Args: []string{reply.Array[0].Str}}}
+	case enums.BRPopCommandName:
+		return []Command{{Name: "RPOP", Args: []string{reply.Array[0].Str}}}
+	case enums.BLMoveCommandName:
+		return []Command{{Name: "LMOVE", Args: command.Args[:4]}}
+	case enums.BZPopMinCommandName:
+		return []Command{{Name: "ZPOPMIN", Args: []string{reply.Array[0].Str}}}
+	case enums.BZPopMaxCommandName:
+		return []Command{{Name: "ZPOPMAX", Args: []string{reply.Array[0].Str}}}
+	case enums.RestoreCommandName, enums.RestoreAskingCommandName:
+		return []Command{propagateRestore(command, store)}
+	default:
+		return []Command{command}
+	}
+}
+
+// propagateSet pins any expiry SET was given to the absolute time it
+// resolved to.
+func propagateSet(command Command, store *keyspace.Keyspace) []Command {
+	relative := false
+	for _, option := range command.Args[2:] {
+		switch strings.ToUpper(option) {
+		case "EX", "PX", "EXAT":
+			relative = true
+		}
+	}
+	if !relative {
+		return []Command{command}
+	}
+
+	key, value := command.Args[0], command.Args[1]
+	when, exists := store.ExpireAt(key)
+	if !exists {
+		return []Command{{Name: "DEL", Args: []string{key}}}
+	}
+	return []Command{{Name: "SET", Args: []string{key, value, "PXAT", strconv.FormatInt(when, 10)}}}
+}
+
+// absoluteExpire returns the command that gives key the TTL it has now, or
+// deletes it if an expiry in the past removed it.
+func absoluteExpire(key string, store *keyspace.Keyspace) Command {
+	when, exists := store.ExpireAt(key)
+	if !exists {
+		return Command{Name: "DEL", Args: []string{key}}
+	}
+	return Command{Name: "PEXPIREAT", Args: []string{key, strconv.FormatInt(when, 10)}}
+}
+
+func propagateSPop(command Command, reply common.RespValue) []Command {
+	args := []string{command.Args[0]}
+	if reply.Type == enums.ArrayRespType {
+		for _, member := range reply.Array {
+			args = append(args, member.Str)
+		}
+	} else {
+		args = append(args, reply.Str)
+	}
+	return []Command{{Name: "SREM", Args: args}}
+}
+
+// propagateRestore returns the RESTORE that recreates the key as it was
+// restored, replacing whatever is there, or the DEL of a key restored
+// with a TTL already past.
+func propagateRestore(command Command, store *keyspace.Keyspace) Command {
+	key, payload := command.Args[0], command.Args[2]
+	if store.LookupRead(key) == nil {
+		return Command{Name: "DEL", Args: []string{key}}
+	}
+	if when, exists := store.ExpireAt(key); exists {
+		return Command{Name: "RESTORE", Args: []string{key, strconv.FormatInt(when, 10), payload, "REPLACE", "ABSTTL"}}
+	}
+	return Command{Name: "RESTORE", Args: []string{key, "0", payload, "REPLACE"}}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(store *keyspace.Keyspace)
+		command  Command
+		expected []Command
+	}{
+		{
+			name:     "deterministic command is logged as is",
+			command:  Command{Name: "LPUSH", Args: []string{"l", "a"}},
+			expected: []Command{{Name: "LPUSH", Args: []string{"l", "a"}}},
+		},
+		{
+			name:     "set with relative expiry",
+			command:  Command{Name: "SET", Args: []string{"k", "v", "ex", "10"}},
+			expected: []Command{{Name: "SET", Args: []string{"k", "v", "PXAT", "1010000"}}},
+		},
+		{
+			name:     "set with absolute expiry",
+			command:  Command{Name: "SET", Args: []string{"k", "v", "PXAT", "2000000"}},
+			expected: []Command{{Name: "SET", Args: []string{"k", "v", "PXAT", "2000000"}}},
+		},
+		{
+			name:     "set expiring at once",
+			command:  Command{Name: "SET", Args: []string{"k", "v", "EXAT", "1"}},
+			expected: []Command{{Name: "DEL", Args: []string{"k"}}},
+		},
+		{
+			name:     "expire",
+			setup:    func(store *keyspace.Keyspace) { store.Set("k", "v") },
+			command:  Command{Name: "EXPIRE", Args: []string{"k", "5", "NX"}},
+			expected: []Command{{Name: "PEXPIREAT", Args: []string{"k", "1005000"}}},
+		},
+		{
+			name:     "expire in the past",
+			setup:    func(store *keyspace.Keyspace) { store.Set("k", "v") },
+			command:  Command{Name: "PEXPIRE", Args: []string{"k", "-1"}},
+			expected: []Command{{Name: "DEL", Args: []string{"k"}}},
+		},
+		{
+			name:     "restore with a ttl",
+			setup:    func(store *keyspace.Keyspace) { store.Set("k", "v"); store.SetExpireAt("k", 1_005_000) },
+			command:  Command{Name: "RESTORE-ASKING", Args: []string{"k", "5000", "payload"}},
+			expected: []Command{{Name: "RESTORE", Args: []string{"k", "1005000", "payload", "REPLACE", "ABSTTL"}}},
+		},
+		{
+			name:     "restore without a ttl",
+			setup:    func(store *keyspace.Keyspace) { store.Set("k", "v") },
+			command:  Command{Name: "RESTORE", Args: []string{"k", "0", "payload"}},
+			expected: []Command{{Name: "RESTORE", Args: []string{"k", "0", "payload", "REPLACE"}}},
+		},
+		{
+			name:     "restore expiring at once",
+			command:  Command{Name: "RESTORE", Args: []string{"k", "1", "payload", "ABSTTL"}},
+			expected: []Command{{Name: "DEL", Args: []string{"k"}}},
+		},
+		{
+			name:     "spop",
+			setup:    func(store *keyspace.Keyspace) { store.SetObject("s", setObject("a")) },
+			command:  Command{Name: "SPOP", Args: []string{"s"}},
+			expected: []Command{{Name: "SREM", Args: []string{"s", "a"}}},
+		},
+		{
+			name:     "spop with count",
+			setup:    func(store *keyspace.Keyspace) { store.SetObject("s", setObject("a")) },
+			command:  Command{Name: "SPOP", Args: []string{"s", "5"}},
+			expected: []Command{{Name: "SREM", Args: []string{"s", "a"}}},
+		},
+		{
+			name:     "blocking pop",
+			setup:    func(store *keyspace.Keyspace) { store.SetObject("b", listObject("x")) },
+			command:  Command{Name: "BRPOP", Args: []string{"a", "b", "0"}},
+			expected: []Command{{Name: "RPOP", Args: []string{"b"}}},
+		},
+		{
+			name:     "blocking move",
+			setup:    func(store *keyspace.Keyspace) { store.SetObject("a", listObject("x")) },
+			command:  Command{Name: "BLMOVE", Args: []string{"a", "b", "LEFT", "RIGHT", "0"}},
+			expected: []Command{{Name: "LMOVE", Args: []string{"a", "b", "LEFT", "RIGHT"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := int64(1_000_000)
+			store := makeClockedStore(&now)
+			if tt.setup != nil {
+				tt.setup(store)
+			}
+			// commands the executor runs itself leave their effect to setup
+			var reply common.RespValue
+			if handler := CommandHandler(tt.command.Name); handler != nil {
+				reply = handler(tt.command, store)
+			}
+			assert.Equal(t, tt.expected, Propagate(tt.command, reply, store))
+		})
+	}
+}
+
+func setObject(members ...string) *keyspace.Object {
+	object := keyspace.NewSetObject()
+	for _, member := range members {
+		object.Set.Add(member)
+	}
+	return object
+}
+
+func listObject(elements ...string) *keyspace.Object {
+	object := keyspace.NewListObject()
+	for _, element := range elements {
+		object.List.PushBack(element)
+	}
+	return object
+}
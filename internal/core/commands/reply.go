@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+const (
+	syntaxError         = "ERR syntax error"
+	notIntegerError     = "ERR value is not an integer or out of range"
+	invalidExpireError  = "ERR invalid expire time in '%s' command"
+	wrongTypeError      = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	noSuchKeyError      = "ERR no such key"
+	mustBePositiveError = "ERR value is out of range, must be positive"
+)
+
+func errorReply(message string) common.RespValue {
+	return common.RespValue{
+		Type: enums.ErrorRespType,
+		Str:  message,
+	}
+}
+
+func wrongArityReply(command Command) common.RespValue {
+	return errorReply(common.WrongNumberOfArgumentsError(command.Name))
+}
+
+func okReply() common.RespValue {
+	return common.RespValue{
+		Type: enums.SimpleStringRespType,
+		Str:  "OK",
+	}
+}
+
+func integerReply(value int64) common.RespValue {
+	return common.RespValue{
+		Type: enums.IntRespType,
+		Int:  value,
+	}
+}
+
+func bulkReply(value string) common.RespValue {
+	return common.RespValue{
+		Type: enums.BulkStringRespType,
+		Str:  value,
+	}
+}
+
+func nullBulkReply() common.RespValue {
+	return common.RespValue{
+		Type:   enums.BulkStringRespType,
+		IsNull: true,
+	}
+}
+
+// arrayReply returns an array of bulk strings.
+func arrayReply(values []string) common.RespValue {
+	elements := make([]*common.RespValue, len(values))
+	for i, value := range values {
+		elements[i] = &common.RespValue{
+			Type: enums.BulkStringRespType,
+			Str:  value,
+		}
+	}
+	return common.RespValue{
+		Type:  enums.ArrayRespType,
+		Array: elements,
+	}
+}
+
+func nullArrayReply() common.RespValue {
+	return common.RespValue{
+		Type:   enums.ArrayRespType,
+		IsNull: true,
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"iter"
+	"strconv"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// rewriteItemsPerCommand caps how many elements one rewritten command
+// carries, as AOF_REWRITE_ITEMS_PER_CMD does in Redis, so a huge value does
+// not become a single huge command.
+const rewriteItemsPerCommand = 64
+
+// Rewrite yields the commands that recreate object at key from nothing,
+// followed by a PEXPIREAT if expireAt is not negative. It is how an AOF
+// rewrite turns the dataset back into a log. The object is only read.
+func Rewrite(key string, object *keyspace.Object, expireAt int64) iter.Seq[Command] {
+	return func(yield func(Command) bool) {
+		var ok bool
+		switch object.Type {
+		case keyspace.StringType:
+			ok = yield(Command{Name: "SET", Args: []string{key, object.Str}})
+		case keyspace.ListType:
+			ok = rewriteItems(yield, "RPUSH", key, object.List.All(), 1)
+		case keyspace.HashType:
+			ok = rewriteItems(yield, "HSET", key, pairs(object.Hash), 2)
+		case keyspace.SetType:
+			ok = rewriteItems(yield, "SADD", key, object.Set.All(), 1)
+		case keyspace.ZSetType:
+			ok = rewriteItems(yield, "ZADD", key, scoredMembers(object.ZSet), 2)
+		}
+		if ok && expireAt >= 0 {
+			yield(Command{Name: "PEXPIREAT", Args: []string{key, strconv.FormatInt(expireAt, 10)}})
+		}
+	}
+}
+
+// rewriteItems yields name commands adding values to key, in batches of
+// rewriteItemsPerCommand items of width arguments each.
+func rewriteItems(yield func(Command) bool, name, key string, values iter.Seq[string], width int) bool {
+	args := []string{key}
+	for value := range values {
+		args = append(args, value)
+		if len(args)-1 == rewriteItemsPerCommand*width {
+			if !yield(Command{Name: name, Args: args}) {
+				return false
+			}
+			args = []string{key}
+		}
+	}
+	if len(args) == 1 {
+		return true
+	}
+	return yield(Command{Name: name, Args: args})
+}
+
+func pairs(hash map[string]string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for field, value := range hash {
+			if !yield(field) || !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// scoredMembers yields score, member, score, member and so on, the order
+// ZADD takes them in.
+func scoredMembers(zset *keyspace.ZSet) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for member, score := range zset.All() {
+			if !yield(formatScore(score)) || !yield(member) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+func TestRewrite(t *testing.T) {
+	long := make([]string, 150)
+	for i := range long {
+		long[i] = strconv.Itoa(i)
+	}
+	hash := keyspace.NewHashObject()
+	hash.Hash["f"] = "v"
+	hash.Hash["g"] = "w"
+	zset := keyspace.NewZSetObject()
+	zset.ZSet.Add("low", math.Inf(-1))
+	zset.ZSet.Add("tiny", 1e-7)
+	zset.ZSet.Add("third", 1.0/3)
+
+	tests := []struct {
+		name     string
+		object   *keyspace.Object
+		expireAt int64
+		commands int
+	}{
+		{"string", keyspace.NewStringObject("v"), -1, 1},
+		{"string with ttl", keyspace.NewStringObject("v"), 5_000_000, 2},
+		{"list in batches", listObject(long...), -1, 3},
+		{"hash", hash, -1, 1},
+		{"intset", setObject(long[:10]...), -1, 1},
+		{"zset", zset, 5_000_000, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := int64(1_000_000)
+			store := makeClockedStore(&now)
+
+			cmds := slices.Collect(Rewrite("k", tt.object, tt.expireAt))
+			assert.Len(t, cmds, tt.commands)
+			for _, command := range cmds {
+				reply := CommandHandler(command.Name)(command, store)
+				require.NotEqual(t, enums.ErrorRespType, reply.Type, reply.Str)
+			}
+
+			assert.Equal(t, contents(tt.object), contents(store.LookupRead("k")))
+			when, exists := store.ExpireAt("k")
+			assert.Equal(t, tt.expireAt >= 0, exists)
+			if exists {
+				assert.Equal(t, tt.expireAt, when)
+			}
+		})
+	}
+}
+
+// contents returns what object holds in a form assert.Equal can compare,
+// since two equal sorted sets rarely have the same skiplist.
+func contents(object *keyspace.Object) any {
+	switch object.Type {
+	case keyspace.ListType:
+		return slices.Collect(object.List.All())
+	case keyspace.HashType:
+		return object.Hash
+	case keyspace.SetType:
+		return slices.Sorted(object.Set.All())
+	case keyspace.ZSetType:
+		return maps.Collect(object.ZSet.All())
+	}
+	return object.Str
+}
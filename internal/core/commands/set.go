@@ -0,0 +1,486 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// HandlerSAdd implements SADD key member [member ...] and returns the number
+// of members that were not already present.
+func HandlerSAdd(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := createSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	var added int64
+	for _, member := range command.Args[1:] {
+		if set.Add(member) {
+			added++
+		}
+	}
+	if added > 0 {
+		store.SignalModified(command.Args[0])
+	}
+	return integerReply(added)
+}
+
+// HandlerSRem implements SREM key member [member ...].
+func HandlerSRem(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	key := command.Args[0]
+	set, ok := writeSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if set == nil {
+		return integerReply(0)
+	}
+
+	var removed int64
+	for _, member := range command.Args[1:] {
+		if set.Remove(member) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptySet(store, key, set)
+	return integerReply(removed)
+}
+
+func HandlerSCard(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := readSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if set == nil {
+		return integerReply(0)
+	}
+	return integerReply(int64(set.Len()))
+}
+
+func HandlerSIsMember(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := readSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if set != nil && set.Contains(command.Args[1]) {
+		return integerReply(1)
+	}
+	return integerReply(0)
+}
+
+// HandlerSMIsMember implements SMISMEMBER key member [member ...], replying
+// with 1 or 0 for each member in order.
+func HandlerSMIsMember(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := readSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	elements := make([]*common.RespValue, 0, len(command.Args)-1)
+	for _, member := range command.Args[1:] {
+		var found int64
+		if set != nil && set.Contains(member) {
+			found = 1
+		}
+		elements = append(elements, &common.RespValue{
+			Type: enums.IntRespType,
+			Int:  found,
+		})
+	}
+	return common.RespValue{
+		Type:  enums.ArrayRespType,
+		Array: elements,
+	}
+}
+
+func HandlerSMembers(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := readSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if set == nil {
+		return arrayReply(nil)
+	}
+	return arrayReply(set.Members())
+}
+
+// HandlerSPop implements SPOP key [count].
+func HandlerSPop(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 1 || len(command.Args) > 2 {
+		return wrongArityReply(command)
+	}
+
+	hasCount := len(command.Args) == 2
+	count := 1
+	if hasCount {
+		parsed, err := strconv.Atoi(command.Args[1])
+		if err != nil || parsed < 0 {
+			return errorReply(mustBePositiveError)
+		}
+		count = parsed
+	}
+
+	key := command.Args[0]
+	set, ok := writeSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if set == nil {
+		if hasCount {
+			return arrayReply(nil)
+		}
+		return nullBulkReply()
+	}
+
+	popped := keyspace.RandomSample(set.All(), count)
+	for _, member := range popped {
+		set.Remove(member)
+	}
+	if len(popped) > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptySet(store, key, set)
+
+	if !hasCount {
+		return bulkReply(popped[0])
+	}
+	return arrayReply(popped)
+}
+
+// HandlerSRandMember implements SRANDMEMBER key [count]. A positive count
+// returns distinct members; a negative one may repeat them.
+func HandlerSRandMember(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 1 || len(command.Args) > 2 {
+		return wrongArityReply(command)
+	}
+
+	set, ok := readSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	if len(command.Args) == 1 {
+		if set == nil {
+			return nullBulkReply()
+		}
+		return bulkReply(keyspace.RandomSample(set.All(), 1)[0])
+	}
+
+	count, err := strconv.Atoi(command.Args[1])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+	switch {
+	case set == nil || count == 0:
+		return arrayReply(nil)
+	case count < 0:
+		return arrayReply(keyspace.RandomSampleWithRepeats(set.All(), -count))
+	default:
+		return arrayReply(keyspace.RandomSample(set.All(), min(count, set.Len())))
+	}
+}
+
+// HandlerSMove implements SMOVE source destination member.
+func HandlerSMove(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+
+	source, destination, member := command.Args[0], command.Args[1], command.Args[2]
+	sourceSet, ok := writeSet(store, source)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	destinationSet, ok := writeSet(store, destination)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if sourceSet == nil || !sourceSet.Contains(member) {
+		return integerReply(0)
+	}
+	if source == destination {
+		return integerReply(1)
+	}
+
+	sourceSet.Remove(member)
+	store.SignalModified(source)
+	deleteIfEmptySet(store, source, sourceSet)
+
+	if destinationSet == nil {
+		destinationSet, _ = createSet(store, destination)
+	}
+	if destinationSet.Add(member) {
+		store.SignalModified(destination)
+	}
+	return integerReply(1)
+}
+
+// HandlerSInter implements SINTER key [key ...].
+func HandlerSInter(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, intersectSets, false)
+}
+
+// HandlerSUnion implements SUNION key [key ...].
+func HandlerSUnion(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, unionSets, false)
+}
+
+// HandlerSDiff implements SDIFF key [key ...], the members of the first set
+// that are in none of the others.
+func HandlerSDiff(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, diffSets, false)
+}
+
+// HandlerSInterStore implements SINTERSTORE destination key [key ...].
+func HandlerSInterStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, intersectSets, true)
+}
+
+// HandlerSUnionStore implements SUNIONSTORE destination key [key ...].
+func HandlerSUnionStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, unionSets, true)
+}
+
+// HandlerSDiffStore implements SDIFFSTORE destination key [key ...].
+func HandlerSDiffStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	return setAlgebraGeneric(command, store, diffSets, true)
+}
+
+// HandlerSInterCard implements SINTERCARD numkeys key [key ...] [LIMIT limit].
+// A non-zero limit stops counting once it is reached.
+func HandlerSInterCard(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	numKeys, err := strconv.Atoi(command.Args[0])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+	if numKeys <= 0 {
+		return errorReply("ERR numkeys should be greater than 0")
+	}
+	if numKeys > len(command.Args)-1 {
+		return errorReply("ERR Number of keys can't be greater than number of args")
+	}
+
+	keys := command.Args[1 : 1+numKeys]
+	limit := 0
+	rest := command.Args[1+numKeys:]
+	for i := 0; i < len(rest); i++ {
+		if !strings.EqualFold(rest[i], "LIMIT") || i+1 >= len(rest) {
+			return errorReply(syntaxError)
+		}
+		i++
+		limit, err = strconv.Atoi(rest[i])
+		if err != nil {
+			return errorReply(notIntegerError)
+		}
+		if limit < 0 {
+			return errorReply("ERR LIMIT can't be negative")
+		}
+	}
+
+	sets, ok := readSets(store, keys)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	return integerReply(int64(len(intersectSetsWithLimit(sets, limit))))
+}
+
+// setAlgebraGeneric runs one of the set algebra operations over the keys in
+// the command, either replying with the result or, when storing, writing it
+// to the destination key and replying with its size.
+func setAlgebraGeneric(command Command, store *keyspace.Keyspace, operation func([]*keyspace.Set) []string, storing bool) common.RespValue {
+	minArgs := 1
+	if storing {
+		minArgs = 2
+	}
+	if len(command.Args) < minArgs {
+		return wrongArityReply(command)
+	}
+
+	keys := command.Args
+	if storing {
+		keys = command.Args[1:]
+	}
+	sets, ok := readSets(store, keys)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	result := operation(sets)
+
+	if !storing {
+		return arrayReply(result)
+	}
+
+	destination := command.Args[0]
+	if len(result) == 0 {
+		store.Delete(destination)
+		return integerReply(0)
+	}
+	object := keyspace.NewSetObject()
+	for _, member := range result {
+		object.Set.Add(member)
+	}
+	store.SetObject(destination, object)
+	return integerReply(int64(len(result)))
+}
+
+func intersectSets(sets []*keyspace.Set) []string {
+	return intersectSetsWithLimit(sets, 0)
+}
+
+// intersectSetsWithLimit walks the smallest set and probes the others, so
+// the cost is bounded by the smallest input. A limit of 0 means no limit.
+func intersectSetsWithLimit(sets []*keyspace.Set, limit int) []string {
+	smallest := -1
+	for i, set := range sets {
+		if set == nil {
+			return []string{}
+		}
+		if smallest == -1 || set.Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+
+	result := []string{}
+	for member := range sets[smallest].All() {
+		inAll := true
+		for i, set := range sets {
+			if i != smallest && !set.Contains(member) {
+				inAll = false
+				break
+			}
+		}
+		if !inAll {
+			continue
+		}
+		result = append(result, member)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+func unionSets(sets []*keyspace.Set) []string {
+	seen := make(map[string]struct{})
+	result := []string{}
+	for _, set := range sets {
+		if set == nil {
+			continue
+		}
+		for member := range set.All() {
+			if _, exists := seen[member]; !exists {
+				seen[member] = struct{}{}
+				result = append(result, member)
+			}
+		}
+	}
+	return result
+}
+
+func diffSets(sets []*keyspace.Set) []string {
+	result := []string{}
+	if sets[0] == nil {
+		return result
+	}
+	for member := range sets[0].All() {
+		found := false
+		for _, other := range sets[1:] {
+			if other != nil && other.Contains(member) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+// readSets loads the set at each key, with nil for missing keys. The
+// boolean is false if any key holds another type.
+func readSets(store *keyspace.Keyspace, keys []string) ([]*keyspace.Set, bool) {
+	sets := make([]*keyspace.Set, len(keys))
+	for i, key := range keys {
+		set, ok := readSet(store, key)
+		if !ok {
+			return nil, false
+		}
+		sets[i] = set
+	}
+	return sets, true
+}
+
+// readSet returns the set stored at key, or nil if the key is missing. The
+// boolean is false when the key holds another type.
+func readSet(store *keyspace.Keyspace, key string) (*keyspace.Set, bool) {
+	return setFromObject(store.LookupRead(key))
+}
+
+// writeSet is readSet for callers that modify the set.
+func writeSet(store *keyspace.Keyspace, key string) (*keyspace.Set, bool) {
+	return setFromObject(store.LookupWrite(key))
+}
+
+// createSet is writeSet for callers that add members, creating an empty set
+// at key if there is none.
+func createSet(store *keyspace.Keyspace, key string) (*keyspace.Set, bool) {
+	set, ok := writeSet(store, key)
+	if !ok || set != nil {
+		return set, ok
+	}
+	object := keyspace.NewSetObject()
+	store.SetObject(key, object)
+	return object.Set, true
+}
+
+func setFromObject(object *keyspace.Object) (*keyspace.Set, bool) {
+	if object == nil {
+		return nil, true
+	}
+	if object.Type != keyspace.SetType {
+		return nil, false
+	}
+	return object.Set, true
+}
+
+func deleteIfEmptySet(store *keyspace.Keyspace, key string, set *keyspace.Set) {
+	if set.Len() == 0 {
+		store.Delete(key)
+	}
+}
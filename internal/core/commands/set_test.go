@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// makeSetStore returns a store holding each key of sets as a set.
+func makeSetStore(sets map[string][]string) *keyspace.Keyspace {
+	store := makeStore()
+	for key, members := range sets {
+		HandlerSAdd(Command{Name: "SADD", Args: append([]string{key}, members...)}, store)
+	}
+	return store
+}
+
+func smembers(store *keyspace.Keyspace, key string) []string {
+	return sorted(bulkStrings(HandlerSMembers(Command{Name: "SMEMBERS", Args: []string{key}}, store)))
+}
+
+func TestSAddSRem(t *testing.T) {
+	store := makeStore()
+
+	assert.Equal(t, int64(3), HandlerSAdd(Command{Name: "SADD", Args: []string{"s", "a", "b", "a", "c"}}, store).Int)
+	assert.Equal(t, int64(1), HandlerSAdd(Command{Name: "SADD", Args: []string{"s", "c", "d"}}, store).Int)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, smembers(store, "s"))
+
+	assert.Equal(t, int64(2), HandlerSRem(Command{Name: "SREM", Args: []string{"s", "a", "b", "zz"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerSRem(Command{Name: "SREM", Args: []string{"missing", "a"}}, store).Int)
+	HandlerSRem(Command{Name: "SREM", Args: []string{"s", "c", "d"}}, store)
+	assert.False(t, store.Exists("s"))
+
+	resp := HandlerSAdd(Command{Name: "SADD", Args: []string{"s"}}, store)
+	assert.Equal(t, common.WrongNumberOfArgumentsError("SADD"), resp.Str)
+}
+
+func TestSAddKeepsIntsetForIntegers(t *testing.T) {
+	store := makeSetStore(map[string][]string{"ids": {"1", "2", "3"}, "tags": {"1", "go"}})
+
+	assert.True(t, store.LookupRead("ids").Set.IsIntset())
+	assert.False(t, store.LookupRead("tags").Set.IsIntset())
+}
+
+func TestSCardSIsMemberSMIsMember(t *testing.T) {
+	store := makeSetStore(map[string][]string{"s": {"a", "b"}})
+
+	assert.Equal(t, int64(2), HandlerSCard(Command{Name: "SCARD", Args: []string{"s"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerSCard(Command{Name: "SCARD", Args: []string{"missing"}}, store).Int)
+	assert.Equal(t, int64(1), HandlerSIsMember(Command{Name: "SISMEMBER", Args: []string{"s", "a"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerSIsMember(Command{Name: "SISMEMBER", Args: []string{"s", "z"}}, store).Int)
+	assert.Equal(t, int64(0), HandlerSIsMember(Command{Name: "SISMEMBER", Args: []string{"missing", "a"}}, store).Int)
+
+	resp := HandlerSMIsMember(Command{Name: "SMISMEMBER", Args: []string{"s", "a", "z", "b"}}, store)
+	found := make([]int64, 0, len(resp.Array))
+	for _, element := range resp.Array {
+		found = append(found, element.Int)
+	}
+	assert.Equal(t, []int64{1, 0, 1}, found)
+}
+
+func TestSPop(t *testing.T) {
+	store := makeSetStore(map[string][]string{"s": {"a", "b", "c"}})
+
+	single := HandlerSPop(Command{Name: "SPOP", Args: []string{"s"}}, store)
+	assert.Contains(t, []string{"a", "b", "c"}, single.Str)
+	assert.Equal(t, int64(2), HandlerSCard(Command{Name: "SCARD", Args: []string{"s"}}, store).Int)
+
+	popped := bulkStrings(HandlerSPop(Command{Name: "SPOP", Args: []string{"s", "5"}}, store))
+	assert.Len(t, popped, 2)
+	assert.NotContains(t, popped, single.Str)
+	assert.False(t, store.Exists("s"))
+
+	assert.Equal(t, nullBulkReply(), HandlerSPop(Command{Name: "SPOP", Args: []string{"s"}}, store))
+	assert.Empty(t, HandlerSPop(Command{Name: "SPOP", Args: []string{"s", "2"}}, store).Array)
+	assert.Equal(t, errorReply(mustBePositiveError), HandlerSPop(Command{Name: "SPOP", Args: []string{"s", "-1"}}, store))
+}
+
+func TestSRandMember(t *testing.T) {
+	store := makeSetStore(map[string][]string{"s": {"1", "2", "3"}})
+	srandmember := func(args ...string) common.RespValue {
+		return HandlerSRandMember(Command{Name: "SRANDMEMBER", Args: args}, store)
+	}
+
+	assert.Contains(t, []string{"1", "2", "3"}, srandmember("s").Str)
+	assert.Equal(t, nullBulkReply(), srandmember("missing"))
+	assert.Equal(t, []string{"1", "2", "3"}, sorted(bulkStrings(srandmember("s", "10"))))
+	assert.Len(t, bulkStrings(srandmember("s", "-7")), 7)
+	distinct := bulkStrings(srandmember("s", "2"))
+	assert.Len(t, distinct, 2)
+	assert.NotEqual(t, distinct[0], distinct[1])
+	assert.Empty(t, srandmember("missing", "3").Array)
+
+	// SRANDMEMBER never modifies the set
+	assert.Equal(t, []string{"1", "2", "3"}, smembers(store, "s"))
+}
+
+func TestSMove(t *testing.T) {
+	store := makeSetStore(map[string][]string{"src": {"a", "b"}, "dst": {"c"}})
+	smove := func(args ...string) int64 {
+		return HandlerSMove(Command{Name: "SMOVE", Args: args}, store).Int
+	}
+
+	assert.Equal(t, int64(1), smove("src", "dst", "a"))
+	assert.Equal(t, int64(0), smove("src", "dst", "zz"))
+	assert.Equal(t, int64(1), smove("src", "src", "b"))
+	assert.Equal(t, int64(1), smove("src", "new", "b"))
+	assert.Equal(t, int64(0), smove("missing", "dst", "a"))
+
+	assert.False(t, store.Exists("src"))
+	assert.Equal(t, []string{"a", "c"}, smembers(store, "dst"))
+	assert.Equal(t, []string{"b"}, smembers(store, "new"))
+}
+
+func TestSetAlgebra(t *testing.T) {
+	sets := map[string][]string{
+		"a": {"1", "2", "3", "x"},
+		"b": {"2", "3", "4"},
+		"c": {"3", "x", "5"},
+	}
+	tests := []struct {
+		name     string
+		handler  func(Command, *keyspace.Keyspace) common.RespValue
+		keys     []string
+		expected []string
+	}{
+		{name: "inter", handler: HandlerSInter, keys: []string{"a", "b", "c"}, expected: []string{"3"}},
+		{name: "inter with missing", handler: HandlerSInter, keys: []string{"a", "missing"}, expected: []string{}},
+		{name: "inter single", handler: HandlerSInter, keys: []string{"b"}, expected: []string{"2", "3", "4"}},
+		{name: "union", handler: HandlerSUnion, keys: []string{"a", "b", "missing"}, expected: []string{"1", "2", "3", "4", "x"}},
+		{name: "diff", handler: HandlerSDiff, keys: []string{"a", "b", "c"}, expected: []string{"1"}},
+		{name: "diff with missing first", handler: HandlerSDiff, keys: []string{"missing", "a"}, expected: []string{}},
+		{name: "diff with missing other", handler: HandlerSDiff, keys: []string{"b", "missing"}, expected: []string{"2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeSetStore(sets)
+			resp := tt.handler(Command{Name: tt.name, Args: tt.keys}, store)
+			assert.Equal(t, tt.expected, sorted(bulkStrings(resp)))
+		})
+	}
+}
+
+func TestSetAlgebraStore(t *testing.T) {
+	store := makeSetStore(map[string][]string{"a": {"1", "2", "3"}, "b": {"2", "3", "y"}})
+	store.Set("dst", "overwritten")
+
+	assert.Equal(t, int64(2), HandlerSInterStore(Command{Name: "SINTERSTORE", Args: []string{"dst", "a", "b"}}, store).Int)
+	assert.Equal(t, []string{"2", "3"}, smembers(store, "dst"))
+	assert.True(t, store.LookupRead("dst").Set.IsIntset())
+
+	assert.Equal(t, int64(4), HandlerSUnionStore(Command{Name: "SUNIONSTORE", Args: []string{"dst", "a", "b"}}, store).Int)
+	assert.Equal(t, []string{"1", "2", "3", "y"}, smembers(store, "dst"))
+
+	assert.Equal(t, int64(1), HandlerSDiffStore(Command{Name: "SDIFFSTORE", Args: []string{"dst", "a", "b"}}, store).Int)
+	assert.Equal(t, []string{"1"}, smembers(store, "dst"))
+
+	// an empty result removes the destination
+	assert.Equal(t, int64(0), HandlerSInterStore(Command{Name: "SINTERSTORE", Args: []string{"dst", "a", "missing"}}, store).Int)
+	assert.False(t, store.Exists("dst"))
+
+	// storing into one of the inputs is allowed
+	assert.Equal(t, int64(4), HandlerSUnionStore(Command{Name: "SUNIONSTORE", Args: []string{"a", "a", "b"}}, store).Int)
+	assert.Equal(t, []string{"1", "2", "3", "y"}, smembers(store, "a"))
+}
+
+func TestSInterCard(t *testing.T) {
+	store := makeSetStore(map[string][]string{"a": {"1", "2", "3", "4"}, "b": {"2", "3", "4", "5"}})
+	sintercard := func(args ...string) common.RespValue {
+		return HandlerSInterCard(Command{Name: "SINTERCARD", Args: args}, store)
+	}
+
+	assert.Equal(t, integerReply(3), sintercard("2", "a", "b"))
+	assert.Equal(t, integerReply(2), sintercard("2", "a", "b", "LIMIT", "2"))
+	assert.Equal(t, integerReply(3), sintercard("2", "a", "b", "limit", "0"))
+	assert.Equal(t, integerReply(0), sintercard("2", "a", "missing"))
+	assert.Equal(t, errorReply("ERR numkeys should be greater than 0"), sintercard("0", "a"))
+	assert.Equal(t, errorReply("ERR Number of keys can't be greater than number of args"), sintercard("3", "a", "b"))
+	assert.Equal(t, errorReply("ERR LIMIT can't be negative"), sintercard("2", "a", "b", "LIMIT", "-1"))
+	assert.Equal(t, errorReply(syntaxError), sintercard("1", "a", "b"))
+}
+
+func TestSetWrongType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(Command, *keyspace.Keyspace) common.RespValue
+		args    []string
+	}{
+		{name: "SADD", handler: HandlerSAdd, args: []string{"str", "a"}},
+		{name: "SREM", handler: HandlerSRem, args: []string{"str", "a"}},
+		{name: "SCARD", handler: HandlerSCard, args: []string{"str"}},
+		{name: "SISMEMBER", handler: HandlerSIsMember, args: []string{"str", "a"}},
+		{name: "SMISMEMBER", handler: HandlerSMIsMember, args: []string{"str", "a"}},
+		{name: "SMEMBERS", handler: HandlerSMembers, args: []string{"str"}},
+		{name: "SPOP", handler: HandlerSPop, args: []string{"str"}},
+		{name: "SRANDMEMBER", handler: HandlerSRandMember, args: []string{"str"}},
+		{name: "SMOVE source", handler: HandlerSMove, args: []string{"str", "set", "a"}},
+		{name: "SMOVE destination", handler: HandlerSMove, args: []string{"set", "str", "a"}},
+		{name: "SINTER", handler: HandlerSInter, args: []string{"set", "str"}},
+		{name: "SUNION", handler: HandlerSUnion, args: []string{"set", "str"}},
+		{name: "SDIFF", handler: HandlerSDiff, args: []string{"set", "str"}},
+		{name: "SINTERSTORE", handler: HandlerSInterStore, args: []string{"dst", "set", "str"}},
+		{name: "SINTERCARD", handler: HandlerSInterCard, args: []string{"2", "set", "str"}},
+		{name: "HGET", handler: HandlerHGet, args: []string{"set", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeSetStore(map[string][]string{"set": {"a"}})
+			store.Set("str", "value")
+			resp := tt.handler(Command{Name: tt.name, Args: tt.args}, store)
+			assert.Equal(t, errorReply(wrongTypeError), resp)
+			assert.Equal(t, []string{"a"}, smembers(store, "set"))
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package commands
+
+import "github.com/suryansh0301/Mnemo/internal/enums"
+
+// commandArity gives the number of arguments each command takes, counting
+// the command name, in the Redis convention: a positive arity is exact and
+// a negative one is a minimum. Handlers still validate their own arguments;
+// the table lets a command be rejected before it runs, as when it is
+// queued inside MULTI.
+var commandArity = map[enums.CommandName]int{
+	enums.PingCommandName:    -1,
+	enums.EchoCommandName:    2,
+	enums.QuitCommandName:    -1,
+	enums.SetCommandName:     -3,
+	enums.GetCommandName:     2,
+	enums.IncrCommandName:    2,
+	enums.DeleteCommandName:  2,
+	enums.PersistCommandName: 2,
+
+	enums.ExpireCommandName:      -3,
+	enums.PExpireCommandName:     -3,
+	enums.ExpireAtCommandName:    -3,
+	enums.PExpireAtCommandName:   -3,
+	enums.TTLCommandName:         2,
+	enums.PTTLCommandName:        2,
+	enums.ExpireTimeCommandName:  2,
+	enums.PExpireTimeCommandName: 2,
+
+	enums.LPushCommandName:   -3,
+	enums.RPushCommandName:   -3,
+	enums.LPopCommandName:    -2,
+	enums.RPopCommandName:    -2,
+	enums.LRangeCommandName:  4,
+	enums.LLenCommandName:    2,
+	enums.LIndexCommandName:  3,
+	enums.LSetCommandName:    4,
+	enums.LRemCommandName:    4,
+	enums.LTrimCommandName:   4,
+	enums.LInsertCommandName: 5,
+	enums.LMoveCommandName:   5,
+
+	enums.HSetCommandName:         -4,
+	enums.HSetNXCommandName:       4,
+	enums.HGetCommandName:         3,
+	enums.HMGetCommandName:        -3,
+	enums.HDelCommandName:         -3,
+	enums.HExistsCommandName:      3,
+	enums.HLenCommandName:         2,
+	enums.HKeysCommandName:        2,
+	enums.HValsCommandName:        2,
+	enums.HGetAllCommandName:      2,
+	enums.HIncrByCommandName:      4,
+	enums.HIncrByFloatCommandName: 4,
+	enums.HStrLenCommandName:      3,
+	enums.HRandFieldCommandName:   -2,
+	enums.HScanCommandName:        -3,
+
+	enums.SAddCommandName:        -3,
+	enums.SRemCommandName:        -3,
+	enums.SCardCommandName:       2,
+	enums.SIsMemberCommandName:   3,
+	enums.SMIsMemberCommandName:  -3,
+	enums.SMembersCommandName:    2,
+	enums.SPopCommandName:        -2,
+	enums.SRandMemberCommandName: -2,
+	enums.SMoveCommandName:       4,
+	enums.SInterCommandName:      -2,
+	enums.SUnionCommandName:      -2,
+	enums.SDiffCommandName:       -2,
+	enums.SInterStoreCommandName: -3,
+	enums.SUnionStoreCommandName: -3,
+	enums.SDiffStoreCommandName:  -3,
+	enums.SInterCardCommandName:  -3,
+
+	enums.ZAddCommandName:             -4,
+	enums.ZIncrByCommandName:          4,
+	enums.ZRemCommandName:             -3,
+	enums.ZScoreCommandName:           3,
+	enums.ZMScoreCommandName:          -3,
+	enums.ZCardCommandName:            2,
+	enums.ZRankCommandName:            -3,
+	enums.ZRevRankCommandName:         -3,
+	enums.ZRangeCommandName:           -4,
+	enums.ZRangeStoreCommandName:      -5,
+	enums.ZCountCommandName:           4,
+	enums.ZLexCountCommandName:        4,
+	enums.ZPopMinCommandName:          -2,
+	enums.ZPopMaxCommandName:          -2,
+	enums.ZRemRangeByRankCommandName:  4,
+	enums.ZRemRangeByScoreCommandName: 4,
+	enums.ZRemRangeByLexCommandName:   4,
+	enums.ZUnionStoreCommandName:      -4,
+	enums.ZInterStoreCommandName:      -4,
+
+	enums.BLPopCommandName:    -3,
+	enums.BRPopCommandName:    -3,
+	enums.BLMoveCommandName:   6,
+	enums.BZPopMinCommandName: -3,
+	enums.BZPopMaxCommandName: -3,
+
+	enums.SubscribeCommandName:    -2,
+	enums.UnsubscribeCommandName:  -1,
+	enums.PSubscribeCommandName:   -2,
+	enums.PUnsubscribeCommandName: -1,
+	enums.PublishCommandName:      3,
+	enums.PubsubCommandName:       -2,
+
+	enums.MultiCommandName:   1,
+	enums.ExecCommandName:    1,
+	enums.DiscardCommandName: 1,
+	enums.WatchCommandName:   -2,
+	enums.UnwatchCommandName: 1,
+
+	enums.BgRewriteAOFCommandName: 1,
+	enums.SaveCommandName:         1,
+	enums.BgSaveCommandName:       -1,
+	enums.LastSaveCommandName:     1,
+
+	enums.ReplicaOfCommandName: 3,
+	enums.SlaveOfCommandName:   3,
+	enums.PSyncCommandName:     3,
+	enums.ReplConfCommandName:  -1,
+	enums.RoleCommandName:      1,
+
+	enums.WaitCommandName:    3,
+	enums.WaitAOFCommandName: 4,
+
+	enums.ClusterCommandName: -2,
+	enums.AskingCommandName:  1,
+
+	enums.DumpCommandName:          2,
+	enums.RestoreCommandName:       -4,
+	enums.RestoreAskingCommandName: -4,
+	enums.MigrateCommandName:       -6,
+
+	enums.ConfigCommandName:  -2,
+	enums.InfoCommandName:    -1,
+	enums.SlowlogCommandName: -2,
+	enums.LatencyCommandName: -2,
+	enums.MonitorCommandName: 1,
+	enums.ClientCommandName:  -2,
+}
+
+// writeCommands lists the commands that may modify the keyspace. When one
+// of them does, it is written to the append-only file.
+var writeCommands = map[enums.CommandName]bool{
+	enums.SetCommandName:     true,
+	enums.IncrCommandName:    true,
+	enums.DeleteCommandName:  true,
+	enums.PersistCommandName: true,
+
+	enums.ExpireCommandName:    true,
+	enums.PExpireCommandName:   true,
+	enums.ExpireAtCommandName:  true,
+	enums.PExpireAtCommandName: true,
+
+	enums.LPushCommandName:   true,
+	enums.RPushCommandName:   true,
+	enums.LPopCommandName:    true,
+	enums.RPopCommandName:    true,
+	enums.LSetCommandName:    true,
+	enums.LRemCommandName:    true,
+	enums.LTrimCommandName:   true,
+	enums.LInsertCommandName: true,
+	enums.LMoveCommandName:   true,
+
+	enums.HSetCommandName:         true,
+	enums.HSetNXCommandName:       true,
+	enums.HDelCommandName:         true,
+	enums.HIncrByCommandName:      true,
+	enums.HIncrByFloatCommandName: true,
+
+	enums.SAddCommandName:        true,
+	enums.SRemCommandName:        true,
+	enums.SPopCommandName:        true,
+	enums.SMoveCommandName:       true,
+	enums.SInterStoreCommandName: true,
+	enums.SUnionStoreCommandName: true,
+	enums.SDiffStoreCommandName:  true,
+
+	enums.ZAddCommandName:             true,
+	enums.ZIncrByCommandName:          true,
+	enums.ZRemCommandName:             true,
+	enums.ZRangeStoreCommandName:      true,
+	enums.ZPopMinCommandName:          true,
+	enums.ZPopMaxCommandName:          true,
+	enums.ZRemRangeByRankCommandName:  true,
+	enums.ZRemRangeByScoreCommandName: true,
+	enums.ZRemRangeByLexCommandName:   true,
+	enums.ZUnionStoreCommandName:      true,
+	enums.ZInterStoreCommandName:      true,
+
+	enums.BLPopCommandName:    true,
+	enums.BRPopCommandName:    true,
+	enums.BLMoveCommandName:   true,
+	enums.BZPopMinCommandName: true,
+	enums.BZPopMaxCommandName: true,
+
+	enums.RestoreCommandName:       true,
+	enums.RestoreAskingCommandName: true,
+	enums.MigrateCommandName:       true,
+}
+
+// IsWriteCommand reports whether the named command may modify the
+// keyspace.
+func IsWriteCommand(commandName string) bool {
+	return writeCommands[enums.StringToCommandName(commandName)]
+}
+
+// KnownCommand reports whether the server implements the named command.
+func KnownCommand(commandName string) bool {
+	_, exists := commandArity[enums.StringToCommandName(commandName)]
+	return exists
+}
+
+// CheckArity reports whether command has a number of arguments its
+// command accepts. Unknown commands never do.
+func CheckArity(command Command) bool {
+	arity, exists := commandArity[enums.StringToCommandName(command.Name)]
+	if !exists {
+		return false
+	}
+	given := len(command.Args) + 1
+	if arity < 0 {
+		return given >= -arity
+	}
+	return given == arity
+}
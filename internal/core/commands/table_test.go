@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckArity(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  Command
+		expected bool
+	}{
+		{"exact", Command{Name: "GET", Args: []string{"k"}}, true},
+		{"exact too few", Command{Name: "GET"}, false},
+		{"exact too many", Command{Name: "get", Args: []string{"a", "b"}}, false},
+		{"minimum", Command{Name: "LPUSH", Args: []string{"k", "a", "b"}}, true},
+		{"below minimum", Command{Name: "LPUSH", Args: []string{"k"}}, false},
+		{"no arguments", Command{Name: "MULTI"}, true},
+		{"unknown command", Command{Name: "NOPE"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CheckArity(tt.command))
+		})
+	}
+}
+
+func TestEveryHandlerHasAnArity(t *testing.T) {
+	for name := range commandsHandler {
+		assert.True(t, KnownCommand(string(name)), "no arity for %s", name)
+	}
+}
+
+func TestEveryWriteCommandIsKnown(t *testing.T) {
+	for name := range writeCommands {
+		assert.True(t, KnownCommand(string(name)), "no arity for %s", name)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  Command
+		expected []string
+	}{
+		{"single key", Command{Name: "SET", Args: []string{"k", "v", "EX", "10"}}, []string{"k"}},
+		{"source and destination", Command{Name: "LMOVE", Args: []string{"a", "b", "LEFT", "RIGHT"}}, []string{"a", "b"}},
+		{"every argument", Command{Name: "SUNIONSTORE", Args: []string{"d", "a", "b"}}, []string{"d", "a", "b"}},
+		{"before the timeout", Command{Name: "BLPOP", Args: []string{"a", "b", "0"}}, []string{"a", "b"}},
+		{"numkeys", Command{Name: "SINTERCARD", Args: []string{"2", "a", "b", "LIMIT", "1"}}, []string{"a", "b"}},
+		{"destination and numkeys", Command{Name: "ZUNIONSTORE", Args: []string{"d", "2", "a", "b", "WEIGHTS", "1", "2"}}, []string{"d", "a", "b"}},
+		{"migrate one key", Command{Name: "MIGRATE", Args: []string{"h", "1", "k", "0", "10"}}, []string{"k"}},
+		{"migrate keys", Command{Name: "MIGRATE", Args: []string{"h", "1", "", "0", "10", "AUTH2", "u", "KEYS", "KEYS", "a", "b"}}, []string{"a", "b"}},
+		{"bad numkeys", Command{Name: "SINTERCARD", Args: []string{"5", "a"}}, nil},
+		{"no keys", Command{Name: "PING"}, nil},
+		{"wrong arity", Command{Name: "GET"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Keys(tt.command))
+		})
+	}
+}
+
+func TestEveryKeySpecIsKnown(t *testing.T) {
+	for name := range commandKeys {
+		assert.True(t, KnownCommand(string(name)), "no arity for %s", name)
+	}
+}
@@ -0,0 +1,944 @@
+package commands
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+const (
+	notFloatError       = "ERR value is not a valid float"
+	scoreIsNaNError     = "ERR resulting score is not a number (NaN)"
+	minMaxNotFloatError = "ERR min or max is not a float"
+	minMaxNotLexError   = "ERR min or max not valid string range item"
+)
+
+// HandlerZAdd implements ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member
+// [score member ...]. With INCR it behaves like ZINCRBY and replies with
+// the new score, or a null bulk string when the options stopped the update.
+func HandlerZAdd(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 3 {
+		return wrongArityReply(command)
+	}
+
+	var nx, xx, gt, lt, ch, incr bool
+	i := 1
+options:
+	for ; i < len(command.Args); i++ {
+		switch strings.ToUpper(command.Args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := command.Args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return errorReply(syntaxError)
+	}
+	if nx && xx {
+		return errorReply("ERR XX and NX options at the same time are not compatible")
+	}
+	if gt && lt || nx && (gt || lt) {
+		return errorReply("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) > 2 {
+		return errorReply("ERR INCR option supports a single increment-element pair")
+	}
+
+	// parse every score first so a bad one leaves the set untouched
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, ok := parseFloat(pairs[2*j])
+		if !ok {
+			return errorReply(notFloatError)
+		}
+		scores[j] = score
+	}
+
+	key := command.Args[0]
+	zset, ok := writeZSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		if xx {
+			if incr {
+				return nullBulkReply()
+			}
+			return integerReply(0)
+		}
+		zset, _ = createZSet(store, key)
+	}
+
+	var added, updated int64
+	var incrScore float64
+	incrApplied := false
+	for j, score := range scores {
+		member := pairs[2*j+1]
+		current, exists := zset.Score(member)
+		if !exists {
+			if xx {
+				continue
+			}
+			zset.Add(member, score)
+			added++
+			incrScore, incrApplied = score, true
+			continue
+		}
+
+		if nx {
+			continue
+		}
+		if incr {
+			score += current
+			if math.IsNaN(score) {
+				return errorReply(scoreIsNaNError)
+			}
+		}
+		if gt && score <= current || lt && score >= current {
+			continue
+		}
+		incrScore, incrApplied = score, true
+		if score != current {
+			zset.Add(member, score)
+			updated++
+		}
+	}
+	if added+updated > 0 {
+		store.SignalModified(key)
+	}
+
+	if incr {
+		if !incrApplied {
+			return nullBulkReply()
+		}
+		return bulkReply(formatScore(incrScore))
+	}
+	if ch {
+		return integerReply(added + updated)
+	}
+	return integerReply(added)
+}
+
+// HandlerZIncrBy implements ZINCRBY key increment member.
+func HandlerZIncrBy(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	increment, ok := parseFloat(command.Args[1])
+	if !ok {
+		return errorReply(notFloatError)
+	}
+
+	zset, ok := createZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	member := command.Args[2]
+	current, _ := zset.Score(member)
+	score := current + increment
+	if math.IsNaN(score) {
+		return errorReply(scoreIsNaNError)
+	}
+	zset.Add(member, score)
+	store.SignalModified(command.Args[0])
+	return bulkReply(formatScore(score))
+}
+
+// HandlerZRem implements ZREM key member [member ...].
+func HandlerZRem(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+
+	key := command.Args[0]
+	zset, ok := writeZSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return integerReply(0)
+	}
+
+	var removed int64
+	for _, member := range command.Args[1:] {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptyZSet(store, key, zset)
+	return integerReply(removed)
+}
+
+func HandlerZScore(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArityReply(command)
+	}
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return nullBulkReply()
+	}
+	score, exists := zset.Score(command.Args[1])
+	if !exists {
+		return nullBulkReply()
+	}
+	return bulkReply(formatScore(score))
+}
+
+// HandlerZMScore implements ZMSCORE key member [member ...], with a null
+// entry for every missing member.
+func HandlerZMScore(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 2 {
+		return wrongArityReply(command)
+	}
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+
+	elements := make([]*common.RespValue, 0, len(command.Args)-1)
+	for _, member := range command.Args[1:] {
+		var score float64
+		exists := false
+		if zset != nil {
+			score, exists = zset.Score(member)
+		}
+		elements = append(elements, nullableBulk(formatScore(score), exists))
+	}
+	return common.RespValue{
+		Type:  enums.ArrayRespType,
+		Array: elements,
+	}
+}
+
+func HandlerZCard(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 1 {
+		return wrongArityReply(command)
+	}
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return integerReply(0)
+	}
+	return integerReply(int64(zset.Len()))
+}
+
+// HandlerZRank implements ZRANK key member [WITHSCORE].
+func HandlerZRank(command Command, store *keyspace.Keyspace) common.RespValue {
+	return rankGeneric(command, store, false)
+}
+
+// HandlerZRevRank implements ZREVRANK key member [WITHSCORE].
+func HandlerZRevRank(command Command, store *keyspace.Keyspace) common.RespValue {
+	return rankGeneric(command, store, true)
+}
+
+// HandlerZRange implements ZRANGE key start stop [BYSCORE|BYLEX] [REV]
+// [LIMIT offset count] [WITHSCORES].
+func HandlerZRange(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 3 {
+		return wrongArityReply(command)
+	}
+	spec, errResp := parseZRangeSpec(command.Args[1:], true)
+	if errResp != nil {
+		return *errResp
+	}
+
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return arrayReply(nil)
+	}
+	return zsetReply(spec.run(zset), spec.withScores)
+}
+
+// HandlerZRangeStore implements ZRANGESTORE dst src min max [BYSCORE|BYLEX]
+// [REV] [LIMIT offset count], replying with the size of the stored set.
+func HandlerZRangeStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) < 4 {
+		return wrongArityReply(command)
+	}
+	spec, errResp := parseZRangeSpec(command.Args[2:], false)
+	if errResp != nil {
+		return *errResp
+	}
+
+	zset, ok := readZSet(store, command.Args[1])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	var result []keyspace.ZMember
+	if zset != nil {
+		result = spec.run(zset)
+	}
+	return storeZSetResult(store, command.Args[0], result)
+}
+
+// HandlerZCount implements ZCOUNT key min max.
+func HandlerZCount(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	r, ok := parseScoreRange(command.Args[1], command.Args[2])
+	if !ok {
+		return errorReply(minMaxNotFloatError)
+	}
+
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return integerReply(0)
+	}
+	return integerReply(int64(zset.CountByScore(r)))
+}
+
+// HandlerZLexCount implements ZLEXCOUNT key min max.
+func HandlerZLexCount(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	r, ok := parseLexRange(command.Args[1], command.Args[2])
+	if !ok {
+		return errorReply(minMaxNotLexError)
+	}
+
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return integerReply(0)
+	}
+	return integerReply(int64(zset.CountByLex(r)))
+}
+
+// HandlerZPopMin implements ZPOPMIN key [count].
+func HandlerZPopMin(command Command, store *keyspace.Keyspace) common.RespValue {
+	return zpopGeneric(command, store, false)
+}
+
+// HandlerZPopMax implements ZPOPMAX key [count].
+func HandlerZPopMax(command Command, store *keyspace.Keyspace) common.RespValue {
+	return zpopGeneric(command, store, true)
+}
+
+// HandlerZRemRangeByRank implements ZREMRANGEBYRANK key start stop.
+func HandlerZRemRangeByRank(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	start, err1 := strconv.Atoi(command.Args[1])
+	stop, err2 := strconv.Atoi(command.Args[2])
+	if err1 != nil || err2 != nil {
+		return errorReply(notIntegerError)
+	}
+	return zremRangeGeneric(command, store, func(zset *keyspace.ZSet) int {
+		start, stop := normalizeRange(start, stop, zset.Len())
+		return zset.RemoveRangeByRank(start, stop)
+	})
+}
+
+// HandlerZRemRangeByScore implements ZREMRANGEBYSCORE key min max.
+func HandlerZRemRangeByScore(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	r, ok := parseScoreRange(command.Args[1], command.Args[2])
+	if !ok {
+		return errorReply(minMaxNotFloatError)
+	}
+	return zremRangeGeneric(command, store, func(zset *keyspace.ZSet) int {
+		return zset.RemoveRangeByScore(r)
+	})
+}
+
+// HandlerZRemRangeByLex implements ZREMRANGEBYLEX key min max.
+func HandlerZRemRangeByLex(command Command, store *keyspace.Keyspace) common.RespValue {
+	if len(command.Args) != 3 {
+		return wrongArityReply(command)
+	}
+	r, ok := parseLexRange(command.Args[1], command.Args[2])
+	if !ok {
+		return errorReply(minMaxNotLexError)
+	}
+	return zremRangeGeneric(command, store, func(zset *keyspace.ZSet) int {
+		return zset.RemoveRangeByLex(r)
+	})
+}
+
+// HandlerZUnionStore implements ZUNIONSTORE destination numkeys key
+// [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX].
+func HandlerZUnionStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	return zsetStoreGeneric(command, store, true)
+}
+
+// HandlerZInterStore implements ZINTERSTORE with the same arguments as
+// ZUNIONSTORE.
+func HandlerZInterStore(command Command, store *keyspace.Keyspace) common.RespValue {
+	return zsetStoreGeneric(command, store, false)
+}
+
+func rankGeneric(command Command, store *keyspace.Keyspace, reverse bool) common.RespValue {
+	if len(command.Args) < 2 || len(command.Args) > 3 {
+		return wrongArityReply(command)
+	}
+	withScore := len(command.Args) == 3
+	if withScore && strings.ToUpper(command.Args[2]) != "WITHSCORE" {
+		return errorReply(syntaxError)
+	}
+
+	zset, ok := readZSet(store, command.Args[0])
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	var rank int
+	exists := false
+	if zset != nil {
+		rank, exists = zset.Rank(command.Args[1], reverse)
+	}
+	if !exists {
+		if withScore {
+			return nullArrayReply()
+		}
+		return nullBulkReply()
+	}
+
+	if !withScore {
+		return integerReply(int64(rank))
+	}
+	score, _ := zset.Score(command.Args[1])
+	return common.RespValue{
+		Type: enums.ArrayRespType,
+		Array: []*common.RespValue{
+			{Type: enums.IntRespType, Int: int64(rank)},
+			{Type: enums.BulkStringRespType, Str: formatScore(score)},
+		},
+	}
+}
+
+// zpopGeneric pops the lowest or highest scoring members. The reply is a
+// flat member, score array whether or not a count was given.
+func zpopGeneric(command Command, store *keyspace.Keyspace, highest bool) common.RespValue {
+	if len(command.Args) < 1 || len(command.Args) > 2 {
+		return wrongArityReply(command)
+	}
+	count := 1
+	if len(command.Args) == 2 {
+		parsed, err := strconv.Atoi(command.Args[1])
+		if err != nil || parsed < 0 {
+			return errorReply(mustBePositiveError)
+		}
+		count = parsed
+	}
+
+	key := command.Args[0]
+	zset, ok := writeZSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil || count == 0 {
+		return arrayReply(nil)
+	}
+
+	var popped []keyspace.ZMember
+	if highest {
+		popped = zset.PopMax(count)
+	} else {
+		popped = zset.PopMin(count)
+	}
+	store.SignalModified(key)
+	deleteIfEmptyZSet(store, key, zset)
+	return zsetReply(popped, true)
+}
+
+func zremRangeGeneric(command Command, store *keyspace.Keyspace, remove func(*keyspace.ZSet) int) common.RespValue {
+	key := command.Args[0]
+	zset, ok := writeZSet(store, key)
+	if !ok {
+		return errorReply(wrongTypeError)
+	}
+	if zset == nil {
+		return integerReply(0)
+	}
+	removed := remove(zset)
+	if removed > 0 {
+		store.SignalModified(key)
+	}
+	deleteIfEmptyZSet(store, key, zset)
+	return integerReply(int64(removed))
+}
+
+type zrangeKind int
+
+const (
+	zrangeByRank zrangeKind = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeSpec is a parsed ZRANGE or ZRANGESTORE query.
+type zrangeSpec struct {
+	kind        zrangeKind
+	reverse     bool
+	withScores  bool
+	start, stop int
+	scores      keyspace.ScoreRange
+	lex         keyspace.LexRange
+	offset      int
+	count       int
+}
+
+// parseZRangeSpec parses the min, max and options of a ZRANGE-style
+// command. With REV and BYSCORE or BYLEX, the first bound is the maximum,
+// as in Redis.
+func parseZRangeSpec(args []string, allowWithScores bool) (zrangeSpec, *common.RespValue) {
+	spec := zrangeSpec{count: -1}
+	hasLimit := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			spec.kind = zrangeByScore
+		case "BYLEX":
+			spec.kind = zrangeByLex
+		case "REV":
+			spec.reverse = true
+		case "WITHSCORES":
+			if !allowWithScores {
+				resp := errorReply(syntaxError)
+				return spec, &resp
+			}
+			spec.withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				resp := errorReply(syntaxError)
+				return spec, &resp
+			}
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				resp := errorReply(notIntegerError)
+				return spec, &resp
+			}
+			spec.offset, spec.count = offset, count
+			hasLimit = true
+			i += 2
+		default:
+			resp := errorReply(syntaxError)
+			return spec, &resp
+		}
+	}
+
+	if hasLimit && spec.kind == zrangeByRank {
+		resp := errorReply("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+		return spec, &resp
+	}
+	if spec.withScores && spec.kind == zrangeByLex {
+		resp := errorReply("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+		return spec, &resp
+	}
+
+	lower, upper := args[0], args[1]
+	if spec.reverse && spec.kind != zrangeByRank {
+		lower, upper = upper, lower
+	}
+	switch spec.kind {
+	case zrangeByRank:
+		start, err1 := strconv.Atoi(lower)
+		stop, err2 := strconv.Atoi(upper)
+		if err1 != nil || err2 != nil {
+			resp := errorReply(notIntegerError)
+			return spec, &resp
+		}
+		spec.start, spec.stop = start, stop
+	case zrangeByScore:
+		r, ok := parseScoreRange(lower, upper)
+		if !ok {
+			resp := errorReply(minMaxNotFloatError)
+			return spec, &resp
+		}
+		spec.scores = r
+	case zrangeByLex:
+		r, ok := parseLexRange(lower, upper)
+		if !ok {
+			resp := errorReply(minMaxNotLexError)
+			return spec, &resp
+		}
+		spec.lex = r
+	}
+	return spec, nil
+}
+
+func (spec zrangeSpec) run(zset *keyspace.ZSet) []keyspace.ZMember {
+	switch spec.kind {
+	case zrangeByScore:
+		return zset.RangeByScore(spec.scores, spec.reverse, spec.offset, spec.count)
+	case zrangeByLex:
+		return zset.RangeByLex(spec.lex, spec.reverse, spec.offset, spec.count)
+	default:
+		start, stop := normalizeRange(spec.start, spec.stop, zset.Len())
+		return zset.Range(start, stop, spec.reverse)
+	}
+}
+
+// zsetInput is a ZUNIONSTORE or ZINTERSTORE source. Plain sets are
+// accepted too, with every member scoring 1.
+type zsetInput struct {
+	zset *keyspace.ZSet
+	set  *keyspace.Set
+}
+
+func (in zsetInput) missing() bool {
+	return in.zset == nil && in.set == nil
+}
+
+func (in zsetInput) len() int {
+	switch {
+	case in.zset != nil:
+		return in.zset.Len()
+	case in.set != nil:
+		return in.set.Len()
+	default:
+		return 0
+	}
+}
+
+func (in zsetInput) score(member string) (float64, bool) {
+	switch {
+	case in.zset != nil:
+		return in.zset.Score(member)
+	case in.set != nil:
+		return 1, in.set.Contains(member)
+	default:
+		return 0, false
+	}
+}
+
+func (in zsetInput) all() iter.Seq2[string, float64] {
+	return func(yield func(string, float64) bool) {
+		switch {
+		case in.zset != nil:
+			for member, score := range in.zset.All() {
+				if !yield(member, score) {
+					return
+				}
+			}
+		case in.set != nil:
+			for member := range in.set.All() {
+				if !yield(member, 1) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func zsetStoreGeneric(command Command, store *keyspace.Keyspace, union bool) common.RespValue {
+	if len(command.Args) < 3 {
+		return wrongArityReply(command)
+	}
+	numKeys, err := strconv.Atoi(command.Args[1])
+	if err != nil {
+		return errorReply(notIntegerError)
+	}
+	if numKeys < 1 {
+		return errorReply(fmt.Sprintf("ERR at least 1 input key is needed for '%s' command", strings.ToLower(command.Name)))
+	}
+	if numKeys > len(command.Args)-2 {
+		return errorReply(syntaxError)
+	}
+
+	keys := command.Args[2 : 2+numKeys]
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	aggregate := aggregateSum
+	rest := command.Args[2+numKeys:]
+	for i := 0; i < len(rest); i++ {
+		switch strings.ToUpper(rest[i]) {
+		case "WEIGHTS":
+			if i+numKeys >= len(rest) {
+				return errorReply(syntaxError)
+			}
+			for j := range weights {
+				weight, ok := parseFloat(rest[i+1+j])
+				if !ok {
+					return errorReply("ERR weight value is not a float")
+				}
+				weights[j] = weight
+			}
+			i += numKeys
+		case "AGGREGATE":
+			if i+1 >= len(rest) {
+				return errorReply(syntaxError)
+			}
+			switch strings.ToUpper(rest[i+1]) {
+			case "SUM":
+				aggregate = aggregateSum
+			case "MIN":
+				aggregate = math.Min
+			case "MAX":
+				aggregate = math.Max
+			default:
+				return errorReply(syntaxError)
+			}
+			i++
+		default:
+			return errorReply(syntaxError)
+		}
+	}
+
+	inputs := make([]zsetInput, numKeys)
+	for i, key := range keys {
+		object := store.LookupRead(key)
+		switch {
+		case object == nil:
+		case object.Type == keyspace.ZSetType:
+			inputs[i].zset = object.ZSet
+		case object.Type == keyspace.SetType:
+			inputs[i].set = object.Set
+		default:
+			return errorReply(wrongTypeError)
+		}
+	}
+
+	var result []keyspace.ZMember
+	if union {
+		result = unionZSets(inputs, weights, aggregate)
+	} else {
+		result = intersectZSets(inputs, weights, aggregate)
+	}
+	return storeZSetResult(store, command.Args[0], result)
+}
+
+func unionZSets(inputs []zsetInput, weights []float64, aggregate func(float64, float64) float64) []keyspace.ZMember {
+	index := make(map[string]int)
+	result := []keyspace.ZMember{}
+	for i, input := range inputs {
+		for member, score := range input.all() {
+			score = weightedScore(score, weights[i])
+			if position, seen := index[member]; seen {
+				result[position].Score = aggregate(result[position].Score, score)
+				continue
+			}
+			index[member] = len(result)
+			result = append(result, keyspace.ZMember{Member: member, Score: score})
+		}
+	}
+	return result
+}
+
+// intersectZSets walks the smallest input and probes the others, combining
+// scores in input order.
+func intersectZSets(inputs []zsetInput, weights []float64, aggregate func(float64, float64) float64) []keyspace.ZMember {
+	result := []keyspace.ZMember{}
+	smallest := 0
+	for i, input := range inputs {
+		if input.missing() {
+			return result
+		}
+		if input.len() < inputs[smallest].len() {
+			smallest = i
+		}
+	}
+
+	for member := range inputs[smallest].all() {
+		var combined float64
+		inAll := true
+		for i, input := range inputs {
+			score, exists := input.score(member)
+			if !exists {
+				inAll = false
+				break
+			}
+			score = weightedScore(score, weights[i])
+			if i == 0 {
+				combined = score
+			} else {
+				combined = aggregate(combined, score)
+			}
+		}
+		if inAll {
+			result = append(result, keyspace.ZMember{Member: member, Score: combined})
+		}
+	}
+	return result
+}
+
+// weightedScore multiplies a score by its weight, treating the NaN that
+// inf * 0 produces as 0 like Redis does.
+func weightedScore(score, weight float64) float64 {
+	weighted := score * weight
+	if math.IsNaN(weighted) {
+		return 0
+	}
+	return weighted
+}
+
+func aggregateSum(a, b float64) float64 {
+	sum := a + b
+	// inf + -inf
+	if math.IsNaN(sum) {
+		return 0
+	}
+	return sum
+}
+
+// storeZSetResult replaces destination with a sorted set of members and
+// replies with its size. An empty result deletes destination instead.
+func storeZSetResult(store *keyspace.Keyspace, destination string, members []keyspace.ZMember) common.RespValue {
+	if len(members) == 0 {
+		store.Delete(destination)
+		return integerReply(0)
+	}
+	object := keyspace.NewZSetObject()
+	for _, member := range members {
+		object.ZSet.Add(member.Member, member.Score)
+	}
+	store.SetObject(destination, object)
+	return integerReply(int64(object.ZSet.Len()))
+}
+
+// zsetReply returns the members as a flat array, each followed by its score
+// when withScores is set.
+func zsetReply(members []keyspace.ZMember, withScores bool) common.RespValue {
+	values := make([]string, 0, len(members)*2)
+	for _, member := range members {
+		values = append(values, member.Member)
+		if withScores {
+			values = append(values, formatScore(member.Score))
+		}
+	}
+	return arrayReply(values)
+}
+
+// formatScore prints a score the way Redis does: the shortest decimal that
+// round-trips, switching to exponent notation only for very large or very
+// small magnitudes, and "inf" or "-inf" for infinities.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	abs := math.Abs(score)
+	if abs != 0 && (abs < 1e-5 || abs >= 1e21) {
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// parseScoreRange parses ZRANGEBYSCORE-style bounds, where a leading "("
+// makes a bound exclusive and "-inf" and "+inf" are accepted.
+func parseScoreRange(lower, upper string) (keyspace.ScoreRange, bool) {
+	var r keyspace.ScoreRange
+	var ok1, ok2 bool
+	r.Min, r.MinExclusive, ok1 = parseScoreBound(lower)
+	r.Max, r.MaxExclusive, ok2 = parseScoreBound(upper)
+	return r, ok1 && ok2
+}
+
+func parseScoreBound(bound string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(bound, "(")
+	if exclusive {
+		bound = bound[1:]
+	}
+	value, ok := parseFloat(bound)
+	return value, exclusive, ok
+}
+
+// parseLexRange parses ZRANGEBYLEX-style bounds: "[" or "(" followed by a
+// member for an inclusive or exclusive bound, or "-" and "+" for the
+// infinities.
+func parseLexRange(lower, upper string) (keyspace.LexRange, bool) {
+	var r keyspace.LexRange
+	var ok1, ok2 bool
+	r.Min, ok1 = parseLexBound(lower)
+	r.Max, ok2 = parseLexBound(upper)
+	return r, ok1 && ok2
+}
+
+func parseLexBound(bound string) (keyspace.LexBound, bool) {
+	switch {
+	case bound == "-":
+		return keyspace.LexBound{Infinite: -1}, true
+	case bound == "+":
+		return keyspace.LexBound{Infinite: 1}, true
+	case strings.HasPrefix(bound, "("):
+		return keyspace.LexBound{Value: bound[1:], Exclusive: true}, true
+	case strings.HasPrefix(bound, "["):
+		return keyspace.LexBound{Value: bound[1:]}, true
+	default:
+		return keyspace.LexBound{}, false
+	}
+}
+
+// readZSet returns the sorted set stored at key, or nil if the key is
+// missing. The boolean is false when the key holds another type.
+func readZSet(store *keyspace.Keyspace, key string) (*keyspace.ZSet, bool) {
+	return zsetFromObject(store.LookupRead(key))
+}
+
+// writeZSet is readZSet for callers that modify the sorted set.
+func writeZSet(store *keyspace.Keyspace, key string) (*keyspace.ZSet, bool) {
+	return zsetFromObject(store.LookupWrite(key))
+}
+
+// createZSet is writeZSet for callers that add members, creating an empty
+// sorted set at key if there is none.
+func createZSet(store *keyspace.Keyspace, key string) (*keyspace.ZSet, bool) {
+	zset, ok := writeZSet(store, key)
+	if !ok || zset != nil {
+		return zset, ok
+	}
+	object := keyspace.NewZSetObject()
+	store.SetObject(key, object)
+	return object.ZSet, true
+}
+
+func zsetFromObject(object *keyspace.Object) (*keyspace.ZSet, bool) {
+	if object == nil {
+		return nil, true
+	}
+	if object.Type != keyspace.ZSetType {
+		return nil, false
+	}
+	return object.ZSet, true
+}
+
+func deleteIfEmptyZSet(store *keyspace.Keyspace, key string, zset *keyspace.ZSet) {
+	if zset.Len() == 0 {
+		store.Delete(key)
+	}
+}
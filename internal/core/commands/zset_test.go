@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// makeZSetStore returns a store where key holds the given score, member
+// pairs.
+func makeZSetStore(key string, pairs ...string) *keyspace.Keyspace {
+	store := makeStore()
+	HandlerZAdd(Command{Name: "ZADD", Args: append([]string{key}, pairs...)}, store)
+	return store
+}
+
+func zrange(store *keyspace.Keyspace, args ...string) []string {
+	return bulkStrings(HandlerZRange(Command{Name: "ZRANGE", Args: args}, store))
+}
+
+func TestZAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected common.RespValue
+		members  []string
+	}{
+		{name: "adds new members", args: []string{"z", "4", "d"}, expected: integerReply(1), members: []string{"a", "1", "b", "2", "c", "3", "d", "4"}},
+		{name: "updates without counting", args: []string{"z", "10", "a", "5", "e"}, expected: integerReply(1), members: []string{"b", "2", "c", "3", "e", "5", "a", "10"}},
+		{name: "CH counts updates", args: []string{"z", "CH", "10", "a", "2", "b"}, expected: integerReply(1), members: []string{"b", "2", "c", "3", "a", "10"}},
+		{name: "NX only adds", args: []string{"z", "NX", "10", "a", "4", "d"}, expected: integerReply(1), members: []string{"a", "1", "b", "2", "c", "3", "d", "4"}},
+		{name: "XX only updates", args: []string{"z", "XX", "CH", "10", "a", "4", "d"}, expected: integerReply(1), members: []string{"b", "2", "c", "3", "a", "10"}},
+		{name: "GT only raises", args: []string{"z", "GT", "CH", "0", "a", "5", "b", "1", "d"}, expected: integerReply(2), members: []string{"a", "1", "d", "1", "c", "3", "b", "5"}},
+		{name: "LT only lowers", args: []string{"z", "LT", "CH", "0", "a", "5", "b"}, expected: integerReply(1), members: []string{"a", "0", "b", "2", "c", "3"}},
+		{name: "INCR", args: []string{"z", "INCR", "2.5", "a"}, expected: bulkReply("3.5"), members: []string{"b", "2", "c", "3", "a", "3.5"}},
+		{name: "INCR blocked by GT", args: []string{"z", "GT", "INCR", "-1", "a"}, expected: nullBulkReply(), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "INCR blocked by NX", args: []string{"z", "NX", "INCR", "1", "a"}, expected: nullBulkReply(), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "infinite scores", args: []string{"z", "-inf", "low", "+inf", "high"}, expected: integerReply(2), members: []string{"low", "-inf", "a", "1", "b", "2", "c", "3", "high", "inf"}},
+		{name: "bad score changes nothing", args: []string{"z", "9", "a", "x", "b"}, expected: errorReply(notFloatError), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "NaN score", args: []string{"z", "nan", "a"}, expected: errorReply(notFloatError), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "odd pairs", args: []string{"z", "1", "a", "2"}, expected: errorReply(syntaxError), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "no pairs after options", args: []string{"z", "NX", "CH"}, expected: errorReply(syntaxError), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "NX and XX", args: []string{"z", "NX", "XX", "1", "a"}, expected: errorReply("ERR XX and NX options at the same time are not compatible"), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "GT and LT", args: []string{"z", "GT", "LT", "1", "a"}, expected: errorReply("ERR GT, LT, and/or NX options at the same time are not compatible"), members: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "INCR with many pairs", args: []string{"z", "INCR", "1", "a", "2", "b"}, expected: errorReply("ERR INCR option supports a single increment-element pair"), members: []string{"a", "1", "b", "2", "c", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeZSetStore("z", "1", "a", "2", "b", "3", "c")
+			resp := HandlerZAdd(Command{Name: "ZADD", Args: tt.args}, store)
+			assert.Equal(t, tt.expected, resp)
+			assert.Equal(t, tt.members, zrange(store, "z", "0", "-1", "WITHSCORES"))
+		})
+	}
+}
+
+func TestZAddIncrToNaN(t *testing.T) {
+	store := makeZSetStore("z", "+inf", "high")
+
+	resp := HandlerZAdd(Command{Name: "ZADD", Args: []string{"z", "INCR", "-inf", "high"}}, store)
+	assert.Equal(t, errorReply(scoreIsNaNError), resp)
+	resp = HandlerZIncrBy(Command{Name: "ZINCRBY", Args: []string{"z", "-inf", "high"}}, store)
+	assert.Equal(t, errorReply(scoreIsNaNError), resp)
+	assert.Equal(t, []string{"high", "inf"}, zrange(store, "z", "0", "-1", "WITHSCORES"))
+}
+
+func TestZAddXXOnMissingKey(t *testing.T) {
+	store := makeStore()
+	assert.Equal(t, integerReply(0), HandlerZAdd(Command{Name: "ZADD", Args: []string{"z", "XX", "1", "a"}}, store))
+	assert.Equal(t, nullBulkReply(), HandlerZAdd(Command{Name: "ZADD", Args: []string{"z", "XX", "INCR", "1", "a"}}, store))
+	assert.False(t, store.Exists("z"))
+}
+
+func TestZScoreZMScoreZIncrBy(t *testing.T) {
+	store := makeZSetStore("z", "1.5", "a", "2", "b")
+
+	assert.Equal(t, bulkReply("1.5"), HandlerZScore(Command{Name: "ZSCORE", Args: []string{"z", "a"}}, store))
+	assert.Equal(t, nullBulkReply(), HandlerZScore(Command{Name: "ZSCORE", Args: []string{"z", "x"}}, store))
+	assert.Equal(t, nullBulkReply(), HandlerZScore(Command{Name: "ZSCORE", Args: []string{"missing", "a"}}, store))
+
+	resp := HandlerZMScore(Command{Name: "ZMSCORE", Args: []string{"z", "b", "x", "a"}}, store)
+	assert.Len(t, resp.Array, 3)
+	assert.Equal(t, "2", resp.Array[0].Str)
+	assert.True(t, resp.Array[1].IsNull)
+	assert.Equal(t, "1.5", resp.Array[2].Str)
+
+	assert.Equal(t, bulkReply("4"), HandlerZIncrBy(Command{Name: "ZINCRBY", Args: []string{"z", "2.5", "a"}}, store))
+	assert.Equal(t, bulkReply("-1"), HandlerZIncrBy(Command{Name: "ZINCRBY", Args: []string{"z", "-1", "new"}}, store))
+	assert.Equal(t, errorReply(notFloatError), HandlerZIncrBy(Command{Name: "ZINCRBY", Args: []string{"z", "abc", "a"}}, store))
+	assert.Equal(t, []string{"new", "b", "a"}, zrange(store, "z", "0", "-1"))
+}
+
+func TestZRemZCard(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b")
+
+	assert.Equal(t, integerReply(2), HandlerZCard(Command{Name: "ZCARD", Args: []string{"z"}}, store))
+	assert.Equal(t, integerReply(1), HandlerZRem(Command{Name: "ZREM", Args: []string{"z", "a", "x"}}, store))
+	assert.Equal(t, integerReply(1), HandlerZRem(Command{Name: "ZREM", Args: []string{"z", "b"}}, store))
+	assert.False(t, store.Exists("z"))
+	assert.Equal(t, integerReply(0), HandlerZCard(Command{Name: "ZCARD", Args: []string{"z"}}, store))
+}
+
+func TestZRank(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b", "3", "c")
+
+	assert.Equal(t, integerReply(0), HandlerZRank(Command{Name: "ZRANK", Args: []string{"z", "a"}}, store))
+	assert.Equal(t, integerReply(2), HandlerZRevRank(Command{Name: "ZREVRANK", Args: []string{"z", "a"}}, store))
+	assert.Equal(t, nullBulkReply(), HandlerZRank(Command{Name: "ZRANK", Args: []string{"z", "x"}}, store))
+	assert.Equal(t, nullArrayReply(), HandlerZRank(Command{Name: "ZRANK", Args: []string{"z", "x", "WITHSCORE"}}, store))
+
+	resp := HandlerZRank(Command{Name: "ZRANK", Args: []string{"z", "b", "withscore"}}, store)
+	assert.Equal(t, int64(1), resp.Array[0].Int)
+	assert.Equal(t, "2", resp.Array[1].Str)
+
+	assert.Equal(t, errorReply(syntaxError), HandlerZRank(Command{Name: "ZRANK", Args: []string{"z", "a", "BAD"}}, store))
+}
+
+func TestZRange(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e")
+	lexStore := makeZSetStore("lex", "0", "a", "0", "b", "0", "c", "0", "d")
+
+	tests := []struct {
+		name     string
+		store    *keyspace.Keyspace
+		args     []string
+		expected []string
+	}{
+		{name: "by rank", store: store, args: []string{"z", "1", "3"}, expected: []string{"b", "c", "d"}},
+		{name: "negative ranks", store: store, args: []string{"z", "-2", "-1"}, expected: []string{"d", "e"}},
+		{name: "rank with scores", store: store, args: []string{"z", "0", "1", "WITHSCORES"}, expected: []string{"a", "1", "b", "2"}},
+		{name: "rank rev", store: store, args: []string{"z", "0", "1", "REV"}, expected: []string{"e", "d"}},
+		{name: "out of range", store: store, args: []string{"z", "10", "20"}, expected: []string{}},
+		{name: "by score", store: store, args: []string{"z", "2", "4", "BYSCORE"}, expected: []string{"b", "c", "d"}},
+		{name: "by score exclusive", store: store, args: []string{"z", "(2", "(4", "BYSCORE"}, expected: []string{"c"}},
+		{name: "by score infinite", store: store, args: []string{"z", "-inf", "+inf", "BYSCORE", "LIMIT", "1", "2"}, expected: []string{"b", "c"}},
+		{name: "by score rev", store: store, args: []string{"z", "4", "2", "BYSCORE", "REV", "WITHSCORES"}, expected: []string{"d", "4", "c", "3", "b", "2"}},
+		{name: "by score negative count", store: store, args: []string{"z", "0", "10", "BYSCORE", "LIMIT", "3", "-1"}, expected: []string{"d", "e"}},
+		{name: "by score negative offset", store: store, args: []string{"z", "0", "10", "BYSCORE", "LIMIT", "-1", "2"}, expected: []string{}},
+		{name: "by lex", store: lexStore, args: []string{"lex", "[b", "(d", "BYLEX"}, expected: []string{"b", "c"}},
+		{name: "by lex rev", store: lexStore, args: []string{"lex", "+", "-", "BYLEX", "REV", "LIMIT", "0", "2"}, expected: []string{"d", "c"}},
+		{name: "missing key", store: store, args: []string{"missing", "0", "-1"}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, zrange(tt.store, tt.args...))
+		})
+	}
+}
+
+func TestZRangeErrors(t *testing.T) {
+	store := makeZSetStore("z", "1", "a")
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"z", "0", "-1", "LIMIT", "0", "1"}, expected: "ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"},
+		{args: []string{"z", "-", "+", "BYLEX", "WITHSCORES"}, expected: "ERR syntax error, WITHSCORES not supported in combination with BYLEX"},
+		{args: []string{"z", "a", "b", "BYSCORE"}, expected: minMaxNotFloatError},
+		{args: []string{"z", "a", "b", "BYLEX"}, expected: minMaxNotLexError},
+		{args: []string{"z", "x", "1"}, expected: notIntegerError},
+		{args: []string{"z", "0", "1", "BOGUS"}, expected: syntaxError},
+		{args: []string{"z", "0", "1", "BYSCORE", "LIMIT", "0"}, expected: syntaxError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, errorReply(tt.expected), HandlerZRange(Command{Name: "ZRANGE", Args: tt.args}, store))
+		})
+	}
+}
+
+func TestZRangeStore(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b", "3", "c")
+
+	assert.Equal(t, integerReply(2), HandlerZRangeStore(Command{Name: "ZRANGESTORE", Args: []string{"dst", "z", "(1", "+inf", "BYSCORE"}}, store))
+	assert.Equal(t, []string{"b", "2", "c", "3"}, zrange(store, "dst", "0", "-1", "WITHSCORES"))
+
+	assert.Equal(t, integerReply(0), HandlerZRangeStore(Command{Name: "ZRANGESTORE", Args: []string{"dst", "z", "5", "10"}}, store))
+	assert.False(t, store.Exists("dst"))
+
+	assert.Equal(t, errorReply(syntaxError), HandlerZRangeStore(Command{Name: "ZRANGESTORE", Args: []string{"dst", "z", "0", "1", "WITHSCORES"}}, store))
+}
+
+func TestZCountZLexCount(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b", "3", "c")
+
+	assert.Equal(t, integerReply(2), HandlerZCount(Command{Name: "ZCOUNT", Args: []string{"z", "(1", "3"}}, store))
+	assert.Equal(t, integerReply(3), HandlerZCount(Command{Name: "ZCOUNT", Args: []string{"z", "-inf", "+inf"}}, store))
+	assert.Equal(t, integerReply(0), HandlerZCount(Command{Name: "ZCOUNT", Args: []string{"z", "3", "1"}}, store))
+	assert.Equal(t, errorReply(minMaxNotFloatError), HandlerZCount(Command{Name: "ZCOUNT", Args: []string{"z", "x", "1"}}, store))
+	assert.Equal(t, integerReply(2), HandlerZLexCount(Command{Name: "ZLEXCOUNT", Args: []string{"z", "[b", "+"}}, store))
+	assert.Equal(t, errorReply(minMaxNotLexError), HandlerZLexCount(Command{Name: "ZLEXCOUNT", Args: []string{"z", "b", "+"}}, store))
+}
+
+func TestZPop(t *testing.T) {
+	store := makeZSetStore("z", "1", "a", "2", "b", "3", "c")
+
+	assert.Equal(t, []string{"a", "1"}, bulkStrings(HandlerZPopMin(Command{Name: "ZPOPMIN", Args: []string{"z"}}, store)))
+	assert.Equal(t, []string{"c", "3", "b", "2"}, bulkStrings(HandlerZPopMax(Command{Name: "ZPOPMAX", Args: []string{"z", "5"}}, store)))
+	assert.False(t, store.Exists("z"))
+	assert.Equal(t, []string{}, bulkStrings(HandlerZPopMin(Command{Name: "ZPOPMIN", Args: []string{"z"}}, store)))
+	assert.Equal(t, errorReply(mustBePositiveError), HandlerZPopMin(Command{Name: "ZPOPMIN", Args: []string{"z", "-1"}}, store))
+}
+
+func TestZRemRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   func(Command, *keyspace.Keyspace) common.RespValue
+		args      []string
+		expected  common.RespValue
+		remaining []string
+	}{
+		{name: "by rank", handler: HandlerZRemRangeByRank, args: []string{"z", "0", "1"}, expected: integerReply(2), remaining: []string{"c", "d"}},
+		{name: "by negative rank", handler: HandlerZRemRangeByRank, args: []string{"z", "-1", "-1"}, expected: integerReply(1), remaining: []string{"a", "b", "c"}},
+		{name: "by score", handler: HandlerZRemRangeByScore, args: []string{"z", "(1", "3"}, expected: integerReply(2), remaining: []string{"a", "d"}},
+		{name: "by lex", handler: HandlerZRemRangeByLex, args: []string{"z", "[b", "[c"}, expected: integerReply(2), remaining: []string{"a", "d"}},
+		{name: "bad score", handler: HandlerZRemRangeByScore, args: []string{"z", "x", "3"}, expected: errorReply(minMaxNotFloatError), remaining: []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeZSetStore("z", "1", "a", "2", "b", "3", "c", "4", "d")
+			assert.Equal(t, tt.expected, tt.handler(Command{Name: tt.name, Args: tt.args}, store))
+			assert.Equal(t, tt.remaining, zrange(store, "z", "0", "-1"))
+		})
+	}
+
+	store := makeZSetStore("z", "1", "a")
+	HandlerZRemRangeByRank(Command{Name: "ZREMRANGEBYRANK", Args: []string{"z", "0", "-1"}}, store)
+	assert.False(t, store.Exists("z"))
+}
+
+func TestZUnionInterStore(t *testing.T) {
+	newStore := func() *keyspace.Keyspace {
+		store := makeZSetStore("z1", "1", "a", "2", "b", "3", "c")
+		HandlerZAdd(Command{Name: "ZADD", Args: []string{"z2", "10", "b", "20", "c", "30", "d"}}, store)
+		HandlerSAdd(Command{Name: "SADD", Args: []string{"s", "c", "d"}}, store)
+		return store
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		handler  func(Command, *keyspace.Keyspace) common.RespValue
+		args     []string
+		expected common.RespValue
+		result   []string
+	}{
+		{name: "union sum", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "z2"}, expected: integerReply(4), result: []string{"a", "1", "b", "12", "c", "23", "d", "30"}},
+		{name: "union weights", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "z2", "WEIGHTS", "2", "0.5"}, expected: integerReply(4), result: []string{"a", "2", "b", "9", "d", "15", "c", "16"}},
+		{name: "union max", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "z2", "AGGREGATE", "MAX"}, expected: integerReply(4), result: []string{"a", "1", "b", "10", "c", "20", "d", "30"}},
+		{name: "union with set", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "s"}, expected: integerReply(4), result: []string{"a", "1", "d", "1", "b", "2", "c", "4"}},
+		{name: "union missing", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "missing"}, expected: integerReply(3), result: []string{"a", "1", "b", "2", "c", "3"}},
+		{name: "inter sum", command: "zinterstore", handler: HandlerZInterStore, args: []string{"dst", "2", "z1", "z2"}, expected: integerReply(2), result: []string{"b", "12", "c", "23"}},
+		{name: "inter min", command: "zinterstore", handler: HandlerZInterStore, args: []string{"dst", "3", "z1", "z2", "s", "AGGREGATE", "min"}, expected: integerReply(1), result: []string{"c", "1"}},
+		{name: "inter missing", command: "zinterstore", handler: HandlerZInterStore, args: []string{"dst", "2", "z1", "missing"}, expected: integerReply(0), result: []string{}},
+		{name: "zero numkeys", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "0", "z1"}, expected: errorReply("ERR at least 1 input key is needed for 'zunionstore' command"), result: []string{"x", "1"}},
+		{name: "too few keys", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "3", "z1", "z2"}, expected: errorReply(syntaxError), result: []string{"x", "1"}},
+		{name: "bad weight", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "z2", "WEIGHTS", "1", "x"}, expected: errorReply("ERR weight value is not a float"), result: []string{"x", "1"}},
+		{name: "missing weights", command: "zunionstore", handler: HandlerZUnionStore, args: []string{"dst", "2", "z1", "z2", "WEIGHTS", "1"}, expected: errorReply(syntaxError), result: []string{"x", "1"}},
+		{name: "bad aggregate", command: "zinterstore", handler: HandlerZInterStore, args: []string{"dst", "2", "z1", "z2", "AGGREGATE", "AVG"}, expected: errorReply(syntaxError), result: []string{"x", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore()
+			HandlerZAdd(Command{Name: "ZADD", Args: []string{"dst", "1", "x"}}, store)
+			assert.Equal(t, tt.expected, tt.handler(Command{Name: tt.command, Args: tt.args}, store))
+			assert.Equal(t, tt.result, zrange(store, "dst", "0", "-1", "WITHSCORES"))
+		})
+	}
+}
+
+func TestZUnionStoreInfinities(t *testing.T) {
+	store := makeZSetStore("a", "+inf", "m")
+	HandlerZAdd(Command{Name: "ZADD", Args: []string{"b", "-inf", "m"}}, store)
+
+	HandlerZUnionStore(Command{Name: "ZUNIONSTORE", Args: []string{"dst", "2", "a", "b"}}, store)
+	assert.Equal(t, bulkReply("0"), HandlerZScore(Command{Name: "ZSCORE", Args: []string{"dst", "m"}}, store))
+
+	HandlerZUnionStore(Command{Name: "ZUNIONSTORE", Args: []string{"dst", "1", "a", "WEIGHTS", "0"}}, store)
+	assert.Equal(t, bulkReply("0"), HandlerZScore(Command{Name: "ZSCORE", Args: []string{"dst", "m"}}, store))
+}
+
+func TestFormatScore(t *testing.T) {
+	tests := map[float64]string{
+		0:              "0",
+		1:              "1",
+		-2.5:           "-2.5",
+		1234567:        "1234567",
+		0.1:            "0.1",
+		1e21:           "1e+21",
+		1e-7:           "1e-07",
+		math.Inf(1):    "inf",
+		math.Inf(-1):   "-inf",
+		3.141592653589: "3.141592653589",
+	}
+	for score, expected := range tests {
+		assert.Equal(t, expected, formatScore(score))
+	}
+}
+
+func TestZSetWrongType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(Command, *keyspace.Keyspace) common.RespValue
+		args    []string
+	}{
+		{name: "ZADD", handler: HandlerZAdd, args: []string{"str", "1", "a"}},
+		{name: "ZINCRBY", handler: HandlerZIncrBy, args: []string{"str", "1", "a"}},
+		{name: "ZREM", handler: HandlerZRem, args: []string{"str", "a"}},
+		{name: "ZSCORE", handler: HandlerZScore, args: []string{"str", "a"}},
+		{name: "ZMSCORE", handler: HandlerZMScore, args: []string{"str", "a"}},
+		{name: "ZCARD", handler: HandlerZCard, args: []string{"str"}},
+		{name: "ZRANK", handler: HandlerZRank, args: []string{"str", "a"}},
+		{name: "ZRANGE", handler: HandlerZRange, args: []string{"str", "0", "-1"}},
+		{name: "ZRANGESTORE", handler: HandlerZRangeStore, args: []string{"dst", "str", "0", "-1"}},
+		{name: "ZCOUNT", handler: HandlerZCount, args: []string{"str", "0", "1"}},
+		{name: "ZLEXCOUNT", handler: HandlerZLexCount, args: []string{"str", "-", "+"}},
+		{name: "ZPOPMIN", handler: HandlerZPopMin, args: []string{"str"}},
+		{name: "ZREMRANGEBYRANK", handler: HandlerZRemRangeByRank, args: []string{"str", "0", "1"}},
+		{name: "ZUNIONSTORE", handler: HandlerZUnionStore, args: []string{"dst", "2", "zset", "str"}},
+		{name: "SADD", handler: HandlerSAdd, args: []string{"zset", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := makeZSetStore("zset", "1", "a")
+			store.Set("str", "value")
+			assert.Equal(t, errorReply(wrongTypeError), tt.handler(Command{Name: tt.name, Args: tt.args}, store))
+			assert.Equal(t, []string{"a"}, zrange(store, "zset", "0", "-1"))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{pattern: "*", input: "", match: true},
+		{pattern: "*", input: "anything", match: true},
+		{pattern: "h?llo", input: "hello", match: true},
+		{pattern: "h?llo", input: "hllo", match: false},
+		{pattern: "h*llo", input: "heeeello", match: true},
+		{pattern: "h*llo", input: "hello world", match: false},
+		{pattern: "h[ae]llo", input: "hallo", match: true},
+		{pattern: "h[ae]llo", input: "hillo", match: false},
+		{pattern: "h[^e]llo", input: "hallo", match: true},
+		{pattern: "h[^e]llo", input: "hello", match: false},
+		{pattern: "h[a-b]llo", input: "hbllo", match: true},
+		{pattern: "h[b-a]llo", input: "hallo", match: true},
+		{pattern: "h[a-b]llo", input: "hcllo", match: false},
+		{pattern: "news.*", input: "news.sport", match: true},
+		{pattern: "news.*", input: "weather", match: false},
+		{pattern: `h\*llo`, input: "h*llo", match: true},
+		{pattern: `h\*llo`, input: "hello", match: false},
+		{pattern: `[\]]`, input: "]", match: true},
+		{pattern: "a**b", input: "axxb", match: true},
+		{pattern: "user:*:name", input: "user:42:name", match: true},
+		{pattern: "user:*:name", input: "user:42:email", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.match, GlobMatch(tt.pattern, tt.input))
+		})
+	}
+}
@@ -0,0 +1,355 @@
+// Package config holds the server's configuration: the parameters it
+// takes, their values and how they change. Values start as the defaults,
+// are overridden by a redis.conf-style file and then by command-line
+// flags, and those marked mutable can be changed while the server runs
+// with CONFIG SET, which calls the hook registered for each. CONFIG
+// REWRITE writes the running values back to the file.
+//
+// A Config is not safe for concurrent use; the server only touches it
+// from the executor goroutine once it is running.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+)
+
+// Kind is the type of a parameter's value.
+type Kind int
+
+const (
+	// String takes any value its Check accepts.
+	String Kind = iota
+	// Bool takes yes or no; true and false are accepted too, as flags
+	// give them.
+	Bool
+	// Int takes an integer from Min to Max.
+	Int
+	// Memory takes a number of bytes from Min to Max, with an optional
+	// k, kb, m, mb, g or gb suffix as in Redis.
+	Memory
+	// Enum takes one of Values.
+	Enum
+)
+
+// Param describes a parameter.
+type Param struct {
+	Name    string
+	Kind    Kind
+	Default string
+	Usage   string
+	// Mutable parameters can be changed with CONFIG SET while the server
+	// runs.
+	Mutable bool
+	// Min and Max bound an Int or Memory.
+	Min, Max int64
+	// Values are those an Enum takes.
+	Values []string
+	// Repeated parameters accumulate over several lines of a file, as
+	// save does in redis.conf.
+	Repeated bool
+	// Check, if set, vets a String.
+	Check func(value string) error
+}
+
+// ErrUnknown is returned for a parameter the server does not take.
+var ErrUnknown = errors.New("unknown parameter")
+
+// ParamError is an error concerning one parameter.
+type ParamError struct {
+	Name string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Config is a set of parameters and their values.
+type Config struct {
+	params []*Param
+	byName map[string]*Param
+	values map[string]string
+	hooks  map[string]func(value string) error
+	// path is the file the config was loaded from, if any
+	path string
+}
+
+// New returns a Config holding the server's Parameters at their defaults.
+func New() *Config {
+	return newConfig(Parameters)
+}
+
+func newConfig(params []Param) *Config {
+	c := &Config{
+		byName: make(map[string]*Param, len(params)),
+		values: make(map[string]string, len(params)),
+		hooks:  make(map[string]func(string) error),
+	}
+	for i := range params {
+		p := &params[i]
+		c.params = append(c.params, p)
+		c.byName[p.Name] = p
+		c.values[p.Name] = p.Default
+	}
+	return c
+}
+
+// Path returns the file the config was loaded from, "" if none was.
+func (c *Config) Path() string {
+	return c.path
+}
+
+func (c *Config) param(name string) *Param {
+	p := c.byName[name]
+	if p == nil {
+		panic(fmt.Sprintf("config: unknown parameter %q", name))
+	}
+	return p
+}
+
+// String returns the value of the named parameter.
+func (c *Config) String(name string) string {
+	c.param(name)
+	return c.values[name]
+}
+
+// Bool returns the value of the named Bool parameter.
+func (c *Config) Bool(name string) bool {
+	return c.String(name) == "yes"
+}
+
+// Int returns the value of the named Int or Memory parameter.
+func (c *Config) Int(name string) int64 {
+	n, _ := strconv.ParseInt(c.String(name), 10, 64)
+	return n
+}
+
+// Set sets the named parameter, without calling its hook, as loading the
+// file and flags does.
+func (c *Config) Set(name, value string) error {
+	p := c.byName[strings.ToLower(name)]
+	if p == nil {
+		return &ParamError{Name: name, Err: ErrUnknown}
+	}
+	normalized, err := normalize(p, value)
+	if err != nil {
+		return &ParamError{Name: p.Name, Err: err}
+	}
+	c.values[p.Name] = normalized
+	return nil
+}
+
+// OnChange registers the hook CONFIG SET calls with the new value of the
+// named parameter, which must be mutable. A hook that returns an error
+// rejects the value.
+func (c *Config) OnChange(name string, hook func(value string) error) {
+	if !c.param(name).Mutable {
+		panic(fmt.Sprintf("config: %q is immutable", name))
+	}
+	c.hooks[name] = hook
+}
+
+// Apply sets parameters while the server runs, as CONFIG SET does, given
+// name and value pairs. Every parameter must be known and mutable and
+// every value valid; the hooks are then called in order, and should one
+// fail, those already called are called again with the old values. It
+// changes all of the parameters or none of them.
+func (c *Config) Apply(pairs ...string) error {
+	type change struct {
+		p        *Param
+		old, new string
+	}
+	var changes []change
+	for i := 0; i+1 < len(pairs); i += 2 {
+		p := c.byName[strings.ToLower(pairs[i])]
+		if p == nil {
+			return &ParamError{Name: pairs[i], Err: ErrUnknown}
+		}
+		if !p.Mutable {
+			return &ParamError{Name: p.Name, Err: errors.New("can't set immutable config")}
+		}
+		if slices.ContainsFunc(changes, func(ch change) bool { return ch.p == p }) {
+			return &ParamError{Name: p.Name, Err: errors.New("duplicate parameter")}
+		}
+		value, err := normalize(p, pairs[i+1])
+		if err != nil {
+			return &ParamError{Name: p.Name, Err: err}
+		}
+		changes = append(changes, change{p, c.values[p.Name], value})
+	}
+	for i, ch := range changes {
+		c.values[ch.p.Name] = ch.new
+		hook := c.hooks[ch.p.Name]
+		if hook == nil {
+			continue
+		}
+		if err := hook(ch.new); err != nil {
+			for _, undo := range slices.Backward(changes[:i+1]) {
+				c.values[undo.p.Name] = undo.old
+				if undoHook := c.hooks[undo.p.Name]; undoHook != nil && undo.p != ch.p {
+					undoHook(undo.old)
+				}
+			}
+			return &ParamError{Name: ch.p.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Get returns the parameters matching any of the glob-style patterns, as
+// name and value pairs in the order the parameters are listed.
+func (c *Config) Get(patterns ...string) []string {
+	var pairs []string
+	for _, p := range c.params {
+		for _, pattern := range patterns {
+			if common.GlobMatch(strings.ToLower(pattern), p.Name) {
+				pairs = append(pairs, p.Name, c.values[p.Name])
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// normalize checks value against p and returns it in the form CONFIG GET
+// reports: yes or no for a Bool, and a plain number of bytes for Memory.
+func normalize(p *Param, value string) (string, error) {
+	switch p.Kind {
+	case Bool:
+		switch strings.ToLower(value) {
+		case "yes", "true":
+			return "yes", nil
+		case "no", "false":
+			return "no", nil
+		}
+		return "", errors.New("argument must be 'yes' or 'no'")
+	case Int:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", errors.New("argument couldn't be parsed into an integer")
+		}
+		if n < p.Min || n > p.Max {
+			return "", fmt.Errorf("argument must be between %d and %d inclusive", p.Min, p.Max)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case Memory:
+		n, err := ParseMemory(value)
+		if err != nil {
+			return "", errors.New("argument must be a memory value")
+		}
+		if n < p.Min || n > p.Max {
+			return "", fmt.Errorf("argument must be between %d and %d inclusive", p.Min, p.Max)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case Enum:
+		lower := strings.ToLower(value)
+		if !slices.Contains(p.Values, lower) {
+			return "", fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(p.Values, ", "))
+		}
+		return lower, nil
+	default:
+		if p.Check != nil {
+			if err := p.Check(value); err != nil {
+				return "", err
+			}
+		}
+		return value, nil
+	}
+}
+
+// ParseMemory parses a number of bytes with an optional unit: k, m and g
+// are powers of 1000, kb, mb and gb powers of 1024, as in redis.conf.
+func ParseMemory(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"b", 1}}
+	multiplier := int64(1)
+	for _, unit := range units {
+		if number, found := strings.CutSuffix(lower, unit.suffix); found {
+			lower, multiplier = number, unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("invalid memory value %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// RegisterFlags defines a flag for every parameter on fs, setting it as
+// Set does. Bool flags may be given without a value; parse with ParseFlags
+// to also accept one after them, as in -appendonly yes.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	for _, p := range c.params {
+		usage := p.Usage
+		if p.Mutable {
+			usage += " (CONFIG SET changes it at runtime)"
+		}
+		fs.Var(&flagValue{c: c, p: p}, p.Name, usage)
+	}
+}
+
+// flagValue is the flag.Value of a parameter.
+type flagValue struct {
+	c *Config
+	p *Param
+}
+
+func (v *flagValue) String() string {
+	if v.c == nil {
+		return ""
+	}
+	return v.c.values[v.p.Name]
+}
+
+func (v *flagValue) Set(value string) error {
+	normalized, err := normalize(v.p, value)
+	if err != nil {
+		return err
+	}
+	v.c.values[v.p.Name] = normalized
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.p != nil && v.p.Kind == Bool
+}
+
+// ParseFlags parses args with fs, on which RegisterFlags defined the
+// parameters. A bool flag may be given alone, as -name=value, or followed
+// by its value as in the config file, which the flag package would take
+// for an argument of its own.
+func (c *Config) ParseFlags(fs *flag.FlagSet, args []string) error {
+	joined := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			joined = append(joined, args[i:]...)
+			break
+		}
+		name, isFlag := strings.CutPrefix(arg, "-")
+		name = strings.TrimPrefix(name, "-")
+		if p := c.byName[name]; isFlag && p != nil && p.Kind == Bool && i+1 < len(args) {
+			if _, err := normalize(p, args[i+1]); err == nil {
+				arg += "=" + args[i+1]
+				i++
+			}
+		}
+		joined = append(joined, arg)
+	}
+	return fs.Parse(joined)
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() *Config {
+	return newConfig([]Param{
+		{Name: "port", Kind: Int, Default: "6379", Min: 1, Max: 65535},
+		{Name: "maxclients", Kind: Int, Default: "100", Min: 1, Max: 1000, Mutable: true},
+		{Name: "appendonly", Kind: Bool, Default: "no", Mutable: true},
+		{Name: "appendfsync", Kind: Enum, Default: "everysec", Values: []string{"always", "everysec", "no"}, Mutable: true},
+		{Name: "maxmemory", Kind: Memory, Default: "0", Min: 0, Max: 1 << 40, Mutable: true},
+		{Name: "save", Kind: String, Default: "", Repeated: true, Mutable: true, Check: checkSaveRules},
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	c := New()
+	assert.Equal(t, int64(6379), c.Int("port"))
+	assert.Equal(t, int64(1024), c.Int("executor-queue-size"))
+	assert.False(t, c.Bool("appendonly"))
+	assert.Equal(t, "debug", c.String("loglevel"))
+	for _, p := range Parameters {
+		_, err := normalize(&p, p.Default)
+		assert.NoError(t, err, "the default of %s", p.Name)
+	}
+	assert.Panics(t, func() { c.String("nope") })
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name, value string
+		expected    string
+		err         string
+	}{
+		{"port", "7000", "7000", ""},
+		{"PORT", "+7000", "7000", ""},
+		{"port", "0", "", "argument must be between 1 and 65535 inclusive"},
+		{"port", "x", "", "argument couldn't be parsed into an integer"},
+		{"appendonly", "YES", "yes", ""},
+		{"appendonly", "false", "no", ""},
+		{"appendonly", "1", "", "argument must be 'yes' or 'no'"},
+		{"appendfsync", "Always", "always", ""},
+		{"appendfsync", "sometimes", "", "argument(s) must be one of the following: always, everysec, no"},
+		{"maxmemory", "2mb", "2097152", ""},
+		{"maxmemory", "2m", "2000000", ""},
+		{"maxmemory", "2x", "", "argument must be a memory value"},
+		{"save", "900 1 300 10", "900 1 300 10", ""},
+		{"save", "900", "", "invalid save rules"},
+		{"nope", "1", "", "unknown parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.value, func(t *testing.T) {
+			c := testConfig()
+			err := c.Set(tt.name, tt.value)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c.Get(tt.name)[1])
+		})
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		ok       bool
+	}{
+		{"100", 100, true},
+		{"100b", 100, true},
+		{"1k", 1000, true},
+		{"1KB", 1024, true},
+		{"3mb", 3 << 20, true},
+		{"1g", 1e9, true},
+		{"2gb", 2 << 30, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"kb", 0, false},
+		{"99999999999gb", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			n, err := ParseMemory(tt.value)
+			if !tt.ok {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	c := testConfig()
+	var calls []string
+	c.OnChange("maxclients", func(value string) error {
+		calls = append(calls, "maxclients "+value)
+		return nil
+	})
+	c.OnChange("appendfsync", func(value string) error {
+		calls = append(calls, "appendfsync "+value)
+		return nil
+	})
+	require.NoError(t, c.Apply("maxclients", "10", "APPENDFSYNC", "always", "appendonly", "yes"))
+	assert.Equal(t, []string{"maxclients 10", "appendfsync always"}, calls)
+	assert.Equal(t, []string{"maxclients", "10", "appendonly", "yes", "appendfsync", "always"},
+		c.Get("maxclients", "append*"))
+
+	assert.Panics(t, func() { c.OnChange("port", func(string) error { return nil }) })
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		param string
+		err   string
+	}{
+		{"unknown", []string{"maxclients", "10", "nope", "1"}, "nope", "unknown parameter"},
+		{"immutable", []string{"port", "7000"}, "port", "can't set immutable config"},
+		{"duplicate", []string{"maxclients", "10", "MaxClients", "20"}, "maxclients", "duplicate parameter"},
+		{"invalid", []string{"maxclients", "10", "appendonly", "maybe"}, "appendonly", "argument must be 'yes' or 'no'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig()
+			called := false
+			c.OnChange("maxclients", func(string) error {
+				called = true
+				return nil
+			})
+			err := c.Apply(tt.pairs...)
+			var paramErr *ParamError
+			require.ErrorAs(t, err, &paramErr)
+			assert.Equal(t, tt.param, paramErr.Name)
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, called, "nothing is applied unless every value is valid")
+			assert.Equal(t, int64(100), c.Int("maxclients"))
+		})
+	}
+}
+
+func TestApplyRollsBack(t *testing.T) {
+	c := testConfig()
+	applied := map[string]string{}
+	for _, name := range []string{"maxclients", "appendonly"} {
+		c.OnChange(name, func(value string) error {
+			applied[name] = value
+			return nil
+		})
+	}
+	c.OnChange("maxmemory", func(string) error { return errors.New("out of luck") })
+
+	err := c.Apply("maxclients", "10", "appendonly", "yes", "maxmemory", "1kb")
+	assert.EqualError(t, err, "maxmemory: out of luck")
+	assert.Equal(t, map[string]string{"maxclients": "100", "appendonly": "no"}, applied,
+		"the hooks already called are called again with the old values")
+	assert.Equal(t, []string{"maxclients", "100", "appendonly", "no", "maxmemory", "0"},
+		c.Get("maxclients", "appendonly", "maxmemory"))
+}
+
+func TestGet(t *testing.T) {
+	c := testConfig()
+	assert.Equal(t, []string{"maxclients", "100", "maxmemory", "0"}, c.Get("max*"))
+	assert.Equal(t, []string{"port", "6379", "appendfsync", "everysec"}, c.Get("appendfsync", "PORT", "port"))
+	assert.Equal(t, []string{"appendonly", "no"}, c.Get("append?nly"))
+	assert.Empty(t, c.Get("nope"))
+}
+
+func TestRegisterFlags(t *testing.T) {
+	c := testConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	c.RegisterFlags(fs)
+	require.NoError(t, c.ParseFlags(fs, []string{"-port", "7000", "-appendonly", "-maxmemory", "1kb", "-save", ""}))
+	assert.Equal(t, int64(7000), c.Int("port"))
+	assert.True(t, c.Bool("appendonly"))
+	assert.Equal(t, int64(1024), c.Int("maxmemory"))
+	assert.Equal(t, "", c.String("save"))
+
+	assert.Error(t, c.ParseFlags(fs, []string{"-port", "70000"}))
+	assert.Equal(t, int64(7000), c.Int("port"))
+
+	// a bool flag takes a value after it, as in the config file
+	require.NoError(t, c.ParseFlags(fs, []string{"-appendonly", "no", "-port", "7001"}))
+	assert.False(t, c.Bool("appendonly"))
+	assert.Equal(t, int64(7001), c.Int("port"))
+	require.NoError(t, c.ParseFlags(fs, []string{"--appendonly", "YES"}))
+	assert.True(t, c.Bool("appendonly"))
+	require.NoError(t, c.ParseFlags(fs, []string{"-appendonly=no"}))
+	assert.False(t, c.Bool("appendonly"))
+	require.NoError(t, c.ParseFlags(fs, []string{"-appendonly", "extra"}))
+	assert.True(t, c.Bool("appendonly"))
+	assert.Equal(t, []string{"extra"}, fs.Args(), "anything else after it is an argument")
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rewriteMarker heads the lines CONFIG REWRITE adds to a file.
+const rewriteMarker = "# Generated by CONFIG REWRITE"
+
+// Load reads the file at path, in the format of redis.conf: a parameter
+// name and its value on each line, with blank lines and lines starting
+// with # ignored. A value of several words is taken as they are joined
+// by single spaces, and words may be quoted as in redis-cli. A parameter
+// given twice takes the later value, except a Repeated one, whose values
+// accumulate. The file becomes the one Rewrite writes.
+func (c *Config) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			return fmt.Errorf("config: %s:%d: %w", path, i+1, err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if len(args) == 1 {
+			return fmt.Errorf("config: %s:%d: %s has no value", path, i+1, args[0])
+		}
+		value := strings.Join(args[1:], " ")
+		if p := c.byName[strings.ToLower(args[0])]; p != nil && p.Repeated && seen[p.Name] {
+			value = strings.TrimSpace(c.values[p.Name] + " " + value)
+		}
+		if err := c.Set(args[0], value); err != nil {
+			return fmt.Errorf("config: %s:%d: %w", path, i+1, err)
+		}
+		seen[strings.ToLower(args[0])] = true
+	}
+	if c.path, err = filepath.Abs(path); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+// Rewrite writes the running values to the file the config was loaded
+// from. As CONFIG REWRITE does in Redis, it keeps the file's comments and
+// the order of its lines, replaces the value on the line of each
+// parameter it sets, and appends the parameters that differ from their
+// defaults but are not in the file yet. The new file replaces the old
+// one only once it is complete.
+func (c *Config) Rewrite() error {
+	if c.path == "" {
+		return errors.New("the server is running without a config file")
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	var out []string
+	written := make(map[string]bool)
+	for _, line := range lines {
+		if args, err := splitArgs(line); err == nil && len(args) > 0 && !strings.HasPrefix(args[0], "#") {
+			if p := c.byName[strings.ToLower(args[0])]; p != nil {
+				if !written[p.Name] {
+					out = append(out, c.line(p))
+					written[p.Name] = true
+				}
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	for _, p := range c.params {
+		if written[p.Name] || c.values[p.Name] == p.Default {
+			continue
+		}
+		if !slices.Contains(out, rewriteMarker) {
+			out = append(out, rewriteMarker)
+		}
+		out = append(out, c.line(p))
+	}
+	return writeFile(c.path, []byte(strings.Join(out, "\n")+"\n"))
+}
+
+// line is the line of the file setting p to its value.
+func (c *Config) line(p *Param) string {
+	value := c.values[p.Name]
+	if value != "" && !strings.ContainsFunc(value, needsQuoting) {
+		return p.Name + " " + value
+	}
+	// replicaof and save read back the same from one quoted argument
+	return p.Name + " " + quote(value)
+}
+
+func needsQuoting(r rune) bool {
+	return r <= ' ' || r == '"' || r == '\'' || r == '\\' || r > '~'
+}
+
+// quote quotes s so that splitArgs reads it back as one argument.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch == '\n':
+			b.WriteString(`\n`)
+		case ch == '\r':
+			b.WriteString(`\r`)
+		case ch == '\t':
+			b.WriteString(`\t`)
+		case ch < ' ' || ch > '~':
+			fmt.Fprintf(&b, `\x%02x`, ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// splitArgs splits a line into its words, as Redis's sdssplitargs does:
+// words are separated by spaces, and a word may be "double quoted", with
+// \n, \r, \t, \b, \a, \xHH and backslashed characters, or 'single quoted',
+// where only \' is special.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+		var word strings.Builder
+		for i < len(line) && !isSpace(line[i]) {
+			switch line[i] {
+			case '"':
+				end, err := readDoubleQuoted(line, i+1, &word)
+				if err != nil {
+					return nil, err
+				}
+				i = end
+			case '\'':
+				end, err := readSingleQuoted(line, i+1, &word)
+				if err != nil {
+					return nil, err
+				}
+				i = end
+			default:
+				word.WriteByte(line[i])
+				i++
+			}
+		}
+		args = append(args, word.String())
+	}
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+// readDoubleQuoted reads a double-quoted word starting at i, just past
+// the opening quote, into word and returns where it ends; a closing quote
+// must be followed by a space or the end of the line.
+func readDoubleQuoted(line string, i int, word *strings.Builder) (int, error) {
+	for i < len(line) {
+		ch := line[i]
+		switch {
+		case ch == '"':
+			if i+1 < len(line) && !isSpace(line[i+1]) {
+				return 0, errors.New("closing quote must be followed by a space")
+			}
+			return i + 1, nil
+		case ch == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+			n, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+			word.WriteByte(byte(n))
+			i += 4
+		case ch == '\\' && i+1 < len(line):
+			escapes := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'b': '\b', 'a': '\a'}
+			if escaped, ok := escapes[line[i+1]]; ok {
+				word.WriteByte(escaped)
+			} else {
+				word.WriteByte(line[i+1])
+			}
+			i += 2
+		default:
+			word.WriteByte(ch)
+			i++
+		}
+	}
+	return 0, errors.New("unbalanced quotes")
+}
+
+func readSingleQuoted(line string, i int, word *strings.Builder) (int, error) {
+	for i < len(line) {
+		switch {
+		case line[i] == '\'':
+			if i+1 < len(line) && !isSpace(line[i+1]) {
+				return 0, errors.New("closing quote must be followed by a space")
+			}
+			return i + 1, nil
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+			word.WriteByte('\'')
+			i += 2
+		default:
+			word.WriteByte(line[i])
+			i++
+		}
+	}
+	return 0, errors.New("unbalanced quotes")
+}
+
+func isHex(ch byte) bool {
+	return strings.IndexByte("0123456789abcdefABCDEF", ch) >= 0
+}
+
+// writeFile replaces the file at path with data by writing a temporary
+// file beside it and renaming it over the old one, so a crash leaves
+// either the old file or the new one. The old file's permissions are kept.
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	temp := filepath.Join(filepath.Dir(path), "temp-"+filepath.Base(path))
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		os.Remove(temp)
+	}
+	return err
+}
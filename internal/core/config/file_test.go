@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mnemo.conf")
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `# a comment that doesn't need balanced quotes
+   # nor does an indented one
+
+port 7000
+MaxClients 10
+appendonly yes
+appendonly no
+save 900 1
+save "300 10"
+appendfsync 'always'
+`)
+	c := testConfig()
+	require.NoError(t, c.Load(path))
+	assert.Equal(t, []string{"port", "7000", "maxclients", "10", "appendonly", "no", "appendfsync", "always",
+		"maxmemory", "0", "save", "900 1 300 10"}, c.Get("*"))
+	assert.Equal(t, path, c.Path())
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"unknown", "port 7000\nnope 1\n", "mnemo.conf:2: nope: unknown parameter"},
+		{"invalid", "port 70000\n", "mnemo.conf:1: port: argument must be between 1 and 65535 inclusive"},
+		{"no value", "port\n", "mnemo.conf:1: port has no value"},
+		{"unbalanced", `save "900 1` + "\n", "mnemo.conf:1: unbalanced quotes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testConfig().Load(writeConfig(t, tt.text))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+	assert.Error(t, testConfig().Load(filepath.Join(t.TempDir(), "missing.conf")))
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+		err      string
+	}{
+		{"", nil, ""},
+		{"  save  900 1 ", []string{"save", "900", "1"}, ""},
+		{`a "b c" 'd e'`, []string{"a", "b c", "d e"}, ""},
+		{`"\x41\n\t\"\\"`, []string{"A\n\t\"\\"}, ""},
+		{`'it\'s' ""`, []string{"it's", ""}, ""},
+		{`a"b c"`, []string{"ab c"}, ""},
+		{`"a"b`, nil, "closing quote must be followed by a space"},
+		{`'a`, nil, "unbalanced quotes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			args, err := splitArgs(tt.line)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	path := writeConfig(t, `# the port
+port 7000
+
+# keep this
+save 900 1
+save 300 10
+appendfsync always
+`)
+	c := testConfig()
+	require.NoError(t, c.Load(path))
+	require.NoError(t, c.Apply("save", "60 5", "maxclients", "10", "appendfsync", "everysec"))
+	require.NoError(t, c.Rewrite())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# the port
+port 7000
+
+# keep this
+save "60 5"
+appendfsync everysec
+# Generated by CONFIG REWRITE
+maxclients 10
+`, string(data))
+
+	// the marker is not repeated
+	require.NoError(t, c.Apply("maxmemory", "1kb"))
+	require.NoError(t, c.Rewrite())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Generated by CONFIG REWRITE\nmaxclients 10\nmaxmemory 1024\n")
+	_, err = os.Stat(filepath.Join(filepath.Dir(path), "temp-mnemo.conf"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRewriteRoundTrips(t *testing.T) {
+	path := writeConfig(t, "")
+	c := New()
+	require.NoError(t, c.Load(path))
+	require.NoError(t, c.Set("save", ""))
+	require.NoError(t, c.Set("replicaof", "10.0.0.1 6379"))
+	require.NoError(t, c.Set("dir", `/tmp/a "b"`))
+	require.NoError(t, c.Rewrite())
+
+	again := New()
+	require.NoError(t, again.Load(path))
+	assert.Equal(t, c.Get("*"), again.Get("*"))
+	assert.Equal(t, "", again.String("save"))
+}
+
+func TestRewriteWithoutFile(t *testing.T) {
+	assert.EqualError(t, New().Rewrite(), "the server is running without a config file")
+}
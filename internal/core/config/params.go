@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/core/replication"
+)
+
+// Parameters are the parameters the server takes, in the order CONFIG GET
+// lists them. Names and defaults follow redis.conf where Redis has the
+// same setting.
+var Parameters = []Param{
+	{Name: "port", Kind: Int, Default: "6379", Min: 1, Max: 65535,
+		Usage: "port to listen on"},
+	{Name: "maxclients", Kind: Int, Default: "100000", Min: 1, Max: math.MaxInt32, Mutable: true,
+		Usage: "how many clients may be connected at once"},
+	{Name: "timeout", Kind: Int, Default: "30", Min: 0, Max: math.MaxInt32, Mutable: true,
+		Usage: "seconds a client may sit idle before it is disconnected; 0 means never"},
+	{Name: "write-timeout", Kind: Int, Default: "30", Min: 0, Max: math.MaxInt32, Mutable: true,
+		Usage: "seconds a write to a client may take before it is disconnected; 0 means no limit"},
+	{Name: "executor-queue-size", Kind: Int, Default: "1024", Min: 1, Max: 1 << 24,
+		Usage: "commands that may wait for the executor before clients are made to wait"},
+	{Name: "loglevel", Kind: Enum, Default: "debug", Values: []string{"debug", "verbose", "notice", "warning", "nothing"}, Mutable: true,
+		Usage: "least important messages to log: debug, verbose, notice, warning or nothing"},
+	{Name: "admin-port", Kind: Int, Default: "0", Min: 0, Max: 65535,
+		Usage: "port of the HTTP listener serving /metrics, /healthz and /readyz; 0 disables it"},
+	{Name: "slowlog-log-slower-than", Kind: Int, Default: "10000", Min: -1, Max: math.MaxInt32, Mutable: true,
+		Usage: "microseconds a command must take to be logged in the slow log; -1 disables it"},
+	{Name: "slowlog-max-len", Kind: Int, Default: "128", Min: 0, Max: math.MaxInt32, Mutable: true,
+		Usage: "how many of the latest slow commands the slow log keeps"},
+	{Name: "latency-monitor-threshold", Kind: Int, Default: "0", Min: 0, Max: math.MaxInt32, Mutable: true,
+		Usage: "milliseconds an event must take for the latency monitor to record it; 0 disables it"},
+
+	{Name: "dir", Kind: String, Default: ".",
+		Usage: "directory the snapshot and the append-only directory are kept in"},
+	{Name: "dbfilename", Kind: String, Default: "dump.rdb", Check: checkFileName,
+		Usage: "file name of the snapshot"},
+	{Name: "save", Kind: String, Default: rdb.DefaultSaveRules, Repeated: true, Mutable: true, Check: checkSaveRules,
+		Usage: `snapshot after each "seconds changes" pair is met, e.g. "3600 1 300 100"; "" disables automatic snapshots`},
+
+	{Name: "appendonly", Kind: Bool, Default: "no",
+		Usage: "log every write to an append-only file and replay it at startup"},
+	{Name: "appendfilename", Kind: String, Default: "appendonly.aof", Check: checkFileName,
+		Usage: "base name of the append-only files"},
+	{Name: "appenddirname", Kind: String, Default: "appendonlydir", Check: checkFileName,
+		Usage: "directory holding the append-only files and their manifest"},
+	{Name: "appendfsync", Kind: Enum, Default: "everysec", Values: []string{"always", "everysec", "no"}, Mutable: true,
+		Usage: "when to fsync the append-only file: always, everysec or no"},
+	{Name: "aof-load-truncated", Kind: Bool, Default: "yes", Mutable: true,
+		Usage: "on startup, cut off a truncated command at the end of the append-only file instead of refusing to start"},
+	{Name: "auto-aof-rewrite-percentage", Kind: Int, Default: "100", Min: 0, Max: math.MaxInt32, Mutable: true,
+		Usage: "rewrite the append-only file once it has grown by this percentage since the last rewrite; 0 disables automatic rewrites"},
+	{Name: "auto-aof-rewrite-min-size", Kind: Memory, Default: strconv.Itoa(64 << 20), Min: 0, Max: math.MaxInt64, Mutable: true,
+		Usage: "size the append-only file must reach before it is rewritten automatically"},
+
+	{Name: "replicaof", Kind: String, Default: "", Check: checkReplicaOf,
+		Usage: `replicate from the master at "host port"`},
+	{Name: "replica-read-only", Kind: Bool, Default: "yes", Mutable: true,
+		Usage: "refuse writes from clients while a replica"},
+	{Name: "repl-backlog-size", Kind: Memory, Default: strconv.Itoa(replication.DefaultBacklogSize), Min: 1, Max: math.MaxInt32, Mutable: true,
+		Usage: "bytes of the replication stream kept for replicas that reconnect"},
+
+	{Name: "cluster-enabled", Kind: Bool, Default: "no",
+		Usage: "run as a node of a cluster, serving only the hash slots assigned to it"},
+	{Name: "cluster-config-file", Kind: String, Default: "nodes.conf", Check: checkFileName,
+		Usage: "file in dir where the node keeps its view of the cluster"},
+	{Name: "cluster-node-timeout", Kind: Int, Default: strconv.FormatInt(cluster.DefaultNodeTimeout.Milliseconds(), 10), Min: 1, Max: math.MaxInt32,
+		Usage: "milliseconds a node may go without answering before it is suspected of having failed"},
+	{Name: "cluster-port", Kind: Int, Default: "0", Min: 0, Max: 65535,
+		Usage: "port of the cluster bus; 0 means the client port plus 10000"},
+}
+
+func checkFileName(value string) error {
+	if value == "" || strings.ContainsRune(value, '/') {
+		return errors.New("argument must be a file name, without a directory")
+	}
+	return nil
+}
+
+func checkSaveRules(value string) error {
+	_, err := rdb.ParseSaveRules(value)
+	return err
+}
+
+func checkReplicaOf(value string) error {
+	if value == "" {
+		return nil
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return errors.New(`argument must be "host port"`)
+	}
+	if port, err := strconv.Atoi(fields[1]); err != nil || port <= 0 || port > 65535 {
+		return errors.New("invalid master port")
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package datastore
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/aof"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// maxFsyncMarks bounds fsyncState.marks. Past it the newest mark is moved
+// on instead of a new one added, which only makes WAITAOF see a sync
+// later than it happened.
+const maxFsyncMarks = 64
+
+// fsyncState tracks how far into the replication stream the append-only
+// file has been synced to disk, which is what WAITAOF waits on. The file
+// counts its own bytes, so each flush marks the replication offset it
+// reached until the file has been synced past it.
+type fsyncState struct {
+	marks []fsyncMark
+	// offset is the replication offset the synced file covers
+	offset int64
+	// held stops offset moving while the file is rewritten to hold a
+	// dataset loaded from a master, which it does not hold until then
+	held bool
+}
+
+type fsyncMark struct {
+	aof  int64
+	repl int64
+}
+
+// SetAppendOnly makes the executor log every write to file. Call it before
+// the executor goroutine starts and after the file has been replayed, so
+// the replay is not logged a second time.
+func (e *Executor) SetAppendOnly(file *aof.AOF) {
+	e.aof = file
+}
+
+// call runs a command and, if it is a write that changed the keyspace,
+// logs it. Every command that can write goes through here.
+func (e *Executor) call(command commands.Command, run func() common.RespValue) common.RespValue {
+	dirty := e.dataStore.Dirty()
+	reply := run()
+	if e.dataStore.Dirty() != dirty && reply.Type != enums.ErrorRespType && commands.IsWriteCommand(command.Name) {
+		e.propagate(commands.Propagate(command, reply, e.dataStore)...)
+	}
+	return reply
+}
+
+// propagate logs commands and, on a master, feeds them to the replicas,
+// holding them back while a transaction runs so the whole transaction
+// goes out inside MULTI and EXEC. A replica passes on its master's stream
+// instead, in applyFromMaster.
+func (e *Executor) propagate(cmds ...commands.Command) {
+	if e.aof == nil && e.replication.backlog == nil {
+		return
+	}
+	if e.inExec {
+		e.execLog = append(e.execLog, cmds...)
+		return
+	}
+	if e.aof != nil {
+		if err := e.aof.Append(cmds...); err != nil {
+			slog.Error("failed to append to the append-only file", "error", err)
+		}
+	}
+	if e.replication.master == nil {
+		e.feedReplication(cmds...)
+	}
+}
+
+// propagateTransaction propagates the writes of the transaction that just
+// ran.
+func (e *Executor) propagateTransaction() {
+	logged := e.execLog
+	e.inExec, e.execLog = false, nil
+	if len(logged) == 0 {
+		return
+	}
+	cmds := make([]commands.Command, 0, len(logged)+2)
+	cmds = append(cmds, commands.Command{Name: "MULTI"})
+	cmds = append(cmds, logged...)
+	cmds = append(cmds, commands.Command{Name: "EXEC"})
+	e.propagate(cmds...)
+}
+
+// flushAppendOnly writes out the commands logged since the last flush.
+func (e *Executor) flushAppendOnly() {
+	if e.aof == nil {
+		return
+	}
+	event := latencyAOFWrite
+	if e.aof.Policy() == aof.FsyncAlways {
+		event = latencyAOFFsyncAlways
+	}
+	start := time.Now()
+	err := e.aof.Flush()
+	e.timeEvent(event, start)
+	if err != nil {
+		slog.Error("failed to write the append-only file", "error", err)
+		return
+	}
+	e.trackFsync()
+}
+
+// trackFsync moves the fsynced replication offset on as far as the file
+// has been synced. It must only run when nothing is left buffered, since
+// a file synced to the end then holds the whole stream so far.
+func (e *Executor) trackFsync() {
+	fs := &e.fsync
+	written, synced := e.aof.Offset(), e.aof.SyncedOffset()
+	last := synced
+	if n := len(fs.marks); n > 0 {
+		last = fs.marks[n-1].aof
+	}
+	mark := fsyncMark{aof: written, repl: e.replication.offset}
+	switch {
+	case written <= last:
+	case len(fs.marks) == maxFsyncMarks:
+		fs.marks[len(fs.marks)-1] = mark
+	default:
+		fs.marks = append(fs.marks, mark)
+	}
+	if fs.held {
+		return
+	}
+	previous := fs.offset
+	for len(fs.marks) > 0 && fs.marks[0].aof <= synced {
+		fs.offset = fs.marks[0].repl
+		fs.marks = fs.marks[1:]
+	}
+	if len(fs.marks) == 0 {
+		fs.offset = e.replication.offset
+	}
+	if fs.offset != previous {
+		e.ackMaster()
+	}
+}
+
+// fsyncedOffset is how much of the replication stream the append-only
+// file has synced to disk, or -1 if there is no file.
+func (e *Executor) fsyncedOffset() int64 {
+	if e.aof == nil {
+		return -1
+	}
+	return e.fsync.offset
+}
+
+// handleBgRewriteAOF implements BGREWRITEAOF. Inside a transaction the
+// rewrite is put off until the next cron run instead, like a rewrite
+// Redis cannot start at once, because the snapshot would hold writes the
+// transaction has made but not logged yet.
+func (e *Executor) handleBgRewriteAOF(command commands.Command) common.RespValue {
+	if len(command.Args) != 0 {
+		return wrongArity(command.Name)
+	}
+	if e.aof == nil {
+		return errorValue("ERR Append only file is disabled")
+	}
+	if e.aof.RewriteInProgress() {
+		return errorValue("ERR Background append only file rewriting already in progress")
+	}
+	if e.inExec {
+		e.rewriteScheduled = true
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "Background append only file rewriting scheduled"}
+	}
+	if err := e.startRewrite(); err != nil {
+		return errorValue("ERR Can't execute an AOF background rewriting. Please check the server logs for more information.")
+	}
+	return common.RespValue{Type: enums.SimpleStringRespType, Str: "Background append only file rewriting started"}
+}
+
+// startRewrite starts rewriting the append-only file from a snapshot of
+// the keyspace as it is now.
+func (e *Executor) startRewrite() error {
+	e.rewriteScheduled = false
+	start := time.Now()
+	snapshot := e.dataStore.Snapshot()
+	e.timeEvent(latencySnapshot, start)
+	if err := e.aof.StartRewrite(snapshot); err != nil {
+		e.rewriteErr = err
+		slog.Error("could not start rewriting the append-only file", "error", err)
+		return err
+	}
+	slog.Info("started rewriting the append-only file in the background")
+	return nil
+}
+
+// cronAppendOnly flushes the append-only file, completes a rewrite whose
+// base file is written, and starts a rewrite that was put off or that the
+// growth of the file has made due.
+func (e *Executor) cronAppendOnly() {
+	if e.aof == nil {
+		return
+	}
+	e.flushAppendOnly()
+	if finished, err := e.aof.CheckRewrite(); finished {
+		e.rewriteErr = err
+		if err != nil {
+			slog.Error("rewriting the append-only file failed", "error", err)
+		} else {
+			slog.Info("rewrote the append-only file")
+			// unless this rewrite started before the one it is waiting for
+			if !e.rewriteScheduled {
+				e.fsync.held = false
+			}
+		}
+	}
+	if !e.aof.RewriteInProgress() && (e.rewriteScheduled || e.aof.RewriteDue()) {
+		e.startRewrite()
+	}
+}
+
+// Close disconnects from the master on a replica, saves a final snapshot
+// if there are save rules, and flushes and closes the append-only file, if
+// there is one, giving up on any background save or rewrite in progress.
+// It must be called from the executor goroutine once it has stopped
+// handling requests.
+func (e *Executor) Close() error {
+	e.closeReplication()
+	e.closeMigrate()
+	if e.cluster.node != nil {
+		e.cluster.node.Close()
+	}
+	err := e.closeRDB()
+	if e.aof == nil {
+		return err
+	}
+	err = errors.Join(err, e.aof.Close())
+	e.aof = nil
+	return err
+}
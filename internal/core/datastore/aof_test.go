@@ -0,0 +1,229 @@
+package datastore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/aof"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+const appendFilename = "appendonly.aof"
+
+// appendOnlyExecutor returns an executor logging to a fresh directory, and
+// the directory.
+func appendOnlyExecutor(t *testing.T) (*Executor, string) {
+	t.Helper()
+	dir := t.TempDir()
+	file, err := aof.Open(dir, appendFilename, aof.FsyncAlways)
+	require.NoError(t, err)
+	exec := NewExecutor()
+	exec.SetAppendOnly(file)
+	t.Cleanup(func() { exec.Close() })
+	return exec, dir
+}
+
+// logged returns the commands in the log in dir.
+func logged(t *testing.T, dir string) []commands.Command {
+	t.Helper()
+	var cmds []commands.Command
+	_, err := aof.Load(dir, appendFilename, false, func(command commands.Command) common.RespValue {
+		cmds = append(cmds, command)
+		return common.RespValue{Type: enums.SimpleStringRespType}
+	})
+	require.NoError(t, err)
+	return cmds
+}
+
+func TestAppendOnlyLogsWrites(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "GET", "a")
+	client.do(exec, "DEL", "missing")
+	client.do(exec, "LPUSH", "a", "x")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "PUBLISH", "news", "hi")
+	client.do(exec, "SADD", "s", "m")
+	client.do(exec, "SADD", "s", "m")
+	client.do(exec, "SREM", "s", "missing")
+
+	// reads, writes that change nothing and failed writes are not logged
+	assert.Equal(t, []commands.Command{
+		makeCommand("SET", "a", "1"),
+		makeCommand("INCR", "a"),
+		makeCommand("SADD", "s", "m"),
+	}, logged(t, dir))
+}
+
+func TestAppendOnlyLogsTransactions(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	client.do(exec, "MULTI")
+	client.do(exec, "GET", "a")
+	client.do(exec, "EXEC")
+	client.do(exec, "MULTI")
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "GET", "a")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "EXEC")
+
+	// a transaction that wrote nothing is not logged at all
+	data, err := os.ReadFile(filepath.Join(dir, appendFilename+".1.incr.aof"))
+	require.NoError(t, err)
+	assert.Equal(t, "*1\r\n$5\r\nMULTI\r\n"+
+		"*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"+
+		"*2\r\n$4\r\nINCR\r\n$1\r\na\r\n"+
+		"*1\r\n$4\r\nEXEC\r\n", string(data))
+}
+
+func TestAppendOnlyLogsServedBlockingPop(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	worker, producer := newTestClient(), newTestClient()
+
+	worker.do(exec, "BLPOP", "a", "b", "0")
+	producer.do(exec, "RPUSH", "b", "job")
+	assert.Equal(t, []string{"b", "job"}, replyStrings(worker.reply(t)))
+
+	assert.Equal(t, []commands.Command{
+		makeCommand("RPUSH", "b", "job"),
+		makeCommand("LPOP", "b"),
+	}, logged(t, dir))
+}
+
+func TestAppendOnlyReplay(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	client.do(exec, "SET", "s", "v", "EX", "100")
+	client.do(exec, "RPUSH", "l", "a", "b", "c")
+	client.do(exec, "LPOP", "l")
+	client.do(exec, "SADD", "set", "x", "y")
+	client.do(exec, "SPOP", "set")
+	client.do(exec, "ZADD", "z", "1", "m")
+	client.do(exec, "SET", "gone", "v")
+	client.do(exec, "EXPIRE", "gone", "-1")
+	require.NoError(t, exec.Close())
+
+	replayed := NewExecutor()
+	_, err := aof.Load(dir, appendFilename, false, replayed.Execute)
+	require.NoError(t, err)
+
+	for _, command := range [][]string{
+		{"GET", "s"},
+		{"PEXPIRETIME", "s"},
+		{"LRANGE", "l", "0", "-1"},
+		{"SMEMBERS", "set"},
+		{"ZRANGE", "z", "0", "-1", "WITHSCORES"},
+		{"GET", "gone"},
+	} {
+		name, args := command[0], command[1:]
+		assert.Equal(t, exec.Execute(makeCommand(name, args...)), replayed.Execute(makeCommand(name, args...)), "%v", command)
+	}
+}
+
+// finishRewrite runs the executor's cron until the rewrite in progress has
+// completed.
+func finishRewrite(t *testing.T, exec *Executor) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		exec.Cron()
+		return !exec.aof.RewriteInProgress()
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestBgRewriteAOF(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	for range 50 {
+		client.do(exec, "INCR", "counter")
+		client.reply(t)
+	}
+	client.do(exec, "SADD", "set", "a", "b")
+	client.reply(t)
+
+	client.do(exec, "BGREWRITEAOF")
+	assert.Equal(t, "Background append only file rewriting started", client.reply(t).Str)
+	client.do(exec, "BGREWRITEAOF")
+	assert.Equal(t, "ERR Background append only file rewriting already in progress", client.reply(t).Str)
+	client.do(exec, "INCR", "counter")
+	client.reply(t)
+	finishRewrite(t, exec)
+
+	// the fifty INCRs are now a single SET, followed by the INCR made
+	// during the rewrite
+	cmds := logged(t, dir)
+	assert.Len(t, cmds, 3)
+	assert.Contains(t, cmds, makeCommand("SET", "counter", "50"))
+	assert.Equal(t, makeCommand("INCR", "counter"), cmds[2])
+
+	replayed := NewExecutor()
+	_, err := aof.Load(dir, appendFilename, false, replayed.Execute)
+	require.NoError(t, err)
+	assert.Equal(t, "51", replayed.Execute(makeCommand("GET", "counter")).Str)
+	assert.Equal(t, int64(2), replayed.Execute(makeCommand("SCARD", "set")).Int)
+}
+
+func TestBgRewriteAOFDisabled(t *testing.T) {
+	exec := NewExecutor()
+	assert.Equal(t, "ERR Append only file is disabled", exec.Execute(makeCommand("BGREWRITEAOF")).Str)
+}
+
+func TestBgRewriteAOFInsideMulti(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	client.do(exec, "MULTI")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "BGREWRITEAOF")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "EXEC")
+	for range 4 {
+		client.reply(t)
+	}
+	resp := client.reply(t)
+	assert.Equal(t, "Background append only file rewriting scheduled", resp.Array[1].Str)
+
+	// the rewrite starts once the transaction has been logged, so its
+	// writes are not in both the base and the incr file
+	assert.False(t, exec.aof.RewriteInProgress())
+	exec.Cron()
+	assert.True(t, exec.aof.RewriteInProgress())
+	finishRewrite(t, exec)
+	assert.Equal(t, []commands.Command{makeCommand("SET", "a", "2")}, logged(t, dir))
+}
+
+func TestAutoRewriteAOF(t *testing.T) {
+	exec, dir := appendOnlyExecutor(t)
+	exec.aof.SetAutoRewrite(100, 100)
+	client := newTestClient()
+
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+	exec.Cron()
+	assert.False(t, exec.aof.RewriteInProgress(), "below the minimum size")
+
+	for range 10 {
+		client.do(exec, "INCR", "a")
+		client.reply(t)
+	}
+	exec.Cron()
+	assert.True(t, exec.aof.RewriteInProgress())
+	finishRewrite(t, exec)
+	assert.Equal(t, []commands.Command{makeCommand("SET", "a", "11")}, logged(t, dir))
+
+	// the rewrite reset the base size, so a rewrite is not due again yet
+	client.do(exec, "INCR", "a")
+	client.reply(t)
+	exec.Cron()
+	assert.False(t, exec.aof.RewriteInProgress())
+}
@@ -0,0 +1,241 @@
+package datastore
+
+import (
+	"container/heap"
+	"container/list"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+)
+
+// blockedClient is a client parked by a blocking command.
+type blockedClient struct {
+	value   Value
+	handler commands.BlockingHandler
+	block   *commands.Block
+	// timeoutReply is sent if the wait times out or is cancelled.
+	timeoutReply common.RespValue
+	// wait is set for WAIT and WAITAOF, which wait for acknowledgements
+	// rather than keys and time out with how many they got.
+	wait *waitTarget
+	// deadline is the unix millisecond time the wait times out, or 0.
+	deadline int64
+	// timeoutIndex is the client's place in the timeout heap, or -1 when
+	// it is not there.
+	timeoutIndex int
+	// waits holds the client's entry in the waiter list of each key.
+	waits     []*list.Element
+	unblocked bool
+}
+
+// blockingState tracks every blocked client. Like Redis, each key maps to
+// a FIFO list of the clients waiting on it, so the client that has waited
+// longest is served first.
+type blockingState struct {
+	keys    map[string]*list.List
+	clients map[chan common.RespValue]*blockedClient
+	// queued holds the commands a blocked client pipelined after the one
+	// it is blocked on; they run, in order, once it is unblocked.
+	queued   map[chan common.RespValue][]Value
+	timeouts timeoutHeap
+	// waiting holds the clients blocked by WAIT or WAITAOF, in the order
+	// they blocked. Clients unblocked some other way are skipped.
+	waiting   []*blockedClient
+	readyKeys []string
+	readySet  map[string]struct{}
+}
+
+func newBlockingState() blockingState {
+	return blockingState{
+		keys:     make(map[string]*list.List),
+		clients:  make(map[chan common.RespValue]*blockedClient),
+		queued:   make(map[chan common.RespValue][]Value),
+		readySet: make(map[string]struct{}),
+	}
+}
+
+// BlockedClients returns the number of clients waiting on a blocking
+// command.
+func (e *Executor) BlockedClients() int {
+	return len(e.blocking.clients)
+}
+
+// signalKeyAsReady is the keyspace add hook. It remembers keys that have
+// waiters so serveReadyKeys can try to serve them once the current command
+// has finished.
+func (e *Executor) signalKeyAsReady(key string) {
+	if _, waited := e.blocking.keys[key]; !waited {
+		return
+	}
+	if _, queued := e.blocking.readySet[key]; queued {
+		return
+	}
+	e.blocking.readySet[key] = struct{}{}
+	e.blocking.readyKeys = append(e.blocking.readyKeys, key)
+}
+
+func (e *Executor) blockClient(value Value, handler commands.BlockingHandler, block *commands.Block, timeoutReply common.RespValue) *blockedClient {
+	client := &blockedClient{
+		value:        value,
+		handler:      handler,
+		block:        block,
+		timeoutReply: timeoutReply,
+		timeoutIndex: -1,
+	}
+	if block.Timeout > 0 {
+		client.deadline = e.dataStore.Now() + max(block.Timeout.Milliseconds(), 1)
+		heap.Push(&e.blocking.timeouts, client)
+	}
+
+	for _, key := range block.Keys {
+		waiters, exists := e.blocking.keys[key]
+		if !exists {
+			waiters = list.New()
+			e.blocking.keys[key] = waiters
+		}
+		client.waits = append(client.waits, waiters.PushBack(client))
+	}
+	e.blocking.clients[value.ResponseChan] = client
+	value.Session.setBlocked(true)
+	return client
+}
+
+func (e *Executor) unblockClient(client *blockedClient) {
+	client.unblocked = true
+	if client.timeoutIndex >= 0 {
+		heap.Remove(&e.blocking.timeouts, client.timeoutIndex)
+	}
+	for i, key := range client.block.Keys {
+		waiters := e.blocking.keys[key]
+		waiters.Remove(client.waits[i])
+		if waiters.Len() == 0 {
+			delete(e.blocking.keys, key)
+		}
+	}
+	delete(e.blocking.clients, client.value.ResponseChan)
+	client.value.Session.setBlocked(false)
+}
+
+// serveReadyKeys retries the clients blocked on every key that received a
+// value, oldest waiter first, until the key runs dry. Serving a client can
+// make other keys ready, for example BLMOVE pushing to a list someone else
+// is waiting on, so it loops until nothing is left.
+func (e *Executor) serveReadyKeys() {
+	for len(e.blocking.readyKeys) > 0 {
+		readyKeys := e.blocking.readyKeys
+		e.blocking.readyKeys = nil
+		clear(e.blocking.readySet)
+
+		for _, key := range readyKeys {
+			waiters, exists := e.blocking.keys[key]
+			if !exists {
+				continue
+			}
+			for element := waiters.Front(); element != nil; {
+				object := e.dataStore.LookupRead(key)
+				if object == nil {
+					break
+				}
+				next := element.Next()
+				client := element.Value.(*blockedClient)
+				// a key of another type stays blocked, as in Redis
+				if object.Type == client.block.Type {
+					e.retryBlockedClient(client)
+				}
+				element = next
+			}
+		}
+	}
+}
+
+func (e *Executor) retryBlockedClient(client *blockedClient) {
+	var block *commands.Block
+	reply := e.call(client.value.Command, func() common.RespValue {
+		var reply common.RespValue
+		reply, block = client.handler(client.value.Command, e.dataStore)
+		return reply
+	})
+	if block != nil {
+		return
+	}
+	e.unblockClient(client)
+	e.reply(client.value, reply)
+	e.resumeClient(client.value.ResponseChan)
+}
+
+// resumeClient runs the commands a client queued while it was blocked,
+// stopping early if one of them blocks again. While clients are paused they
+// may be held instead.
+func (e *Executor) resumeClient(responseChan chan common.RespValue) {
+	for {
+		if _, blocked := e.blocking.clients[responseChan]; blocked {
+			return
+		}
+		queued := e.blocking.queued[responseChan]
+		if len(queued) == 0 {
+			delete(e.blocking.queued, responseChan)
+			return
+		}
+		e.blocking.queued[responseChan] = queued[1:]
+		e.dispatch(queued[0])
+	}
+}
+
+// timeoutBlockedClients unblocks every client whose deadline has passed
+// with the command's timeout reply.
+func (e *Executor) timeoutBlockedClients() {
+	now := e.dataStore.Now()
+	for len(e.blocking.timeouts) > 0 {
+		client := e.blocking.timeouts[0]
+		// the clock has millisecond resolution, so wait for the deadline to
+		// pass rather than merely be reached, or a wait could end early
+		if client.deadline >= now {
+			return
+		}
+		heap.Pop(&e.blocking.timeouts)
+		e.unblockClient(client)
+		if client.wait != nil {
+			e.reply(client.value, e.waitReply(client.wait))
+		} else {
+			e.reply(client.value, client.timeoutReply)
+		}
+		e.resumeClient(client.value.ResponseChan)
+	}
+}
+
+// cancelBlockedClient drops the blocked command of a connection that is
+// going away, along with the commands queued behind it, without replying.
+func (e *Executor) cancelBlockedClient(responseChan chan common.RespValue) {
+	if client, blocked := e.blocking.clients[responseChan]; blocked {
+		e.unblockClient(client)
+	}
+	delete(e.blocking.queued, responseChan)
+}
+
+// timeoutHeap orders blocked clients by deadline. Each client knows its
+// place in it, so that one unblocked some other way is taken out at once.
+type timeoutHeap []*blockedClient
+
+func (h timeoutHeap) Len() int           { return len(h) }
+func (h timeoutHeap) Less(i, j int) bool { return h[i].deadline < h[j].deadline }
+
+func (h timeoutHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].timeoutIndex = i
+	h[j].timeoutIndex = j
+}
+
+func (h *timeoutHeap) Push(x any) {
+	client := x.(*blockedClient)
+	client.timeoutIndex = len(*h)
+	*h = append(*h, client)
+}
+
+func (h *timeoutHeap) Pop() any {
+	old := *h
+	client := old[len(old)-1]
+	old[len(old)-1] = nil
+	client.timeoutIndex = -1
+	*h = old[:len(old)-1]
+	return client
+}
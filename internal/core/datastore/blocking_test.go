@@ -0,0 +1,242 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// testClient stands in for a connection: commands go through Handle and
+// replies land on its channel.
+type testClient struct {
+	responses chan common.RespValue
+	session   *Session
+	killed    bool
+}
+
+func newTestClient() *testClient {
+	c := &testClient{responses: make(chan common.RespValue, 16)}
+	c.session = &Session{Kill: func() { c.killed = true }}
+	return c
+}
+
+func (c *testClient) do(exec *Executor, name string, args ...string) {
+	exec.Handle(Value{ResponseChan: c.responses, Session: c.session, Command: makeCommand(name, args...)})
+}
+
+func (c *testClient) disconnect(exec *Executor) {
+	exec.Handle(Value{ResponseChan: c.responses, Session: c.session, Disconnect: true})
+}
+
+func (c *testClient) reply(t *testing.T) common.RespValue {
+	t.Helper()
+	select {
+	case resp := <-c.responses:
+		return resp
+	default:
+		require.FailNow(t, "expected a reply")
+		return common.RespValue{}
+	}
+}
+
+func (c *testClient) assertNoReply(t *testing.T) {
+	t.Helper()
+	assert.Empty(t, c.responses)
+}
+
+// assertClosed drains any replies and checks the executor closed the
+// channel.
+func (c *testClient) assertClosed(t *testing.T) {
+	t.Helper()
+	for {
+		select {
+		case _, open := <-c.responses:
+			if !open {
+				return
+			}
+		default:
+			require.FailNow(t, "expected the response channel to be closed")
+		}
+	}
+}
+
+func replyStrings(resp common.RespValue) []string {
+	values := []string{}
+	for _, element := range resp.Array {
+		values = append(values, element.Str)
+	}
+	return values
+}
+
+func TestBlockingPopServedByPush(t *testing.T) {
+	exec := NewExecutor()
+	worker, producer := newTestClient(), newTestClient()
+
+	worker.do(exec, "BLPOP", "a", "b", "0")
+	worker.assertNoReply(t)
+	assert.Equal(t, 1, exec.BlockedClients())
+
+	producer.do(exec, "RPUSH", "b", "x", "y")
+	assert.Equal(t, int64(2), producer.reply(t).Int)
+	assert.Equal(t, []string{"b", "x"}, replyStrings(worker.reply(t)))
+	assert.Equal(t, 0, exec.BlockedClients())
+
+	// the waiter took one element, the rest stays
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("LLEN", "b")).Int)
+}
+
+func TestBlockingPopServesWaitersInOrder(t *testing.T) {
+	exec := NewExecutor()
+	first, second, producer := newTestClient(), newTestClient(), newTestClient()
+
+	first.do(exec, "BRPOP", "q", "0")
+	second.do(exec, "BRPOP", "q", "0")
+
+	producer.do(exec, "LPUSH", "q", "1")
+	assert.Equal(t, []string{"q", "1"}, replyStrings(first.reply(t)))
+	second.assertNoReply(t)
+
+	producer.do(exec, "LPUSH", "q", "2", "3")
+	assert.Equal(t, []string{"q", "2"}, replyStrings(second.reply(t)))
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("LLEN", "q")).Int)
+}
+
+func TestBlockingPopTimeout(t *testing.T) {
+	exec := NewExecutor()
+	now := int64(1_000_000)
+	exec.dataStore.SetClock(func() int64 { return now })
+	worker := newTestClient()
+
+	worker.do(exec, "BLPOP", "q", "1.5")
+	now += 1400
+	exec.Cron()
+	worker.assertNoReply(t)
+
+	// still waiting at exactly the deadline
+	now += 100
+	exec.Cron()
+	worker.assertNoReply(t)
+
+	now++
+	exec.Cron()
+	assert.Equal(t, nullArray(), worker.reply(t))
+	assert.Equal(t, 0, exec.BlockedClients())
+
+	// a timed-out client is no longer a waiter
+	exec.Execute(makeCommand("RPUSH", "q", "x"))
+	exec.serveReadyKeys()
+	worker.assertNoReply(t)
+}
+
+func TestBlockingServedClientLeavesTimeouts(t *testing.T) {
+	exec := NewExecutor()
+	workers := []*testClient{newTestClient(), newTestClient(), newTestClient()}
+	for _, worker := range workers {
+		worker.do(exec, "BLPOP", "q", "300")
+	}
+	require.Len(t, exec.blocking.timeouts, 3)
+
+	// served by a push, by a disconnect and by a timeout
+	exec.Execute(makeCommand("RPUSH", "q", "x"))
+	exec.serveReadyKeys()
+	assert.Equal(t, []string{"q", "x"}, replyStrings(workers[0].reply(t)))
+	workers[1].disconnect(exec)
+	assert.Len(t, exec.blocking.timeouts, 1)
+	for i, client := range exec.blocking.timeouts {
+		assert.Equal(t, i, client.timeoutIndex)
+	}
+
+	exec.blocking.timeouts[0].deadline = 0
+	exec.Cron()
+	assert.Equal(t, nullArray(), workers[2].reply(t))
+	assert.Empty(t, exec.blocking.timeouts)
+}
+
+func TestBlockingTimeoutServesReadyKeys(t *testing.T) {
+	exec := NewExecutor()
+	now := int64(1_000_000)
+	exec.dataStore.SetClock(func() int64 { return now })
+	producer, consumer := newTestClient(), newTestClient()
+
+	consumer.do(exec, "BLPOP", "b", "0")
+	producer.do(exec, "BLPOP", "a", "1")
+	producer.do(exec, "RPUSH", "b", "x")
+	consumer.assertNoReply(t)
+
+	// the push runs once the producer times out, and feeds the consumer
+	now += 1001
+	exec.Cron()
+	assert.Equal(t, nullArray(), producer.reply(t))
+	assert.Equal(t, int64(1), producer.reply(t).Int)
+	assert.Equal(t, []string{"b", "x"}, replyStrings(consumer.reply(t)))
+	assert.Equal(t, 0, exec.BlockedClients())
+}
+
+func TestBlockedClientQueuesLaterCommands(t *testing.T) {
+	exec := NewExecutor()
+	worker, producer := newTestClient(), newTestClient()
+
+	worker.do(exec, "BLPOP", "q", "0")
+	worker.do(exec, "SET", "k", "v")
+	worker.do(exec, "BLPOP", "q", "0")
+	worker.assertNoReply(t)
+	assert.False(t, exec.dataStore.Exists("k"))
+
+	producer.do(exec, "RPUSH", "q", "1")
+	assert.Equal(t, []string{"q", "1"}, replyStrings(worker.reply(t)))
+	assert.Equal(t, "OK", worker.reply(t).Str)
+	worker.assertNoReply(t)
+	assert.Equal(t, 1, exec.BlockedClients())
+
+	producer.do(exec, "RPUSH", "q", "2")
+	assert.Equal(t, []string{"q", "2"}, replyStrings(worker.reply(t)))
+}
+
+func TestBlockingPopDisconnect(t *testing.T) {
+	exec := NewExecutor()
+	worker, producer := newTestClient(), newTestClient()
+
+	worker.do(exec, "BLPOP", "q", "0")
+	worker.do(exec, "GET", "k")
+	worker.disconnect(exec)
+
+	// nobody is left to read replies, so the channel is just closed
+	worker.assertClosed(t)
+	assert.Equal(t, 0, exec.BlockedClients())
+
+	producer.do(exec, "RPUSH", "q", "x")
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("LLEN", "q")).Int)
+}
+
+func TestBlockingMoveWakesChainedWaiter(t *testing.T) {
+	exec := NewExecutor()
+	mover, consumer, producer := newTestClient(), newTestClient(), newTestClient()
+
+	mover.do(exec, "BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	consumer.do(exec, "BLPOP", "dst", "0")
+
+	producer.do(exec, "RPUSH", "src", "job")
+	assert.Equal(t, "job", mover.reply(t).Str)
+	assert.Equal(t, []string{"dst", "job"}, replyStrings(consumer.reply(t)))
+	assert.False(t, exec.dataStore.Exists("dst"))
+}
+
+func TestBlockingWrongTypeKeepsWaiting(t *testing.T) {
+	exec := NewExecutor()
+	worker, producer := newTestClient(), newTestClient()
+
+	worker.do(exec, "BZPOPMAX", "z", "0")
+	producer.do(exec, "SET", "z", "string")
+	worker.assertNoReply(t)
+
+	producer.do(exec, "DEL", "z")
+	producer.do(exec, "ZADD", "z", "1", "a", "2", "b")
+	assert.Equal(t, []string{"z", "b", "2"}, replyStrings(worker.reply(t)))
+}
+
+func nullArray() common.RespValue {
+	return common.RespValue{Type: enums.ArrayRespType, IsNull: true}
+}
@@ -0,0 +1,376 @@
+package datastore
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// The client types CLIENT LIST TYPE and CLIENT KILL TYPE select. A server
+// has no client of type master: its link to its own master is not a
+// connection that was accepted.
+const (
+	clientTypeNormal  = "normal"
+	clientTypeMaster  = "master"
+	clientTypeReplica = "replica"
+	clientTypePubsub  = "pubsub"
+)
+
+// registerClient adds the connection to the registry the first time the
+// executor hears from it, giving it the next ID.
+func (e *Executor) registerClient(value Value) {
+	session := value.Session
+	if session == nil || session.id != 0 {
+		return
+	}
+	e.nextClientID++
+	session.id = e.nextClientID
+	session.responseChan = value.ResponseChan
+	session.created = time.Now()
+	session.lastInteraction = session.created
+	e.clients[value.ResponseChan] = session
+}
+
+// touchClient records that the connection sent a command, and whether the
+// reply to it is skipped because the command before was CLIENT REPLY SKIP.
+func (e *Executor) touchClient(value Value) {
+	e.registerClient(value)
+	session := value.Session
+	if session == nil {
+		return
+	}
+	session.lastInteraction = time.Now()
+	session.lastCommand = strings.ToLower(value.Command.Name)
+	if session.reply == replySkipNext {
+		session.reply = replyOn
+		session.skipping = true
+	}
+}
+
+// endCommand ends the skipping of the reply to the command just processed.
+// The reply to a command that blocked comes later and is not skipped.
+func (s *Session) endCommand() {
+	if s != nil {
+		s.skipping = false
+	}
+}
+
+// silenced reports whether replies to the connection are dropped.
+func (s *Session) silenced() bool {
+	return s != nil && (s.reply != replyOn || s.skipping)
+}
+
+// clientType returns the type of the connection, as CLIENT LIST TYPE
+// names it. Like Redis, it counts a monitor as a normal client.
+func (e *Executor) clientType(session *Session) string {
+	if _, replica := e.replication.replicas[session.responseChan]; replica {
+		return clientTypeReplica
+	}
+	if e.pubsub.subscribed(session.responseChan) {
+		return clientTypePubsub
+	}
+	return clientTypeNormal
+}
+
+// parseClientType parses the argument of a TYPE filter, which accepts
+// slave as another name for replica.
+func parseClientType(arg string) (string, bool) {
+	switch t := strings.ToLower(arg); t {
+	case clientTypeNormal, clientTypeMaster, clientTypeReplica, clientTypePubsub:
+		return t, true
+	case "slave":
+		return clientTypeReplica, true
+	default:
+		return "", false
+	}
+}
+
+// registeredClients returns the connections in the registry, the oldest
+// first.
+func (e *Executor) registeredClients() []*Session {
+	return slices.SortedFunc(maps.Values(e.clients), func(a, b *Session) int {
+		return cmp.Compare(a.id, b.id)
+	})
+}
+
+// clientInfo describes the connection on one line, in the format of
+// Redis's CLIENT LIST, with the fields Mnemo has.
+func (e *Executor) clientInfo(session *Session, now time.Time) string {
+	var flags strings.Builder
+	if e.clientType(session) == clientTypeReplica {
+		flags.WriteByte('S')
+	}
+	if e.pubsub.subscribed(session.responseChan) {
+		flags.WriteByte('P')
+	}
+	multi := -1
+	if tx := e.transactions.clients[session.responseChan]; tx != nil && tx.inMulti {
+		flags.WriteByte('x')
+		multi = len(tx.queued)
+	}
+	if _, blocked := e.blocking.clients[session.responseChan]; blocked {
+		flags.WriteByte('b')
+	}
+	if e.monitoring(session.responseChan) {
+		flags.WriteByte('O')
+	}
+	if session.noEvict {
+		flags.WriteByte('e')
+	}
+	if flags.Len() == 0 {
+		flags.WriteByte('N')
+	}
+	var channels, patterns int
+	if sub := e.pubsub.subscribers[session.responseChan]; sub != nil {
+		channels, patterns = len(sub.channels), len(sub.patterns)
+	}
+	command := session.lastCommand
+	if command == "" {
+		command = "NULL"
+	}
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=0 sub=%d psub=%d multi=%d qbuf=%d qbuf-free=%d obl=%d oll=%d cmd=%s user=default resp=2",
+		session.id, session.Addr, session.LocalAddr, session.name,
+		int64(now.Sub(session.created).Seconds()), int64(now.Sub(session.lastInteraction).Seconds()),
+		flags.String(), channels, patterns, multi,
+		session.queryBuffer.Load(), session.queryBufferFree.Load(), session.outputBuffer.Load(),
+		len(session.responseChan), command)
+}
+
+// handleClient implements the CLIENT subcommands, which show and manage
+// the connections: LIST, INFO, ID, SETNAME, GETNAME, KILL, PAUSE,
+// UNPAUSE, NO-EVICT and REPLY.
+func (e *Executor) handleClient(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+	unknown := errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try CLIENT HELP.", args[0]))
+	subcommand := strings.ToUpper(args[0])
+	switch subcommand {
+	case "LIST":
+		return e.clientList(args[1:])
+	case "KILL":
+		return e.clientKill(args[1:])
+	case "PAUSE":
+		if len(args) != 2 && len(args) != 3 {
+			return unknown
+		}
+		return e.clientPause(args[1:])
+	case "UNPAUSE":
+		if len(args) != 1 {
+			return unknown
+		}
+		e.unpause()
+		return okValue()
+	}
+	// the rest are about the client itself
+	if e.client == nil || e.client.id == 0 {
+		return errorValue(fmt.Sprintf("ERR CLIENT %s needs a connection", subcommand))
+	}
+	switch {
+	case subcommand == "INFO" && len(args) == 1:
+		return common.RespValue{Type: enums.BulkStringRespType, Str: e.clientInfo(e.client, time.Now()) + "\n"}
+	case subcommand == "ID" && len(args) == 1:
+		return common.RespValue{Type: enums.IntRespType, Int: e.client.id}
+	case subcommand == "SETNAME" && len(args) == 2:
+		if strings.ContainsFunc(args[1], func(r rune) bool { return r < '!' || r > '~' }) {
+			return errorValue("ERR Client names cannot contain spaces, newlines or special characters.")
+		}
+		e.client.name = args[1]
+		return okValue()
+	case subcommand == "GETNAME" && len(args) == 1:
+		if e.client.name == "" {
+			return common.RespValue{Type: enums.BulkStringRespType, IsNull: true}
+		}
+		return common.RespValue{Type: enums.BulkStringRespType, Str: e.client.name}
+	case subcommand == "NO-EVICT" && len(args) == 2:
+		switch strings.ToUpper(args[1]) {
+		case "ON":
+			e.client.noEvict = true
+		case "OFF":
+			e.client.noEvict = false
+		default:
+			return errorValue("ERR syntax error")
+		}
+		return okValue()
+	case subcommand == "REPLY" && len(args) == 2:
+		// OFF and SKIP are not answered; the reply to this command is
+		// dropped like the ones after it
+		switch strings.ToUpper(args[1]) {
+		case "ON":
+			e.client.reply = replyOn
+		case "OFF":
+			e.client.reply = replyOff
+		case "SKIP":
+			e.client.reply = replySkipNext
+		default:
+			return errorValue("ERR syntax error")
+		}
+		return okValue()
+	default:
+		return unknown
+	}
+}
+
+// clientList implements CLIENT LIST [TYPE type] [ID id ...].
+func (e *Executor) clientList(args []string) common.RespValue {
+	clientType := ""
+	var ids map[int64]bool
+	for len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "TYPE":
+			if len(args) < 2 {
+				return errorValue("ERR syntax error")
+			}
+			t, ok := parseClientType(args[1])
+			if !ok {
+				return errorValue(fmt.Sprintf("ERR Unknown client type '%s'", args[1]))
+			}
+			clientType, args = t, args[2:]
+		case "ID":
+			if len(args) < 2 {
+				return errorValue("ERR syntax error")
+			}
+			ids = make(map[int64]bool)
+			for _, arg := range args[1:] {
+				id, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil || id <= 0 {
+					return errorValue("ERR Invalid client ID")
+				}
+				ids[id] = true
+			}
+			args = nil
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+	var b strings.Builder
+	now := time.Now()
+	for _, session := range e.registeredClients() {
+		if clientType != "" && e.clientType(session) != clientType {
+			continue
+		}
+		if ids != nil && !ids[session.id] {
+			continue
+		}
+		b.WriteString(e.clientInfo(session, now))
+		b.WriteByte('\n')
+	}
+	return common.RespValue{Type: enums.BulkStringRespType, Str: b.String()}
+}
+
+// clientFilter selects the clients CLIENT KILL kills. Its zero value
+// selects them all but the one that sent the command.
+type clientFilter struct {
+	id              int64
+	addr, localAddr string
+	clientType      string
+	keepSelf        bool
+	// maxAge selects the clients connected for longer, if it is set
+	maxAge time.Duration
+}
+
+func (f clientFilter) matches(e *Executor, session *Session, now time.Time) bool {
+	switch {
+	case f.id != 0 && session.id != f.id,
+		f.addr != "" && session.Addr != f.addr,
+		f.localAddr != "" && session.LocalAddr != f.localAddr,
+		f.clientType != "" && e.clientType(session) != f.clientType,
+		f.keepSelf && session == e.client,
+		f.maxAge != 0 && now.Sub(session.created) <= f.maxAge:
+		return false
+	}
+	return true
+}
+
+// clientKill implements CLIENT KILL, both the old form, CLIENT KILL
+// addr, which replies OK, and the new one, which takes filters and
+// replies with the number of clients killed.
+func (e *Executor) clientKill(args []string) common.RespValue {
+	if len(args) == 1 {
+		for _, session := range e.registeredClients() {
+			if session.Addr == args[0] {
+				e.killClient(session)
+				return okValue()
+			}
+		}
+		return errorValue("ERR No such client")
+	}
+	if len(args) == 0 || len(args)%2 != 0 {
+		return errorValue("ERR syntax error")
+	}
+	filter := clientFilter{keepSelf: true}
+	for i := 0; i < len(args); i += 2 {
+		arg := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || id <= 0 {
+				return errorValue("ERR client-id should be greater than 0")
+			}
+			filter.id = id
+		case "ADDR":
+			filter.addr = arg
+		case "LADDR":
+			filter.localAddr = arg
+		case "USER":
+			// every client is the default user; there are no others
+			if arg != "default" {
+				return errorValue(fmt.Sprintf("ERR No such user '%s'", arg))
+			}
+		case "TYPE":
+			t, ok := parseClientType(arg)
+			if !ok {
+				return errorValue(fmt.Sprintf("ERR Unknown client type '%s'", arg))
+			}
+			filter.clientType = t
+		case "SKIPME":
+			switch strings.ToLower(arg) {
+			case "yes":
+				filter.keepSelf = true
+			case "no":
+				filter.keepSelf = false
+			default:
+				return errorValue("ERR syntax error")
+			}
+		case "MAXAGE":
+			seconds, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || seconds <= 0 {
+				return errorValue("ERR syntax error")
+			}
+			filter.maxAge = time.Duration(seconds) * time.Second
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+	killed := 0
+	now := time.Now()
+	for _, session := range e.registeredClients() {
+		if filter.matches(e, session, now) {
+			e.killClient(session)
+			killed++
+		}
+	}
+	return common.RespValue{Type: enums.IntRespType, Int: int64(killed)}
+}
+
+// killClient disconnects a client. A client killing itself is only
+// stopped from reading, so that it still gets the reply before its
+// connection closes; the rest of what it sent is ignored.
+func (e *Executor) killClient(session *Session) {
+	if session != e.client {
+		e.dropClient(session.responseChan, session)
+		return
+	}
+	delete(e.clients, session.responseChan)
+	e.dropped[session.responseChan] = struct{}{}
+	session.closeRead()
+}
@@ -0,0 +1,225 @@
+package datastore
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// connect registers client with the executor, as a new connection does.
+func (c *testClient) connect(exec *Executor, addr string) {
+	c.session.Addr = addr
+	c.session.LocalAddr = "127.0.0.1:6379"
+	exec.Handle(Value{ResponseChan: c.responses, Session: c.session, Connect: true})
+}
+
+// clientList returns the lines of a CLIENT LIST reply.
+func clientList(t *testing.T, c *testClient, exec *Executor, args ...string) []string {
+	t.Helper()
+	c.do(exec, "CLIENT", append([]string{"LIST"}, args...)...)
+	reply := c.reply(t)
+	require.Equal(t, enums.BulkStringRespType, reply.Type, reply.Str)
+	return strings.Split(strings.TrimSuffix(reply.Str, "\n"), "\n")
+}
+
+func TestClientList(t *testing.T) {
+	exec := NewExecutor()
+	a, b, idle := newTestClient(), newTestClient(), newTestClient()
+	a.connect(exec, "10.0.0.1:1000")
+	b.connect(exec, "10.0.0.2:2000")
+	idle.connect(exec, "10.0.0.3:3000")
+
+	a.do(exec, "CLIENT", "ID")
+	assert.Equal(t, int64(1), a.reply(t).Int)
+	b.do(exec, "CLIENT", "GETNAME")
+	assert.True(t, b.reply(t).IsNull)
+	b.do(exec, "CLIENT", "SETNAME", "worker-1")
+	assert.Equal(t, "OK", b.reply(t).Str)
+	b.do(exec, "CLIENT", "GETNAME")
+	assert.Equal(t, "worker-1", b.reply(t).Str)
+	b.do(exec, "CLIENT", "SETNAME", "two words")
+	assert.Equal(t, "ERR Client names cannot contain spaces, newlines or special characters.", b.reply(t).Str)
+	b.do(exec, "SUBSCRIBE", "news")
+	b.reply(t)
+	a.do(exec, "MULTI")
+	a.reply(t)
+	a.do(exec, "SET", "k", "v")
+	a.reply(t)
+
+	// a is inside MULTI, so ask without a connection
+	reply := exec.Execute(makeCommand("CLIENT", "LIST"))
+	lines := strings.Split(strings.TrimSuffix(reply.Str, "\n"), "\n")
+	require.Len(t, lines, 3, reply.Str)
+	assert.Regexp(t, `^id=1 addr=10\.0\.0\.1:1000 laddr=127\.0\.0\.1:6379 name= age=0 idle=0 flags=x db=0 sub=0 psub=0 multi=1 qbuf=0 qbuf-free=0 obl=0 oll=0 cmd=set user=default resp=2$`, lines[0])
+	assert.Regexp(t, `^id=2 addr=10\.0\.0\.2:2000 .* name=worker-1 .* flags=P db=0 sub=1 psub=0 multi=-1 .* cmd=subscribe `, lines[1])
+	assert.Regexp(t, `^id=3 .* flags=N .* cmd=NULL `, lines[2], "a client that has sent nothing")
+	a.do(exec, "DISCARD")
+	a.reply(t)
+
+	assert.Len(t, clientList(t, a, exec, "TYPE", "pubsub"), 1)
+	assert.Len(t, clientList(t, a, exec, "TYPE", "normal"), 2)
+	assert.Equal(t, []string{""}, clientList(t, a, exec, "TYPE", "master"))
+	lines = clientList(t, a, exec, "ID", "3", "1", "9")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "id=1 "))
+	assert.True(t, strings.HasPrefix(lines[1], "id=3 "))
+
+	a.do(exec, "CLIENT", "INFO")
+	info := a.reply(t).Str
+	assert.True(t, strings.HasPrefix(info, "id=1 ") && strings.HasSuffix(info, "\n"), info)
+
+	for _, args := range [][]string{
+		{"LIST", "TYPE", "nobody"},
+		{"LIST", "ID", "x"},
+		{"LIST", "FOO"},
+		{"SETNAME"},
+		{"NOSUCH"},
+	} {
+		a.do(exec, "CLIENT", args...)
+		assert.Equal(t, enums.ErrorRespType, a.reply(t).Type, args)
+	}
+
+	idle.disconnect(exec)
+	assert.Len(t, clientList(t, a, exec), 2, "a closed connection leaves the registry")
+}
+
+func TestClientKill(t *testing.T) {
+	exec := NewExecutor()
+	a, b, c := newTestClient(), newTestClient(), newTestClient()
+	a.connect(exec, "10.0.0.1:1000")
+	b.connect(exec, "10.0.0.2:2000")
+	c.connect(exec, "10.0.0.3:3000")
+
+	a.do(exec, "CLIENT", "KILL", "10.0.0.9:9000")
+	assert.Equal(t, "ERR No such client", a.reply(t).Str)
+	a.do(exec, "CLIENT", "KILL", "10.0.0.2:2000")
+	assert.Equal(t, "OK", a.reply(t).Str)
+	assert.True(t, b.killed)
+	b.do(exec, "PING")
+	b.assertNoReply(t)
+	b.disconnect(exec)
+	b.assertClosed(t)
+
+	a.do(exec, "CLIENT", "KILL", "USER", "nobody")
+	assert.Equal(t, "ERR No such user 'nobody'", a.reply(t).Str)
+	a.do(exec, "CLIENT", "KILL", "ID", "0")
+	assert.Equal(t, "ERR client-id should be greater than 0", a.reply(t).Str)
+	a.do(exec, "CLIENT", "KILL", "TYPE", "pubsub")
+	assert.Equal(t, int64(0), a.reply(t).Int)
+
+	// the filters skip the client killing, unless told not to
+	a.do(exec, "CLIENT", "KILL", "USER", "default")
+	assert.Equal(t, int64(1), a.reply(t).Int)
+	assert.True(t, c.killed)
+	assert.False(t, a.killed)
+
+	a.do(exec, "CLIENT", "KILL", "ID", "1", "SKIPME", "no")
+	assert.Equal(t, int64(1), a.reply(t).Int, "a client killing itself still gets the reply")
+	assert.True(t, a.killed)
+	a.do(exec, "PING")
+	a.assertNoReply(t)
+	a.disconnect(exec)
+	a.assertClosed(t)
+	assert.Empty(t, exec.clients)
+}
+
+func TestClientPause(t *testing.T) {
+	exec := NewExecutor()
+	admin, writer, reader := newTestClient(), newTestClient(), newTestClient()
+
+	admin.do(exec, "CLIENT", "PAUSE", "60000", "WRITE")
+	assert.Equal(t, "OK", admin.reply(t).Str)
+	writer.do(exec, "SET", "k", "v")
+	writer.do(exec, "GET", "k")
+	writer.assertNoReply(t)
+	reader.do(exec, "GET", "k")
+	assert.True(t, reader.reply(t).IsNull, "reads go on while writes are paused")
+	reader.do(exec, "PUBLISH", "news", "hi")
+	reader.assertNoReply(t)
+
+	admin.do(exec, "CLIENT", "UNPAUSE")
+	assert.Equal(t, "OK", admin.reply(t).Str)
+	assert.Equal(t, "OK", writer.reply(t).Str)
+	assert.Equal(t, "v", writer.reply(t).Str, "a client's requests keep their order")
+	assert.Equal(t, int64(0), reader.reply(t).Int)
+
+	admin.do(exec, "CLIENT", "PAUSE", "60000")
+	admin.reply(t)
+	reader.do(exec, "GET", "k")
+	reader.assertNoReply(t)
+	admin.do(exec, "CLIENT", "PAUSE", "10")
+	admin.reply(t)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), exec.pause.until, time.Second,
+		"a pause can only be made longer")
+
+	// the pause ends by itself; a held client that leaves is forgotten
+	writer.do(exec, "SET", "k", "w")
+	writer.disconnect(exec)
+	exec.pause.until = time.Now().Add(-time.Millisecond)
+	exec.Cron()
+	assert.Equal(t, "v", reader.reply(t).Str)
+	assert.Equal(t, "v", exec.Execute(makeCommand("GET", "k")).Str)
+
+	admin.do(exec, "CLIENT", "PAUSE", "-1")
+	assert.Equal(t, "ERR timeout is negative", admin.reply(t).Str)
+	admin.do(exec, "CLIENT", "PAUSE", "10", "READS")
+	assert.Equal(t, "ERR syntax error", admin.reply(t).Str)
+}
+
+func TestClientPauseWriteInsideMulti(t *testing.T) {
+	exec := NewExecutor()
+	admin, writer := newTestClient(), newTestClient()
+
+	admin.do(exec, "CLIENT", "PAUSE", "60000", "WRITE")
+	admin.reply(t)
+	writer.do(exec, "MULTI")
+	assert.Equal(t, "OK", writer.reply(t).Str)
+	writer.do(exec, "SET", "k", "v")
+	assert.Equal(t, "QUEUED", writer.reply(t).Str, "a write inside MULTI is queued, not held")
+	writer.do(exec, "GET", "k")
+	assert.Equal(t, "QUEUED", writer.reply(t).Str)
+	writer.do(exec, "EXEC")
+	writer.assertNoReply(t)
+
+	admin.do(exec, "CLIENT", "UNPAUSE")
+	admin.reply(t)
+	assert.Equal(t, []string{"OK", "v"}, replyStrings(writer.reply(t)))
+}
+
+func TestClientReply(t *testing.T) {
+	exec := NewExecutor()
+	c := newTestClient()
+
+	c.do(exec, "CLIENT", "REPLY", "OFF")
+	c.do(exec, "SET", "k", "v")
+	c.do(exec, "GET", "k")
+	c.assertNoReply(t)
+	c.do(exec, "CLIENT", "REPLY", "ON")
+	assert.Equal(t, "OK", c.reply(t).Str)
+
+	c.do(exec, "CLIENT", "REPLY", "SKIP")
+	c.do(exec, "GET", "k")
+	c.do(exec, "INCR", "n")
+	assert.Equal(t, int64(1), c.reply(t).Int, "only the reply to the next command is skipped")
+	c.assertNoReply(t)
+
+	// pushes are replies too
+	subscriber, monitor := newTestClient(), newTestClient()
+	subscriber.do(exec, "CLIENT", "REPLY", "OFF")
+	subscriber.do(exec, "SUBSCRIBE", "news")
+	monitor.do(exec, "CLIENT", "REPLY", "OFF")
+	monitor.do(exec, "MONITOR")
+	c.do(exec, "PUBLISH", "news", "hi")
+	assert.Equal(t, int64(1), c.reply(t).Int)
+	subscriber.assertNoReply(t)
+	monitor.assertNoReply(t)
+
+	c.do(exec, "CLIENT", "NO-EVICT", "ON")
+	assert.Equal(t, "OK", c.reply(t).Str)
+	assert.Contains(t, exec.clientInfo(c.session, time.Now()), " flags=e ")
+}
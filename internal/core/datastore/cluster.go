@@ -0,0 +1,404 @@
+package datastore
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// clusterState is the executor's part in cluster mode.
+type clusterState struct {
+	node *cluster.Cluster
+	// asking holds the connections that sent ASKING, whose next command
+	// may use a slot this node is importing.
+	asking map[chan common.RespValue]struct{}
+}
+
+// EnableCluster turns on cluster mode: this node serves only the keys in
+// its slots, redirecting clients elsewhere for the rest, and talks to the
+// other nodes over a bus served on listener. It must be called before the
+// executor goroutine starts, after any data is loaded.
+func (e *Executor) EnableCluster(cfg cluster.Config, listener net.Listener) error {
+	e.dataStore.IndexSlots()
+	node, err := cluster.Open(cfg, listener, func(slot int) int {
+		return e.dataStore.CountKeysInSlot(slot)
+	}, func(fn func()) bool {
+		return e.Submit(Value{run: func(*Executor) { fn() }})
+	})
+	if err != nil {
+		return err
+	}
+	node.SetSlotLostHook(e.deleteKeysInSlot)
+	e.cluster = clusterState{node: node, asking: make(map[chan common.RespValue]struct{})}
+	return nil
+}
+
+// deleteKeysInSlot deletes the keys of a slot another node has taken
+// over, replicating the deletes like any other write.
+func (e *Executor) deleteKeysInSlot(slot int) {
+	keys := e.dataStore.KeysInSlot(slot, e.dataStore.CountKeysInSlot(slot))
+	for _, key := range keys {
+		e.execute(commands.Command{Name: string(enums.DeleteCommandName), Args: []string{key}})
+	}
+}
+
+// clusterRedirect returns the error that sends a client to the node
+// serving the keys of cmds, or nil if this node serves them. All the keys
+// must hash to one slot. asking is set when the client sent ASKING, which
+// lets it use a slot being imported, as RESTORE-ASKING does itself.
+func (e *Executor) clusterRedirect(cmds []commands.Command, asking bool) *common.RespValue {
+	slot := -1
+	var keys []string
+	for _, command := range cmds {
+		if enums.StringToCommandName(command.Name) == enums.RestoreAskingCommandName {
+			asking = true
+		}
+		for _, key := range commands.Keys(command) {
+			keySlot := cluster.KeySlot(key)
+			if slot >= 0 && keySlot != slot {
+				reply := errorValue("CROSSSLOT Keys in request don't hash to the same slot")
+				return &reply
+			}
+			slot = keySlot
+			keys = append(keys, key)
+		}
+	}
+	if slot < 0 {
+		return nil
+	}
+	c := e.cluster.node
+	if !c.StateOK() {
+		reply := errorValue("CLUSTERDOWN The cluster is down")
+		return &reply
+	}
+	owner := c.SlotOwner(slot)
+	if owner == nil {
+		reply := errorValue("CLUSTERDOWN Hash slot not served")
+		return &reply
+	}
+	missing := func() int {
+		n := 0
+		for _, key := range keys {
+			if !e.dataStore.Exists(key) {
+				n++
+			}
+		}
+		return n
+	}
+	if owner == c.Myself() {
+		// keys already moved away are asked for on the node importing them
+		if target := c.Migrating(slot); target != nil {
+			if n := missing(); n == len(keys) {
+				reply := errorValue(fmt.Sprintf("ASK %d %s", slot, target.Addr()))
+				return &reply
+			} else if n > 0 {
+				reply := errorValue("TRYAGAIN Multiple keys request during rehashing of slot")
+				return &reply
+			}
+		}
+		return nil
+	}
+	if asking && c.Importing(slot) != nil {
+		if len(keys) > 1 && missing() > 0 {
+			reply := errorValue("TRYAGAIN Multiple keys request during rehashing of slot")
+			return &reply
+		}
+		return nil
+	}
+	reply := errorValue(fmt.Sprintf("MOVED %d %s", slot, owner.Addr()))
+	return &reply
+}
+
+// resetAsking clears a connection's ASKING once the command after it has
+// run, or the transaction it was sent before.
+func (e *Executor) resetAsking(responseChan chan common.RespValue, name enums.CommandName) {
+	if name == enums.AskingCommandName {
+		return
+	}
+	if tx := e.transactions.clients[responseChan]; tx != nil && tx.inMulti {
+		return
+	}
+	delete(e.cluster.asking, responseChan)
+}
+
+// handleAsking implements ASKING, which lets the connection's next
+// command use a slot this node is importing.
+func (e *Executor) handleAsking(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	if e.cluster.node == nil {
+		e.reply(value, errorValue(clusterDisabledError))
+		return
+	}
+	e.cluster.asking[value.ResponseChan] = struct{}{}
+	e.reply(value, okValue())
+}
+
+// cronCluster runs the bus's periodic work and sends clients blocked on
+// keys this node no longer serves where the keys are now.
+func (e *Executor) cronCluster() {
+	c := e.cluster.node
+	if c == nil {
+		return
+	}
+	c.Cron()
+	var redirected []*blockedClient
+	for _, client := range e.blocking.clients {
+		if client.wait == nil && len(client.block.Keys) > 0 {
+			redirected = append(redirected, client)
+		}
+	}
+	for _, client := range redirected {
+		slot := cluster.KeySlot(client.block.Keys[0])
+		var reply common.RespValue
+		switch owner := c.SlotOwner(slot); {
+		case !c.StateOK():
+			reply = errorValue("CLUSTERDOWN The cluster is down")
+		case owner == nil:
+			reply = errorValue("CLUSTERDOWN Hash slot not served")
+		case owner != c.Myself() && c.Importing(slot) == nil:
+			reply = errorValue(fmt.Sprintf("MOVED %d %s", slot, owner.Addr()))
+		default:
+			continue
+		}
+		e.unblockClient(client)
+		e.reply(client.value, reply)
+		e.resumeClient(client.value.ResponseChan)
+	}
+}
+
+const clusterDisabledError = "ERR This instance has cluster support disabled"
+
+// handleCluster implements the CLUSTER subcommands.
+func (e *Executor) handleCluster(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+	c := e.cluster.node
+	if c == nil {
+		return errorValue(clusterDisabledError)
+	}
+	subcommand := strings.ToUpper(args[0])
+	args = args[1:]
+	switch {
+	case subcommand == "INFO" && len(args) == 0:
+		return common.RespValue{Type: enums.BulkStringRespType, Str: c.Info()}
+	case subcommand == "NODES" && len(args) == 0:
+		return common.RespValue{Type: enums.BulkStringRespType, Str: c.NodesText()}
+	case subcommand == "MYID" && len(args) == 0:
+		return common.RespValue{Type: enums.BulkStringRespType, Str: c.Myself().ID}
+	case subcommand == "SLOTS" && len(args) == 0:
+		return e.clusterSlots()
+	case subcommand == "SHARDS" && len(args) == 0:
+		return e.clusterShards()
+	case subcommand == "KEYSLOT" && len(args) == 1:
+		return common.RespValue{Type: enums.IntRespType, Int: int64(cluster.KeySlot(args[0]))}
+	case subcommand == "COUNTKEYSINSLOT" && len(args) == 1:
+		slot, err := strconv.Atoi(args[0])
+		if err != nil || slot < 0 || slot >= cluster.SlotCount {
+			return errorValue("ERR Invalid slot")
+		}
+		return common.RespValue{Type: enums.IntRespType, Int: int64(e.dataStore.CountKeysInSlot(slot))}
+	case subcommand == "GETKEYSINSLOT" && len(args) == 2:
+		slot, err1 := strconv.Atoi(args[0])
+		count, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || slot < 0 || slot >= cluster.SlotCount || count < 0 {
+			return errorValue("ERR Invalid slot or number of keys")
+		}
+		keys := []*common.RespValue{}
+		for _, key := range e.dataStore.KeysInSlot(slot, count) {
+			keys = append(keys, bulkElement(key))
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: keys}
+	case subcommand == "MEET" && (len(args) == 2 || len(args) == 3):
+		port, err := strconv.Atoi(args[1])
+		busPort := 0
+		if err == nil && len(args) == 3 {
+			busPort, err = strconv.Atoi(args[2])
+		}
+		if err != nil {
+			return errorValue(fmt.Sprintf("ERR Invalid base port specified: %s", args[1]))
+		}
+		return replyFor(c.Meet(args[0], port, busPort))
+	case (subcommand == "ADDSLOTS" || subcommand == "DELSLOTS") && len(args) > 0:
+		slots, errReply := parseSlots(args)
+		if errReply != nil {
+			return *errReply
+		}
+		if subcommand == "ADDSLOTS" {
+			return replyFor(c.AddSlots(slots))
+		}
+		return replyFor(c.DelSlots(slots))
+	case (subcommand == "ADDSLOTSRANGE" || subcommand == "DELSLOTSRANGE") && len(args) > 0 && len(args)%2 == 0:
+		slots, errReply := parseSlotRanges(args)
+		if errReply != nil {
+			return *errReply
+		}
+		if subcommand == "ADDSLOTSRANGE" {
+			return replyFor(c.AddSlots(slots))
+		}
+		return replyFor(c.DelSlots(slots))
+	case subcommand == "FLUSHSLOTS" && len(args) == 0:
+		if e.dataStore.Len() != 0 {
+			return errorValue("ERR DB must be empty to perform CLUSTER FLUSHSLOTS.")
+		}
+		c.FlushSlots()
+		return okValue()
+	case subcommand == "SETSLOT" && (len(args) == 2 || len(args) == 3):
+		slots, errReply := parseSlots(args[:1])
+		if errReply != nil {
+			return *errReply
+		}
+		id := ""
+		if len(args) == 3 {
+			id = args[2]
+		} else if !strings.EqualFold(args[1], "stable") {
+			return errorValue("ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")
+		}
+		return replyFor(c.SetSlot(slots[0], args[1], id))
+	case subcommand == "FORGET" && len(args) == 1:
+		return replyFor(c.Forget(args[0]))
+	case subcommand == "SET-CONFIG-EPOCH" && len(args) == 1:
+		epoch, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return errorValue("ERR value is not an integer or out of range")
+		}
+		if epoch < 0 {
+			return errorValue(fmt.Sprintf("ERR Invalid config epoch specified: %d", epoch))
+		}
+		return replyFor(c.SetConfigEpoch(uint64(epoch)))
+	case subcommand == "BUMPEPOCH" && len(args) == 0:
+		bumped, epoch := c.BumpEpoch()
+		status := "STILL"
+		if bumped {
+			status = "BUMPED"
+		}
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: fmt.Sprintf("%s %d", status, epoch)}
+	case subcommand == "SAVECONFIG" && len(args) == 0:
+		return replyFor(c.SaveConfig())
+	case subcommand == "COUNT-FAILURE-REPORTS" && len(args) == 1:
+		count, err := c.CountFailureReports(args[0])
+		if err != nil {
+			return errorValue(err.Error())
+		}
+		return common.RespValue{Type: enums.IntRespType, Int: int64(count)}
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try CLUSTER HELP.", command.Args[0]))
+	}
+}
+
+func replyFor(err error) common.RespValue {
+	if err != nil {
+		return errorValue(err.Error())
+	}
+	return okValue()
+}
+
+func parseSlot(arg string) (int, *common.RespValue) {
+	slot, err := strconv.Atoi(arg)
+	if err != nil || slot < 0 || slot >= cluster.SlotCount {
+		reply := errorValue("ERR Invalid or out of range slot")
+		return 0, &reply
+	}
+	return slot, nil
+}
+
+func parseSlots(args []string) ([]int, *common.RespValue) {
+	slots := make([]int, 0, len(args))
+	for _, arg := range args {
+		slot, errReply := parseSlot(arg)
+		if errReply != nil {
+			return nil, errReply
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// parseSlotRanges parses start end pairs into the slots they cover.
+func parseSlotRanges(args []string) ([]int, *common.RespValue) {
+	var slots []int
+	for i := 0; i < len(args); i += 2 {
+		start, errReply := parseSlot(args[i])
+		if errReply != nil {
+			return nil, errReply
+		}
+		end, errReply := parseSlot(args[i+1])
+		if errReply != nil {
+			return nil, errReply
+		}
+		if start > end {
+			reply := errorValue(fmt.Sprintf("ERR start slot number %d is greater than end slot number %d", start, end))
+			return nil, &reply
+		}
+		for slot := start; slot <= end; slot++ {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+// clusterSlots is CLUSTER SLOTS: each range of slots with the address and
+// ID of the node serving it.
+func (e *Executor) clusterSlots() common.RespValue {
+	ranges := []*common.RespValue{}
+	for _, r := range e.cluster.node.SlotRanges() {
+		ranges = append(ranges, &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+			intElement(r.Start),
+			intElement(r.End),
+			{Type: enums.ArrayRespType, Array: []*common.RespValue{
+				bulkElement(r.Node.IP),
+				intElement(r.Node.Port),
+				bulkElement(r.Node.ID),
+				{Type: enums.ArrayRespType, Array: []*common.RespValue{}},
+			}},
+		}})
+	}
+	return common.RespValue{Type: enums.ArrayRespType, Array: ranges}
+}
+
+// clusterShards is CLUSTER SHARDS: each master with its slots, as start
+// and end pairs, and a description of the node.
+func (e *Executor) clusterShards() common.RespValue {
+	c := e.cluster.node
+	shards := []*common.RespValue{}
+	for _, node := range c.Nodes() {
+		slots := []*common.RespValue{}
+		for _, r := range node.Slots() {
+			slots = append(slots, intElement(r.Start), intElement(r.End))
+		}
+		health := "online"
+		if node.Failed() {
+			health = "failed"
+		}
+		var offset int64
+		if node == c.Myself() {
+			offset = e.replication.offset
+		}
+		description := []*common.RespValue{
+			bulkElement("id"), bulkElement(node.ID),
+			bulkElement("port"), intElement(node.Port),
+			bulkElement("ip"), bulkElement(node.IP),
+			bulkElement("endpoint"), bulkElement(node.IP),
+			bulkElement("role"), bulkElement("master"),
+			bulkElement("replication-offset"), {Type: enums.IntRespType, Int: offset},
+			bulkElement("health"), bulkElement(health),
+		}
+		shards = append(shards, &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+			bulkElement("slots"), {Type: enums.ArrayRespType, Array: slots},
+			bulkElement("nodes"), {Type: enums.ArrayRespType, Array: []*common.RespValue{
+				{Type: enums.ArrayRespType, Array: description},
+			}},
+		}})
+	}
+	return common.RespValue{Type: enums.ArrayRespType, Array: shards}
+}
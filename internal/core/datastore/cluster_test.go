@@ -0,0 +1,296 @@
+package datastore
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// clusterExecutor returns an executor in cluster mode, on its own, that
+// claims to serve clients on port.
+func clusterExecutor(t *testing.T, port int) *Executor {
+	t.Helper()
+	bus, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	exec := NewExecutor()
+	cfg := cluster.Config{
+		File:        filepath.Join(t.TempDir(), "nodes.conf"),
+		Port:        port,
+		NodeTimeout: time.Second,
+	}
+	require.NoError(t, exec.EnableCluster(cfg, bus))
+	t.Cleanup(func() {
+		exec.Close()
+		exec.Stop()
+	})
+	return exec
+}
+
+// pump runs what the bus has handed each executor, standing in for their
+// goroutines, until cond holds.
+func pump(t *testing.T, cond func() bool, execs ...*Executor) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		for _, exec := range execs {
+			exec.Cron()
+			for len(exec.ExecutorChan) > 0 {
+				exec.Handle(<-exec.ExecutorChan)
+			}
+		}
+		return cond()
+	}, 5*time.Second, time.Millisecond)
+}
+
+// twoNodeCluster returns two executors sharing the slots, the first
+// serving 0-8191 and the second the rest.
+func twoNodeCluster(t *testing.T) (*Executor, *Executor) {
+	t.Helper()
+	a, b := clusterExecutor(t, 7000), clusterExecutor(t, 7001)
+	a.Execute(makeCommand("CLUSTER", "ADDSLOTSRANGE", "0", "8191"))
+	b.Execute(makeCommand("CLUSTER", "ADDSLOTSRANGE", "8192", "16383"))
+	// distinct epochs up front, so no collision is resolved mid-test
+	a.Execute(makeCommand("CLUSTER", "SET-CONFIG-EPOCH", "1"))
+	b.Execute(makeCommand("CLUSTER", "SET-CONFIG-EPOCH", "2"))
+	busPort := strconv.Itoa(b.cluster.node.Myself().BusPort)
+	require.Equal(t, "OK", a.Execute(makeCommand("CLUSTER", "MEET", "127.0.0.1", "7001", busPort)).Str)
+	pump(t, func() bool {
+		return a.cluster.node.StateOK() && b.cluster.node.StateOK()
+	}, a, b)
+	return a, b
+}
+
+func TestClusterRedirects(t *testing.T) {
+	a, _ := twoNodeCluster(t)
+	client := newTestClient()
+	require.Equal(t, 12182, cluster.KeySlot("foo"))
+	require.Equal(t, 5061, cluster.KeySlot("bar"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"served here", []string{"SET", "bar", "1"}, "OK"},
+		{"served elsewhere", []string{"GET", "foo"}, "MOVED 12182 127.0.0.1:7001"},
+		{"keys in different slots", []string{"SINTER", "bar", "foo"}, "CROSSSLOT Keys in request don't hash to the same slot"},
+		{"hash tags share a slot", []string{"SINTERSTORE", "{bar}1", "{bar}2"}, ""},
+		{"no keys", []string{"PING"}, "PONG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.do(a, tt.args[0], tt.args[1:]...)
+			reply := client.reply(t)
+			if tt.expected == "" {
+				assert.NotEqual(t, enums.ErrorRespType, reply.Type, reply.Str)
+				return
+			}
+			assert.Equal(t, tt.expected, reply.Str)
+		})
+	}
+}
+
+func TestClusterTransactions(t *testing.T) {
+	a, _ := twoNodeCluster(t)
+	client := newTestClient()
+
+	// a queued command for another node aborts the transaction
+	client.do(a, "MULTI")
+	client.reply(t)
+	client.do(a, "SET", "foo", "1")
+	assert.Equal(t, "MOVED 12182 127.0.0.1:7001", client.reply(t).Str)
+	client.do(a, "EXEC")
+	assert.Equal(t, "EXECABORT Transaction discarded because of previous errors.", client.reply(t).Str)
+
+	// every key of the transaction must be in one slot
+	client.do(a, "MULTI")
+	client.reply(t)
+	client.do(a, "SET", "bar", "1")
+	client.reply(t)
+	client.do(a, "SET", "baz", "1")
+	client.reply(t)
+	client.do(a, "EXEC")
+	assert.Equal(t, "CROSSSLOT Keys in request don't hash to the same slot", client.reply(t).Str)
+	assert.False(t, a.dataStore.Exists("bar"))
+}
+
+func TestClusterAsk(t *testing.T) {
+	a, b := twoNodeCluster(t)
+	client := newTestClient()
+	idA, idB := a.cluster.node.Myself().ID, b.cluster.node.Myself().ID
+	a.Execute(makeCommand("SET", "bar", "1"))
+
+	assert.Equal(t, "OK", a.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "MIGRATING", idB)).Str)
+	assert.Equal(t, "OK", b.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "IMPORTING", idA)).Str)
+
+	// keys still here are served here; the rest are asked for on b
+	client.do(a, "GET", "bar")
+	assert.Equal(t, "1", client.reply(t).Str)
+	client.do(a, "GET", "{bar}x")
+	assert.Equal(t, "ASK 5061 127.0.0.1:7001", client.reply(t).Str)
+	client.do(a, "SINTER", "{bar}x", "bar")
+	assert.Equal(t, "TRYAGAIN Multiple keys request during rehashing of slot", client.reply(t).Str)
+
+	// b serves the slot only right after ASKING
+	client.do(b, "SET", "{bar}x", "2")
+	assert.Equal(t, "MOVED 5061 127.0.0.1:7000", client.reply(t).Str)
+	client.do(b, "ASKING")
+	client.reply(t)
+	client.do(b, "SET", "{bar}x", "2")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	client.do(b, "GET", "{bar}x")
+	assert.Equal(t, "MOVED 5061 127.0.0.1:7000", client.reply(t).Str)
+
+	// the slot cannot be handed over while a still holds keys in it
+	reply := a.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "NODE", idB))
+	assert.Equal(t, "ERR Can't assign hashslot 5061 to a different node while I still hold keys for this hash slot.", reply.Str)
+	a.Execute(makeCommand("DEL", "bar"))
+	assert.Equal(t, "OK", b.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "NODE", idB)).Str)
+	assert.Equal(t, "OK", a.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "NODE", idB)).Str)
+	client.do(a, "GET", "{bar}x")
+	assert.Equal(t, "MOVED 5061 127.0.0.1:7001", client.reply(t).Str)
+	client.do(b, "GET", "{bar}x")
+	assert.Equal(t, "2", client.reply(t).Str)
+}
+
+func TestClusterMovesBlockedClients(t *testing.T) {
+	a, b := twoNodeCluster(t)
+	client := newTestClient()
+	client.do(a, "BLPOP", "bar", "0")
+	client.assertNoReply(t)
+
+	// b takes the slot over, with the greatest epoch so a gives way
+	idB := b.cluster.node.Myself().ID
+	assert.Equal(t, "OK", b.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "NODE", idB)).Str)
+	b.Execute(makeCommand("CLUSTER", "BUMPEPOCH"))
+	pump(t, func() bool {
+		return a.cluster.node.SlotOwner(5061) == a.cluster.node.Node(idB)
+	}, a, b)
+	a.Cron()
+	assert.Equal(t, "MOVED 5061 127.0.0.1:7001", client.reply(t).Str)
+	assert.Equal(t, 0, a.BlockedClients())
+}
+
+func TestClusterDeletesKeysOfLostSlot(t *testing.T) {
+	a, b := twoNodeCluster(t)
+	a.Execute(makeCommand("SET", "bar", "1"))
+	a.Execute(makeCommand("SET", "{bar}2", "1"))
+	a.Execute(makeCommand("SET", "baz", "1"))
+
+	idB := b.cluster.node.Myself().ID
+	b.Execute(makeCommand("CLUSTER", "SETSLOT", "5061", "NODE", idB))
+	b.Execute(makeCommand("CLUSTER", "BUMPEPOCH"))
+	pump(t, func() bool {
+		return a.dataStore.CountKeysInSlot(5061) == 0
+	}, a, b)
+	assert.False(t, a.dataStore.Exists("bar"))
+	assert.True(t, a.dataStore.Exists("baz"))
+}
+
+func TestClusterDown(t *testing.T) {
+	exec := clusterExecutor(t, 7000)
+	client := newTestClient()
+	client.do(exec, "GET", "foo")
+	assert.Equal(t, "CLUSTERDOWN The cluster is down", client.reply(t).Str)
+	// without keys a command needs no slot
+	client.do(exec, "PING")
+	assert.Equal(t, "PONG", client.reply(t).Str)
+
+	exec.Execute(makeCommand("CLUSTER", "ADDSLOTSRANGE", "0", "16383"))
+	client.do(exec, "SET", "foo", "1")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	exec.Execute(makeCommand("CLUSTER", "DELSLOTS", "12182"))
+	client.do(exec, "GET", "foo")
+	assert.Equal(t, "CLUSTERDOWN The cluster is down", client.reply(t).Str)
+}
+
+func TestClusterCommand(t *testing.T) {
+	exec := clusterExecutor(t, 7000)
+	id := exec.cluster.node.Myself().ID
+	exec.Execute(makeCommand("CLUSTER", "ADDSLOTS", "5061", "12182"))
+	exec.Execute(makeCommand("SET", "bar", "1"))
+	exec.Execute(makeCommand("SET", "{bar}2", "1"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected any
+	}{
+		{"keyslot", []string{"KEYSLOT", "{user1000}.following"}, int64(3443)},
+		{"countkeysinslot", []string{"COUNTKEYSINSLOT", "5061"}, int64(2)},
+		{"getkeysinslot", []string{"GETKEYSINSLOT", "12182", "10"}, []string{}},
+		{"myid", []string{"MYID"}, id},
+		{"slots", []string{"SLOTS"}, 2},
+		{"shards", []string{"SHARDS"}, 1},
+		{"bumpepoch", []string{"BUMPEPOCH"}, "BUMPED 1"},
+		{"bumpepoch again", []string{"BUMPEPOCH"}, "STILL 1"},
+		{"addslots busy", []string{"ADDSLOTS", "5061"}, "ERR Slot 5061 is already busy"},
+		{"addslots twice", []string{"ADDSLOTS", "1", "1"}, "ERR Slot 1 specified multiple times"},
+		{"addslots out of range", []string{"ADDSLOTS", "16384"}, "ERR Invalid or out of range slot"},
+		{"addslotsrange backwards", []string{"ADDSLOTSRANGE", "5", "1"}, "ERR start slot number 5 is greater than end slot number 1"},
+		{"delslots unassigned", []string{"DELSLOTS", "1"}, "ERR Slot 1 is already unassigned"},
+		{"setslot unknown node", []string{"SETSLOT", "5061", "MIGRATING", "nope"}, "ERR I don't know about node nope"},
+		{"setslot not owner", []string{"SETSLOT", "1", "MIGRATING", id}, "ERR I'm not the owner of hash slot 1"},
+		{"setslot bad action", []string{"SETSLOT", "1", "SIDEWAYS", id}, "ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP"},
+		{"forget myself", []string{"FORGET", id}, "ERR I tried hard but I can't forget myself..."},
+		{"forget unknown", []string{"FORGET", "nope"}, "ERR Unknown node nope"},
+		{"meet bad address", []string{"MEET", "nowhere", "7001"}, "ERR Invalid node address specified: nowhere:7001"},
+		{"set-config-epoch", []string{"SET-CONFIG-EPOCH", "5"}, "ERR Node config epoch is already non-zero"},
+		{"flushslots with keys", []string{"FLUSHSLOTS"}, "ERR DB must be empty to perform CLUSTER FLUSHSLOTS."},
+		{"unknown subcommand", []string{"NOPE"}, "ERR unknown subcommand or wrong number of arguments for 'NOPE'. Try CLUSTER HELP."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := exec.Execute(makeCommand("CLUSTER", tt.args...))
+			switch expected := tt.expected.(type) {
+			case int64:
+				assert.Equal(t, expected, reply.Int)
+			case int:
+				assert.Len(t, reply.Array, expected)
+			case []string:
+				assert.Equal(t, expected, replyStrings(reply))
+			case string:
+				assert.Equal(t, expected, reply.Str)
+			}
+		})
+	}
+}
+
+func TestClusterNodesAndInfo(t *testing.T) {
+	exec := clusterExecutor(t, 7000)
+	exec.Execute(makeCommand("CLUSTER", "ADDSLOTSRANGE", "0", "16383"))
+	id := exec.cluster.node.Myself().ID
+
+	nodes := exec.Execute(makeCommand("CLUSTER", "NODES")).Str
+	busPort := exec.cluster.node.Myself().BusPort
+	assert.Equal(t, fmt.Sprintf("%s :7000@%d myself,master - 0 0 0 connected 0-16383\n", id, busPort), nodes)
+	info := exec.Execute(makeCommand("CLUSTER", "INFO")).Str
+	assert.Contains(t, info, "cluster_state:ok\r\n")
+	assert.Contains(t, info, "cluster_slots_assigned:16384\r\n")
+	assert.Contains(t, info, "cluster_known_nodes:1\r\n")
+	assert.Contains(t, info, "cluster_size:1\r\n")
+}
+
+func TestClusterDisabled(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+	for _, args := range [][]string{{"CLUSTER", "INFO"}, {"ASKING"}} {
+		client.do(exec, args[0], args[1:]...)
+		assert.Equal(t, "ERR This instance has cluster support disabled", client.reply(t).Str)
+	}
+}
+
+func TestReplicaOfInClusterMode(t *testing.T) {
+	exec := clusterExecutor(t, 7000)
+	reply := exec.Execute(makeCommand("REPLICAOF", "127.0.0.1", "6379"))
+	assert.Equal(t, "ERR REPLICAOF not allowed in cluster mode.", reply.Str)
+}
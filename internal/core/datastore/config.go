@@ -0,0 +1,152 @@
+package datastore
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/aof"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/config"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// SetConfig has CONFIG read and change cfg, and registers the hooks that
+// apply the executor's own mutable parameters when CONFIG SET changes
+// them. The server's other hooks are registered on cfg by its caller; all
+// of them run on the executor goroutine. Call it before the executor
+// goroutine starts.
+func (e *Executor) SetConfig(cfg *config.Config) {
+	e.config = cfg
+	setSlowlog := func(string) error {
+		e.slowlog.slowerThan = time.Duration(cfg.Int("slowlog-log-slower-than")) * time.Microsecond
+		e.slowlog.setMaxLen(int(cfg.Int("slowlog-max-len")))
+		return nil
+	}
+	setSlowlog("")
+	cfg.OnChange("slowlog-log-slower-than", setSlowlog)
+	cfg.OnChange("slowlog-max-len", setSlowlog)
+	setLatencyThreshold := func(string) error {
+		e.latency.threshold = time.Duration(cfg.Int("latency-monitor-threshold")) * time.Millisecond
+		return nil
+	}
+	setLatencyThreshold("")
+	cfg.OnChange("latency-monitor-threshold", setLatencyThreshold)
+	cfg.OnChange("save", func(value string) error {
+		rules, err := rdb.ParseSaveRules(value)
+		if err != nil {
+			return err
+		}
+		e.saving.rules = rules
+		return nil
+	})
+	cfg.OnChange("appendfsync", func(value string) error {
+		policy, err := aof.ParseFsyncPolicy(value)
+		if err != nil {
+			return err
+		}
+		if e.aof != nil {
+			e.aof.SetPolicy(policy)
+		}
+		return nil
+	})
+	setAutoRewrite := func(string) error {
+		if e.aof != nil {
+			e.aof.SetAutoRewrite(int(cfg.Int("auto-aof-rewrite-percentage")), cfg.Int("auto-aof-rewrite-min-size"))
+		}
+		return nil
+	}
+	cfg.OnChange("auto-aof-rewrite-percentage", setAutoRewrite)
+	cfg.OnChange("auto-aof-rewrite-min-size", setAutoRewrite)
+	cfg.OnChange("replica-read-only", func(value string) error {
+		e.replication.readOnly = value == "yes"
+		return nil
+	})
+	cfg.OnChange("repl-backlog-size", func(value string) error {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		e.replication.backlogSize = size
+		if e.replication.backlog != nil {
+			e.replication.backlog.Resize(size)
+		}
+		return nil
+	})
+}
+
+// handleConfig implements CONFIG GET pattern [pattern ...], CONFIG SET
+// parameter value [parameter value ...], CONFIG REWRITE and CONFIG
+// RESETSTAT.
+func (e *Executor) handleConfig(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+	subcommand := strings.ToUpper(args[0])
+	if e.config == nil && subcommand != "RESETSTAT" {
+		return errorValue("ERR CONFIG is not available without a configuration")
+	}
+	switch {
+	case subcommand == "GET" && len(args) >= 2:
+		pairs := e.config.Get(args[1:]...)
+		elements := make([]*common.RespValue, len(pairs))
+		for i, s := range pairs {
+			elements[i] = bulkElement(s)
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: elements}
+	case subcommand == "SET" && len(args) >= 3 && len(args)%2 == 1:
+		err := e.config.Apply(args[1:]...)
+		var paramErr *config.ParamError
+		switch {
+		case errors.Is(err, config.ErrUnknown) && errors.As(err, &paramErr):
+			return errorValue(fmt.Sprintf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", paramErr.Name))
+		case errors.As(err, &paramErr):
+			return errorValue(fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - %v", paramErr.Name, paramErr.Err))
+		case err != nil:
+			return errorValue("ERR CONFIG SET failed - " + err.Error())
+		}
+		return okValue()
+	case subcommand == "REWRITE" && len(args) == 1:
+		if e.config.Path() == "" {
+			return errorValue("ERR The server is running without a config file")
+		}
+		if err := e.config.Rewrite(); err != nil {
+			return errorValue("ERR Rewriting config file: " + err.Error())
+		}
+		return okValue()
+	case subcommand == "RESETSTAT" && len(args) == 1:
+		e.resetStats()
+		return okValue()
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try CONFIG HELP.", args[0]))
+	}
+}
+
+// resetStats zeroes the statistics CONFIG RESETSTAT resets.
+func (e *Executor) resetStats() {
+	e.dataStore.ResetExpireStats()
+	start, runID := e.stats.start, e.stats.runID
+	e.stats = newStatsState()
+	e.stats.start, e.stats.runID = start, runID
+	e.connections.Received.Store(0)
+	e.connections.Rejected.Store(0)
+}
+
+// noteReplicaOf records the master REPLICAOF set, addr or "" for none, as
+// the replicaof parameter, so CONFIG REWRITE persists it as Redis does.
+func (e *Executor) noteReplicaOf(addr string) {
+	if e.config == nil {
+		return
+	}
+	value := ""
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		value = host + " " + port
+	}
+	e.config.Set("replicaof", value)
+}
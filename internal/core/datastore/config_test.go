@@ -0,0 +1,107 @@
+package datastore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/config"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+)
+
+func configExecutor(t *testing.T) (*Executor, *config.Config) {
+	t.Helper()
+	exec := NewExecutor()
+	cfg := config.New()
+	exec.SetConfig(cfg)
+	return exec, cfg
+}
+
+func TestConfigGetSet(t *testing.T) {
+	exec, _ := configExecutor(t)
+
+	get := exec.Execute(makeCommand("CONFIG", "GET", "replica-*", "port"))
+	assert.Equal(t, []string{"port", "6379", "replica-read-only", "yes"}, replyStrings(get))
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("CONFIG", "SET", "save", "60 5", "replica-read-only", "no")).Str)
+	assert.Equal(t, []rdb.SaveRule{{Period: time.Minute, Changes: 5}}, exec.saving.rules)
+	assert.False(t, exec.replication.readOnly)
+	get = exec.Execute(makeCommand("CONFIG", "GET", "save", "replica-read-only"))
+	assert.Equal(t, []string{"save", "60 5", "replica-read-only", "no"}, replyStrings(get))
+}
+
+func TestConfigSetBacklogSize(t *testing.T) {
+	exec, _ := configExecutor(t)
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	require.NotNil(t, exec.replication.backlog)
+	exec.Execute(makeCommand("SET", "a", strings.Repeat("x", 200)))
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("CONFIG", "SET", "repl-backlog-size", "100")).Str)
+	assert.Equal(t, 100, exec.replication.backlogSize)
+	backlog := exec.replication.backlog
+	_, ok := backlog.Since(backlog.Offset() - 99)
+	assert.True(t, ok, "the most recent bytes are kept")
+	_, ok = backlog.Since(backlog.Offset() - 100)
+	assert.False(t, ok)
+}
+
+func TestConfigErrors(t *testing.T) {
+	exec, _ := configExecutor(t)
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"unknown parameter", []string{"SET", "nope", "1"},
+			"ERR Unknown option or number of arguments for CONFIG SET - 'nope'"},
+		{"immutable", []string{"SET", "port", "7000"},
+			"ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config"},
+		{"invalid value", []string{"SET", "maxclients", "0"},
+			"ERR CONFIG SET failed (possibly related to argument 'maxclients') - argument must be between 1 and 2147483647 inclusive"},
+		{"odd arguments", []string{"SET", "maxclients", "1", "timeout"},
+			"ERR unknown subcommand or wrong number of arguments for 'SET'. Try CONFIG HELP."},
+		{"no pattern", []string{"GET"},
+			"ERR unknown subcommand or wrong number of arguments for 'GET'. Try CONFIG HELP."},
+		{"unknown subcommand", []string{"FOO"},
+			"ERR unknown subcommand or wrong number of arguments for 'FOO'. Try CONFIG HELP."},
+		{"no file", []string{"REWRITE"}, "ERR The server is running without a config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exec.Execute(makeCommand("CONFIG", tt.args...)).Str)
+		})
+	}
+
+	exec = NewExecutor()
+	assert.Equal(t, "ERR CONFIG is not available without a configuration",
+		exec.Execute(makeCommand("CONFIG", "GET", "*")).Str)
+	assert.Equal(t, "OK", exec.Execute(makeCommand("CONFIG", "RESETSTAT")).Str)
+}
+
+func TestConfigRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mnemo.conf")
+	require.NoError(t, os.WriteFile(path, []byte("# mnemo\nmaxclients 10\n"), 0o644))
+	exec, cfg := configExecutor(t)
+	require.NoError(t, cfg.Load(path))
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("CONFIG", "SET", "maxclients", "20")).Str)
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", "127.0.0.1", "1")).Str)
+	t.Cleanup(func() {
+		exec.Close()
+		exec.Stop()
+	})
+	assert.Equal(t, "127.0.0.1 1", cfg.String("replicaof"))
+	assert.Equal(t, "OK", exec.Execute(makeCommand("CONFIG", "REWRITE")).Str)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# mnemo\nmaxclients 20\n# Generated by CONFIG REWRITE\nreplicaof \"127.0.0.1 1\"\n", string(data))
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", "NO", "ONE")).Str)
+	assert.Equal(t, "", cfg.String("replicaof"))
+}
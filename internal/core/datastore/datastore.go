@@ -2,36 +2,492 @@ package datastore
 
 import (
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/suryansh0301/Mnemo/internal/core/aof"
 	"github.com/suryansh0301/Mnemo/internal/core/commands"
 	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/config"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
+const (
+	// CronHz is how many times per second the executor runs its periodic
+	// housekeeping.
+	CronHz = 10
+	// activeExpireCyclePerc is the share of each cron period the active
+	// expiry cycle may spend reclaiming keys.
+	activeExpireCyclePerc = 25
+	// DefaultQueueSize is how many requests may wait for the executor
+	// before Submit blocks.
+	DefaultQueueSize = 1024
+)
+
 type Executor struct {
-	dataStore    map[string]string
+	dataStore    *keyspace.Keyspace
 	ExecutorChan chan Value
+	blocking     blockingState
+	pubsub       pubsubState
+	transactions transactionState
+	aof          *aof.AOF
+	// inExec is set while EXEC runs, when the writes to log are gathered in
+	// execLog.
+	inExec  bool
+	execLog []commands.Command
+	// rewriteScheduled is set when BGREWRITEAOF could not start the
+	// rewrite at once; the next cron run starts it.
+	rewriteScheduled bool
+	// rewriteErr is the outcome of the last rewrite
+	rewriteErr  error
+	fsync       fsyncState
+	saving      saveState
+	replication replicationState
+	cluster     clusterState
+	migrate     migrateState
+	// config is what CONFIG reads and changes, nil if the server has none
+	config      *config.Config
+	stats       statsState
+	connections Connections
+	// metrics is nil unless SetMetrics was called
+	metrics *commandMetrics
+	slowlog slowlogState
+	latency latencyState
+	// client is the connection whose request is being processed, nil
+	// while there is none or it has no session
+	client *Session
+	// replied catches the reply to the executor command being processed;
+	// see runExecutorCommand
+	replied *capturedReply
+	// clients is the registry of connections CLIENT LIST shows, and
+	// nextClientID the ID the next one to register gets
+	clients      map[chan common.RespValue]*Session
+	nextClientID int64
+	pause        pauseState
+	// monitors holds the connections that ran MONITOR
+	monitors map[chan common.RespValue]*Session
+	// dropped holds connections the executor has killed but whose
+	// Disconnect has not arrived yet; anything else they sent is ignored.
+	dropped  map[chan common.RespValue]struct{}
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
+// Value is a request from a connection. Replies, and Pub/Sub messages, are
+// sent on ResponseChan, which also identifies the connection.
 type Value struct {
 	ResponseChan chan common.RespValue
 	Command      commands.Command
+	// Session is the state shared with the connection. It may be nil when
+	// the caller has no connection, as in tests.
+	Session *Session
+	// Disconnect tells the executor that the connection is closing and has
+	// sent its last request. The executor forgets the connection, cancels
+	// anything it is blocked on and closes ResponseChan once every earlier
+	// reply has been queued. It carries no command.
+	Disconnect bool
+	// Connect tells the executor that the connection has opened, so that
+	// it is registered, and listed by CLIENT LIST, before it sends
+	// anything. It carries no command and gets no reply.
+	Connect bool
+	// run is work handed to the executor goroutine from elsewhere in the
+	// package, such as the commands a replica receives from its master.
+	// It carries no command and gets no reply.
+	run func(*Executor)
+}
+
+// executorCommands are run by the executor itself rather than by a
+// commands handler, because they need connection state and not just the
+// keyspace.
+var executorCommands map[enums.CommandName]func(*Executor, Value)
+
+// executorHandlers need executor state, but not a connection, so they
+// reply like any other command and can run inside a transaction.
+var executorHandlers map[enums.CommandName]func(*Executor, commands.Command) common.RespValue
+
+func init() {
+	executorCommands = map[enums.CommandName]func(*Executor, Value){
+		enums.SubscribeCommandName: func(e *Executor, value Value) {
+			e.handleSubscribe(value, false)
+		},
+		enums.PSubscribeCommandName: func(e *Executor, value Value) {
+			e.handleSubscribe(value, true)
+		},
+		enums.UnsubscribeCommandName: func(e *Executor, value Value) {
+			e.handleUnsubscribe(value, false)
+		},
+		enums.PUnsubscribeCommandName: func(e *Executor, value Value) {
+			e.handleUnsubscribe(value, true)
+		},
+		enums.MultiCommandName:   (*Executor).handleMulti,
+		enums.ExecCommandName:    (*Executor).handleExec,
+		enums.DiscardCommandName: (*Executor).handleDiscard,
+		enums.WatchCommandName:   (*Executor).handleWatch,
+		enums.UnwatchCommandName: (*Executor).handleUnwatch,
+
+		enums.PSyncCommandName:    (*Executor).handlePsync,
+		enums.ReplConfCommandName: (*Executor).handleReplConf,
+		enums.WaitCommandName:     (*Executor).handleWait,
+		enums.WaitAOFCommandName:  (*Executor).handleWait,
+
+		enums.AskingCommandName: (*Executor).handleAsking,
+
+		enums.MonitorCommandName: (*Executor).handleMonitor,
+	}
+	executorHandlers = map[enums.CommandName]func(*Executor, commands.Command) common.RespValue{
+		enums.PublishCommandName: (*Executor).handlePublish,
+		enums.PubsubCommandName:  (*Executor).handlePubsub,
+
+		enums.BgRewriteAOFCommandName: (*Executor).handleBgRewriteAOF,
+		enums.SaveCommandName:         (*Executor).handleSave,
+		enums.BgSaveCommandName:       (*Executor).handleBgSave,
+		enums.LastSaveCommandName:     (*Executor).handleLastSave,
+
+		enums.ReplicaOfCommandName: (*Executor).handleReplicaOf,
+		enums.SlaveOfCommandName:   (*Executor).handleReplicaOf,
+		enums.RoleCommandName:      (*Executor).handleRole,
+		enums.WaitCommandName:      (*Executor).handleWaitNow,
+		enums.WaitAOFCommandName:   (*Executor).handleWaitNow,
+
+		enums.ClusterCommandName: (*Executor).handleCluster,
+
+		enums.DumpCommandName:          (*Executor).handleDump,
+		enums.RestoreCommandName:       (*Executor).handleRestore,
+		enums.RestoreAskingCommandName: (*Executor).handleRestore,
+		enums.MigrateCommandName:       (*Executor).handleMigrate,
+
+		enums.ConfigCommandName:  (*Executor).handleConfig,
+		enums.InfoCommandName:    (*Executor).handleInfo,
+		enums.SlowlogCommandName: (*Executor).handleSlowlog,
+		enums.LatencyCommandName: (*Executor).handleLatency,
+		enums.ClientCommandName:  (*Executor).handleClient,
+	}
 }
 
 func NewExecutor() *Executor {
-	return &Executor{
-		dataStore:    make(map[string]string),
-		ExecutorChan: make(chan Value, 1024),
+	return NewExecutorWithQueue(DefaultQueueSize)
+}
+
+// NewExecutorWithQueue returns an executor whose queue holds queueSize
+// requests.
+func NewExecutorWithQueue(queueSize int) *Executor {
+	e := &Executor{
+		dataStore:    keyspace.New(),
+		ExecutorChan: make(chan Value, queueSize),
+		blocking:     newBlockingState(),
+		pubsub:       newPubsubState(),
+		transactions: newTransactionState(),
+		replication:  newReplicationState(),
+		stats:        newStatsState(),
+		slowlog:      newSlowlogState(),
+		latency:      newLatencyState(),
+		monitors:     make(map[chan common.RespValue]*Session),
+		clients:      make(map[chan common.RespValue]*Session),
+		pause:        newPauseState(),
+		dropped:      make(map[chan common.RespValue]struct{}),
+		done:         make(chan struct{}),
+	}
+	e.dataStore.SetAddHook(e.signalKeyAsReady)
+	e.dataStore.SetModifyHook(e.touchWatchedKey)
+	return e
+}
+
+// Submit queues value for the executor goroutine. It reports false, without
+// queueing, once the executor has been stopped.
+func (e *Executor) Submit(value Value) bool {
+	select {
+	case e.ExecutorChan <- value:
+		return true
+	case <-e.done:
+		return false
+	}
+}
+
+// Stop makes the executor goroutine exit and Submit refuse new work. It is
+// safe to call more than once.
+func (e *Executor) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.done)
+	})
+}
+
+// Done is closed when Stop is called.
+func (e *Executor) Done() <-chan struct{} {
+	return e.done
+}
+
+// Handle runs one request on the executor goroutine and replies to it,
+// unless the command blocks, in which case the reply comes later. Commands
+// a blocked client pipelines behind the blocking one wait their turn, as
+// do those held by CLIENT PAUSE.
+func (e *Executor) Handle(value Value) {
+	switch {
+	case value.run != nil:
+		value.run(e)
+	case value.Disconnect:
+		e.disconnectClient(value.ResponseChan)
+		return
+	case value.Connect:
+		e.registerClient(value)
+		return
+	default:
+		if !e.dispatch(value) {
+			return
+		}
+	}
+	e.serveReadyKeys()
+	// like the client's writer, batch the writes of pipelined commands
+	if len(e.ExecutorChan) == 0 {
+		e.flushAppendOnly()
+		e.serveWaitingClients()
+		e.flushReplication()
+	}
+}
+
+// dispatch processes value, unless its connection has been dropped, or it
+// has to wait behind a blocked command or for clients to be unpaused. It
+// reports whether it processed it.
+func (e *Executor) dispatch(value Value) bool {
+	if _, dropped := e.dropped[value.ResponseChan]; dropped {
+		return false
+	}
+	if _, blocked := e.blocking.clients[value.ResponseChan]; blocked {
+		e.blocking.queued[value.ResponseChan] = append(e.blocking.queued[value.ResponseChan], value)
+		return false
+	}
+	if e.holdForPause(value) {
+		return false
+	}
+	e.process(value)
+	return true
+}
+
+func (e *Executor) process(value Value) {
+	previous := e.client
+	e.client = value.Session
+	e.touchClient(value)
+	defer func() {
+		e.client = previous
+		value.Session.endCommand()
+	}()
+	name := enums.StringToCommandName(value.Command.Name)
+	if e.monitoring(value.ResponseChan) {
+		// as in Redis, a monitor may only QUIT, and what else it sends
+		// goes unanswered
+		if name == enums.QuitCommandName {
+			e.reply(value, okValue())
+		}
+		return
+	}
+	if e.pubsub.subscribed(value.ResponseChan) {
+		if name == enums.PingCommandName {
+			e.handleSubscribedPing(value)
+			return
+		}
+		if !allowedWhileSubscribed[name] {
+			e.reply(value, errorValue(fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(value.Command.Name))))
+			return
+		}
+	}
+	if e.cluster.node != nil {
+		defer e.resetAsking(value.ResponseChan, name)
+	}
+	if tx := e.transactions.clients[value.ResponseChan]; tx != nil && tx.inMulti && !runsInsideMulti[name] {
+		e.queueCommand(value, tx)
+		return
+	}
+	if e.readOnly() && commands.IsWriteCommand(value.Command.Name) {
+		e.reply(value, errorValue(readOnlyError))
+		return
+	}
+	if e.cluster.node != nil {
+		_, asking := e.cluster.asking[value.ResponseChan]
+		if redirect := e.clusterRedirect([]commands.Command{value.Command}, asking); redirect != nil {
+			e.reply(value, *redirect)
+			return
+		}
+	}
+	if handle, exists := executorCommands[name]; exists {
+		start := time.Now()
+		reply := e.runExecutorCommand(handle, value)
+		e.commandDone(value.Command, reply, time.Since(start))
+		return
+	}
+
+	handler := commands.BlockingCommandHandler(value.Command.Name)
+	if handler == nil {
+		e.reply(value, e.Execute(value.Command))
+		return
+	}
+
+	var block *commands.Block
+	start := time.Now()
+	reply := e.call(value.Command, func() common.RespValue {
+		var reply common.RespValue
+		reply, block = handler(value.Command, e.dataStore)
+		return reply
+	})
+	e.commandDone(value.Command, reply, time.Since(start))
+	if block == nil {
+		e.reply(value, reply)
+		return
+	}
+	e.blockClient(value, handler, block, reply)
+}
+
+// capturedReply is the last reply sent to responseChan while an executor
+// command runs.
+type capturedReply struct {
+	responseChan chan common.RespValue
+	reply        common.RespValue
+}
+
+// runExecutorCommand runs an executor command, which replies itself, and
+// returns its reply for commandDone. A command that only pushes frames, or
+// that blocks, returns the zero value, which counts as a success.
+func (e *Executor) runExecutorCommand(handle func(*Executor, Value), value Value) common.RespValue {
+	previous := e.replied
+	captured := &capturedReply{responseChan: value.ResponseChan}
+	e.replied = captured
+	defer func() { e.replied = previous }()
+	handle(e, value)
+	return captured.reply
+}
+
+// disconnectClient forgets a connection that has sent its last request and
+// closes its response channel, which tells the connection's writer that
+// nothing more will come.
+func (e *Executor) disconnectClient(responseChan chan common.RespValue) {
+	delete(e.dropped, responseChan)
+	e.forgetClient(responseChan)
+	close(responseChan)
+}
+
+// dropClient kills a connection the executor can no longer serve. Its
+// state goes at once, and anything it sends before its Disconnect arrives
+// is ignored.
+func (e *Executor) dropClient(responseChan chan common.RespValue, session *Session) {
+	e.forgetClient(responseChan)
+	e.dropped[responseChan] = struct{}{}
+	session.kill()
+}
+
+// forgetClient removes every trace of a connection from the executor.
+func (e *Executor) forgetClient(responseChan chan common.RespValue) {
+	e.cancelBlockedClient(responseChan)
+	e.discardTransaction(responseChan)
+	if sub, subscribed := e.pubsub.subscribers[responseChan]; subscribed {
+		e.pubsub.unsubscribeAll(sub)
+	}
+	delete(e.replication.replicas, responseChan)
+	delete(e.cluster.asking, responseChan)
+	delete(e.monitors, responseChan)
+	delete(e.clients, responseChan)
+	e.releaseHeld(responseChan)
+}
+
+// reply sends response without ever blocking the executor goroutine,
+// unless the client turned its replies off with CLIENT REPLY.
+func (e *Executor) reply(value Value, response common.RespValue) {
+	if e.replied != nil && e.replied.responseChan == value.ResponseChan {
+		e.replied.reply = response
+	}
+	if value.Session.silenced() {
+		return
+	}
+	select {
+	case value.ResponseChan <- response:
+	default:
+		slog.Debug("response channel full, dropping response")
 	}
 }
 
+// Execute runs command and returns its reply, logging it to the
+// append-only file if it wrote anything, and timing it for the metrics,
+// the slow log and the latency monitor. Blocking commands never block
+// here; they reply as if their timeout had passed.
 func (e *Executor) Execute(command commands.Command) common.RespValue {
+	start := time.Now()
+	reply := e.execute(command)
+	e.commandDone(command, reply, time.Since(start))
+	return reply
+}
+
+// commandDone records a command that took duration to reply with reply,
+// and shows it to the monitors. As in Redis, an unknown command or one
+// with the wrong number of arguments is not counted as processed.
+func (e *Executor) commandDone(command commands.Command, reply common.RespValue, duration time.Duration) {
+	if commands.CheckArity(command) {
+		e.stats.commands++
+	}
+	e.metrics.observe(command.Name, reply, duration)
+	e.logSlowCommand(command, duration)
+	e.latency.add(latencyCommand, duration)
+	e.feedMonitors(command)
+}
+
+// execute runs command as Execute does, but untimed, for the commands the
+// executor issues itself.
+func (e *Executor) execute(command commands.Command) common.RespValue {
+	if handle, exists := executorHandlers[enums.StringToCommandName(command.Name)]; exists {
+		return handle(e, command)
+	}
 	handler := commands.CommandHandler(command.Name)
 	if handler == nil {
-		return common.RespValue{
-			Type: enums.ErrorRespType,
-			Str:  fmt.Sprintf("ERR unknown command '%s'", command.Name),
-		}
+		return unknownCommand(command.Name)
+	}
+	return e.call(command, func() common.RespValue {
+		return handler(command, e.dataStore)
+	})
+}
+
+func errorValue(message string) common.RespValue {
+	return common.RespValue{
+		Type: enums.ErrorRespType,
+		Str:  message,
 	}
-	return handler(command, e.dataStore)
+}
+
+func unknownCommand(commandName string) common.RespValue {
+	return errorValue(fmt.Sprintf("ERR unknown command '%s'", commandName))
+}
+
+func wrongArity(commandName string) common.RespValue {
+	return errorValue(common.WrongNumberOfArgumentsError(commandName))
+}
+
+// Cron runs the executor's periodic housekeeping. It must be called from the
+// executor goroutine, CronHz times per second. Blocked clients time out
+// here, so timeouts are honoured to within one cron period.
+func (e *Executor) Cron() {
+	budget := time.Second / CronHz * activeExpireCyclePerc / 100
+	start := time.Now()
+	e.dataStore.ActiveExpireCycle(budget)
+	e.timeEvent(latencyExpireCycle, start)
+	start = time.Now()
+	if e.dataStore.CopySnapshots(budget) > 0 {
+		e.timeEvent(latencySnapshot, start)
+	}
+	e.timeoutBlockedClients()
+	// a client that timed out runs the commands it queued, which may push
+	// to keys other clients block on
+	e.serveReadyKeys()
+	e.cronPause()
+	e.cronAppendOnly()
+	e.serveWaitingClients()
+	e.cronSave()
+	e.cronReplication()
+	e.cronCluster()
+	// as may the clients WAIT released, or a slot move redirected
+	e.serveReadyKeys()
+	e.cronMigrate()
+	e.cronStats()
+}
+
+// ExpireStats returns the keyspace expiry counters.
+func (e *Executor) ExpireStats() keyspace.ExpireStats {
+	return e.dataStore.ExpireStats()
 }
@@ -106,3 +106,36 @@ func TestExecuteOverwrite(t *testing.T) {
 	resp := exec.Execute(makeCommand("GET", "foo"))
 	assert.Equal(t, "baz", resp.Str)
 }
+
+func TestExecuteExpiry(t *testing.T) {
+	exec := NewExecutor()
+	now := int64(1_000_000)
+	exec.dataStore.SetClock(func() int64 { return now })
+
+	exec.Execute(makeCommand("SET", "session", "abc", "PX", "500"))
+	resp := exec.Execute(makeCommand("PTTL", "session"))
+	assert.Equal(t, int64(500), resp.Int)
+
+	now += 501
+	resp = exec.Execute(makeCommand("GET", "session"))
+	assert.True(t, resp.IsNull)
+
+	resp = exec.Execute(makeCommand("TTL", "session"))
+	assert.Equal(t, int64(-2), resp.Int)
+}
+
+func TestExecuteCronExpiresKeys(t *testing.T) {
+	exec := NewExecutor()
+	now := int64(1_000_000)
+	exec.dataStore.SetClock(func() int64 { return now })
+
+	exec.Execute(makeCommand("SET", "a", "1", "PX", "10"))
+	exec.Execute(makeCommand("SET", "b", "1", "PX", "10"))
+	exec.Execute(makeCommand("SET", "c", "1"))
+
+	now += 11
+	exec.Cron()
+
+	assert.Equal(t, 1, exec.dataStore.Len())
+	assert.Equal(t, int64(2), exec.ExpireStats().ExpiredKeys)
+}
@@ -0,0 +1,362 @@
+package datastore
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/replication"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// redisVersion is the Redis release whose commands and INFO fields Mnemo
+// follows. Clients and monitoring tools read it from INFO to tell what the
+// server supports.
+const redisVersion = "7.2.0"
+
+// opsSamples is how many cron periods instantaneous_ops_per_sec averages
+// over, as STATS_METRIC_SAMPLES does in Redis.
+const opsSamples = 16
+
+// Connections counts the server's client connections. The server keeps it
+// current from its accept loop and connection goroutines; INFO reads it.
+type Connections struct {
+	// Connected is how many clients are connected now.
+	Connected atomic.Int64
+	// Received is how many connections have been accepted, and Rejected
+	// how many of them were turned away because maxclients were connected.
+	Received atomic.Int64
+	Rejected atomic.Int64
+}
+
+// Connections returns the connection counters for the server to keep.
+func (e *Executor) Connections() *Connections {
+	return &e.connections
+}
+
+// statsState holds the counters INFO reports that the executor keeps.
+type statsState struct {
+	start time.Time
+	runID string
+	// commands counts the commands clients have sent
+	commands int64
+	// ops holds the commands per second of the last cron periods, the
+	// latest at opsIndex, as of lastSample, when commands was
+	// lastSampleCommands
+	ops                [opsSamples]int64
+	opsIndex           int
+	lastSample         time.Time
+	lastSampleCommands int64
+	peakMemory         uint64
+}
+
+func newStatsState() statsState {
+	now := time.Now()
+	return statsState{start: now, runID: replication.NewID(), lastSample: now}
+}
+
+// sampleOps records the commands per second since the last sample, as
+// Redis's trackInstantaneousMetric does.
+func (s *statsState) sampleOps(now time.Time) {
+	if elapsed := now.Sub(s.lastSample).Milliseconds(); elapsed > 0 {
+		s.ops[s.opsIndex] = (s.commands - s.lastSampleCommands) * 1000 / elapsed
+		s.opsIndex = (s.opsIndex + 1) % opsSamples
+	}
+	s.lastSample = now
+	s.lastSampleCommands = s.commands
+}
+
+// opsPerSec averages the samples.
+func (s *statsState) opsPerSec() int64 {
+	var sum int64
+	for _, ops := range s.ops {
+		sum += ops
+	}
+	return sum / opsSamples
+}
+
+// readMemory returns the bytes held by live heap objects, which stand in
+// for Redis's used_memory, and the bytes the Go runtime has obtained from
+// the operating system, which stand in for its RSS.
+func readMemory() (used, obtained uint64) {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/heap/objects:bytes"},
+		{Name: "/memory/classes/total:bytes"},
+	}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		used = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		obtained = samples[1].Value.Uint64()
+	}
+	return used, obtained
+}
+
+// cronStats samples the commands per second and the peak memory used.
+func (e *Executor) cronStats() {
+	e.stats.sampleOps(time.Now())
+	used, _ := readMemory()
+	e.stats.peakMemory = max(e.stats.peakMemory, used)
+}
+
+// infoSections are the sections of INFO, in the order it lists them.
+var infoSections = []struct {
+	name  string
+	write func(e *Executor, b *infoBuilder)
+}{
+	{"Server", (*Executor).infoServer},
+	{"Clients", (*Executor).infoClients},
+	{"Memory", (*Executor).infoMemory},
+	{"Persistence", (*Executor).infoPersistence},
+	{"Stats", (*Executor).infoStats},
+	{"Replication", (*Executor).infoReplication},
+	{"Cluster", (*Executor).infoCluster},
+	{"Keyspace", (*Executor).infoKeyspace},
+}
+
+// handleInfo implements INFO [section ...]. With no section, or default,
+// all or everything, every section is listed; names are not case
+// sensitive and unknown ones are ignored, as in Redis.
+func (e *Executor) handleInfo(command commands.Command) common.RespValue {
+	wanted := make(map[string]bool)
+	all := len(command.Args) == 0
+	for _, arg := range command.Args {
+		switch section := strings.ToLower(arg); section {
+		case "default", "all", "everything":
+			all = true
+		default:
+			wanted[section] = true
+		}
+	}
+	var b infoBuilder
+	for _, section := range infoSections {
+		if !all && !wanted[strings.ToLower(section.name)] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + section.name + "\r\n")
+		section.write(e, &b)
+	}
+	return common.RespValue{Type: enums.BulkStringRespType, Str: b.String()}
+}
+
+// infoBuilder writes the name:value lines of INFO.
+type infoBuilder struct {
+	strings.Builder
+}
+
+func (b *infoBuilder) field(name string, value any) {
+	fmt.Fprintf(b, "%s:%v\r\n", name, value)
+}
+
+// flag writes a field that is 1 or 0.
+func (b *infoBuilder) flag(name string, set bool) {
+	if set {
+		b.field(name, 1)
+	} else {
+		b.field(name, 0)
+	}
+}
+
+func (e *Executor) infoServer(b *infoBuilder) {
+	mode := "standalone"
+	if e.cluster.node != nil {
+		mode = "cluster"
+	}
+	uptime := time.Since(e.stats.start)
+	executable, _ := os.Executable()
+	configFile := ""
+	if e.config != nil {
+		configFile = e.config.Path()
+	}
+	b.field("redis_version", redisVersion)
+	b.field("redis_mode", mode)
+	b.field("os", runtime.GOOS+" "+runtime.GOARCH)
+	b.field("arch_bits", strconv.IntSize)
+	b.field("go_version", runtime.Version())
+	b.field("process_id", os.Getpid())
+	b.field("run_id", e.stats.runID)
+	b.field("tcp_port", e.replication.listeningPort)
+	b.field("server_time_usec", time.Now().UnixMicro())
+	b.field("uptime_in_seconds", int64(uptime.Seconds()))
+	b.field("uptime_in_days", int64(uptime.Hours()/24))
+	b.field("hz", CronHz)
+	b.field("executable", executable)
+	b.field("config_file", configFile)
+}
+
+func (e *Executor) infoClients(b *infoBuilder) {
+	b.field("connected_clients", e.connections.Connected.Load())
+	if e.config != nil {
+		b.field("maxclients", e.config.Int("maxclients"))
+	}
+	b.field("blocked_clients", len(e.blocking.clients))
+	b.field("pubsub_clients", len(e.pubsub.subscribers))
+	watching := 0
+	for _, tx := range e.transactions.clients {
+		if len(tx.watched) > 0 {
+			watching++
+		}
+	}
+	b.field("watching_clients", watching)
+	b.field("total_watched_keys", len(e.transactions.watchedKeys))
+	b.field("executor_queue_depth", len(e.ExecutorChan))
+	b.field("executor_queue_size", cap(e.ExecutorChan))
+}
+
+func (e *Executor) infoMemory(b *infoBuilder) {
+	used, obtained := readMemory()
+	e.stats.peakMemory = max(e.stats.peakMemory, used)
+	b.field("used_memory", used)
+	b.field("used_memory_human", bytesToHuman(used))
+	b.field("used_memory_rss", obtained)
+	b.field("used_memory_rss_human", bytesToHuman(obtained))
+	b.field("used_memory_peak", e.stats.peakMemory)
+	b.field("used_memory_peak_human", bytesToHuman(e.stats.peakMemory))
+	b.field("used_memory_peak_perc", fmt.Sprintf("%.2f%%", float64(used)*100/float64(max(e.stats.peakMemory, 1))))
+	b.field("mem_fragmentation_ratio", fmt.Sprintf("%.2f", float64(obtained)/float64(max(used, 1))))
+	b.field("mem_allocator", "go")
+}
+
+// bytesToHuman formats n as Redis's INFO does, as in 1.50M.
+func bytesToHuman(n uint64) string {
+	const units = "KMGTP"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f%c", value, units[unit])
+}
+
+func (e *Executor) infoPersistence(b *infoBuilder) {
+	b.field("loading", 0)
+	b.field("rdb_changes_since_last_save", e.dataStore.Dirty()-e.saving.dirty)
+	b.flag("rdb_bgsave_in_progress", e.saving.bgsave != nil)
+	b.field("rdb_last_save_time", e.saving.lastSave.Unix())
+	b.field("rdb_last_bgsave_status", status(e.saving.lastBgsaveErr))
+	b.flag("aof_enabled", e.aof != nil)
+	b.flag("aof_rewrite_in_progress", e.aof != nil && e.aof.RewriteInProgress())
+	b.flag("aof_rewrite_scheduled", e.rewriteScheduled)
+	b.field("aof_last_bgrewrite_status", status(e.rewriteErr))
+	if e.aof != nil {
+		current, base := e.aof.Sizes()
+		b.field("aof_current_size", current)
+		b.field("aof_base_size", base)
+	}
+}
+
+// status is how INFO reports the outcome of a background job.
+func status(err error) string {
+	if err != nil {
+		return "err"
+	}
+	return "ok"
+}
+
+func (e *Executor) infoStats(b *infoBuilder) {
+	expire := e.dataStore.ExpireStats()
+	b.field("total_connections_received", e.connections.Received.Load())
+	b.field("total_commands_processed", e.stats.commands)
+	b.field("instantaneous_ops_per_sec", e.stats.opsPerSec())
+	b.field("rejected_connections", e.connections.Rejected.Load())
+	b.field("expired_keys", expire.ExpiredKeys)
+	b.field("expired_stale_perc", fmt.Sprintf("%.2f", expire.ExpiredStalePerc))
+	b.field("expired_time_cap_reached_count", expire.ExpiredTimeCapReachedCount)
+	b.field("expire_cycle_cpu_milliseconds", expire.ExpireCycleCPUTime.Milliseconds())
+	b.field("pubsub_channels", len(e.pubsub.channels))
+	b.field("pubsub_patterns", len(e.pubsub.patterns))
+}
+
+func (e *Executor) infoReplication(b *infoBuilder) {
+	repl := &e.replication
+	if m := repl.master; m != nil {
+		host, port, _ := net.SplitHostPort(m.addr)
+		b.field("role", "slave")
+		b.field("master_host", host)
+		b.field("master_port", port)
+		if m.state == replication.StateConnected {
+			b.field("master_link_status", "up")
+		} else {
+			b.field("master_link_status", "down")
+		}
+		b.flag("master_sync_in_progress", m.state == replication.StateSync)
+		b.field("slave_repl_offset", repl.offset)
+		b.flag("slave_read_only", repl.readOnly)
+	} else {
+		b.field("role", "master")
+	}
+
+	var replicas []*replica
+	for _, r := range repl.replicas {
+		if r.state != replicaHandshake {
+			replicas = append(replicas, r)
+		}
+	}
+	slices.SortFunc(replicas, func(a, b *replica) int {
+		return cmp.Compare(a.listeningPort, b.listeningPort)
+	})
+	b.field("connected_slaves", len(replicas))
+	for i, r := range replicas {
+		host, state := "", "wait_bgsave"
+		if r.session != nil {
+			host, _, _ = net.SplitHostPort(r.session.Addr)
+		}
+		if r.state == replicaOnline {
+			state = "online"
+		}
+		lag := int64(time.Since(r.ackTime).Seconds())
+		b.field(fmt.Sprintf("slave%d", i), fmt.Sprintf("ip=%s,port=%d,state=%s,offset=%d,lag=%d",
+			host, r.listeningPort, state, r.ackOffset, lag))
+	}
+
+	id2, id2Offset := repl.id2, repl.id2Offset
+	if id2 == "" {
+		// Redis reports an ID of zeroes when there is none
+		id2, id2Offset = strings.Repeat("0", len(repl.id)), -1
+	}
+	b.field("master_replid", repl.id)
+	b.field("master_replid2", id2)
+	b.field("master_repl_offset", repl.offset)
+	b.field("second_repl_offset", id2Offset)
+	if backlog := repl.backlog; backlog != nil {
+		b.field("repl_backlog_active", 1)
+		b.field("repl_backlog_size", repl.backlogSize)
+		b.field("repl_backlog_first_byte_offset", backlog.Offset()-int64(backlog.Len())+1)
+		b.field("repl_backlog_histlen", backlog.Len())
+	} else {
+		b.field("repl_backlog_active", 0)
+		b.field("repl_backlog_size", repl.backlogSize)
+		b.field("repl_backlog_first_byte_offset", 0)
+		b.field("repl_backlog_histlen", 0)
+	}
+}
+
+func (e *Executor) infoCluster(b *infoBuilder) {
+	b.flag("cluster_enabled", e.cluster.node != nil)
+}
+
+// infoKeyspace lists the one database, as Redis lists those that hold
+// keys.
+func (e *Executor) infoKeyspace(b *infoBuilder) {
+	if keys := e.dataStore.Len(); keys > 0 {
+		b.field("db0", fmt.Sprintf("keys=%d,expires=%d,avg_ttl=%d", keys, e.dataStore.ExpiresLen(), e.dataStore.AvgTTL()))
+	}
+}
@@ -0,0 +1,183 @@
+package datastore
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// infoFields parses an INFO reply into its section names and fields.
+func infoFields(t *testing.T, reply common.RespValue) ([]string, map[string]string) {
+	t.Helper()
+	require.Equal(t, enums.BulkStringRespType, reply.Type)
+	var sections []string
+	fields := make(map[string]string)
+	for _, line := range strings.Split(reply.Str, "\r\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# "):
+			sections = append(sections, strings.TrimPrefix(line, "# "))
+		default:
+			name, value, found := strings.Cut(line, ":")
+			require.True(t, found, "line %q", line)
+			fields[name] = value
+		}
+	}
+	return sections, fields
+}
+
+func TestInfo(t *testing.T) {
+	exec, _ := configExecutor(t)
+	exec.SetReplication(6380, 1<<20, true)
+	exec.Connections().Connected.Store(2)
+	exec.Connections().Received.Store(5)
+	exec.Connections().Rejected.Store(1)
+	client, subscriber := newTestClient(), newTestClient()
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "SET", "b", "2")
+	client.do(exec, "PEXPIRE", "b", "100000")
+	client.do(exec, "WATCH", "a")
+	subscriber.do(exec, "SUBSCRIBE", "x", "y")
+
+	sections, fields := infoFields(t, exec.Execute(makeCommand("INFO")))
+	assert.Equal(t, []string{"Server", "Clients", "Memory", "Persistence", "Stats", "Replication", "Cluster", "Keyspace"}, sections)
+	expected := map[string]string{
+		"redis_version":               redisVersion,
+		"redis_mode":                  "standalone",
+		"tcp_port":                    "6380",
+		"hz":                          "10",
+		"connected_clients":           "2",
+		"maxclients":                  "100000",
+		"pubsub_clients":              "1",
+		"watching_clients":            "1",
+		"total_watched_keys":          "1",
+		"executor_queue_size":         "1024",
+		"mem_allocator":               "go",
+		"rdb_changes_since_last_save": "3",
+		"aof_enabled":                 "0",
+		"total_connections_received":  "5",
+		"total_commands_processed":    "5",
+		"rejected_connections":        "1",
+		"pubsub_channels":             "2",
+		"role":                        "master",
+		"connected_slaves":            "0",
+		"second_repl_offset":          "-1",
+		"cluster_enabled":             "0",
+	}
+	for name, value := range expected {
+		assert.Equal(t, value, fields[name], name)
+	}
+	assert.Regexp(t, `^keys=2,expires=1,avg_ttl=\d+$`, fields["db0"])
+	assert.Len(t, fields["run_id"], 40)
+	assert.NotEmpty(t, fields["used_memory"])
+}
+
+func TestInfoSections(t *testing.T) {
+	exec := NewExecutor()
+	sections, fields := infoFields(t, exec.Execute(makeCommand("INFO", "KEYSPACE", "clients", "nope")))
+	assert.Equal(t, []string{"Clients", "Keyspace"}, sections)
+	assert.NotContains(t, fields, "db0", "an empty database is not listed")
+	assert.NotContains(t, fields, "maxclients", "without a configuration")
+
+	for _, all := range []string{"default", "all", "everything"} {
+		sections, _ = infoFields(t, exec.Execute(makeCommand("INFO", all)))
+		assert.Len(t, sections, len(infoSections), all)
+	}
+	reply := exec.Execute(makeCommand("INFO", "nope"))
+	assert.Equal(t, common.RespValue{Type: enums.BulkStringRespType, Str: ""}, reply)
+}
+
+func TestInfoReplication(t *testing.T) {
+	exec := NewExecutor()
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	exec.Execute(makeCommand("SET", "a", "1"))
+	_, fields := infoFields(t, exec.Execute(makeCommand("INFO", "replication")))
+	assert.Equal(t, "1", fields["connected_slaves"])
+	assert.Regexp(t, `^ip=,port=\d+,state=online,offset=\d+,lag=0$`, fields["slave0"])
+	assert.Equal(t, "1", fields["repl_backlog_active"])
+	assert.Equal(t, "1", fields["repl_backlog_first_byte_offset"])
+	assert.Equal(t, fields["master_repl_offset"], fields["repl_backlog_histlen"])
+
+	exec = replicaExecutor(t)
+	_, fields = infoFields(t, exec.Execute(makeCommand("INFO", "replication")))
+	assert.Equal(t, "slave", fields["role"])
+	assert.Equal(t, "127.0.0.1", fields["master_host"])
+	assert.Equal(t, "down", fields["master_link_status"])
+	assert.Equal(t, "1", fields["slave_read_only"])
+}
+
+func TestInfoStats(t *testing.T) {
+	exec := NewExecutor()
+	a, b, admin, writer := newTestClient(), newTestClient(), newTestClient(), newTestClient()
+	a.do(exec, "PSUBSCRIBE", "news.*")
+	b.do(exec, "PSUBSCRIBE", "news.*")
+	admin.do(exec, "NOPE")
+	admin.do(exec, "GET")
+	admin.do(exec, "CLIENT", "PAUSE", "60000", "WRITE")
+	writer.do(exec, "SET", "k", "v")
+
+	// neither the unknown command, the wrong arity nor the held SET count
+	_, fields := infoFields(t, exec.Execute(makeCommand("INFO", "stats")))
+	assert.Equal(t, "3", fields["total_commands_processed"])
+	assert.Equal(t, "1", fields["pubsub_patterns"], "patterns are counted once, however many subscribe")
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("PUBSUB", "NUMPAT")).Int)
+
+	admin.do(exec, "CLIENT", "UNPAUSE")
+	_, fields = infoFields(t, exec.Execute(makeCommand("INFO", "stats")))
+	assert.Equal(t, "7", fields["total_commands_processed"], "the held SET counts once, when it runs")
+}
+
+func TestInfoResetStat(t *testing.T) {
+	exec := NewExecutor()
+	exec.Connections().Received.Store(5)
+	client := newTestClient()
+	client.do(exec, "PING")
+	_, fields := infoFields(t, exec.Execute(makeCommand("INFO", "stats", "server")))
+	runID := fields["run_id"]
+	assert.Equal(t, "1", fields["total_commands_processed"])
+
+	exec.Execute(makeCommand("CONFIG", "RESETSTAT"))
+	_, fields = infoFields(t, exec.Execute(makeCommand("INFO", "stats", "server")))
+	assert.Equal(t, "1", fields["total_commands_processed"], "the CONFIG RESETSTAT itself")
+	assert.Equal(t, "0", fields["total_connections_received"])
+	assert.Equal(t, runID, fields["run_id"])
+}
+
+func TestOpsPerSec(t *testing.T) {
+	start := time.Now()
+	s := statsState{lastSample: start}
+	for i := 1; i <= opsSamples; i++ {
+		s.commands += 50
+		s.sampleOps(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+	assert.Equal(t, int64(500), s.opsPerSec())
+
+	// half the samples idle
+	for i := 1; i <= opsSamples/2; i++ {
+		s.sampleOps(start.Add(time.Duration(opsSamples+i) * 100 * time.Millisecond))
+	}
+	assert.Equal(t, int64(250), s.opsPerSec())
+}
+
+func TestBytesToHuman(t *testing.T) {
+	tests := map[uint64]string{
+		0:                "0B",
+		1023:             "1023B",
+		1024:             "1.00K",
+		1536:             "1.50K",
+		5 << 20:          "5.00M",
+		3 << 30:          "3.00G",
+		1 << 50:          "1.00P",
+		1<<50*1024 + 123: "1024.00P",
+	}
+	for n, expected := range tests {
+		assert.Equal(t, expected, bytesToHuman(n), n)
+	}
+}
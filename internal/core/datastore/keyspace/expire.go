@@ -0,0 +1,154 @@
+package keyspace
+
+import "time"
+
+// ExpireAt returns the unix millisecond time at which key expires. The
+// boolean is false when the key is missing or has no TTL.
+func (k *Keyspace) ExpireAt(key string) (int64, bool) {
+	if k.expireIfNeeded(key) {
+		return 0, false
+	}
+	when, exists := k.expires[key]
+	return when, exists
+}
+
+// SetExpireAt sets the TTL of an existing key to the given unix millisecond
+// time. It reports false if the key does not exist.
+func (k *Keyspace) SetExpireAt(key string, when int64) bool {
+	if !k.Exists(key) {
+		return false
+	}
+	k.preserve(key)
+	k.expires[key] = when
+	k.signalModified(key)
+	return true
+}
+
+// Persist removes the TTL from key, reporting whether there was one.
+func (k *Keyspace) Persist(key string) bool {
+	if _, exists := k.ExpireAt(key); !exists {
+		return false
+	}
+	k.preserve(key)
+	delete(k.expires, key)
+	k.signalModified(key)
+	return true
+}
+
+const (
+	// activeExpireKeysPerLoop is how many keys with a TTL are sampled per
+	// iteration of the active expiry cycle.
+	activeExpireKeysPerLoop = 20
+	// activeExpireAcceptableStale is the percentage of expired keys in a
+	// sample below which the cycle stops, assuming the rest is mostly live.
+	activeExpireAcceptableStale = 10
+	// activeExpireTimeCheckInterval is how many iterations run between
+	// checks of the time budget, since reading the clock is not free.
+	activeExpireTimeCheckInterval = 16
+)
+
+// ExpireStats counts the work done reclaiming expired keys. Field names
+// follow the matching INFO stats fields in Redis.
+type ExpireStats struct {
+	// ExpiredKeys is the number of keys removed because their TTL passed,
+	// whether found lazily on access or by the active cycle.
+	ExpiredKeys int64
+	// ExpiredStalePerc estimates the percentage of keys with a TTL that are
+	// logically expired but still held in memory.
+	ExpiredStalePerc float64
+	// ExpiredTimeCapReachedCount is the number of active cycles that
+	// stopped because they exhausted their time budget.
+	ExpiredTimeCapReachedCount int64
+	// ExpireCycleCPUTime is the total time spent in active cycles. It is
+	// kept at full resolution, as a cycle usually takes well under a
+	// millisecond.
+	ExpireCycleCPUTime time.Duration
+}
+
+// ExpireStats returns the expiry counters accumulated so far.
+func (k *Keyspace) ExpireStats() ExpireStats {
+	return k.expireStats
+}
+
+// ResetExpireStats zeroes the expiry counters, as CONFIG RESETSTAT does.
+func (k *Keyspace) ResetExpireStats() {
+	k.expireStats = ExpireStats{}
+}
+
+// AvgTTL estimates the TTL left on the keys that have one, in
+// milliseconds, from the keys the active expiry cycle samples, as Redis's
+// avg_ttl does. It is 0 until a cycle has sampled a live key.
+func (k *Keyspace) AvgTTL() int64 {
+	return k.avgTTL
+}
+
+// ActiveExpireCycle reclaims expired keys nobody is reading, using the
+// probabilistic sampling Redis uses: it repeatedly samples keys that have a
+// TTL, deletes the expired ones and keeps going only while a sample is
+// mostly expired. It never runs longer than budget and returns the number
+// of keys it removed.
+func (k *Keyspace) ActiveExpireCycle(budget time.Duration) int {
+	start := time.Now()
+	now := k.clock()
+
+	var (
+		totalSampled int
+		totalExpired int
+		iteration    int
+	)
+
+	for len(k.expires) > 0 {
+		sampled, expired := 0, 0
+		var ttlSum, ttlSamples int64
+
+		// map iteration starts at a random position, which gives a cheap
+		// random sample of the keys with a TTL
+		for key, when := range k.expires {
+			if sampled == activeExpireKeysPerLoop {
+				break
+			}
+			sampled++
+			if now > when {
+				k.removeExpired(key)
+				expired++
+			} else {
+				ttlSum += when - now
+				ttlSamples++
+			}
+		}
+
+		// like Redis, weigh each sample's average in slowly
+		if ttlSamples > 0 {
+			avg := ttlSum / ttlSamples
+			if k.avgTTL == 0 {
+				k.avgTTL = avg
+			} else {
+				k.avgTTL = k.avgTTL/50*49 + avg/50
+			}
+		}
+
+		totalSampled += sampled
+		totalExpired += expired
+		iteration++
+
+		if iteration%activeExpireTimeCheckInterval == 0 && time.Since(start) > budget {
+			k.expireStats.ExpiredTimeCapReachedCount++
+			break
+		}
+
+		if expired*100 <= sampled*activeExpireAcceptableStale {
+			break
+		}
+	}
+
+	k.expireStats.ExpireCycleCPUTime += time.Since(start)
+
+	// smooth the stale estimate so one lucky sample does not swing it
+	currentPerc := 0.0
+	if totalSampled > 0 {
+		currentPerc = float64(totalExpired) / float64(totalSampled) * 100
+	}
+	k.expireStats.ExpiredStalePerc = currentPerc*0.05 + k.expireStats.ExpiredStalePerc*0.95
+
+	return totalExpired
+}
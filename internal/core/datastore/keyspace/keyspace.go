@@ -0,0 +1,186 @@
+package keyspace
+
+import "time"
+
+// Keyspace holds every key visible to clients. Expiry times live in a
+// separate map, the same split Redis uses, so that keys without a TTL cost
+// nothing extra and the keys that can expire are cheap to enumerate.
+//
+// A Keyspace is not safe for concurrent use; it is owned by the executor
+// goroutine.
+type Keyspace struct {
+	data        map[string]*Object
+	expires     map[string]int64
+	clock       func() int64
+	expireStats ExpireStats
+	// avgTTL estimates the TTL left on keys that have one, in
+	// milliseconds; see ActiveExpireCycle.
+	avgTTL     int64
+	addHook    func(key string)
+	modifyHook func(key string)
+	dirty      int64
+	// epoch counts the snapshots taken, snapshots those still held, and
+	// copying those still being copied; see Snapshot.
+	epoch     uint64
+	snapshots int
+	copying   []*Snapshot
+	// slotKeys holds the keys hashing to each cluster slot; see IndexSlots.
+	slotKeys []map[string]struct{}
+}
+
+func New() *Keyspace {
+	return &Keyspace{
+		data:    make(map[string]*Object),
+		expires: make(map[string]int64),
+		clock:   func() int64 { return time.Now().UnixMilli() },
+	}
+}
+
+// SetClock replaces the millisecond clock used for expiry decisions.
+func (k *Keyspace) SetClock(clock func() int64) {
+	k.clock = clock
+}
+
+// Now returns the current time in unix milliseconds.
+func (k *Keyspace) Now() int64 {
+	return k.clock()
+}
+
+// SetAddHook registers a function that SetObject calls with the key every
+// time it stores a value. The executor uses it to learn when a key that
+// blocked clients are waiting on can serve them.
+func (k *Keyspace) SetAddHook(hook func(key string)) {
+	k.addHook = hook
+}
+
+// SetModifyHook registers a function called with the key every time a key
+// is written, deleted, expired or has its TTL changed. The executor uses it
+// to fail transactions that WATCH the key. A caller that changes an object
+// got from LookupWrite reports it with SignalModified.
+func (k *Keyspace) SetModifyHook(hook func(key string)) {
+	k.modifyHook = hook
+}
+
+// Dirty returns the number of modifications made to the keyspace so far.
+// Comparing it before and after a command tells whether the command
+// changed anything.
+func (k *Keyspace) Dirty() int64 {
+	return k.dirty
+}
+
+// LookupRead returns the object stored at key, or nil if there is none. A
+// key whose TTL has passed is removed on access and reported as missing.
+func (k *Keyspace) LookupRead(key string) *Object {
+	if k.expireIfNeeded(key) {
+		return nil
+	}
+	return k.data[key]
+}
+
+// LookupWrite returns the object stored at key for a caller that intends to
+// modify it in place. Objects must only be modified through LookupWrite,
+// which copies the object first if a snapshot is still reading it. The
+// caller calls SignalModified once it has changed the object, so a write
+// that turns out to change nothing, like SADD of a member already there,
+// neither fails a WATCH nor counts as dirty.
+func (k *Keyspace) LookupWrite(key string) *Object {
+	object := k.LookupRead(key)
+	if object == nil {
+		return nil
+	}
+	return k.own(key, object)
+}
+
+// SignalModified records that the object at key was changed in place.
+func (k *Keyspace) SignalModified(key string) {
+	k.signalModified(key)
+}
+
+// Exists reports whether key is present and not expired.
+func (k *Keyspace) Exists(key string) bool {
+	return k.LookupRead(key) != nil
+}
+
+// Set stores a string value at key and discards any TTL the key had.
+func (k *Keyspace) Set(key, value string) {
+	k.SetObject(key, NewStringObject(value))
+}
+
+// SetKeepTTL stores a string value at key and retains any TTL the key had.
+func (k *Keyspace) SetKeepTTL(key, value string) {
+	k.expireIfNeeded(key)
+	k.preserve(key)
+	object := NewStringObject(value)
+	object.epoch = k.epoch
+	k.data[key] = object
+	k.indexKey(key)
+	k.signalModified(key)
+}
+
+// SetObject stores object at key, replacing any value of any type, and
+// discards any TTL the key had. The object must be a new one.
+func (k *Keyspace) SetObject(key string, object *Object) {
+	k.preserve(key)
+	object.epoch = k.epoch
+	k.data[key] = object
+	k.indexKey(key)
+	delete(k.expires, key)
+	k.signalModified(key)
+	if k.addHook != nil {
+		k.addHook(key)
+	}
+}
+
+// Delete removes key and its TTL, reporting whether a live key was removed.
+func (k *Keyspace) Delete(key string) bool {
+	if k.expireIfNeeded(key) {
+		return false
+	}
+	if _, exists := k.data[key]; !exists {
+		return false
+	}
+	k.preserve(key)
+	delete(k.data, key)
+	k.unindexKey(key)
+	delete(k.expires, key)
+	k.signalModified(key)
+	return true
+}
+
+// Len returns the number of keys, including expired keys that have not
+// been reclaimed yet.
+func (k *Keyspace) Len() int {
+	return len(k.data)
+}
+
+// ExpiresLen returns the number of keys with a TTL.
+func (k *Keyspace) ExpiresLen() int {
+	return len(k.expires)
+}
+
+// expireIfNeeded deletes key when its TTL has passed and reports whether it
+// did so.
+func (k *Keyspace) expireIfNeeded(key string) bool {
+	when, exists := k.expires[key]
+	if !exists || k.clock() <= when {
+		return false
+	}
+	k.removeExpired(key)
+	return true
+}
+
+func (k *Keyspace) removeExpired(key string) {
+	k.preserve(key)
+	delete(k.data, key)
+	k.unindexKey(key)
+	delete(k.expires, key)
+	k.expireStats.ExpiredKeys++
+	k.signalModified(key)
+}
+
+func (k *Keyspace) signalModified(key string) {
+	k.dirty++
+	if k.modifyHook != nil {
+		k.modifyHook(key)
+	}
+}
@@ -0,0 +1,184 @@
+package keyspace
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredKeyIsInvisible(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	k.Set("foo", "bar")
+	assert.True(t, k.SetExpireAt("foo", 1_500))
+
+	object := k.LookupRead("foo")
+	assert.NotNil(t, object)
+	assert.Equal(t, "bar", object.Str)
+
+	// the key is still alive at exactly its expiry time
+	now = 1_500
+	assert.True(t, k.Exists("foo"))
+
+	now = 1_501
+	assert.Nil(t, k.LookupRead("foo"))
+	assert.Equal(t, 0, k.Len())
+	_, hasTTL := k.ExpireAt("foo")
+	assert.False(t, hasTTL)
+}
+
+func TestSetExpireAtMissingKey(t *testing.T) {
+	k := New()
+	assert.False(t, k.SetExpireAt("missing", 1))
+	assert.False(t, k.Persist("missing"))
+}
+
+func TestSetClearsTTL(t *testing.T) {
+	k := New()
+	k.Set("foo", "bar")
+	k.SetExpireAt("foo", k.Now()+10_000)
+
+	k.SetKeepTTL("foo", "baz")
+	_, hasTTL := k.ExpireAt("foo")
+	assert.True(t, hasTTL)
+
+	k.Set("foo", "qux")
+	_, hasTTL = k.ExpireAt("foo")
+	assert.False(t, hasTTL)
+}
+
+func TestDeleteRemovesTTL(t *testing.T) {
+	k := New()
+	k.Set("foo", "bar")
+	k.SetExpireAt("foo", k.Now()+10_000)
+
+	assert.True(t, k.Delete("foo"))
+	assert.False(t, k.Delete("foo"))
+
+	k.Set("foo", "bar")
+	_, hasTTL := k.ExpireAt("foo")
+	assert.False(t, hasTTL)
+}
+
+func TestActiveExpireCycleReclaimsExpiredKeys(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	for i := range 1000 {
+		key := strconv.Itoa(i)
+		k.Set(key, "v")
+		k.SetExpireAt(key, 1_100)
+	}
+	k.Set("persistent", "v")
+	k.Set("volatile", "v")
+	k.SetExpireAt("volatile", 10_000)
+
+	assert.Equal(t, int64(0), k.AvgTTL())
+	assert.Equal(t, 0, k.ActiveExpireCycle(time.Second))
+	assert.Equal(t, 1001, k.ExpiresLen())
+	// every key sampled but volatile has 100ms left
+	assert.InDelta(t, 100, k.AvgTTL(), 500)
+
+	now = 2_000
+	removed := k.ActiveExpireCycle(time.Second)
+	assert.Equal(t, 1000, removed)
+	assert.Equal(t, 2, k.Len())
+	assert.Equal(t, 1, k.ExpiresLen())
+	assert.True(t, k.Exists("persistent"))
+	assert.True(t, k.Exists("volatile"))
+
+	stats := k.ExpireStats()
+	assert.Equal(t, int64(1000), stats.ExpiredKeys)
+	assert.Greater(t, stats.ExpiredStalePerc, 0.0)
+	assert.Positive(t, stats.ExpireCycleCPUTime, "a cycle under a millisecond still counts")
+}
+
+func TestActiveExpireCycleStopsOnMostlyLiveSample(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	for i := range 1000 {
+		key := strconv.Itoa(i)
+		k.Set(key, "v")
+		k.SetExpireAt(key, 10_000)
+	}
+
+	// a single expired key in a sample of live ones is below the
+	// acceptable stale threshold, so one iteration is all that runs
+	k.Set("stale", "v")
+	k.SetExpireAt("stale", 1_001)
+	now = 1_002
+
+	assert.LessOrEqual(t, k.ActiveExpireCycle(time.Second), 1)
+	assert.Equal(t, int64(0), k.ExpireStats().ExpiredTimeCapReachedCount)
+}
+
+func TestActiveExpireCycleRespectsBudget(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	for i := range 10_000 {
+		key := strconv.Itoa(i)
+		k.Set(key, "v")
+		k.SetExpireAt(key, 1_001)
+	}
+	now = 2_000
+
+	k.ActiveExpireCycle(0)
+	assert.Equal(t, int64(1), k.ExpireStats().ExpiredTimeCapReachedCount)
+	assert.Greater(t, k.Len(), 0)
+}
+
+func TestLazyExpiryCountsExpiredKeys(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	k.Set("foo", "bar")
+	k.SetExpireAt("foo", 1_001)
+	now = 1_002
+
+	assert.False(t, k.Exists("foo"))
+	assert.Equal(t, int64(1), k.ExpireStats().ExpiredKeys)
+}
+
+func TestModifyHook(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+	modified := []string{}
+	k.SetModifyHook(func(key string) { modified = append(modified, key) })
+
+	k.Set("a", "1")
+	k.LookupRead("a")
+	k.LookupWrite("missing")
+	k.Delete("missing")
+	k.Persist("a")
+	assert.Equal(t, []string{"a"}, modified)
+	assert.Equal(t, int64(1), k.Dirty())
+
+	k.LookupWrite("a")
+	assert.Equal(t, []string{"a"}, modified, "a lookup for writing changes nothing by itself")
+	assert.Equal(t, int64(1), k.Dirty())
+	k.SignalModified("a")
+	k.SetKeepTTL("a", "2")
+	k.SetExpireAt("a", 1_500)
+	k.Persist("a")
+	k.Delete("a")
+	assert.Equal(t, []string{"a", "a", "a", "a", "a", "a"}, modified)
+
+	// a key reclaimed on expiry has changed too
+	k.Set("b", "1")
+	k.SetExpireAt("b", 1_500)
+	modified = modified[:0]
+	now = 2_000
+	assert.False(t, k.Exists("b"))
+	assert.Equal(t, []string{"b"}, modified)
+}
@@ -0,0 +1,232 @@
+package keyspace
+
+import "iter"
+
+const minListCapacity = 8
+
+// List is a double-ended queue of strings backed by a ring buffer. Pushes
+// and pops at either end are amortised O(1) and indexing is O(1); inserting
+// or removing in the middle shifts whichever side of the list is shorter.
+type List struct {
+	buf  []string
+	head int
+	size int
+}
+
+func NewList() *List {
+	return &List{
+		buf: make([]string, minListCapacity),
+	}
+}
+
+func (l *List) Len() int {
+	return l.size
+}
+
+func (l *List) PushFront(value string) {
+	l.grow()
+	l.head = l.wrap(l.head - 1)
+	l.buf[l.head] = value
+	l.size++
+}
+
+func (l *List) PushBack(value string) {
+	l.grow()
+	l.buf[l.wrap(l.head+l.size)] = value
+	l.size++
+}
+
+// PopFront removes and returns the first element. The list must not be
+// empty.
+func (l *List) PopFront() string {
+	value := l.buf[l.head]
+	l.buf[l.head] = ""
+	l.head = l.wrap(l.head + 1)
+	l.size--
+	l.shrink()
+	return value
+}
+
+// PopBack removes and returns the last element. The list must not be empty.
+func (l *List) PopBack() string {
+	tail := l.wrap(l.head + l.size - 1)
+	value := l.buf[tail]
+	l.buf[tail] = ""
+	l.size--
+	l.shrink()
+	return value
+}
+
+// Index returns the element at position i, counting from 0 at the head.
+func (l *List) Index(i int) string {
+	return l.buf[l.wrap(l.head+i)]
+}
+
+// Set replaces the element at position i.
+func (l *List) Set(i int, value string) {
+	l.buf[l.wrap(l.head+i)] = value
+}
+
+// Range returns a copy of the elements from start to stop inclusive. Both
+// positions must already be normalised to the list bounds.
+func (l *List) Range(start, stop int) []string {
+	if start > stop {
+		return []string{}
+	}
+	values := make([]string, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		values = append(values, l.Index(i))
+	}
+	return values
+}
+
+// Insert places value so that it ends up at position i, shifting the
+// elements on the shorter side of i by one.
+func (l *List) Insert(i int, value string) {
+	if i <= l.size/2 {
+		l.PushFront(value)
+		for j := 0; j < i; j++ {
+			l.Set(j, l.Index(j+1))
+		}
+	} else {
+		l.PushBack(value)
+		for j := l.size - 1; j > i; j-- {
+			l.Set(j, l.Index(j-1))
+		}
+	}
+	l.Set(i, value)
+}
+
+// Remove deletes the element at position i.
+func (l *List) Remove(i int) {
+	if i < l.size/2 {
+		for j := i; j > 0; j-- {
+			l.Set(j, l.Index(j-1))
+		}
+		l.PopFront()
+		return
+	}
+	for j := i; j < l.size-1; j++ {
+		l.Set(j, l.Index(j+1))
+	}
+	l.PopBack()
+}
+
+// RemoveValue deletes up to count occurrences of value, scanning from the
+// head when count is positive and from the tail when it is negative. A
+// count of zero removes every occurrence. It returns the number removed.
+func (l *List) RemoveValue(value string, count int) int {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	// mark the victims first so the list is compacted in a single pass
+	remove := make(map[int]struct{})
+	if count >= 0 {
+		for i := 0; i < l.size && (limit == 0 || len(remove) < limit); i++ {
+			if l.Index(i) == value {
+				remove[i] = struct{}{}
+			}
+		}
+	} else {
+		for i := l.size - 1; i >= 0 && len(remove) < limit; i-- {
+			if l.Index(i) == value {
+				remove[i] = struct{}{}
+			}
+		}
+	}
+	if len(remove) == 0 {
+		return 0
+	}
+
+	kept := make([]string, 0, l.size-len(remove))
+	for i := 0; i < l.size; i++ {
+		if _, skip := remove[i]; !skip {
+			kept = append(kept, l.Index(i))
+		}
+	}
+	l.reset(kept)
+	return len(remove)
+}
+
+// Trim keeps only the elements from start to stop inclusive. Both positions
+// must already be normalised to the list bounds; start > stop empties it.
+func (l *List) Trim(start, stop int) {
+	if start > stop {
+		l.reset(nil)
+		return
+	}
+	for range l.size - 1 - stop {
+		l.PopBack()
+	}
+	for range start {
+		l.PopFront()
+	}
+}
+
+// IndexOf returns the position of the first element equal to value, or -1.
+func (l *List) IndexOf(value string) int {
+	for i := 0; i < l.size; i++ {
+		if l.Index(i) == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// All iterates over the elements from head to tail. The list must not be
+// modified while the iteration is in progress.
+func (l *List) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for i := 0; i < l.size; i++ {
+			if !yield(l.Index(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (l *List) clone() *List {
+	c := &List{}
+	c.reset(l.Range(0, l.size-1))
+	return c
+}
+
+func (l *List) reset(values []string) {
+	l.buf = make([]string, max(minListCapacity, len(values)))
+	copy(l.buf, values)
+	l.head = 0
+	l.size = len(values)
+}
+
+func (l *List) wrap(i int) int {
+	n := len(l.buf)
+	return ((i % n) + n) % n
+}
+
+// grow doubles the buffer when it is full, unrolling the ring so the head
+// lands at index 0.
+func (l *List) grow() {
+	if l.size < len(l.buf) {
+		return
+	}
+	l.resize(len(l.buf) * 2)
+}
+
+// shrink halves the buffer once it is three quarters empty so a list that
+// was briefly large does not hold on to its peak memory.
+func (l *List) shrink() {
+	if len(l.buf) > minListCapacity && l.size <= len(l.buf)/4 {
+		l.resize(len(l.buf) / 2)
+	}
+}
+
+func (l *List) resize(capacity int) {
+	buf := make([]string, capacity)
+	for i := 0; i < l.size; i++ {
+		buf[i] = l.Index(i)
+	}
+	l.buf = buf
+	l.head = 0
+}
@@ -0,0 +1,107 @@
+package keyspace
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listOf(values ...string) *List {
+	l := NewList()
+	for _, value := range values {
+		l.PushBack(value)
+	}
+	return l
+}
+
+func contents(l *List) []string {
+	return l.Range(0, l.Len()-1)
+}
+
+func TestListPushPopBothEnds(t *testing.T) {
+	l := NewList()
+	l.PushBack("b")
+	l.PushFront("a")
+	l.PushBack("c")
+
+	assert.Equal(t, []string{"a", "b", "c"}, contents(l))
+	assert.Equal(t, "a", l.PopFront())
+	assert.Equal(t, "c", l.PopBack())
+	assert.Equal(t, "b", l.PopFront())
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestListGrowsAndShrinksAcrossWrap(t *testing.T) {
+	l := NewList()
+
+	// push on both ends so the ring wraps before it has to grow
+	expected := make([]string, 0, 1000)
+	for i := range 500 {
+		l.PushFront("f" + strconv.Itoa(i))
+		l.PushBack("b" + strconv.Itoa(i))
+	}
+	for i := 499; i >= 0; i-- {
+		expected = append(expected, "f"+strconv.Itoa(i))
+	}
+	for i := range 500 {
+		expected = append(expected, "b"+strconv.Itoa(i))
+	}
+	assert.Equal(t, expected, contents(l))
+
+	for range 990 {
+		l.PopFront()
+	}
+	assert.Equal(t, expected[990:], contents(l))
+	assert.Less(t, len(l.buf), 64)
+}
+
+func TestListInsertAndRemove(t *testing.T) {
+	l := listOf("a", "b", "d", "e", "f")
+
+	l.Insert(2, "c")
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, contents(l))
+	l.Insert(5, "x")
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "x", "f"}, contents(l))
+	l.Insert(0, "start")
+	l.Insert(l.Len(), "end")
+	assert.Equal(t, []string{"start", "a", "b", "c", "d", "e", "x", "f", "end"}, contents(l))
+
+	l.Remove(1)
+	l.Remove(5)
+	assert.Equal(t, []string{"start", "b", "c", "d", "e", "f", "end"}, contents(l))
+	l.Remove(0)
+	l.Remove(l.Len() - 1)
+	assert.Equal(t, []string{"b", "c", "d", "e", "f"}, contents(l))
+}
+
+func TestListRemoveValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		removed  int
+		expected []string
+	}{
+		{name: "all", count: 0, removed: 3, expected: []string{"b", "c"}},
+		{name: "from head", count: 2, removed: 2, expected: []string{"b", "c", "a"}},
+		{name: "from tail", count: -2, removed: 2, expected: []string{"a", "b", "c"}},
+		{name: "more than present", count: 10, removed: 3, expected: []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := listOf("a", "b", "a", "c", "a")
+			assert.Equal(t, tt.removed, l.RemoveValue("a", tt.count))
+			assert.Equal(t, tt.expected, contents(l))
+		})
+	}
+}
+
+func TestListTrim(t *testing.T) {
+	l := listOf("a", "b", "c", "d", "e")
+	l.Trim(1, 3)
+	assert.Equal(t, []string{"b", "c", "d"}, contents(l))
+
+	l.Trim(1, 0)
+	assert.Equal(t, 0, l.Len())
+}
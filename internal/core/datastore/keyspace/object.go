@@ -0,0 +1,92 @@
+package keyspace
+
+import "maps"
+
+// ObjectType identifies the data type of a stored value.
+type ObjectType int
+
+const (
+	StringType ObjectType = iota
+	ListType
+	HashType
+	SetType
+	ZSetType
+)
+
+var objectTypeNames = map[ObjectType]string{
+	StringType: "string",
+	ListType:   "list",
+	HashType:   "hash",
+	SetType:    "set",
+	ZSetType:   "zset",
+}
+
+// String returns the name Redis uses for the type, as reported by TYPE.
+func (t ObjectType) String() string {
+	return objectTypeNames[t]
+}
+
+// Object is a value stored in the keyspace. Only the field matching Type is
+// meaningful.
+type Object struct {
+	Type ObjectType
+	Str  string
+	List *List
+	Hash map[string]string
+	Set  *Set
+	ZSet *ZSet
+	// epoch is the keyspace snapshot epoch the object was created in. An
+	// object from an earlier epoch may be shared with a snapshot.
+	epoch uint64
+}
+
+func NewStringObject(value string) *Object {
+	return &Object{
+		Type: StringType,
+		Str:  value,
+	}
+}
+
+func NewListObject() *Object {
+	return &Object{
+		Type: ListType,
+		List: NewList(),
+	}
+}
+
+func NewHashObject() *Object {
+	return &Object{
+		Type: HashType,
+		Hash: make(map[string]string),
+	}
+}
+
+func NewSetObject() *Object {
+	return &Object{
+		Type: SetType,
+		Set:  NewSet(),
+	}
+}
+
+func NewZSetObject() *Object {
+	return &Object{
+		Type: ZSetType,
+		ZSet: NewZSet(),
+	}
+}
+
+// clone returns a deep copy of the object, for copy-on-write.
+func (o *Object) clone() *Object {
+	c := &Object{Type: o.Type, Str: o.Str}
+	switch o.Type {
+	case ListType:
+		c.List = o.List.clone()
+	case HashType:
+		c.Hash = maps.Clone(o.Hash)
+	case SetType:
+		c.Set = o.Set.clone()
+	case ZSetType:
+		c.ZSet = o.ZSet.clone()
+	}
+	return c
+}
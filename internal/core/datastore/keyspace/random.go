@@ -0,0 +1,41 @@
+package keyspace
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+)
+
+// RandomSample returns up to k distinct elements chosen uniformly at random,
+// in random order, using reservoir sampling so the elements never have to
+// be copied out first.
+func RandomSample(elements iter.Seq[string], k int) []string {
+	sample := make([]string, 0, k)
+	seen := 0
+	for element := range elements {
+		if seen < k {
+			sample = append(sample, element)
+		} else if j := rand.IntN(seen + 1); j < k {
+			sample[j] = element
+		}
+		seen++
+	}
+	rand.Shuffle(len(sample), func(i, j int) {
+		sample[i], sample[j] = sample[j], sample[i]
+	})
+	return sample
+}
+
+// RandomSampleWithRepeats returns k elements chosen uniformly at random,
+// where the same element may be chosen more than once.
+func RandomSampleWithRepeats(elements iter.Seq[string], k int) []string {
+	all := slices.Collect(elements)
+	if len(all) == 0 {
+		return []string{}
+	}
+	sample := make([]string, k)
+	for i := range sample {
+		sample[i] = all[rand.IntN(len(all))]
+	}
+	return sample
+}
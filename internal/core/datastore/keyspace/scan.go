@@ -0,0 +1,59 @@
+package keyspace
+
+import (
+	"cmp"
+	"hash/fnv"
+	"iter"
+	"slices"
+)
+
+// Scan returns one page of a cursor-based iteration over elements, for the
+// SCAN family of commands. Elements are visited in the order of a 64-bit
+// hash of their value and the cursor is the hash to resume from, so the
+// order does not depend on how the underlying map happens to be laid out.
+// That gives the same guarantee Redis makes: an element present for the
+// whole iteration is returned at least once, however the collection
+// changes between calls.
+//
+// Cursor 0 starts an iteration and a returned cursor of 0 ends it. A page
+// can hold more than count elements when several share a hash, because a
+// page never splits elements with the same position.
+func Scan(elements iter.Seq[string], cursor uint64, count int) (uint64, []string) {
+	type positioned struct {
+		position uint64
+		element  string
+	}
+
+	var remaining []positioned
+	for element := range elements {
+		if position := scanPosition(element); position >= cursor {
+			remaining = append(remaining, positioned{position, element})
+		}
+	}
+	slices.SortFunc(remaining, func(a, b positioned) int {
+		return cmp.Compare(a.position, b.position)
+	})
+
+	end := min(count, len(remaining))
+	for end > 0 && end < len(remaining) && remaining[end].position == remaining[end-1].position {
+		end++
+	}
+
+	page := make([]string, end)
+	for i := range end {
+		page[i] = remaining[i].element
+	}
+
+	if end == len(remaining) {
+		return 0, page
+	}
+	return remaining[end].position, page
+}
+
+// scanPosition never returns 0, since that cursor value is reserved for the
+// start and end of an iteration.
+func scanPosition(element string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(element))
+	return max(h.Sum64(), 1)
+}
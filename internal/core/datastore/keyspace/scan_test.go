@@ -0,0 +1,75 @@
+package keyspace
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanVisitsEveryElementOnce(t *testing.T) {
+	set := make(map[string]struct{})
+	for i := range 1000 {
+		set[strconv.Itoa(i)] = struct{}{}
+	}
+
+	seen := make(map[string]int)
+	cursor := uint64(0)
+	calls := 0
+	for {
+		next, page := Scan(maps.Keys(set), cursor, 10)
+		for _, element := range page {
+			seen[element]++
+		}
+		calls++
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Len(t, seen, 1000)
+	for _, n := range seen {
+		assert.Equal(t, 1, n)
+	}
+	assert.Equal(t, 100, calls)
+}
+
+func TestScanSurvivesConcurrentChanges(t *testing.T) {
+	set := make(map[string]struct{})
+	for i := range 100 {
+		set["stable"+strconv.Itoa(i)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{})
+	cursor := uint64(0)
+	round := 0
+	for {
+		next, page := Scan(maps.Keys(set), cursor, 7)
+		for _, element := range page {
+			seen[element] = struct{}{}
+		}
+
+		// churn elements between calls
+		set["added"+strconv.Itoa(round)] = struct{}{}
+		delete(set, "added"+strconv.Itoa(round-1))
+		round++
+
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	for i := range 100 {
+		assert.Contains(t, seen, "stable"+strconv.Itoa(i))
+	}
+}
+
+func TestScanEmpty(t *testing.T) {
+	next, page := Scan(slices.Values([]string(nil)), 0, 10)
+	assert.Equal(t, uint64(0), next)
+	assert.Empty(t, page)
+}
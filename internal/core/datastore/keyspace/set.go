@@ -0,0 +1,149 @@
+package keyspace
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// SetMaxIntsetEntries is the largest set kept in the compact integer
+// encoding, matching the set-max-intset-entries default in Redis.
+const SetMaxIntsetEntries = 512
+
+// Set is an unordered collection of unique strings.
+//
+// Like Redis, a set whose members are all integers starts out as an intset:
+// a sorted []int64 searched with binary search, which costs 8 bytes per
+// member instead of a string plus a map bucket. It converts to a hash
+// table for good once a non-integer member is added or it grows past
+// SetMaxIntsetEntries.
+type Set struct {
+	ints    []int64
+	members map[string]struct{}
+}
+
+func NewSet() *Set {
+	return &Set{}
+}
+
+// IsIntset reports whether the set is still using the integer encoding.
+func (s *Set) IsIntset() bool {
+	return s.members == nil
+}
+
+func (s *Set) Len() int {
+	if s.IsIntset() {
+		return len(s.ints)
+	}
+	return len(s.members)
+}
+
+// Add inserts member, reporting whether it was not already present.
+func (s *Set) Add(member string) bool {
+	if s.IsIntset() {
+		if value, ok := parseSetInteger(member); ok {
+			index, found := slices.BinarySearch(s.ints, value)
+			if found {
+				return false
+			}
+			if len(s.ints) < SetMaxIntsetEntries {
+				s.ints = slices.Insert(s.ints, index, value)
+				return true
+			}
+		}
+		s.convertToHashTable()
+	}
+
+	if _, exists := s.members[member]; exists {
+		return false
+	}
+	s.members[member] = struct{}{}
+	return true
+}
+
+// Remove deletes member, reporting whether it was present.
+func (s *Set) Remove(member string) bool {
+	if s.IsIntset() {
+		value, ok := parseSetInteger(member)
+		if !ok {
+			return false
+		}
+		index, found := slices.BinarySearch(s.ints, value)
+		if !found {
+			return false
+		}
+		s.ints = slices.Delete(s.ints, index, index+1)
+		return true
+	}
+
+	if _, exists := s.members[member]; !exists {
+		return false
+	}
+	delete(s.members, member)
+	return true
+}
+
+func (s *Set) Contains(member string) bool {
+	if s.IsIntset() {
+		value, ok := parseSetInteger(member)
+		if !ok {
+			return false
+		}
+		_, found := slices.BinarySearch(s.ints, value)
+		return found
+	}
+	_, exists := s.members[member]
+	return exists
+}
+
+// All iterates over the members. The set must not be modified while the
+// iteration is in progress.
+func (s *Set) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if s.IsIntset() {
+			for _, value := range s.ints {
+				if !yield(strconv.FormatInt(value, 10)) {
+					return
+				}
+			}
+			return
+		}
+		for member := range s.members {
+			if !yield(member) {
+				return
+			}
+		}
+	}
+}
+
+// Members returns a copy of every member.
+func (s *Set) Members() []string {
+	return slices.AppendSeq(make([]string, 0, s.Len()), s.All())
+}
+
+func (s *Set) clone() *Set {
+	return &Set{
+		ints:    slices.Clone(s.ints),
+		members: maps.Clone(s.members),
+	}
+}
+
+func (s *Set) convertToHashTable() {
+	s.members = make(map[string]struct{}, len(s.ints)+1)
+	for _, value := range s.ints {
+		s.members[strconv.FormatInt(value, 10)] = struct{}{}
+	}
+	s.ints = nil
+}
+
+// parseSetInteger accepts only the canonical decimal form of an int64, so
+// that converting back with FormatInt gives the exact member that was
+// added; "007" or "+7" must stay strings.
+func parseSetInteger(member string) (int64, bool) {
+	value, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(value, 10) != member {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,63 @@
+package keyspace
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetIntsetEncoding(t *testing.T) {
+	s := NewSet()
+	assert.True(t, s.Add("3"))
+	assert.True(t, s.Add("-1"))
+	assert.True(t, s.Add("2"))
+	assert.False(t, s.Add("2"))
+
+	assert.True(t, s.IsIntset())
+	assert.Equal(t, []int64{-1, 2, 3}, s.ints)
+	assert.True(t, s.Contains("-1"))
+	assert.False(t, s.Contains("4"))
+	assert.False(t, s.Contains("abc"))
+
+	assert.True(t, s.Remove("2"))
+	assert.False(t, s.Remove("2"))
+	assert.False(t, s.Remove("abc"))
+	assert.Equal(t, []string{"-1", "3"}, s.Members())
+}
+
+func TestSetNonCanonicalIntegersAreStrings(t *testing.T) {
+	for _, member := range []string{"007", "+7", "-0", " 7", "9223372036854775808"} {
+		t.Run(member, func(t *testing.T) {
+			s := NewSet()
+			s.Add("7")
+			s.Add(member)
+			assert.False(t, s.IsIntset())
+			assert.True(t, s.Contains(member))
+			assert.True(t, s.Contains("7"))
+			assert.Equal(t, 2, s.Len())
+		})
+	}
+}
+
+func TestSetConvertsWhenTooLarge(t *testing.T) {
+	s := NewSet()
+	for i := range SetMaxIntsetEntries {
+		s.Add(strconv.Itoa(i))
+	}
+	assert.True(t, s.IsIntset())
+
+	s.Add(strconv.Itoa(SetMaxIntsetEntries))
+	assert.False(t, s.IsIntset())
+	assert.Equal(t, SetMaxIntsetEntries+1, s.Len())
+
+	members := s.Members()
+	sort.Slice(members, func(i, j int) bool {
+		a, _ := strconv.Atoi(members[i])
+		b, _ := strconv.Atoi(members[j])
+		return a < b
+	})
+	assert.Equal(t, "0", members[0])
+	assert.Equal(t, strconv.Itoa(SetMaxIntsetEntries), members[len(members)-1])
+}
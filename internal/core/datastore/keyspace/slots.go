@@ -0,0 +1,60 @@
+package keyspace
+
+import "github.com/suryansh0301/Mnemo/internal/core/cluster"
+
+// IndexSlots makes the keyspace keep track of the keys hashing to each
+// cluster slot, which CountKeysInSlot and KeysInSlot need. Only cluster
+// mode needs it, so only cluster mode pays for it.
+func (k *Keyspace) IndexSlots() {
+	k.slotKeys = make([]map[string]struct{}, cluster.SlotCount)
+	for key := range k.data {
+		k.indexKey(key)
+	}
+}
+
+func (k *Keyspace) indexKey(key string) {
+	if k.slotKeys == nil {
+		return
+	}
+	slot := cluster.KeySlot(key)
+	if k.slotKeys[slot] == nil {
+		k.slotKeys[slot] = make(map[string]struct{})
+	}
+	k.slotKeys[slot][key] = struct{}{}
+}
+
+func (k *Keyspace) unindexKey(key string) {
+	if k.slotKeys == nil {
+		return
+	}
+	slot := cluster.KeySlot(key)
+	delete(k.slotKeys[slot], key)
+	if len(k.slotKeys[slot]) == 0 {
+		k.slotKeys[slot] = nil
+	}
+}
+
+// CountKeysInSlot returns the number of keys hashing to slot, including
+// expired keys not reclaimed yet. It is zero unless IndexSlots was called.
+func (k *Keyspace) CountKeysInSlot(slot int) int {
+	if k.slotKeys == nil {
+		return 0
+	}
+	return len(k.slotKeys[slot])
+}
+
+// KeysInSlot returns up to count of the keys hashing to slot, in no
+// particular order.
+func (k *Keyspace) KeysInSlot(slot, count int) []string {
+	if k.slotKeys == nil {
+		return nil
+	}
+	keys := make([]string, 0, min(count, len(k.slotKeys[slot])))
+	for key := range k.slotKeys[slot] {
+		if len(keys) == count {
+			break
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,34 @@
+package keyspace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/cluster"
+)
+
+func TestSlotIndex(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+	k.Set("{a}1", "x")
+	slot := cluster.KeySlot("a")
+	assert.Zero(t, k.CountKeysInSlot(slot), "not indexed until asked")
+
+	// keys there already are indexed, and so are those added later
+	k.IndexSlots()
+	k.Set("{a}2", "x")
+	k.SetObject("{a}3", k.LookupRead("{a}1"))
+	k.Set("{a}1", "y")
+	k.Set("other", "x")
+	assert.Equal(t, 3, k.CountKeysInSlot(slot))
+	assert.ElementsMatch(t, []string{"{a}1", "{a}2", "{a}3"}, k.KeysInSlot(slot, 10))
+	assert.Len(t, k.KeysInSlot(slot, 2), 2)
+
+	k.Delete("{a}2")
+	k.SetExpireAt("{a}3", 1_500)
+	now = 2_000
+	assert.False(t, k.Exists("{a}3"))
+	assert.Equal(t, []string{"{a}1"}, k.KeysInSlot(slot, 10))
+	assert.Equal(t, 1, k.CountKeysInSlot(cluster.KeySlot("other")))
+}
@@ -0,0 +1,215 @@
+package keyspace
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"time"
+)
+
+// snapshotCopyBatch is how many keys a snapshot copies between checks of
+// the time budget. Snapshot copies one batch at once, so a keyspace no
+// bigger than that is ready to read straight away.
+const snapshotCopyBatch = 1024
+
+// Snapshot is a frozen, point-in-time view of a keyspace that another
+// goroutine can read while the executor goes on writing, which is what
+// fork gives Redis for BGSAVE and BGREWRITEAOF.
+//
+// Taking one copies nothing but a first batch of keys. The executor copies
+// the rest of the key index a batch at a time, from its cron, in
+// CopySnapshots; until a key has been copied, a write to it first hands
+// the snapshot the value and TTL it had, so the keys copied later are
+// still the ones there were when the snapshot was taken. Values are
+// shared with the live keyspace until the executor writes to one, when
+// LookupWrite swaps a copy into the live keyspace and leaves the original
+// to the snapshot.
+//
+// That leaves two costs that grow with the data. The snapshot's index
+// holds every key, built over as many cron periods as it takes. And the
+// first write to a value while a snapshot is held copies the whole value
+// on the executor goroutine: for a large list, hash, set or sorted set
+// that is a copy of the collection, where Redis would copy only the pages
+// written to.
+type Snapshot struct {
+	// data holds the keys copied so far, with a nil value for a key that
+	// was written to before being copied but did not exist when the
+	// snapshot was taken.
+	data    map[string]*Object
+	expires map[string]int64
+	keys    int
+	time    int64
+	// next pulls the keys still to copy from the live keyspace, until
+	// stop ends the iteration and ready is closed.
+	next     func() (string, *Object, bool)
+	stop     func()
+	ready    chan struct{}
+	keyspace *Keyspace
+	released bool
+}
+
+// Snapshot freezes the current contents of the keyspace. It must be called
+// from the executor goroutine, and the snapshot released from there once
+// it has been read, so writes stop paying for copies.
+func (k *Keyspace) Snapshot() *Snapshot {
+	k.epoch++
+	k.snapshots++
+	s := &Snapshot{
+		data:     make(map[string]*Object),
+		expires:  make(map[string]int64),
+		time:     k.clock(),
+		ready:    make(chan struct{}),
+		keyspace: k,
+	}
+	s.next, s.stop = iter.Pull2(maps.All(k.data))
+	k.copying = append(k.copying, s)
+	s.copy(snapshotCopyBatch)
+	return s
+}
+
+// CopySnapshots goes on copying the keys of the snapshots not yet ready,
+// for no longer than budget, and returns the number of keys it copied.
+func (k *Keyspace) CopySnapshots(budget time.Duration) int {
+	start := time.Now()
+	copied := 0
+	for len(k.copying) > 0 && time.Since(start) <= budget {
+		copied += k.copying[0].copy(snapshotCopyBatch)
+	}
+	return copied
+}
+
+// CompleteSnapshots copies the rest of every snapshot not yet ready, for
+// a caller that cannot wait for the cron.
+func (k *Keyspace) CompleteSnapshots() {
+	for len(k.copying) > 0 {
+		k.copying[0].copy(snapshotCopyBatch)
+	}
+}
+
+// copy copies up to n more keys from the live keyspace, and makes the
+// snapshot ready once there are none left. It returns the number of keys
+// it copied.
+func (s *Snapshot) copy(n int) int {
+	copied := 0
+	for copied < n {
+		key, object, more := s.next()
+		if !more {
+			s.finishCopy()
+			close(s.ready)
+			break
+		}
+		if _, exists := s.data[key]; !exists {
+			s.add(key, object)
+			copied++
+		}
+	}
+	return copied
+}
+
+// add copies the value and TTL key has in the live keyspace, which are
+// still the ones it had when the snapshot was taken.
+func (s *Snapshot) add(key string, object *Object) {
+	s.data[key] = object
+	if object == nil {
+		return
+	}
+	s.keys++
+	if when, exists := s.keyspace.expires[key]; exists {
+		s.expires[key] = when
+	}
+}
+
+func (s *Snapshot) finishCopy() {
+	s.stop()
+	s.next, s.stop = nil, nil
+	k := s.keyspace
+	k.copying = slices.DeleteFunc(k.copying, func(c *Snapshot) bool { return c == s })
+}
+
+// preserve hands the value and TTL key has to every snapshot that has not
+// copied it yet. It is called before key is written, deleted, expired or
+// has its TTL changed.
+func (k *Keyspace) preserve(key string) {
+	for _, s := range k.copying {
+		if _, exists := s.data[key]; !exists {
+			s.add(key, k.data[key])
+		}
+	}
+}
+
+// Release tells the keyspace the snapshot is no longer read, giving up on
+// copying it if it is not yet ready. It must be called from the executor
+// goroutine; calling it again does nothing.
+func (s *Snapshot) Release() {
+	if s.released {
+		return
+	}
+	s.released = true
+	s.keyspace.snapshots--
+	if s.next != nil {
+		s.finishCopy()
+		close(s.ready)
+	}
+	s.data, s.expires = nil, nil
+}
+
+// Ready returns a channel that is closed once the snapshot has been
+// copied and can be read. A reader that can be canceled waits on it
+// alongside its cancel channel.
+func (s *Snapshot) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Time returns the unix millisecond time at which the snapshot was taken.
+func (s *Snapshot) Time() int64 {
+	return s.time
+}
+
+// Len returns the number of keys in the snapshot, including any that had
+// expired but not been reclaimed when it was taken. It must not be called
+// before the snapshot is ready.
+func (s *Snapshot) Len() int {
+	return s.keys
+}
+
+// All iterates over the keys that were live when the snapshot was taken,
+// with their values, waiting for the snapshot to be ready first. It is
+// safe to call from any goroutine until the snapshot is released, and the
+// values must not be modified; but on the executor goroutine, which copies
+// the snapshot, only once it is ready.
+func (s *Snapshot) All() iter.Seq2[string, *Object] {
+	return func(yield func(string, *Object) bool) {
+		<-s.ready
+		for key, object := range s.data {
+			if object == nil {
+				continue
+			}
+			if when, exists := s.expires[key]; exists && when < s.time {
+				continue
+			}
+			if !yield(key, object) {
+				return
+			}
+		}
+	}
+}
+
+// ExpireAt returns the unix millisecond time at which key expires, as it
+// was when the snapshot was taken.
+func (s *Snapshot) ExpireAt(key string) (int64, bool) {
+	when, exists := s.expires[key]
+	return when, exists
+}
+
+// own makes the object stored at key safe to modify in place, copying it
+// first if a snapshot may still be reading it.
+func (k *Keyspace) own(key string, object *Object) *Object {
+	if k.snapshots == 0 || object.epoch == k.epoch {
+		return object
+	}
+	k.preserve(key)
+	object = object.clone()
+	object.epoch = k.epoch
+	k.data[key] = object
+	return object
+}
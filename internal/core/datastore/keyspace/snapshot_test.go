@@ -0,0 +1,157 @@
+package keyspace
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotIsFrozen(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+
+	k.Set("string", "a")
+	list := NewListObject()
+	list.List.PushBack("x")
+	k.SetObject("list", list)
+	hash := NewHashObject()
+	hash.Hash["f"] = "1"
+	k.SetObject("hash", hash)
+	set := NewSetObject()
+	set.Set.Add("1")
+	k.SetObject("set", set)
+	zset := NewZSetObject()
+	zset.ZSet.Add("m", 1)
+	k.SetObject("zset", zset)
+	k.Set("ttl", "t")
+	k.SetExpireAt("ttl", 5_000)
+	k.Set("expired", "e")
+	k.SetExpireAt("expired", 1_500)
+
+	now = 2_000
+	snapshot := k.Snapshot()
+	assert.Equal(t, int64(2_000), snapshot.Time())
+
+	// modify every value in place, replace one and delete another
+	k.LookupWrite("list").List.PushBack("y")
+	k.LookupWrite("hash").Hash["f"] = "2"
+	k.LookupWrite("set").Set.Add("not an int")
+	k.LookupWrite("zset").ZSet.Add("m", 2)
+	k.Set("string", "b")
+	k.Delete("ttl")
+	k.Set("new", "n")
+
+	frozen := maps.Collect(snapshot.All())
+	assert.ElementsMatch(t, []string{"string", "list", "hash", "set", "zset", "ttl"}, slices.Collect(maps.Keys(frozen)))
+	assert.Equal(t, "a", frozen["string"].Str)
+	assert.Equal(t, []string{"x"}, slices.Collect(frozen["list"].List.All()))
+	assert.Equal(t, "1", frozen["hash"].Hash["f"])
+	assert.Equal(t, []string{"1"}, frozen["set"].Set.Members())
+	score, _ := frozen["zset"].ZSet.Score("m")
+	assert.Equal(t, float64(1), score)
+	when, exists := snapshot.ExpireAt("ttl")
+	assert.True(t, exists)
+	assert.Equal(t, int64(5_000), when)
+
+	// the live keyspace has the writes
+	assert.Equal(t, []string{"x", "y"}, slices.Collect(k.LookupRead("list").List.All()))
+	assert.Equal(t, "2", k.LookupRead("hash").Hash["f"])
+	assert.Equal(t, 2, k.LookupRead("set").Set.Len())
+	score, _ = k.LookupRead("zset").ZSet.Score("m")
+	assert.Equal(t, float64(2), score)
+}
+
+func TestSnapshotCopiesOnlyOnce(t *testing.T) {
+	k := New()
+	list := NewListObject()
+	k.SetObject("list", list)
+
+	// with no snapshot held, values are modified in place
+	assert.Same(t, list, k.LookupWrite("list"))
+
+	snapshot := k.Snapshot()
+	copied := k.LookupWrite("list")
+	assert.NotSame(t, list, copied)
+	assert.Same(t, copied, k.LookupWrite("list"))
+
+	// values created while the snapshot is held are not shared with it
+	fresh := NewListObject()
+	k.SetObject("fresh", fresh)
+	assert.Same(t, fresh, k.LookupWrite("fresh"))
+
+	snapshot.Release()
+	snapshot.Release()
+	assert.Same(t, copied, k.LookupWrite("list"))
+	assert.Equal(t, 0, k.snapshots)
+}
+
+func TestSnapshotCopiedInBatches(t *testing.T) {
+	now := int64(1_000)
+	k := New()
+	k.SetClock(func() int64 { return now })
+	keys := 3 * snapshotCopyBatch
+	for i := range keys {
+		k.Set(strconv.Itoa(i), "old")
+	}
+	k.SetExpireAt("0", 5_000)
+	k.SetExpireAt("1", 5_000)
+	k.SetExpireAt("2", 1_500)
+	list := NewListObject()
+	list.List.PushBack("x")
+	k.SetObject("list", list)
+
+	now = 2_000
+	snapshot := k.Snapshot()
+	assert.Len(t, snapshot.data, snapshotCopyBatch, "one batch is copied at once")
+	select {
+	case <-snapshot.Ready():
+		t.Fatal("ready before the rest is copied")
+	default:
+	}
+
+	// write to half the keys, copied or not, before the rest is copied
+	for i := 0; i < keys; i += 2 {
+		k.Set(strconv.Itoa(i), "new")
+	}
+	k.LookupWrite("list").List.PushBack("y")
+	k.Persist("1")
+	k.Delete("list")
+	k.Set("created", "c")
+	assert.Positive(t, k.CopySnapshots(time.Hour))
+	<-snapshot.Ready()
+
+	frozen := maps.Collect(snapshot.All())
+	assert.Len(t, frozen, keys, "every key but the one expired when the snapshot was taken")
+	assert.Equal(t, keys+1, snapshot.Len())
+	for key, object := range frozen {
+		if key != "list" {
+			assert.Equal(t, "old", object.Str, key)
+		}
+	}
+	assert.NotContains(t, frozen, "2")
+	assert.NotContains(t, frozen, "created")
+	assert.Equal(t, []string{"x"}, slices.Collect(frozen["list"].List.All()))
+	when, exists := snapshot.ExpireAt("1")
+	assert.True(t, exists)
+	assert.Equal(t, int64(5_000), when)
+	assert.Empty(t, k.copying)
+	snapshot.Release()
+}
+
+func TestSnapshotReleasedBeforeReady(t *testing.T) {
+	k := New()
+	for i := range 2 * snapshotCopyBatch {
+		k.Set(strconv.Itoa(i), "v")
+	}
+	snapshot := k.Snapshot()
+	snapshot.Release()
+	<-snapshot.Ready()
+	assert.Empty(t, k.copying)
+	assert.Zero(t, k.CopySnapshots(time.Hour))
+	assert.Equal(t, 0, k.snapshots)
+}
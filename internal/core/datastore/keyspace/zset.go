@@ -0,0 +1,539 @@
+package keyspace
+
+import (
+	"iter"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	zskiplistMaxLevel = 32
+	zskiplistP        = 0.25
+)
+
+// ZMember is a sorted set member together with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZSet is a sorted set: unique members ordered by score, with ties broken
+// by comparing the members bytewise.
+//
+// As in Redis, it is a skiplist plus a hash map from member to score. The
+// map answers ZSCORE in O(1), and the skiplist keeps the order. Each
+// skiplist link records its span, the number of nodes it jumps over, so
+// rank lookups and rank ranges are O(log n) as well.
+type ZSet struct {
+	dict map[string]float64
+	zsl  *zskiplist
+}
+
+func NewZSet() *ZSet {
+	return &ZSet{
+		dict: make(map[string]float64),
+		zsl:  newZskiplist(),
+	}
+}
+
+func (z *ZSet) Len() int {
+	return len(z.dict)
+}
+
+func (z *ZSet) Score(member string) (float64, bool) {
+	score, exists := z.dict[member]
+	return score, exists
+}
+
+// Add sets the score of member, reporting whether it was newly added.
+func (z *ZSet) Add(member string, score float64) bool {
+	current, exists := z.dict[member]
+	if !exists {
+		z.zsl.insert(score, member)
+		z.dict[member] = score
+		return true
+	}
+	if current != score {
+		z.zsl.updateScore(current, member, score)
+		z.dict[member] = score
+	}
+	return false
+}
+
+// Remove deletes member, reporting whether it was present.
+func (z *ZSet) Remove(member string) bool {
+	score, exists := z.dict[member]
+	if !exists {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// Rank returns the 0-based position of member, counting from the highest
+// score when reverse is set.
+func (z *ZSet) Rank(member string, reverse bool) (int, bool) {
+	score, exists := z.dict[member]
+	if !exists {
+		return 0, false
+	}
+	rank := z.zsl.rank(score, member)
+	if reverse {
+		return z.Len() - rank, true
+	}
+	return rank - 1, true
+}
+
+// Range returns the members at positions start through stop inclusive,
+// which must already be in bounds. With reverse, positions count from the
+// highest score.
+func (z *ZSet) Range(start, stop int, reverse bool) []ZMember {
+	if start > stop || start >= z.Len() {
+		return []ZMember{}
+	}
+	var node *zskiplistNode
+	if reverse {
+		node = z.zsl.byRank(z.Len() - start)
+	} else {
+		node = z.zsl.byRank(start + 1)
+	}
+
+	result := make([]ZMember, 0, stop-start+1)
+	for ; node != nil && len(result) < stop-start+1; node = node.next(reverse) {
+		result = append(result, ZMember{Member: node.member, Score: node.score})
+	}
+	return result
+}
+
+// RangeByScore returns the members whose scores fall in r, skipping the
+// first offset of them and returning at most count; a negative count means
+// no limit. With reverse, the walk starts from the highest score.
+func (z *ZSet) RangeByScore(r ScoreRange, reverse bool, offset, count int) []ZMember {
+	return z.rangeGeneric(r, reverse, offset, count)
+}
+
+// RangeByLex is RangeByScore for a range of members, which is only
+// meaningful when every member has the same score.
+func (z *ZSet) RangeByLex(r LexRange, reverse bool, offset, count int) []ZMember {
+	return z.rangeGeneric(r, reverse, offset, count)
+}
+
+// CountByScore returns the number of members whose scores fall in r.
+func (z *ZSet) CountByScore(r ScoreRange) int {
+	return z.countGeneric(r)
+}
+
+// CountByLex returns the number of members that fall in r.
+func (z *ZSet) CountByLex(r LexRange) int {
+	return z.countGeneric(r)
+}
+
+// RemoveRangeByRank deletes the members at positions start through stop
+// inclusive and returns how many were removed.
+func (z *ZSet) RemoveRangeByRank(start, stop int) int {
+	return z.removeMembers(z.Range(start, stop, false))
+}
+
+func (z *ZSet) RemoveRangeByScore(r ScoreRange) int {
+	return z.removeMembers(z.RangeByScore(r, false, 0, -1))
+}
+
+func (z *ZSet) RemoveRangeByLex(r LexRange) int {
+	return z.removeMembers(z.RangeByLex(r, false, 0, -1))
+}
+
+// PopMin removes and returns up to count members with the lowest scores.
+func (z *ZSet) PopMin(count int) []ZMember {
+	popped := z.Range(0, count-1, false)
+	z.removeMembers(popped)
+	return popped
+}
+
+// PopMax removes and returns up to count members with the highest scores,
+// highest first.
+func (z *ZSet) PopMax(count int) []ZMember {
+	popped := z.Range(0, count-1, true)
+	z.removeMembers(popped)
+	return popped
+}
+
+// All iterates over the members in ascending order. The set must not be
+// modified while the iteration is in progress.
+func (z *ZSet) All() iter.Seq2[string, float64] {
+	return func(yield func(string, float64) bool) {
+		for node := z.zsl.header.level[0].forward; node != nil; node = node.level[0].forward {
+			if !yield(node.member, node.score) {
+				return
+			}
+		}
+	}
+}
+
+func (z *ZSet) clone() *ZSet {
+	c := NewZSet()
+	for member, score := range z.All() {
+		c.Add(member, score)
+	}
+	return c
+}
+
+func (z *ZSet) rangeGeneric(r zrange, reverse bool, offset, count int) []ZMember {
+	result := []ZMember{}
+	if offset < 0 {
+		return result
+	}
+
+	var node *zskiplistNode
+	if reverse {
+		node = z.zsl.lastInRange(r)
+	} else {
+		node = z.zsl.firstInRange(r)
+	}
+	for ; node != nil && offset > 0; offset-- {
+		node = node.next(reverse)
+	}
+
+	for ; node != nil && count != 0; node = node.next(reverse) {
+		if reverse && !r.aboveMin(node) || !reverse && !r.belowMax(node) {
+			break
+		}
+		result = append(result, ZMember{Member: node.member, Score: node.score})
+		count--
+	}
+	return result
+}
+
+// countGeneric finds the first and last members in range and subtracts
+// their ranks, which is O(log n) however many members match.
+func (z *ZSet) countGeneric(r zrange) int {
+	first := z.zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+func (z *ZSet) removeMembers(members []ZMember) int {
+	for _, member := range members {
+		z.Remove(member.Member)
+	}
+	return len(members)
+}
+
+// ScoreRange is an interval of scores for ZRANGE BYSCORE and friends.
+type ScoreRange struct {
+	Min, Max                   float64
+	MinExclusive, MaxExclusive bool
+}
+
+func (r ScoreRange) aboveMin(node *zskiplistNode) bool {
+	if r.MinExclusive {
+		return node.score > r.Min
+	}
+	return node.score >= r.Min
+}
+
+func (r ScoreRange) belowMax(node *zskiplistNode) bool {
+	if r.MaxExclusive {
+		return node.score < r.Max
+	}
+	return node.score <= r.Max
+}
+
+func (r ScoreRange) empty() bool {
+	return r.Min > r.Max || r.Min == r.Max && (r.MinExclusive || r.MaxExclusive)
+}
+
+// LexBound is one end of a LexRange.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	// Infinite is -1 for "-", which sorts before every member, and 1 for
+	// "+", which sorts after every member. Value is ignored when it is set.
+	Infinite int
+}
+
+// LexRange is an interval of members for ZRANGE BYLEX and friends.
+type LexRange struct {
+	Min, Max LexBound
+}
+
+// compareToBound compares member with b the way strings.Compare would.
+func compareToBound(member string, b LexBound) int {
+	if b.Infinite != 0 {
+		return -b.Infinite
+	}
+	return strings.Compare(member, b.Value)
+}
+
+func (r LexRange) aboveMin(node *zskiplistNode) bool {
+	c := compareToBound(node.member, r.Min)
+	if r.Min.Exclusive {
+		return c > 0
+	}
+	return c >= 0
+}
+
+func (r LexRange) belowMax(node *zskiplistNode) bool {
+	c := compareToBound(node.member, r.Max)
+	if r.Max.Exclusive {
+		return c < 0
+	}
+	return c <= 0
+}
+
+func (r LexRange) empty() bool {
+	if r.Min.Infinite == 1 || r.Max.Infinite == -1 {
+		return true
+	}
+	if r.Min.Infinite == -1 || r.Max.Infinite == 1 {
+		return false
+	}
+	c := strings.Compare(r.Min.Value, r.Max.Value)
+	return c > 0 || c == 0 && (r.Min.Exclusive || r.Max.Exclusive)
+}
+
+// zrange is the interval interface shared by ScoreRange and LexRange.
+type zrange interface {
+	aboveMin(node *zskiplistNode) bool
+	belowMax(node *zskiplistNode) bool
+	empty() bool
+}
+
+type zskiplistLevel struct {
+	forward *zskiplistNode
+	span    int
+}
+
+type zskiplistNode struct {
+	member   string
+	score    float64
+	backward *zskiplistNode
+	level    []zskiplistLevel
+}
+
+func (n *zskiplistNode) next(reverse bool) *zskiplistNode {
+	if reverse {
+		return n.backward
+	}
+	return n.level[0].forward
+}
+
+// before reports whether n sorts before the element (score, member).
+func (n *zskiplistNode) before(score float64, member string) bool {
+	return n.score < score || n.score == score && n.member < member
+}
+
+// zskiplist follows the Redis t_zset.c skiplist. The header is a sentinel
+// with every level; ranks are 1-based, with the header at rank 0.
+type zskiplist struct {
+	header *zskiplistNode
+	tail   *zskiplistNode
+	length int
+	level  int
+}
+
+func newZskiplist() *zskiplist {
+	return &zskiplist{
+		header: &zskiplistNode{level: make([]zskiplistLevel, zskiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+// randomLevel returns a level between 1 and zskiplistMaxLevel where each
+// extra level is zskiplistP as likely as the one below it.
+func randomLevel() int {
+	level := 1
+	for level < zskiplistMaxLevel && rand.Float64() < zskiplistP {
+		level++
+	}
+	return level
+}
+
+// insert adds a new node. The caller guarantees member is not present.
+func (zsl *zskiplist) insert(score float64, member string) *zskiplistNode {
+	var update [zskiplistMaxLevel]*zskiplistNode
+	var rank [zskiplistMaxLevel]int
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+
+	x = &zskiplistNode{
+		member: member,
+		score:  score,
+		level:  make([]zskiplistLevel, level),
+	}
+	for i := range level {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// levels above the new node now jump over one more node
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+// find returns the node for (score, member), or nil, along with the last
+// node before it on every level.
+func (zsl *zskiplist) find(score float64, member string) (*zskiplistNode, [zskiplistMaxLevel]*zskiplistNode) {
+	var update [zskiplistMaxLevel]*zskiplistNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x != nil && x.score == score && x.member == member {
+		return x, update
+	}
+	return nil, update
+}
+
+func (zsl *zskiplist) deleteNode(x *zskiplistNode, update *[zskiplistMaxLevel]*zskiplistNode) {
+	for i := range zsl.level {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+func (zsl *zskiplist) delete(score float64, member string) bool {
+	x, update := zsl.find(score, member)
+	if x == nil {
+		return false
+	}
+	zsl.deleteNode(x, &update)
+	return true
+}
+
+// updateScore moves member from oldScore to newScore. When the node keeps
+// its position it is updated in place instead of being reinserted.
+func (zsl *zskiplist) updateScore(oldScore float64, member string, newScore float64) {
+	x, update := zsl.find(oldScore, member)
+	if x == nil {
+		return
+	}
+
+	next := x.level[0].forward
+	if (x.backward == nil || x.backward.before(newScore, member)) &&
+		(next == nil || !next.before(newScore, member)) {
+		x.score = newScore
+		return
+	}
+	zsl.deleteNode(x, &update)
+	zsl.insert(newScore, member)
+}
+
+// rank returns the 1-based rank of (score, member), or 0 if it is missing.
+func (zsl *zskiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.before(score, member) || x.level[i].forward.score == score && x.level[i].forward.member == member) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node at a 1-based rank, or nil if it is out of range.
+func (zsl *zskiplist) byRank(rank int) *zskiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank && x != zsl.header {
+			return x
+		}
+	}
+	return nil
+}
+
+func (zsl *zskiplist) firstInRange(r zrange) *zskiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *zskiplist) lastInRange(r zrange) *zskiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.aboveMin(x) {
+		return nil
+	}
+	return x
+}
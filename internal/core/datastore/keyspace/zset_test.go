@@ -0,0 +1,160 @@
+package keyspace
+
+import (
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sortedModel is the reference ordering a ZSet must agree with.
+func sortedModel(model map[string]float64) []ZMember {
+	members := make([]ZMember, 0, len(model))
+	for member, score := range model {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+	return members
+}
+
+// checkSpans verifies that the span of every link matches the number of
+// nodes it skips.
+func checkSpans(t *testing.T, zsl *zskiplist) {
+	t.Helper()
+	positions := map[*zskiplistNode]int{zsl.header: 0}
+	rank := 0
+	for node := zsl.header.level[0].forward; node != nil; node = node.level[0].forward {
+		rank++
+		positions[node] = rank
+	}
+	require.Equal(t, zsl.length, rank)
+
+	for node := range positions {
+		for i := 0; i < len(node.level) && i < zsl.level; i++ {
+			link := node.level[i]
+			if link.forward == nil {
+				continue
+			}
+			require.Equal(t, positions[link.forward]-positions[node], link.span, "level %d", i)
+		}
+	}
+}
+
+func TestZSetMatchesModel(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	zset := NewZSet()
+	model := map[string]float64{}
+
+	for range 5000 {
+		member := "m" + strconv.Itoa(rng.IntN(300))
+		if rng.IntN(4) == 0 {
+			_, exists := model[member]
+			assert.Equal(t, exists, zset.Remove(member))
+			delete(model, member)
+			continue
+		}
+		score := float64(rng.IntN(50))
+		_, exists := model[member]
+		assert.Equal(t, !exists, zset.Add(member, score))
+		model[member] = score
+	}
+
+	checkSpans(t, zset.zsl)
+	expected := sortedModel(model)
+	require.Equal(t, expected, zset.Range(0, zset.Len()-1, false))
+	for i, member := range expected {
+		rank, ok := zset.Rank(member.Member, false)
+		assert.True(t, ok)
+		assert.Equal(t, i, rank)
+		rank, _ = zset.Rank(member.Member, true)
+		assert.Equal(t, len(expected)-1-i, rank)
+	}
+
+	r := ScoreRange{Min: 10, Max: 20, MinExclusive: true}
+	var inRange []ZMember
+	for _, member := range expected {
+		if member.Score > 10 && member.Score <= 20 {
+			inRange = append(inRange, member)
+		}
+	}
+	assert.Equal(t, inRange, zset.RangeByScore(r, false, 0, -1))
+	assert.Equal(t, len(inRange), zset.CountByScore(r))
+	assert.Equal(t, inRange[3:8], zset.RangeByScore(r, false, 3, 5))
+	reversed := zset.RangeByScore(r, true, 0, 2)
+	assert.Equal(t, []ZMember{inRange[len(inRange)-1], inRange[len(inRange)-2]}, reversed)
+}
+
+func TestZSetUpdateScoreKeepsOrder(t *testing.T) {
+	zset := NewZSet()
+	zset.Add("a", 1)
+	zset.Add("b", 2)
+	zset.Add("c", 3)
+
+	// stays in place
+	assert.False(t, zset.Add("b", 2.5))
+	// moves to the front
+	assert.False(t, zset.Add("c", 0))
+
+	checkSpans(t, zset.zsl)
+	assert.Equal(t, []ZMember{{"c", 0}, {"a", 1}, {"b", 2.5}}, zset.Range(0, 2, false))
+	score, ok := zset.Score("c")
+	assert.True(t, ok)
+	assert.Equal(t, float64(0), score)
+}
+
+func TestZSetRangeByLex(t *testing.T) {
+	zset := NewZSet()
+	for _, member := range []string{"a", "b", "c", "d", "e"} {
+		zset.Add(member, 0)
+	}
+	members := func(zm []ZMember) []string {
+		result := []string{}
+		for _, m := range zm {
+			result = append(result, m.Member)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		r        LexRange
+		expected []string
+	}{
+		{name: "everything", r: LexRange{Min: LexBound{Infinite: -1}, Max: LexBound{Infinite: 1}}, expected: []string{"a", "b", "c", "d", "e"}},
+		{name: "inclusive", r: LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Value: "d"}}, expected: []string{"b", "c", "d"}},
+		{name: "exclusive", r: LexRange{Min: LexBound{Value: "b", Exclusive: true}, Max: LexBound{Value: "d", Exclusive: true}}, expected: []string{"c"}},
+		{name: "open start", r: LexRange{Min: LexBound{Infinite: -1}, Max: LexBound{Value: "bb"}}, expected: []string{"a", "b"}},
+		{name: "inverted", r: LexRange{Min: LexBound{Value: "d"}, Max: LexBound{Value: "b"}}, expected: []string{}},
+		{name: "plus as min", r: LexRange{Min: LexBound{Infinite: 1}, Max: LexBound{Infinite: 1}}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, members(zset.RangeByLex(tt.r, false, 0, -1)))
+			assert.Equal(t, len(tt.expected), zset.CountByLex(tt.r))
+		})
+	}
+}
+
+func TestZSetPopAndRemoveRanges(t *testing.T) {
+	zset := NewZSet()
+	for i := range 10 {
+		zset.Add(strconv.Itoa(i), float64(i))
+	}
+
+	assert.Equal(t, []ZMember{{"0", 0}, {"1", 1}}, zset.PopMin(2))
+	assert.Equal(t, []ZMember{{"9", 9}}, zset.PopMax(1))
+	assert.Equal(t, 2, zset.RemoveRangeByRank(0, 1))
+	assert.Equal(t, 2, zset.RemoveRangeByScore(ScoreRange{Min: 7, Max: 100}))
+	assert.Equal(t, []ZMember{{"4", 4}, {"5", 5}, {"6", 6}}, zset.PopMin(10))
+	assert.Equal(t, 0, zset.Len())
+	checkSpans(t, zset.zsl)
+}
@@ -0,0 +1,201 @@
+package datastore
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// The events the latency monitor tracks: the executor stalling on a
+// command, on the active expiry cycle, on writing or syncing the
+// append-only file, or on copying the keyspace for BGSAVE or BGREWRITEAOF.
+const (
+	latencyCommand        = "command"
+	latencyExpireCycle    = "expire-cycle"
+	latencyAOFWrite       = "aof-write"
+	latencyAOFFsyncAlways = "aof-fsync-always"
+	latencySnapshot       = "snapshot"
+)
+
+// latencyHistoryLen is how many samples an event keeps, as LATENCY_TS_LEN
+// does in Redis.
+const latencyHistoryLen = 160
+
+// latencySample is the worst spike of an event in a second.
+type latencySample struct {
+	// time is in seconds since the epoch, and latency in milliseconds
+	time, latency int64
+}
+
+// latencySeries holds the latest samples of an event in a ring, and its
+// worst spike ever.
+type latencySeries struct {
+	samples [latencyHistoryLen]latencySample
+	// next is where the next sample goes; samples not yet written have a
+	// time of 0
+	next int
+	max  int64
+}
+
+// history returns the samples, the oldest first.
+func (s *latencySeries) history() []latencySample {
+	var history []latencySample
+	for i := range latencyHistoryLen {
+		if sample := s.samples[(s.next+i)%latencyHistoryLen]; sample.time != 0 {
+			history = append(history, sample)
+		}
+	}
+	return history
+}
+
+func (s *latencySeries) latest() latencySample {
+	return s.samples[(s.next+latencyHistoryLen-1)%latencyHistoryLen]
+}
+
+// latencyState is the latency monitor, which records the events that
+// take at least threshold.
+type latencyState struct {
+	// threshold is 0 when the monitor is off
+	threshold time.Duration
+	events    map[string]*latencySeries
+}
+
+func newLatencyState() latencyState {
+	return latencyState{events: make(map[string]*latencySeries)}
+}
+
+// add records that event took latency, if that is long enough to count as
+// a spike.
+func (l *latencyState) add(event string, latency time.Duration) {
+	if l.threshold == 0 || latency < l.threshold {
+		return
+	}
+	l.record(event, time.Now().Unix(), latency.Milliseconds())
+}
+
+// record adds a sample of event, keeping only the worst of those in the
+// same second, as Redis's latencyAddSample does.
+func (l *latencyState) record(event string, now, latency int64) {
+	series := l.events[event]
+	if series == nil {
+		series = &latencySeries{}
+		l.events[event] = series
+	}
+	series.max = max(series.max, latency)
+	if previous := &series.samples[(series.next+latencyHistoryLen-1)%latencyHistoryLen]; previous.time == now {
+		previous.latency = max(previous.latency, latency)
+		return
+	}
+	series.samples[series.next] = latencySample{time: now, latency: latency}
+	series.next = (series.next + 1) % latencyHistoryLen
+}
+
+// timeEvent records event as having taken from start until now.
+func (e *Executor) timeEvent(event string, start time.Time) {
+	e.latency.add(event, time.Since(start))
+}
+
+// handleLatency implements LATENCY LATEST, LATENCY HISTORY event, LATENCY
+// RESET [event ...] and LATENCY DOCTOR.
+func (e *Executor) handleLatency(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+	events := slices.Sorted(maps.Keys(e.latency.events))
+	switch subcommand := strings.ToUpper(args[0]); {
+	case subcommand == "LATEST" && len(args) == 1:
+		elements := make([]*common.RespValue, len(events))
+		for i, event := range events {
+			series := e.latency.events[event]
+			latest := series.latest()
+			elements[i] = &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+				bulkElement(event),
+				{Type: enums.IntRespType, Int: latest.time},
+				{Type: enums.IntRespType, Int: latest.latency},
+				{Type: enums.IntRespType, Int: series.max},
+			}}
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: elements}
+	case subcommand == "HISTORY" && len(args) == 2:
+		var elements []*common.RespValue
+		if series := e.latency.events[strings.ToLower(args[1])]; series != nil {
+			for _, sample := range series.history() {
+				elements = append(elements, &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+					{Type: enums.IntRespType, Int: sample.time},
+					{Type: enums.IntRespType, Int: sample.latency},
+				}})
+			}
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: elements}
+	case subcommand == "RESET":
+		reset := 0
+		if len(args) == 1 {
+			reset = len(e.latency.events)
+			clear(e.latency.events)
+		}
+		for _, event := range args[1:] {
+			if _, exists := e.latency.events[strings.ToLower(event)]; exists {
+				delete(e.latency.events, strings.ToLower(event))
+				reset++
+			}
+		}
+		return common.RespValue{Type: enums.IntRespType, Int: int64(reset)}
+	case subcommand == "DOCTOR" && len(args) == 1:
+		return common.RespValue{Type: enums.BulkStringRespType, Str: e.latencyReport(events)}
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try LATENCY HELP.", args[0]))
+	}
+}
+
+// latencyAdvice is what LATENCY DOCTOR suggests for each event.
+var latencyAdvice = map[string]string{
+	latencyCommand:        "Check SLOWLOG GET for the commands that were slow. Commands over many elements, such as KEYS, or SMEMBERS and LRANGE of large collections, run in one go while every other client waits.",
+	latencyExpireCycle:    "Many keys expired at about the same time. Spreading their TTLs out, with some randomness, spreads the work of deleting them.",
+	latencyAOFWrite:       "Writing the append-only file stalled. Check that the disk is not overloaded, or shared with a busy process.",
+	latencyAOFFsyncAlways: "With appendfsync always every write waits for the disk. appendfsync everysec syncs in the background, risking a second of writes.",
+	latencySnapshot:       "Copying the keyspace for BGSAVE or BGREWRITEAOF is spread over the cron, but takes longer the more keys there are, and the first write to a large collection while a save is in progress copies the whole collection. Consider saving less often, or only on a replica.",
+}
+
+// latencyReport describes the spikes of each event, for LATENCY DOCTOR.
+func (e *Executor) latencyReport(events []string) string {
+	if len(events) == 0 {
+		if e.latency.threshold == 0 {
+			return "Latency monitoring is disabled. Enable it with CONFIG SET latency-monitor-threshold <milliseconds>.\n"
+		}
+		return "No latency spike has been observed.\n"
+	}
+	var b strings.Builder
+	b.WriteString("Latency spikes were observed:\n\n")
+	for i, event := range events {
+		series := e.latency.events[event]
+		history := series.history()
+		var sum int64
+		for _, sample := range history {
+			sum += sample.latency
+		}
+		average := float64(sum) / float64(len(history))
+		var deviation float64
+		for _, sample := range history {
+			deviation += math.Abs(float64(sample.latency) - average)
+		}
+		deviation /= float64(len(history))
+		period := float64(history[len(history)-1].time-history[0].time) / float64(len(history))
+		fmt.Fprintf(&b, "%d. %s: %d latency spikes (average %.0fms, mean deviation %.0fms, period %.2f sec). Worst all time event %dms.\n",
+			i+1, event, len(history), average, deviation, period, series.max)
+	}
+	b.WriteString("\nAdvice:\n\n")
+	for _, event := range events {
+		if advice, exists := latencyAdvice[event]; exists {
+			fmt.Fprintf(&b, "- %s: %s\n", event, advice)
+		}
+	}
+	return b.String()
+}
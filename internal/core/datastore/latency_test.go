@@ -0,0 +1,100 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+)
+
+func latencyPairs(reply common.RespValue) [][]int64 {
+	var pairs [][]int64
+	for _, element := range reply.Array {
+		pairs = append(pairs, []int64{element.Array[0].Int, element.Array[1].Int})
+	}
+	return pairs
+}
+
+func TestLatencyRecord(t *testing.T) {
+	l := newLatencyState()
+	l.add(latencyCommand, time.Second)
+	assert.Empty(t, l.events, "the monitor is off")
+
+	l.threshold = 2 * time.Millisecond
+	l.add(latencyCommand, time.Millisecond)
+	assert.Empty(t, l.events, "below the threshold")
+	l.add(latencyCommand, 3*time.Millisecond)
+	assert.Len(t, l.events[latencyCommand].history(), 1)
+
+	l = newLatencyState()
+	l.record(latencyCommand, 100, 5)
+	l.record(latencyCommand, 100, 9)
+	l.record(latencyCommand, 100, 7)
+	l.record(latencyCommand, 101, 3)
+	series := l.events[latencyCommand]
+	assert.Equal(t, []latencySample{{100, 9}, {101, 3}}, series.history(), "one sample a second, the worst")
+	assert.Equal(t, latencySample{101, 3}, series.latest())
+	assert.Equal(t, int64(9), series.max)
+
+	for i := range latencyHistoryLen {
+		l.record(latencyCommand, int64(200+i), 1)
+	}
+	history := series.history()
+	assert.Len(t, history, latencyHistoryLen)
+	assert.Equal(t, int64(200), history[0].time)
+	assert.Equal(t, int64(9), series.max, "the worst outlives its sample")
+}
+
+func TestLatency(t *testing.T) {
+	exec, _ := configExecutor(t)
+	assert.Contains(t, exec.Execute(makeCommand("LATENCY", "DOCTOR")).Str, "disabled")
+	exec.Execute(makeCommand("CONFIG", "SET", "latency-monitor-threshold", "100"))
+	assert.Equal(t, 100*time.Millisecond, exec.latency.threshold)
+	assert.Contains(t, exec.Execute(makeCommand("LATENCY", "DOCTOR")).Str, "No latency spike")
+
+	exec.latency.record(latencyExpireCycle, 1000, 150)
+	exec.latency.record(latencyCommand, 1000, 300)
+	exec.latency.record(latencyCommand, 1010, 200)
+
+	latest := exec.Execute(makeCommand("LATENCY", "LATEST"))
+	assert.Len(t, latest.Array, 2)
+	for i, expected := range []struct {
+		event   string
+		numbers []int64
+	}{
+		{"command", []int64{1010, 200, 300}},
+		{"expire-cycle", []int64{1000, 150, 150}},
+	} {
+		entry := latest.Array[i].Array
+		assert.Equal(t, expected.event, entry[0].Str)
+		assert.Equal(t, expected.numbers, []int64{entry[1].Int, entry[2].Int, entry[3].Int})
+	}
+
+	history := exec.Execute(makeCommand("LATENCY", "HISTORY", "command"))
+	assert.Equal(t, [][]int64{{1000, 300}, {1010, 200}}, latencyPairs(history))
+	assert.Empty(t, exec.Execute(makeCommand("LATENCY", "HISTORY", "nope")).Array)
+
+	doctor := exec.Execute(makeCommand("LATENCY", "DOCTOR")).Str
+	assert.Contains(t, doctor, "1. command: 2 latency spikes (average 250ms, mean deviation 50ms, period 5.00 sec). Worst all time event 300ms.")
+	assert.Contains(t, doctor, "2. expire-cycle: 1 latency spikes")
+	assert.Contains(t, doctor, "- expire-cycle: ")
+
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("LATENCY", "RESET", "command", "nope")).Int)
+	assert.Equal(t, int64(1), exec.Execute(makeCommand("LATENCY", "RESET")).Int)
+	assert.Empty(t, exec.Execute(makeCommand("LATENCY", "LATEST")).Array)
+}
+
+func TestLatencyEvents(t *testing.T) {
+	exec := NewExecutor()
+	// every event takes at least a nanosecond
+	exec.latency.threshold = time.Nanosecond
+	exec.SetRDB(t.TempDir()+"/dump.rdb", nil)
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Execute(makeCommand("BGSAVE"))
+	finishBgsave(t, exec)
+	for _, event := range []string{latencyCommand, latencySnapshot, latencyExpireCycle} {
+		assert.Contains(t, exec.latency.events, event)
+	}
+}
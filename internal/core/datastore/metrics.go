@@ -0,0 +1,91 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/metrics"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// commandBuckets are the upper bounds, in seconds, of the buckets command
+// latencies are counted in: from 10µs, about what a GET costs, up to a
+// second.
+var commandBuckets = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.1, 1,
+}
+
+// commandMetrics are the metrics the executor records for every command
+// it runs.
+type commandMetrics struct {
+	calls    *metrics.CounterVec
+	duration *metrics.HistogramVec
+	// byName caches each command's metrics, so recording them takes no
+	// lock. Only the executor goroutine touches it.
+	byName map[enums.CommandName]*commandStats
+}
+
+type commandStats struct {
+	ok, failed *metrics.Counter
+	duration   *metrics.Histogram
+}
+
+// SetMetrics registers the executor's metrics on r and has it record the
+// count and latency of every command from then on. Call it before the
+// executor goroutine starts, and after loading any data, so what was
+// loaded does not count as commands.
+func (e *Executor) SetMetrics(r *metrics.Registry) {
+	e.metrics = &commandMetrics{
+		calls: r.CounterVec("mnemo_commands_total",
+			"Commands processed, by command and by whether they replied with an error.", "command", "result"),
+		duration: r.HistogramVec("mnemo_command_duration_seconds",
+			"Time spent running commands on the executor, by command.", commandBuckets, "command"),
+		byName: make(map[enums.CommandName]*commandStats),
+	}
+	r.GaugeFunc("mnemo_connected_clients", "Clients connected.", func() float64 {
+		return float64(e.connections.Connected.Load())
+	})
+	r.CounterFunc("mnemo_connections_received_total", "Connections accepted.", func() float64 {
+		return float64(e.connections.Received.Load())
+	})
+	r.CounterFunc("mnemo_connections_rejected_total", "Connections refused because maxclients were connected.", func() float64 {
+		return float64(e.connections.Rejected.Load())
+	})
+	r.GaugeFunc("mnemo_executor_queue_depth", "Requests waiting for the executor.", func() float64 {
+		return float64(len(e.ExecutorChan))
+	})
+	r.GaugeFunc("mnemo_executor_queue_capacity", "Requests that may wait for the executor before clients block.", func() float64 {
+		return float64(cap(e.ExecutorChan))
+	})
+}
+
+// observe records a command that took duration and replied with reply.
+// It does nothing if the executor has no metrics.
+func (m *commandMetrics) observe(name string, reply common.RespValue, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	command := enums.StringToCommandName(name)
+	stats := m.byName[command]
+	if stats == nil {
+		// unknown names share one label, so clients cannot make up new
+		// series
+		label := string(command)
+		if command == "" {
+			label = "unknown"
+		}
+		stats = &commandStats{
+			ok:       m.calls.WithLabelValues(label, "ok"),
+			failed:   m.calls.WithLabelValues(label, "error"),
+			duration: m.duration.WithLabelValues(label),
+		}
+		m.byName[command] = stats
+	}
+	if reply.Type == enums.ErrorRespType {
+		stats.failed.Inc()
+	} else {
+		stats.ok.Inc()
+	}
+	stats.duration.Observe(duration.Seconds())
+}
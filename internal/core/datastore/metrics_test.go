@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "before", "1"))
+	registry := metrics.NewRegistry()
+	exec.SetMetrics(registry)
+	exec.Connections().Connected.Store(3)
+	client := newTestClient()
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "GET", "a")
+	client.do(exec, "GET", "a", "b")
+	client.do(exec, "NOPE")
+	client.do(exec, "MULTI")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "EXEC")
+	client.do(exec, "EXEC")
+	// BLPOP with data goes the blocking handlers' way without blocking
+	client.do(exec, "RPUSH", "list", "x")
+	client.do(exec, "BLPOP", "list", "0")
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+	text := b.String()
+	for _, line := range []string{
+		`mnemo_commands_total{command="set",result="ok"} 1`,
+		`mnemo_commands_total{command="get",result="ok"} 1`,
+		`mnemo_commands_total{command="get",result="error"} 1`,
+		`mnemo_commands_total{command="unknown",result="error"} 1`,
+		`mnemo_commands_total{command="incr",result="ok"} 1`,
+		`mnemo_commands_total{command="blpop",result="ok"} 1`,
+		`mnemo_commands_total{command="multi",result="ok"} 1`,
+		`mnemo_commands_total{command="exec",result="ok"} 1`,
+		`mnemo_commands_total{command="exec",result="error"} 1`,
+		`mnemo_command_duration_seconds_count{command="exec"} 2`,
+		`mnemo_command_duration_seconds_count{command="get"} 2`,
+		`mnemo_command_duration_seconds_bucket{command="set",le="+Inf"} 1`,
+		`mnemo_connected_clients 3`,
+		`mnemo_executor_queue_depth 0`,
+		`mnemo_executor_queue_capacity 1024`,
+	} {
+		assert.Contains(t, text, line+"\n")
+	}
+}
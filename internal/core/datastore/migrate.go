@@ -0,0 +1,368 @@
+package datastore
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+const (
+	// migrateConnIdle is how long MIGRATE keeps an unused connection to a
+	// target open, as Redis does, so that moving a slot a batch of keys at
+	// a time connects only once.
+	migrateConnIdle = 10 * time.Second
+	// migrateMaxConns bounds the connections kept open; the least
+	// recently used goes when another is needed.
+	migrateMaxConns = 64
+)
+
+// migrateState holds the connections MIGRATE keeps open, by target
+// address.
+type migrateState struct {
+	conns map[string]*migrateConn
+}
+
+type migrateConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	lastUsed time.Time
+}
+
+// handleDump implements DUMP, which serializes a key's value in the
+// format RESTORE takes.
+func (e *Executor) handleDump(command commands.Command) common.RespValue {
+	if !commands.CheckArity(command) {
+		return wrongArity(command.Name)
+	}
+	object := e.dataStore.LookupRead(command.Args[0])
+	if object == nil {
+		return common.RespValue{Type: enums.BulkStringRespType, IsNull: true}
+	}
+	return common.RespValue{Type: enums.BulkStringRespType, Str: string(rdb.Dump(object))}
+}
+
+// handleRestore implements RESTORE, which creates a key from a DUMP
+// payload, and RESTORE-ASKING, the form MIGRATE sends in cluster mode,
+// which may use a slot being imported.
+func (e *Executor) handleRestore(command commands.Command) common.RespValue {
+	if !commands.CheckArity(command) {
+		return wrongArity(command.Name)
+	}
+	return e.call(command, func() common.RespValue {
+		return e.restore(command.Args)
+	})
+}
+
+func (e *Executor) restore(args []string) common.RespValue {
+	key, payload := args[0], args[2]
+	ttl, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return errorValue("ERR value is not an integer or out of range")
+	}
+	replace, absTTL := false, false
+	for i := 3; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "REPLACE":
+			replace = true
+		case option == "ABSTTL":
+			absTTL = true
+		case (option == "IDLETIME" || option == "FREQ") && i+1 < len(args):
+			// there is no eviction to keep access statistics for, but
+			// the values must still make sense
+			i++
+			value, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return errorValue("ERR value is not an integer or out of range")
+			}
+			if option == "IDLETIME" && value < 0 {
+				return errorValue("ERR Invalid IDLETIME value, must be >= 0")
+			}
+			if option == "FREQ" && (value < 0 || value > 255) {
+				return errorValue("ERR Invalid FREQ value, must be >= 0 and <= 255")
+			}
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+	if ttl < 0 {
+		return errorValue("ERR Invalid TTL value, must be >= 0")
+	}
+	if !replace && e.dataStore.Exists(key) {
+		return errorValue("BUSYKEY Target key name already exists.")
+	}
+	object, err := rdb.Restore([]byte(payload))
+	if errors.Is(err, rdb.ErrDumpPayload) {
+		return errorValue("ERR DUMP payload version or checksum are wrong")
+	}
+	if err != nil {
+		return errorValue("ERR Bad data format")
+	}
+
+	expireAt := ttl
+	if ttl > 0 && !absTTL {
+		expireAt += e.dataStore.Now()
+	}
+	if ttl > 0 && expireAt < e.dataStore.Now() {
+		// already expired: all that is left to do is what REPLACE
+		// would have done to the old value
+		e.dataStore.Delete(key)
+		return okValue()
+	}
+	e.dataStore.SetObject(key, object)
+	if ttl > 0 {
+		e.dataStore.SetExpireAt(key, expireAt)
+	}
+	return okValue()
+}
+
+// migrateOptions are MIGRATE's arguments.
+type migrateOptions struct {
+	addr    string
+	keys    []string
+	timeout time.Duration
+	copy    bool
+	replace bool
+	// auth is the AUTH command to send first, if any
+	auth []string
+}
+
+// handleMigrate implements MIGRATE, which moves keys to another server:
+// it sends each key's DUMP in a RESTORE and, once the target has
+// restored it, deletes it here unless COPY is given. As in Redis the
+// executor waits for the target, up to the timeout, so no command runs
+// between a key being dumped and being deleted.
+func (e *Executor) handleMigrate(command commands.Command) common.RespValue {
+	if !commands.CheckArity(command) {
+		return wrongArity(command.Name)
+	}
+	opts, errReply := parseMigrate(command.Args)
+	if errReply != nil {
+		return *errReply
+	}
+	var keys []string
+	for _, key := range opts.keys {
+		if e.dataStore.Exists(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "NOKEY"}
+	}
+
+	restore := string(enums.RestoreCommandName)
+	if e.cluster.node != nil {
+		restore = string(enums.RestoreAskingCommandName)
+	}
+	var request []byte
+	replies := 0
+	if opts.auth != nil {
+		request = append(request, parser.EncodeCommand(commands.Command{Name: opts.auth[0], Args: opts.auth[1:]})...)
+		replies++
+	}
+	sent := make([]string, 0, len(keys))
+	now := e.dataStore.Now()
+	for _, key := range keys {
+		ttl := int64(0)
+		if when, exists := e.dataStore.ExpireAt(key); exists {
+			ttl = max(when-now, 1)
+		}
+		args := []string{key, strconv.FormatInt(ttl, 10), string(rdb.Dump(e.dataStore.LookupRead(key)))}
+		if opts.replace {
+			args = append(args, "REPLACE")
+		}
+		request = append(request, parser.EncodeCommand(commands.Command{Name: restore, Args: args})...)
+		sent = append(sent, key)
+		replies++
+	}
+
+	lines, errReply := e.migrateExchange(opts, request, replies)
+	if errReply != nil {
+		return *errReply
+	}
+	var targetError string
+	if opts.auth != nil {
+		if strings.HasPrefix(lines[0], "-") {
+			targetError = lines[0][1:]
+		}
+		lines = lines[1:]
+	}
+	for i, key := range sent {
+		if strings.HasPrefix(lines[i], "-") {
+			if targetError == "" {
+				targetError = lines[i][1:]
+			}
+			continue
+		}
+		// a key the target restored is deleted even if another failed,
+		// so that no key is left on both
+		if !opts.copy {
+			// deleted as a DEL of its own, which is how the move reaches
+			// the append-only file and the replicas
+			e.execute(commands.Command{Name: string(enums.DeleteCommandName), Args: []string{key}})
+		}
+	}
+	if targetError != "" {
+		return errorValue("ERR Target instance replied with error: " + targetError)
+	}
+	return okValue()
+}
+
+func parseMigrate(args []string) (migrateOptions, *common.RespValue) {
+	fail := func(message string) (migrateOptions, *common.RespValue) {
+		reply := errorValue(message)
+		return migrateOptions{}, &reply
+	}
+	port, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fail("ERR value is not an integer or out of range")
+	}
+	db, err1 := strconv.Atoi(args[3])
+	timeout, err2 := strconv.Atoi(args[4])
+	if err1 != nil || err2 != nil {
+		return fail("ERR value is not an integer or out of range")
+	}
+	if db != 0 {
+		return fail("ERR DB index is out of range")
+	}
+	if timeout <= 0 {
+		timeout = 1000
+	}
+	opts := migrateOptions{
+		addr:    net.JoinHostPort(args[0], strconv.Itoa(port)),
+		timeout: time.Duration(timeout) * time.Millisecond,
+	}
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COPY":
+			opts.copy = true
+		case "REPLACE":
+			opts.replace = true
+		case "AUTH":
+			if i+1 >= len(args) {
+				return fail("ERR syntax error")
+			}
+			opts.auth = []string{"AUTH", args[i+1]}
+			i++
+		case "AUTH2":
+			if i+2 >= len(args) {
+				return fail("ERR syntax error")
+			}
+			opts.auth = []string{"AUTH", args[i+1], args[i+2]}
+			i += 2
+		case "KEYS":
+			if args[2] != "" {
+				return fail("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+			}
+			i = len(args)
+		default:
+			return fail("ERR syntax error")
+		}
+	}
+	opts.keys = commands.MigrateKeys(args)
+	return opts, nil
+}
+
+// migrateExchange writes request to the target and reads the given
+// number of one-line replies. A cached connection the target has since
+// closed is noticed on first use, and the request is sent again on a
+// new one.
+func (e *Executor) migrateExchange(opts migrateOptions, request []byte, replies int) ([]string, *common.RespValue) {
+	for attempt := 0; ; attempt++ {
+		mc, cached, err := e.migrateConn(opts.addr, opts.timeout)
+		if err != nil {
+			reply := errorValue("IOERR error or timeout connecting to the client")
+			return nil, &reply
+		}
+		mc.conn.SetDeadline(time.Now().Add(opts.timeout))
+		if _, err := mc.conn.Write(request); err != nil {
+			e.closeMigrateConn(opts.addr)
+			if cached && attempt == 0 {
+				continue
+			}
+			reply := errorValue("IOERR error or timeout writing to target instance")
+			return nil, &reply
+		}
+		lines := make([]string, 0, replies)
+		for len(lines) < replies {
+			line, err := mc.reader.ReadString('\n')
+			if err != nil {
+				e.closeMigrateConn(opts.addr)
+				if cached && attempt == 0 && len(lines) == 0 && !isTimeout(err) {
+					break
+				}
+				reply := errorValue("IOERR error or timeout reading to target instance")
+				return nil, &reply
+			}
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+		if len(lines) < replies {
+			continue
+		}
+		mc.lastUsed = time.Now()
+		return lines, nil
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// migrateConn returns the connection to addr, opening one if none is
+// kept, and reports whether it was kept from an earlier MIGRATE.
+func (e *Executor) migrateConn(addr string, timeout time.Duration) (*migrateConn, bool, error) {
+	if mc := e.migrate.conns[addr]; mc != nil {
+		return mc, true, nil
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.migrate.conns == nil {
+		e.migrate.conns = make(map[string]*migrateConn)
+	}
+	if len(e.migrate.conns) >= migrateMaxConns {
+		var oldest string
+		for addr, mc := range e.migrate.conns {
+			if oldest == "" || mc.lastUsed.Before(e.migrate.conns[oldest].lastUsed) {
+				oldest = addr
+			}
+		}
+		e.closeMigrateConn(oldest)
+	}
+	mc := &migrateConn{conn: conn, reader: bufio.NewReader(conn), lastUsed: time.Now()}
+	e.migrate.conns[addr] = mc
+	return mc, false, nil
+}
+
+func (e *Executor) closeMigrateConn(addr string) {
+	if mc := e.migrate.conns[addr]; mc != nil {
+		mc.conn.Close()
+		delete(e.migrate.conns, addr)
+	}
+}
+
+// cronMigrate closes the connections MIGRATE has not used for a while.
+func (e *Executor) cronMigrate() {
+	for addr, mc := range e.migrate.conns {
+		if time.Since(mc.lastUsed) > migrateConnIdle {
+			e.closeMigrateConn(addr)
+		}
+	}
+}
+
+// closeMigrate closes every connection MIGRATE keeps.
+func (e *Executor) closeMigrate() {
+	for addr := range e.migrate.conns {
+		e.closeMigrateConn(addr)
+	}
+}
@@ -0,0 +1,251 @@
+package datastore
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// migrateTarget serves an executor over TCP for MIGRATE to connect to.
+// The test goroutine must go through do to touch the executor.
+type migrateTarget struct {
+	exec     *Executor
+	addr     string
+	listener net.Listener
+	mu       sync.Mutex
+}
+
+func startMigrateTarget(t *testing.T) *migrateTarget {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target := &migrateTarget{exec: NewExecutor(), addr: listener.Addr().String(), listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go target.serve(conn)
+		}
+	}()
+	return target
+}
+
+func (m *migrateTarget) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		if err != nil {
+			return
+		}
+		buf = append(buf, chunk[:n]...)
+		for {
+			parsed := parser.Parse(buf)
+			if parsed.Error() != nil || parsed.BytesConsumed() == 0 {
+				break
+			}
+			command, err := parser.Decoder(parsed)
+			buf = buf[parsed.BytesConsumed():]
+			if err != nil {
+				return
+			}
+			if _, err := conn.Write(parser.Encoder(m.do(command))); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (m *migrateTarget) do(command commands.Command) common.RespValue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exec.Execute(command)
+}
+
+func (m *migrateTarget) port() string {
+	_, port, _ := net.SplitHostPort(m.addr)
+	return port
+}
+
+func TestDumpRestore(t *testing.T) {
+	exec := NewExecutor()
+	exec.Execute(makeCommand("RPUSH", "list", "a", "b", "c"))
+	payload := exec.Execute(makeCommand("DUMP", "list"))
+	require.Equal(t, enums.BulkStringRespType, payload.Type)
+	assert.True(t, exec.Execute(makeCommand("DUMP", "missing")).IsNull)
+
+	resp := exec.Execute(makeCommand("RESTORE", "copy", "0", payload.Str))
+	assert.Equal(t, "OK", resp.Str)
+	resp = exec.Execute(makeCommand("LRANGE", "copy", "0", "-1"))
+	require.Len(t, resp.Array, 3)
+	assert.Equal(t, "c", resp.Array[2].Str)
+	assert.Equal(t, int64(-1), exec.Execute(makeCommand("TTL", "copy")).Int)
+
+	resp = exec.Execute(makeCommand("RESTORE", "copy", "0", payload.Str))
+	assert.Equal(t, "BUSYKEY Target key name already exists.", resp.Str)
+	resp = exec.Execute(makeCommand("RESTORE", "copy", "5000", payload.Str, "REPLACE"))
+	assert.Equal(t, "OK", resp.Str)
+	assert.Equal(t, int64(5), exec.Execute(makeCommand("TTL", "copy")).Int)
+
+	when := exec.dataStore.Now() + 60_000
+	resp = exec.Execute(makeCommand("RESTORE", "abs", strconv.FormatInt(when, 10), payload.Str, "ABSTTL"))
+	assert.Equal(t, "OK", resp.Str)
+	assert.Equal(t, when, exec.Execute(makeCommand("PEXPIRETIME", "abs")).Int)
+
+	// a TTL already in the past leaves no key
+	resp = exec.Execute(makeCommand("RESTORE", "abs", "1", payload.Str, "ABSTTL", "REPLACE"))
+	assert.Equal(t, "OK", resp.Str)
+	assert.Equal(t, int64(-2), exec.Execute(makeCommand("PTTL", "abs")).Int)
+}
+
+func TestRestoreErrors(t *testing.T) {
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "k", "v"))
+	payload := exec.Execute(makeCommand("DUMP", "k")).Str
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"negative ttl", []string{"n", "-1", payload}, "ERR Invalid TTL value, must be >= 0"},
+		{"bad ttl", []string{"n", "x", payload}, "ERR value is not an integer or out of range"},
+		{"bad option", []string{"n", "0", payload, "NOPE"}, "ERR syntax error"},
+		{"bad idletime", []string{"n", "0", payload, "IDLETIME", "-1"}, "ERR Invalid IDLETIME value, must be >= 0"},
+		{"bad freq", []string{"n", "0", payload, "FREQ", "256"}, "ERR Invalid FREQ value, must be >= 0 and <= 255"},
+		{"corrupt payload", []string{"n", "0", payload[:len(payload)-1] + "x"}, "ERR DUMP payload version or checksum are wrong"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := exec.Execute(makeCommand("RESTORE", test.args...))
+			assert.Equal(t, enums.ErrorRespType, resp.Type)
+			assert.Equal(t, test.want, resp.Str)
+		})
+	}
+	assert.Equal(t, int64(-2), exec.Execute(makeCommand("PTTL", "n")).Int)
+}
+
+func TestMigrateMovesKeys(t *testing.T) {
+	target := startMigrateTarget(t)
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Execute(makeCommand("SADD", "b", "x", "y"))
+	exec.Execute(makeCommand("SET", "c", "3", "PX", "60000"))
+
+	resp := exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "", "0", "1000", "KEYS", "a", "b", "c", "missing"))
+	require.Equal(t, "OK", resp.Str, resp.Str)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.Equal(t, int64(-2), exec.Execute(makeCommand("PTTL", key)).Int, key)
+		assert.NotEqual(t, int64(-2), target.do(makeCommand("PTTL", key)).Int, key)
+	}
+	assert.Equal(t, int64(2), target.do(makeCommand("SCARD", "b")).Int)
+	assert.Greater(t, target.do(makeCommand("PTTL", "c")).Int, int64(0))
+	assert.Equal(t, int64(-1), target.do(makeCommand("PTTL", "a")).Int)
+
+	resp = exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "a", "0", "1000"))
+	assert.Equal(t, "NOKEY", resp.Str)
+}
+
+func TestMigrateCopyAndReplace(t *testing.T) {
+	target := startMigrateTarget(t)
+	target.do(makeCommand("SET", "a", "old"))
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "a", "new"))
+	exec.Execute(makeCommand("SET", "b", "2"))
+
+	// a is refused but b still moves, and only b leaves this node
+	resp := exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "", "0", "1000", "KEYS", "a", "b"))
+	assert.Equal(t, "ERR Target instance replied with error: BUSYKEY Target key name already exists.", resp.Str)
+	assert.Equal(t, "new", exec.Execute(makeCommand("GET", "a")).Str)
+	assert.Equal(t, int64(-2), exec.Execute(makeCommand("PTTL", "b")).Int)
+	assert.Equal(t, "2", target.do(makeCommand("GET", "b")).Str)
+
+	resp = exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "a", "0", "1000", "COPY", "REPLACE"))
+	assert.Equal(t, "OK", resp.Str)
+	assert.Equal(t, "new", exec.Execute(makeCommand("GET", "a")).Str)
+	assert.Equal(t, "new", target.do(makeCommand("GET", "a")).Str)
+}
+
+func TestMigrateReusesConnection(t *testing.T) {
+	target := startMigrateTarget(t)
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Execute(makeCommand("SET", "b", "2"))
+	exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "a", "0", "1000"))
+	conn := exec.migrate.conns[target.addr]
+	require.NotNil(t, conn)
+
+	// a connection the target closed is replaced without an error
+	conn.conn.Close()
+	resp := exec.Execute(makeCommand("MIGRATE", "127.0.0.1", target.port(), "b", "0", "1000"))
+	assert.Equal(t, "OK", resp.Str)
+	assert.Equal(t, "2", target.do(makeCommand("GET", "b")).Str)
+	assert.NotSame(t, conn, exec.migrate.conns[target.addr])
+
+	exec.closeMigrate()
+	assert.Empty(t, exec.migrate.conns)
+}
+
+func TestMigrateErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "a", "1"))
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no target", []string{"127.0.0.1", port, "a", "0", "100"}, "IOERR error or timeout connecting to the client"},
+		{"db", []string{"127.0.0.1", port, "a", "1", "100"}, "ERR DB index is out of range"},
+		{"keys with key", []string{"127.0.0.1", port, "a", "0", "100", "KEYS", "b"}, "ERR When using MIGRATE KEYS option, the key argument must be set to the empty string"},
+		{"bad option", []string{"127.0.0.1", port, "a", "0", "100", "NOPE"}, "ERR syntax error"},
+		{"bad port", []string{"127.0.0.1", "x", "a", "0", "100"}, "ERR value is not an integer or out of range"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := exec.Execute(makeCommand("MIGRATE", test.args...))
+			assert.Equal(t, enums.ErrorRespType, resp.Type)
+			assert.Equal(t, test.want, resp.Str)
+		})
+	}
+	assert.Equal(t, "1", exec.Execute(makeCommand("GET", "a")).Str)
+}
+
+// The move reaches the replicas as a DEL, and RESTORE as a RESTORE with
+// the expiry it resolved to.
+func TestMigratePropagates(t *testing.T) {
+	target := startMigrateTarget(t)
+	exec := NewExecutor()
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+
+	writer := newTestClient()
+	writer.do(exec, "SET", "a", "1")
+	writer.reply(t)
+	replica.rawReply(t)
+	writer.do(exec, "DUMP", "a")
+	payload := writer.reply(t).Str
+	writer.do(exec, "MIGRATE", "127.0.0.1", target.port(), "a", "0", "1000")
+	assert.Equal(t, stream(makeCommand("del", "a")), replica.rawReply(t))
+
+	writer.do(exec, "RESTORE", "b", "0", payload)
+	assert.Equal(t, stream(makeCommand("RESTORE", "b", "0", payload, "REPLACE")), replica.rawReply(t))
+}
@@ -0,0 +1,108 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// hiddenFromMonitors are the administrative commands, which Redis does not
+// show to monitors either, so that MONITOR does not give away what they
+// were given, such as the values CONFIG SET sets.
+var hiddenFromMonitors = map[enums.CommandName]bool{
+	enums.MonitorCommandName:      true,
+	enums.ConfigCommandName:       true,
+	enums.SlowlogCommandName:      true,
+	enums.LatencyCommandName:      true,
+	enums.PSyncCommandName:        true,
+	enums.ReplConfCommandName:     true,
+	enums.ReplicaOfCommandName:    true,
+	enums.SlaveOfCommandName:      true,
+	enums.SaveCommandName:         true,
+	enums.BgSaveCommandName:       true,
+	enums.BgRewriteAOFCommandName: true,
+}
+
+// handleMonitor implements MONITOR, which has the connection shown every
+// command the executor runs from then on. Like a subscriber's messages,
+// the commands are pushed onto the connection's own bounded queue, and a
+// monitor that cannot keep up is disconnected rather than allowed to
+// stall the executor.
+func (e *Executor) handleMonitor(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	e.monitors[value.ResponseChan] = value.Session
+	value.Session.setMonitor(true)
+	e.reply(value, okValue())
+}
+
+// monitoring reports whether the connection has run MONITOR.
+func (e *Executor) monitoring(responseChan chan common.RespValue) bool {
+	_, monitoring := e.monitors[responseChan]
+	return monitoring
+}
+
+// feedMonitors shows command, which the current client has just run, to
+// every monitor.
+func (e *Executor) feedMonitors(command commands.Command) {
+	if len(e.monitors) == 0 || hiddenFromMonitors[enums.StringToCommandName(command.Name)] {
+		return
+	}
+	addr := ""
+	if e.client != nil {
+		addr = e.client.Addr
+	}
+	frame := common.RespValue{Type: enums.SimpleStringRespType, Str: monitorLine(time.Now(), addr, command)}
+	for responseChan, session := range e.monitors {
+		e.push(responseChan, session, frame)
+	}
+}
+
+// monitorLine formats command as Redis shows it to monitors: the time, in
+// seconds since the epoch to the microsecond, the database and the
+// client's address, and each argument quoted.
+func monitorLine(now time.Time, addr string, command commands.Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d.%06d [0 %s] %s", now.Unix(), now.Nanosecond()/1000, addr, repr(command.Name))
+	for _, arg := range command.Args {
+		b.WriteByte(' ')
+		b.WriteString(repr(arg))
+	}
+	return b.String()
+}
+
+// repr quotes s as Redis's sdscatrepr does, escaping quotes, backslashes
+// and anything unprintable, so that the line holds no CR or LF.
+func repr(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch == '\n':
+			b.WriteString(`\n`)
+		case ch == '\r':
+			b.WriteString(`\r`)
+		case ch == '\t':
+			b.WriteString(`\t`)
+		case ch == '\a':
+			b.WriteString(`\a`)
+		case ch == '\b':
+			b.WriteString(`\b`)
+		case ch < ' ' || ch > '~':
+			fmt.Fprintf(&b, `\x%02x`, ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// monitorLines returns the commands a monitor has been shown, without
+// their times.
+func monitorLines(t *testing.T, monitor *testClient) []string {
+	t.Helper()
+	var lines []string
+	for len(monitor.responses) > 0 {
+		frame := monitor.reply(t)
+		assert.Equal(t, enums.SimpleStringRespType, frame.Type)
+		assert.Regexp(t, `^\d+\.\d{6} `, frame.Str)
+		lines = append(lines, frame.Str[len("1700000000.000000 "):])
+	}
+	return lines
+}
+
+func TestMonitor(t *testing.T) {
+	exec := NewExecutor()
+	monitor, client := newTestClient(), newTestClient()
+	client.session.Addr = "10.0.0.2:4000"
+	monitor.do(exec, "MONITOR")
+	assert.Equal(t, "OK", monitor.reply(t).Str)
+	assert.True(t, monitor.session.Waiting(), "a monitor is not idle")
+
+	client.do(exec, "set", "a", "x y\n\"")
+	client.do(exec, "CONFIG", "GET", "*")
+	client.do(exec, "MULTI")
+	client.do(exec, "INCR", "n")
+	client.do(exec, "EXEC")
+	client.do(exec, "RPUSH", "list", "x")
+	client.do(exec, "BLPOP", "list", "0")
+	assert.Equal(t, []string{
+		`[0 10.0.0.2:4000] "set" "a" "x y\n\""`,
+		`[0 10.0.0.2:4000] "MULTI"`,
+		`[0 10.0.0.2:4000] "INCR" "n"`,
+		`[0 10.0.0.2:4000] "EXEC"`,
+		`[0 10.0.0.2:4000] "RPUSH" "list" "x"`,
+		`[0 10.0.0.2:4000] "BLPOP" "list" "0"`,
+	}, monitorLines(t, monitor), "CONFIG is hidden, and the queued INCR shows when it runs")
+
+	// what a monitor sends is ignored
+	monitor.do(exec, "SET", "b", "1")
+	monitor.assertNoReply(t)
+	assert.Equal(t, "", exec.Execute(makeCommand("GET", "b")).Str)
+	assert.Equal(t, []string{`[0 ] "GET" "b"`}, monitorLines(t, monitor), "a command without a client")
+	monitor.do(exec, "QUIT")
+	assert.Equal(t, "OK", monitor.reply(t).Str)
+
+	monitor.disconnect(exec)
+	assert.Empty(t, exec.monitors)
+	client.do(exec, "PING")
+	monitor.assertClosed(t)
+}
+
+func TestMonitorNotAllowed(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "MONITOR")
+	assert.Equal(t, "ERR Command not allowed inside a transaction", client.reply(t).Str)
+
+	client = newTestClient()
+	client.do(exec, "SUBSCRIBE", "x")
+	client.reply(t)
+	client.do(exec, "MONITOR")
+	assert.Contains(t, client.reply(t).Str, "ERR Can't execute 'monitor'")
+	assert.Empty(t, exec.monitors)
+}
+
+func TestMonitorSlow(t *testing.T) {
+	exec := NewExecutor()
+	slow, fast, client := newTestClient(), newTestClient(), newTestClient()
+	slow.do(exec, "MONITOR")
+	fast.do(exec, "MONITOR")
+	fast.reply(t)
+	// the slow monitor never reads even its OK
+	for range cap(slow.responses) - 1 {
+		client.do(exec, "PING")
+		client.reply(t)
+		fast.reply(t)
+	}
+	assert.False(t, slow.killed)
+	assert.False(t, fast.killed)
+
+	// the slow monitor's queue is full: it is dropped, and nobody waits
+	client.do(exec, "PING")
+	assert.Equal(t, "PONG", client.reply(t).Str)
+	assert.True(t, slow.killed)
+	assert.False(t, fast.killed)
+	assert.Len(t, exec.monitors, 1)
+	assert.Len(t, monitorLines(t, fast), 1)
+}
+
+func TestMonitorLine(t *testing.T) {
+	now := time.Unix(1700000000, 1234567)
+	line := monitorLine(now, "127.0.0.1:6000", makeCommand("SET", "k", "a\r\n\t\a\b\x00\xff\\ é"))
+	assert.Equal(t, `1700000000.001234 [0 127.0.0.1:6000] "SET" "k" "a\r\n\t\a\b\x00\xff\\ \xc3\xa9"`, line)
+}
@@ -0,0 +1,136 @@
+package datastore
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// pauseState is what CLIENT PAUSE set. While clients are paused, their
+// requests are held, in the order they arrived, and run once the pause
+// ends, so a failover can let the replicas catch up with a master that
+// takes no more writes.
+type pauseState struct {
+	// until is when the pause ends, zero if there is none
+	until time.Time
+	// writesOnly is set by CLIENT PAUSE WRITE, which holds only the
+	// commands that may change the dataset or be propagated
+	writesOnly bool
+	held       []Value
+	// heldClients counts the requests held for each connection. Once one
+	// of them is held, the rest wait behind it, to keep them in order.
+	heldClients map[chan common.RespValue]int
+}
+
+func newPauseState() pauseState {
+	return pauseState{heldClients: make(map[chan common.RespValue]int)}
+}
+
+// clientPause implements CLIENT PAUSE timeout [WRITE|ALL]. As in Redis, a
+// second pause sets the mode, but can only make the pause longer.
+func (e *Executor) clientPause(args []string) common.RespValue {
+	ms, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errorValue("ERR timeout is not an integer or out of range")
+	}
+	if ms < 0 {
+		return errorValue("ERR timeout is negative")
+	}
+	writesOnly := false
+	if len(args) == 2 {
+		switch strings.ToUpper(args[1]) {
+		case "WRITE":
+			writesOnly = true
+		case "ALL":
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+	e.pause.writesOnly = writesOnly
+	if until := time.Now().Add(time.Duration(ms) * time.Millisecond); until.After(e.pause.until) {
+		e.pause.until = until
+	}
+	return okValue()
+}
+
+// holdForPause holds value if clients are paused, and reports whether it
+// did. Replicas are never paused, and the CLIENT commands are never held,
+// so that the clients can still be managed, and the pause ended early.
+func (e *Executor) holdForPause(value Value) bool {
+	if e.pause.until.IsZero() {
+		return false
+	}
+	if !time.Now().Before(e.pause.until) {
+		e.unpause()
+		return false
+	}
+	if e.pause.heldClients[value.ResponseChan] == 0 && !e.pauses(value) {
+		return false
+	}
+	e.pause.held = append(e.pause.held, value)
+	e.pause.heldClients[value.ResponseChan]++
+	return true
+}
+
+// pauses reports whether the pause applies to value.
+func (e *Executor) pauses(value Value) bool {
+	if _, replica := e.replication.replicas[value.ResponseChan]; replica {
+		return false
+	}
+	name := enums.StringToCommandName(value.Command.Name)
+	if name == enums.ClientCommandName {
+		return false
+	}
+	if !e.pause.writesOnly {
+		return true
+	}
+	// a write sent inside MULTI is only queued; the EXEC is held instead
+	if tx := e.transactions.clients[value.ResponseChan]; tx != nil && tx.inMulti && !runsInsideMulti[name] {
+		return false
+	}
+	switch {
+	case commands.IsWriteCommand(value.Command.Name), name == enums.PublishCommandName:
+		return true
+	case name == enums.ExecCommandName:
+		tx := e.transactions.clients[value.ResponseChan]
+		return tx != nil && slices.ContainsFunc(tx.queued, func(command commands.Command) bool {
+			return commands.IsWriteCommand(command.Name) || enums.StringToCommandName(command.Name) == enums.PublishCommandName
+		})
+	}
+	return false
+}
+
+// unpause ends the pause and runs the requests it held.
+func (e *Executor) unpause() {
+	held := e.pause.held
+	e.pause = newPauseState()
+	for _, value := range held {
+		if e.dispatch(value) {
+			e.serveReadyKeys()
+		}
+	}
+}
+
+// cronPause ends the pause once its time is up.
+func (e *Executor) cronPause() {
+	if !e.pause.until.IsZero() && !time.Now().Before(e.pause.until) {
+		e.unpause()
+	}
+}
+
+// releaseHeld drops the requests held for a connection that is going
+// away.
+func (e *Executor) releaseHeld(responseChan chan common.RespValue) {
+	if e.pause.heldClients[responseChan] == 0 {
+		return
+	}
+	delete(e.pause.heldClients, responseChan)
+	e.pause.held = slices.DeleteFunc(e.pause.held, func(value Value) bool {
+		return value.ResponseChan == responseChan
+	})
+}
@@ -0,0 +1,294 @@
+package datastore
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// allowedWhileSubscribed lists the commands a connection may run while it
+// has at least one subscription.
+var allowedWhileSubscribed = map[enums.CommandName]bool{
+	enums.SubscribeCommandName:    true,
+	enums.UnsubscribeCommandName:  true,
+	enums.PSubscribeCommandName:   true,
+	enums.PUnsubscribeCommandName: true,
+	enums.PingCommandName:         true,
+	enums.QuitCommandName:         true,
+}
+
+// pubsubState holds every subscription. As in Redis, channels and patterns
+// map to their subscribers and every subscriber records what it listens
+// to, so both PUBLISH and dropping a client are cheap.
+type pubsubState struct {
+	channels    map[string]map[chan common.RespValue]*subscriber
+	patterns    map[string]map[chan common.RespValue]*subscriber
+	subscribers map[chan common.RespValue]*subscriber
+}
+
+type subscriber struct {
+	responseChan chan common.RespValue
+	session      *Session
+	channels     map[string]struct{}
+	patterns     map[string]struct{}
+}
+
+func (s *subscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+func newPubsubState() pubsubState {
+	return pubsubState{
+		channels:    make(map[string]map[chan common.RespValue]*subscriber),
+		patterns:    make(map[string]map[chan common.RespValue]*subscriber),
+		subscribers: make(map[chan common.RespValue]*subscriber),
+	}
+}
+
+func (p *pubsubState) subscribed(responseChan chan common.RespValue) bool {
+	_, exists := p.subscribers[responseChan]
+	return exists
+}
+
+// subscribe adds name to the channels, or patterns, of sub and reports
+// whether it was new.
+func (p *pubsubState) subscribe(sub *subscriber, name string, pattern bool) bool {
+	own, index := sub.channels, p.channels
+	if pattern {
+		own, index = sub.patterns, p.patterns
+	}
+	if _, exists := own[name]; exists {
+		return false
+	}
+	own[name] = struct{}{}
+	if index[name] == nil {
+		index[name] = make(map[chan common.RespValue]*subscriber)
+	}
+	index[name][sub.responseChan] = sub
+	p.subscribers[sub.responseChan] = sub
+	return true
+}
+
+func (p *pubsubState) unsubscribe(sub *subscriber, name string, pattern bool) {
+	own, index := sub.channels, p.channels
+	if pattern {
+		own, index = sub.patterns, p.patterns
+	}
+	if _, exists := own[name]; !exists {
+		return
+	}
+	delete(own, name)
+	delete(index[name], sub.responseChan)
+	if len(index[name]) == 0 {
+		delete(index, name)
+	}
+	if sub.count() == 0 {
+		delete(p.subscribers, sub.responseChan)
+	}
+}
+
+func (p *pubsubState) unsubscribeAll(sub *subscriber) {
+	for channel := range sub.channels {
+		p.unsubscribe(sub, channel, false)
+	}
+	for pattern := range sub.patterns {
+		p.unsubscribe(sub, pattern, true)
+	}
+}
+
+// handleSubscribe implements SUBSCRIBE and PSUBSCRIBE, confirming each
+// channel or pattern with the connection's new subscription count.
+func (e *Executor) handleSubscribe(value Value, pattern bool) {
+	if len(value.Command.Args) == 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+
+	sub, exists := e.pubsub.subscribers[value.ResponseChan]
+	if !exists {
+		sub = &subscriber{
+			responseChan: value.ResponseChan,
+			session:      value.Session,
+			channels:     make(map[string]struct{}),
+			patterns:     make(map[string]struct{}),
+		}
+	}
+	kind := "subscribe"
+	if pattern {
+		kind = "psubscribe"
+	}
+	for _, name := range value.Command.Args {
+		e.pubsub.subscribe(sub, name, pattern)
+		if !e.push(sub.responseChan, sub.session, pubsubFrame(kind, bulkElement(name), intElement(sub.count()))) {
+			return
+		}
+	}
+	value.Session.setSubscribed(true)
+}
+
+// handleUnsubscribe implements UNSUBSCRIBE and PUNSUBSCRIBE. Without
+// arguments it drops every channel, or pattern, the connection has.
+func (e *Executor) handleUnsubscribe(value Value, pattern bool) {
+	kind := "unsubscribe"
+	if pattern {
+		kind = "punsubscribe"
+	}
+
+	sub := e.pubsub.subscribers[value.ResponseChan]
+	names := value.Command.Args
+	if len(names) == 0 && sub != nil {
+		own := sub.channels
+		if pattern {
+			own = sub.patterns
+		}
+		for name := range own {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+
+	if len(names) == 0 {
+		count := 0
+		if sub != nil {
+			count = sub.count()
+		}
+		e.push(value.ResponseChan, value.Session, pubsubFrame(kind, &common.RespValue{Type: enums.BulkStringRespType, IsNull: true}, intElement(count)))
+		return
+	}
+
+	for _, name := range names {
+		count := 0
+		if sub != nil {
+			e.pubsub.unsubscribe(sub, name, pattern)
+			count = sub.count()
+		}
+		if !e.push(value.ResponseChan, value.Session, pubsubFrame(kind, bulkElement(name), intElement(count))) {
+			return
+		}
+	}
+	if sub == nil || sub.count() == 0 {
+		value.Session.setSubscribed(false)
+	}
+}
+
+// handlePublish implements PUBLISH channel message, replying with the
+// number of subscribers the message was delivered to.
+func (e *Executor) handlePublish(command commands.Command) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArity(command.Name)
+	}
+	return common.RespValue{
+		Type: enums.IntRespType,
+		Int:  int64(e.publish(command.Args[0], command.Args[1])),
+	}
+}
+
+// publish delivers message to the subscribers of channel and of every
+// pattern matching it, and returns how many deliveries were made.
+func (e *Executor) publish(channel, message string) int {
+	receivers := 0
+	for _, sub := range e.pubsub.channels[channel] {
+		if e.push(sub.responseChan, sub.session, pubsubFrame("message", bulkElement(channel), bulkElement(message))) {
+			receivers++
+		}
+	}
+	for pattern, subs := range e.pubsub.patterns {
+		if !common.GlobMatch(pattern, channel) {
+			continue
+		}
+		for _, sub := range subs {
+			if e.push(sub.responseChan, sub.session, pubsubFrame("pmessage", bulkElement(pattern), bulkElement(channel), bulkElement(message))) {
+				receivers++
+			}
+		}
+	}
+	return receivers
+}
+
+// handlePubsub implements PUBSUB CHANNELS [pattern], PUBSUB NUMSUB
+// [channel ...] and PUBSUB NUMPAT.
+func (e *Executor) handlePubsub(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+
+	subcommand := strings.ToUpper(args[0])
+	switch {
+	case subcommand == "CHANNELS" && len(args) <= 2:
+		channels := []*common.RespValue{}
+		for channel := range e.pubsub.channels {
+			if len(args) == 1 || common.GlobMatch(args[1], channel) {
+				channels = append(channels, bulkElement(channel))
+			}
+		}
+		slices.SortFunc(channels, func(a, b *common.RespValue) int {
+			return strings.Compare(a.Str, b.Str)
+		})
+		return common.RespValue{Type: enums.ArrayRespType, Array: channels}
+	case subcommand == "NUMSUB":
+		counts := make([]*common.RespValue, 0, 2*(len(args)-1))
+		for _, channel := range args[1:] {
+			counts = append(counts, bulkElement(channel), intElement(len(e.pubsub.channels[channel])))
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: counts}
+	case subcommand == "NUMPAT" && len(args) == 1:
+		return common.RespValue{Type: enums.IntRespType, Int: int64(len(e.pubsub.patterns))}
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try PUBSUB HELP.", args[0]))
+	}
+}
+
+// handleSubscribedPing answers PING in subscribed mode, where Redis replies
+// with a "pong" array rather than a status.
+func (e *Executor) handleSubscribedPing(value Value) {
+	if len(value.Command.Args) > 1 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	message := ""
+	if len(value.Command.Args) == 1 {
+		message = value.Command.Args[0]
+	}
+	e.push(value.ResponseChan, value.Session, pubsubFrame("pong", bulkElement(message)))
+}
+
+// push sends a Pub/Sub frame. A subscriber whose channel is full is
+// falling behind the publishers; rather than stall the executor or silently
+// lose messages, the connection is dropped, much like Redis does once a
+// Pub/Sub client exceeds its output buffer limit. push reports whether the
+// frame was sent. A connection that turned its replies off with CLIENT
+// REPLY gets no frames, but still counts as reached.
+func (e *Executor) push(responseChan chan common.RespValue, session *Session, frame common.RespValue) bool {
+	if session.silenced() {
+		return true
+	}
+	select {
+	case responseChan <- frame:
+		return true
+	default:
+		slog.Info("dropping client that cannot keep up with its replies")
+		e.dropClient(responseChan, session)
+		return false
+	}
+}
+
+func pubsubFrame(kind string, elements ...*common.RespValue) common.RespValue {
+	return common.RespValue{
+		Type:  enums.ArrayRespType,
+		Array: append([]*common.RespValue{bulkElement(kind)}, elements...),
+	}
+}
+
+func bulkElement(value string) *common.RespValue {
+	return &common.RespValue{Type: enums.BulkStringRespType, Str: value}
+}
+
+func intElement(value int) *common.RespValue {
+	return &common.RespValue{Type: enums.IntRespType, Int: int64(value)}
+}
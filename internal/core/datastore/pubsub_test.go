@@ -0,0 +1,175 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// frameValues flattens a Pub/Sub frame, with integers as int64 and null
+// bulk strings as nil.
+func frameValues(resp common.RespValue) []any {
+	values := []any{}
+	for _, element := range resp.Array {
+		switch {
+		case element.IsNull:
+			values = append(values, nil)
+		case element.Type == enums.IntRespType:
+			values = append(values, element.Int)
+		default:
+			values = append(values, element.Str)
+		}
+	}
+	return values
+}
+
+func TestSubscribeAndPublish(t *testing.T) {
+	exec := NewExecutor()
+	subscriber, publisher := newTestClient(), newTestClient()
+
+	subscriber.do(exec, "SUBSCRIBE", "news", "sport")
+	assert.Equal(t, []any{"subscribe", "news", int64(1)}, frameValues(subscriber.reply(t)))
+	assert.Equal(t, []any{"subscribe", "sport", int64(2)}, frameValues(subscriber.reply(t)))
+	assert.True(t, subscriber.session.Waiting())
+
+	publisher.do(exec, "PUBLISH", "news", "hello")
+	assert.Equal(t, int64(1), publisher.reply(t).Int)
+	assert.Equal(t, []any{"message", "news", "hello"}, frameValues(subscriber.reply(t)))
+
+	publisher.do(exec, "PUBLISH", "weather", "rain")
+	assert.Equal(t, int64(0), publisher.reply(t).Int)
+	subscriber.assertNoReply(t)
+}
+
+func TestPatternSubscribe(t *testing.T) {
+	exec := NewExecutor()
+	subscriber, publisher := newTestClient(), newTestClient()
+
+	subscriber.do(exec, "PSUBSCRIBE", "news.*")
+	subscriber.do(exec, "SUBSCRIBE", "news.tech")
+	assert.Equal(t, []any{"psubscribe", "news.*", int64(1)}, frameValues(subscriber.reply(t)))
+	assert.Equal(t, []any{"subscribe", "news.tech", int64(2)}, frameValues(subscriber.reply(t)))
+
+	// a channel matched both directly and by pattern is delivered twice
+	publisher.do(exec, "PUBLISH", "news.tech", "go")
+	assert.Equal(t, int64(2), publisher.reply(t).Int)
+	assert.Equal(t, []any{"message", "news.tech", "go"}, frameValues(subscriber.reply(t)))
+	assert.Equal(t, []any{"pmessage", "news.*", "news.tech", "go"}, frameValues(subscriber.reply(t)))
+}
+
+func TestUnsubscribe(t *testing.T) {
+	exec := NewExecutor()
+	subscriber, publisher := newTestClient(), newTestClient()
+
+	subscriber.do(exec, "SUBSCRIBE", "b", "a")
+	subscriber.reply(t)
+	subscriber.reply(t)
+
+	subscriber.do(exec, "UNSUBSCRIBE")
+	assert.Equal(t, []any{"unsubscribe", "a", int64(1)}, frameValues(subscriber.reply(t)))
+	assert.Equal(t, []any{"unsubscribe", "b", int64(0)}, frameValues(subscriber.reply(t)))
+	assert.False(t, subscriber.session.Waiting())
+
+	subscriber.do(exec, "PUNSUBSCRIBE")
+	assert.Equal(t, []any{"punsubscribe", nil, int64(0)}, frameValues(subscriber.reply(t)))
+
+	publisher.do(exec, "PUBLISH", "a", "x")
+	assert.Equal(t, int64(0), publisher.reply(t).Int)
+
+	// outside subscribed mode every command works again
+	subscriber.do(exec, "PING")
+	assert.Equal(t, "PONG", subscriber.reply(t).Str)
+}
+
+func TestSubscribedModeRestrictsCommands(t *testing.T) {
+	exec := NewExecutor()
+	subscriber := newTestClient()
+
+	subscriber.do(exec, "SUBSCRIBE", "a")
+	subscriber.reply(t)
+
+	subscriber.do(exec, "GET", "k")
+	resp := subscriber.reply(t)
+	assert.Equal(t, enums.ErrorRespType, resp.Type)
+	assert.Equal(t, "ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", resp.Str)
+
+	subscriber.do(exec, "PING")
+	assert.Equal(t, []any{"pong", ""}, frameValues(subscriber.reply(t)))
+	subscriber.do(exec, "PING", "hi")
+	assert.Equal(t, []any{"pong", "hi"}, frameValues(subscriber.reply(t)))
+}
+
+func TestPubsubIntrospection(t *testing.T) {
+	exec := NewExecutor()
+	first, second, client := newTestClient(), newTestClient(), newTestClient()
+
+	first.do(exec, "SUBSCRIBE", "news", "sport")
+	second.do(exec, "SUBSCRIBE", "news")
+	second.do(exec, "PSUBSCRIBE", "n*", "s*")
+
+	client.do(exec, "PUBSUB", "CHANNELS")
+	assert.Equal(t, []string{"news", "sport"}, replyStrings(client.reply(t)))
+	client.do(exec, "PUBSUB", "channels", "n*")
+	assert.Equal(t, []string{"news"}, replyStrings(client.reply(t)))
+
+	client.do(exec, "PUBSUB", "NUMSUB", "news", "sport", "none")
+	assert.Equal(t, []any{"news", int64(2), "sport", int64(1), "none", int64(0)}, frameValues(client.reply(t)))
+
+	client.do(exec, "PUBSUB", "NUMPAT")
+	assert.Equal(t, int64(2), client.reply(t).Int)
+
+	client.do(exec, "PUBSUB", "NUMPAT", "extra")
+	assert.Equal(t, "ERR unknown subcommand or wrong number of arguments for 'NUMPAT'. Try PUBSUB HELP.", client.reply(t).Str)
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	exec := NewExecutor()
+	slow, fast, publisher := newTestClient(), newTestClient(), newTestClient()
+	slow.responses = make(chan common.RespValue, 2)
+
+	slow.do(exec, "SUBSCRIBE", "a")
+	fast.do(exec, "SUBSCRIBE", "a")
+	fast.reply(t)
+
+	publisher.do(exec, "PUBLISH", "a", "1")
+	assert.Equal(t, int64(2), publisher.reply(t).Int)
+	assert.False(t, slow.killed)
+
+	// the slow subscriber's buffer is full, so it is dropped rather than
+	// stalling the executor
+	publisher.do(exec, "PUBLISH", "a", "2")
+	assert.Equal(t, int64(1), publisher.reply(t).Int)
+	assert.True(t, slow.killed)
+	fast.reply(t)
+	fast.reply(t)
+
+	// whatever it sends before its Disconnect is ignored
+	slow.reply(t)
+	slow.reply(t)
+	slow.do(exec, "PING")
+	slow.assertNoReply(t)
+
+	publisher.do(exec, "PUBSUB", "NUMSUB", "a")
+	assert.Equal(t, []any{"a", int64(1)}, frameValues(publisher.reply(t)))
+
+	slow.disconnect(exec)
+	slow.assertClosed(t)
+	assert.Empty(t, exec.dropped)
+}
+
+func TestDisconnectRemovesSubscriptions(t *testing.T) {
+	exec := NewExecutor()
+	subscriber, publisher := newTestClient(), newTestClient()
+
+	subscriber.do(exec, "SUBSCRIBE", "a")
+	subscriber.do(exec, "PSUBSCRIBE", "*")
+	subscriber.disconnect(exec)
+	subscriber.assertClosed(t)
+
+	publisher.do(exec, "PUBLISH", "a", "x")
+	assert.Equal(t, int64(0), publisher.reply(t).Int)
+	publisher.do(exec, "PUBSUB", "NUMPAT")
+	assert.Equal(t, int64(0), publisher.reply(t).Int)
+}
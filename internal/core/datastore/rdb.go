@@ -0,0 +1,200 @@
+package datastore
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// bgsaveRetryDelay is how long the save rules wait before trying again
+// after a BGSAVE fails, as CONFIG_BGSAVE_RETRY_DELAY does in Redis.
+const bgsaveRetryDelay = 5 * time.Second
+
+// saveState tracks RDB snapshots.
+type saveState struct {
+	path  string
+	rules []rdb.SaveRule
+	// dirty is the keyspace's dirty count as of the last successful save,
+	// so Dirty() - dirty is the number of changes since then
+	dirty    int64
+	lastSave time.Time
+	// lastBgsaveErr is the outcome of the last BGSAVE, which lastBgsaveTry
+	// started
+	lastBgsaveErr   error
+	lastBgsaveTry   time.Time
+	bgsave          *bgsave
+	bgsaveScheduled bool
+}
+
+// bgsave is a BGSAVE in progress. The snapshot is written by a goroutine
+// of its own, which sends the outcome on done.
+type bgsave struct {
+	snapshot *keyspace.Snapshot
+	// dirty is the keyspace's dirty count when the snapshot was taken
+	dirty  int64
+	done   chan error
+	cancel chan struct{}
+}
+
+// SetRDB has the executor snapshot the keyspace to path on SAVE and
+// BGSAVE, whenever one of rules is met, and at Close if there are any
+// rules. Call it before the executor goroutine starts, and after loading
+// any data, so what was loaded does not count as changes to save.
+func (e *Executor) SetRDB(path string, rules []rdb.SaveRule) {
+	e.saving.path = path
+	e.saving.rules = rules
+	e.saving.dirty = e.dataStore.Dirty()
+	e.saving.lastSave = time.Now()
+}
+
+// LoadRDB loads the snapshot at path into the keyspace, which must be
+// empty, and returns the number of keys loaded.
+func (e *Executor) LoadRDB(path string) (int, error) {
+	return rdb.Load(path, e.dataStore)
+}
+
+// handleSave implements SAVE, which snapshots the keyspace on the
+// executor goroutine, so nothing else runs until it is done.
+func (e *Executor) handleSave(command commands.Command) common.RespValue {
+	if len(command.Args) != 0 {
+		return wrongArity(command.Name)
+	}
+	if e.saving.path == "" {
+		return errorValue("ERR snapshotting is not configured")
+	}
+	if e.saving.bgsave != nil {
+		return errorValue("ERR Background save already in progress")
+	}
+	if err := e.save(); err != nil {
+		return errorValue("ERR Failed to save the snapshot. Please check the server logs for more information.")
+	}
+	return okValue()
+}
+
+// handleBgSave implements BGSAVE [SCHEDULE]. Inside a transaction the save
+// is put off until the next cron run, so the snapshot never holds half a
+// transaction.
+func (e *Executor) handleBgSave(command commands.Command) common.RespValue {
+	if len(command.Args) > 1 {
+		return wrongArity(command.Name)
+	}
+	if len(command.Args) == 1 && !strings.EqualFold(command.Args[0], "SCHEDULE") {
+		return errorValue("ERR syntax error")
+	}
+	if e.saving.path == "" {
+		return errorValue("ERR snapshotting is not configured")
+	}
+	if e.saving.bgsave != nil {
+		return errorValue("ERR Background save already in progress")
+	}
+	if e.inExec {
+		e.saving.bgsaveScheduled = true
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "Background saving scheduled"}
+	}
+	e.startBgsave()
+	return common.RespValue{Type: enums.SimpleStringRespType, Str: "Background saving started"}
+}
+
+func (e *Executor) handleLastSave(command commands.Command) common.RespValue {
+	if len(command.Args) != 0 {
+		return wrongArity(command.Name)
+	}
+	return common.RespValue{Type: enums.IntRespType, Int: e.saving.lastSave.Unix()}
+}
+
+// save snapshots the keyspace and writes it out before returning.
+func (e *Executor) save() error {
+	start := time.Now()
+	snapshot := e.dataStore.Snapshot()
+	defer snapshot.Release()
+	e.dataStore.CompleteSnapshots()
+	if err := rdb.Save(e.saving.path, snapshot, nil); err != nil {
+		slog.Error("failed to save the snapshot", "error", err)
+		return err
+	}
+	e.saving.dirty = e.dataStore.Dirty()
+	e.saving.lastSave = time.Now()
+	slog.Info("saved the snapshot", "keys", snapshot.Len(), "duration", time.Since(start))
+	return nil
+}
+
+// startBgsave snapshots the keyspace and has a goroutine write it out.
+func (e *Executor) startBgsave() {
+	start := time.Now()
+	bg := &bgsave{
+		snapshot: e.dataStore.Snapshot(),
+		dirty:    e.dataStore.Dirty(),
+		done:     make(chan error, 1),
+		cancel:   make(chan struct{}),
+	}
+	e.timeEvent(latencySnapshot, start)
+	e.saving.bgsave = bg
+	e.saving.bgsaveScheduled = false
+	e.saving.lastBgsaveTry = time.Now()
+	path := e.saving.path
+	go func() {
+		bg.done <- rdb.Save(path, bg.snapshot, bg.cancel)
+	}()
+	slog.Info("started saving the snapshot in the background")
+}
+
+// cronSave completes a BGSAVE whose snapshot has been written, and starts
+// one that was put off or that a save rule calls for.
+func (e *Executor) cronSave() {
+	if bg := e.saving.bgsave; bg != nil {
+		select {
+		case err := <-bg.done:
+			e.finishBgsave(bg, err)
+		default:
+			return
+		}
+	}
+	if e.saving.path == "" {
+		return
+	}
+	if e.saving.bgsaveScheduled {
+		e.startBgsave()
+		return
+	}
+	changes := e.dataStore.Dirty() - e.saving.dirty
+	if !rdb.Due(e.saving.rules, changes, time.Since(e.saving.lastSave)) {
+		return
+	}
+	if e.saving.lastBgsaveErr != nil && time.Since(e.saving.lastBgsaveTry) < bgsaveRetryDelay {
+		return
+	}
+	e.startBgsave()
+}
+
+func (e *Executor) finishBgsave(bg *bgsave, err error) {
+	keys := bg.snapshot.Len()
+	bg.snapshot.Release()
+	e.saving.bgsave = nil
+	e.saving.lastBgsaveErr = err
+	if err != nil {
+		slog.Error("background save failed", "error", err)
+		return
+	}
+	e.saving.dirty = bg.dirty
+	e.saving.lastSave = time.Now()
+	slog.Info("background save done", "keys", keys)
+}
+
+// closeRDB abandons a BGSAVE in progress and, if there are save rules,
+// saves a final snapshot.
+func (e *Executor) closeRDB() error {
+	if bg := e.saving.bgsave; bg != nil {
+		close(bg.cancel)
+		e.finishBgsave(bg, <-bg.done)
+	}
+	if e.saving.path == "" || len(e.saving.rules) == 0 {
+		return nil
+	}
+	return e.save()
+}
@@ -0,0 +1,193 @@
+package datastore
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+)
+
+// snapshottingExecutor returns an executor saving to a fresh file with the
+// given save rules, and the file's path.
+func snapshottingExecutor(t *testing.T, rules string) (*Executor, string) {
+	t.Helper()
+	parsed, err := rdb.ParseSaveRules(rules)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	exec := NewExecutor()
+	exec.SetRDB(path, parsed)
+	return exec, path
+}
+
+// restored loads the snapshot at path into a new executor.
+func restored(t *testing.T, path string) *Executor {
+	t.Helper()
+	exec := NewExecutor()
+	_, err := exec.LoadRDB(path)
+	require.NoError(t, err)
+	return exec
+}
+
+// finishBgsave runs the executor's cron until the BGSAVE in progress has
+// completed.
+func finishBgsave(t *testing.T, exec *Executor) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		exec.Cron()
+		return exec.saving.bgsave == nil
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestSave(t *testing.T) {
+	exec, path := snapshottingExecutor(t, "")
+	exec.saving.lastSave = time.Unix(1, 0)
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Execute(makeCommand("RPUSH", "l", "x", "y"))
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("SAVE")).Str)
+	assert.InDelta(t, time.Now().Unix(), exec.Execute(makeCommand("LASTSAVE")).Int, 1)
+
+	loaded := restored(t, path)
+	assert.Equal(t, "1", loaded.Execute(makeCommand("GET", "a")).Str)
+	assert.Equal(t, []string{"x", "y"}, replyStrings(loaded.Execute(makeCommand("LRANGE", "l", "0", "-1"))))
+}
+
+func TestBgSave(t *testing.T) {
+	exec, path := snapshottingExecutor(t, "")
+	exec.Execute(makeCommand("SET", "a", "1"))
+
+	assert.Equal(t, "Background saving started", exec.Execute(makeCommand("BGSAVE")).Str)
+	assert.Equal(t, "ERR Background save already in progress", exec.Execute(makeCommand("BGSAVE")).Str)
+	assert.Equal(t, "ERR Background save already in progress", exec.Execute(makeCommand("SAVE")).Str)
+	// writes made while the snapshot is written are not in it
+	exec.Execute(makeCommand("SET", "a", "2"))
+	exec.Execute(makeCommand("SET", "b", "1"))
+	finishBgsave(t, exec)
+	assert.NoError(t, exec.saving.lastBgsaveErr)
+
+	loaded := restored(t, path)
+	assert.Equal(t, "1", loaded.Execute(makeCommand("GET", "a")).Str)
+	assert.True(t, loaded.Execute(makeCommand("GET", "b")).IsNull)
+	// the two writes since the snapshot are still to be saved
+	assert.Equal(t, int64(2), exec.dataStore.Dirty()-exec.saving.dirty)
+}
+
+func TestBgSaveLargeKeyspace(t *testing.T) {
+	exec, path := snapshottingExecutor(t, "")
+	const keys = 5000
+	for i := range keys {
+		exec.Execute(makeCommand("SET", strconv.Itoa(i), "1"))
+	}
+
+	// the keyspace is copied from the cron, so the writes made before it
+	// gets to a key are not in the snapshot either
+	assert.Equal(t, "Background saving started", exec.Execute(makeCommand("BGSAVE")).Str)
+	for i := range keys {
+		exec.Execute(makeCommand("SET", strconv.Itoa(i), "2"))
+	}
+	exec.Execute(makeCommand("DEL", "0"))
+	finishBgsave(t, exec)
+	require.NoError(t, exec.saving.lastBgsaveErr)
+
+	loaded := restored(t, path)
+	assert.Equal(t, keys, loaded.dataStore.Len())
+	for i := range keys {
+		assert.Equal(t, "1", loaded.Execute(makeCommand("GET", strconv.Itoa(i))).Str)
+	}
+
+	// a save still waiting for its snapshot is given up on at close
+	assert.Equal(t, "Background saving started", exec.Execute(makeCommand("BGSAVE")).Str)
+	assert.NoError(t, exec.Close())
+}
+
+func TestBgSaveErrors(t *testing.T) {
+	exec := NewExecutor()
+	assert.Equal(t, "ERR snapshotting is not configured", exec.Execute(makeCommand("BGSAVE")).Str)
+	assert.Equal(t, "ERR snapshotting is not configured", exec.Execute(makeCommand("SAVE")).Str)
+
+	exec, _ = snapshottingExecutor(t, "")
+	assert.Equal(t, "ERR syntax error", exec.Execute(makeCommand("BGSAVE", "NOW")).Str)
+	assert.Equal(t, "Background saving started", exec.Execute(makeCommand("BGSAVE", "schedule")).Str)
+	finishBgsave(t, exec)
+
+	exec.saving.path = filepath.Join(t.TempDir(), "missing", "dump.rdb")
+	assert.Equal(t, "ERR Failed to save the snapshot. Please check the server logs for more information.", exec.Execute(makeCommand("SAVE")).Str)
+}
+
+func TestBgSaveInsideMulti(t *testing.T) {
+	exec, path := snapshottingExecutor(t, "")
+	client := newTestClient()
+
+	client.do(exec, "MULTI")
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "BGSAVE")
+	client.do(exec, "SET", "b", "1")
+	client.do(exec, "EXEC")
+	for range 4 {
+		client.reply(t)
+	}
+	assert.Equal(t, "Background saving scheduled", client.reply(t).Array[1].Str)
+
+	// the save starts after the transaction, so it holds all of it
+	assert.Nil(t, exec.saving.bgsave)
+	finishBgsave(t, exec)
+	loaded := restored(t, path)
+	assert.Equal(t, "1", loaded.Execute(makeCommand("GET", "b")).Str)
+}
+
+func TestSaveRules(t *testing.T) {
+	exec, path := snapshottingExecutor(t, "60 2")
+
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Execute(makeCommand("SET", "b", "1"))
+	exec.Cron()
+	assert.Nil(t, exec.saving.bgsave, "the period has not passed")
+
+	exec.saving.lastSave = time.Now().Add(-time.Minute)
+	exec.Execute(makeCommand("GET", "a"))
+	exec.Cron()
+	require.NotNil(t, exec.saving.bgsave)
+	finishBgsave(t, exec)
+	assert.Equal(t, "1", restored(t, path).Execute(makeCommand("GET", "b")).Str)
+
+	// nothing has changed since, so nothing is due
+	exec.saving.lastSave = time.Now().Add(-time.Hour)
+	exec.Cron()
+	assert.Nil(t, exec.saving.bgsave)
+}
+
+func TestSaveRulesRetryAfterFailure(t *testing.T) {
+	exec, _ := snapshottingExecutor(t, "1 1")
+	exec.saving.path = filepath.Join(t.TempDir(), "missing", "dump.rdb")
+	exec.saving.lastSave = time.Now().Add(-time.Minute)
+	exec.Execute(makeCommand("SET", "a", "1"))
+
+	exec.Cron()
+	finishBgsave(t, exec)
+	assert.Error(t, exec.saving.lastBgsaveErr)
+
+	// a failed save is not retried at once
+	exec.Cron()
+	assert.Nil(t, exec.saving.bgsave)
+	exec.saving.lastBgsaveTry = time.Now().Add(-bgsaveRetryDelay)
+	exec.Cron()
+	assert.NotNil(t, exec.saving.bgsave)
+	finishBgsave(t, exec)
+}
+
+func TestCloseSaves(t *testing.T) {
+	exec, path := snapshottingExecutor(t, rdb.DefaultSaveRules)
+	exec.Execute(makeCommand("SET", "a", "1"))
+	require.NoError(t, exec.Close())
+	assert.Equal(t, "1", restored(t, path).Execute(makeCommand("GET", "a")).Str)
+
+	// without save rules nothing is saved at shutdown
+	exec, path = snapshottingExecutor(t, "")
+	exec.Execute(makeCommand("SET", "a", "1"))
+	require.NoError(t, exec.Close())
+	assert.NoFileExists(t, path)
+}
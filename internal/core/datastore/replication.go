@@ -0,0 +1,684 @@
+package datastore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/core/replication"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// replicationPingInterval is how often a master pings its replicas
+// through the stream, so they can tell it is alive, as Redis's default
+// repl-ping-replica-period.
+const replicationPingInterval = 10 * time.Second
+
+const readOnlyError = "READONLY You can't write against a read only replica."
+
+// replicationState is the executor's side of replication, both as a
+// master to its replicas and as a replica of its master.
+type replicationState struct {
+	// id names the history of the dataset and offset is how far the
+	// replication stream has got in it. id2 is the ID the history had
+	// before this server was promoted, which holds up to id2Offset, so
+	// replicas of its old master can continue from it.
+	id        string
+	id2       string
+	offset    int64
+	id2Offset int64
+	// backlog is created when the first replica connects, or the first
+	// write is logged to the append-only file, and the offset only moves
+	// from then on, as in Redis
+	backlog     *replication.Backlog
+	backlogSize int
+	// pending is the stream written since it was last sent to replicas
+	pending []byte
+	// getAck asks the replicas to acknowledge at once on the next flush,
+	// for a client blocked in WAIT or WAITAOF
+	getAck   bool
+	replicas map[chan common.RespValue]*replica
+	fullSync *fullSync
+	lastPing time.Time
+	// lastCheck is when the replicas were last kept alive and checked for
+	// timeouts
+	lastCheck     time.Time
+	listeningPort int
+
+	// master is set on a replica
+	master   *masterLink
+	readOnly bool
+}
+
+func newReplicationState() replicationState {
+	return replicationState{
+		id:          replication.NewID(),
+		backlogSize: replication.DefaultBacklogSize,
+		replicas:    make(map[chan common.RespValue]*replica),
+		readOnly:    true,
+	}
+}
+
+// masterLink is a replica's link to its master.
+type masterLink struct {
+	addr  string
+	link  *replication.Link
+	state replication.State
+	// session stands for the master as the client of the commands it
+	// sends, for the slow log and monitors
+	session *Session
+}
+
+type replicaState int
+
+const (
+	// replicaHandshake is a connection that has sent REPLCONF but not
+	// PSYNC yet
+	replicaHandshake replicaState = iota
+	replicaWaitSnapshot
+	replicaOnline
+)
+
+// replica is a connection a replica has made to this server.
+type replica struct {
+	responseChan  chan common.RespValue
+	session       *Session
+	state         replicaState
+	listeningPort int
+	ackOffset     int64
+	// aofAckOffset is how much of the stream the replica has synced to
+	// its append-only file, or -1 if it has none
+	aofAckOffset int64
+	ackTime      time.Time
+}
+
+// fullSync is a snapshot being written for replicas that could not
+// continue from the backlog. A goroutine of its own writes it to data,
+// then sends the outcome on done.
+type fullSync struct {
+	snapshot *keyspace.Snapshot
+	offset   int64
+	data     []byte
+	done     chan error
+	cancel   chan struct{}
+	// stream is what was written after the snapshot was taken, which the
+	// waiting replicas are sent once they have it
+	stream []byte
+}
+
+// SetReplication configures replication. listeningPort is the port
+// clients connect to, which a replica tells its master; backlogSize is
+// the size of the backlog kept for replicas that reconnect; and readOnly
+// makes a replica refuse writes from its clients. Call it before the
+// executor goroutine starts.
+func (e *Executor) SetReplication(listeningPort, backlogSize int, readOnly bool) {
+	e.replication.listeningPort = listeningPort
+	e.replication.backlogSize = backlogSize
+	e.replication.readOnly = readOnly
+}
+
+// ReplicaOf makes the executor a replica of the master at addr, as
+// REPLICAOF does. Call it before the executor goroutine starts.
+func (e *Executor) ReplicaOf(addr string) {
+	e.replicaOf(addr)
+}
+
+// readOnly reports whether clients' writes are refused.
+func (e *Executor) readOnly() bool {
+	return e.replication.master != nil && e.replication.readOnly
+}
+
+// handleReplicaOf implements REPLICAOF host port, and REPLICAOF NO ONE,
+// which turns a replica back into a master.
+func (e *Executor) handleReplicaOf(command commands.Command) common.RespValue {
+	if len(command.Args) != 2 {
+		return wrongArity(command.Name)
+	}
+	if e.cluster.node != nil {
+		return errorValue("ERR REPLICAOF not allowed in cluster mode.")
+	}
+	host, port := command.Args[0], command.Args[1]
+	if strings.EqualFold(host, "no") && strings.EqualFold(port, "one") {
+		if e.replication.master != nil {
+			e.promote()
+		}
+		e.noteReplicaOf("")
+		return okValue()
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return errorValue("ERR Invalid master port")
+	}
+	addr := net.JoinHostPort(host, port)
+	if m := e.replication.master; m != nil && m.addr == addr {
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "OK Already connected to specified master"}
+	}
+	e.replicaOf(addr)
+	e.noteReplicaOf(addr)
+	return okValue()
+}
+
+// replicaOf connects to a new master. It first tries to continue from
+// its own position, which works when the new master was a replica of the
+// same master, as after a failover.
+func (e *Executor) replicaOf(addr string) {
+	if m := e.replication.master; m != nil {
+		m.link.Close()
+	}
+	// our replicas resync with us, as what we hold is about to change
+	e.disconnectReplicas()
+	e.unblockWaitingClients()
+	m := &masterLink{addr: addr, session: &Session{Addr: addr}}
+	m.link = replication.Connect(addr, e.replication.listeningPort, &masterHandler{e: e, m: m})
+	e.replication.master = m
+	slog.Info("replicating from a new master", "master", addr)
+}
+
+// promote stops replicating. The history so far keeps its ID as the
+// second ID, so other replicas of the old master can continue from this
+// server once they are pointed at it.
+func (e *Executor) promote() {
+	e.replication.master.link.Close()
+	e.replication.master = nil
+	e.replication.id2 = e.replication.id
+	e.replication.id2Offset = e.replication.offset + 1
+	e.replication.id = replication.NewID()
+	e.disconnectReplicas()
+	slog.Info("promoted to master", "replid", e.replication.id, "replid2", e.replication.id2)
+}
+
+// handleReplConf implements REPLCONF, which a replica sends to describe
+// itself before PSYNC, and then to acknowledge the offset it has reached.
+// An acknowledgement gets no reply.
+func (e *Executor) handleReplConf(value Value) {
+	args := value.Command.Args
+	if len(args)%2 != 0 {
+		e.reply(value, errorValue("ERR syntax error"))
+		return
+	}
+	acked := false
+	for i := 0; i < len(args); i += 2 {
+		switch option := strings.ToLower(args[i]); option {
+		case "listening-port":
+			port, err := strconv.Atoi(args[i+1])
+			if err != nil || port < 0 || port > 65535 {
+				e.reply(value, errorValue("ERR value is out of range"))
+				return
+			}
+			e.replicaFor(value).listeningPort = port
+		case "capa", "ip-address":
+		case "ack", "fack":
+			// ACK offset, which may be followed by FACK and the offset the
+			// replica's append-only file has synced
+			offset, err := strconv.ParseInt(args[i+1], 10, 64)
+			if r := e.replication.replicas[value.ResponseChan]; err == nil && r != nil && r.state == replicaOnline {
+				if option == "ack" {
+					r.ackOffset = max(r.ackOffset, offset)
+					r.ackTime = time.Now()
+				} else {
+					r.aofAckOffset = max(r.aofAckOffset, offset)
+				}
+			}
+			acked = true
+		case "getack":
+			// only a master asks, on the stream
+			return
+		default:
+			e.reply(value, errorValue(fmt.Sprintf("ERR Unrecognized REPLCONF option: %s", args[i])))
+			return
+		}
+	}
+	if acked {
+		e.serveWaitingClients()
+		return
+	}
+	e.reply(value, okValue())
+}
+
+// handlePsync implements PSYNC replid offset. The replica continues from
+// offset if its history is this server's and the backlog still holds
+// everything since; otherwise it is sent a snapshot and the stream from
+// where the snapshot was taken.
+func (e *Executor) handlePsync(value Value) {
+	if m := e.replication.master; m != nil && m.state != replication.StateConnected {
+		e.reply(value, errorValue("NOMASTERLINK Can't SYNC while not connected with my master"))
+		return
+	}
+	offset, err := strconv.ParseInt(value.Command.Args[1], 10, 64)
+	if err != nil {
+		e.reply(value, errorValue("ERR value is not an integer or out of range"))
+		return
+	}
+	// the stream so far goes to the replicas already here, not this one
+	e.flushReplication()
+	r := e.replicaFor(value)
+	r.session.setReplica(true)
+	if e.continueReplica(r, value.Command.Args[0], offset) {
+		return
+	}
+	e.fullResync(r)
+}
+
+// replicaFor returns the replica state of the connection value came from.
+func (e *Executor) replicaFor(value Value) *replica {
+	r, exists := e.replication.replicas[value.ResponseChan]
+	if !exists {
+		r = &replica{responseChan: value.ResponseChan, session: value.Session, aofAckOffset: -1}
+		e.replication.replicas[value.ResponseChan] = r
+	}
+	return r
+}
+
+func (e *Executor) continueReplica(r *replica, id string, offset int64) bool {
+	repl := &e.replication
+	if repl.backlog == nil || (id != repl.id && (id != repl.id2 || offset > repl.id2Offset)) {
+		return false
+	}
+	missed, ok := repl.backlog.Since(offset)
+	if !ok {
+		return false
+	}
+	if e.sendToReplica(r, "+CONTINUE "+repl.id+"\r\n"+string(missed)) {
+		r.state = replicaOnline
+		r.ackTime = time.Now()
+		slog.Info("replica continued from the backlog", "offset", offset, "bytes", len(missed))
+	}
+	return true
+}
+
+// fullResync has a replica wait for a snapshot, sharing one already being
+// written if there is one.
+func (e *Executor) fullResync(r *replica) {
+	if e.replication.backlog == nil {
+		e.replication.backlog = replication.NewBacklog(e.replication.backlogSize, e.replication.offset)
+	}
+	fs := e.replication.fullSync
+	if fs == nil {
+		fs = e.startFullSync()
+	}
+	if e.sendToReplica(r, fmt.Sprintf("+FULLRESYNC %s %d\r\n", e.replication.id, fs.offset)) {
+		r.state = replicaWaitSnapshot
+	}
+}
+
+func (e *Executor) startFullSync() *fullSync {
+	fs := &fullSync{
+		snapshot: e.dataStore.Snapshot(),
+		offset:   e.replication.offset,
+		done:     make(chan error, 1),
+		cancel:   make(chan struct{}),
+	}
+	e.replication.fullSync = fs
+	go func() {
+		var buf bytes.Buffer
+		err := rdb.Write(&buf, fs.snapshot, fs.cancel)
+		fs.data = buf.Bytes()
+		fs.done <- err
+	}()
+	slog.Info("writing a snapshot for replicas", "offset", fs.offset)
+	return fs
+}
+
+// finishFullSync sends the snapshot to the replicas waiting for it,
+// followed by the stream since, and brings them online.
+func (e *Executor) finishFullSync(fs *fullSync, err error) {
+	fs.snapshot.Release()
+	e.replication.fullSync = nil
+	if err != nil {
+		slog.Error("failed to write the snapshot for replicas", "error", err)
+	}
+	payload := "$" + strconv.Itoa(len(fs.data)) + "\r\n" + string(fs.data) + string(fs.stream)
+	for _, r := range e.replication.replicas {
+		if r.state != replicaWaitSnapshot {
+			continue
+		}
+		if err != nil {
+			e.dropClient(r.responseChan, r.session)
+		} else if e.sendToReplica(r, payload) {
+			r.state = replicaOnline
+			r.ackTime = time.Now()
+			slog.Info("sent a replica the snapshot", "bytes", len(fs.data))
+		}
+	}
+}
+
+// cancelFullSync abandons a snapshot being written for replicas.
+func (e *Executor) cancelFullSync() {
+	if fs := e.replication.fullSync; fs != nil {
+		close(fs.cancel)
+		<-fs.done
+		fs.snapshot.Release()
+		e.replication.fullSync = nil
+	}
+}
+
+// disconnectReplicas drops every replica, which has them reconnect and
+// find out what has changed.
+func (e *Executor) disconnectReplicas() {
+	for _, r := range e.replication.replicas {
+		if r.state != replicaHandshake {
+			e.dropClient(r.responseChan, r.session)
+		}
+	}
+	e.cancelFullSync()
+}
+
+// feedReplication adds commands to the replication stream. It starts the
+// stream if need be, as it is only called once there is a replica or an
+// append-only file, and WAITAOF measures what the file has synced by it.
+func (e *Executor) feedReplication(cmds ...commands.Command) {
+	if e.replication.backlog == nil {
+		e.replication.backlog = replication.NewBacklog(e.replication.backlogSize, e.replication.offset)
+	}
+	for _, command := range cmds {
+		e.writeReplication(parser.EncodeCommand(command))
+	}
+}
+
+func (e *Executor) writeReplication(p []byte) {
+	e.replication.backlog.Append(p)
+	e.replication.offset += int64(len(p))
+	e.replication.pending = append(e.replication.pending, p...)
+}
+
+// flushReplication sends the stream written since the last flush to the
+// replicas, and keeps it for those waiting for a snapshot.
+func (e *Executor) flushReplication() {
+	if e.replication.getAck {
+		e.replication.getAck = false
+		e.feedReplication(commands.Command{Name: "REPLCONF", Args: []string{"GETACK", "*"}})
+	}
+	pending := e.replication.pending
+	if len(pending) == 0 {
+		return
+	}
+	e.replication.pending = pending[:0]
+	if fs := e.replication.fullSync; fs != nil {
+		fs.stream = append(fs.stream, pending...)
+	}
+	stream := string(pending)
+	for _, r := range e.replication.replicas {
+		if r.state == replicaOnline {
+			e.sendToReplica(r, stream)
+		}
+	}
+}
+
+// sendToReplica queues part of the stream for a replica. A replica that
+// cannot keep up is dropped rather than sent a stream with a gap in it;
+// it will continue from the backlog when it reconnects.
+func (e *Executor) sendToReplica(r *replica, stream string) bool {
+	select {
+	case r.responseChan <- common.RespValue{Type: enums.RawRespType, Str: stream}:
+		return true
+	default:
+		slog.Warn("dropping a replica that cannot keep up with the stream")
+		e.dropClient(r.responseChan, r.session)
+		return false
+	}
+}
+
+// cronReplication completes a snapshot written for replicas, pings them
+// and keeps those waiting for a snapshot alive, and drops any that have
+// stopped acknowledging.
+func (e *Executor) cronReplication() {
+	if fs := e.replication.fullSync; fs != nil {
+		select {
+		case err := <-fs.done:
+			e.finishFullSync(fs, err)
+		default:
+		}
+	}
+	now := time.Now()
+	if e.replication.master == nil && len(e.replication.replicas) > 0 && now.Sub(e.replication.lastPing) >= replicationPingInterval {
+		e.replication.lastPing = now
+		e.feedReplication(commands.Command{Name: "PING"})
+	}
+	if now.Sub(e.replication.lastCheck) >= time.Second {
+		e.replication.lastCheck = now
+		for _, r := range e.replication.replicas {
+			switch {
+			case r.state == replicaWaitSnapshot:
+				e.sendToReplica(r, "\n")
+			case r.state == replicaOnline && now.Sub(r.ackTime) > replication.Timeout:
+				slog.Warn("dropping a replica that has stopped acknowledging")
+				e.dropClient(r.responseChan, r.session)
+			}
+		}
+	}
+	e.flushReplication()
+}
+
+// applyFromMaster runs commands from the master's stream, which writes
+// them to the append-only file but passes the stream on to this server's
+// own replicas as the master sent it.
+func (e *Executor) applyFromMaster(cmds []commands.Command, raw []byte) {
+	getAck := false
+	switch {
+	case len(cmds) > 1:
+		// MULTI, the transaction's writes and EXEC
+		e.inExec = true
+		for _, command := range cmds[1 : len(cmds)-1] {
+			e.Execute(command)
+		}
+		e.propagateTransaction()
+	case enums.StringToCommandName(cmds[0].Name) == enums.ReplConfCommandName:
+		getAck = len(cmds[0].Args) > 0 && strings.EqualFold(cmds[0].Args[0], "GETACK")
+	default:
+		e.Execute(cmds[0])
+	}
+	e.writeReplication(raw)
+	if getAck {
+		e.ackMaster()
+	}
+}
+
+// ackMaster acknowledges the stream to the master at once, rather than
+// waiting for the link's next acknowledgement.
+func (e *Executor) ackMaster() {
+	if m := e.replication.master; m != nil && m.state == replication.StateConnected {
+		go m.link.Ack(e.replication.offset, e.fsyncedOffset())
+	}
+}
+
+// replaceDataset swaps in the dataset loaded from a master's snapshot.
+// As a FLUSHALL would, it fails every transaction watching a key, and it
+// rewrites the append-only file, which held the old dataset.
+func (e *Executor) replaceDataset(store *keyspace.Keyspace) {
+	store.SetAddHook(e.signalKeyAsReady)
+	store.SetModifyHook(e.touchWatchedKey)
+	if e.cluster.node != nil {
+		store.IndexSlots()
+	}
+	// the cron only copies snapshots of the dataset in use
+	e.dataStore.CompleteSnapshots()
+	e.dataStore = store
+	for _, tx := range e.transactions.clients {
+		if len(tx.watched) > 0 {
+			tx.dirtyCAS = true
+		}
+	}
+	// everything loaded counts as unsaved
+	e.saving.dirty = 0
+	e.disconnectReplicas()
+	if e.aof == nil {
+		return
+	}
+	// nothing in the file is part of the new history, which it only holds
+	// once it has been rewritten
+	e.fsync.offset, e.fsync.marks, e.fsync.held = -1, nil, true
+	if e.aof.RewriteInProgress() {
+		e.rewriteScheduled = true
+	} else {
+		e.startRewrite()
+	}
+}
+
+// handleRole implements ROLE.
+func (e *Executor) handleRole(command commands.Command) common.RespValue {
+	if len(command.Args) != 0 {
+		return wrongArity(command.Name)
+	}
+	if m := e.replication.master; m != nil {
+		host, port, _ := net.SplitHostPort(m.addr)
+		n, _ := strconv.Atoi(port)
+		return common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+			bulkElement("slave"), bulkElement(host), intElement(n), bulkElement(m.state.String()),
+			{Type: enums.IntRespType, Int: e.replication.offset},
+		}}
+	}
+	replicas := make([]*common.RespValue, 0, len(e.replication.replicas))
+	for _, r := range e.replication.replicas {
+		if r.state == replicaHandshake {
+			continue
+		}
+		host := ""
+		if r.session != nil {
+			host, _, _ = net.SplitHostPort(r.session.Addr)
+		}
+		replicas = append(replicas, &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+			bulkElement(host), bulkElement(strconv.Itoa(r.listeningPort)), bulkElement(strconv.FormatInt(r.ackOffset, 10)),
+		}})
+	}
+	slices.SortFunc(replicas, func(a, b *common.RespValue) int {
+		return strings.Compare(a.Array[1].Str, b.Array[1].Str)
+	})
+	return common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+		bulkElement("master"),
+		{Type: enums.IntRespType, Int: e.replication.offset},
+		{Type: enums.ArrayRespType, Array: replicas},
+	}}
+}
+
+// closeReplication disconnects from the master, if there is one, and
+// abandons any snapshot being written for replicas.
+func (e *Executor) closeReplication() {
+	if m := e.replication.master; m != nil {
+		m.link.Close()
+	}
+	e.cancelFullSync()
+}
+
+var errReplicationStopped = errors.New("replication from this master has stopped")
+
+// masterHandler applies what a replica's link reads from the master on
+// the executor goroutine. Anything from a master that has since been
+// replaced is ignored.
+type masterHandler struct {
+	e *Executor
+	m *masterLink
+}
+
+func (h *masterHandler) Progress() (int64, int64) {
+	var offset, fsynced int64
+	if !h.wait(func(e *Executor) {
+		offset, fsynced = e.replication.offset, e.fsyncedOffset()
+	}) {
+		return 0, -1
+	}
+	return offset, fsynced
+}
+
+func (h *masterHandler) Position() (string, int64) {
+	var id string
+	var offset int64
+	if !h.wait(func(e *Executor) {
+		id, offset = e.replication.id, e.replication.offset
+	}) {
+		return "", 0
+	}
+	return id, offset
+}
+
+// FullSync loads the snapshot on the link's goroutine, so the executor
+// goes on serving the old dataset until the new one is ready.
+func (h *masterHandler) FullSync(id string, offset int64, r io.Reader) error {
+	start := time.Now()
+	store := keyspace.New()
+	loaded, err := rdb.Read(r, store)
+	if err != nil {
+		return err
+	}
+	if !h.wait(func(e *Executor) {
+		e.replaceDataset(store)
+		e.replication.id, e.replication.offset = id, offset
+		e.replication.id2, e.replication.id2Offset = "", 0
+		e.replication.backlog = replication.NewBacklog(e.replication.backlogSize, offset)
+		e.replication.pending = nil
+	}) {
+		return errReplicationStopped
+	}
+	slog.Info("loaded the master's snapshot", "keys", loaded, "duration", time.Since(start))
+	return nil
+}
+
+func (h *masterHandler) Continue(id string) {
+	h.wait(func(e *Executor) {
+		if id != e.replication.id {
+			// the master has been promoted since
+			e.replication.id2, e.replication.id2Offset = e.replication.id, e.replication.offset+1
+			e.replication.id = id
+			e.disconnectReplicas()
+		}
+		if e.replication.backlog == nil {
+			e.replication.backlog = replication.NewBacklog(e.replication.backlogSize, e.replication.offset)
+		}
+	})
+}
+
+func (h *masterHandler) Apply(cmds []commands.Command, raw []byte) {
+	h.run(func(e *Executor) {
+		e.client = h.m.session
+		e.applyFromMaster(cmds, raw)
+		e.client = nil
+	})
+}
+
+func (h *masterHandler) SetState(state replication.State) {
+	h.run(func(e *Executor) {
+		h.m.state = state
+	})
+}
+
+// run has fn run on the executor goroutine, unless the master has been
+// replaced by then.
+func (h *masterHandler) run(fn func(*Executor)) {
+	h.e.Submit(Value{run: func(e *Executor) {
+		if e.replication.master == h.m {
+			fn(e)
+		}
+	}})
+}
+
+// wait is run that waits for fn to have run. It reports false if fn did
+// not run, because the master was replaced or the executor stopped.
+func (h *masterHandler) wait(fn func(*Executor)) bool {
+	ran := make(chan bool, 1)
+	submitted := h.e.Submit(Value{run: func(e *Executor) {
+		current := e.replication.master == h.m
+		if current {
+			fn(e)
+		}
+		ran <- current
+	}})
+	if !submitted {
+		return false
+	}
+	select {
+	case ok := <-ran:
+		return ok
+	case <-h.e.Done():
+		return false
+	}
+}
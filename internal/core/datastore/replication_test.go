@@ -0,0 +1,336 @@
+package datastore
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/core/rdb"
+	"github.com/suryansh0301/Mnemo/internal/core/replication"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// stream encodes commands as they appear in the replication stream.
+func stream(cmds ...commands.Command) string {
+	var out []byte
+	for _, command := range cmds {
+		out = append(out, parser.EncodeCommand(command)...)
+	}
+	return string(out)
+}
+
+// rawReply returns the next reply, which must be part of the stream.
+func (c *testClient) rawReply(t *testing.T) string {
+	t.Helper()
+	reply := c.reply(t)
+	require.Equal(t, enums.RawRespType, reply.Type)
+	return reply.Str
+}
+
+// syncReplica has client sync as a replica, and returns the replication
+// ID, the offset it is at and the dataset it was sent.
+func syncReplica(t *testing.T, exec *Executor, client *testClient) (string, int64, *keyspace.Keyspace) {
+	t.Helper()
+	// no PING while the test looks at the stream
+	exec.replication.lastPing = time.Now()
+	client.do(exec, "PSYNC", "?", "-1")
+	fields := strings.Fields(client.rawReply(t))
+	require.Len(t, fields, 3)
+	require.Equal(t, "+FULLRESYNC", fields[0])
+	offset, err := strconv.ParseInt(fields[2], 10, 64)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		exec.Cron()
+		return exec.replication.fullSync == nil
+	}, 5*time.Second, time.Millisecond)
+	var payload string
+	for payload == "" || payload == "\n" {
+		payload = client.rawReply(t)
+	}
+	header, rest, _ := strings.Cut(payload, "\r\n")
+	size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+	require.NoError(t, err)
+	store := keyspace.New()
+	_, err = rdb.Read(strings.NewReader(rest[:size]), store)
+	require.NoError(t, err)
+	require.Equal(t, "", rest[size:], "no stream was expected after the snapshot")
+	return fields[1], offset, store
+}
+
+// replicaExecutor returns an executor replicating from a master that is
+// not there. The tests stand in for it.
+func replicaExecutor(t *testing.T) *Executor {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	exec := NewExecutor()
+	exec.ReplicaOf(addr)
+	t.Cleanup(func() {
+		exec.Close()
+		exec.Stop()
+	})
+	return exec
+}
+
+func TestFullResync(t *testing.T) {
+	exec := NewExecutor()
+	exec.Execute(makeCommand("SET", "a", "1"))
+	replica, writer := newTestClient(), newTestClient()
+	// no PING while the test looks at the stream
+	exec.replication.lastPing = time.Now()
+
+	replica.do(exec, "PSYNC", "?", "-1")
+	assert.Equal(t, "+FULLRESYNC "+exec.replication.id+" 0\r\n", replica.rawReply(t))
+	assert.True(t, replica.session.Waiting())
+	// written after the snapshot was taken, so sent after it
+	writer.do(exec, "SET", "b", "2")
+	replica.assertNoReply(t)
+
+	require.Eventually(t, func() bool {
+		exec.Cron()
+		return exec.replication.fullSync == nil
+	}, 5*time.Second, time.Millisecond)
+	payload := replica.rawReply(t)
+	for payload == "\n" {
+		payload = replica.rawReply(t)
+	}
+	header, rest, _ := strings.Cut(payload, "\r\n")
+	size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+	require.NoError(t, err)
+	store := keyspace.New()
+	_, err = rdb.Read(strings.NewReader(rest[:size]), store)
+	require.NoError(t, err)
+	assert.Equal(t, "1", store.LookupRead("a").Str)
+	assert.Nil(t, store.LookupRead("b"))
+	assert.Equal(t, stream(makeCommand("SET", "b", "2")), rest[size:])
+
+	writer.do(exec, "SET", "c", "3")
+	assert.Equal(t, stream(makeCommand("SET", "c", "3")), replica.rawReply(t))
+	assert.Equal(t, int64(2*len(stream(makeCommand("SET", "b", "2")))), exec.replication.offset)
+}
+
+func TestPartialResync(t *testing.T) {
+	exec := NewExecutor()
+	first := newTestClient()
+	id, offset, _ := syncReplica(t, exec, first)
+	writer := newTestClient()
+	writer.do(exec, "SET", "a", "1")
+	writer.do(exec, "EXPIRE", "a", "100")
+	missed := first.rawReply(t) + first.rawReply(t)
+	assert.True(t, strings.HasPrefix(missed, stream(makeCommand("SET", "a", "1"))))
+	assert.Contains(t, missed, "PEXPIREAT")
+
+	tests := []struct {
+		name     string
+		id       string
+		offset   int64
+		expected string
+	}{
+		{"from where it was", id, offset + 1, "+CONTINUE " + id + "\r\n" + missed},
+		{"caught up", id, exec.replication.offset + 1, "+CONTINUE " + id + "\r\n"},
+		{"unknown history", "0123", offset + 1, "+FULLRESYNC"},
+		{"ahead of the master", id, exec.replication.offset + 2, "+FULLRESYNC"},
+	}
+	continued := make([]*testClient, 0, len(tests))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replica := newTestClient()
+			replica.do(exec, "PSYNC", tt.id, strconv.FormatInt(tt.offset, 10))
+			reply := replica.rawReply(t)
+			if strings.HasPrefix(tt.expected, "+FULLRESYNC") {
+				assert.True(t, strings.HasPrefix(reply, tt.expected), reply)
+				return
+			}
+			assert.Equal(t, tt.expected, reply)
+			continued = append(continued, replica)
+		})
+	}
+
+	// replicas that continued are sent the stream from then on
+	writer.do(exec, "INCR", "n")
+	for _, replica := range continued {
+		assert.Equal(t, stream(makeCommand("INCR", "n")), replica.rawReply(t))
+	}
+}
+
+func TestReplConf(t *testing.T) {
+	exec := NewExecutor()
+	replica := newTestClient()
+	replica.session.Addr = "10.0.0.7:53412"
+
+	replica.do(exec, "REPLCONF", "listening-port", "6380", "capa", "psync2")
+	assert.Equal(t, "OK", replica.reply(t).Str)
+	replica.do(exec, "REPLCONF", "listening-port")
+	assert.Equal(t, "ERR syntax error", replica.reply(t).Str)
+	replica.do(exec, "REPLCONF", "rdb-only", "1")
+	assert.Equal(t, "ERR Unrecognized REPLCONF option: rdb-only", replica.reply(t).Str)
+
+	syncReplica(t, exec, replica)
+	replica.do(exec, "REPLCONF", "ACK", "30")
+	replica.assertNoReply(t)
+	role := exec.Execute(makeCommand("ROLE"))
+	require.Len(t, role.Array, 3)
+	assert.Equal(t, "master", role.Array[0].Str)
+	require.Len(t, role.Array[2].Array, 1)
+	assert.Equal(t, []string{"10.0.0.7", "6380", "30"}, replyStrings(*role.Array[2].Array[0]))
+
+	// PSYNC and REPLCONF cannot be queued
+	client := newTestClient()
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "PSYNC", "?", "-1")
+	assert.Equal(t, "ERR Command not allowed inside a transaction", client.reply(t).Str)
+}
+
+func TestReplicaDisconnect(t *testing.T) {
+	exec := NewExecutor()
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	replica.disconnect(exec)
+	replica.assertClosed(t)
+	assert.Empty(t, exec.replication.replicas)
+	exec.Execute(makeCommand("SET", "a", "1"))
+	exec.Cron()
+}
+
+func TestSlowReplicaIsDropped(t *testing.T) {
+	exec := NewExecutor()
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	writer := newTestClient()
+	for i := 0; i <= cap(replica.responses); i++ {
+		writer.do(exec, "SET", "a", strconv.Itoa(i))
+	}
+	assert.True(t, replica.killed)
+	assert.Empty(t, exec.replication.replicas)
+}
+
+func TestReadOnlyReplica(t *testing.T) {
+	exec := replicaExecutor(t)
+	client := newTestClient()
+
+	client.do(exec, "SET", "a", "1")
+	assert.Equal(t, readOnlyError, client.reply(t).Str)
+	client.do(exec, "GET", "a")
+	assert.True(t, client.reply(t).IsNull)
+
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "GET", "a")
+	assert.Equal(t, "QUEUED", client.reply(t).Str)
+	client.do(exec, "DEL", "a")
+	assert.Equal(t, readOnlyError, client.reply(t).Str)
+	client.do(exec, "EXEC")
+	assert.Equal(t, "EXECABORT Transaction discarded because of previous errors.", client.reply(t).Str)
+
+	role := exec.Execute(makeCommand("ROLE"))
+	host, port, _ := net.SplitHostPort(exec.replication.master.addr)
+	assert.Equal(t, []string{"slave", host, "", "connect", ""}, replyStrings(role))
+	assert.Equal(t, port, strconv.FormatInt(role.Array[2].Int, 10))
+
+	replica := newTestClient()
+	replica.do(exec, "PSYNC", "?", "-1")
+	assert.Equal(t, "NOMASTERLINK Can't SYNC while not connected with my master", replica.reply(t).Str)
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", "NO", "ONE")).Str)
+	client.do(exec, "SET", "a", "1")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	assert.Equal(t, "master", exec.Execute(makeCommand("ROLE")).Array[0].Str)
+}
+
+func TestBecomingReplicaAbortsQueuedWrites(t *testing.T) {
+	exec := NewExecutor()
+	t.Cleanup(func() { exec.Close() })
+	client := newTestClient()
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", host, port)).Str)
+	assert.Equal(t, "OK Already connected to specified master", exec.Execute(makeCommand("SLAVEOF", host, port)).Str)
+
+	client.do(exec, "EXEC")
+	assert.Equal(t, "EXECABORT Transaction discarded because of: "+readOnlyError, client.reply(t).Str)
+}
+
+func TestReplicaOfErrors(t *testing.T) {
+	exec := NewExecutor()
+	assert.Equal(t, "ERR Invalid master port", exec.Execute(makeCommand("REPLICAOF", "localhost", "port")).Str)
+	assert.Equal(t, "ERR Invalid master port", exec.Execute(makeCommand("REPLICAOF", "localhost", "70000")).Str)
+	// already a master
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", "NO", "ONE")).Str)
+	assert.Nil(t, exec.replication.master)
+	assert.Empty(t, exec.replication.id2)
+}
+
+func TestApplyFromMaster(t *testing.T) {
+	exec := replicaExecutor(t)
+	exec.replication.master.state = replication.StateConnected
+	exec.replication.backlog = replication.NewBacklog(replication.DefaultBacklogSize, 0)
+	subReplica := newTestClient()
+	_, offset, _ := syncReplica(t, exec, subReplica)
+	assert.Equal(t, int64(0), offset)
+
+	apply := func(cmds ...commands.Command) string {
+		raw := stream(cmds...)
+		exec.Handle(Value{run: func(e *Executor) {
+			e.applyFromMaster(cmds, []byte(raw))
+		}})
+		// the link's own values wait in ExecutorChan, so the stream is
+		// only passed on by cron
+		exec.Cron()
+		return raw
+	}
+	// writes from the master are applied though clients cannot write
+	set := apply(makeCommand("SET", "a", "1"))
+	tx := apply(makeCommand("MULTI"), makeCommand("INCR", "n"), makeCommand("INCR", "n"), makeCommand("EXEC"))
+	ping := apply(makeCommand("PING"))
+	getAck := apply(makeCommand("REPLCONF", "GETACK", "*"))
+
+	assert.Equal(t, "1", exec.Execute(makeCommand("GET", "a")).Str)
+	assert.Equal(t, "2", exec.Execute(makeCommand("GET", "n")).Str)
+	assert.Equal(t, int64(len(set+tx+ping+getAck)), exec.replication.offset)
+	// the stream is passed on to this replica's own replicas as it came
+	assert.Equal(t, set, subReplica.rawReply(t))
+	assert.Equal(t, tx, subReplica.rawReply(t))
+	assert.Equal(t, ping, subReplica.rawReply(t))
+	assert.Equal(t, getAck, subReplica.rawReply(t))
+}
+
+func TestPromoteKeepsHistory(t *testing.T) {
+	exec := replicaExecutor(t)
+	exec.replication.master.state = replication.StateConnected
+	exec.replication.backlog = replication.NewBacklog(replication.DefaultBacklogSize, 0)
+	exec.Handle(Value{run: func(e *Executor) {
+		e.applyFromMaster([]commands.Command{makeCommand("SET", "a", "1")}, []byte(stream(makeCommand("SET", "a", "1"))))
+	}})
+	oldID, offset := exec.replication.id, exec.replication.offset
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("REPLICAOF", "NO", "ONE")).Str)
+	assert.NotEqual(t, oldID, exec.replication.id)
+	exec.Execute(makeCommand("SET", "b", "2"))
+
+	// a replica of the old master continues from the promoted one
+	replica := newTestClient()
+	replica.do(exec, "PSYNC", oldID, strconv.FormatInt(offset+1, 10))
+	assert.Equal(t, "+CONTINUE "+exec.replication.id+"\r\n"+stream(makeCommand("SET", "b", "2")), replica.rawReply(t))
+	// but not from beyond what the old master had sent
+	late := newTestClient()
+	late.do(exec, "PSYNC", oldID, strconv.FormatInt(offset+2, 10))
+	assert.True(t, strings.HasPrefix(late.rawReply(t), "+FULLRESYNC"))
+}
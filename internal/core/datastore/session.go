@@ -0,0 +1,122 @@
+package datastore
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+)
+
+// Session is the state a connection shares with the executor. The
+// connection creates one and sends it with every Value. The executor calls
+// Kill and keeps the flags current, so the connection can tell whether it
+// is expected to sit silent.
+type Session struct {
+	// Kill closes the connection. The executor uses it to drop a client it
+	// can no longer serve, such as a subscriber that cannot keep up.
+	Kill func()
+	// CloseRead stops the connection reading, so that it closes once the
+	// replies already queued for it are written. A client killing itself
+	// with CLIENT KILL goes this way, so it still gets the reply.
+	CloseRead func()
+	// Addr is the client's address, host:port, and LocalAddr the server's
+	// address it connected to.
+	Addr      string
+	LocalAddr string
+
+	// The rest of the client's identity belongs to the executor, which
+	// fills it in when the connection registers; only it touches these.
+	// id is 0 until then.
+	id           int64
+	responseChan chan common.RespValue
+	// name is the name the client has given itself
+	name string
+	// created is when the connection registered, and lastInteraction when
+	// it last sent a command, lastCommand
+	created, lastInteraction time.Time
+	lastCommand              string
+	reply                    replyMode
+	// skipping is set while the command after CLIENT REPLY SKIP runs
+	skipping bool
+	noEvict  bool
+
+	blocked    atomic.Bool
+	subscribed atomic.Bool
+	replica    atomic.Bool
+	monitor    atomic.Bool
+
+	// the sizes of the connection's buffers, which the connection keeps
+	// current for CLIENT LIST
+	queryBuffer     atomic.Int64
+	queryBufferFree atomic.Int64
+	outputBuffer    atomic.Int64
+}
+
+// replyMode is what CLIENT REPLY set.
+type replyMode int
+
+const (
+	replyOn replyMode = iota
+	replyOff
+	// replySkipNext drops the reply to the next command only
+	replySkipNext
+)
+
+// SetQueryBuffer records that the connection holds used bytes of requests
+// not yet parsed, with room for free more.
+func (s *Session) SetQueryBuffer(used, free int) {
+	s.queryBuffer.Store(int64(used))
+	s.queryBufferFree.Store(int64(free))
+}
+
+// SetOutputBuffer records that the connection holds n bytes of replies not
+// yet written to the socket.
+func (s *Session) SetOutputBuffer(n int) {
+	s.outputBuffer.Store(int64(n))
+}
+
+// Waiting reports whether the connection is blocked on a command,
+// subscribed to Pub/Sub messages, a replica's link or a monitor. A waiting
+// client is not idle even if it sends nothing.
+func (s *Session) Waiting() bool {
+	return s.blocked.Load() || s.subscribed.Load() || s.replica.Load() || s.monitor.Load()
+}
+
+func (s *Session) setBlocked(blocked bool) {
+	if s != nil {
+		s.blocked.Store(blocked)
+	}
+}
+
+func (s *Session) setSubscribed(subscribed bool) {
+	if s != nil {
+		s.subscribed.Store(subscribed)
+	}
+}
+
+func (s *Session) setReplica(replica bool) {
+	if s != nil {
+		s.replica.Store(replica)
+	}
+}
+
+func (s *Session) setMonitor(monitor bool) {
+	if s != nil {
+		s.monitor.Store(monitor)
+	}
+}
+
+func (s *Session) kill() {
+	if s != nil && s.Kill != nil {
+		s.Kill()
+	}
+}
+
+// closeRead stops the connection reading, or kills it if it cannot.
+func (s *Session) closeRead() {
+	if s != nil && s.CloseRead != nil {
+		s.CloseRead()
+		return
+	}
+	s.kill()
+}
@@ -0,0 +1,173 @@
+package datastore
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// The slow log's defaults, Redis's: commands that take 10ms or more are
+// logged, and the last 128 of them kept.
+const (
+	defaultSlowlogSlowerThan = 10 * time.Millisecond
+	defaultSlowlogMaxLen     = 128
+)
+
+// slowlogMaxArgs and slowlogMaxString bound how much of a command an entry
+// keeps, as SLOWLOG_ENTRY_MAX_ARGC and SLOWLOG_ENTRY_MAX_STRING do in
+// Redis, so that logging a huge command does not keep it in memory.
+const (
+	slowlogMaxArgs   = 32
+	slowlogMaxString = 128
+)
+
+// slowlogEntry is a command the slow log recorded.
+type slowlogEntry struct {
+	id       int64
+	time     time.Time
+	duration time.Duration
+	// args is the command's name and arguments, truncated
+	args []string
+	// client and name are the address and name of the client that sent
+	// the command, empty if it has none
+	client, name string
+}
+
+// slowlogState is the slow log: the latest commands that took at least
+// slowerThan, in a ring of at most maxLen entries.
+type slowlogState struct {
+	// slowerThan is negative when the slow log is off
+	slowerThan time.Duration
+	maxLen     int
+	// entries is the ring. Once it is full the oldest entry is at start;
+	// until then start is 0.
+	entries []slowlogEntry
+	start   int
+	// nextID numbers the entries; unlike them it survives SLOWLOG RESET
+	nextID int64
+}
+
+func newSlowlogState() slowlogState {
+	return slowlogState{slowerThan: defaultSlowlogSlowerThan, maxLen: defaultSlowlogMaxLen}
+}
+
+func (s *slowlogState) add(entry slowlogEntry) {
+	entry.id = s.nextID
+	s.nextID++
+	switch {
+	case s.maxLen == 0:
+	case len(s.entries) < s.maxLen:
+		s.entries = append(s.entries, entry)
+	default:
+		s.entries[s.start] = entry
+		s.start = (s.start + 1) % len(s.entries)
+	}
+}
+
+// newest returns up to count entries, the newest first.
+func (s *slowlogState) newest(count int) []slowlogEntry {
+	count = min(count, len(s.entries))
+	entries := make([]slowlogEntry, count)
+	for i := range entries {
+		entries[i] = s.entries[(s.start+len(s.entries)-1-i)%len(s.entries)]
+	}
+	return entries
+}
+
+// setMaxLen resizes the ring, dropping the oldest entries if it shrinks.
+func (s *slowlogState) setMaxLen(maxLen int) {
+	entries := s.newest(maxLen)
+	slices.Reverse(entries)
+	s.entries, s.start, s.maxLen = entries, 0, maxLen
+}
+
+func (s *slowlogState) reset() {
+	s.entries, s.start = nil, 0
+}
+
+// logSlowCommand adds command to the slow log if it ran for long enough.
+func (e *Executor) logSlowCommand(command commands.Command, duration time.Duration) {
+	if e.slowlog.slowerThan < 0 || duration < e.slowlog.slowerThan {
+		return
+	}
+	entry := slowlogEntry{time: time.Now(), duration: duration, args: slowlogArgs(command)}
+	if e.client != nil {
+		entry.client, entry.name = e.client.Addr, e.client.name
+	}
+	e.slowlog.add(entry)
+}
+
+// slowlogArgs returns the name and arguments of command as the slow log
+// keeps them: past slowlogMaxArgs the rest are counted in a last
+// argument, and past slowlogMaxString bytes an argument is cut short.
+func slowlogArgs(command commands.Command) []string {
+	all := append([]string{command.Name}, command.Args...)
+	kept := all
+	if len(all) > slowlogMaxArgs {
+		kept = all[:slowlogMaxArgs-1]
+	}
+	args := make([]string, len(kept), min(len(all), slowlogMaxArgs))
+	for i, arg := range kept {
+		if len(arg) > slowlogMaxString {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxString], len(arg)-slowlogMaxString)
+		}
+		args[i] = arg
+	}
+	if len(all) > slowlogMaxArgs {
+		args = append(args, fmt.Sprintf("... (%d more arguments)", len(all)-len(kept)))
+	}
+	return args
+}
+
+// handleSlowlog implements SLOWLOG GET [count], SLOWLOG LEN and SLOWLOG
+// RESET.
+func (e *Executor) handleSlowlog(command commands.Command) common.RespValue {
+	args := command.Args
+	if len(args) == 0 {
+		return wrongArity(command.Name)
+	}
+	switch subcommand := strings.ToUpper(args[0]); {
+	case subcommand == "GET" && len(args) <= 2:
+		count := 10
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < -1 {
+				return errorValue("ERR count should be greater than or equal to -1")
+			}
+			count = n
+			if n == -1 {
+				count = len(e.slowlog.entries)
+			}
+		}
+		entries := e.slowlog.newest(count)
+		elements := make([]*common.RespValue, len(entries))
+		for i, entry := range entries {
+			args := make([]*common.RespValue, len(entry.args))
+			for j, arg := range entry.args {
+				args[j] = bulkElement(arg)
+			}
+			elements[i] = &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+				{Type: enums.IntRespType, Int: entry.id},
+				{Type: enums.IntRespType, Int: entry.time.Unix()},
+				{Type: enums.IntRespType, Int: entry.duration.Microseconds()},
+				{Type: enums.ArrayRespType, Array: args},
+				bulkElement(entry.client),
+				bulkElement(entry.name),
+			}}
+		}
+		return common.RespValue{Type: enums.ArrayRespType, Array: elements}
+	case subcommand == "LEN" && len(args) == 1:
+		return common.RespValue{Type: enums.IntRespType, Int: int64(len(e.slowlog.entries))}
+	case subcommand == "RESET" && len(args) == 1:
+		e.slowlog.reset()
+		return okValue()
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'. Try SLOWLOG HELP.", args[0]))
+	}
+}
@@ -0,0 +1,118 @@
+package datastore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// slowlogIDs returns the ids of the entries in a SLOWLOG GET reply.
+func slowlogIDs(t *testing.T, reply common.RespValue) []int64 {
+	t.Helper()
+	require.Equal(t, enums.ArrayRespType, reply.Type)
+	ids := make([]int64, len(reply.Array))
+	for i, entry := range reply.Array {
+		require.Len(t, entry.Array, 6)
+		ids[i] = entry.Array[0].Int
+	}
+	return ids
+}
+
+func TestSlowlog(t *testing.T) {
+	exec, _ := configExecutor(t)
+	assert.Equal(t, defaultSlowlogSlowerThan, exec.slowlog.slowerThan)
+	exec.Execute(makeCommand("CONFIG", "SET", "slowlog-log-slower-than", "0", "slowlog-max-len", "3"))
+	client := newTestClient()
+	client.session.Addr = "10.0.0.1:5000"
+	client.session.name = "worker"
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "GET", "a")
+	client.do(exec, "INCR", "a")
+
+	reply := exec.Execute(makeCommand("SLOWLOG", "GET"))
+	// the CONFIG SET that enabled it is logged too, and was pushed out
+	assert.Equal(t, []int64{3, 2, 1}, slowlogIDs(t, reply))
+	newest := reply.Array[0].Array
+	assert.Greater(t, newest[1].Int, int64(0))
+	assert.GreaterOrEqual(t, newest[2].Int, int64(0))
+	assert.Equal(t, []string{"INCR", "a"}, replyStrings(*newest[3]))
+	assert.Equal(t, "10.0.0.1:5000", newest[4].Str)
+	assert.Equal(t, "worker", newest[5].Str)
+
+	// the SLOWLOG commands are logged as well, once they have replied
+	assert.Equal(t, int64(3), exec.Execute(makeCommand("SLOWLOG", "LEN")).Int)
+	assert.Equal(t, []int64{5}, slowlogIDs(t, exec.Execute(makeCommand("SLOWLOG", "GET", "1"))))
+	assert.Equal(t, []int64{6, 5, 4}, slowlogIDs(t, exec.Execute(makeCommand("SLOWLOG", "GET", "-1"))))
+	assert.Equal(t, "ERR count should be greater than or equal to -1", exec.Execute(makeCommand("SLOWLOG", "GET", "-2")).Str)
+	assert.Empty(t, exec.Execute(makeCommand("SLOWLOG", "GET", "0")).Array)
+
+	assert.Equal(t, "OK", exec.Execute(makeCommand("SLOWLOG", "RESET")).Str)
+	assert.Equal(t, []int64{10}, slowlogIDs(t, exec.Execute(makeCommand("SLOWLOG", "GET"))), "ids go on after a reset")
+
+	// the commands the executor runs itself are logged too
+	client.do(exec, "MULTI")
+	client.do(exec, "SET", "b", "2")
+	client.do(exec, "EXEC")
+	newest = exec.Execute(makeCommand("SLOWLOG", "GET", "1")).Array[0].Array
+	assert.Equal(t, []string{"EXEC"}, replyStrings(*newest[3]))
+
+	exec.Execute(makeCommand("CONFIG", "SET", "slowlog-log-slower-than", "-1"))
+	exec.Execute(makeCommand("SLOWLOG", "RESET"))
+	client.do(exec, "GET", "a")
+	assert.Equal(t, int64(0), exec.Execute(makeCommand("SLOWLOG", "LEN")).Int)
+
+	assert.Equal(t, enums.ErrorRespType, exec.Execute(makeCommand("SLOWLOG", "NOPE")).Type)
+	assert.Equal(t, enums.ErrorRespType, exec.Execute(makeCommand("SLOWLOG", "LEN", "1")).Type)
+}
+
+func TestSlowlogRing(t *testing.T) {
+	s := slowlogState{maxLen: 3}
+	ids := func(count int) []int64 {
+		var ids []int64
+		for _, entry := range s.newest(count) {
+			ids = append(ids, entry.id)
+		}
+		return ids
+	}
+	for range 5 {
+		s.add(slowlogEntry{})
+	}
+	assert.Equal(t, []int64{4, 3, 2}, ids(10))
+	assert.Equal(t, []int64{4}, ids(1))
+
+	s.setMaxLen(2)
+	assert.Equal(t, []int64{4, 3}, ids(10))
+	s.setMaxLen(4)
+	s.add(slowlogEntry{})
+	s.add(slowlogEntry{})
+	s.add(slowlogEntry{})
+	assert.Equal(t, []int64{7, 6, 5, 4}, ids(10))
+
+	s.setMaxLen(0)
+	s.add(slowlogEntry{})
+	assert.Empty(t, ids(10))
+}
+
+func TestSlowlogArgs(t *testing.T) {
+	long := strings.Repeat("x", slowlogMaxString+10)
+	args := slowlogArgs(makeCommand("SET", "key", long))
+	assert.Equal(t, []string{"SET", "key", strings.Repeat("x", slowlogMaxString) + "... (10 more bytes)"}, args)
+
+	many := make([]string, 40)
+	for i := range many {
+		many[i] = "m"
+	}
+	args = slowlogArgs(makeCommand("SADD", many...))
+	assert.Len(t, args, slowlogMaxArgs)
+	assert.Equal(t, "SADD", args[0])
+	assert.Equal(t, "... (10 more arguments)", args[slowlogMaxArgs-1])
+
+	args = slowlogArgs(makeCommand("SADD", many[:slowlogMaxArgs-1]...))
+	assert.Len(t, args, slowlogMaxArgs, "exactly the maximum is kept whole")
+	assert.Equal(t, "m", args[slowlogMaxArgs-1])
+}
@@ -0,0 +1,276 @@
+package datastore
+
+import (
+	"slices"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// runsInsideMulti lists the commands that run at once inside MULTI rather
+// than being queued, as in Redis.
+var runsInsideMulti = map[enums.CommandName]bool{
+	enums.MultiCommandName:   true,
+	enums.ExecCommandName:    true,
+	enums.DiscardCommandName: true,
+	enums.WatchCommandName:   true,
+	enums.QuitCommandName:    true,
+	enums.AskingCommandName:  true,
+}
+
+// notAllowedInMulti lists commands that cannot be queued, because their
+// replies are pushed to the connection rather than returned, or, for a
+// replica's commands, because they turn it into something other than a
+// client.
+var notAllowedInMulti = map[enums.CommandName]bool{
+	enums.SubscribeCommandName:    true,
+	enums.UnsubscribeCommandName:  true,
+	enums.PSubscribeCommandName:   true,
+	enums.PUnsubscribeCommandName: true,
+	enums.PSyncCommandName:        true,
+	enums.ReplConfCommandName:     true,
+	enums.MonitorCommandName:      true,
+}
+
+// multiState is the transaction state of a connection. It exists from the
+// first WATCH or MULTI until EXEC, DISCARD, UNWATCH outside MULTI, or the
+// connection closing.
+type multiState struct {
+	responseChan chan common.RespValue
+	inMulti      bool
+	queued       []commands.Command
+	// dirtyExec is set when a command fails to queue; EXEC then aborts.
+	dirtyExec bool
+	// dirtyCAS is set when a watched key is modified; EXEC then fails.
+	dirtyCAS bool
+	watched  map[string]struct{}
+}
+
+// transactionState holds the transaction state of every connection and,
+// like Redis, maps each watched key to the connections watching it, so a
+// write only has to look up the key it touched.
+type transactionState struct {
+	clients     map[chan common.RespValue]*multiState
+	watchedKeys map[string]map[chan common.RespValue]*multiState
+}
+
+func newTransactionState() transactionState {
+	return transactionState{
+		clients:     make(map[chan common.RespValue]*multiState),
+		watchedKeys: make(map[string]map[chan common.RespValue]*multiState),
+	}
+}
+
+func (e *Executor) multiStateOf(responseChan chan common.RespValue) *multiState {
+	tx, exists := e.transactions.clients[responseChan]
+	if !exists {
+		tx = &multiState{
+			responseChan: responseChan,
+			watched:      make(map[string]struct{}),
+		}
+		e.transactions.clients[responseChan] = tx
+	}
+	return tx
+}
+
+// touchWatchedKey is the keyspace modify hook. It fails the transaction of
+// every connection watching key.
+func (e *Executor) touchWatchedKey(key string) {
+	for _, tx := range e.transactions.watchedKeys[key] {
+		tx.dirtyCAS = true
+	}
+}
+
+// queueCommand queues a command sent inside MULTI. A command that cannot
+// be queued gets an error and makes the EXEC abort.
+func (e *Executor) queueCommand(value Value, tx *multiState) {
+	name := enums.StringToCommandName(value.Command.Name)
+	var redirect *common.RespValue
+	if e.cluster.node != nil {
+		_, asking := e.cluster.asking[value.ResponseChan]
+		redirect = e.clusterRedirect([]commands.Command{value.Command}, asking)
+	}
+	switch {
+	case !commands.KnownCommand(value.Command.Name):
+		tx.dirtyExec = true
+		e.reply(value, unknownCommand(value.Command.Name))
+	case !commands.CheckArity(value.Command):
+		tx.dirtyExec = true
+		e.reply(value, wrongArity(value.Command.Name))
+	case notAllowedInMulti[name]:
+		tx.dirtyExec = true
+		e.reply(value, errorValue("ERR Command not allowed inside a transaction"))
+	case e.readOnly() && commands.IsWriteCommand(value.Command.Name):
+		tx.dirtyExec = true
+		e.reply(value, errorValue(readOnlyError))
+	case redirect != nil:
+		tx.dirtyExec = true
+		e.reply(value, *redirect)
+	default:
+		tx.queued = append(tx.queued, value.Command)
+		e.reply(value, common.RespValue{Type: enums.SimpleStringRespType, Str: "QUEUED"})
+	}
+}
+
+func (e *Executor) handleMulti(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	tx := e.multiStateOf(value.ResponseChan)
+	if tx.inMulti {
+		e.reply(value, errorValue("ERR MULTI calls can not be nested"))
+		return
+	}
+	tx.inMulti = true
+	e.reply(value, okValue())
+}
+
+// handleExec runs the queued commands one after another with nothing in
+// between, which is what makes a transaction atomic here: the executor
+// goroutine is the only thing that touches the keyspace. The reply is an
+// array of their replies, or a null array if a watched key was modified.
+func (e *Executor) handleExec(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	tx := e.transactions.clients[value.ResponseChan]
+	if tx == nil || !tx.inMulti {
+		e.reply(value, errorValue("ERR EXEC without MULTI"))
+		return
+	}
+	if tx.dirtyExec {
+		e.discardTransaction(value.ResponseChan)
+		e.reply(value, errorValue("EXECABORT Transaction discarded because of previous errors."))
+		return
+	}
+	// the server may have become a replica since the writes were queued
+	if e.readOnly() && slices.ContainsFunc(tx.queued, func(command commands.Command) bool {
+		return commands.IsWriteCommand(command.Name)
+	}) {
+		e.discardTransaction(value.ResponseChan)
+		e.reply(value, errorValue("EXECABORT Transaction discarded because of: "+readOnlyError))
+		return
+	}
+	// the keys must all still be served here, by then together
+	if e.cluster.node != nil {
+		_, asking := e.cluster.asking[value.ResponseChan]
+		if redirect := e.clusterRedirect(tx.queued, asking); redirect != nil {
+			e.discardTransaction(value.ResponseChan)
+			e.reply(value, *redirect)
+			return
+		}
+	}
+	// a watched key that has expired since WATCH is reclaimed here, which
+	// counts as a modification
+	for key := range tx.watched {
+		e.dataStore.Exists(key)
+	}
+	if tx.dirtyCAS {
+		e.discardTransaction(value.ResponseChan)
+		e.reply(value, common.RespValue{Type: enums.ArrayRespType, IsNull: true})
+		return
+	}
+
+	e.discardTransaction(value.ResponseChan)
+	e.inExec = true
+	replies := make([]*common.RespValue, len(tx.queued))
+	for i, command := range tx.queued {
+		var reply common.RespValue
+		if enums.StringToCommandName(command.Name) == enums.UnwatchCommandName {
+			// the watches went with the transaction state
+			reply = okValue()
+		} else {
+			reply = e.Execute(command)
+		}
+		replies[i] = &reply
+	}
+	e.propagateTransaction()
+	e.reply(value, common.RespValue{Type: enums.ArrayRespType, Array: replies})
+}
+
+func (e *Executor) handleDiscard(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	tx := e.transactions.clients[value.ResponseChan]
+	if tx == nil || !tx.inMulti {
+		e.reply(value, errorValue("ERR DISCARD without MULTI"))
+		return
+	}
+	e.discardTransaction(value.ResponseChan)
+	e.reply(value, okValue())
+}
+
+// handleWatch implements WATCH key [key ...]: if any of the keys is
+// modified before the connection's next EXEC, that EXEC fails.
+func (e *Executor) handleWatch(value Value) {
+	if len(value.Command.Args) == 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	tx := e.multiStateOf(value.ResponseChan)
+	if tx.inMulti {
+		e.reply(value, errorValue("ERR WATCH inside MULTI is not allowed"))
+		return
+	}
+	for _, key := range value.Command.Args {
+		if _, watched := tx.watched[key]; watched {
+			continue
+		}
+		// reclaim the key first if it has already expired, so that does
+		// not count against the watch
+		e.dataStore.Exists(key)
+		tx.watched[key] = struct{}{}
+		watchers, exists := e.transactions.watchedKeys[key]
+		if !exists {
+			watchers = make(map[chan common.RespValue]*multiState)
+			e.transactions.watchedKeys[key] = watchers
+		}
+		watchers[value.ResponseChan] = tx
+	}
+	e.reply(value, okValue())
+}
+
+func (e *Executor) handleUnwatch(value Value) {
+	if len(value.Command.Args) != 0 {
+		e.reply(value, wrongArity(value.Command.Name))
+		return
+	}
+	if tx := e.transactions.clients[value.ResponseChan]; tx != nil {
+		e.unwatchAllKeys(tx)
+		if !tx.inMulti {
+			delete(e.transactions.clients, value.ResponseChan)
+		}
+	}
+	e.reply(value, okValue())
+}
+
+// discardTransaction drops a connection's queued commands and watches.
+func (e *Executor) discardTransaction(responseChan chan common.RespValue) {
+	tx, exists := e.transactions.clients[responseChan]
+	if !exists {
+		return
+	}
+	e.unwatchAllKeys(tx)
+	delete(e.transactions.clients, responseChan)
+}
+
+func (e *Executor) unwatchAllKeys(tx *multiState) {
+	for key := range tx.watched {
+		watchers := e.transactions.watchedKeys[key]
+		delete(watchers, tx.responseChan)
+		if len(watchers) == 0 {
+			delete(e.transactions.watchedKeys, key)
+		}
+	}
+	clear(tx.watched)
+	tx.dirtyCAS = false
+}
+
+func okValue() common.RespValue {
+	return common.RespValue{Type: enums.SimpleStringRespType, Str: "OK"}
+}
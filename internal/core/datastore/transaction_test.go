@@ -0,0 +1,255 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+func TestMultiExec(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+
+	client.do(exec, "MULTI")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	client.do(exec, "SET", "a", "1")
+	client.do(exec, "INCR", "a")
+	client.do(exec, "LPUSH", "a", "x")
+	for range 3 {
+		assert.Equal(t, "QUEUED", client.reply(t).Str)
+	}
+	// nothing runs before EXEC
+	assert.False(t, exec.dataStore.Exists("a"))
+
+	client.do(exec, "EXEC")
+	resp := client.reply(t)
+	assert.Equal(t, enums.ArrayRespType, resp.Type)
+	assert.Len(t, resp.Array, 3)
+	assert.Equal(t, "OK", resp.Array[0].Str)
+	assert.Equal(t, int64(2), resp.Array[1].Int)
+	// a command failing at run time does not stop the others
+	assert.Equal(t, enums.ErrorRespType, resp.Array[2].Type)
+
+	client.do(exec, "EXEC")
+	assert.Equal(t, "ERR EXEC without MULTI", client.reply(t).Str)
+}
+
+func TestMultiErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		expected string
+	}{
+		{"unknown command", []string{"NOPE"}, "ERR unknown command 'NOPE'"},
+		{"wrong arity", []string{"INCR"}, "ERR wrong number of arguments for 'INCR' command"},
+		{"subscribe", []string{"SUBSCRIBE", "a"}, "ERR Command not allowed inside a transaction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := NewExecutor()
+			client := newTestClient()
+
+			client.do(exec, "MULTI")
+			client.do(exec, "SET", "a", "1")
+			client.reply(t)
+			client.reply(t)
+
+			client.do(exec, tt.command[0], tt.command[1:]...)
+			assert.Equal(t, tt.expected, client.reply(t).Str)
+
+			client.do(exec, "EXEC")
+			assert.Equal(t, "EXECABORT Transaction discarded because of previous errors.", client.reply(t).Str)
+			assert.False(t, exec.dataStore.Exists("a"))
+		})
+	}
+}
+
+func TestMultiNestedAndDiscard(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+
+	client.do(exec, "DISCARD")
+	assert.Equal(t, "ERR DISCARD without MULTI", client.reply(t).Str)
+
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "MULTI")
+	assert.Equal(t, "ERR MULTI calls can not be nested", client.reply(t).Str)
+	client.do(exec, "WATCH", "a")
+	assert.Equal(t, "ERR WATCH inside MULTI is not allowed", client.reply(t).Str)
+
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+	client.do(exec, "DISCARD")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	assert.False(t, exec.dataStore.Exists("a"))
+
+	// neither error above dirtied the transaction
+	client.do(exec, "MULTI")
+	client.do(exec, "PING")
+	client.do(exec, "EXEC")
+	client.reply(t)
+	client.reply(t)
+	assert.Equal(t, "PONG", client.reply(t).Array[0].Str)
+}
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		touch   []string
+		aborted bool
+	}{
+		{"untouched", nil, false},
+		{"read only", []string{"GET", "a"}, false},
+		{"other key", []string{"SET", "b", "1"}, false},
+		{"overwritten", []string{"SET", "a", "2"}, true},
+		{"modified in place", []string{"INCR", "a"}, true},
+		{"deleted", []string{"DEL", "a"}, true},
+		{"ttl changed", []string{"EXPIRE", "a", "100"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := NewExecutor()
+			client, other := newTestClient(), newTestClient()
+			exec.Execute(makeCommand("SET", "a", "1"))
+
+			client.do(exec, "WATCH", "a")
+			assert.Equal(t, "OK", client.reply(t).Str)
+			if tt.touch != nil {
+				other.do(exec, tt.touch[0], tt.touch[1:]...)
+			}
+
+			client.do(exec, "MULTI")
+			client.do(exec, "SET", "a", "mine")
+			client.do(exec, "EXEC")
+			client.reply(t)
+			client.reply(t)
+			resp := client.reply(t)
+			if tt.aborted {
+				assert.True(t, resp.IsNull)
+				assert.NotEqual(t, "mine", exec.Execute(makeCommand("GET", "a")).Str)
+			} else {
+				assert.Len(t, resp.Array, 1)
+				assert.Equal(t, "mine", exec.Execute(makeCommand("GET", "a")).Str)
+			}
+			assert.Empty(t, exec.transactions.watchedKeys)
+		})
+	}
+}
+
+func TestWatchNoOpWrite(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup, touch []string
+		aborted      bool
+	}{
+		{"sadd existing member", []string{"SADD", "w", "m"}, []string{"SADD", "w", "m"}, false},
+		{"sadd new member", []string{"SADD", "w", "m"}, []string{"SADD", "w", "n"}, true},
+		{"srem missing member", []string{"SADD", "w", "m"}, []string{"SREM", "w", "n"}, false},
+		{"lrem no match", []string{"RPUSH", "w", "x"}, []string{"LREM", "w", "0", "y"}, false},
+		{"linsert missing pivot", []string{"RPUSH", "w", "x"}, []string{"LINSERT", "w", "BEFORE", "y", "z"}, false},
+		{"hsetnx existing field", []string{"HSET", "w", "f", "v"}, []string{"HSETNX", "w", "f", "v2"}, false},
+		{"hdel missing field", []string{"HSET", "w", "f", "v"}, []string{"HDEL", "w", "g"}, false},
+		{"zadd nx existing member", []string{"ZADD", "w", "1", "m"}, []string{"ZADD", "w", "NX", "2", "m"}, false},
+		{"zadd same score", []string{"ZADD", "w", "1", "m"}, []string{"ZADD", "w", "1", "m"}, false},
+		{"zadd new score", []string{"ZADD", "w", "1", "m"}, []string{"ZADD", "w", "2", "m"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := NewExecutor()
+			client, other := newTestClient(), newTestClient()
+			exec.Execute(makeCommand(tt.setup[0], tt.setup[1:]...))
+
+			client.do(exec, "WATCH", "w")
+			assert.Equal(t, "OK", client.reply(t).Str)
+			other.do(exec, tt.touch[0], tt.touch[1:]...)
+			assert.NotEqual(t, enums.ErrorRespType, other.reply(t).Type)
+
+			client.do(exec, "MULTI")
+			client.do(exec, "PING")
+			client.do(exec, "EXEC")
+			client.reply(t)
+			client.reply(t)
+			assert.Equal(t, tt.aborted, client.reply(t).IsNull)
+		})
+	}
+}
+
+func TestWatchExpiredKey(t *testing.T) {
+	now := int64(1_000)
+	exec := NewExecutor()
+	exec.dataStore.SetClock(func() int64 { return now })
+	client := newTestClient()
+
+	exec.Execute(makeCommand("SET", "a", "1", "PX", "100"))
+	client.do(exec, "WATCH", "a")
+	client.reply(t)
+
+	// nobody touched the key, but it expired between WATCH and EXEC
+	now = 2_000
+	client.do(exec, "MULTI")
+	client.do(exec, "EXEC")
+	client.reply(t)
+	assert.True(t, client.reply(t).IsNull)
+}
+
+func TestUnwatch(t *testing.T) {
+	exec := NewExecutor()
+	client, other := newTestClient(), newTestClient()
+
+	client.do(exec, "WATCH", "a", "b")
+	client.do(exec, "UNWATCH")
+	client.reply(t)
+	assert.Equal(t, "OK", client.reply(t).Str)
+	assert.Empty(t, exec.transactions.watchedKeys)
+	assert.Empty(t, exec.transactions.clients)
+
+	other.do(exec, "SET", "a", "1")
+	client.do(exec, "MULTI")
+	client.do(exec, "UNWATCH")
+	assert.Equal(t, "OK", client.reply(t).Str)
+	assert.Equal(t, "QUEUED", client.reply(t).Str)
+	client.do(exec, "EXEC")
+	assert.Equal(t, "OK", client.reply(t).Array[0].Str)
+}
+
+func TestExecRunsBlockingCommandsWithoutBlocking(t *testing.T) {
+	exec := NewExecutor()
+	client, waiter := newTestClient(), newTestClient()
+
+	waiter.do(exec, "BLPOP", "q", "0")
+	client.do(exec, "MULTI")
+	client.do(exec, "BLPOP", "empty", "0")
+	client.do(exec, "RPUSH", "q", "job")
+	client.do(exec, "PUBLISH", "news", "hi")
+	client.do(exec, "EXEC")
+	for range 4 {
+		client.reply(t)
+	}
+	resp := client.reply(t)
+	assert.True(t, resp.Array[0].IsNull)
+	assert.Equal(t, int64(1), resp.Array[1].Int)
+	assert.Equal(t, int64(0), resp.Array[2].Int)
+
+	// the blocked client is served once the transaction is done
+	assert.Equal(t, []string{"q", "job"}, replyStrings(waiter.reply(t)))
+}
+
+func TestDisconnectDiscardsTransaction(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+
+	client.do(exec, "WATCH", "a")
+	client.do(exec, "MULTI")
+	client.do(exec, "SET", "a", "1")
+	client.disconnect(exec)
+	client.assertClosed(t)
+
+	assert.False(t, exec.dataStore.Exists("a"))
+	assert.Empty(t, exec.transactions.clients)
+	assert.Empty(t, exec.transactions.watchedKeys)
+}
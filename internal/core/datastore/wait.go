@@ -0,0 +1,186 @@
+package datastore
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// waitTarget is what WAIT or WAITAOF waits for: numReplicas replicas to
+// acknowledge offset and, for WAITAOF, numLocal local fsyncs to cover it.
+// WAITAOF counts what replicas have synced to their append-only files
+// rather than what they have received.
+type waitTarget struct {
+	aof         bool
+	offset      int64
+	numLocal    int64
+	numReplicas int64
+}
+
+// handleWait implements WAIT numreplicas timeout and WAITAOF numlocal
+// numreplicas timeout. The client blocks until enough replicas, or the
+// append-only file, have caught up with everything written so far, or
+// the timeout, in milliseconds, passes; only it waits, as the executor
+// goes on serving everyone else.
+func (e *Executor) handleWait(value Value) {
+	target, timeout, errReply := e.parseWait(value.Command)
+	if errReply != nil {
+		e.reply(value, *errReply)
+		return
+	}
+	if e.waitDone(target) {
+		e.reply(value, e.waitReply(target))
+		return
+	}
+	client := e.blockClient(value, nil, &commands.Block{Timeout: timeout}, common.RespValue{})
+	client.wait = target
+	e.blocking.waiting = append(e.blocking.waiting, client)
+	if target.numReplicas > 0 && len(e.replication.replicas) > 0 {
+		e.replication.getAck = true
+	}
+}
+
+// handleWaitNow is WAIT or WAITAOF inside a transaction, where nothing
+// blocks, so they reply with how many have caught up already.
+func (e *Executor) handleWaitNow(command commands.Command) common.RespValue {
+	target, _, errReply := e.parseWait(command)
+	if errReply != nil {
+		return *errReply
+	}
+	return e.waitReply(target)
+}
+
+func (e *Executor) parseWait(command commands.Command) (*waitTarget, time.Duration, *common.RespValue) {
+	if !commands.CheckArity(command) {
+		reply := wrongArity(command.Name)
+		return nil, 0, &reply
+	}
+	target := &waitTarget{
+		aof:    enums.StringToCommandName(command.Name) == enums.WaitAOFCommandName,
+		offset: e.replication.offset,
+	}
+	args := command.Args
+	if e.replication.master != nil {
+		var reply common.RespValue
+		if target.aof {
+			reply = errorValue("ERR WAITAOF cannot be used with replica instances. Please also note that writes to replicas are just local and are not propagated.")
+		} else {
+			reply = errorValue("ERR WAIT cannot be used with replica instances. Please also note that since Redis 4.0 if a replica is configured to be writable (which is not the default) writes to replicas are just local and are not propagated.")
+		}
+		return nil, 0, &reply
+	}
+	if target.aof {
+		numLocal, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			reply := errorValue("ERR value is not an integer or out of range")
+			return nil, 0, &reply
+		}
+		target.numLocal = numLocal
+		args = args[1:]
+	}
+	numReplicas, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		reply := errorValue("ERR value is not an integer or out of range")
+		return nil, 0, &reply
+	}
+	target.numReplicas = numReplicas
+	milliseconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		reply := errorValue("ERR timeout is not an integer or out of range")
+		return nil, 0, &reply
+	}
+	if milliseconds < 0 {
+		reply := errorValue("ERR timeout is negative")
+		return nil, 0, &reply
+	}
+	if target.numLocal > 0 && e.aof == nil {
+		reply := errorValue("ERR WAITAOF cannot be used when numlocal is set but appendonly is disabled.")
+		return nil, 0, &reply
+	}
+	return target, time.Duration(milliseconds) * time.Millisecond, nil
+}
+
+// waitCounts returns whether the local append-only file has synced
+// target's offset, as 0 or 1, and how many replicas have acknowledged it.
+func (e *Executor) waitCounts(target *waitTarget) (local, replicas int64) {
+	if target.aof && e.aof != nil && e.fsync.offset >= target.offset {
+		local = 1
+	}
+	for _, r := range e.replication.replicas {
+		if r.state != replicaOnline {
+			continue
+		}
+		acked := r.ackOffset
+		if target.aof {
+			acked = r.aofAckOffset
+		}
+		if acked >= target.offset {
+			replicas++
+		}
+	}
+	return local, replicas
+}
+
+func (e *Executor) waitDone(target *waitTarget) bool {
+	local, replicas := e.waitCounts(target)
+	return local >= target.numLocal && replicas >= target.numReplicas
+}
+
+// waitReply is WAIT's count of replicas, or WAITAOF's pair of local and
+// replica counts.
+func (e *Executor) waitReply(target *waitTarget) common.RespValue {
+	local, replicas := e.waitCounts(target)
+	if !target.aof {
+		return common.RespValue{Type: enums.IntRespType, Int: replicas}
+	}
+	return common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+		{Type: enums.IntRespType, Int: local},
+		{Type: enums.IntRespType, Int: replicas},
+	}}
+}
+
+// serveWaitingClients replies to the WAIT and WAITAOF clients that have
+// got the acknowledgements they were waiting for.
+func (e *Executor) serveWaitingClients() {
+	if len(e.blocking.waiting) == 0 {
+		return
+	}
+	waiting := e.blocking.waiting[:0]
+	var served []*blockedClient
+	for _, client := range e.blocking.waiting {
+		switch {
+		case client.unblocked:
+		case e.waitDone(client.wait):
+			served = append(served, client)
+		default:
+			waiting = append(waiting, client)
+		}
+	}
+	clear(e.blocking.waiting[len(waiting):])
+	e.blocking.waiting = waiting
+	// replying resumes the client, which may block again, so it comes
+	// after the list is settled
+	for _, client := range served {
+		e.unblockClient(client)
+		e.reply(client.value, e.waitReply(client.wait))
+		e.resumeClient(client.value.ResponseChan)
+	}
+}
+
+// unblockWaitingClients fails every WAIT and WAITAOF, for when the server
+// becomes a replica and the offsets they wait for stop meaning anything.
+func (e *Executor) unblockWaitingClients() {
+	waiting := e.blocking.waiting
+	e.blocking.waiting = nil
+	for _, client := range waiting {
+		if client.unblocked {
+			continue
+		}
+		e.unblockClient(client)
+		e.reply(client.value, errorValue("UNBLOCKED force unblock from blocking operation, instance state changed (master -> replica?)"))
+		e.resumeClient(client.value.ResponseChan)
+	}
+}
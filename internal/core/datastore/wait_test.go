@@ -0,0 +1,179 @@
+package datastore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+func intValues(resp common.RespValue) []int64 {
+	values := []int64{}
+	for _, element := range resp.Array {
+		values = append(values, element.Int)
+	}
+	return values
+}
+
+func TestWait(t *testing.T) {
+	exec := NewExecutor()
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	client := newTestClient()
+
+	// the replica has everything written so far
+	client.do(exec, "WAIT", "0", "0")
+	assert.Equal(t, int64(1), client.reply(t).Int)
+
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+	set := stream(makeCommand("SET", "a", "1"))
+	assert.Equal(t, set, replica.rawReply(t))
+	client.do(exec, "WAIT", "1", "0")
+	client.do(exec, "PING")
+	client.assertNoReply(t)
+	assert.Equal(t, 1, exec.BlockedClients())
+	// the replica is asked to acknowledge at once
+	getAck := stream(makeCommand("REPLCONF", "GETACK", "*"))
+	assert.Equal(t, getAck, replica.rawReply(t))
+
+	// an acknowledgement short of the write is not enough
+	replica.do(exec, "REPLCONF", "ACK", "1")
+	client.assertNoReply(t)
+	replica.do(exec, "REPLCONF", "ACK", strconv.Itoa(len(set+getAck)))
+	assert.Equal(t, int64(1), client.reply(t).Int)
+	assert.Equal(t, "PONG", client.reply(t).Str)
+	assert.Equal(t, 0, exec.BlockedClients())
+
+	// already acknowledged
+	client.do(exec, "WAIT", "1", "0")
+	assert.Equal(t, int64(1), client.reply(t).Int)
+}
+
+func TestWaitTimeout(t *testing.T) {
+	exec := NewExecutor()
+	now := int64(1_000_000)
+	exec.dataStore.SetClock(func() int64 { return now })
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	client := newTestClient()
+
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+	client.do(exec, "WAIT", "2", "100")
+	replica.do(exec, "REPLCONF", "ACK", strconv.FormatInt(exec.replication.offset, 10))
+	client.assertNoReply(t)
+	now += 101
+	exec.Cron()
+	// times out with how many replicas did acknowledge
+	assert.Equal(t, int64(1), client.reply(t).Int)
+	assert.Equal(t, 0, exec.BlockedClients())
+	assert.Empty(t, exec.blocking.waiting)
+}
+
+func TestWaitDisconnect(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+	client.do(exec, "WAIT", "1", "0")
+	client.disconnect(exec)
+	client.assertClosed(t)
+	assert.Equal(t, 0, exec.BlockedClients())
+	exec.Cron()
+	assert.Empty(t, exec.blocking.waiting)
+}
+
+func TestWaitInsideMulti(t *testing.T) {
+	exec := NewExecutor()
+	client := newTestClient()
+	client.do(exec, "MULTI")
+	client.reply(t)
+	client.do(exec, "WAIT", "1", "0")
+	assert.Equal(t, "QUEUED", client.reply(t).Str)
+	client.do(exec, "WAITAOF", "0", "1", "0")
+	assert.Equal(t, "QUEUED", client.reply(t).Str)
+	client.do(exec, "EXEC")
+	// nothing blocks inside a transaction
+	reply := client.reply(t)
+	require.Len(t, reply.Array, 2)
+	assert.Equal(t, int64(0), reply.Array[0].Int)
+	assert.Equal(t, []int64{0, 0}, intValues(*reply.Array[1]))
+}
+
+func TestWaitErrors(t *testing.T) {
+	exec := NewExecutor()
+	replica := replicaExecutor(t)
+	tests := []struct {
+		name     string
+		exec     *Executor
+		args     []string
+		expected string
+	}{
+		{"WAIT", exec, []string{"one", "0"}, "ERR value is not an integer or out of range"},
+		{"WAIT", exec, []string{"1", "1.5"}, "ERR timeout is not an integer or out of range"},
+		{"WAIT", exec, []string{"1", "-1"}, "ERR timeout is negative"},
+		{"WAIT", exec, []string{"1"}, "ERR wrong number of arguments for 'WAIT' command"},
+		{"WAITAOF", exec, []string{"x", "0", "0"}, "ERR value is not an integer or out of range"},
+		{"WAITAOF", exec, []string{"1", "0", "0"}, "ERR WAITAOF cannot be used when numlocal is set but appendonly is disabled."},
+		{"WAIT", replica, []string{"1", "0"}, "ERR WAIT cannot be used with replica instances. Please also note that since Redis 4.0 if a replica is configured to be writable (which is not the default) writes to replicas are just local and are not propagated."},
+		{"WAITAOF", replica, []string{"0", "1", "0"}, "ERR WAITAOF cannot be used with replica instances. Please also note that writes to replicas are just local and are not propagated."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient()
+			client.do(tt.exec, tt.name, tt.args...)
+			reply := client.reply(t)
+			assert.Equal(t, enums.ErrorRespType, reply.Type)
+			assert.Equal(t, tt.expected, reply.Str)
+		})
+	}
+}
+
+func TestWaitAOF(t *testing.T) {
+	exec, _ := appendOnlyExecutor(t)
+	client := newTestClient()
+
+	// with appendfsync always the file is synced before the reply
+	client.do(exec, "SET", "a", "1")
+	client.reply(t)
+	client.do(exec, "WAITAOF", "1", "0", "0")
+	assert.Equal(t, []int64{1, 0}, intValues(client.reply(t)))
+
+	replica := newTestClient()
+	syncReplica(t, exec, replica)
+	client.do(exec, "SET", "b", "2")
+	client.reply(t)
+	replica.rawReply(t)
+	client.do(exec, "WAITAOF", "1", "1", "0")
+	client.assertNoReply(t)
+	replica.rawReply(t)
+	offset := strconv.FormatInt(exec.replication.offset, 10)
+
+	// receiving the write is not enough, the replica must sync it
+	replica.do(exec, "REPLCONF", "ACK", offset)
+	client.assertNoReply(t)
+	replica.do(exec, "REPLCONF", "ACK", offset, "FACK", "-1")
+	client.assertNoReply(t)
+	replica.do(exec, "REPLCONF", "ACK", offset, "FACK", offset)
+	assert.Equal(t, []int64{1, 1}, intValues(client.reply(t)))
+}
+
+func TestWaitUnblockedOnBecomingReplica(t *testing.T) {
+	exec := NewExecutor()
+	t.Cleanup(func() { exec.Close() })
+	client := newTestClient()
+	client.do(exec, "WAIT", "1", "0")
+	client.assertNoReply(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	exec.Execute(makeCommand("REPLICAOF", host, port))
+	assert.Equal(t, "UNBLOCKED force unblock from blocking operation, instance state changed (master -> replica?)", client.reply(t).Str)
+	assert.Equal(t, 0, exec.BlockedClients())
+}
@@ -0,0 +1,308 @@
+// Package metrics keeps counters, gauges and histograms and serves them
+// in the Prometheus text exposition format, for the server's /metrics
+// endpoint. Counters and histograms are updated with atomics, so the
+// goroutines recording them never wait on a scrape.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a count that only goes up.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Histogram counts observations in buckets by their upper bounds, and
+// keeps their sum.
+type Histogram struct {
+	bounds []float64
+	// counts holds the observations in each bucket, not cumulatively, with
+	// one more for those above every bound
+	counts []atomic.Uint64
+	// sum holds the bits of a float64
+	sum atomic.Uint64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+	h.counts[i].Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	var count uint64
+	for i := range h.counts {
+		count += h.counts[i].Load()
+	}
+	return count
+}
+
+// family is a metric and its children, one per set of label values.
+type family struct {
+	name, help, kind string
+	labels           []string
+	// write writes the samples of the family
+	write func(w io.Writer, f *family)
+
+	mu       sync.RWMutex
+	children map[string]*child
+	newChild func() any
+}
+
+type child struct {
+	values []string
+	metric any
+}
+
+// get returns the child with values, creating it the first time.
+func (f *family) get(values []string) any {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, not %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	c := f.children[key]
+	f.mu.RUnlock()
+	if c != nil {
+		return c.metric
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c = f.children[key]; c == nil {
+		c = &child{values: slices.Clone(values), metric: f.newChild()}
+		f.children[key] = c
+	}
+	return c.metric
+}
+
+// sorted returns the children ordered by their label values, so the
+// output is stable.
+func (f *family) sorted() []*child {
+	f.mu.RLock()
+	children := make([]*child, 0, len(f.children))
+	for _, c := range f.children {
+		children = append(children, c)
+	}
+	f.mu.RUnlock()
+	slices.SortFunc(children, func(a, b *child) int {
+		return slices.Compare(a.values, b.values)
+	})
+	return children
+}
+
+// CounterVec is a counter with labels.
+type CounterVec struct {
+	f *family
+}
+
+// WithLabelValues returns the counter for values, given in the order of
+// the labels.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.f.get(values).(*Counter)
+}
+
+// HistogramVec is a histogram with labels.
+type HistogramVec struct {
+	f *family
+}
+
+// WithLabelValues returns the histogram for values, given in the order of
+// the labels.
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.f.get(values).(*Histogram)
+}
+
+// Registry holds metrics and writes them out. It is an http.Handler
+// serving them to Prometheus.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f *family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.families {
+		if existing.name == f.name {
+			panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+		}
+	}
+	f.children = make(map[string]*child)
+	r.families = append(r.families, f)
+}
+
+// Counter registers a counter without labels.
+func (r *Registry) Counter(name, help string) *Counter {
+	return r.CounterVec(name, help).WithLabelValues()
+}
+
+// CounterVec registers a counter with the given labels.
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	f := &family{name: name, help: help, kind: "counter", labels: labels,
+		newChild: func() any { return &Counter{} },
+		write: func(w io.Writer, f *family) {
+			for _, c := range f.sorted() {
+				writeSample(w, f.name, f.labels, c.values, "", "", float64(c.metric.(*Counter).Value()))
+			}
+		}}
+	r.register(f)
+	return &CounterVec{f}
+}
+
+// CounterFunc registers a counter whose value value returns when the
+// metrics are written, for a count kept elsewhere.
+func (r *Registry) CounterFunc(name, help string, value func() float64) {
+	r.registerFunc(name, help, "counter", value)
+}
+
+// GaugeFunc registers a gauge whose value value returns when the metrics
+// are written.
+func (r *Registry) GaugeFunc(name, help string, value func() float64) {
+	r.registerFunc(name, help, "gauge", value)
+}
+
+func (r *Registry) registerFunc(name, help, kind string, value func() float64) {
+	r.register(&family{name: name, help: help, kind: kind,
+		write: func(w io.Writer, f *family) {
+			writeSample(w, f.name, nil, nil, "", "", value())
+		}})
+}
+
+// HistogramVec registers a histogram with the given labels, whose buckets
+// have the upper bounds buckets, in increasing order.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: the buckets of %s are not in order", name))
+	}
+	f := &family{name: name, help: help, kind: "histogram", labels: labels,
+		newChild: func() any { return newHistogram(buckets) },
+		write: func(w io.Writer, f *family) {
+			for _, c := range f.sorted() {
+				writeHistogram(w, f, c.values, c.metric.(*Histogram))
+			}
+		}}
+	r.register(f)
+	return &HistogramVec{f}
+}
+
+// WriteText writes every metric in the Prometheus text format, in the
+// order they were registered.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		f.write(&b, f)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+func writeHistogram(w io.Writer, f *family, values []string, h *Histogram) {
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i].Load()
+		writeSample(w, f.name+"_bucket", f.labels, values, "le", formatFloat(bound), float64(cumulative))
+	}
+	cumulative += h.counts[len(h.bounds)].Load()
+	writeSample(w, f.name+"_bucket", f.labels, values, "le", "+Inf", float64(cumulative))
+	writeSample(w, f.name+"_sum", f.labels, values, "", "", math.Float64frombits(h.sum.Load()))
+	writeSample(w, f.name+"_count", f.labels, values, "", "", float64(cumulative))
+}
+
+// writeSample writes a line of name with its labels, and the extra label,
+// if any, and value.
+func writeSample(w io.Writer, name string, labels, values []string, extra, extraValue string, value float64) {
+	io.WriteString(w, name)
+	if len(labels) > 0 || extra != "" {
+		io.WriteString(w, "{")
+		for i, label := range labels {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extra, extraValue)
+		}
+		io.WriteString(w, "}")
+	}
+	fmt.Fprintf(w, " %s\n", formatFloat(value))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
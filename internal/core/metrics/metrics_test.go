@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func text(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	calls := r.CounterVec("calls_total", "Calls made.", "command", "result")
+	bytes := r.Counter("bytes_total", "Bytes read,\nin all.")
+	depth := 3
+	r.GaugeFunc("queue_depth", "Queued requests.", func() float64 { return float64(depth) })
+	latency := r.HistogramVec("latency_seconds", "Latency.", []float64{0.001, 0.01}, "command")
+
+	calls.WithLabelValues("set", "ok").Inc()
+	calls.WithLabelValues("get", "ok").Add(2)
+	calls.WithLabelValues("get", `a "b"\`).Inc()
+	bytes.Add(100)
+	latency.WithLabelValues("get").Observe(0.0005)
+	latency.WithLabelValues("get").Observe(0.001)
+	latency.WithLabelValues("get").Observe(0.5)
+
+	assert.Equal(t, `# HELP calls_total Calls made.
+# TYPE calls_total counter
+calls_total{command="get",result="a \"b\"\\"} 1
+calls_total{command="get",result="ok"} 2
+calls_total{command="set",result="ok"} 1
+# HELP bytes_total Bytes read,\nin all.
+# TYPE bytes_total counter
+bytes_total 100
+# HELP queue_depth Queued requests.
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{command="get",le="0.001"} 2
+latency_seconds_bucket{command="get",le="0.01"} 2
+latency_seconds_bucket{command="get",le="+Inf"} 3
+latency_seconds_sum{command="get"} 0.5015
+latency_seconds_count{command="get"} 3
+`, text(t, r))
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	calls := r.CounterVec("calls_total", "Calls.", "command")
+	assert.Panics(t, func() { r.Counter("calls_total", "Again.") })
+	assert.Panics(t, func() { calls.WithLabelValues("a", "b") })
+	assert.Panics(t, func() { r.HistogramVec("h", "Unordered.", []float64{2, 1}) })
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	calls := r.CounterVec("calls_total", "Calls.", "command")
+	latency := r.HistogramVec("latency_seconds", "Latency.", []float64{1}, "command")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				calls.WithLabelValues("get").Inc()
+				latency.WithLabelValues("get").Observe(0.5)
+			}
+		}()
+		// scraped meanwhile
+		go func() {
+			defer wg.Done()
+			r.WriteText(io.Discard)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(8000), calls.WithLabelValues("get").Value())
+	assert.Equal(t, uint64(8000), latency.WithLabelValues("get").Count())
+	assert.Contains(t, text(t, r), "latency_seconds_sum{command=\"get\"} 4000\n")
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("bytes_total", "Bytes.").Add(7)
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "bytes_total 7\n")
+}
@@ -0,0 +1,79 @@
+package resp
+
+import (
+	"net"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+)
+
+// Client is a connection to a server, for tools and servers that talk to
+// other servers. It is not safe for concurrent use.
+type Client struct {
+	Addr string
+	// Timeout bounds each Do and Receive; zero means no limit
+	Timeout time.Duration
+	conn    net.Conn
+	buf     []byte
+}
+
+// Dial connects to the server at addr.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Addr: addr, conn: conn}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// LocalAddr returns the address the connection is from.
+func (c *Client) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Do sends a command and returns the reply. An error reply is a reply;
+// the error is for the connection failing.
+func (c *Client) Do(args ...string) (common.RespValue, error) {
+	c.setDeadline()
+	if _, err := c.conn.Write(EncodeCommand(commands.Command{Name: args[0], Args: args[1:]})); err != nil {
+		return common.RespValue{}, err
+	}
+	return c.Receive()
+}
+
+// Receive reads the next reply, or message for a subscribed connection.
+func (c *Client) Receive() (common.RespValue, error) {
+	c.setDeadline()
+	chunk := make([]byte, 4096)
+	for {
+		if len(c.buf) > 0 {
+			parsed := Parse(c.buf)
+			if parsed.Error() != nil {
+				return common.RespValue{}, parsed.Error()
+			}
+			if consumed := parsed.BytesConsumed(); consumed > 0 {
+				c.buf = c.buf[consumed:]
+				return *parsed.Resp, nil
+			}
+		}
+		n, err := c.conn.Read(chunk)
+		if err != nil {
+			return common.RespValue{}, err
+		}
+		c.buf = append(c.buf, chunk[:n]...)
+	}
+}
+
+func (c *Client) setDeadline() {
+	if c.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.Timeout))
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
 	"github.com/suryansh0301/Mnemo/internal/core/common"
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
 
+// maxPooledBufferSize keeps a single huge reply (a large LRANGE, say) from
+// pinning its scratch buffer in the pool forever.
+const maxPooledBufferSize = 64 * 1024
+
 var bufPool = sync.Pool{
 	New: func() any {
 		b := make([]byte, 0, 256)
@@ -15,84 +20,72 @@ var bufPool = sync.Pool{
 	},
 }
 
-var encoderHandler map[enums.RespType]func(value common.RespValue) []byte
+var encoderHandler map[enums.RespType]func(buf []byte, value *common.RespValue) []byte
 
 func init() {
-	encoderHandler = make(map[enums.RespType]func(value common.RespValue) []byte)
-
-	encoderHandler[enums.SimpleStringRespType] = func(value common.RespValue) []byte {
-		bufPtr := bufPool.Get().(*[]byte)
-		buf := (*bufPtr)[:0]
+	encoderHandler = make(map[enums.RespType]func(buf []byte, value *common.RespValue) []byte)
 
+	encoderHandler[enums.SimpleStringRespType] = func(buf []byte, value *common.RespValue) []byte {
 		buf = append(buf, '+')
 		buf = append(buf, value.Str...)
-		buf = append(buf, '\r', '\n')
-
-		result := make([]byte, len(buf))
-		copy(result, buf)
-
-		*bufPtr = buf
-		bufPool.Put(bufPtr)
-
-		return result
+		return append(buf, '\r', '\n')
 	}
 
-	encoderHandler[enums.IntRespType] = func(value common.RespValue) []byte {
-		bufPtr := bufPool.Get().(*[]byte)
-		buf := (*bufPtr)[:0]
-
+	encoderHandler[enums.IntRespType] = func(buf []byte, value *common.RespValue) []byte {
 		buf = append(buf, ':')
 		buf = strconv.AppendInt(buf, value.Int, 10)
-		buf = append(buf, '\r', '\n')
-
-		result := make([]byte, len(buf))
-		copy(result, buf)
-
-		*bufPtr = buf
-		bufPool.Put(bufPtr)
-
-		return result
+		return append(buf, '\r', '\n')
 	}
 
-	encoderHandler[enums.BulkStringRespType] = func(value common.RespValue) []byte {
+	encoderHandler[enums.BulkStringRespType] = func(buf []byte, value *common.RespValue) []byte {
 		if value.IsNull {
-			return []byte("$-1\r\n")
+			return append(buf, "$-1\r\n"...)
 		}
 
-		bufPtr := bufPool.Get().(*[]byte)
-		buf := (*bufPtr)[:0]
-
 		buf = append(buf, '$')
 		buf = strconv.AppendInt(buf, int64(len(value.Str)), 10)
 		buf = append(buf, '\r', '\n')
-		buf = append(buf, []byte(value.Str)...)
-		buf = append(buf, '\r', '\n')
-
-		result := make([]byte, len(buf))
-		copy(result, buf)
-
-		*bufPtr = buf
-		bufPool.Put(bufPtr)
+		buf = append(buf, value.Str...)
+		return append(buf, '\r', '\n')
+	}
 
-		return result
+	encoderHandler[enums.ErrorRespType] = func(buf []byte, value *common.RespValue) []byte {
+		buf = append(buf, '-')
+		buf = append(buf, value.Str...)
+		return append(buf, '\r', '\n')
 	}
 
-	encoderHandler[enums.ErrorRespType] = func(value common.RespValue) []byte {
-		bufPtr := bufPool.Get().(*[]byte)
-		buf := (*bufPtr)[:0]
+	encoderHandler[enums.ArrayRespType] = func(buf []byte, value *common.RespValue) []byte {
+		if value.IsNull {
+			return append(buf, "*-1\r\n"...)
+		}
 
-		buf = append(buf, '-')
-		buf = append(buf, []byte(value.Str)...)
+		buf = append(buf, '*')
+		buf = strconv.AppendInt(buf, int64(len(value.Array)), 10)
 		buf = append(buf, '\r', '\n')
+		for _, element := range value.Array {
+			buf = appendValue(buf, element)
+		}
+		return buf
+	}
 
-		result := make([]byte, len(buf))
-		copy(result, buf)
-
-		*bufPtr = buf
-		bufPool.Put(bufPtr)
+	encoderHandler[enums.RawRespType] = func(buf []byte, value *common.RespValue) []byte {
+		return append(buf, value.Str...)
+	}
+}
 
-		return result
+// appendValue encodes one array element. A nil element is sent as a null
+// bulk string, which is how Redis represents missing entries in replies
+// such as MGET.
+func appendValue(buf []byte, value *common.RespValue) []byte {
+	if value == nil {
+		return append(buf, "$-1\r\n"...)
 	}
+	handler, exists := encoderHandler[value.Type]
+	if !exists {
+		return append(buf, "-ERR internal error\r\n"...)
+	}
+	return handler(buf, value)
 }
 
 func Encoder(resp common.RespValue) []byte {
@@ -100,5 +93,29 @@ func Encoder(resp common.RespValue) []byte {
 	if !exists {
 		return []byte("-ERR internal error\r\n")
 	}
-	return handler(resp)
+
+	bufPtr := bufPool.Get().(*[]byte)
+	buf := handler((*bufPtr)[:0], &resp)
+
+	result := make([]byte, len(buf))
+	copy(result, buf)
+
+	if cap(buf) <= maxPooledBufferSize {
+		*bufPtr = buf
+		bufPool.Put(bufPtr)
+	}
+
+	return result
+}
+
+// EncodeCommand encodes a command as a client sends it, an array of bulk
+// strings, which is also how the append-only file and the replication
+// stream hold it.
+func EncodeCommand(command commands.Command) []byte {
+	elements := make([]*common.RespValue, 0, len(command.Args)+1)
+	elements = append(elements, &common.RespValue{Type: enums.BulkStringRespType, Str: command.Name})
+	for _, arg := range command.Args {
+		elements = append(elements, &common.RespValue{Type: enums.BulkStringRespType, Str: arg})
+	}
+	return Encoder(common.RespValue{Type: enums.ArrayRespType, Array: elements})
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
 	"github.com/suryansh0301/Mnemo/internal/core/common"
 	"github.com/suryansh0301/Mnemo/internal/enums"
 )
@@ -148,3 +149,106 @@ func TestEncodeUnknownType(t *testing.T) {
 	result := string(Encoder(common.RespValue{Type: 99}))
 	assert.Equal(t, "-ERR internal error\r\n", result)
 }
+
+func TestEncodeArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    common.RespValue
+		expected string
+	}{
+		{
+			name:     "null array",
+			input:    common.RespValue{Type: enums.ArrayRespType, IsNull: true},
+			expected: "*-1\r\n",
+		},
+		{
+			name:     "empty array",
+			input:    common.RespValue{Type: enums.ArrayRespType},
+			expected: "*0\r\n",
+		},
+		{
+			name: "bulk strings",
+			input: common.RespValue{
+				Type: enums.ArrayRespType,
+				Array: []*common.RespValue{
+					{Type: enums.BulkStringRespType, Str: "foo"},
+					{Type: enums.BulkStringRespType, Str: "bar"},
+				},
+			},
+			expected: "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n",
+		},
+		{
+			name: "mixed element types",
+			input: common.RespValue{
+				Type: enums.ArrayRespType,
+				Array: []*common.RespValue{
+					{Type: enums.SimpleStringRespType, Str: "OK"},
+					{Type: enums.IntRespType, Int: -7},
+					{Type: enums.BulkStringRespType, IsNull: true},
+					{Type: enums.ErrorRespType, Str: "ERR boom"},
+					nil,
+				},
+			},
+			expected: "*5\r\n+OK\r\n:-7\r\n$-1\r\n-ERR boom\r\n$-1\r\n",
+		},
+		{
+			name: "nested arrays",
+			input: common.RespValue{
+				Type: enums.ArrayRespType,
+				Array: []*common.RespValue{
+					{Type: enums.BulkStringRespType, Str: "0"},
+					{
+						Type: enums.ArrayRespType,
+						Array: []*common.RespValue{
+							{Type: enums.BulkStringRespType, Str: "field"},
+							{Type: enums.ArrayRespType},
+							{Type: enums.ArrayRespType, IsNull: true},
+						},
+					},
+				},
+			},
+			expected: "*2\r\n$1\r\n0\r\n*3\r\n$5\r\nfield\r\n*0\r\n*-1\r\n",
+		},
+		{
+			name: "unknown element type",
+			input: common.RespValue{
+				Type:  enums.ArrayRespType,
+				Array: []*common.RespValue{{Type: 99}},
+			},
+			expected: "*1\r\n-ERR internal error\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(Encoder(tt.input))
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestEncodeLargeArray(t *testing.T) {
+	elements := make([]*common.RespValue, 10_000)
+	for i := range elements {
+		elements[i] = &common.RespValue{Type: enums.BulkStringRespType, Str: "value"}
+	}
+
+	result := Encoder(common.RespValue{Type: enums.ArrayRespType, Array: elements})
+	parsed := Parse(result)
+	assert.NoError(t, parsed.Error())
+	assert.Equal(t, len(result), parsed.BytesConsumed())
+	assert.Len(t, parsed.Resp.Array, 10_000)
+
+	// the pool must still hand out usable buffers afterwards
+	assert.Equal(t, "+OK\r\n", string(Encoder(common.RespValue{Type: enums.SimpleStringRespType, Str: "OK"})))
+}
+
+func TestEncodeRaw(t *testing.T) {
+	input := common.RespValue{Type: enums.RawRespType, Str: "$3\r\nabc*1\r\n$4\r\nPING\r\n"}
+	assert.Equal(t, "$3\r\nabc*1\r\n$4\r\nPING\r\n", string(Encoder(input)))
+}
+
+func TestEncodeCommand(t *testing.T) {
+	command := commands.Command{Name: "SET", Args: []string{"key", ""}}
+	assert.Equal(t, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n", string(EncodeCommand(command)))
+}
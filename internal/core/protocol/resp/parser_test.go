@@ -320,6 +320,20 @@ func TestEncoderRoundTrip(t *testing.T) {
 			Type:   enums.BulkStringRespType,
 			IsNull: true,
 		},
+		{
+			Type:   enums.ArrayRespType,
+			IsNull: true,
+		},
+		{
+			Type: enums.ArrayRespType,
+		},
+		{
+			Type: enums.ArrayRespType,
+			Array: []*common.RespValue{
+				{Type: enums.BulkStringRespType, Str: "a"},
+				{Type: enums.ArrayRespType, Array: []*common.RespValue{{Type: enums.IntRespType, Int: 1}}},
+			},
+		},
 	}
 
 	for _, v := range values {
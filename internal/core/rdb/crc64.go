@@ -0,0 +1,53 @@
+package rdb
+
+import (
+	"bufio"
+	"hash/crc64"
+	"io"
+)
+
+// jonesTable is for the CRC-64/Jones checksum Redis puts at the end of an
+// RDB file, given in the reversed form hash/crc64 expects.
+var jonesTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// crc64Update adds p to crc. The Jones variant used by Redis has neither
+// the initial nor the final inversion that hash/crc64 applies, so both are
+// undone here.
+func crc64Update(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, jonesTable, p)
+}
+
+// checksumWriter checksums everything written through it.
+type checksumWriter struct {
+	w   io.Writer
+	crc uint64
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.crc = crc64Update(c.crc, p[:n])
+	return n, err
+}
+
+// checksumReader checksums everything read through it.
+type checksumReader struct {
+	r   *bufio.Reader
+	crc uint64
+	one [1]byte
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc = crc64Update(c.crc, p[:n])
+	return n, err
+}
+
+func (c *checksumReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	c.one[0] = b
+	c.crc = crc64Update(c.crc, c.one[:])
+	return b, nil
+}
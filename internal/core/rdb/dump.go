@@ -0,0 +1,59 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// A DUMP payload is a single value as Redis writes it in an RDB file,
+// without its key, followed by the RDB version it was written in and the
+// CRC64 of both, as two little-endian fields. Payloads are exchanged with
+// Redis as they are, so keys can be moved with DUMP and RESTORE, or
+// MIGRATE, in either direction.
+
+// ErrDumpPayload is returned by Restore for a payload that fails its
+// checksum or comes from a newer RDB version than Mnemo reads.
+var ErrDumpPayload = errors.New("rdb: DUMP payload version or checksum are wrong")
+
+// Dump serializes object into a DUMP payload.
+func Dump(object *keyspace.Object) []byte {
+	var buf bytes.Buffer
+	e := &redisEncoder{w: &buf, version: RedisVersion, dump: true}
+	e.object("", object)
+	payload := binary.LittleEndian.AppendUint16(buf.Bytes(), RedisVersion)
+	return binary.LittleEndian.AppendUint64(payload, crc64Update(0, payload))
+}
+
+// Restore reads the object serialized in a DUMP payload.
+func Restore(payload []byte) (*keyspace.Object, error) {
+	if len(payload) < 10 {
+		return nil, ErrDumpPayload
+	}
+	footer := len(payload) - 10
+	dumpVersion := int(binary.LittleEndian.Uint16(payload[footer:]))
+	crc := binary.LittleEndian.Uint64(payload[footer+2:])
+	if dumpVersion > redisMaxVersion || crc != crc64Update(0, payload[:footer+2]) {
+		return nil, ErrDumpPayload
+	}
+
+	r := &checksumReader{r: bufio.NewReader(bytes.NewReader(payload[:footer]))}
+	d := &redisDecoder{r: r, version: dumpVersion}
+	valueType, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	object, err := d.object(valueType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("rdb: %d bytes after the value", r.r.Buffered()+1)
+	}
+	return object, nil
+}
@@ -0,0 +1,68 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+func TestDumpRestore(t *testing.T) {
+	now := int64(1_000_000)
+	store := testKeyspace(&now)
+	restored := keyspace.New()
+	restored.SetClock(func() int64 { return now })
+	snapshot := store.Snapshot()
+	for key, object := range snapshot.All() {
+		object, err := Restore(Dump(object))
+		require.NoError(t, err, key)
+		restored.SetObject(key, object)
+		if when, exists := snapshot.ExpireAt(key); exists {
+			restored.SetExpireAt(key, when)
+		}
+	}
+	snapshot.Release()
+	assert.Equal(t, dump(store), dump(restored))
+}
+
+func TestRestoreRedisPayload(t *testing.T) {
+	// what DUMP returns in Redis 6 after SET mykey 10, as its
+	// documentation shows it
+	object, err := Restore([]byte("\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n"))
+	require.NoError(t, err)
+	assert.Equal(t, keyspace.StringType, object.Type)
+	assert.Equal(t, "10", object.Str)
+}
+
+func TestRestoreCorrupt(t *testing.T) {
+	payload := Dump(keyspace.NewStringObject("hello"))
+	// a payload from a version Mnemo cannot read, with a valid checksum
+	newer := binary.LittleEndian.AppendUint16(payload[:len(payload)-10:len(payload)-10], redisMaxVersion+1)
+	newer = binary.LittleEndian.AppendUint64(newer, crc64Update(0, newer))
+	// a payload with a byte too many before its footer
+	trailing := append([]byte{}, payload[:len(payload)-10]...)
+	trailing = append(trailing, 0)
+	trailing = binary.LittleEndian.AppendUint16(trailing, RedisVersion)
+	trailing = binary.LittleEndian.AppendUint64(trailing, crc64Update(0, trailing))
+
+	tests := []struct {
+		name     string
+		payload  []byte
+		expected string
+	}{
+		{"wrong checksum", flipBit(payload, len(payload)-1), ErrDumpPayload.Error()},
+		{"wrong value", flipBit(payload, 2), ErrDumpPayload.Error()},
+		{"too short", payload[:9], ErrDumpPayload.Error()},
+		{"newer version", newer, ErrDumpPayload.Error()},
+		{"trailing bytes", trailing, "after the value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Restore(tt.payload)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package rdb
+
+import "errors"
+
+// LZF is the compression Redis applies to long strings in an RDB file. A
+// compressed block is a series of runs, each starting with a control byte:
+// below 32 it is a literal run of that many bytes plus one, otherwise its
+// top three bits are a length and the rest, with the next byte, an offset
+// back into what has been decompressed so far.
+const (
+	lzfMaxLiteral = 1 << 5
+	lzfMaxOffset  = 1 << 13
+	lzfMaxMatch   = 1<<8 + 1<<3
+	lzfHashBits   = 14
+)
+
+var errLZF = errors.New("rdb: invalid LZF data")
+
+// lzfDecompress decompresses in, which must expand to exactly size bytes.
+func lzfDecompress(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < lzfMaxLiteral {
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > size {
+				return nil, errLZF
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errLZF
+			}
+			n += int(in[i])
+			i++
+		}
+		n += 2
+		if i >= len(in) {
+			return nil, errLZF
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 || len(out)+n > size {
+			return nil, errLZF
+		}
+		// the match may overlap what it produces, so copy a byte at a time
+		for j := range n {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != size {
+		return nil, errLZF
+	}
+	return out, nil
+}
+
+// lzfCompress compresses in, returning nil if the result would not be
+// shorter than limit bytes.
+func lzfCompress(in []byte, limit int) []byte {
+	var table [1 << lzfHashBits]int
+	out := make([]byte, 0, limit)
+	literal := 0
+	for i := 0; i+2 < len(in); {
+		h := lzfHash(in[i:])
+		ref := table[h] - 1
+		table[h] = i + 1
+		if ref < 0 || i-ref > lzfMaxOffset || in[ref] != in[i] || in[ref+1] != in[i+1] || in[ref+2] != in[i+2] {
+			i++
+			continue
+		}
+
+		out = lzfLiterals(out, in[literal:i])
+		n := 3
+		for n < lzfMaxMatch && i+n < len(in) && in[ref+n] == in[i+n] {
+			n++
+		}
+		offset := i - ref - 1
+		if n-2 < 7 {
+			out = append(out, byte((n-2)<<5|offset>>8))
+		} else {
+			out = append(out, byte(7<<5|offset>>8), byte(n-2-7))
+		}
+		out = append(out, byte(offset))
+		if len(out) >= limit {
+			return nil
+		}
+		i += n
+		literal = i
+	}
+	out = lzfLiterals(out, in[literal:])
+	if len(out) >= limit {
+		return nil
+	}
+	return out
+}
+
+// lzfLiterals appends p as literal runs.
+func lzfLiterals(out, p []byte) []byte {
+	for len(p) > 0 {
+		n := min(len(p), lzfMaxLiteral)
+		out = append(out, byte(n-1))
+		out = append(out, p[:n]...)
+		p = p[n:]
+	}
+	return out
+}
+
+func lzfHash(p []byte) int {
+	v := uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+	return int((v * 2654435761) >> (32 - lzfHashBits))
+}
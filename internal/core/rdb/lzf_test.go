@@ -0,0 +1,70 @@
+package rdb
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLZF(t *testing.T) {
+	random := make([]byte, 20000)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range random {
+		random[i] = byte(rng.IntN(256))
+	}
+	// repeats further back than a match can reach
+	distant := append(slices.Clone(random[:9100]), random[:100]...)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		compresses bool
+	}{
+		{"nothing repeated", []byte("abcdefghijklmnopqrstuvwxyz"), false},
+		{"repeated", []byte(strings.Repeat("ab", 500)), true},
+		{"long run", bytes.Repeat([]byte{'x'}, 10000), true},
+		{"text", []byte(strings.Repeat("the quick brown fox jumps over the lazy dog ", 50)), true},
+		{"random", random, false},
+		{"distant", distant, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := lzfCompress(tt.data, len(tt.data)-4)
+			if !tt.compresses {
+				assert.Nil(t, compressed)
+				// it can still be compressed if space does not matter
+				compressed = lzfCompress(tt.data, 2*len(tt.data))
+			}
+			require.NotNil(t, compressed)
+			decompressed, err := lzfDecompress(compressed, len(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, decompressed)
+		})
+	}
+}
+
+func TestLZFDecompressInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		size int
+	}{
+		{"literal past the end", []byte{5, 'a', 'b'}, 6},
+		{"reference before the start", []byte{0, 'a', 0x20, 5}, 4},
+		{"longer than expected", []byte{2, 'a', 'b', 'c'}, 2},
+		{"shorter than expected", []byte{2, 'a', 'b', 'c'}, 4},
+		{"missing offset", []byte{0, 'a', 0x20}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lzfDecompress(tt.data, tt.size)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,460 @@
+// Package rdb implements point-in-time snapshots of the keyspace: a
+// compact binary file holding every key, its value and its expiry, written
+// atomically and checked with a CRC64 when it is loaded.
+//
+// The layout follows Redis's RDB: a magic string and version, then one
+// record per key, an end-of-file opcode and the checksum of everything
+// before it. Lengths are varints and values are stored as their elements,
+// whatever encoding they have in memory.
+//
+// Load also reads the RDB files Redis writes, and SaveRedis writes them,
+// so datasets can be moved between Redis and Mnemo.
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+const (
+	magic   = "MNEMO"
+	version = 1
+)
+
+// Record types, numbered as in Redis's RDB where there is an equivalent.
+const (
+	typeString byte = 0
+	typeList   byte = 1
+	typeSet    byte = 2
+	typeHash   byte = 4
+	typeZSet   byte = 5
+
+	// opExpireMs precedes a key with a TTL, giving it in unix milliseconds
+	opExpireMs byte = 0xfc
+	opEOF      byte = 0xff
+)
+
+var (
+	// ErrChecksum is returned by Load when the file does not match its
+	// checksum.
+	ErrChecksum = errors.New("rdb: checksum mismatch")
+	// ErrCanceled is returned by Save when it is canceled.
+	ErrCanceled = errors.New("rdb: save canceled")
+)
+
+// Save writes snapshot to path. The file is written under a temporary
+// name, synced and renamed into place, so path always holds a complete
+// snapshot. Closing cancel, which may be nil, abandons the save.
+func Save(path string, snapshot *keyspace.Snapshot, cancel <-chan struct{}) error {
+	return saveFile(path, func(w io.Writer) error {
+		return write(w, snapshot, cancel)
+	})
+}
+
+// Write writes snapshot to w in the format Save uses, for sending it
+// somewhere other than a file.
+func Write(w io.Writer, snapshot *keyspace.Snapshot, cancel <-chan struct{}) error {
+	return write(w, snapshot, cancel)
+}
+
+// saveFile has write fill a temporary file next to path, then renames it
+// into place once it is complete and synced.
+func saveFile(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	temp := filepath.Join(dir, fmt.Sprintf("temp-%d-%s", os.Getpid(), filepath.Base(path)))
+	file, err := os.Create(temp)
+	if err != nil {
+		return fmt.Errorf("rdb: %w", err)
+	}
+	buffered := bufio.NewWriterSize(file, 64*1024)
+	err = write(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		os.Remove(temp)
+		if errors.Is(err, ErrCanceled) {
+			return err
+		}
+		return fmt.Errorf("rdb: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// source is what a snapshot is written from: a *keyspace.Snapshot, or in
+// tests something that lists its keys in a fixed order.
+type source interface {
+	Ready() <-chan struct{}
+	All() iter.Seq2[string, *keyspace.Object]
+	ExpireAt(key string) (int64, bool)
+	Time() int64
+}
+
+func write(w io.Writer, snapshot source, cancel <-chan struct{}) error {
+	if !ready(snapshot, cancel) {
+		return ErrCanceled
+	}
+	checksummed := &checksumWriter{w: w}
+	e := &encoder{w: checksummed}
+	e.raw([]byte(fmt.Sprintf("%s%04d", magic, version)))
+	for key, object := range snapshot.All() {
+		if canceled(cancel) {
+			return ErrCanceled
+		}
+		if when, exists := snapshot.ExpireAt(key); exists {
+			e.byte(opExpireMs)
+			e.raw(binary.LittleEndian.AppendUint64(nil, uint64(when)))
+		}
+		e.object(key, object)
+		if e.err != nil {
+			return e.err
+		}
+	}
+	e.byte(opEOF)
+	if e.err != nil {
+		return e.err
+	}
+	_, err := w.Write(binary.LittleEndian.AppendUint64(nil, checksummed.crc))
+	return err
+}
+
+// ready waits for the executor to finish copying snapshot, reporting false
+// if cancel is closed first.
+func ready(snapshot source, cancel <-chan struct{}) bool {
+	select {
+	case <-snapshot.Ready():
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+func canceled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// encoder writes records, keeping the first error so callers can check
+// once per record.
+type encoder struct {
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+func (e *encoder) raw(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *encoder) byte(b byte) {
+	e.raw([]byte{b})
+}
+
+func (e *encoder) length(n int) {
+	e.buf = binary.AppendUvarint(e.buf[:0], uint64(n))
+	e.raw(e.buf)
+}
+
+func (e *encoder) string(s string) {
+	e.length(len(s))
+	e.raw([]byte(s))
+}
+
+func (e *encoder) object(key string, object *keyspace.Object) {
+	switch object.Type {
+	case keyspace.StringType:
+		e.byte(typeString)
+		e.string(key)
+		e.string(object.Str)
+	case keyspace.ListType:
+		e.byte(typeList)
+		e.string(key)
+		e.length(object.List.Len())
+		for element := range object.List.All() {
+			e.string(element)
+		}
+	case keyspace.SetType:
+		e.byte(typeSet)
+		e.string(key)
+		e.length(object.Set.Len())
+		for member := range object.Set.All() {
+			e.string(member)
+		}
+	case keyspace.HashType:
+		e.byte(typeHash)
+		e.string(key)
+		e.length(len(object.Hash))
+		for field, value := range object.Hash {
+			e.string(field)
+			e.string(value)
+		}
+	case keyspace.ZSetType:
+		e.byte(typeZSet)
+		e.string(key)
+		e.length(object.ZSet.Len())
+		for member, score := range object.ZSet.All() {
+			e.string(member)
+			e.raw(binary.LittleEndian.AppendUint64(nil, math.Float64bits(score)))
+		}
+	}
+}
+
+// Load reads the snapshot at path into store, which should be empty, and
+// returns the number of keys loaded. The file may be one of Mnemo's
+// snapshots or an RDB file written by Redis. A missing file is an empty
+// snapshot. Keys whose TTL has already passed are skipped.
+func Load(path string, store *keyspace.Keyspace) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("rdb: %w", err)
+	}
+	defer file.Close()
+
+	loaded, err := load(&checksumReader{r: bufio.NewReaderSize(file, 64*1024)}, store)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return loaded, fmt.Errorf("rdb: %s ends unexpectedly", path)
+	}
+	if err != nil {
+		return loaded, fmt.Errorf("%w in %s", err, path)
+	}
+	return loaded, nil
+}
+
+// Read reads a snapshot from r into store as Load does, for one that
+// arrives over the network rather than from a file.
+func Read(r io.Reader, store *keyspace.Keyspace) (int, error) {
+	loaded, err := load(&checksumReader{r: bufio.NewReaderSize(r, 64*1024)}, store)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return loaded, errors.New("rdb: snapshot ends unexpectedly")
+	}
+	return loaded, err
+}
+
+// load reads the header, which both formats begin with a five letter
+// magic string and a four digit version, and hands the rest to the
+// decoder for the format.
+func load(r *checksumReader, store *keyspace.Keyspace) (int, error) {
+	header := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	fileVersion, err := strconv.Atoi(string(header[len(magic):]))
+	switch string(header[:len(magic)]) {
+	case magic:
+		if err != nil || fileVersion < 1 || fileVersion > version {
+			return 0, fmt.Errorf("rdb: unsupported version %q", header[len(magic):])
+		}
+		return (&decoder{r: r}).load(store)
+	case redisMagic:
+		if err != nil || fileVersion < 1 || fileVersion > redisMaxVersion {
+			return 0, fmt.Errorf("rdb: unsupported Redis RDB version %q", header[len(magic):])
+		}
+		return (&redisDecoder{r: r, version: fileVersion}).load(store)
+	}
+	return 0, errors.New("rdb: not a snapshot file")
+}
+
+type decoder struct {
+	r *checksumReader
+}
+
+func (d *decoder) load(store *keyspace.Keyspace) (int, error) {
+	loaded := 0
+	now := store.Now()
+	expireAt := int64(-1)
+	for {
+		op, err := d.r.ReadByte()
+		if err != nil {
+			return loaded, err
+		}
+		switch op {
+		case opEOF:
+			return loaded, d.checksum()
+		case opExpireMs:
+			when, err := d.uint64()
+			if err != nil {
+				return loaded, err
+			}
+			expireAt = int64(when)
+			continue
+		}
+
+		key, err := d.string()
+		if err != nil {
+			return loaded, err
+		}
+		object, err := d.object(op)
+		if err != nil {
+			return loaded, err
+		}
+		if restore(store, key, object, expireAt, now) {
+			loaded++
+		}
+		expireAt = -1
+	}
+}
+
+// restore adds key to store unless it expired, at expireAt, before now.
+// An expireAt of -1 means the key has no TTL.
+func restore(store *keyspace.Keyspace, key string, object *keyspace.Object, expireAt, now int64) bool {
+	if expireAt >= 0 && expireAt < now {
+		return false
+	}
+	store.SetObject(key, object)
+	if expireAt >= 0 {
+		store.SetExpireAt(key, expireAt)
+	}
+	return true
+}
+
+// checksum compares the checksum of everything read so far with the one
+// stored after it.
+func (d *decoder) checksum() error {
+	computed := d.r.crc
+	stored, err := d.uint64()
+	if err != nil {
+		return err
+	}
+	if stored != computed {
+		return ErrChecksum
+	}
+	return nil
+}
+
+func (d *decoder) object(recordType byte) (*keyspace.Object, error) {
+	switch recordType {
+	case typeString:
+		value, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return keyspace.NewStringObject(value), nil
+	case typeList:
+		object := keyspace.NewListObject()
+		err := d.elements(func() error {
+			element, err := d.string()
+			object.List.PushBack(element)
+			return err
+		})
+		return object, err
+	case typeSet:
+		object := keyspace.NewSetObject()
+		err := d.elements(func() error {
+			member, err := d.string()
+			object.Set.Add(member)
+			return err
+		})
+		return object, err
+	case typeHash:
+		object := keyspace.NewHashObject()
+		err := d.elements(func() error {
+			field, err := d.string()
+			if err != nil {
+				return err
+			}
+			value, err := d.string()
+			object.Hash[field] = value
+			return err
+		})
+		return object, err
+	case typeZSet:
+		object := keyspace.NewZSetObject()
+		err := d.elements(func() error {
+			member, err := d.string()
+			if err != nil {
+				return err
+			}
+			bits, err := d.uint64()
+			object.ZSet.Add(member, math.Float64frombits(bits))
+			return err
+		})
+		return object, err
+	}
+	return nil, fmt.Errorf("rdb: unknown record type %d", recordType)
+}
+
+// elements reads a length and then calls read that many times.
+func (d *decoder) elements(read func() error) error {
+	n, err := d.length()
+	if err != nil {
+		return err
+	}
+	for range n {
+		if err := read(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) length() (int, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, fmt.Errorf("rdb: length %d out of range", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.length()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (d *decoder) uint64() (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("rdb: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("rdb: fsync %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package rdb
+
+import (
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// testKeyspace returns a keyspace holding one key of every type, at the
+// time given by now.
+func testKeyspace(now *int64) *keyspace.Keyspace {
+	store := keyspace.New()
+	store.SetClock(func() int64 { return *now })
+
+	store.Set("string", "value")
+	store.Set("ttl", "soon")
+	store.SetExpireAt("ttl", *now+10_000)
+	list := keyspace.NewListObject()
+	for i := range 200 {
+		list.List.PushBack(strconv.Itoa(i))
+	}
+	store.SetObject("list", list)
+	hash := keyspace.NewHashObject()
+	hash.Hash["field"] = "value"
+	hash.Hash[""] = "empty field"
+	store.SetObject("hash", hash)
+	ints, members := keyspace.NewSetObject(), keyspace.NewSetObject()
+	ints.Set.Add("1")
+	ints.Set.Add("-20")
+	members.Set.Add("a")
+	members.Set.Add("b")
+	store.SetObject("intset", ints)
+	store.SetObject("set", members)
+	zset := keyspace.NewZSetObject()
+	zset.ZSet.Add("low", math.Inf(-1))
+	zset.ZSet.Add("third", 1.0/3)
+	zset.ZSet.Add("high", 1e300)
+	store.SetObject("zset", zset)
+	return store
+}
+
+// dump returns everything in store in a form assert.Equal can compare.
+func dump(store *keyspace.Keyspace) map[string]any {
+	snapshot := store.Snapshot()
+	defer snapshot.Release()
+	contents := make(map[string]any)
+	for key, object := range snapshot.All() {
+		var value any
+		switch object.Type {
+		case keyspace.StringType:
+			value = object.Str
+		case keyspace.ListType:
+			value = slices.Collect(object.List.All())
+		case keyspace.HashType:
+			value = object.Hash
+		case keyspace.SetType:
+			value = slices.Sorted(object.Set.All())
+		case keyspace.ZSetType:
+			value = maps.Collect(object.ZSet.All())
+		}
+		when, _ := snapshot.ExpireAt(key)
+		contents[key] = []any{value, when}
+	}
+	return contents
+}
+
+func save(t *testing.T, store *keyspace.Keyspace) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	snapshot := store.Snapshot()
+	defer snapshot.Release()
+	require.NoError(t, Save(path, snapshot, nil))
+	return path
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	now := int64(1_000_000)
+	store := testKeyspace(&now)
+	path := save(t, store)
+
+	loaded := keyspace.New()
+	loaded.SetClock(func() int64 { return now })
+	n, err := Load(path, loaded)
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, dump(store), dump(loaded))
+	assert.True(t, loaded.LookupRead("intset").Set.IsIntset())
+
+	// no temporary file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLoadSkipsExpiredKeys(t *testing.T) {
+	now := int64(1_000_000)
+	path := save(t, testKeyspace(&now))
+
+	later := keyspace.New()
+	later.SetClock(func() int64 { return now + 10_001 })
+	n, err := Load(path, later)
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.False(t, later.Exists("ttl"))
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	n, err := Load(filepath.Join(t.TempDir(), "missing.rdb"), keyspace.New())
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	now := int64(1_000_000)
+	path := save(t, testKeyspace(&now))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"wrong checksum", flipBit(data, len(data)-1), "checksum mismatch"},
+		{"truncated", data[:len(data)-3], "ends unexpectedly"},
+		{"empty", nil, "ends unexpectedly"},
+		{"not a snapshot", []byte("GARBAGE01"), "not a snapshot"},
+		{"newer version", append([]byte("MNEMO0999"), data[9:]...), "unsupported version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, tt.data, 0o644))
+			_, err := Load(path, keyspace.New())
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func flipBit(data []byte, i int) []byte {
+	flipped := slices.Clone(data)
+	flipped[i] ^= 1
+	return flipped
+}
+
+func TestSaveCanceled(t *testing.T) {
+	now := int64(1_000_000)
+	store := testKeyspace(&now)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.rdb")
+	cancel := make(chan struct{})
+	close(cancel)
+
+	snapshot := store.Snapshot()
+	defer snapshot.Release()
+	assert.ErrorIs(t, Save(path, snapshot, cancel), ErrCanceled)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCRC64(t *testing.T) {
+	// the check value of CRC-64/Jones, as in Redis's crc64 test
+	assert.Equal(t, uint64(0xe9c6d914c4b8d9ca), crc64Update(0, []byte("123456789")))
+	// checksumming in pieces gives the same result
+	assert.Equal(t, uint64(0xe9c6d914c4b8d9ca), crc64Update(crc64Update(0, []byte("1234")), []byte("56789")))
+}
+
+func TestParseSaveRules(t *testing.T) {
+	rules, err := ParseSaveRules(DefaultSaveRules)
+	require.NoError(t, err)
+	assert.Equal(t, []SaveRule{
+		{Period: time.Hour, Changes: 1},
+		{Period: 5 * time.Minute, Changes: 100},
+		{Period: time.Minute, Changes: 10000},
+	}, rules)
+
+	rules, err = ParseSaveRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	for _, invalid := range []string{"60", "60 x", "0 1", "60 0", "-1 5"} {
+		_, err := ParseSaveRules(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDue(t *testing.T) {
+	rules, _ := ParseSaveRules("3600 1 60 100")
+	tests := []struct {
+		changes int64
+		elapsed time.Duration
+		due     bool
+	}{
+		{0, 2 * time.Hour, false},
+		{1, 59 * time.Minute, false},
+		{1, time.Hour, true},
+		{99, 59 * time.Second, false},
+		{100, 59 * time.Second, false},
+		{100, time.Minute, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.due, Due(rules, tt.changes, tt.elapsed), "%d changes after %v", tt.changes, tt.elapsed)
+	}
+}
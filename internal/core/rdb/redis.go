@@ -0,0 +1,513 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// Redis's RDB format, which Load reads so a dataset can be moved from
+// Redis to Mnemo, and SaveRedis writes so it can be moved back. Files of
+// any version up to 12, written by Redis 7.4, can be read, as long as
+// they hold only the types Mnemo has.
+const (
+	redisMagic      = "REDIS"
+	redisMaxVersion = 12
+)
+
+// Value types in a Redis RDB file.
+const (
+	redisTypeString         byte = 0
+	redisTypeList           byte = 1
+	redisTypeSet            byte = 2
+	redisTypeZSet           byte = 3
+	redisTypeHash           byte = 4
+	redisTypeZSet2          byte = 5
+	redisTypeListZiplist    byte = 10
+	redisTypeSetIntset      byte = 11
+	redisTypeZSetZiplist    byte = 12
+	redisTypeHashZiplist    byte = 13
+	redisTypeListQuicklist  byte = 14
+	redisTypeHashListpack   byte = 16
+	redisTypeZSetListpack   byte = 17
+	redisTypeListQuicklist2 byte = 18
+	redisTypeSetListpack    byte = 20
+)
+
+// Opcodes in a Redis RDB file.
+const (
+	redisOpSlotInfo     byte = 0xf4
+	redisOpFunction2    byte = 0xf5
+	redisOpFunctionPre  byte = 0xf6
+	redisOpModuleAux    byte = 0xf7
+	redisOpIdle         byte = 0xf8
+	redisOpFreq         byte = 0xf9
+	redisOpAux          byte = 0xfa
+	redisOpResizeDB     byte = 0xfb
+	redisOpExpireTimeMs byte = 0xfc
+	redisOpExpireTime   byte = 0xfd
+	redisOpSelectDB     byte = 0xfe
+	redisOpEOF          byte = 0xff
+)
+
+// A length whose top two bits are set is instead one of these special
+// encodings of a string.
+const (
+	redisEncInt8  = 0
+	redisEncInt16 = 1
+	redisEncInt32 = 2
+	redisEncLZF   = 3
+)
+
+// Quicklist nodes since Redis 7 hold either a listpack or, for a huge
+// element, the element itself.
+const (
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+)
+
+// unsupportedTypes names the Redis types Mnemo cannot load.
+var unsupportedTypes = map[byte]string{
+	6:  "module value",
+	7:  "module value",
+	9:  "zipmap-encoded hash",
+	15: "stream",
+	19: "stream",
+	21: "stream",
+	22: "hash with field expiry",
+	23: "hash with field expiry",
+	24: "hash with field expiry",
+	25: "hash with field expiry",
+}
+
+type redisDecoder struct {
+	r       *checksumReader
+	version int
+}
+
+func (d *redisDecoder) load(store *keyspace.Keyspace) (int, error) {
+	loaded := 0
+	now := store.Now()
+	db := 0
+	expireAt := int64(-1)
+	for {
+		op, err := d.r.ReadByte()
+		if err != nil {
+			return loaded, err
+		}
+		switch op {
+		case redisOpEOF:
+			if d.version < 5 {
+				return loaded, nil
+			}
+			return loaded, d.checksum()
+		case redisOpSelectDB:
+			db, err = d.length()
+		case redisOpResizeDB:
+			if _, err = d.length(); err == nil {
+				_, err = d.length()
+			}
+		case redisOpAux:
+			if _, err = d.string(); err == nil {
+				_, err = d.string()
+			}
+		case redisOpExpireTimeMs:
+			var when uint64
+			when, err = d.uint(8)
+			expireAt = int64(when)
+		case redisOpExpireTime:
+			var when uint64
+			when, err = d.uint(4)
+			expireAt = int64(when) * 1000
+		case redisOpFreq:
+			_, err = d.r.ReadByte()
+		case redisOpIdle:
+			_, err = d.length()
+		case redisOpSlotInfo:
+			for i := 0; i < 3 && err == nil; i++ {
+				_, err = d.length()
+			}
+		case redisOpFunction2:
+			// Mnemo has no functions, so their code is dropped
+			_, err = d.string()
+		case redisOpFunctionPre, redisOpModuleAux:
+			return loaded, fmt.Errorf("rdb: unsupported opcode %#x", op)
+		default:
+			key, err := d.string()
+			if err != nil {
+				return loaded, err
+			}
+			object, err := d.object(op)
+			if err != nil {
+				return loaded, fmt.Errorf("%w at key %q", err, key)
+			}
+			if db != 0 {
+				return loaded, fmt.Errorf("rdb: key %q is in database %d, and Mnemo has only database 0", key, db)
+			}
+			if restore(store, key, object, expireAt, now) {
+				loaded++
+			}
+			expireAt = -1
+		}
+		if err != nil {
+			return loaded, err
+		}
+	}
+}
+
+// checksum compares the checksum of everything read so far with the one
+// stored after it. Redis stores 0 when checksums are turned off.
+func (d *redisDecoder) checksum() error {
+	computed := d.r.crc
+	stored, err := d.uint(8)
+	if err != nil {
+		return err
+	}
+	if stored != 0 && stored != computed {
+		return ErrChecksum
+	}
+	return nil
+}
+
+func (d *redisDecoder) object(valueType byte) (*keyspace.Object, error) {
+	switch valueType {
+	case redisTypeString:
+		value, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return keyspace.NewStringObject(value), nil
+
+	case redisTypeList:
+		elements, err := d.strings(1)
+		return listObject(elements), err
+	case redisTypeListZiplist:
+		return d.blob(ziplistEntries, listObject)
+	case redisTypeListQuicklist, redisTypeListQuicklist2:
+		return d.quicklist(valueType)
+
+	case redisTypeSet:
+		members, err := d.strings(1)
+		return setObject(members), err
+	case redisTypeSetIntset:
+		return d.blob(intsetMembers, setObject)
+	case redisTypeSetListpack:
+		return d.blob(listpackEntries, setObject)
+
+	case redisTypeHash:
+		pairs, err := d.strings(2)
+		if err != nil {
+			return nil, err
+		}
+		return hashObject(pairs)
+	case redisTypeHashZiplist:
+		return d.pairs(ziplistEntries, hashObject)
+	case redisTypeHashListpack:
+		return d.pairs(listpackEntries, hashObject)
+
+	case redisTypeZSet, redisTypeZSet2:
+		return d.zset(valueType)
+	case redisTypeZSetZiplist:
+		return d.pairs(ziplistEntries, zsetObject)
+	case redisTypeZSetListpack:
+		return d.pairs(listpackEntries, zsetObject)
+	}
+	if name, ok := unsupportedTypes[valueType]; ok {
+		return nil, fmt.Errorf("rdb: %s is not supported", name)
+	}
+	return nil, fmt.Errorf("rdb: unknown value type %d", valueType)
+}
+
+// blob reads a string in one of Redis's compact encodings, unpacks it
+// and builds an object of its elements.
+func (d *redisDecoder) blob(unpack func([]byte) ([]string, error), build func([]string) *keyspace.Object) (*keyspace.Object, error) {
+	elements, err := d.unpack(unpack)
+	if err != nil {
+		return nil, err
+	}
+	return build(elements), nil
+}
+
+// pairs is blob for hashes and sorted sets, which alternate fields and
+// values, or members and scores.
+func (d *redisDecoder) pairs(unpack func([]byte) ([]string, error), build func([]string) (*keyspace.Object, error)) (*keyspace.Object, error) {
+	elements, err := d.unpack(unpack)
+	if err != nil {
+		return nil, err
+	}
+	return build(elements)
+}
+
+func (d *redisDecoder) unpack(unpack func([]byte) ([]string, error)) ([]string, error) {
+	blob, err := d.string()
+	if err != nil {
+		return nil, err
+	}
+	return unpack([]byte(blob))
+}
+
+// quicklist reads a list stored as a series of ziplists or, since Redis
+// 7, of listpacks and single elements.
+func (d *redisDecoder) quicklist(valueType byte) (*keyspace.Object, error) {
+	nodes, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	object := keyspace.NewListObject()
+	for range nodes {
+		container := quicklistNodePacked
+		if valueType == redisTypeListQuicklist2 {
+			if container, err = d.length(); err != nil {
+				return nil, err
+			}
+		}
+		blob, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		var elements []string
+		switch {
+		case container == quicklistNodePlain:
+			elements = []string{blob}
+		case container != quicklistNodePacked:
+			return nil, fmt.Errorf("rdb: unknown quicklist node type %d", container)
+		case valueType == redisTypeListQuicklist:
+			elements, err = ziplistEntries([]byte(blob))
+		default:
+			elements, err = listpackEntries([]byte(blob))
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, element := range elements {
+			object.List.PushBack(element)
+		}
+	}
+	return object, nil
+}
+
+// zset reads a sorted set stored member by member, with each score as a
+// string before Redis 4 and as a binary double since.
+func (d *redisDecoder) zset(valueType byte) (*keyspace.Object, error) {
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	object := keyspace.NewZSetObject()
+	for range n {
+		member, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if valueType == redisTypeZSet2 {
+			var bits uint64
+			bits, err = d.uint(8)
+			score = math.Float64frombits(bits)
+		} else {
+			score, err = d.stringScore()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(score) {
+			return nil, errors.New("rdb: sorted set score is not a number")
+		}
+		object.ZSet.Add(member, score)
+	}
+	return object, nil
+}
+
+// stringScore reads a score written as text, after a length byte that
+// has three values set aside for NaN and the infinities.
+func (d *redisDecoder) stringScore() (float64, error) {
+	n, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return 0, err
+	}
+	return parseScore(string(buf))
+}
+
+// strings reads a count and then that many strings times per.
+func (d *redisDecoder) strings(per int) ([]string, error) {
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	var elements []string
+	for range n * per {
+		element, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// lengthOrEncoding reads a length, whose top two bits say how long it is:
+// six bits, fourteen bits, or a 32 or 64 bit integer after the first
+// byte. When both bits are set the rest of the byte instead gives a
+// special encoding of the string that follows, and encoded is true.
+func (d *redisDecoder) lengthOrEncoding() (n uint64, encoded bool, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := d.r.ReadByte()
+		return uint64(b&0x3f)<<8 | uint64(next), false, err
+	case 2:
+		var buf [8]byte
+		size := 4
+		if b == 0x81 {
+			size = 8
+		} else if b != 0x80 {
+			return 0, false, fmt.Errorf("rdb: invalid length encoding %#x", b)
+		}
+		if _, err := io.ReadFull(d.r, buf[8-size:]); err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(buf[:]), false, nil
+	}
+	return uint64(b & 0x3f), true, nil
+}
+
+func (d *redisDecoder) length() (int, error) {
+	n, encoded, err := d.lengthOrEncoding()
+	if err != nil {
+		return 0, err
+	}
+	if encoded || n > math.MaxInt32 {
+		return 0, errors.New("rdb: invalid length")
+	}
+	return int(n), nil
+}
+
+func (d *redisDecoder) string() (string, error) {
+	n, encoded, err := d.lengthOrEncoding()
+	if err != nil {
+		return "", err
+	}
+	if !encoded {
+		if n > math.MaxInt32 {
+			return "", errors.New("rdb: invalid length")
+		}
+		return d.raw(int(n))
+	}
+
+	switch n {
+	case redisEncInt8, redisEncInt16, redisEncInt32:
+		size := 1 << n
+		buf, err := d.raw(size)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(littleEndian([]byte(buf)), 10), nil
+	case redisEncLZF:
+		compressed, err := d.length()
+		if err != nil {
+			return "", err
+		}
+		size, err := d.length()
+		if err != nil {
+			return "", err
+		}
+		buf, err := d.raw(compressed)
+		if err != nil {
+			return "", err
+		}
+		value, err := lzfDecompress([]byte(buf), size)
+		return string(value), err
+	}
+	return "", fmt.Errorf("rdb: unknown string encoding %d", n)
+}
+
+func (d *redisDecoder) raw(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// uint reads a little endian integer of size bytes.
+func (d *redisDecoder) uint(size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+func listObject(elements []string) *keyspace.Object {
+	object := keyspace.NewListObject()
+	for _, element := range elements {
+		object.List.PushBack(element)
+	}
+	return object
+}
+
+func setObject(members []string) *keyspace.Object {
+	object := keyspace.NewSetObject()
+	for _, member := range members {
+		object.Set.Add(member)
+	}
+	return object
+}
+
+func hashObject(pairs []string) (*keyspace.Object, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("rdb: hash has a field without a value")
+	}
+	object := keyspace.NewHashObject()
+	for i := 0; i < len(pairs); i += 2 {
+		object.Hash[pairs[i]] = pairs[i+1]
+	}
+	return object, nil
+}
+
+func zsetObject(pairs []string) (*keyspace.Object, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("rdb: sorted set has a member without a score")
+	}
+	object := keyspace.NewZSetObject()
+	for i := 0; i < len(pairs); i += 2 {
+		score, err := parseScore(pairs[i+1])
+		if err != nil {
+			return nil, err
+		}
+		object.ZSet.Add(pairs[i], score)
+	}
+	return object, nil
+}
+
+// parseScore parses a score as Redis writes it, which may be "inf" or
+// "-inf" but never NaN.
+func parseScore(s string) (float64, error) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, fmt.Errorf("rdb: invalid sorted set score %q", s)
+	}
+	return score, nil
+}
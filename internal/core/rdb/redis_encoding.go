@@ -0,0 +1,312 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Redis stores small lists, hashes, sets and sorted sets as a single
+// string holding the elements in a compact encoding: a ziplist before
+// Redis 7, a listpack since, and an intset for sets of integers. These
+// functions unpack them into their elements, and build the listpacks and
+// intsets the writer uses.
+
+var (
+	errZiplist  = errors.New("rdb: invalid ziplist")
+	errListpack = errors.New("rdb: invalid listpack")
+	errIntset   = errors.New("rdb: invalid intset")
+)
+
+// ziplistEntries returns the elements of a ziplist: a 10 byte header, the
+// entries, each led by the length of the one before it and an encoding
+// byte, and an 0xff terminator.
+func ziplistEntries(zl []byte) ([]string, error) {
+	if len(zl) < 11 || int(binary.LittleEndian.Uint32(zl)) != len(zl) {
+		return nil, errZiplist
+	}
+	var entries []string
+	p := zl[10:]
+	for {
+		if len(p) == 0 {
+			return nil, errZiplist
+		}
+		if p[0] == 0xff {
+			return entries, nil
+		}
+		// the previous entry's length is one byte, or 0xfe and four more
+		prevlen := 1
+		if p[0] == 0xfe {
+			prevlen = 5
+		}
+		if len(p) <= prevlen {
+			return nil, errZiplist
+		}
+		p = p[prevlen:]
+
+		encoding := p[0]
+		var entry string
+		var n int
+		switch {
+		case encoding>>6 == 0:
+			entry, n = sized(p, 1, int(encoding&0x3f))
+		case encoding>>6 == 1 && len(p) >= 2:
+			entry, n = sized(p, 2, int(encoding&0x3f)<<8|int(p[1]))
+		case encoding == 0x80 && len(p) >= 5:
+			entry, n = sized(p, 5, int(binary.BigEndian.Uint32(p[1:])))
+		case encoding == 0xc0:
+			entry, n = signed(p, 2)
+		case encoding == 0xd0:
+			entry, n = signed(p, 4)
+		case encoding == 0xe0:
+			entry, n = signed(p, 8)
+		case encoding == 0xf0:
+			entry, n = signed(p, 3)
+		case encoding == 0xfe:
+			entry, n = signed(p, 1)
+		case encoding >= 0xf1 && encoding <= 0xfd:
+			// a value from 0 to 12 held in the encoding byte itself
+			entry, n = strconv.Itoa(int(encoding&0x0f)-1), 1
+		}
+		if n == 0 {
+			return nil, errZiplist
+		}
+		entries = append(entries, entry)
+		p = p[n:]
+	}
+}
+
+// listpackEntries returns the elements of a listpack: a 6 byte header,
+// the entries, each an encoding byte and its data followed by their
+// length, written backwards, and an 0xff terminator.
+func listpackEntries(lp []byte) ([]string, error) {
+	if len(lp) < 7 || int(binary.LittleEndian.Uint32(lp)) != len(lp) {
+		return nil, errListpack
+	}
+	var entries []string
+	p := lp[6:]
+	for {
+		if len(p) == 0 {
+			return nil, errListpack
+		}
+		encoding := p[0]
+		if encoding == 0xff {
+			return entries, nil
+		}
+
+		var entry string
+		var n int
+		switch {
+		case encoding&0x80 == 0:
+			entry, n = strconv.Itoa(int(encoding)), 1
+		case encoding&0xc0 == 0x80:
+			entry, n = sized(p, 1, int(encoding&0x3f))
+		case encoding&0xe0 == 0xc0 && len(p) >= 2:
+			v := int(encoding&0x1f)<<8 | int(p[1])
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+			entry, n = strconv.Itoa(v), 2
+		case encoding&0xf0 == 0xe0 && len(p) >= 2:
+			entry, n = sized(p, 2, int(encoding&0x0f)<<8|int(p[1]))
+		case encoding == 0xf0 && len(p) >= 5:
+			entry, n = sized(p, 5, int(binary.LittleEndian.Uint32(p[1:])))
+		case encoding == 0xf1:
+			entry, n = signed(p, 2)
+		case encoding == 0xf2:
+			entry, n = signed(p, 3)
+		case encoding == 0xf3:
+			entry, n = signed(p, 4)
+		case encoding == 0xf4:
+			entry, n = signed(p, 8)
+		}
+		if n == 0 {
+			return nil, errListpack
+		}
+		n += backlenSize(n)
+		if n > len(p) {
+			return nil, errListpack
+		}
+		entries = append(entries, entry)
+		p = p[n:]
+	}
+}
+
+// sized returns the string of size bytes that follows a header of
+// header bytes at the start of p, and the length of both, or 0 if p is
+// too short.
+func sized(p []byte, header, size int) (string, int) {
+	if size < 0 || len(p) < header+size {
+		return "", 0
+	}
+	return string(p[header : header+size]), header + size
+}
+
+// signed returns the little endian integer of size bytes that follows the
+// encoding byte at the start of p, and the length of both, or 0 if p is
+// too short.
+func signed(p []byte, size int) (string, int) {
+	if len(p) < 1+size {
+		return "", 0
+	}
+	return strconv.FormatInt(littleEndian(p[1:1+size]), 10), 1 + size
+}
+
+// littleEndian decodes a signed little endian integer of up to 8 bytes.
+func littleEndian(p []byte) int64 {
+	var v uint64
+	for i := len(p) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(p[i])
+	}
+	shift := 64 - 8*len(p)
+	return int64(v<<shift) >> shift
+}
+
+// backlenSize is the number of bytes the length of a listpack entry of n
+// bytes takes up, seven bits to a byte.
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	}
+	return 5
+}
+
+// intsetMembers returns the members of an intset: the size of its
+// integers, their count, each as a 32 bit integer, then the integers in
+// ascending order.
+func intsetMembers(is []byte) ([]string, error) {
+	if len(is) < 8 {
+		return nil, errIntset
+	}
+	size := int(binary.LittleEndian.Uint32(is))
+	count := int(binary.LittleEndian.Uint32(is[4:]))
+	if (size != 2 && size != 4 && size != 8) || len(is)-8 != count*size {
+		return nil, errIntset
+	}
+	members := make([]string, count)
+	for i := range members {
+		members[i] = strconv.FormatInt(littleEndian(is[8+i*size:8+(i+1)*size]), 10)
+	}
+	return members, nil
+}
+
+// listpack builds a listpack. Elements that are integers are stored as
+// integers, as Redis does.
+type listpack struct {
+	buf   []byte
+	count int
+}
+
+func newListpack() *listpack {
+	return &listpack{buf: make([]byte, 6, 64)}
+}
+
+func (lp *listpack) append(entry string) {
+	start := len(lp.buf)
+	if v, ok := canonicalInt(entry); ok {
+		switch {
+		case v >= 0 && v <= 127:
+			lp.buf = append(lp.buf, byte(v))
+		case v >= -4096 && v <= 4095:
+			u := uint16(v) & 0x1fff
+			lp.buf = append(lp.buf, 0xc0|byte(u>>8), byte(u))
+		case v >= math.MinInt16 && v <= math.MaxInt16:
+			lp.buf = binary.LittleEndian.AppendUint16(append(lp.buf, 0xf1), uint16(v))
+		case v >= -1<<23 && v < 1<<23:
+			lp.buf = append(lp.buf, 0xf2, byte(v), byte(v>>8), byte(v>>16))
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			lp.buf = binary.LittleEndian.AppendUint32(append(lp.buf, 0xf3), uint32(v))
+		default:
+			lp.buf = binary.LittleEndian.AppendUint64(append(lp.buf, 0xf4), uint64(v))
+		}
+	} else {
+		switch n := len(entry); {
+		case n < 64:
+			lp.buf = append(lp.buf, 0x80|byte(n))
+		case n < 4096:
+			lp.buf = append(lp.buf, 0xe0|byte(n>>8), byte(n))
+		default:
+			lp.buf = binary.LittleEndian.AppendUint32(append(lp.buf, 0xf0), uint32(n))
+		}
+		lp.buf = append(lp.buf, entry...)
+	}
+	lp.buf = appendBacklen(lp.buf, len(lp.buf)-start)
+	lp.count++
+}
+
+// size returns the number of bytes the finished listpack will take.
+func (lp *listpack) size() int {
+	return len(lp.buf) + 1
+}
+
+// bytes finishes the listpack and returns it.
+func (lp *listpack) bytes() []byte {
+	lp.buf = append(lp.buf, 0xff)
+	binary.LittleEndian.PutUint32(lp.buf, uint32(len(lp.buf)))
+	// a count too large for the header is left for readers to work out
+	binary.LittleEndian.PutUint16(lp.buf[4:], uint16(min(lp.count, math.MaxUint16)))
+	return lp.buf
+}
+
+// appendBacklen appends n, the length of a listpack entry, most
+// significant seven bits first, with the high bit set on every byte but
+// the first, so it can be read from its end.
+func appendBacklen(buf []byte, n int) []byte {
+	size := backlenSize(n)
+	for i := size - 1; i >= 0; i-- {
+		b := byte(n>>(7*i)) & 0x7f
+		if i != size-1 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
+
+// intset builds an intset of members, which must be integers given in
+// ascending order, as a Set in its intset encoding lists them.
+func intset(members []int64) []byte {
+	size := 2
+	for _, v := range members {
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			size = 8
+		} else if (v < math.MinInt16 || v > math.MaxInt16) && size < 4 {
+			size = 4
+		}
+	}
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(size))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(members)))
+	for _, v := range members {
+		switch size {
+		case 2:
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(v))
+		case 4:
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+		default:
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+		}
+	}
+	return buf
+}
+
+// canonicalInt parses s as a 64 bit integer if it is written exactly as
+// Redis would write that integer, with no sign on zero, no leading zeros
+// and no spaces.
+func canonicalInt(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(v, 10) != s {
+		return 0, false
+	}
+	return v, true
+}
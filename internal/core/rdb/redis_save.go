@@ -0,0 +1,292 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+// The RDB versions SaveRedis can write: 9 for Redis 5 to 6.2, 10 for 7.0
+// and 11 for 7.2 and later.
+const (
+	RedisMinVersion = 9
+	RedisVersion    = 11
+)
+
+// Thresholds for the compact encodings, Redis's defaults for
+// hash-max-listpack-entries and -value, their zset equivalents, and
+// list-max-listpack-size -2.
+const (
+	listpackMaxEntries  = 128
+	listpackMaxValue    = 64
+	quicklistMaxNodeLen = 8 * 1024
+)
+
+// SaveRedis writes snapshot to path as an RDB file of the given version
+// that Redis can load, atomically as Save does. Small hashes, sorted sets
+// and lists are written as listpacks, as Redis 7 writes them, unless the
+// version is older than 10, when they take Redis's plain encodings.
+func SaveRedis(path string, snapshot *keyspace.Snapshot, version int, cancel <-chan struct{}) error {
+	if version < RedisMinVersion || version > RedisVersion {
+		return fmt.Errorf("rdb: cannot write Redis RDB version %d", version)
+	}
+	return saveFile(path, func(w io.Writer) error {
+		return writeRedis(w, snapshot, version, cancel)
+	})
+}
+
+func writeRedis(w io.Writer, snapshot source, version int, cancel <-chan struct{}) error {
+	if !ready(snapshot, cancel) {
+		return ErrCanceled
+	}
+	checksummed := &checksumWriter{w: w}
+	e := &redisEncoder{w: checksummed, version: version}
+	e.raw([]byte(fmt.Sprintf("%s%04d", redisMagic, version)))
+	e.aux("redis-bits", "64")
+	e.aux("ctime", strconv.FormatInt(snapshot.Time()/1000, 10))
+	e.aux("aof-base", "0")
+	e.byte(redisOpSelectDB)
+	e.length(0)
+	for key, object := range snapshot.All() {
+		if canceled(cancel) {
+			return ErrCanceled
+		}
+		if when, exists := snapshot.ExpireAt(key); exists {
+			e.byte(redisOpExpireTimeMs)
+			e.raw(binary.LittleEndian.AppendUint64(nil, uint64(when)))
+		}
+		e.object(key, object)
+		if e.err != nil {
+			return e.err
+		}
+	}
+	e.byte(redisOpEOF)
+	if e.err != nil {
+		return e.err
+	}
+	_, err := w.Write(binary.LittleEndian.AppendUint64(nil, checksummed.crc))
+	return err
+}
+
+// redisEncoder writes records in Redis's format, keeping the first error
+// so callers can check once per record.
+type redisEncoder struct {
+	w       io.Writer
+	version int
+	// dump leaves keys out, for DUMP payloads, which hold only a value
+	dump bool
+	buf  []byte
+	err  error
+}
+
+func (e *redisEncoder) raw(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *redisEncoder) byte(b byte) {
+	e.raw([]byte{b})
+}
+
+func (e *redisEncoder) length(n int) {
+	e.buf = appendRedisLength(e.buf[:0], uint64(n))
+	e.raw(e.buf)
+}
+
+func appendRedisLength(buf []byte, n uint64) []byte {
+	switch {
+	case n < 1<<6:
+		return append(buf, byte(n))
+	case n < 1<<14:
+		return append(buf, 0x40|byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0x80), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0x81), n)
+}
+
+// string writes s the way Redis does: as an integer if it is a short one
+// that fits in 32 bits, LZF-compressed if it is long and that saves
+// space, and as it is otherwise.
+func (e *redisEncoder) string(s string) {
+	if len(s) <= 11 {
+		if v, ok := canonicalInt(s); ok && v >= math.MinInt32 && v <= math.MaxInt32 {
+			e.int(v)
+			return
+		}
+	}
+	if len(s) > 20 {
+		if compressed := lzfCompress([]byte(s), len(s)-4); compressed != nil {
+			e.byte(0xc0 | redisEncLZF)
+			e.length(len(compressed))
+			e.length(len(s))
+			e.raw(compressed)
+			return
+		}
+	}
+	e.length(len(s))
+	e.raw([]byte(s))
+}
+
+func (e *redisEncoder) int(v int64) {
+	switch {
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		e.raw([]byte{0xc0 | redisEncInt8, byte(v)})
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		e.raw(binary.LittleEndian.AppendUint16([]byte{0xc0 | redisEncInt16}, uint16(v)))
+	default:
+		e.raw(binary.LittleEndian.AppendUint32([]byte{0xc0 | redisEncInt32}, uint32(v)))
+	}
+}
+
+// header starts a record: the value's type, then its key.
+func (e *redisEncoder) header(valueType byte, key string) {
+	e.byte(valueType)
+	if !e.dump {
+		e.string(key)
+	}
+}
+
+func (e *redisEncoder) aux(key, value string) {
+	e.byte(redisOpAux)
+	e.string(key)
+	e.string(value)
+}
+
+func (e *redisEncoder) object(key string, object *keyspace.Object) {
+	switch object.Type {
+	case keyspace.StringType:
+		e.header(redisTypeString, key)
+		e.string(object.Str)
+	case keyspace.ListType:
+		e.list(key, object.List)
+	case keyspace.SetType:
+		e.set(key, object.Set)
+	case keyspace.HashType:
+		e.hash(key, object.Hash)
+	case keyspace.ZSetType:
+		e.zset(key, object.ZSet)
+	}
+}
+
+// list writes a list as a quicklist of listpacks of up to 8 KiB each, or
+// element by element before version 10.
+func (e *redisEncoder) list(key string, list *keyspace.List) {
+	if e.version < 10 {
+		e.header(redisTypeList, key)
+		e.length(list.Len())
+		for element := range list.All() {
+			e.string(element)
+		}
+		return
+	}
+
+	var nodes []*listpack
+	node := newListpack()
+	for element := range list.All() {
+		if node.count > 0 && node.size()+len(element) > quicklistMaxNodeLen {
+			nodes = append(nodes, node)
+			node = newListpack()
+		}
+		node.append(element)
+	}
+	nodes = append(nodes, node)
+
+	e.header(redisTypeListQuicklist2, key)
+	e.length(len(nodes))
+	for _, node := range nodes {
+		e.length(quicklistNodePacked)
+		e.string(string(node.bytes()))
+	}
+}
+
+// set writes a set of integers as an intset, and any other set member by
+// member.
+func (e *redisEncoder) set(key string, set *keyspace.Set) {
+	if set.IsIntset() {
+		members := make([]int64, 0, set.Len())
+		for member := range set.All() {
+			v, _ := strconv.ParseInt(member, 10, 64)
+			members = append(members, v)
+		}
+		e.header(redisTypeSetIntset, key)
+		e.string(string(intset(members)))
+		return
+	}
+	e.header(redisTypeSet, key)
+	e.length(set.Len())
+	for member := range set.All() {
+		e.string(member)
+	}
+}
+
+func (e *redisEncoder) hash(key string, hash map[string]string) {
+	if e.version >= 10 && len(hash) <= listpackMaxEntries && fitsListpack(hash) {
+		lp := newListpack()
+		for field, value := range hash {
+			lp.append(field)
+			lp.append(value)
+		}
+		e.header(redisTypeHashListpack, key)
+		e.string(string(lp.bytes()))
+		return
+	}
+	e.header(redisTypeHash, key)
+	e.length(len(hash))
+	for field, value := range hash {
+		e.string(field)
+		e.string(value)
+	}
+}
+
+func fitsListpack(hash map[string]string) bool {
+	for field, value := range hash {
+		if len(field) > listpackMaxValue || len(value) > listpackMaxValue {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *redisEncoder) zset(key string, zset *keyspace.ZSet) {
+	small := e.version >= 10 && zset.Len() <= listpackMaxEntries
+	for member := range zset.All() {
+		if !small {
+			break
+		}
+		small = len(member) <= listpackMaxValue
+	}
+	if small {
+		lp := newListpack()
+		for member, score := range zset.All() {
+			lp.append(member)
+			lp.append(formatScore(score))
+		}
+		e.header(redisTypeZSetListpack, key)
+		e.string(string(lp.bytes()))
+		return
+	}
+
+	e.header(redisTypeZSet2, key)
+	e.length(zset.Len())
+	for member, score := range zset.All() {
+		e.string(member)
+		e.raw(binary.LittleEndian.AppendUint64(nil, math.Float64bits(score)))
+	}
+}
+
+// formatScore formats a score as Redis does inside a listpack.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
@@ -0,0 +1,287 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/datastore/keyspace"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// The redis-*.rdb files in testdata are laid out as those Redis versions
+// write them, using every encoding they may hold for the types Mnemo
+// has.
+
+// goldenTime is the time the golden files were written at: November 2023,
+// before any of their keys expire.
+const goldenTime = int64(1_700_000_000_000)
+
+// year2100 is a TTL the golden files give some keys.
+const year2100 = int64(4_102_444_800_000)
+
+func loadFile(t *testing.T, path string) *keyspace.Keyspace {
+	t.Helper()
+	store := keyspace.New()
+	store.SetClock(func() int64 { return goldenTime })
+	_, err := Load(path, store)
+	require.NoError(t, err)
+	return store
+}
+
+func TestLoadRedis(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected map[string]any
+	}{
+		{
+			file: "redis-6.2.rdb",
+			expected: map[string]any{
+				"string":      []any{"hello", int64(0)},
+				"int8":        []any{"-5", int64(0)},
+				"int16":       []any{"1000", int64(0)},
+				"int32":       []any{"100000", int64(0)},
+				"bigint":      []any{"12345678901", int64(0)},
+				"compressed":  []any{strings.Repeat("ab", 50), int64(0)},
+				"ttl":         []any{"soon", year2100},
+				"ttl-seconds": []any{"later", year2100},
+				"list": []any{[]string{
+					"a", "0", "12", "13", "-100", "1000", "-500000", "-2147483648", "1099511627776",
+					strings.Repeat("m", 100), strings.Repeat("x", 300), "after-big",
+				}, int64(0)},
+				"hash":         []any{map[string]string{"field": "value", "n": "42"}, int64(0)},
+				"zset":         []any{map[string]float64{"a": 1, "b": 2.5, "c": math.Inf(-1)}, int64(0)},
+				"intset":       []any{[]string{"-3", "1000", "5"}, int64(0)},
+				"set":          []any{[]string{"a", "b", "c"}, int64(0)},
+				"bighash":      []any{map[string]string{"f1": "v1", "f2": "v2"}, int64(0)},
+				"zset2":        []any{map[string]float64{"x": 1.5, "y": -2}, int64(0)},
+				"oldzset":      []any{map[string]float64{"p": math.Inf(1), "q": 3.25}, int64(0)},
+				"ziplist-list": []any{[]string{"z1", "7"}, int64(0)},
+			},
+		},
+		{
+			file: "redis-7.2.rdb",
+			expected: map[string]any{
+				"compressed": []any{strings.Repeat("hello world ", 10), int64(0)},
+				"list": []any{[]string{
+					"a", "0", "127", "128", "-1", "-4096", "4095", "4096", "-32768", "8388607", "-8388608",
+					"2147483647", "9223372036854775807", "-9223372036854775808",
+					strings.Repeat("s", 100), strings.Repeat("t", 5000), "plain element", "tail",
+				}, int64(0)},
+				"hash":   []any{map[string]string{"field": "value", "n": "42", "neg": "-7"}, int64(0)},
+				"zset":   []any{map[string]float64{"a": 1, "b": 2.5, "c": math.Inf(-1), "d": 1e300}, int64(0)},
+				"set":    []any{[]string{"x", "y", "z"}, int64(0)},
+				"intset": []any{[]string{"-1099511627776", "1", "1099511627776"}, int64(0)},
+				"ttl":    []any{"soon", year2100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			contents := dump(loadFile(t, filepath.Join("testdata", tt.file)))
+			// a string too long for a six or fourteen bit length, of
+			// random bytes so it is not compressed
+			if large, ok := contents["large"]; ok {
+				assert.Len(t, large.([]any)[0], 16400)
+				delete(contents, "large")
+			}
+			assert.Equal(t, tt.expected, contents)
+		})
+	}
+}
+
+func TestLoadRedisChecksum(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "redis-7.2.rdb"))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+
+	require.NoError(t, os.WriteFile(path, flipBit(data, len(data)-1), 0o644))
+	_, err = Load(path, keyspace.New())
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	// Redis writes a zero checksum when rdbchecksum is off
+	binary.LittleEndian.PutUint64(data[len(data)-8:], 0)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	loaded := loadFile(t, path)
+	assert.Equal(t, 7, loaded.Len())
+}
+
+func TestLoadRedisUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  []byte
+		expected string
+	}{
+		{"stream", record(15, "events", ""), `stream is not supported at key "events"`},
+		{"module", record(7, "bloom", ""), `module value is not supported at key "bloom"`},
+		{"unknown type", record(99, "key", ""), "unknown value type 99"},
+		{"other database", append([]byte{redisOpSelectDB, 1}, record(0, "key", "value")...), `key "key" is in database 1`},
+		{"module aux", []byte{redisOpModuleAux}, "unsupported opcode 0xf7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte("REDIS0011"), tt.records...)
+			data = append(data, redisOpEOF)
+			data = binary.LittleEndian.AppendUint64(data, crc64Update(0, data))
+			path := filepath.Join(t.TempDir(), "dump.rdb")
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+			_, err := Load(path, keyspace.New())
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+// record returns a key of the given Redis type whose value is one short
+// string.
+func record(valueType byte, key, value string) []byte {
+	data := []byte{valueType, byte(len(key))}
+	data = append(data, key...)
+	data = append(data, byte(len(value)))
+	return append(data, value...)
+}
+
+// goldenKeyspace holds what the export-v*.rdb golden files do.
+func goldenKeyspace() *keyspace.Keyspace {
+	store := keyspace.New()
+	store.SetClock(func() int64 { return goldenTime })
+	hash := keyspace.NewHashObject()
+	hash.Hash["field"] = "value"
+	store.SetObject("hash", hash)
+	ints := keyspace.NewSetObject()
+	for _, member := range []string{"70000", "1", "2"} {
+		ints.Set.Add(member)
+	}
+	store.SetObject("intset", ints)
+	list := keyspace.NewListObject()
+	for _, element := range []string{"a", "1", "-5000", strings.Repeat("a longer element ", 3)} {
+		list.List.PushBack(element)
+	}
+	store.SetObject("list", list)
+	store.Set("number", "12345")
+	set := keyspace.NewSetObject()
+	set.Set.Add("member")
+	store.SetObject("set", set)
+	store.Set("string", "hello")
+	store.SetExpireAt("string", year2100)
+	store.Set("text", strings.Repeat("abc", 20))
+	zset := keyspace.NewZSetObject()
+	zset.ZSet.Add("a", 1)
+	zset.ZSet.Add("b", 2.5)
+	zset.ZSet.Add("c", math.Inf(-1))
+	store.SetObject("zset", zset)
+	return store
+}
+
+// sortedSnapshot lists a snapshot's keys in order, so what is written
+// from it can be compared byte for byte.
+type sortedSnapshot struct {
+	*keyspace.Snapshot
+}
+
+func (s sortedSnapshot) All() iter.Seq2[string, *keyspace.Object] {
+	return func(yield func(string, *keyspace.Object) bool) {
+		objects := make(map[string]*keyspace.Object)
+		for key, object := range s.Snapshot.All() {
+			objects[key] = object
+		}
+		for _, key := range slices.Sorted(func(yield func(string) bool) {
+			for key := range objects {
+				if !yield(key) {
+					return
+				}
+			}
+		}) {
+			if !yield(key, objects[key]) {
+				return
+			}
+		}
+	}
+}
+
+func TestSaveRedisGolden(t *testing.T) {
+	for _, version := range []int{RedisMinVersion, RedisVersion} {
+		t.Run(strconv.Itoa(version), func(t *testing.T) {
+			snapshot := goldenKeyspace().Snapshot()
+			defer snapshot.Release()
+			var buf bytes.Buffer
+			require.NoError(t, writeRedis(&buf, sortedSnapshot{snapshot}, version, nil))
+
+			golden := filepath.Join("testdata", "export-v"+strconv.Itoa(version)+".rdb")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, expected, buf.Bytes())
+			assert.Equal(t, dump(goldenKeyspace()), dump(loadFile(t, golden)))
+		})
+	}
+}
+
+func TestSaveRedisRoundTrip(t *testing.T) {
+	now := goldenTime
+	store := testKeyspace(&now)
+	// values too large for the compact encodings
+	store.Set("compressible", strings.Repeat("0123456789", 1000))
+	bigHash, bigZSet, bigList := keyspace.NewHashObject(), keyspace.NewZSetObject(), keyspace.NewListObject()
+	for i := range 1000 {
+		bigHash.Hash["field"+strconv.Itoa(i)] = strconv.Itoa(i)
+		bigZSet.ZSet.Add("member"+strconv.Itoa(i), float64(i)/7)
+		bigList.List.PushBack(strings.Repeat("x", i%50))
+	}
+	bigHash.Hash["long"] = strings.Repeat("v", 100)
+	store.SetObject("bighash", bigHash)
+	store.SetObject("bigzset", bigZSet)
+	store.SetObject("biglist", bigList)
+
+	for version := RedisMinVersion; version <= RedisVersion; version++ {
+		t.Run(strconv.Itoa(version), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dump.rdb")
+			snapshot := store.Snapshot()
+			require.NoError(t, SaveRedis(path, snapshot, version, nil))
+			snapshot.Release()
+			assert.Equal(t, dump(store), dump(loadFile(t, path)))
+		})
+	}
+}
+
+func TestSaveRedisVersion(t *testing.T) {
+	snapshot := keyspace.New().Snapshot()
+	defer snapshot.Release()
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	assert.Error(t, SaveRedis(path, snapshot, RedisMinVersion-1, nil))
+	assert.Error(t, SaveRedis(path, snapshot, RedisVersion+1, nil))
+	assert.NoFileExists(t, path)
+}
+
+func TestListpack(t *testing.T) {
+	// every integer encoding at both ends of its range, and strings with
+	// each length encoding
+	entries := []string{
+		"0", "127", "128", "-1", "-4096", "4095", "4096", "-4097",
+		"-32768", "32767", "32768", "-8388608", "8388607", "8388608",
+		"-2147483648", "2147483647", "2147483648", "-9223372036854775808", "9223372036854775807",
+		"", "07", "-0", "1.5", strings.Repeat("a", 63), strings.Repeat("b", 64),
+		strings.Repeat("c", 4095), strings.Repeat("d", 4096), strings.Repeat("e", 20000),
+	}
+	lp := newListpack()
+	for _, entry := range entries {
+		lp.append(entry)
+	}
+	unpacked, err := listpackEntries(lp.bytes())
+	require.NoError(t, err)
+	assert.Equal(t, entries, unpacked)
+}
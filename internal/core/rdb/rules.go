@@ -0,0 +1,54 @@
+package rdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SaveRule asks for a snapshot once at least Changes writes have been made
+// and Period has passed since the last one, like a save line in
+// redis.conf.
+type SaveRule struct {
+	Period  time.Duration
+	Changes int64
+}
+
+// DefaultSaveRules are Redis's default save points: after an hour if
+// anything changed, after five minutes if 100 keys did, and after a
+// minute if 10000 did.
+const DefaultSaveRules = "3600 1 300 100 60 10000"
+
+// ParseSaveRules parses save points given as pairs of seconds and
+// changes, as in "3600 1 300 100". An empty string is no rules at all.
+func ParseSaveRules(value string) ([]SaveRule, error) {
+	fields := strings.Fields(value)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("rdb: invalid save rules %q, want pairs of seconds and changes", value)
+	}
+	rules := make([]SaveRule, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil || seconds < 1 {
+			return nil, fmt.Errorf("rdb: invalid save period %q", fields[i])
+		}
+		changes, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || changes < 1 {
+			return nil, fmt.Errorf("rdb: invalid save changes %q", fields[i+1])
+		}
+		rules = append(rules, SaveRule{Period: time.Duration(seconds) * time.Second, Changes: changes})
+	}
+	return rules, nil
+}
+
+// Due reports whether any of rules is met when changes writes have been
+// made and elapsed has passed since the last snapshot.
+func Due(rules []SaveRule, changes int64, elapsed time.Duration) bool {
+	for _, rule := range rules {
+		if changes >= rule.Changes && elapsed >= rule.Period {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Package replication holds the parts of master-replica replication that
+// do not need the keyspace: the backlog a master keeps of its replication
+// stream, and the link a replica keeps to its master.
+//
+// As in Redis, a master's history is named by a replication ID and every
+// byte of its stream has an offset, counted from 1. A replica that knows
+// the ID and the offset it has reached can ask the master to continue
+// from there; a master that still has those bytes in its backlog sends
+// them instead of a full snapshot.
+package replication
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// DefaultBacklogSize is Redis's default repl-backlog-size.
+const DefaultBacklogSize = 1 << 20
+
+// NewID returns a new random replication ID: 40 hexadecimal characters,
+// as Redis uses.
+func NewID() string {
+	id := make([]byte, 20)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Backlog keeps the most recent bytes of a replication stream in a ring
+// buffer.
+type Backlog struct {
+	buf []byte
+	// offset is that of the last byte appended, and held how many of the
+	// bytes up to it are still in buf
+	offset int64
+	held   int
+}
+
+// NewBacklog returns an empty backlog holding up to size bytes, for a
+// stream that has reached offset.
+func NewBacklog(size int, offset int64) *Backlog {
+	return &Backlog{buf: make([]byte, size), offset: offset}
+}
+
+// Resize makes the backlog hold up to size bytes, keeping as many of the
+// most recent ones as fit.
+func (b *Backlog) Resize(size int) {
+	if size == len(b.buf) {
+		return
+	}
+	kept := min(b.held, size)
+	recent, _ := b.Since(b.offset - int64(kept) + 1)
+	*b = *NewBacklog(size, b.offset-int64(kept))
+	b.Append(recent)
+}
+
+// Append adds p to the end of the stream, pushing out the oldest bytes
+// once the backlog is full.
+func (b *Backlog) Append(p []byte) {
+	b.offset += int64(len(p))
+	b.held = min(b.held+len(p), len(b.buf))
+	if len(p) > len(b.buf) {
+		p = p[len(p)-len(b.buf):]
+	}
+	start := b.index(b.offset - int64(len(p)) + 1)
+	copied := copy(b.buf[start:], p)
+	copy(b.buf, p[copied:])
+}
+
+// Since returns the bytes from offset to the end of the stream, and false
+// if the backlog no longer holds them all or offset is past the end. An
+// offset just past the end gives nothing, and true.
+func (b *Backlog) Since(offset int64) ([]byte, bool) {
+	first := b.offset - int64(b.held) + 1
+	if offset < first || offset > b.offset+1 {
+		return nil, false
+	}
+	n := int(b.offset + 1 - offset)
+	out := make([]byte, 0, n)
+	start := b.index(offset)
+	end := min(start+n, len(b.buf))
+	out = append(out, b.buf[start:end]...)
+	return append(out, b.buf[:n-(end-start)]...), true
+}
+
+// Offset returns the offset of the last byte in the stream.
+func (b *Backlog) Offset() int64 {
+	return b.offset
+}
+
+// Len returns how many bytes of the stream the backlog holds.
+func (b *Backlog) Len() int {
+	return b.held
+}
+
+// index returns where the byte at offset goes in buf.
+func (b *Backlog) index(offset int64) int {
+	return int((offset - 1) % int64(len(b.buf)))
+}
@@ -0,0 +1,80 @@
+package replication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBacklog(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    int64
+		appends  []string
+		since    int64
+		expected string
+		ok       bool
+	}{
+		{"everything", 0, []string{"abc", "def"}, 1, "abcdef", true},
+		{"from the middle", 0, []string{"abc", "def"}, 3, "cdef", true},
+		{"caught up", 0, []string{"abc"}, 4, "", true},
+		{"past the end", 0, []string{"abc"}, 5, "", false},
+		{"before the start", 100, []string{"abc"}, 100, "", false},
+		{"after a later start", 100, []string{"abc"}, 101, "abc", true},
+		{"wrapped", 0, []string{"abcdef", "ghij"}, 3, "cdefghij", true},
+		{"pushed out", 0, []string{"abcdef", "ghij"}, 2, "", false},
+		{"larger than the backlog", 0, []string{"abcdefghijkl"}, 5, "efghijkl", true},
+		{"empty", 7, nil, 8, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBacklog(8, tt.start)
+			for _, p := range tt.appends {
+				b.Append([]byte(p))
+			}
+			data, ok := b.Since(tt.since)
+			assert.Equal(t, tt.ok, ok)
+			if ok {
+				assert.Equal(t, tt.expected, string(data))
+			}
+		})
+	}
+}
+
+func TestBacklogOffset(t *testing.T) {
+	b := NewBacklog(4, 10)
+	assert.Equal(t, int64(10), b.Offset())
+	assert.Equal(t, 0, b.Len())
+	b.Append([]byte("abcdef"))
+	assert.Equal(t, int64(16), b.Offset())
+	assert.Equal(t, 4, b.Len())
+	data, ok := b.Since(13)
+	assert.True(t, ok)
+	assert.Equal(t, "cdef", string(data))
+}
+
+func TestBacklogResize(t *testing.T) {
+	b := NewBacklog(8, 0)
+	b.Append([]byte("abcdefgh"))
+	b.Resize(4)
+	assert.Equal(t, int64(8), b.Offset())
+	_, ok := b.Since(4)
+	assert.False(t, ok, "shrinking drops the oldest bytes")
+	data, ok := b.Since(5)
+	assert.True(t, ok)
+	assert.Equal(t, "efgh", string(data))
+
+	b.Resize(6)
+	b.Append([]byte("ij"))
+	data, ok = b.Since(5)
+	assert.True(t, ok, "growing keeps what was held")
+	assert.Equal(t, "efghij", string(data))
+}
+
+func TestNewID(t *testing.T) {
+	id := NewID()
+	assert.Len(t, id, 40)
+	assert.Regexp(t, "^[0-9a-f]+$", id)
+	assert.NotEqual(t, id, NewID())
+}
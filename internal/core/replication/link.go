@@ -0,0 +1,362 @@
+package replication
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+)
+
+const (
+	// Timeout is how long either end of a link waits on a silent peer
+	// before giving up on it, Redis's default repl-timeout.
+	Timeout = 60 * time.Second
+	// RetryInterval is how long a replica waits before connecting to its
+	// master again.
+	RetryInterval = time.Second
+	// AckInterval is how often a replica reports its offset to its master.
+	AckInterval = time.Second
+)
+
+// State is how far a replica's link to its master has got, named as
+// Redis's ROLE reports it.
+type State int
+
+const (
+	// StateConnect is a link waiting to connect.
+	StateConnect State = iota
+	StateConnecting
+	// StateSync is a link receiving a snapshot.
+	StateSync
+	// StateConnected is a link receiving the command stream.
+	StateConnected
+)
+
+func (s State) String() string {
+	return [...]string{"connect", "connecting", "sync", "connected"}[s]
+}
+
+// Handler is what a Link hands the master's data to. Its methods are
+// called from the link's goroutine, except Progress, which is called
+// from the goroutine that sends acknowledgements.
+type Handler interface {
+	// Position returns the replication ID of the replica's dataset and the
+	// offset it has reached, to ask the master to continue from.
+	Position() (id string, offset int64)
+	// Progress returns the offset the replica has reached and how much of
+	// the stream its append-only file has synced to disk, or -1 if it has
+	// none, to acknowledge to the master.
+	Progress() (offset, fsynced int64)
+	// FullSync replaces the dataset with the snapshot read from r, which
+	// puts the replica at offset in the history named id.
+	FullSync(id string, offset int64, r io.Reader) error
+	// Continue reports that the master carries on from Position. id is
+	// the master's replication ID, which is new to the replica if the
+	// master has been promoted since.
+	Continue(id string)
+	// Apply runs commands from the stream, a whole transaction at a
+	// time. raw is the bytes they took in the stream.
+	Apply(cmds []commands.Command, raw []byte)
+	// SetState reports the link's progress.
+	SetState(state State)
+}
+
+var errClosed = errors.New("replication: link closed")
+
+// Link is a replica's connection to its master. It connects, asks to
+// continue from where the replica is and otherwise takes a full snapshot,
+// then applies the command stream until the connection fails, when it
+// starts over.
+type Link struct {
+	addr          string
+	listeningPort int
+	handler       Handler
+	stop          chan struct{}
+	closeOnce     sync.Once
+	// mu guards conn, which acknowledgements are written to from other
+	// goroutines
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Connect starts a link to the master at addr. listeningPort is the port
+// the replica serves clients on, which it tells the master.
+func Connect(addr string, listeningPort int, handler Handler) *Link {
+	l := &Link{
+		addr:          addr,
+		listeningPort: listeningPort,
+		handler:       handler,
+		stop:          make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Addr returns the master's address.
+func (l *Link) Addr() string {
+	return l.addr
+}
+
+// Close disconnects from the master for good. It does not wait for the
+// link's goroutine, which may still make one last call to the handler.
+func (l *Link) Close() {
+	l.closeOnce.Do(func() {
+		close(l.stop)
+	})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+// Ack tells the master the replica has reached offset, and synced the
+// stream up to fsynced to its append-only file. It may be called from any
+// goroutine.
+func (l *Link) Ack(offset, fsynced int64) {
+	l.write("REPLCONF", "ACK", strconv.FormatInt(offset, 10), "FACK", strconv.FormatInt(fsynced, 10))
+}
+
+func (l *Link) run() {
+	for {
+		err := l.connect()
+		select {
+		case <-l.stop:
+			return
+		default:
+		}
+		slog.Warn("lost the link to the master", "master", l.addr, "error", err)
+		l.handler.SetState(StateConnect)
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(RetryInterval):
+		}
+	}
+}
+
+// connect runs one connection to the master, from the handshake to the
+// end of the stream.
+func (l *Link) connect() error {
+	l.handler.SetState(StateConnecting)
+	conn, err := net.DialTimeout("tcp", l.addr, Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !l.setConn(conn) {
+		return errClosed
+	}
+	defer l.setConn(nil)
+	r := bufio.NewReader(deadlineReader{conn})
+
+	if err := l.handshake(r); err != nil {
+		return err
+	}
+	if err := l.psync(r); err != nil {
+		return err
+	}
+	l.handler.SetState(StateConnected)
+	slog.Info("streaming from the master", "master", l.addr)
+
+	stopAcks := make(chan struct{})
+	defer close(stopAcks)
+	go l.ackEvery(stopAcks)
+	return l.stream(r)
+}
+
+// handshake introduces the replica, as Redis replicas do before PSYNC.
+func (l *Link) handshake(r *bufio.Reader) error {
+	for _, args := range [][]string{
+		{"PING"},
+		{"REPLCONF", "listening-port", strconv.Itoa(l.listeningPort)},
+		{"REPLCONF", "capa", "psync2"},
+	} {
+		reply, err := l.request(r, args...)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(reply, "-") {
+			return fmt.Errorf("master replied to %s with %s", strings.Join(args, " "), reply[1:])
+		}
+	}
+	return nil
+}
+
+// psync asks the master to continue from the replica's position, and
+// loads the snapshot it sends if it cannot.
+func (l *Link) psync(r *bufio.Reader) error {
+	id, offset := l.handler.Position()
+	reply, err := l.request(r, "PSYNC", id, strconv.FormatInt(offset+1, 10))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(reply)
+	switch {
+	case len(fields) == 3 && fields[0] == "+FULLRESYNC":
+		offset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid reply to PSYNC: %s", reply)
+		}
+		slog.Info("full resync from the master", "master", l.addr, "replid", fields[1], "offset", offset)
+		l.handler.SetState(StateSync)
+		return l.fullSync(r, fields[1], offset)
+	case len(fields) > 0 && fields[0] == "+CONTINUE":
+		newID := id
+		if len(fields) > 1 {
+			newID = fields[1]
+		}
+		slog.Info("partial resync from the master", "master", l.addr, "offset", offset)
+		l.handler.Continue(newID)
+		return nil
+	}
+	return fmt.Errorf("master replied to PSYNC with %s", reply)
+}
+
+// fullSync reads the snapshot, sent as a bulk string without the final
+// CRLF, and has the handler load it.
+func (l *Link) fullSync(r *bufio.Reader, id string, offset int64) error {
+	header, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(strings.TrimPrefix(header, "$"), 10, 64)
+	if !strings.HasPrefix(header, "$") || err != nil || size < 0 {
+		return fmt.Errorf("invalid snapshot header %q", header)
+	}
+	snapshot := io.LimitReader(r, size)
+	if err := l.handler.FullSync(id, offset, snapshot); err != nil {
+		return err
+	}
+	// the snapshot may end with bytes the decoder did not need
+	_, err = io.Copy(io.Discard, snapshot)
+	return err
+}
+
+// stream applies the master's commands until the connection fails.
+func (l *Link) stream(r *bufio.Reader) error {
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+	var tx []commands.Command
+	var raw []byte
+	for {
+		n, err := r.Read(chunk)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, chunk[:n]...)
+		for len(buf) > 0 {
+			parsed := parser.Parse(buf)
+			if parsed.Error() != nil {
+				return parsed.Error()
+			}
+			consumed := parsed.BytesConsumed()
+			if consumed == 0 {
+				break
+			}
+			command, err := parser.Decoder(parsed)
+			if err != nil {
+				return err
+			}
+			raw = append(raw, buf[:consumed]...)
+			buf = buf[consumed:]
+
+			// a transaction is applied whole, so the replica never holds
+			// half of one
+			switch {
+			case strings.EqualFold(command.Name, "MULTI"):
+				tx = append(tx[:0], command)
+				continue
+			case tx != nil:
+				tx = append(tx, command)
+				if !strings.EqualFold(command.Name, "EXEC") {
+					continue
+				}
+				l.handler.Apply(tx, raw)
+			default:
+				l.handler.Apply([]commands.Command{command}, raw)
+			}
+			tx, raw = nil, nil
+		}
+	}
+}
+
+func (l *Link) ackEvery(stop <-chan struct{}) {
+	ticker := time.NewTicker(AckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.Ack(l.handler.Progress())
+		}
+	}
+}
+
+// request sends a command and reads its one-line reply.
+func (l *Link) request(r *bufio.Reader, args ...string) (string, error) {
+	if err := l.write(args...); err != nil {
+		return "", err
+	}
+	return readLine(r)
+}
+
+func (l *Link) write(args ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return errClosed
+	}
+	l.conn.SetWriteDeadline(time.Now().Add(Timeout))
+	_, err := l.conn.Write(parser.EncodeCommand(commands.Command{Name: args[0], Args: args[1:]}))
+	return err
+}
+
+// setConn records the connection acknowledgements go to, and reports
+// false if the link has been closed.
+func (l *Link) setConn(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.stop:
+		return false
+	default:
+	}
+	l.conn = conn
+	return true
+}
+
+// readLine reads a line, skipping the empty ones a master sends to keep
+// the link alive while it prepares a snapshot.
+func readLine(r *bufio.Reader) (string, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			return line, nil
+		}
+	}
+}
+
+// deadlineReader gives every read from the master Timeout to complete, so
+// a master that goes silent is noticed.
+type deadlineReader struct {
+	conn net.Conn
+}
+
+func (d deadlineReader) Read(p []byte) (int, error) {
+	d.conn.SetReadDeadline(time.Now().Add(Timeout))
+	return d.conn.Read(p)
+}
@@ -0,0 +1,194 @@
+package replication
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+)
+
+// recorder is a Handler that reports what the link hands it.
+type recorder struct {
+	id        string
+	offset    int64
+	snapshots chan string
+	continued chan string
+	applied   chan applied
+	states    chan State
+}
+
+type applied struct {
+	cmds []commands.Command
+	raw  string
+}
+
+func newRecorder(id string, offset int64) *recorder {
+	return &recorder{
+		id:        id,
+		offset:    offset,
+		snapshots: make(chan string, 4),
+		continued: make(chan string, 4),
+		applied:   make(chan applied, 16),
+		states:    make(chan State, 64),
+	}
+}
+
+func (r *recorder) Position() (string, int64) {
+	return r.id, r.offset
+}
+
+func (r *recorder) Progress() (int64, int64) {
+	return r.offset, -1
+}
+
+func (r *recorder) FullSync(id string, offset int64, snapshot io.Reader) error {
+	data, err := io.ReadAll(snapshot)
+	r.snapshots <- id + " " + strconv.FormatInt(offset, 10) + " " + string(data)
+	return err
+}
+
+func (r *recorder) Continue(id string) {
+	r.continued <- id
+}
+
+func (r *recorder) Apply(cmds []commands.Command, raw []byte) {
+	r.applied <- applied{cmds, string(raw)}
+}
+
+func (r *recorder) SetState(state State) {
+	r.states <- state
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out")
+		var zero T
+		return zero
+	}
+}
+
+// fakeMaster accepts the link's connections and replays a script.
+type fakeMaster struct {
+	t        *testing.T
+	listener net.Listener
+}
+
+func newFakeMaster(t *testing.T) *fakeMaster {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	return &fakeMaster{t: t, listener: listener}
+}
+
+func (m *fakeMaster) accept() (net.Conn, *bufio.Reader) {
+	conn, err := m.listener.Accept()
+	require.NoError(m.t, err)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	m.t.Cleanup(func() { conn.Close() })
+	return conn, bufio.NewReader(conn)
+}
+
+// expect reads a command from the replica and checks it is the one given.
+func (m *fakeMaster) expect(r *bufio.Reader, expected string) {
+	m.t.Helper()
+	header, err := r.ReadString('\n')
+	require.NoError(m.t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	require.NoError(m.t, err)
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			require.NoError(m.t, err)
+		}
+		arg, err := r.ReadString('\n')
+		require.NoError(m.t, err)
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	assert.Equal(m.t, expected, strings.Join(args, " "))
+}
+
+func (m *fakeMaster) handshake(conn net.Conn, r *bufio.Reader, psync string) {
+	m.expect(r, "PING")
+	conn.Write([]byte("+PONG\r\n"))
+	m.expect(r, "REPLCONF listening-port 7000")
+	conn.Write([]byte("+OK\r\n"))
+	m.expect(r, "REPLCONF capa psync2")
+	conn.Write([]byte("+OK\r\n"))
+	m.expect(r, psync)
+}
+
+func TestLinkFullSync(t *testing.T) {
+	master := newFakeMaster(t)
+	handler := newRecorder("old", 10)
+	link := Connect(master.listener.Addr().String(), 7000, handler)
+	defer link.Close()
+
+	conn, r := master.accept()
+	master.handshake(conn, r, "PSYNC old 11")
+	// keepalives while the snapshot is written, then the snapshot and
+	// the stream, split where the link has to wait for the rest
+	conn.Write([]byte("+FULLRESYNC new 100\r\n\n\n$8\r\nsnap"))
+	conn.Write([]byte("shot*1\r\n$4\r\nPING\r\n*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1"))
+	conn.Write([]byte("\r\nv\r\n*1\r\n$4\r\nEXEC\r\n"))
+
+	assert.Equal(t, "new 100 snapshot", receive(t, handler.snapshots))
+	ping := receive(t, handler.applied)
+	assert.Equal(t, []commands.Command{{Name: "PING", Args: []string{}}}, ping.cmds)
+	assert.Equal(t, "*1\r\n$4\r\nPING\r\n", ping.raw)
+	tx := receive(t, handler.applied)
+	require.Len(t, tx.cmds, 3)
+	assert.Equal(t, "SET", tx.cmds[1].Name)
+	assert.Equal(t, "*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n*1\r\n$4\r\nEXEC\r\n", tx.raw)
+
+	link.Ack(42, 40)
+	master.expect(r, "REPLCONF ACK 42 FACK 40")
+	// and every AckInterval, with the handler's progress
+	master.expect(r, "REPLCONF ACK 10 FACK -1")
+}
+
+func TestLinkContinueAndReconnect(t *testing.T) {
+	master := newFakeMaster(t)
+	handler := newRecorder("id", 5)
+	link := Connect(master.listener.Addr().String(), 7000, handler)
+	defer link.Close()
+
+	conn, r := master.accept()
+	master.handshake(conn, r, "PSYNC id 6")
+	conn.Write([]byte("+CONTINUE promoted\r\n"))
+	assert.Equal(t, "promoted", receive(t, handler.continued))
+	conn.Close()
+
+	// the link starts over once the connection is lost
+	conn, r = master.accept()
+	master.handshake(conn, r, "PSYNC id 6")
+	conn.Write([]byte("-ERR no\r\n"))
+	var states []State
+	for len(states) < 5 {
+		states = append(states, receive(t, handler.states))
+	}
+	assert.Equal(t, []State{StateConnecting, StateConnected, StateConnect, StateConnecting, StateConnect}, states)
+}
+
+func TestLinkHandshakeError(t *testing.T) {
+	master := newFakeMaster(t)
+	handler := newRecorder("id", 0)
+	link := Connect(master.listener.Addr().String(), 7000, handler)
+	defer link.Close()
+
+	conn, r := master.accept()
+	master.expect(r, "PING")
+	conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+	assert.Equal(t, StateConnecting, receive(t, handler.states))
+	assert.Equal(t, StateConnect, receive(t, handler.states))
+}
@@ -0,0 +1,346 @@
+package sentinel
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// accept serves clients until the listener is closed.
+func (s *Sentinel) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve answers one client's commands, each run on the goroutine running
+// Run.
+func (s *Sentinel) serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+	for {
+		n, err := conn.Read(chunk)
+		if err != nil {
+			return
+		}
+		buf = append(buf, chunk[:n]...)
+		for len(buf) > 0 {
+			parsed := parser.Parse(buf)
+			if parsed.Error() != nil {
+				conn.Write(parser.Encoder(errorValue("ERR Protocol error: " + parsed.Error().Error())))
+				return
+			}
+			consumed := parsed.BytesConsumed()
+			if consumed == 0 {
+				break
+			}
+			buf = buf[consumed:]
+			command, err := parser.Decoder(parsed)
+			if err != nil {
+				conn.Write(parser.Encoder(errorValue("ERR Protocol error: " + err.Error())))
+				return
+			}
+			replies := make(chan common.RespValue, 1)
+			s.post(func() { replies <- s.execute(command) })
+			var reply common.RespValue
+			select {
+			case reply = <-replies:
+			case <-s.done:
+				return
+			}
+			if _, err := conn.Write(parser.Encoder(reply)); err != nil {
+				return
+			}
+			if command.Name == "QUIT" {
+				return
+			}
+		}
+	}
+}
+
+func errorValue(message string) common.RespValue {
+	return common.RespValue{Type: enums.ErrorRespType, Str: message}
+}
+
+func okValue() common.RespValue {
+	return common.RespValue{Type: enums.SimpleStringRespType, Str: "OK"}
+}
+
+func bulk(s string) *common.RespValue {
+	return &common.RespValue{Type: enums.BulkStringRespType, Str: s}
+}
+
+func array(elements ...*common.RespValue) common.RespValue {
+	if elements == nil {
+		elements = []*common.RespValue{}
+	}
+	return common.RespValue{Type: enums.ArrayRespType, Array: elements}
+}
+
+// execute runs a client's command.
+func (s *Sentinel) execute(command commands.Command) common.RespValue {
+	switch command.Name {
+	case "PING":
+		return common.RespValue{Type: enums.SimpleStringRespType, Str: "PONG"}
+	case "QUIT":
+		return okValue()
+	case "ROLE":
+		names := []*common.RespValue{}
+		for _, name := range s.masterNames() {
+			names = append(names, bulk(name))
+		}
+		return array(bulk("sentinel"), &common.RespValue{Type: enums.ArrayRespType, Array: names})
+	case "SENTINEL":
+		if len(command.Args) == 0 {
+			return errorValue("ERR wrong number of arguments for 'sentinel' command")
+		}
+		return s.sentinelCommand(strings.ToUpper(command.Args[0]), command.Args[1:])
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown command '%s'", command.Name))
+	}
+}
+
+func (s *Sentinel) masterNames() []string {
+	names := make([]string, 0, len(s.masters))
+	for name := range s.masters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// sentinelCommand implements SENTINEL's subcommands.
+func (s *Sentinel) sentinelCommand(subcommand string, args []string) common.RespValue {
+	named := func() (*master, *common.RespValue) {
+		m := s.masters[args[0]]
+		if m == nil {
+			reply := errorValue("ERR No such master with that name")
+			return nil, &reply
+		}
+		return m, nil
+	}
+	switch {
+	case subcommand == "MYID" && len(args) == 0:
+		return *bulk(s.id)
+	case subcommand == "GET-MASTER-ADDR-BY-NAME" && len(args) == 1:
+		m := s.masters[args[0]]
+		if m == nil {
+			return common.RespValue{Type: enums.ArrayRespType, IsNull: true}
+		}
+		host, port, _ := net.SplitHostPort(m.addr)
+		return array(bulk(host), bulk(port))
+	case subcommand == "MASTERS" && len(args) == 0:
+		var all []*common.RespValue
+		for _, name := range s.masterNames() {
+			info := s.masterInfo(s.masters[name])
+			all = append(all, &info)
+		}
+		return array(all...)
+	case subcommand == "MASTER" && len(args) == 1:
+		m, errReply := named()
+		if errReply != nil {
+			return *errReply
+		}
+		return s.masterInfo(m)
+	case (subcommand == "REPLICAS" || subcommand == "SLAVES") && len(args) == 1:
+		m, errReply := named()
+		if errReply != nil {
+			return *errReply
+		}
+		var all []*common.RespValue
+		for _, addr := range sortedKeys(m.replicas) {
+			info := replicaInfo(m.replicas[addr])
+			all = append(all, &info)
+		}
+		return array(all...)
+	case subcommand == "SENTINELS" && len(args) == 1:
+		m, errReply := named()
+		if errReply != nil {
+			return *errReply
+		}
+		var all []*common.RespValue
+		for _, id := range sortedKeys(m.sentinels) {
+			info := peerInfo(m.sentinels[id])
+			all = append(all, &info)
+		}
+		return array(all...)
+	case subcommand == "IS-MASTER-DOWN-BY-ADDR" && len(args) == 4:
+		return s.isMasterDownByAddr(args)
+	case subcommand == "CKQUORUM" && len(args) == 1:
+		m, errReply := named()
+		if errReply != nil {
+			return *errReply
+		}
+		usable := 1
+		for _, p := range m.sentinels {
+			if time.Since(p.lastHello) < 5*2*s.cfg.PingPeriod {
+				usable++
+			}
+		}
+		voters := len(m.sentinels) + 1
+		if usable < m.cfg.Quorum {
+			return errorValue(fmt.Sprintf("NOQUORUM %d usable Sentinels. Not enough available Sentinels to reach the specified quorum for this master", usable))
+		}
+		if usable < voters/2+1 {
+			return errorValue(fmt.Sprintf("NOQUORUM %d usable Sentinels. Not enough available Sentinels to reach the majority and authorize a failover", usable))
+		}
+		return common.RespValue{Type: enums.SimpleStringRespType,
+			Str: fmt.Sprintf("OK %d usable Sentinels. Quorum and failover authorization can be reached", usable)}
+	case subcommand == "FAILOVER" && len(args) == 1:
+		m, errReply := named()
+		if errReply != nil {
+			return *errReply
+		}
+		if m.failoverState != failoverNone {
+			return errorValue("INPROG Failover already in progress")
+		}
+		if s.selectReplica(m) == nil {
+			return errorValue("NOGOODSLAVE No suitable replica to promote")
+		}
+		slog.Info("+failover-requested", "master", m.cfg.Name)
+		s.startFailover(m, true)
+		return okValue()
+	default:
+		return errorValue(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'", subcommand))
+	}
+}
+
+// isMasterDownByAddr answers another sentinel asking whether the master
+// at ip port is down here, and, unless the run ID is *, asking for this
+// sentinel's vote to fail it over in the given epoch.
+func (s *Sentinel) isMasterDownByAddr(args []string) common.RespValue {
+	epoch, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return errorValue("ERR value is not an integer or out of range")
+	}
+	addr := net.JoinHostPort(args[0], args[1])
+	var m *master
+	for _, candidate := range s.masters {
+		if candidate.addr == addr {
+			m = candidate
+		}
+	}
+	down, leader, leaderEpoch := int64(0), "*", uint64(0)
+	if m != nil {
+		if m.instance.down() {
+			down = 1
+		}
+		if args[3] != "*" {
+			leader, leaderEpoch = s.vote(m, args[3], epoch)
+		}
+	}
+	return array(
+		&common.RespValue{Type: enums.IntRespType, Int: down},
+		bulk(leader),
+		&common.RespValue{Type: enums.IntRespType, Int: int64(leaderEpoch)},
+	)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// fields turns name and value pairs into the flat array Redis Sentinel
+// describes servers with.
+func fields(pairs ...string) common.RespValue {
+	elements := make([]*common.RespValue, len(pairs))
+	for i, s := range pairs {
+		elements[i] = bulk(s)
+	}
+	return array(elements...)
+}
+
+func millisSince(t time.Time) string {
+	return strconv.FormatInt(time.Since(t).Milliseconds(), 10)
+}
+
+func flags(inst *instance, role string, odown bool) string {
+	names := []string{role}
+	if inst.down() {
+		names = append(names, "s_down")
+	}
+	if odown {
+		names = append(names, "o_down")
+	}
+	if inst.conn == nil && !inst.probing {
+		names = append(names, "disconnected")
+	}
+	return strings.Join(names, ",")
+}
+
+func (s *Sentinel) masterInfo(m *master) common.RespValue {
+	host, port, _ := net.SplitHostPort(m.addr)
+	masterFlags := flags(m.instance, "master", m.odown)
+	if m.failoverState != failoverNone {
+		masterFlags += ",failover_in_progress"
+	}
+	return fields(
+		"name", m.cfg.Name,
+		"ip", host,
+		"port", port,
+		"flags", masterFlags,
+		"last-ping-reply", millisSince(m.lastReply),
+		"role-reported", m.role,
+		"num-slaves", strconv.Itoa(len(m.replicas)),
+		"num-other-sentinels", strconv.Itoa(len(m.sentinels)),
+		"quorum", strconv.Itoa(m.cfg.Quorum),
+		"down-after-milliseconds", strconv.FormatInt(m.cfg.DownAfter.Milliseconds(), 10),
+		"failover-timeout", strconv.FormatInt(m.cfg.FailoverTimeout.Milliseconds(), 10),
+		"config-epoch", strconv.FormatUint(m.configEpoch, 10),
+		"failover-state", m.failoverState.String(),
+	)
+}
+
+func replicaInfo(r *instance) common.RespValue {
+	host, port, _ := net.SplitHostPort(r.addr)
+	masterHost, masterPort, _ := net.SplitHostPort(r.masterAddr)
+	linkStatus := "err"
+	if r.linkUp {
+		linkStatus = "ok"
+	}
+	return fields(
+		"name", r.addr,
+		"ip", host,
+		"port", port,
+		"flags", flags(r, "slave", false),
+		"last-ping-reply", millisSince(r.lastReply),
+		"role-reported", r.role,
+		"master-host", masterHost,
+		"master-port", masterPort,
+		"master-link-status", linkStatus,
+		"slave-repl-offset", strconv.FormatInt(r.offset, 10),
+	)
+}
+
+func peerInfo(p *peer) common.RespValue {
+	host, port, _ := net.SplitHostPort(p.addr)
+	return fields(
+		"name", p.addr,
+		"ip", host,
+		"port", port,
+		"runid", p.id,
+		"flags", "sentinel",
+		"last-hello-message", millisSince(p.lastHello),
+		"voted-leader", p.leader,
+		"voted-leader-epoch", strconv.FormatUint(p.leaderEpoch, 10),
+	)
+}
@@ -0,0 +1,320 @@
+package sentinel
+
+import (
+	"cmp"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+)
+
+type failoverState int
+
+const (
+	failoverNone failoverState = iota
+	// failoverWaitStart seeks election as the sentinel to run it
+	failoverWaitStart
+	// failoverWaitPromotion waits for the chosen replica to report it is
+	// a master
+	failoverWaitPromotion
+)
+
+func (f failoverState) String() string {
+	switch f {
+	case failoverWaitStart:
+		return "wait_start"
+	case failoverWaitPromotion:
+		return "wait_promotion"
+	default:
+		return "none"
+	}
+}
+
+// master is a master being watched, with its replicas and the other
+// sentinels watching it.
+type master struct {
+	cfg MasterConfig
+	// instance is the server currently serving as the master
+	*instance
+	replicas  map[string]*instance
+	sentinels map[string]*peer
+	// configEpoch is the epoch of the failover that made instance the
+	// master; a hello with a greater one has newer news
+	configEpoch uint64
+	odown       bool
+
+	failoverState failoverState
+	failoverEpoch uint64
+	// failoverStart is when the last failover started, pushed a little
+	// into the future so that sentinels that find the master down at the
+	// same time do not all seek election at once
+	failoverStart time.Time
+	stateChanged  time.Time
+	// forced is set for a failover asked for with SENTINEL FAILOVER,
+	// which needs no agreement
+	forced   bool
+	promoted *instance
+
+	// leader is who this sentinel voted for to fail the master over, in
+	// leaderEpoch
+	leader      string
+	leaderEpoch uint64
+}
+
+func newMaster(cfg MasterConfig) *master {
+	return &master{
+		cfg:       cfg,
+		instance:  newInstance(cfg.Addr),
+		replicas:  make(map[string]*instance),
+		sentinels: make(map[string]*peer),
+	}
+}
+
+// instances returns the master and its replicas.
+func (m *master) instances() []*instance {
+	all := make([]*instance, 0, len(m.replicas)+1)
+	all = append(all, m.instance)
+	for _, r := range m.replicas {
+		all = append(all, r)
+	}
+	return all
+}
+
+// checkObjectivelyDown marks m objectively down while it is subjectively
+// down here and, counting this sentinel, a quorum of sentinels recently
+// said it is down there too.
+func (s *Sentinel) checkObjectivelyDown(m *master) {
+	odown := false
+	if m.instance.down() {
+		votes := 1
+		for _, p := range m.sentinels {
+			if p.masterDown && time.Since(p.replied) < 5*s.cfg.PingPeriod {
+				votes++
+			}
+		}
+		odown = votes >= m.cfg.Quorum
+	}
+	if odown != m.odown {
+		m.odown = odown
+		if odown {
+			slog.Info("+odown", "master", m.cfg.Name, "addr", m.addr, "quorum", m.cfg.Quorum)
+		} else {
+			slog.Info("-odown", "master", m.cfg.Name, "addr", m.addr)
+		}
+	}
+}
+
+// startFailoverIfNeeded starts a failover of an objectively down master,
+// unless one was tried too recently.
+func (s *Sentinel) startFailoverIfNeeded(m *master) {
+	if !m.odown || m.failoverState != failoverNone || time.Since(m.failoverStart) < 2*m.cfg.FailoverTimeout {
+		return
+	}
+	s.startFailover(m, false)
+}
+
+func (s *Sentinel) startFailover(m *master, forced bool) {
+	s.currentEpoch++
+	m.failoverEpoch = s.currentEpoch
+	m.forced = forced
+	m.failoverStart = time.Now()
+	if !forced {
+		m.failoverStart = m.failoverStart.Add(rand.N(s.cfg.PingPeriod))
+	}
+	m.setFailoverState(failoverWaitStart)
+	slog.Info("+try-failover", "master", m.cfg.Name, "epoch", m.failoverEpoch)
+}
+
+// seekingElection reports whether this sentinel is standing to fail m
+// over.
+func (m *master) seekingElection(now time.Time) bool {
+	return m.failoverState == failoverWaitStart && !now.Before(m.failoverStart)
+}
+
+func (m *master) setFailoverState(state failoverState) {
+	m.failoverState = state
+	m.stateChanged = time.Now()
+}
+
+func (s *Sentinel) abortFailover(m *master, reason string) {
+	slog.Warn("-failover-abort", "master", m.cfg.Name, "reason", reason)
+	m.setFailoverState(failoverNone)
+	m.promoted = nil
+	m.forced = false
+}
+
+// failover moves a failover of m along.
+func (s *Sentinel) failover(m *master) {
+	switch m.failoverState {
+	case failoverWaitStart:
+		if time.Now().Before(m.failoverStart) {
+			return
+		}
+		if !m.forced {
+			if leader := s.leader(m, m.failoverEpoch); leader != s.id {
+				electionTimeout := min(m.cfg.FailoverTimeout, 10*s.cfg.PingPeriod)
+				if time.Since(m.stateChanged) > electionTimeout {
+					s.abortFailover(m, "not elected")
+				}
+				return
+			}
+			slog.Info("+elected-leader", "master", m.cfg.Name, "epoch", m.failoverEpoch)
+		}
+		replica := s.selectReplica(m)
+		if replica == nil {
+			s.abortFailover(m, "no good replica")
+			return
+		}
+		slog.Info("+selected-slave", "master", m.cfg.Name, "slave", replica.addr, "offset", replica.offset)
+		m.promoted = replica
+		s.send(replica.addr, m.cfg.DownAfter, "REPLICAOF", "NO", "ONE")
+		m.setFailoverState(failoverWaitPromotion)
+	case failoverWaitPromotion:
+		if m.promoted.role != "master" {
+			if time.Since(m.stateChanged) > m.cfg.FailoverTimeout {
+				s.abortFailover(m, "the replica was not promoted in time")
+			}
+			return
+		}
+		slog.Info("+promoted-slave", "master", m.cfg.Name, "slave", m.promoted.addr)
+		m.configEpoch = m.failoverEpoch
+		newAddr := m.promoted.addr
+		host, port, _ := net.SplitHostPort(newAddr)
+		for _, inst := range m.instances() {
+			if inst.addr != newAddr {
+				// the old master too, which is told once it is back if it
+				// cannot be reached now
+				inst.reconfigured = time.Now()
+				s.send(inst.addr, m.cfg.DownAfter, "REPLICAOF", host, port)
+			}
+		}
+		s.switchMaster(m, newAddr)
+	}
+}
+
+// leader counts the votes the other sentinels have cast in epoch, casts
+// this sentinel's own, for the leading candidate or else itself, and
+// returns the winner if it has both a majority of the sentinels and the
+// quorum.
+func (s *Sentinel) leader(m *master, epoch uint64) string {
+	votes := make(map[string]int)
+	for _, p := range m.sentinels {
+		if p.leader != "" && p.leaderEpoch == epoch {
+			votes[p.leader]++
+		}
+	}
+	candidate := winner(votes)
+	if candidate == "" {
+		candidate = s.id
+	}
+	if voted, votedEpoch := s.vote(m, candidate, epoch); votedEpoch == epoch {
+		votes[voted]++
+	}
+	leader := winner(votes)
+	voters := len(m.sentinels) + 1
+	if votes[leader] < voters/2+1 || votes[leader] < m.cfg.Quorum {
+		return ""
+	}
+	return leader
+}
+
+// winner returns the candidate with the most votes, the lowest ID among
+// equals, or "" if there are no votes.
+func winner(votes map[string]int) string {
+	best := ""
+	for candidate, n := range votes {
+		if best == "" || n > votes[best] || (n == votes[best] && candidate < best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// vote casts this sentinel's vote in epoch for candidate to fail m over,
+// unless it has already voted in that epoch, and returns who it voted
+// for. A sentinel that votes for another holds off from seeking election
+// itself.
+func (s *Sentinel) vote(m *master, candidate string, epoch uint64) (string, uint64) {
+	if epoch > s.currentEpoch {
+		s.currentEpoch = epoch
+	}
+	if m.leaderEpoch < epoch && s.currentEpoch <= epoch {
+		m.leader, m.leaderEpoch = candidate, s.currentEpoch
+		slog.Info("+vote-for-leader", "master", m.cfg.Name, "leader", candidate, "epoch", epoch)
+		if candidate != s.id {
+			m.failoverStart = time.Now().Add(rand.N(s.cfg.PingPeriod))
+		}
+	}
+	return m.leader, m.leaderEpoch
+}
+
+// selectReplica picks the replica to promote: of those that are up and
+// answered recently, the one that has replicated the most of the master's
+// history, the lowest address among equals.
+func (s *Sentinel) selectReplica(m *master) *instance {
+	var candidates []*instance
+	for _, r := range m.replicas {
+		if r.down() || r.role != "slave" || time.Since(r.lastReply) > 5*s.cfg.PingPeriod ||
+			time.Since(r.lastRole) > 5*s.cfg.PingPeriod {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	slices.SortFunc(candidates, func(a, b *instance) int {
+		if c := cmp.Compare(b.offset, a.offset); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.addr, b.addr)
+	})
+	return candidates[0]
+}
+
+// switchMaster makes the server at addr m's master, and every other
+// server m had its replicas, starting afresh with each: what they say
+// from now on is what counts.
+func (s *Sentinel) switchMaster(m *master, addr string) {
+	slog.Info("+switch-master", "master", m.cfg.Name, "from", m.addr, "to", addr)
+	old := m.instances()
+	m.instance = newInstance(addr)
+	m.replicas = make(map[string]*instance)
+	for _, inst := range old {
+		inst.close()
+		if inst.addr != addr {
+			m.replicas[inst.addr] = newInstance(inst.addr)
+		}
+	}
+	for _, inst := range m.instances() {
+		s.watch(m, inst)
+	}
+	m.odown = false
+	m.setFailoverState(failoverNone)
+	m.promoted = nil
+	m.forced = false
+}
+
+// fixReplicas points back at the master any replica that has been
+// replicating from elsewhere, or acting as a master, for long enough that
+// it cannot be news this sentinel has yet to hear: the old master back
+// after a failover, say, or a replica a failed failover left behind.
+func (s *Sentinel) fixReplicas(m *master) {
+	if m.instance.down() || m.failoverState != failoverNone || m.instance.role != "master" {
+		return
+	}
+	settle := 4 * 2 * s.cfg.PingPeriod
+	host, port, _ := net.SplitHostPort(m.addr)
+	for _, r := range m.replicas {
+		if r.role == "" || r.down() || (r.role == "slave" && r.masterAddr == m.addr) {
+			continue
+		}
+		if time.Since(r.roleChanged) < settle || time.Since(r.reconfigured) < settle {
+			continue
+		}
+		slog.Info("+fix-slave-config", "master", m.cfg.Name, "slave", r.addr, "role", r.role, "replicating-from", r.masterAddr)
+		r.reconfigured = time.Now()
+		s.send(r.addr, m.cfg.DownAfter, "REPLICAOF", host, port)
+	}
+}
@@ -0,0 +1,344 @@
+package sentinel
+
+import (
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	parser "github.com/suryansh0301/Mnemo/internal/core/protocol/resp"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// instance is a server being watched, a master or one of its replicas.
+type instance struct {
+	addr    string
+	created time.Time
+	// lastReply is when it last answered a ping, or when it started being
+	// watched
+	lastReply time.Time
+	lastProbe time.Time
+	lastHello time.Time
+	// downSince is when it was found subjectively down, zero if it is not
+	downSince time.Time
+
+	// what ROLE last said: its role, and for a replica its master, whether
+	// its link to the master is up and how far it has replicated
+	role       string
+	masterAddr string
+	linkUp     bool
+	offset     int64
+	lastRole   time.Time
+	// roleChanged is when role or masterAddr last changed
+	roleChanged time.Time
+	// reconfigured is when it was last told to replicate from the master
+	reconfigured time.Time
+
+	// probing is set while a probe holds conn
+	probing bool
+	conn    *parser.Client
+	// localIP is the address this sentinel reaches it from
+	localIP string
+	// stop ends its subscriber goroutine
+	stop chan struct{}
+}
+
+func newInstance(addr string) *instance {
+	now := time.Now()
+	return &instance{addr: addr, created: now, lastReply: now, stop: make(chan struct{})}
+}
+
+func (inst *instance) down() bool {
+	return !inst.downSince.IsZero()
+}
+
+// close stops watching the instance.
+func (inst *instance) close() {
+	close(inst.stop)
+	if inst.conn != nil && !inst.probing {
+		inst.conn.Close()
+	}
+}
+
+// closed reports whether close has been called.
+func (inst *instance) closed() bool {
+	select {
+	case <-inst.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// watch starts the goroutine that listens for other sentinels' hellos on
+// inst, reconnecting whenever the connection drops.
+func (s *Sentinel) watch(m *master, inst *instance) {
+	timeout := m.cfg.DownAfter
+	go func() {
+		for {
+			if conn, err := parser.Dial(inst.addr, timeout); err == nil {
+				s.subscribe(inst, conn)
+			}
+			select {
+			case <-inst.stop:
+				return
+			case <-s.done:
+				return
+			case <-time.After(s.cfg.PingPeriod):
+			}
+		}
+	}()
+}
+
+func (s *Sentinel) subscribe(inst *instance, conn *parser.Client) {
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-inst.stop:
+		case <-s.done:
+		case <-finished:
+		}
+		conn.Close()
+	}()
+	conn.Timeout = s.cfg.PingPeriod * 5
+	if _, err := conn.Do("SUBSCRIBE", helloChannel); err != nil {
+		return
+	}
+	// a subscriber waits for messages however long they take
+	conn.Timeout = 0
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		if len(msg.Array) == 3 && msg.Array[0].Str == "message" {
+			text := msg.Array[2].Str
+			s.post(func() { s.receiveHello(text) })
+		}
+	}
+}
+
+// probe pings inst and asks its role, publishing hello on it too unless
+// hello is empty, and hands the result back to the sentinel.
+func (s *Sentinel) probe(m *master, inst *instance, hello string) {
+	inst.probing = true
+	inst.lastProbe = time.Now()
+	conn := inst.conn
+	timeout := m.cfg.DownAfter
+	go func() {
+		var err error
+		if conn == nil {
+			if conn, err = parser.Dial(inst.addr, timeout); err != nil {
+				s.post(func() { s.probed(inst, nil, probeResult{}, err) })
+				return
+			}
+		}
+		conn.Timeout = timeout
+		var result probeResult
+		var ping common.RespValue
+		if ping, err = conn.Do("PING"); err == nil {
+			result.pinged = ping.Type != enums.ErrorRespType || isBusyReply(ping.Str)
+			result.role, err = conn.Do("ROLE")
+		}
+		if err == nil && hello != "" {
+			_, err = conn.Do("PUBLISH", helloChannel, hello)
+		}
+		if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+			result.localIP = addr.IP.String()
+		}
+		s.post(func() { s.probed(inst, conn, result, err) })
+	}()
+}
+
+// isBusyReply reports whether an error reply to PING still shows the
+// server is up, only busy, as Redis counts LOADING and MASTERDOWN.
+func isBusyReply(reply string) bool {
+	return strings.HasPrefix(reply, "LOADING") || strings.HasPrefix(reply, "MASTERDOWN") || strings.HasPrefix(reply, "BUSY")
+}
+
+type probeResult struct {
+	pinged  bool
+	role    common.RespValue
+	localIP string
+}
+
+func (s *Sentinel) probed(inst *instance, conn *parser.Client, result probeResult, err error) {
+	inst.probing = false
+	if inst.closed() {
+		if conn != nil {
+			conn.Close()
+		}
+		return
+	}
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		inst.conn = nil
+		return
+	}
+	inst.conn = conn
+	inst.localIP = result.localIP
+	now := time.Now()
+	if result.pinged {
+		inst.lastReply = now
+	}
+	s.readRole(inst, result.role, now)
+}
+
+// readRole records what ROLE said: ["master", offset, [[ip, port,
+// offset], ...]] or ["slave", host, port, state, offset].
+func (s *Sentinel) readRole(inst *instance, reply common.RespValue, now time.Time) {
+	if reply.Type != enums.ArrayRespType || len(reply.Array) < 2 {
+		return
+	}
+	role, masterAddr := reply.Array[0].Str, ""
+	switch {
+	case role == "master" && len(reply.Array) == 3:
+		inst.offset = reply.Array[1].Int
+		for _, r := range reply.Array[2].Array {
+			if len(r.Array) == 3 {
+				s.discoverReplica(inst, net.JoinHostPort(r.Array[0].Str, r.Array[1].Str))
+			}
+		}
+	case role == "slave" && len(reply.Array) == 5:
+		masterAddr = net.JoinHostPort(reply.Array[1].Str, strconv.FormatInt(reply.Array[2].Int, 10))
+		inst.linkUp = reply.Array[3].Str == "connected"
+		inst.offset = reply.Array[4].Int
+	default:
+		return
+	}
+	if role != inst.role || masterAddr != inst.masterAddr {
+		if inst.role != "" {
+			slog.Info("+role-change", "instance", inst.addr, "role", role, "master", masterAddr)
+		}
+		inst.role, inst.masterAddr, inst.roleChanged = role, masterAddr, now
+	}
+	inst.lastRole = now
+}
+
+// discoverReplica adds a replica the master at inst listed, if inst is
+// still the master of one of the masters being watched.
+func (s *Sentinel) discoverReplica(inst *instance, addr string) {
+	for _, m := range s.masters {
+		if m.instance != inst || m.replicas[addr] != nil {
+			continue
+		}
+		r := newInstance(addr)
+		m.replicas[addr] = r
+		s.watch(m, r)
+		slog.Info("+slave", "master", m.cfg.Name, "slave", addr)
+	}
+}
+
+// checkSubjectivelyDown marks inst down once it has gone unanswered for
+// the down-after period, and up again once it answers.
+func (s *Sentinel) checkSubjectivelyDown(m *master, inst *instance) {
+	late := time.Since(inst.lastReply) > m.cfg.DownAfter
+	switch {
+	case late && !inst.down():
+		inst.downSince = time.Now()
+		slog.Info("+sdown", "master", m.cfg.Name, "instance", inst.addr)
+	case !late && inst.down():
+		inst.downSince = time.Time{}
+		slog.Info("-sdown", "master", m.cfg.Name, "instance", inst.addr)
+	}
+}
+
+// peer is another sentinel watching the same master.
+type peer struct {
+	id        string
+	addr      string
+	lastHello time.Time
+	conn      *parser.Client
+	// asking is set while a question holds conn
+	asking  bool
+	lastAsk time.Time
+	// its last answer to SENTINEL IS-MASTER-DOWN-BY-ADDR: whether it
+	// finds the master down, and who it voted for to lead a failover
+	masterDown  bool
+	replied     time.Time
+	leader      string
+	leaderEpoch uint64
+	removed     bool
+}
+
+func (p *peer) close() {
+	p.removed = true
+	if p.conn != nil && !p.asking {
+		p.conn.Close()
+	}
+}
+
+// askPeer asks p whether it finds m down and, while this sentinel is
+// seeking election to fail m over, for its vote.
+func (s *Sentinel) askPeer(m *master, p *peer) {
+	p.asking = true
+	p.lastAsk = time.Now()
+	conn := p.conn
+	host, port, _ := net.SplitHostPort(m.addr)
+	candidate, epoch := "*", s.currentEpoch
+	if m.seekingElection(time.Now()) {
+		candidate, epoch = s.id, m.failoverEpoch
+	}
+	args := []string{"SENTINEL", "IS-MASTER-DOWN-BY-ADDR", host, port, strconv.FormatUint(epoch, 10), candidate}
+	timeout := s.cfg.PingPeriod
+	go func() {
+		var err error
+		if conn == nil {
+			if conn, err = parser.Dial(p.addr, timeout); err != nil {
+				s.post(func() { s.peerReplied(m, p, nil, common.RespValue{}, err) })
+				return
+			}
+		}
+		conn.Timeout = timeout
+		reply, err := conn.Do(args...)
+		s.post(func() { s.peerReplied(m, p, conn, reply, err) })
+	}()
+}
+
+func (s *Sentinel) peerReplied(m *master, p *peer, conn *parser.Client, reply common.RespValue, err error) {
+	p.asking = false
+	if p.removed || err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		p.conn = nil
+		return
+	}
+	p.conn = conn
+	if reply.Type != enums.ArrayRespType || len(reply.Array) != 3 {
+		return
+	}
+	p.masterDown = reply.Array[0].Int == 1
+	p.replied = time.Now()
+	if leader := reply.Array[1].Str; leader != "*" {
+		if p.leader != leader || p.leaderEpoch != uint64(reply.Array[2].Int) {
+			slog.Info("+vote-for-leader", "master", m.cfg.Name, "sentinel", p.addr, "leader", leader, "epoch", reply.Array[2].Int)
+		}
+		p.leader, p.leaderEpoch = leader, uint64(reply.Array[2].Int)
+	}
+}
+
+// send sends a command to the server at addr on a connection of its own,
+// logging any failure.
+func (s *Sentinel) send(addr string, timeout time.Duration, args ...string) {
+	go func() {
+		conn, err := parser.Dial(addr, timeout)
+		if err != nil {
+			slog.Warn("could not reach a server", "server", addr, "error", err)
+			return
+		}
+		defer conn.Close()
+		conn.Timeout = timeout
+		reply, err := conn.Do(args...)
+		if err == nil && reply.Type == enums.ErrorRespType {
+			slog.Warn("a server refused a command", "server", addr, "command", args[0], "error", reply.Str)
+		} else if err != nil {
+			slog.Warn("could not send a command", "server", addr, "command", args[0], "error", err)
+		}
+	}()
+}
@@ -0,0 +1,294 @@
+// Package sentinel watches masters and their replicas and, when a master
+// fails, promotes one of its replicas in its place, as Redis Sentinel
+// does.
+//
+// A sentinel pings every server it watches. One that stops answering for
+// the down-after period is subjectively down; once a quorum of the
+// sentinels watching a master agree it is down, it is objectively down.
+// The sentinels then elect one of themselves, by majority, to run the
+// failover: it promotes the replica that has the most of the master's
+// history and points the other replicas at it. Sentinels find each other
+// through hello messages published on the servers they watch, which also
+// carry the master's address, so every sentinel learns the outcome of a
+// failover.
+package sentinel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDownAfter is how long a server may go without answering a
+	// ping before it is considered down.
+	DefaultDownAfter = 30 * time.Second
+	// DefaultFailoverTimeout bounds each step of a failover, and is half
+	// the time before a failed one is tried again.
+	DefaultFailoverTimeout = 3 * time.Minute
+	// DefaultPingPeriod is how often every server is pinged.
+	DefaultPingPeriod = time.Second
+	// helloChannel is the channel sentinels announce themselves on.
+	helloChannel = "__sentinel__:hello"
+)
+
+// MasterConfig names a master to watch and says when to fail it over.
+type MasterConfig struct {
+	// Name is how clients ask for the master
+	Name string
+	// Addr is the master's address when the sentinel starts
+	Addr string
+	// Quorum is how many sentinels must agree the master is down
+	Quorum int
+	// DownAfter is DefaultDownAfter if zero
+	DownAfter time.Duration
+	// FailoverTimeout is DefaultFailoverTimeout if zero
+	FailoverTimeout time.Duration
+}
+
+// Config is what a sentinel watches.
+type Config struct {
+	Masters []MasterConfig
+	// PingPeriod is how often every server is pinged; hellos go out
+	// every two periods. DefaultPingPeriod if zero.
+	PingPeriod time.Duration
+}
+
+// Sentinel watches masters, answering clients on its listener. All of
+// its state belongs to the goroutine running Run, which the other
+// goroutines hand their results to with post.
+type Sentinel struct {
+	cfg      Config
+	listener net.Listener
+	id       string
+	// announcePort is the port other sentinels reach this one on
+	announcePort int
+	currentEpoch uint64
+	masters      map[string]*master
+	tasks        chan func()
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// New returns a sentinel watching the masters in cfg and serving clients
+// on listener. Run starts it.
+func New(cfg Config, listener net.Listener) (*Sentinel, error) {
+	if cfg.PingPeriod <= 0 {
+		cfg.PingPeriod = DefaultPingPeriod
+	}
+	s := &Sentinel{
+		cfg:      cfg,
+		listener: listener,
+		id:       newID(),
+		masters:  make(map[string]*master),
+		tasks:    make(chan func(), 256),
+		done:     make(chan struct{}),
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		s.announcePort = addr.Port
+	}
+	for _, mc := range cfg.Masters {
+		if mc.Name == "" {
+			return nil, errors.New("sentinel: a master must have a name")
+		}
+		if _, exists := s.masters[mc.Name]; exists {
+			return nil, fmt.Errorf("sentinel: master %q is given twice", mc.Name)
+		}
+		if _, _, err := net.SplitHostPort(mc.Addr); err != nil {
+			return nil, fmt.Errorf("sentinel: master %q: %w", mc.Name, err)
+		}
+		if mc.Quorum <= 0 {
+			return nil, fmt.Errorf("sentinel: master %q: quorum must be positive", mc.Name)
+		}
+		if mc.DownAfter <= 0 {
+			mc.DownAfter = DefaultDownAfter
+		}
+		if mc.FailoverTimeout <= 0 {
+			mc.FailoverTimeout = DefaultFailoverTimeout
+		}
+		s.masters[mc.Name] = newMaster(mc)
+	}
+	return s, nil
+}
+
+func newID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ID returns the sentinel's run ID.
+func (s *Sentinel) ID() string {
+	return s.id
+}
+
+// Run watches the masters and serves clients until Close is called.
+func (s *Sentinel) Run() {
+	for _, m := range s.masters {
+		s.watch(m, m.instance)
+	}
+	go s.accept()
+	ticker := time.NewTicker(s.cfg.PingPeriod / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			for _, m := range s.masters {
+				for _, inst := range m.instances() {
+					inst.close()
+				}
+				for _, p := range m.sentinels {
+					p.close()
+				}
+			}
+			return
+		case fn := <-s.tasks:
+			fn()
+		case <-ticker.C:
+			for _, m := range s.masters {
+				s.tick(m)
+			}
+		}
+	}
+}
+
+// Close stops the sentinel. Run closes its connections as it returns.
+func (s *Sentinel) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.listener.Close()
+	})
+}
+
+// post runs fn on the goroutine running Run, unless the sentinel has
+// stopped.
+func (s *Sentinel) post(fn func()) {
+	select {
+	case s.tasks <- fn:
+	case <-s.done:
+	}
+}
+
+// tick does what is due for m: probing its servers, asking the other
+// sentinels about it and moving any failover along.
+func (s *Sentinel) tick(m *master) {
+	now := time.Now()
+	for _, inst := range m.instances() {
+		if !inst.probing && now.Sub(inst.lastProbe) >= s.cfg.PingPeriod {
+			hello := ""
+			if now.Sub(inst.lastHello) >= 2*s.cfg.PingPeriod {
+				hello = s.hello(m)
+				inst.lastHello = now
+			}
+			s.probe(m, inst, hello)
+		}
+		s.checkSubjectivelyDown(m, inst)
+	}
+	s.checkObjectivelyDown(m)
+	if m.instance.down() {
+		// a candidate asks for votes on every tick, so that the first to
+		// stand usually gathers them before the others stand too
+		for _, p := range m.sentinels {
+			if !p.asking && (now.Sub(p.lastAsk) >= s.cfg.PingPeriod || m.seekingElection(now)) {
+				s.askPeer(m, p)
+			}
+		}
+	}
+	s.startFailoverIfNeeded(m)
+	s.failover(m)
+	s.fixReplicas(m)
+}
+
+// hello is the message announcing this sentinel and what it knows of m:
+// its address, port and ID, the current epoch, and the master's name,
+// address and config epoch.
+func (s *Sentinel) hello(m *master) string {
+	host, port, _ := net.SplitHostPort(m.addr)
+	return fmt.Sprintf("%s,%d,%s,%d,%s,%s,%s,%d", s.announceIP(m), s.announcePort, s.id, s.currentEpoch,
+		m.cfg.Name, host, port, m.configEpoch)
+}
+
+// announceIP is the address this sentinel reaches m's servers from,
+// which is the one the other sentinels can reach it on too.
+func (s *Sentinel) announceIP(m *master) string {
+	for _, inst := range m.instances() {
+		if inst.localIP != "" {
+			return inst.localIP
+		}
+	}
+	return "127.0.0.1"
+}
+
+// receiveHello handles a hello published by a sentinel, which may be
+// this one.
+func (s *Sentinel) receiveHello(text string) {
+	h, err := parseHello(text)
+	if err != nil {
+		slog.Debug("ignoring a malformed hello", "hello", text, "error", err)
+		return
+	}
+	m := s.masters[h.masterName]
+	if h.id == s.id || m == nil {
+		return
+	}
+	p := m.sentinels[h.id]
+	if p == nil {
+		// a sentinel restarted with a new ID replaces the old one
+		for id, old := range m.sentinels {
+			if old.addr == h.addr {
+				old.close()
+				delete(m.sentinels, id)
+			}
+		}
+		p = &peer{id: h.id, addr: h.addr}
+		m.sentinels[h.id] = p
+		slog.Info("+sentinel", "master", m.cfg.Name, "sentinel", h.addr, "id", h.id)
+	}
+	p.lastHello = time.Now()
+	if h.currentEpoch > s.currentEpoch {
+		s.currentEpoch = h.currentEpoch
+	}
+	if h.masterConfigEpoch > m.configEpoch {
+		m.configEpoch = h.masterConfigEpoch
+		if h.masterAddr != m.addr {
+			slog.Info("+config-update-from", "master", m.cfg.Name, "sentinel", h.addr)
+			s.switchMaster(m, h.masterAddr)
+		}
+	}
+}
+
+type hello struct {
+	addr              string
+	id                string
+	currentEpoch      uint64
+	masterName        string
+	masterAddr        string
+	masterConfigEpoch uint64
+}
+
+func parseHello(text string) (hello, error) {
+	fields := strings.Split(text, ",")
+	if len(fields) != 8 {
+		return hello{}, fmt.Errorf("%d fields instead of 8", len(fields))
+	}
+	epoch, err1 := strconv.ParseUint(fields[3], 10, 64)
+	configEpoch, err2 := strconv.ParseUint(fields[7], 10, 64)
+	if err1 != nil || err2 != nil {
+		return hello{}, errors.New("invalid epoch")
+	}
+	return hello{
+		addr:              net.JoinHostPort(fields[0], fields[1]),
+		id:                fields[2],
+		currentEpoch:      epoch,
+		masterName:        fields[4],
+		masterAddr:        net.JoinHostPort(fields[5], fields[6]),
+		masterConfigEpoch: configEpoch,
+	}, nil
+}
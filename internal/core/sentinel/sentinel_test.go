@@ -0,0 +1,257 @@
+package sentinel
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/suryansh0301/Mnemo/internal/core/commands"
+	"github.com/suryansh0301/Mnemo/internal/core/common"
+	"github.com/suryansh0301/Mnemo/internal/enums"
+)
+
+// newTestSentinel returns a sentinel watching mymaster at 127.0.0.1:6379
+// with a quorum of 2, not yet running.
+func newTestSentinel(t *testing.T) *Sentinel {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s, err := New(Config{
+		Masters:    []MasterConfig{{Name: "mymaster", Addr: "127.0.0.1:6379", Quorum: 2}},
+		PingPeriod: 100 * time.Millisecond,
+	}, listener)
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func intValue(n int64) *common.RespValue {
+	return &common.RespValue{Type: enums.IntRespType, Int: n}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		masters  []MasterConfig
+		expected string
+	}{
+		{"no name", []MasterConfig{{Addr: "a:1", Quorum: 1}}, "a master must have a name"},
+		{"twice", []MasterConfig{{Name: "m", Addr: "a:1", Quorum: 1}, {Name: "m", Addr: "b:1", Quorum: 1}}, `master "m" is given twice`},
+		{"bad address", []MasterConfig{{Name: "m", Addr: "a", Quorum: 1}}, "missing port"},
+		{"no quorum", []MasterConfig{{Name: "m", Addr: "a:1"}}, "quorum must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			_, err = New(Config{Masters: tt.masters}, listener)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseHello(t *testing.T) {
+	h, err := parseHello("10.0.0.1,26379,abc,7,mymaster,10.0.0.2,6379,5")
+	require.NoError(t, err)
+	assert.Equal(t, hello{
+		addr:              "10.0.0.1:26379",
+		id:                "abc",
+		currentEpoch:      7,
+		masterName:        "mymaster",
+		masterAddr:        "10.0.0.2:6379",
+		masterConfigEpoch: 5,
+	}, h)
+
+	for _, text := range []string{"", "a,b,c", "10.0.0.1,26379,abc,x,mymaster,10.0.0.2,6379,5", "10.0.0.1,26379,abc,7,mymaster,10.0.0.2,6379,-1"} {
+		_, err := parseHello(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestReceiveHello(t *testing.T) {
+	s := newTestSentinel(t)
+	m := s.masters["mymaster"]
+
+	s.receiveHello("127.0.0.1,26380,peer1,3,mymaster,127.0.0.1,6379,0")
+	require.Contains(t, m.sentinels, "peer1")
+	assert.Equal(t, "127.0.0.1:26380", m.sentinels["peer1"].addr)
+	assert.Equal(t, uint64(3), s.currentEpoch)
+
+	// its own hellos and ones about other masters are ignored
+	s.receiveHello("127.0.0.1,26379," + s.id + ",3,mymaster,127.0.0.1,6379,0")
+	s.receiveHello("127.0.0.1,26381,peer2,3,other,127.0.0.1,6379,0")
+	assert.Len(t, m.sentinels, 1)
+
+	// a restarted sentinel replaces itself
+	s.receiveHello("127.0.0.1,26380,peer3,3,mymaster,127.0.0.1,6379,0")
+	assert.Len(t, m.sentinels, 1)
+	assert.Contains(t, m.sentinels, "peer3")
+
+	// a newer config epoch moves the master
+	s.receiveHello("127.0.0.1,26380,peer3,4,mymaster,127.0.0.1,6380,4")
+	assert.Equal(t, "127.0.0.1:6380", m.addr)
+	assert.Equal(t, uint64(4), m.configEpoch)
+	assert.Contains(t, m.replicas, "127.0.0.1:6379")
+
+	// and an older one does not
+	s.receiveHello("127.0.0.1,26380,peer3,4,mymaster,127.0.0.1,6379,2")
+	assert.Equal(t, "127.0.0.1:6380", m.addr)
+}
+
+func TestReadRole(t *testing.T) {
+	s := newTestSentinel(t)
+	m := s.masters["mymaster"]
+	now := time.Now()
+
+	s.readRole(m.instance, array(bulk("master"), intValue(100), &common.RespValue{Type: enums.ArrayRespType, Array: []*common.RespValue{
+		{Type: enums.ArrayRespType, Array: []*common.RespValue{bulk("127.0.0.1"), bulk("6380"), bulk("90")}},
+	}}), now)
+	assert.Equal(t, "master", m.role)
+	assert.Equal(t, int64(100), m.offset)
+	require.Contains(t, m.replicas, "127.0.0.1:6380")
+
+	r := m.replicas["127.0.0.1:6380"]
+	s.readRole(r, array(bulk("slave"), bulk("127.0.0.1"), intValue(6379), bulk("connected"), intValue(90)), now)
+	assert.Equal(t, "slave", r.role)
+	assert.Equal(t, "127.0.0.1:6379", r.masterAddr)
+	assert.True(t, r.linkUp)
+	assert.Equal(t, int64(90), r.offset)
+	assert.Equal(t, now, r.roleChanged)
+
+	// a reply that cannot be read changes nothing
+	s.readRole(r, errorValue("ERR unknown command 'ROLE'"), now.Add(time.Second))
+	assert.Equal(t, "slave", r.role)
+	assert.Equal(t, now, r.lastRole)
+}
+
+func TestSelectReplica(t *testing.T) {
+	s := newTestSentinel(t)
+	m := s.masters["mymaster"]
+	now := time.Now()
+	replica := func(addr string, offset int64) *instance {
+		r := newInstance(addr)
+		r.role, r.offset, r.lastRole = "slave", offset, now
+		m.replicas[addr] = r
+		return r
+	}
+	assert.Nil(t, s.selectReplica(m))
+
+	replica("127.0.0.1:6382", 50)
+	behind := replica("127.0.0.1:6381", 40)
+	assert.Equal(t, "127.0.0.1:6382", s.selectReplica(m).addr)
+
+	tied := replica("127.0.0.1:6380", 50)
+	assert.Equal(t, "127.0.0.1:6380", s.selectReplica(m).addr, "the lowest address breaks ties")
+
+	tied.downSince = now
+	m.replicas["127.0.0.1:6382"].lastRole = now.Add(-time.Minute)
+	assert.Same(t, behind, s.selectReplica(m), "replicas that are down or silent are passed over")
+
+	behind.role = "master"
+	assert.Nil(t, s.selectReplica(m))
+}
+
+func TestWinner(t *testing.T) {
+	assert.Equal(t, "", winner(map[string]int{}))
+	assert.Equal(t, "b", winner(map[string]int{"a": 1, "b": 2}))
+	assert.Equal(t, "a", winner(map[string]int{"b": 2, "a": 2, "c": 1}))
+}
+
+func TestVote(t *testing.T) {
+	s := newTestSentinel(t)
+	m := s.masters["mymaster"]
+
+	leader, epoch := s.vote(m, "peer1", 1)
+	assert.Equal(t, "peer1", leader)
+	assert.Equal(t, uint64(1), epoch)
+	assert.Equal(t, uint64(1), s.currentEpoch)
+	assert.True(t, m.failoverStart.After(time.Now().Add(-time.Second)), "voting for another holds off its own failover")
+
+	leader, epoch = s.vote(m, "peer2", 1)
+	assert.Equal(t, "peer1", leader, "one vote per epoch")
+	assert.Equal(t, uint64(1), epoch)
+
+	leader, epoch = s.vote(m, "peer2", 2)
+	assert.Equal(t, "peer2", leader)
+	assert.Equal(t, uint64(2), epoch)
+
+	leader, _ = s.vote(m, "peer3", 1)
+	assert.Equal(t, "peer2", leader, "no votes in past epochs")
+}
+
+func TestLeader(t *testing.T) {
+	// the peers' votes in epoch 1, "" for none, and "self" for this sentinel
+	tests := []struct {
+		name     string
+		votes    []string
+		expected string
+	}{
+		{"alone", nil, ""},
+		{"one peer agrees", []string{"self", ""}, "self"},
+		{"peers elect another", []string{"peer1", "peer1"}, "peer1"},
+		{"its own vote breaks a tie", []string{"peer1", "peer2"}, "peer1"},
+		{"not yet", []string{"", ""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSentinel(t)
+			m := s.masters["mymaster"]
+			self := func(id string) string {
+				if id == "self" {
+					return s.id
+				}
+				return id
+			}
+			for i, vote := range tt.votes {
+				p := &peer{id: "peer" + strconv.Itoa(i+1)}
+				if vote != "" {
+					p.leader, p.leaderEpoch = self(vote), 1
+				}
+				m.sentinels[p.id] = p
+			}
+			assert.Equal(t, self(tt.expected), s.leader(m, 1))
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	s := newTestSentinel(t)
+	m := s.masters["mymaster"]
+	run := func(args ...string) common.RespValue {
+		return s.execute(commands.Command{Name: "SENTINEL", Args: args})
+	}
+
+	reply := run("get-master-addr-by-name", "mymaster")
+	require.Len(t, reply.Array, 2)
+	assert.Equal(t, "127.0.0.1", reply.Array[0].Str)
+	assert.Equal(t, "6379", reply.Array[1].Str)
+	assert.True(t, run("get-master-addr-by-name", "nope").IsNull)
+
+	assert.Equal(t, s.id, run("myid").Str)
+	assert.Equal(t, "ERR No such master with that name", run("master", "nope").Str)
+	assert.Equal(t, "mymaster", run("master", "mymaster").Array[1].Str)
+	assert.Len(t, run("masters").Array, 1)
+	assert.Empty(t, run("replicas", "mymaster").Array)
+	assert.Contains(t, run("ckquorum", "mymaster").Str, "NOQUORUM 1 usable Sentinels")
+	assert.Contains(t, run("failover", "mymaster").Str, "NOGOODSLAVE")
+	assert.Contains(t, run("bogus").Str, "ERR unknown subcommand")
+
+	reply = run("is-master-down-by-addr", "127.0.0.1", "6379", "0", "*")
+	require.Len(t, reply.Array, 3)
+	assert.Equal(t, int64(0), reply.Array[0].Int)
+	assert.Equal(t, "*", reply.Array[1].Str)
+
+	m.downSince = time.Now()
+	reply = run("is-master-down-by-addr", "127.0.0.1", "6379", "1", "peer1")
+	assert.Equal(t, int64(1), reply.Array[0].Int)
+	assert.Equal(t, "peer1", reply.Array[1].Str)
+	assert.Equal(t, int64(1), reply.Array[2].Int)
+
+	reply = run("is-master-down-by-addr", "127.0.0.1", "6380", "1", "peer1")
+	assert.Equal(t, int64(0), reply.Array[0].Int, "an address it does not watch is not down")
+}
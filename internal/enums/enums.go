@@ -18,6 +18,9 @@ const (
 	IntRespType
 	ArrayRespType
 	ErrorRespType
+	// RawRespType is bytes that are already encoded, such as the
+	// replication stream, and are written as they are
+	RawRespType
 )
 
 type CommandName string
@@ -29,6 +32,131 @@ const (
 	PingCommandName   CommandName = "ping"
 	DeleteCommandName CommandName = "del"
 	EchoCommandName   CommandName = "echo"
+
+	ExpireCommandName      CommandName = "expire"
+	PExpireCommandName     CommandName = "pexpire"
+	ExpireAtCommandName    CommandName = "expireat"
+	PExpireAtCommandName   CommandName = "pexpireat"
+	TTLCommandName         CommandName = "ttl"
+	PTTLCommandName        CommandName = "pttl"
+	PersistCommandName     CommandName = "persist"
+	ExpireTimeCommandName  CommandName = "expiretime"
+	PExpireTimeCommandName CommandName = "pexpiretime"
+
+	LPushCommandName   CommandName = "lpush"
+	RPushCommandName   CommandName = "rpush"
+	LPopCommandName    CommandName = "lpop"
+	RPopCommandName    CommandName = "rpop"
+	LRangeCommandName  CommandName = "lrange"
+	LLenCommandName    CommandName = "llen"
+	LIndexCommandName  CommandName = "lindex"
+	LSetCommandName    CommandName = "lset"
+	LRemCommandName    CommandName = "lrem"
+	LTrimCommandName   CommandName = "ltrim"
+	LInsertCommandName CommandName = "linsert"
+	LMoveCommandName   CommandName = "lmove"
+
+	HSetCommandName         CommandName = "hset"
+	HSetNXCommandName       CommandName = "hsetnx"
+	HGetCommandName         CommandName = "hget"
+	HMGetCommandName        CommandName = "hmget"
+	HDelCommandName         CommandName = "hdel"
+	HExistsCommandName      CommandName = "hexists"
+	HLenCommandName         CommandName = "hlen"
+	HKeysCommandName        CommandName = "hkeys"
+	HValsCommandName        CommandName = "hvals"
+	HGetAllCommandName      CommandName = "hgetall"
+	HIncrByCommandName      CommandName = "hincrby"
+	HIncrByFloatCommandName CommandName = "hincrbyfloat"
+	HStrLenCommandName      CommandName = "hstrlen"
+	HRandFieldCommandName   CommandName = "hrandfield"
+	HScanCommandName        CommandName = "hscan"
+
+	SAddCommandName        CommandName = "sadd"
+	SRemCommandName        CommandName = "srem"
+	SCardCommandName       CommandName = "scard"
+	SIsMemberCommandName   CommandName = "sismember"
+	SMIsMemberCommandName  CommandName = "smismember"
+	SMembersCommandName    CommandName = "smembers"
+	SPopCommandName        CommandName = "spop"
+	SRandMemberCommandName CommandName = "srandmember"
+	SMoveCommandName       CommandName = "smove"
+	SInterCommandName      CommandName = "sinter"
+	SUnionCommandName      CommandName = "sunion"
+	SDiffCommandName       CommandName = "sdiff"
+	SInterStoreCommandName CommandName = "sinterstore"
+	SUnionStoreCommandName CommandName = "sunionstore"
+	SDiffStoreCommandName  CommandName = "sdiffstore"
+	SInterCardCommandName  CommandName = "sintercard"
+
+	ZAddCommandName             CommandName = "zadd"
+	ZIncrByCommandName          CommandName = "zincrby"
+	ZRemCommandName             CommandName = "zrem"
+	ZScoreCommandName           CommandName = "zscore"
+	ZMScoreCommandName          CommandName = "zmscore"
+	ZCardCommandName            CommandName = "zcard"
+	ZRankCommandName            CommandName = "zrank"
+	ZRevRankCommandName         CommandName = "zrevrank"
+	ZRangeCommandName           CommandName = "zrange"
+	ZRangeStoreCommandName      CommandName = "zrangestore"
+	ZCountCommandName           CommandName = "zcount"
+	ZLexCountCommandName        CommandName = "zlexcount"
+	ZPopMinCommandName          CommandName = "zpopmin"
+	ZPopMaxCommandName          CommandName = "zpopmax"
+	ZRemRangeByRankCommandName  CommandName = "zremrangebyrank"
+	ZRemRangeByScoreCommandName CommandName = "zremrangebyscore"
+	ZRemRangeByLexCommandName   CommandName = "zremrangebylex"
+	ZUnionStoreCommandName      CommandName = "zunionstore"
+	ZInterStoreCommandName      CommandName = "zinterstore"
+
+	BLPopCommandName    CommandName = "blpop"
+	BRPopCommandName    CommandName = "brpop"
+	BLMoveCommandName   CommandName = "blmove"
+	BZPopMinCommandName CommandName = "bzpopmin"
+	BZPopMaxCommandName CommandName = "bzpopmax"
+
+	SubscribeCommandName    CommandName = "subscribe"
+	UnsubscribeCommandName  CommandName = "unsubscribe"
+	PSubscribeCommandName   CommandName = "psubscribe"
+	PUnsubscribeCommandName CommandName = "punsubscribe"
+	PublishCommandName      CommandName = "publish"
+	PubsubCommandName       CommandName = "pubsub"
+	QuitCommandName         CommandName = "quit"
+
+	MultiCommandName   CommandName = "multi"
+	ExecCommandName    CommandName = "exec"
+	DiscardCommandName CommandName = "discard"
+	WatchCommandName   CommandName = "watch"
+	UnwatchCommandName CommandName = "unwatch"
+
+	BgRewriteAOFCommandName CommandName = "bgrewriteaof"
+	SaveCommandName         CommandName = "save"
+	BgSaveCommandName       CommandName = "bgsave"
+	LastSaveCommandName     CommandName = "lastsave"
+
+	ReplicaOfCommandName CommandName = "replicaof"
+	SlaveOfCommandName   CommandName = "slaveof"
+	PSyncCommandName     CommandName = "psync"
+	ReplConfCommandName  CommandName = "replconf"
+	RoleCommandName      CommandName = "role"
+
+	WaitCommandName    CommandName = "wait"
+	WaitAOFCommandName CommandName = "waitaof"
+
+	ClusterCommandName CommandName = "cluster"
+	AskingCommandName  CommandName = "asking"
+
+	DumpCommandName          CommandName = "dump"
+	RestoreCommandName       CommandName = "restore"
+	RestoreAskingCommandName CommandName = "restore-asking"
+	MigrateCommandName       CommandName = "migrate"
+
+	ConfigCommandName  CommandName = "config"
+	InfoCommandName    CommandName = "info"
+	SlowlogCommandName CommandName = "slowlog"
+	LatencyCommandName CommandName = "latency"
+	MonitorCommandName CommandName = "monitor"
+	ClientCommandName  CommandName = "client"
 )
 
 var stringToCommandName = map[string]CommandName{
@@ -38,6 +166,131 @@ var stringToCommandName = map[string]CommandName{
 	"ping": PingCommandName,
 	"del":  DeleteCommandName,
 	"echo": EchoCommandName,
+
+	"expire":      ExpireCommandName,
+	"pexpire":     PExpireCommandName,
+	"expireat":    ExpireAtCommandName,
+	"pexpireat":   PExpireAtCommandName,
+	"ttl":         TTLCommandName,
+	"pttl":        PTTLCommandName,
+	"persist":     PersistCommandName,
+	"expiretime":  ExpireTimeCommandName,
+	"pexpiretime": PExpireTimeCommandName,
+
+	"lpush":   LPushCommandName,
+	"rpush":   RPushCommandName,
+	"lpop":    LPopCommandName,
+	"rpop":    RPopCommandName,
+	"lrange":  LRangeCommandName,
+	"llen":    LLenCommandName,
+	"lindex":  LIndexCommandName,
+	"lset":    LSetCommandName,
+	"lrem":    LRemCommandName,
+	"ltrim":   LTrimCommandName,
+	"linsert": LInsertCommandName,
+	"lmove":   LMoveCommandName,
+
+	"hset":         HSetCommandName,
+	"hsetnx":       HSetNXCommandName,
+	"hget":         HGetCommandName,
+	"hmget":        HMGetCommandName,
+	"hdel":         HDelCommandName,
+	"hexists":      HExistsCommandName,
+	"hlen":         HLenCommandName,
+	"hkeys":        HKeysCommandName,
+	"hvals":        HValsCommandName,
+	"hgetall":      HGetAllCommandName,
+	"hincrby":      HIncrByCommandName,
+	"hincrbyfloat": HIncrByFloatCommandName,
+	"hstrlen":      HStrLenCommandName,
+	"hrandfield":   HRandFieldCommandName,
+	"hscan":        HScanCommandName,
+
+	"sadd":        SAddCommandName,
+	"srem":        SRemCommandName,
+	"scard":       SCardCommandName,
+	"sismember":   SIsMemberCommandName,
+	"smismember":  SMIsMemberCommandName,
+	"smembers":    SMembersCommandName,
+	"spop":        SPopCommandName,
+	"srandmember": SRandMemberCommandName,
+	"smove":       SMoveCommandName,
+	"sinter":      SInterCommandName,
+	"sunion":      SUnionCommandName,
+	"sdiff":       SDiffCommandName,
+	"sinterstore": SInterStoreCommandName,
+	"sunionstore": SUnionStoreCommandName,
+	"sdiffstore":  SDiffStoreCommandName,
+	"sintercard":  SInterCardCommandName,
+
+	"zadd":             ZAddCommandName,
+	"zincrby":          ZIncrByCommandName,
+	"zrem":             ZRemCommandName,
+	"zscore":           ZScoreCommandName,
+	"zmscore":          ZMScoreCommandName,
+	"zcard":            ZCardCommandName,
+	"zrank":            ZRankCommandName,
+	"zrevrank":         ZRevRankCommandName,
+	"zrange":           ZRangeCommandName,
+	"zrangestore":      ZRangeStoreCommandName,
+	"zcount":           ZCountCommandName,
+	"zlexcount":        ZLexCountCommandName,
+	"zpopmin":          ZPopMinCommandName,
+	"zpopmax":          ZPopMaxCommandName,
+	"zremrangebyrank":  ZRemRangeByRankCommandName,
+	"zremrangebyscore": ZRemRangeByScoreCommandName,
+	"zremrangebylex":   ZRemRangeByLexCommandName,
+	"zunionstore":      ZUnionStoreCommandName,
+	"zinterstore":      ZInterStoreCommandName,
+
+	"blpop":    BLPopCommandName,
+	"brpop":    BRPopCommandName,
+	"blmove":   BLMoveCommandName,
+	"bzpopmin": BZPopMinCommandName,
+	"bzpopmax": BZPopMaxCommandName,
+
+	"subscribe":    SubscribeCommandName,
+	"unsubscribe":  UnsubscribeCommandName,
+	"psubscribe":   PSubscribeCommandName,
+	"punsubscribe": PUnsubscribeCommandName,
+	"publish":      PublishCommandName,
+	"pubsub":       PubsubCommandName,
+	"quit":         QuitCommandName,
+
+	"multi":   MultiCommandName,
+	"exec":    ExecCommandName,
+	"discard": DiscardCommandName,
+	"watch":   WatchCommandName,
+	"unwatch": UnwatchCommandName,
+
+	"bgrewriteaof": BgRewriteAOFCommandName,
+	"save":         SaveCommandName,
+	"bgsave":       BgSaveCommandName,
+	"lastsave":     LastSaveCommandName,
+
+	"replicaof": ReplicaOfCommandName,
+	"slaveof":   SlaveOfCommandName,
+	"psync":     PSyncCommandName,
+	"replconf":  ReplConfCommandName,
+	"role":      RoleCommandName,
+
+	"wait":    WaitCommandName,
+	"waitaof": WaitAOFCommandName,
+
+	"cluster": ClusterCommandName,
+	"asking":  AskingCommandName,
+
+	"dump":           DumpCommandName,
+	"restore":        RestoreCommandName,
+	"restore-asking": RestoreAskingCommandName,
+	"migrate":        MigrateCommandName,
+
+	"config":  ConfigCommandName,
+	"info":    InfoCommandName,
+	"slowlog": SlowlogCommandName,
+	"latency": LatencyCommandName,
+	"monitor": MonitorCommandName,
+	"client":  ClientCommandName,
 }
 
 func StringToCommandName(commandName string) CommandName {